@@ -0,0 +1,202 @@
+// Package graphql serves a read-only GraphQL API alongside the REST one, so
+// a client can fetch, say, a project with its issues, their comments and the
+// project's members in one request:
+//
+//	query {
+//	  project(id: "my-project") {
+//	    name
+//	    members { username role }
+//	    issues(status: "open") { title comments { content author { username } } }
+//	  }
+//	}
+//
+// The schema is built with graphql-go and supports introspection, so GraphQL
+// tools can discover it. Mutations and subscriptions aren't supported;
+// changes go through REST. Queries are limited in depth, aliases and
+// complexity before they run; see limitsRule.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Error codes sent in an error's extensions. Those shared with the REST API
+// mean the same thing there.
+const (
+	codeInvalidQuery    = "invalid_query" // A query that can't be parsed or doesn't fit the schema
+	codeInvalidRequest  = "invalid_request"
+	codeUnauthenticated = "unauthenticated"
+	codeInternal        = "internal_error"
+	codeInvalidID       = "invalid_id"
+	codeForbidden       = "forbidden"
+	codeNotTeamMember   = "not_team_member"
+	codeUserNotFound    = "user_not_found"
+	codeTeamNotFound    = "team_not_found"
+	codeProjectNotFound = "project_not_found"
+	codeTicketNotFound  = "ticket_not_found"
+	codePayloadTooLarge = "payload_too_large"
+)
+
+// Request is the body of a GraphQL request
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Error is one entry of a response's "errors". Path leads to the field that
+// failed, e.g. ["project", "issues", 2, "comments"].
+type Error struct {
+	Message    string                    `json:"message"`
+	Locations  []location.SourceLocation `json:"locations,omitempty"`
+	Path       []any                     `json:"path,omitempty"`
+	Extensions map[string]any            `json:"extensions,omitempty"`
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Extensions: map[string]any{"code": code}}
+}
+
+// Response is the body of a GraphQL response. Data is left out when the
+// request couldn't be run at all.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Handler serves GraphQL requests POSTed as JSON. It must run behind the
+// auth middleware; queries are answered as the authenticated user.
+//
+// A query that runs gets 200, even if some fields failed: those are null in
+// "data" and explained in "errors". One that can't run at all gets 400 and
+// only "errors".
+func Handler(s *services.Services) func(*router.Context) {
+	schema, err := newSchema(s)
+	if err != nil {
+		panic("graphql: invalid schema: " + err.Error())
+	}
+	// Fragment cycles are rejected on their own first: with one, graphql-go's
+	// OverlappingFieldsCanBeMergedRule recurses until the stack overflows
+	checks := [][]graphql.ValidationRuleFn{
+		{graphql.NoFragmentCyclesRule},
+		append(slices.Clone(graphql.SpecifiedRules), limitsRule),
+	}
+
+	return func(c *router.Context) {
+		if id, ok := c.Request.Context().Value(middleware.UserIDKey).(string); !ok || id == "" {
+			c.JSON(http.StatusUnauthorized, Response{Errors: []*Error{newError(codeUnauthenticated, "User not authenticated")}})
+			return
+		}
+
+		var req Request
+		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, Response{Errors: []*Error{newError(codePayloadTooLarge, "Request body is too large")}})
+				return
+			}
+			c.JSON(http.StatusBadRequest, Response{Errors: []*Error{newError(codeInvalidRequest, "Invalid request body")}})
+			return
+		}
+		if req.Query == "" {
+			c.JSON(http.StatusBadRequest, Response{Errors: []*Error{newError(codeInvalidRequest, "Query is required")}})
+			return
+		}
+
+		ctx := c.Request.Context()
+		doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{Errors: errorsFrom(ctx, gqlerrors.FormatErrors(err))})
+			return
+		}
+		for _, rules := range checks {
+			if valid := graphql.ValidateDocument(&schema, doc, rules); !valid.IsValid {
+				c.JSON(http.StatusBadRequest, Response{Errors: errorsFrom(ctx, valid.Errors)})
+				return
+			}
+		}
+
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       ctx,
+		})
+		status := http.StatusOK
+		if result.Data == nil {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{Data: result.Data, Errors: errorsFrom(ctx, result.Errors)})
+	}
+}
+
+// errorsFrom converts the errors graphql-go reports. Resolver errors already
+// carry a code from errorFor; of the rest, those at a field are unexpected
+// failures such as a recovered panic, and the others are about the query.
+func errorsFrom(ctx context.Context, errs []gqlerrors.FormattedError) []*Error {
+	var out []*Error
+	for _, err := range errs {
+		e := &Error{Message: err.Message, Locations: err.Locations, Path: err.Path, Extensions: err.Extensions}
+		if _, ok := e.Extensions["code"]; !ok {
+			if len(e.Path) > 0 {
+				log.Printf("GraphQL field %v failed: %s", e.Path, e.Message)
+				e.Message = "An error occurred processing your request"
+				e.Extensions = map[string]any{"code": codeInternal}
+			} else {
+				e.Extensions = map[string]any{"code": codeInvalidQuery}
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// fieldError is an error returned by a resolver. graphql-go copies its
+// Extensions into the response.
+type fieldError struct {
+	code, msg string
+}
+
+func (e *fieldError) Error() string { return e.msg }
+
+func (e *fieldError) Extensions() map[string]any { return map[string]any{"code": e.code} }
+
+// errorFor describes an error returned by a resolver. Anything unexpected is
+// logged and reported as an internal error, so its details don't leak.
+func errorFor(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		return &fieldError{codeInvalidID, "Invalid ID"}
+	case errors.Is(err, services.ErrUserNotFound):
+		return &fieldError{codeUserNotFound, "User not found"}
+	case errors.Is(err, services.ErrTeamNotFound):
+		return &fieldError{codeTeamNotFound, "Team not found"}
+	case errors.Is(err, services.ErrProjectNotFound):
+		return &fieldError{codeProjectNotFound, "Project not found"}
+	case errors.Is(err, services.ErrIssueNotFound):
+		return &fieldError{codeTicketNotFound, "Issue not found"}
+	case errors.Is(err, services.ErrNotProjectOwner):
+		return &fieldError{codeForbidden, "You don't have permission to access this project"}
+	case errors.Is(err, services.ErrNotTeamMember), errors.Is(err, services.ErrNotMember):
+		return &fieldError{codeNotTeamMember, "You are not a member of this team"}
+	}
+	if ctx.Err() == nil {
+		log.Printf("GraphQL resolver failed: %v", err)
+	}
+	return &fieldError{codeInternal, "An error occurred processing your request"}
+}
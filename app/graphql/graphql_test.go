@@ -0,0 +1,194 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	owner    = "11111111-1111-1111-1111-111111111111"
+	stranger = "22222222-2222-2222-2222-222222222222"
+	project  = "55555555-5555-5555-5555-555555555555"
+	issue    = "66666666-6666-6666-6666-666666666666"
+	comment  = "77777777-7777-7777-7777-777777777777"
+)
+
+// newServer serves /graphql over a personal project with one issue, which
+// has one comment
+func newServer(t *testing.T) (*storetest.DB, func(user, body string) *httptest.ResponseRecorder) {
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"GetProjectByID":   {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), pgtype.UUID{}, pgtype.Text{String: "active", Valid: true}},
+			"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}},
+			"GetIssueByID":     {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+		},
+		Lists: map[string][][]any{
+			"GetProjectIssues": {{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{}, pgtype.Text{String: "open", Valid: true}}},
+			"GetIssueComments": {{storetest.MustUUID(t, comment), "Seen it too", storetest.MustUUID(t, owner), storetest.MustUUID(t, issue), pgtype.UUID{}, pgtype.Timestamp{}, pgtype.Timestamp{}, pgtype.Timestamp{}, "ada@example.com", pgtype.Text{}, pgtype.Text{String: "ada", Valid: true}}},
+		},
+	}
+	queries := store.New(db)
+	memory := cache.NewMemory()
+	teams := services.NewTeamService(queries, memory, services.CacheTTLs{})
+	projects := services.NewProjectService(queries, memory, teams, services.CacheTTLs{})
+	svcs := &services.Services{
+		TeamService:    teams,
+		ProjectService: projects,
+		IssueService:   services.NewIssueService(queries, memory, projects),
+		CommentService: services.NewCommentService(queries, memory, projects, services.CacheTTLs{}),
+	}
+
+	rg := router.NewRouter()
+	rg.POST("/graphql", Handler(svcs))
+	mux := router.ServeMux(rg)
+
+	return db, func(user, body string) *httptest.ResponseRecorder {
+		db.Calls = nil
+		req := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, user))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+}
+
+func TestNestedProjectQuery(t *testing.T) {
+	db, post := newServer(t)
+	body := `{
+		"query": "query Board($id: ID!) { project(id: $id) { name issues { title status comments { content author { username } } } } }",
+		"variables": {"id": "` + project + `"}
+	}`
+
+	t.Run("Owner gets the project with its issues and comments", func(t *testing.T) {
+		rr := post(owner, body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		assertJSON(t, rr.Body.Bytes(), `{"data":{"project":{"name":"Tickit","issues":[{"title":"Crash on login","status":"open","comments":[{"content":"Seen it too","author":{"username":"ada"}}]}]}}}`)
+	})
+
+	t.Run("Others get an error instead of the project", func(t *testing.T) {
+		rr := post(stranger, body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Data   map[string]any `json:"data"`
+			Errors []Error        `json:"errors"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid response %s: %v", rr.Body.String(), err)
+		}
+		if project, ok := resp.Data["project"]; !ok || project != nil {
+			t.Errorf("Expected project to be null, got %v", resp.Data)
+		}
+		if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != codeForbidden || !reflect.DeepEqual(resp.Errors[0].Path, []any{"project"}) {
+			t.Errorf("Expected one forbidden error at [project], got %+v", resp.Errors)
+		}
+		if db.Count("GetProjectIssues") > 0 || db.Count("GetIssueComments") > 0 {
+			t.Errorf("Expected no issues or comments to be read, got %v", db.Calls)
+		}
+	})
+
+	t.Run("Nested issues are checked too", func(t *testing.T) {
+		rr := post(stranger, `{"query": "{ issue(id: \"`+issue+`\") { title comments { content } } }"}`)
+		if !strings.Contains(rr.Body.String(), `"data":{"issue":null}`) || !strings.Contains(rr.Body.String(), codeForbidden) {
+			t.Errorf("Expected a null issue and a forbidden error, got %s", rr.Body.String())
+		}
+	})
+}
+
+func TestQueryFeatures(t *testing.T) {
+	_, post := newServer(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Aliases", `{ p: project(id: "` + project + `") { title: name } }`, `{"p":{"title":"Tickit"}}`},
+		{"Fragments", `{ project(id: "` + project + `") { ...Names } } fragment Names on Project { name slug }`, `{"project":{"name":"Tickit","slug":""}}`},
+		{"Inline fragments", `{ project(id: "` + project + `") { ... on Project { name } __typename } }`, `{"project":{"name":"Tickit","__typename":"Project"}}`},
+		{"Skip and include", `{ project(id: "` + project + `") { name @skip(if: true) status @include(if: true) } }`, `{"project":{"status":"active"}}`},
+		{"Null fields", `{ project(id: "` + project + `") { description teamId team { name } } }`, `{"project":{"description":null,"teamId":null,"team":null}}`},
+		{"Introspection", `{ __schema { queryType { name } } }`, `{"__schema":{"queryType":{"name":"Query"}}}`},
+		{"Type introspection", `{ __type(name: "Comment") { fields { name } } }`, `{"__type":{"fields":[` +
+			`{"name":"author"},{"name":"authorId"},{"name":"content"},{"name":"createdAt"},{"name":"edited"},{"name":"editedAt"},{"name":"id"},{"name":"updatedAt"}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(Request{Query: tt.query})
+			rr := post(owner, string(body))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			assertJSON(t, rr.Body.Bytes(), `{"data":`+tt.want+`}`)
+		})
+	}
+}
+
+func TestInvalidQueries(t *testing.T) {
+	_, post := newServer(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Syntax error", `{ project(id: "x") { name }`, "Syntax Error GraphQL (1:28)"},
+		{"Mutation", `mutation { deleteProject(id: "x") }`, "Only queries are supported"},
+		{"Unknown field", `{ project(id: "x") { owner { name } } }`, `Cannot query field \"owner\" on type \"Project\"`},
+		{"Missing argument", `{ project { name } }`, `argument \"id\" of type \"ID!\" is required`},
+		{"Missing selection", `{ project(id: "x") }`, "must have a sub selection"},
+		{"Undefined variable", `{ project(id: $id) { name } }`, `Variable \"$id\" is not defined`},
+		{"Missing variable", `query ($id: ID!) { project(id: $id) { name } }`, `Variable \"$id\" of required type \"ID!\" was not provided`},
+		{"Fragment cycle", `{ project(id: "x") { ...A } } fragment A on Project { ...A }`, `Cannot spread fragment \"A\" within itself`},
+		{"Too deep", `{ project(id: "x") { issues { project { issues { project { issues { project { issues { title } } } } } } } } }`, "nested more than 8 levels"},
+		{"Too many aliases", `{ project(id: "x") { ` + strings.Repeat("n: name ", maxAliases+1) + `} }`, "more than 30 aliases"},
+		{"Too complex", `{ teams { projects { issues { comments { id content authorId createdAt updatedAt } } } } }`, "Query is too complex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(Request{Query: tt.query})
+			rr := post(owner, string(body))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if strings.Contains(rr.Body.String(), `"data"`) || !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("Expected only an error containing %q, got %s", tt.want, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), codeInvalidQuery) {
+				t.Errorf("Expected the error to have code %s, got %s", codeInvalidQuery, rr.Body.String())
+			}
+		})
+	}
+}
+
+// assertJSON compares JSON by value, since graphql-go returns objects as maps
+// and so sorts their keys
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("Invalid response %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("Invalid want %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("Got  %s\nwant %s", got, want)
+	}
+}
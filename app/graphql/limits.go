@@ -0,0 +1,123 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/kinds"
+	"github.com/graphql-go/graphql/language/visitor"
+)
+
+// Limits on a query, checked before it runs. Each level of nesting can fan
+// out into a query per item of the level above, and each alias asks for a
+// field again, so these bound the work one request can ask for.
+const (
+	maxDepth      = 8
+	maxAliases    = 30
+	maxComplexity = 5000 // Fields a query may resolve, see weight
+	listSize      = 10   // Items a list field is assumed to hold when weighing a query
+)
+
+// limitsRule reports operations that aren't queries and queries over the
+// limits. Introspection fields are answered from the schema without reaching
+// a service, so they aren't weighed.
+func limitsRule(ctx *graphql.ValidationContext) *graphql.ValidationRuleInstance {
+	report := func(node ast.Node, format string, args ...any) {
+		ctx.ReportError(gqlerrors.NewError(fmt.Sprintf(format, args...), []ast.Node{node}, "", nil, []int{}, nil))
+	}
+	return &graphql.ValidationRuleInstance{VisitorOpts: &visitor.VisitorOptions{
+		KindFuncMap: map[string]visitor.NamedVisitFuncs{
+			kinds.OperationDefinition: {Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+				op, ok := p.Node.(*ast.OperationDefinition)
+				if !ok {
+					return visitor.ActionNoChange, nil
+				}
+				if op.Operation != ast.OperationTypeQuery {
+					report(op, "Only queries are supported; changes go through the REST API")
+					return visitor.ActionNoChange, nil
+				}
+
+				w := &weight{ctx: ctx, spreading: map[string]bool{}}
+				w.add(op.SelectionSet, ctx.Schema().QueryType(), 1, 1)
+				if w.depth > maxDepth {
+					report(op, "Query is nested more than %d levels deep", maxDepth)
+				}
+				if w.aliases > maxAliases {
+					report(op, "Query uses more than %d aliases", maxAliases)
+				}
+				if w.cost > maxComplexity {
+					report(op, "Query is too complex: it may resolve more than %d fields", maxComplexity)
+				}
+				return visitor.ActionNoChange, nil
+			}},
+		},
+	}}
+}
+
+// weight measures an operation with its fragments expanded. Its cost counts
+// each field once for every time it may be resolved, taking each list to
+// hold listSize items.
+type weight struct {
+	ctx       *graphql.ValidationContext
+	depth     int
+	aliases   int
+	cost      int
+	spreading map[string]bool // Fragments being expanded, so cycles end
+}
+
+func (w *weight) add(set *ast.SelectionSet, parent *graphql.Object, level, times int) {
+	if set == nil || parent == nil {
+		return
+	}
+	for _, selection := range set.Selections {
+		// A query far over the limit is rejected all the same, and stopping
+		// early keeps fragments spread many times over from taking long
+		if w.cost > maxComplexity {
+			return
+		}
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if sel.Alias != nil {
+				w.aliases++
+			}
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			w.cost += times
+			w.depth = max(w.depth, level)
+			field := parent.Fields()[sel.Name.Value]
+			if field == nil {
+				continue // Reported by graphql.FieldsOnCorrectTypeRule
+			}
+			n := times
+			if _, ok := graphql.GetNullable(field.Type).(*graphql.List); ok {
+				n *= listSize
+			}
+			child, _ := graphql.GetNamed(field.Type).(*graphql.Object)
+			w.add(sel.SelectionSet, child, level+1, n)
+		case *ast.InlineFragment:
+			w.add(sel.SelectionSet, w.typeOf(sel.TypeCondition, parent), level, times)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			fragment := w.ctx.Fragment(name)
+			if fragment == nil || w.spreading[name] {
+				continue
+			}
+			w.spreading[name] = true
+			w.add(fragment.SelectionSet, w.typeOf(fragment.TypeCondition, parent), level, times)
+			delete(w.spreading, name)
+		}
+	}
+}
+
+// typeOf returns the type a fragment applies to
+func (w *weight) typeOf(condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	obj, _ := w.ctx.Schema().Type(condition.Name.Value).(*graphql.Object)
+	return obj
+}
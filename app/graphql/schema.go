@@ -0,0 +1,326 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/graphql-go/graphql"
+)
+
+// newSchema returns the schema, rooted at the Query type. Every resolver goes
+// through a service with the requesting user's ID, so the same access checks
+// apply as on the REST routes.
+func newSchema(s *services.Services) (graphql.Schema, error) {
+	query := newObject("Query")
+	user := newObject("User")
+	publicUser := newObject("PublicUser")
+	team := newObject("Team")
+	member := newObject("TeamMember")
+	project := newObject("Project")
+	issue := newObject("Issue")
+	comment := newObject("Comment")
+	label := newObject("Label")
+
+	getTeam := func(ctx context.Context, ref string) (any, error) {
+		teamID, err := s.TeamService.ResolveTeamID(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		isMember, err := s.TeamService.CheckTeamMembership(ctx, teamID, userID(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, services.ErrNotTeamMember
+		}
+		t, err := s.TeamService.GetTeamByID(ctx, teamID)
+		if err != nil {
+			return nil, err
+		}
+		return teamInfo(t), nil
+	}
+
+	queryFields := graphql.Fields{
+		"me": {Type: user, Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.UserService.GetUserProfile(p.Context, userID(p.Context))
+		})},
+		"user": {Type: publicUser, Args: graphql.FieldConfigArgument{"username": {Type: graphql.NewNonNull(graphql.String)}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				return s.UserService.GetPublicProfile(p.Context, p.Args["username"].(string))
+			})},
+		"projects": {Type: graphql.NewList(project), Args: graphql.FieldConfigArgument{"includeArchived": {Type: graphql.Boolean}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				includeArchived, _ := p.Args["includeArchived"].(bool)
+				return s.ProjectService.GetUserProjects(p.Context, userID(p.Context), includeArchived)
+			})},
+		"project": {Type: project, Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				// Projects may be named by their ID or their slug
+				projectID, err := s.ProjectService.ResolveProjectID(p.Context, p.Args["id"].(string))
+				if err != nil {
+					return nil, err
+				}
+				proj, err := s.ProjectService.GetProjectByID(p.Context, projectID, userID(p.Context))
+				if err != nil {
+					return nil, err
+				}
+				return projectInfo(proj), nil
+			})},
+		"issue": {Type: issue, Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				return s.IssueService.GetIssueByID(p.Context, p.Args["id"].(string), userID(p.Context))
+			})},
+		"teams": {Type: graphql.NewList(team), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.TeamService.GetUserTeams(p.Context, userID(p.Context))
+		})},
+		"team": {Type: team, Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				return getTeam(p.Context, p.Args["id"].(string))
+			})},
+	}
+	addFields(query, queryFields)
+
+	addFields(user, graphql.Fields{
+		"id":        prop(graphql.ID, func(u services.UserProfile) any { return u.ID.String() }),
+		"email":     prop(graphql.String, func(u services.UserProfile) any { return u.Email }),
+		"name":      prop(graphql.String, func(u services.UserProfile) any { return optional(u.Name) }),
+		"username":  prop(graphql.String, func(u services.UserProfile) any { return optional(u.Username) }),
+		"avatarUrl": prop(graphql.String, func(u services.UserProfile) any { return optional(u.AvatarURL) }),
+		"bio":       prop(graphql.String, func(u services.UserProfile) any { return optional(u.Bio) }),
+		"createdAt": prop(graphql.String, func(u services.UserProfile) any { return timestamp(u.CreatedAt) }),
+		"projects":  queryFields["projects"],
+		"teams":     queryFields["teams"],
+	})
+
+	addFields(publicUser, graphql.Fields{
+		"username":  prop(graphql.String, func(u services.PublicProfile) any { return u.Username }),
+		"name":      prop(graphql.String, func(u services.PublicProfile) any { return optional(u.Name) }),
+		"avatarUrl": prop(graphql.String, func(u services.PublicProfile) any { return optional(u.AvatarURL) }),
+		"bio":       prop(graphql.String, func(u services.PublicProfile) any { return optional(u.Bio) }),
+	})
+
+	addFields(team, graphql.Fields{
+		"id":          prop(graphql.ID, func(t services.TeamInfo) any { return t.ID }),
+		"name":        prop(graphql.String, func(t services.TeamInfo) any { return t.Name }),
+		"slug":        prop(graphql.String, func(t services.TeamInfo) any { return t.Slug }),
+		"description": prop(graphql.String, func(t services.TeamInfo) any { return optional(t.Description) }),
+		"avatarUrl":   prop(graphql.String, func(t services.TeamInfo) any { return optional(t.AvatarURL) }),
+		"createdAt":   prop(graphql.String, func(t services.TeamInfo) any { return timestamp(t.CreatedAt) }),
+		"updatedAt":   prop(graphql.String, func(t services.TeamInfo) any { return timestamp(t.UpdatedAt) }),
+		"members": {Type: graphql.NewList(member), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.TeamService.GetTeamMembers(p.Context, source[services.TeamInfo](p).ID, userID(p.Context))
+		})},
+		"projects": {Type: graphql.NewList(project), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.ProjectService.GetTeamProjects(p.Context, source[services.TeamInfo](p).ID, userID(p.Context))
+		})},
+	})
+
+	addFields(member, graphql.Fields{
+		"userId":    prop(graphql.ID, func(m services.TeamMemberInfo) any { return m.UserID }),
+		"email":     prop(graphql.String, func(m services.TeamMemberInfo) any { return m.Email }),
+		"name":      prop(graphql.String, func(m services.TeamMemberInfo) any { return optional(m.Name) }),
+		"username":  prop(graphql.String, func(m services.TeamMemberInfo) any { return optional(m.Username) }),
+		"avatarUrl": prop(graphql.String, func(m services.TeamMemberInfo) any { return optional(m.AvatarURL) }),
+		"role":      prop(graphql.String, func(m services.TeamMemberInfo) any { return m.Role }),
+	})
+
+	addFields(project, graphql.Fields{
+		"id":          prop(graphql.ID, func(p services.ProjectInfo) any { return p.ID }),
+		"name":        prop(graphql.String, func(p services.ProjectInfo) any { return p.Name }),
+		"slug":        prop(graphql.String, func(p services.ProjectInfo) any { return p.Slug }),
+		"description": prop(graphql.String, func(p services.ProjectInfo) any { return optional(p.Description) }),
+		"status":      prop(graphql.String, func(p services.ProjectInfo) any { return p.Status }),
+		"ownerId":     prop(graphql.ID, func(p services.ProjectInfo) any { return p.OwnerID }),
+		"teamId":      prop(graphql.ID, func(p services.ProjectInfo) any { return optional(p.TeamID) }),
+		"createdAt":   prop(graphql.String, func(p services.ProjectInfo) any { return timestamp(p.CreatedAt) }),
+		"updatedAt":   prop(graphql.String, func(p services.ProjectInfo) any { return timestamp(p.UpdatedAt) }),
+		"team": {Type: team, Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			if teamID := source[services.ProjectInfo](p).TeamID; teamID != "" {
+				return getTeam(p.Context, teamID)
+			}
+			return nil, nil
+		})},
+		// Members of a team project are its team's; a personal project has
+		// only its owner
+		"members": {Type: graphql.NewList(member), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			proj := source[services.ProjectInfo](p)
+			if proj.TeamID != "" {
+				return s.TeamService.GetTeamMembers(p.Context, proj.TeamID, userID(p.Context))
+			}
+			if _, err := s.ProjectService.GetProjectByID(p.Context, proj.ID, userID(p.Context)); err != nil {
+				return nil, err
+			}
+			owner, err := s.UserService.GetUserProfile(p.Context, proj.OwnerID)
+			if err != nil {
+				return nil, err
+			}
+			return []services.TeamMemberInfo{{
+				UserID:    proj.OwnerID,
+				Email:     owner.Email,
+				Name:      owner.Name,
+				Username:  owner.Username,
+				AvatarURL: owner.AvatarURL,
+				Role:      "owner",
+			}}, nil
+		})},
+		"issues": {Type: graphql.NewList(issue), Args: graphql.FieldConfigArgument{"status": {Type: graphql.String}},
+			Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+				projectID := source[services.ProjectInfo](p).ID
+				if status, ok := p.Args["status"].(string); ok {
+					return s.IssueService.GetIssuesByStatus(p.Context, projectID, status, userID(p.Context))
+				}
+				return s.IssueService.GetProjectIssues(p.Context, projectID, userID(p.Context))
+			})},
+		"labels": {Type: graphql.NewList(label), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.IssueService.GetProjectLabels(p.Context, source[services.ProjectInfo](p).ID, userID(p.Context))
+		})},
+	})
+
+	addFields(issue, graphql.Fields{
+		"id":          prop(graphql.ID, func(i services.IssueInfo) any { return i.ID }),
+		"number":      prop(graphql.Int, func(i services.IssueInfo) any { return i.Number }),
+		"projectId":   prop(graphql.ID, func(i services.IssueInfo) any { return i.ProjectID }),
+		"title":       prop(graphql.String, func(i services.IssueInfo) any { return i.Title }),
+		"description": prop(graphql.String, func(i services.IssueInfo) any { return optional(i.Description) }),
+		"status":      prop(graphql.String, func(i services.IssueInfo) any { return i.Status }),
+		"priority":    prop(graphql.String, func(i services.IssueInfo) any { return optional(i.Priority) }),
+		"reporterId":  prop(graphql.ID, func(i services.IssueInfo) any { return i.ReporterID }),
+		"assigneeId":  prop(graphql.ID, func(i services.IssueInfo) any { return optional(i.AssigneeID) }),
+		"dueDate": prop(graphql.String, func(i services.IssueInfo) any {
+			if i.DueDate == nil {
+				return nil
+			}
+			return i.DueDate.Format(time.RFC3339)
+		}),
+		"overdue":   prop(graphql.Boolean, func(i services.IssueInfo) any { return i.Overdue }),
+		"createdAt": prop(graphql.String, func(i services.IssueInfo) any { return timestamp(i.CreatedAt) }),
+		"updatedAt": prop(graphql.String, func(i services.IssueInfo) any { return timestamp(i.UpdatedAt) }),
+		"project": {Type: project, Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			proj, err := s.ProjectService.GetProjectByID(p.Context, source[services.IssueInfo](p).ProjectID, userID(p.Context))
+			if err != nil {
+				return nil, err
+			}
+			return projectInfo(proj), nil
+		})},
+		"comments": {Type: graphql.NewList(comment), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.CommentService.GetIssueComments(p.Context, source[services.IssueInfo](p).ID, userID(p.Context))
+		})},
+		"labels": {Type: graphql.NewList(label), Resolve: resolver(func(p graphql.ResolveParams) (any, error) {
+			return s.IssueService.GetIssueLabels(p.Context, source[services.IssueInfo](p).ID, userID(p.Context))
+		})},
+	})
+
+	addFields(comment, graphql.Fields{
+		"id":        prop(graphql.ID, func(c services.CommentInfo) any { return c.ID }),
+		"content":   prop(graphql.String, func(c services.CommentInfo) any { return c.Content }),
+		"authorId":  prop(graphql.ID, func(c services.CommentInfo) any { return c.UserID }),
+		"edited":    prop(graphql.Boolean, func(c services.CommentInfo) any { return c.Edited }),
+		"editedAt":  prop(graphql.String, func(c services.CommentInfo) any { return timestamp(c.EditedAt) }),
+		"createdAt": prop(graphql.String, func(c services.CommentInfo) any { return timestamp(c.CreatedAt) }),
+		"updatedAt": prop(graphql.String, func(c services.CommentInfo) any { return timestamp(c.UpdatedAt) }),
+		"author": {Type: publicUser, Resolve: func(p graphql.ResolveParams) (any, error) {
+			c := source[services.CommentInfo](p)
+			return services.PublicProfile{Username: c.UserUsername, Name: c.UserName, AvatarURL: c.UserAvatar}, nil
+		}},
+	})
+
+	addFields(label, graphql.Fields{
+		"id":        prop(graphql.ID, func(l services.LabelInfo) any { return l.ID }),
+		"name":      prop(graphql.String, func(l services.LabelInfo) any { return l.Name }),
+		"color":     prop(graphql.String, func(l services.LabelInfo) any { return optional(l.Color) }),
+		"createdAt": prop(graphql.String, func(l services.LabelInfo) any { return timestamp(l.CreatedAt) }),
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// newObject returns an object type whose fields are added once every type
+// exists, since they refer to each other
+func newObject(name string) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: graphql.Fields{}})
+}
+
+func addFields(obj *graphql.Object, fields graphql.Fields) {
+	for name, field := range fields {
+		obj.AddFieldConfig(name, field)
+	}
+}
+
+// resolver wraps a field's resolver so the errors it returns are described
+// by errorFor
+func resolver(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		value, err := resolve(p)
+		if err != nil {
+			return nil, errorFor(p.Context, err)
+		}
+		return value, nil
+	}
+}
+
+// source returns the value a field is resolved from. Top-level fields
+// resolve to pointers and list items to values, so it accepts either.
+func source[T any](p graphql.ResolveParams) T {
+	if v, ok := p.Source.(*T); ok {
+		return *v
+	}
+	return p.Source.(T)
+}
+
+// prop returns a scalar field read from a value of type T
+func prop[T any](typ graphql.Output, get func(T) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(source[T](p)), nil
+	}}
+}
+
+// timestamp returns nil for an unset timestamp, which is sent as null
+func timestamp(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// optional returns nil for an empty string, which is sent as null
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func userID(ctx context.Context) string {
+	id, _ := ctx.Value(middleware.UserIDKey).(string)
+	return id
+}
+
+func projectInfo(p *store.Project) services.ProjectInfo {
+	return services.ProjectInfo{
+		ID:          p.ID.String(),
+		Name:        p.Name,
+		Description: p.Description.String,
+		OwnerID:     p.OwnerID.String(),
+		TeamID:      p.TeamID.String(),
+		Status:      p.Status.String,
+		CreatedAt:   services.FormatTimestamp(p.CreatedAt),
+		UpdatedAt:   services.FormatTimestamp(p.UpdatedAt),
+		Version:     p.Version,
+		Slug:        p.Slug,
+	}
+}
+
+func teamInfo(t *store.Team) services.TeamInfo {
+	return services.TeamInfo{
+		ID:          t.ID.String(),
+		Name:        t.Name,
+		Description: t.Description.String,
+		AvatarURL:   t.AvatarUrl.String,
+		CreatedAt:   services.FormatTimestamp(t.CreatedAt),
+		UpdatedAt:   services.FormatTimestamp(t.UpdatedAt),
+		Slug:        t.Slug,
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// NewAdminMiddleware creates a middleware that ensures the authenticated user
+// is an admin whose account hasn't been disabled.
+// It must run after AuthMiddleware.
+func NewAdminMiddleware(queries *store.Queries) func(http.Handler) http.Handler {
+	return NewResourceGuard(func(r *http.Request, userID pgtype.UUID) error {
+		access, err := queries.GetUserAccess(r.Context(), userID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && (!access.IsAdmin || access.DisabledAt.Valid)) {
+			return guardErr(ErrResourceForbidden, "forbidden", "Forbidden: admin access required")
+		}
+		return err
+	})
+}
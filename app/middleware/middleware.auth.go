@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
@@ -12,26 +14,126 @@ type contextKey string
 
 const UserIDKey contextKey = "user_id"
 
+// ClaimsKey holds the validated *auth.Claims of the request's token
+const ClaimsKey contextKey = "claims"
+
+// APIKeyHeader carries an API key, as an alternative to sending it as a
+// bearer token
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyKey holds the *auth.APIKey a request was made with. It is unset for
+// requests made with a login token, which carry ClaimsKey instead.
+const APIKeyKey contextKey = "api_key"
+
+// ScopesKey holds the scopes of the request's principal, as a []string:
+// every scope for a login token, or those granted to an API key
+const ScopesKey contextKey = "scopes"
+
+// AccountChecker reports whether a user's account has been disabled, such as
+// services.UserService
+type AccountChecker interface {
+	IsAccountDisabled(ctx context.Context, userID string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves an API key to its owner and scopes, such as
+// services.APIKeyService. Keys that don't exist, have been revoked or have
+// expired are reported with auth.ErrInvalidAPIKey.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKey, error)
+}
+
 // AuthMiddleware validates the JWT token in the Authorization header
 // and injects the user ID into the request context.
+// It does not check for revoked tokens or disabled accounts; routes should
+// use NewAuthMiddleware.
 func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return NewAuthMiddleware(nil, nil, nil)(next)
+}
+
+// NewAuthMiddleware creates a middleware that authenticates requests with
+// either a JWT or, when keys is set, an API key, and injects the user ID into
+// the request context along with the token's claims or the key.
+//
+// A JWT is sent as a bearer token in the Authorization header; tokens
+// revoked through denylist are rejected. An API key is sent either the same
+// way or in the X-API-Key header. The principal's scopes are injected too,
+// for RequireScope. Accounts disabled according to accounts are rejected
+// however the request is authenticated. Any of the checks is skipped when
+// its argument is nil.
+func NewAuthMiddleware(denylist *auth.Denylist, accounts AccountChecker, keys APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(APIKeyHeader)
+			keyHeader := token != ""
+			if !keyHeader {
+				authHeader := r.Header.Get("Authorization")
+				if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+					writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: no token provided")
+					return
+				}
+				token = strings.TrimPrefix(authHeader, "Bearer ")
+			}
 
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "Unauthorized: no token provided", http.StatusUnauthorized)
-			return
-		}
+			ctx := r.Context()
+			var userID string
+			var scopes []string
+			if keyHeader || auth.IsAPIKey(token) {
+				if keys == nil {
+					writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: API keys are not accepted")
+					return
+				}
+				key, err := keys.AuthenticateAPIKey(ctx, token)
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: invalid API key")
+					return
+				}
+				if err != nil {
+					log.Printf("Auth: %v", err)
+					writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Unable to verify API key")
+					return
+				}
+				userID, scopes = key.UserID, key.Scopes
+				ctx = context.WithValue(ctx, APIKeyKey, key)
+			} else {
+				claims, err := auth.ValidateJWT(token)
+				if err != nil {
+					writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: invalid token")
+					return
+				}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+				if denylist != nil {
+					revoked, err := denylist.IsRevoked(ctx, claims)
+					if err != nil {
+						log.Printf("Auth: %v", err)
+						writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Unable to verify token")
+						return
+					}
+					if revoked {
+						writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: token has been revoked")
+						return
+					}
+				}
+				userID, scopes = claims.UserID, auth.Scopes
+				ctx = context.WithValue(ctx, ClaimsKey, claims)
+			}
 
-		claims, err := auth.ValidateJWT(token)
-		if err != nil {
-			http.Error(w, "Unauthorized: invalid token", http.StatusUnauthorized)
-			return
-		}
+			if accounts != nil {
+				disabled, err := accounts.IsAccountDisabled(ctx, userID)
+				if err != nil {
+					log.Printf("Auth: %v", err)
+					writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Unable to verify account")
+					return
+				}
+				if disabled {
+					// Same body as a refused login
+					writeError(w, http.StatusForbidden, "account_disabled", "This account has been disabled")
+					return
+				}
+			}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+			ctx = context.WithValue(ctx, UserIDKey, userID)
+			ctx = context.WithValue(ctx, ScopesKey, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
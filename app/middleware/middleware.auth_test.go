@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+)
+
+// fakeKeys resolves the API keys it holds. A nil entry fails as if the
+// database were down.
+type fakeKeys map[string]*auth.APIKey
+
+func (k fakeKeys) AuthenticateAPIKey(_ context.Context, key string) (*auth.APIKey, error) {
+	found, ok := k[key]
+	if !ok {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if found == nil {
+		return nil, errors.New("connection refused")
+	}
+	return found, nil
+}
+
+// fakeAccounts reports the users it holds as disabled
+type fakeAccounts map[string]bool
+
+func (a fakeAccounts) IsAccountDisabled(_ context.Context, userID string) (bool, error) {
+	return a[userID], nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		disabled = "22222222-2222-2222-2222-222222222222"
+		readKey  = auth.APIKeyPrefix + "read"
+		writeKey = auth.APIKeyPrefix + "write"
+		downKey  = auth.APIKeyPrefix + "down"
+		oldKey   = auth.APIKeyPrefix + "disabled"
+	)
+	keys := fakeKeys{
+		readKey:  {ID: "k1", UserID: owner, Scopes: []string{auth.ScopeRead}},
+		writeKey: {ID: "k2", UserID: owner, Scopes: []string{auth.ScopeWrite}},
+		oldKey:   {ID: "k3", UserID: disabled, Scopes: []string{auth.ScopeWrite}},
+		downKey:  nil,
+	}
+
+	rg := router.NewRouter()
+	api := rg.Group("/api", NewAuthMiddleware(nil, fakeAccounts{disabled: true}, keys))
+	whoami := func(c *router.Context) {
+		key, _ := c.Request.Context().Value(APIKeyKey).(*auth.APIKey)
+		if key == nil {
+			c.Error(http.StatusInternalServerError, "no_key", "Expected the API key in the context")
+			return
+		}
+		if scopes, _ := c.Request.Context().Value(ScopesKey).([]string); !slices.Equal(scopes, key.Scopes) {
+			c.Error(http.StatusInternalServerError, "no_scopes", "Expected the key's scopes in the context")
+			return
+		}
+		c.Status(http.StatusOK, c.Request.Context().Value(UserIDKey).(string)+" with "+key.ID)
+	}
+	api.GET("/tickets", whoami)
+	mux := router.ServeMux(rg)
+
+	do := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header = header
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	bearer := func(key string) http.Header { return http.Header{"Authorization": {"Bearer " + key}} }
+	keyHeader := func(key string) http.Header {
+		h := http.Header{}
+		h.Set(APIKeyHeader, key)
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		status int
+	}{
+		{"Bearer key", "GET", "/api/tickets", bearer(readKey), http.StatusOK},
+		{"X-API-Key header", "GET", "/api/tickets", keyHeader(readKey), http.StatusOK},
+		{"Write key", "GET", "/api/tickets", bearer(writeKey), http.StatusOK},
+		{"Unknown key", "GET", "/api/tickets", bearer(auth.APIKeyPrefix + "revoked"), http.StatusUnauthorized},
+		{"X-API-Key must be a key", "GET", "/api/tickets", keyHeader("not-a-key"), http.StatusUnauthorized},
+		{"Key store down", "GET", "/api/tickets", bearer(downKey), http.StatusServiceUnavailable},
+		{"Disabled account's key", "GET", "/api/tickets", bearer(oldKey), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(tt.method, tt.path, tt.header)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d (%s)", tt.status, rr.Code, rr.Body.String())
+			}
+			if tt.status == http.StatusOK && !strings.Contains(rr.Body.String(), owner) {
+				t.Errorf("Expected the key's owner to be the user, got %s", rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("Keys are refused without an authenticator", func(t *testing.T) {
+		h := NewAuthMiddleware(nil, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Expected the request to be refused")
+		}))
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+readKey)
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", rr.Code)
+		}
+	})
+}
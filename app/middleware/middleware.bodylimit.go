@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+// BodyLimit returns a middleware capping request bodies at maxBytes.
+// Requests declaring a larger Content-Length are refused with 413 before the
+// handler runs; other bodies are wrapped in http.MaxBytesReader, so reading
+// past the limit fails and BindAndValidate responds with 413 as well.
+// Requests matching one of the exempt route patterns, e.g. uploads with
+// limits of their own, are left alone.
+func BodyLimit(maxBytes int64, exempt ...string) func(http.Handler) http.Handler {
+	patterns := make([]*router.Pattern, len(exempt))
+	for i, pattern := range exempt {
+		patterns[i] = router.NewPattern(pattern)
+	}
+	tooLarge := fmt.Sprintf("Request body must be at most %d bytes", maxBytes)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, pattern := range patterns {
+				if pattern.Matches(r.URL.Path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if r.ContentLength > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large", tooLarge)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+type noteRequest struct {
+	Note string `json:"note"`
+}
+
+func (r *noteRequest) Validate(v *validator.Validator) {}
+
+func TestBodyLimit(t *testing.T) {
+	rg := router.NewRouter()
+	var received string
+	rg.POST("/notes", func(c *router.Context) {
+		var req noteRequest
+		if !c.BindAndValidate(&req) {
+			return
+		}
+		received = req.Note
+		c.Status(http.StatusCreated)
+	})
+	rg.POST("/uploads", func(c *router.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = string(body)
+		c.Status(http.StatusCreated)
+	})
+	mux := BodyLimit(32, "/uploads")(router.ServeMux(rg))
+
+	post := func(path string, body io.Reader) *httptest.ResponseRecorder {
+		received = ""
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("POST", path, body))
+		return rr
+	}
+	big := `{"note":"` + strings.Repeat("x", 64) + `"}`
+
+	t.Run("Under the limit", func(t *testing.T) {
+		if rr := post("/notes", strings.NewReader(`{"note":"hello"}`)); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if received != "hello" {
+			t.Errorf("Handler got %q, want hello", received)
+		}
+	})
+
+	t.Run("Declared length over the limit", func(t *testing.T) {
+		rr := post("/notes", strings.NewReader(big))
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status 413, got %d", rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "payload_too_large") {
+			t.Errorf("Expected a payload_too_large error, got %s", rr.Body.String())
+		}
+		if received != "" {
+			t.Error("Expected the handler not to run")
+		}
+	})
+
+	t.Run("Unknown length over the limit", func(t *testing.T) {
+		// Wrapping the reader hides its length, as with a chunked body
+		rr := post("/notes", io.MultiReader(strings.NewReader(big)))
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Exempt route", func(t *testing.T) {
+		if rr := post("/uploads", strings.NewReader(big)); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", rr.Code)
+		}
+		if received != big {
+			t.Errorf("Handler got %d bytes, want all %d", len(received), len(big))
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCORSWildcardCredentials is returned by NewCORS when a wildcard origin is
+// combined with AllowCredentials, which browsers refuse to honour.
+var ErrCORSWildcardCredentials = errors.New("cors: wildcard origin cannot be used with credentials")
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-CSRF-Token"}
+)
+
+// CORSConfig configures the middleware returned by NewCORS.
+// Empty AllowedMethods and AllowedHeaders fall back to sensible defaults.
+type CORSConfig struct {
+	AllowedOrigins   []string // Exact origins, or "*" to allow any origin
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // How long browsers may cache preflight results
+}
+
+type cors struct {
+	origins     map[string]bool
+	wildcard    bool
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+// NewCORS returns a middleware that applies cfg to cross-origin requests.
+// Allowed origins are echoed back, preflight requests are answered directly
+// and requests from other origins receive no CORS headers.
+func NewCORS(cfg CORSConfig) (func(http.Handler) http.Handler, error) {
+	c := &cors{
+		origins:     make(map[string]bool),
+		methods:     strings.Join(orDefault(cfg.AllowedMethods, defaultCORSMethods), ", "),
+		headers:     strings.Join(orDefault(cfg.AllowedHeaders, defaultCORSHeaders), ", "),
+		credentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimSpace(origin)
+		switch origin {
+		case "":
+		case "*":
+			c.wildcard = true
+		default:
+			c.origins[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+		}
+	}
+	if c.wildcard && c.credentials {
+		return nil, ErrCORSWildcardCredentials
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return c.handler, nil
+}
+
+func (c *cors) allowed(origin string) bool {
+	return c.wildcard || c.origins[strings.ToLower(origin)]
+}
+
+func (c *cors) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if !c.allowed(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.wildcard {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", c.methods)
+			h.Set("Access-Control-Allow-Headers", c.headers)
+			if c.maxAge != "" {
+				h.Set("Access-Control-Max-Age", c.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func orDefault(values, fallback []string) []string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
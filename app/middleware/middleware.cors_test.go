@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cors, err := NewCORS(CORSConfig{
+		AllowedOrigins:   []string{"https://app.tickit.dev"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewCORS returned error: %v", err)
+	}
+	handler := cors(ok)
+
+	t.Run("Preflight from allowed origin", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/projects", nil)
+		req.Header.Set("Origin", "https://app.tickit.dev")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", rr.Code)
+		}
+		want := map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.tickit.dev",
+			"Access-Control-Allow-Methods":     "GET, POST",
+			"Access-Control-Allow-Headers":     "Content-Type",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "600",
+		}
+		for k, v := range want {
+			if got := rr.Header().Get(k); got != v {
+				t.Errorf("Expected %s %q, got %q", k, v, got)
+			}
+		}
+	})
+
+	t.Run("Simple request from allowed origin", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/projects", nil)
+		req.Header.Set("Origin", "https://app.tickit.dev")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://app.tickit.dev" {
+			t.Errorf("Expected origin to be echoed, got %q", got)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "" {
+			t.Errorf("Expected no Allow-Methods outside preflight, got %q", got)
+		}
+	})
+
+	t.Run("Disallowed origin gets no CORS headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/projects", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no Allow-Origin header, got %q", got)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("Expected no Allow-Credentials header, got %q", got)
+		}
+	})
+
+	t.Run("Preflight from disallowed origin is rejected", func(t *testing.T) {
+		req := httptest.NewRequest("OPTIONS", "/projects", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no Allow-Origin header, got %q", got)
+		}
+	})
+
+	t.Run("Wildcard origin", func(t *testing.T) {
+		wildcard, err := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}})
+		if err != nil {
+			t.Fatalf("NewCORS returned error: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/projects", nil)
+		req.Header.Set("Origin", "https://anywhere.example")
+		rr := httptest.NewRecorder()
+		wildcard(ok).ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Expected Allow-Origin *, got %q", got)
+		}
+	})
+
+	t.Run("Wildcard with credentials is rejected", func(t *testing.T) {
+		_, err := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+		if !errors.Is(err, ErrCORSWildcardCredentials) {
+			t.Errorf("Expected ErrCORSWildcardCredentials, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+)
+
+// CSRF cookie and header names. Clients read the cookie and echo its value in
+// the header on state-changing requests.
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// CSRFMiddleware protects cookie-authenticated routes with the double-submit
+// cookie pattern. Safe requests are issued a random token cookie if they lack
+// one; POST, PUT, PATCH and DELETE must send the same value in X-CSRF-Token or
+// are rejected with 403. Requests carrying a bearer token or an API key are
+// exempt, since a cross-site page cannot make the browser attach either
+// header.
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(CSRFCookieName)
+		hasToken := err == nil && cookie.Value != ""
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			if !hasToken {
+				token := router.NewCookie(CSRFCookieName, auth.GenerateSecureToken(32), 0)
+				token.HttpOnly = false // Scripts must read it to send the header
+				http.SetCookie(w, token)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || r.Header.Get(APIKeyHeader) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := r.Header.Get(CSRFHeaderName)
+		if !hasToken || header == "" || subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+			writeError(w, http.StatusForbidden, "invalid_csrf_token", "Missing or invalid CSRF token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	handler := CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A safe request is issued a token
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/projects", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected GET to pass, got %d", rr.Code)
+	}
+	var token *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == CSRFCookieName {
+			token = c
+		}
+	}
+	if token == nil || token.Value == "" {
+		t.Fatal("Expected GET to issue a CSRF cookie")
+	}
+	if token.HttpOnly {
+		t.Error("CSRF cookie must be readable by scripts")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		cookie bool
+		header string
+		bearer bool
+		want   int
+	}{
+		{"GET without token is exempt", "GET", false, "", false, http.StatusOK},
+		{"Matching token passes", "POST", true, token.Value, false, http.StatusOK},
+		{"Matching token passes on DELETE", "DELETE", true, token.Value, false, http.StatusOK},
+		{"Missing header is rejected", "POST", true, "", false, http.StatusForbidden},
+		{"Missing cookie is rejected", "PUT", false, token.Value, false, http.StatusForbidden},
+		{"Mismatched token is rejected", "PATCH", true, "forged", false, http.StatusForbidden},
+		{"Bearer-authenticated request is exempt", "POST", false, "", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/projects", nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token.Value})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer some.jwt.token")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rr.Code)
+			}
+			if tt.want == http.StatusForbidden {
+				if code := errorCode(t, rr); code != "invalid_csrf_token" {
+					t.Errorf("Code = %q, want invalid_csrf_token", code)
+				}
+			}
+		})
+	}
+
+	// A client that already has a token keeps it
+	req := httptest.NewRequest("GET", "/projects", nil)
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token.Value})
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("Expected existing CSRF cookie to be kept")
+	}
+}
@@ -3,43 +3,47 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"runtime/debug"
+
+	"github.com/Bethel-nz/tickit/app/router"
 )
 
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("---> %s %s HTTP/%d.%d\n",
+		log.Printf("---> %s %s HTTP/%d.%d from %s\n",
 			r.Method,
 			r.URL.Path,
 			r.ProtoMajor,
 			r.ProtoMinor,
+			router.ClientIP(r),
 		)
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RecovererMiddleware turns a panicking handler into a 500 JSON response and
+// logs the stack trace, tagged with the X-Request-ID header when present.
+// http.ErrAbortHandler is re-panicked so net/http can abort the response.
 func RecovererMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("panic: %v", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
 			}
-		}()
-		next.ServeHTTP(w, r)
-	})
-}
-
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+			if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
+				log.Printf("panic: %v [request_id=%s]\n%s", err, requestID, debug.Stack())
+			} else {
+				log.Printf("panic: %v\n%s", err, debug.Stack())
+			}
 
+			w.Header().Set("Connection", "close")
+			writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
+		}()
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make retries of a
+// POST safe.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyLockTTL bounds how long an in-flight request holds its key, so a
+// crashed handler cannot block retries forever.
+const idempotencyLockTTL = 30 * time.Second
+
+// idempotentResponse is what gets stored in Redis for a key.
+type idempotentResponse struct {
+	Pending     bool   `json:"pending,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// NewIdempotencyMiddleware creates a middleware that replays the first
+// response for POST requests carrying an Idempotency-Key header.
+// Keys are scoped to the authenticated user, so it must run after AuthMiddleware.
+// A retry that arrives while the first request is still running gets 409, and
+// reusing a key with a different method, path or body gets 422.
+// 5xx responses are not stored so the client can retry them.
+func NewIdempotencyMiddleware(cache *redis.Client, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			if userID == "" {
+				writeError(w, http.StatusUnauthorized, "unauthenticated", "User not authenticated")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			cacheKey := "idempotency:" + userID + ":" + key
+			fingerprint := requestFingerprint(r, body)
+
+			pending, _ := json.Marshal(idempotentResponse{Pending: true, Fingerprint: fingerprint})
+			acquired, err := cache.SetNX(ctx, cacheKey, pending, idempotencyLockTTL).Result()
+			if err != nil {
+				// Without Redis we can't deduplicate; serve the request normally.
+				log.Printf("Idempotency check failed: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !acquired {
+				replayIdempotentResponse(w, cache, r, cacheKey, fingerprint)
+				return
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if p := recover(); p != nil {
+					cache.Del(ctx, cacheKey)
+					panic(p)
+				}
+				if rec.status >= 500 {
+					cache.Del(ctx, cacheKey)
+					return
+				}
+				stored, _ := json.Marshal(idempotentResponse{
+					Fingerprint: fingerprint,
+					Status:      rec.status,
+					ContentType: rec.Header().Get("Content-Type"),
+					Body:        rec.body.Bytes(),
+				})
+				if err := cache.Set(ctx, cacheKey, stored, ttl).Err(); err != nil {
+					log.Printf("Failed to store idempotent response: %v", err)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, cache *redis.Client, r *http.Request, cacheKey, fingerprint string) {
+	data, err := cache.Get(r.Context(), cacheKey).Bytes()
+	if err != nil {
+		writeError(w, http.StatusConflict, "idempotency_key_in_use", "Request with this Idempotency-Key is in progress")
+		return
+	}
+
+	var stored idempotentResponse
+	if err := json.Unmarshal(data, &stored); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
+		return
+	}
+
+	switch {
+	case stored.Fingerprint != fingerprint:
+		writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+	case stored.Pending:
+		writeError(w, http.StatusConflict, "idempotency_key_in_use", "Request with this Idempotency-Key is in progress")
+	default:
+		if stored.ContentType != "" {
+			w.Header().Set("Content-Type", stored.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+	}
+}
+
+// requestFingerprint identifies a request so a key can't be reused for a different payload.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecorder tees the response so it can be stored after the handler returns.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer cache.Close()
+
+	var created int32
+	release := make(chan struct{})
+	close(release)
+	// Served through the router, as the create endpoints are, so the body
+	// the middleware records is the one the handler writes
+	rg := router.NewRouter()
+	rg.POST("/projects/{project_id}/tickets", func(c *router.Context) {
+		<-release
+		n := atomic.AddInt32(&created, 1)
+		c.JSON(http.StatusCreated, map[string]int32{"id": n})
+	}, NewIdempotencyMiddleware(cache, time.Hour))
+	handler := router.ServeMux(rg)
+
+	do := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/projects/1/tickets", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Retry replays the first response", func(t *testing.T) {
+		atomic.StoreInt32(&created, 0)
+		first := do("user-1", "key-a", `{"title":"Bug"}`)
+		second := do("user-1", "key-a", `{"title":"Bug"}`)
+
+		if got := atomic.LoadInt32(&created); got != 1 {
+			t.Errorf("Expected 1 resource to be created, got %d", got)
+		}
+		if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+			t.Errorf("Expected both responses to be 201, got %d and %d", first.Code, second.Code)
+		}
+		if first.Body.String() != `{"id":1}`+"\n" || first.Body.String() != second.Body.String() {
+			t.Errorf("Expected identical bodies, got %q and %q", first.Body.String(), second.Body.String())
+		}
+		if second.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("Expected replayed response to be marked")
+		}
+	})
+
+	t.Run("Keys are scoped per user", func(t *testing.T) {
+		atomic.StoreInt32(&created, 0)
+		do("user-1", "key-b", `{}`)
+		do("user-2", "key-b", `{}`)
+		if got := atomic.LoadInt32(&created); got != 2 {
+			t.Errorf("Expected 2 resources to be created, got %d", got)
+		}
+	})
+
+	t.Run("Reusing a key with a different body is rejected", func(t *testing.T) {
+		do("user-1", "key-c", `{"title":"One"}`)
+		rr := do("user-1", "key-c", `{"title":"Two"}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422, got %d", rr.Code)
+		}
+		if code := errorCode(t, rr); code != "idempotency_key_reused" {
+			t.Errorf("Code = %q, want idempotency_key_reused", code)
+		}
+	})
+
+	t.Run("Concurrent request with the same key gets 409", func(t *testing.T) {
+		release = make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			do("user-1", "key-d", `{}`)
+			close(done)
+		}()
+
+		// Wait until the first request holds the key.
+		for !mr.Exists("idempotency:user-1:key-d") {
+			time.Sleep(time.Millisecond)
+		}
+		rr := do("user-1", "key-d", `{}`)
+		close(release)
+		<-done
+
+		if rr.Code != http.StatusConflict {
+			t.Errorf("Expected status 409, got %d", rr.Code)
+		}
+		if code := errorCode(t, rr); code != "idempotency_key_in_use" {
+			t.Errorf("Code = %q, want idempotency_key_in_use", code)
+		}
+	})
+
+	t.Run("Requests without a key are not deduplicated", func(t *testing.T) {
+		atomic.StoreInt32(&created, 0)
+		do("user-1", "", `{}`)
+		do("user-1", "", `{}`)
+		if got := atomic.LoadInt32(&created); got != 2 {
+			t.Errorf("Expected 2 resources to be created, got %d", got)
+		}
+	})
+}
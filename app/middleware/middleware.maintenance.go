@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+)
+
+// maintenanceKey is the cache key whose presence turns maintenance mode on.
+// Its value is when it turns itself off, if ever. Keeping it in the shared
+// cache lets one request switch every instance at once.
+const maintenanceKey = "maintenance"
+
+// Maintenance puts the API in read-only mode, e.g. during a deploy: while it
+// is on, POST, PUT, PATCH and DELETE requests get 503 Service Unavailable
+// with a Retry-After header, and everything else is served as usual.
+type Maintenance struct {
+	cache      cache.Cache
+	retryAfter time.Duration
+}
+
+// MaintenanceStatus describes whether maintenance mode is on
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Until   *time.Time `json:"until,omitempty"` // When it turns itself off, if ever
+}
+
+// NewMaintenance returns maintenance mode backed by cache. Blocked clients are
+// told to retry after retryAfter.
+func NewMaintenance(c cache.Cache, retryAfter time.Duration) *Maintenance {
+	return &Maintenance{cache: c, retryAfter: retryAfter}
+}
+
+// Enable turns maintenance mode on. It turns itself off after d, so a deploy
+// that dies halfway can't leave the API read-only; 0 keeps it on until Disable.
+func (m *Maintenance) Enable(ctx context.Context, d time.Duration) error {
+	var until string
+	if d > 0 {
+		until = time.Now().Add(d).UTC().Format(time.RFC3339)
+	}
+	return m.cache.Set(ctx, maintenanceKey, until, d)
+}
+
+// Disable turns maintenance mode off
+func (m *Maintenance) Disable(ctx context.Context) error {
+	return m.cache.Del(ctx, maintenanceKey)
+}
+
+// Status reports whether maintenance mode is on, and until when
+func (m *Maintenance) Status(ctx context.Context) (MaintenanceStatus, error) {
+	value, err := m.cache.Get(ctx, maintenanceKey)
+	if errors.Is(err, cache.ErrMiss) {
+		return MaintenanceStatus{}, nil
+	}
+	if err != nil {
+		return MaintenanceStatus{}, err
+	}
+	if value == "" {
+		return MaintenanceStatus{Enabled: true}, nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return MaintenanceStatus{}, err
+	}
+	return MaintenanceStatus{Enabled: true, Until: &until}, nil
+}
+
+// Middleware blocks state-changing requests while maintenance mode is on. If
+// the cache can't be reached the request is let through rather than failing it.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, err := m.cache.Get(r.Context(), maintenanceKey)
+		if errors.Is(err, cache.ErrMiss) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to check maintenance mode: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(m.retryAfter.Seconds())))
+		writeError(w, http.StatusServiceUnavailable, "maintenance", "The API is read-only for maintenance, please try again shortly")
+	})
+}
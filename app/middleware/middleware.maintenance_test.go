@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestMaintenance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	m := NewMaintenance(cache.NewRedis(client), 2*time.Minute)
+	served := 0
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusOK)
+	}))
+	ctx := context.Background()
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	t.Run("Off", func(t *testing.T) {
+		for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE"} {
+			if rr := send(method, "/projects"); rr.Code != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d", method, rr.Code)
+			}
+		}
+	})
+
+	if err := m.Enable(ctx, 0); err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+
+	t.Run("Writes blocked", func(t *testing.T) {
+		for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+			before := served
+			rr := send(method, "/projects")
+
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Errorf("%s: expected status 503, got %d", method, rr.Code)
+			}
+			if got := rr.Header().Get("Retry-After"); got != "120" {
+				t.Errorf("%s: expected Retry-After 120, got %q", method, got)
+			}
+			var body router.ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || body.Error.Code != "maintenance" {
+				t.Errorf("%s: expected the maintenance code, got %+v (%v)", method, body, err)
+			}
+			if served != before {
+				t.Errorf("%s: expected the handler not to run", method)
+			}
+		}
+	})
+
+	t.Run("Reads pass", func(t *testing.T) {
+		for _, tt := range []struct{ method, path string }{
+			{"GET", "/projects"},
+			{"GET", "/health"},
+			{"HEAD", "/projects"},
+			{"OPTIONS", "/projects"},
+		} {
+			if rr := send(tt.method, tt.path); rr.Code != http.StatusOK {
+				t.Errorf("%s %s: expected status 200, got %d", tt.method, tt.path, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		status, err := m.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		if !status.Enabled || status.Until != nil {
+			t.Errorf("Status = %+v, want enabled without an end", status)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		if err := m.Disable(ctx); err != nil {
+			t.Fatalf("Disable failed: %v", err)
+		}
+		if rr := send("POST", "/projects"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+		if status, _ := m.Status(ctx); status.Enabled {
+			t.Errorf("Status = %+v, want disabled", status)
+		}
+	})
+
+	t.Run("Turns itself off", func(t *testing.T) {
+		if err := m.Enable(ctx, 30*time.Minute); err != nil {
+			t.Fatalf("Enable failed: %v", err)
+		}
+		status, err := m.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		if status.Until == nil || time.Until(*status.Until) > 30*time.Minute || time.Until(*status.Until) < 29*time.Minute {
+			t.Errorf("Status = %+v, want it to end in 30 minutes", status)
+		}
+		if rr := send("DELETE", "/projects/1"); rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", rr.Code)
+		}
+
+		mr.FastForward(31 * time.Minute)
+		if rr := send("DELETE", "/projects/1"); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200 once expired, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Redis down", func(t *testing.T) {
+		if err := m.Enable(ctx, 0); err != nil {
+			t.Fatalf("Enable failed: %v", err)
+		}
+		mr.Close()
+		if rr := send("POST", "/projects"); rr.Code != http.StatusOK {
+			t.Errorf("Expected writes to be let through, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "path", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+)
+
+// unmatchedPath labels requests that did not match any registered route,
+// keeping arbitrary 404 URLs out of the label set.
+const unmatchedPath = "unmatched"
+
+// MetricsMiddleware records request counts, latencies and in-flight requests.
+// Requests are labelled with the matched route pattern (/users/{id}) rather
+// than the raw URL to keep label cardinality bounded.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		r, pattern := router.CaptureRoutePattern(r)
+		rec := router.RecordResponse(w)
+		next.ServeHTTP(rec, r)
+
+		path := *pattern
+		if path == "" {
+			path = unmatchedPath
+		}
+
+		status := strconv.Itoa(router.ResponseStatus(rec))
+		httpRequestsTotal.WithLabelValues(r.Method, path, status).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, path).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	rg := router.NewRouter()
+	rg.GET("/users/{id}", func(c *router.Context) {
+		c.Status(http.StatusCreated)
+	})
+	handler := MetricsMiddleware(router.ServeMux(rg))
+
+	t.Run("Labels by route pattern", func(t *testing.T) {
+		counter := httpRequestsTotal.WithLabelValues("GET", "/users/{id}", "201")
+		before := testutil.ToFloat64(counter)
+
+		for _, url := range []string{"/users/1", "/users/2"} {
+			req := httptest.NewRequest("GET", url, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+		}
+
+		if got := testutil.ToFloat64(counter) - before; got != 2 {
+			t.Errorf("counter increased by %v, want 2", got)
+		}
+		if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "/users/1", "201")); got != 0 {
+			t.Errorf("raw URL label should not be recorded, got %v", got)
+		}
+	})
+
+	t.Run("Unmatched routes", func(t *testing.T) {
+		counter := httpRequestsTotal.WithLabelValues("GET", unmatchedPath, "404")
+		before := testutil.ToFloat64(counter)
+
+		req := httptest.NewRequest("GET", "/does/not/exist", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("counter increased by %v, want 1", got)
+		}
+	})
+}
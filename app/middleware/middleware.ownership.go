@@ -1,54 +1,235 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/permissions"
+	"github.com/Bethel-nz/tickit/internal/slug"
+	"github.com/Bethel-nz/tickit/internal/validator"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// NewOwnershipMiddleware creates a middleware that ensures the authenticated user owns the project.
-// This follows the standard middleware pattern used in the router.
-func NewOwnershipMiddleware(queries *store.Queries) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// Errors returned by a ResourceResolver, mapped to HTTP statuses by NewResourceGuard
+var (
+	ErrInvalidResourceID = errors.New("invalid resource ID")
+	ErrResourceNotFound  = errors.New("resource not found")
+	ErrResourceForbidden = errors.New("forbidden")
+)
 
-			projectID := r.PathValue("id")
-			if projectID == "" {
-				http.Error(w, "Missing project ID", http.StatusBadRequest)
-				return
-			}
+// guardError carries the error code and message shown to the client
+// alongside one of the resolver errors above.
+type guardError struct {
+	kind error
+	code string
+	msg  string
+}
+
+func (e *guardError) Error() string { return e.msg }
+func (e *guardError) Unwrap() error { return e.kind }
+
+func guardErr(kind error, code, msg string) error {
+	return &guardError{kind: kind, code: code, msg: msg}
+}
+
+// ResourceResolver looks up the resource addressed by the request and checks
+// that userID may act on it. Path parameters are available via r.PathValue.
+type ResourceResolver func(r *http.Request, userID pgtype.UUID) error
 
-			userID := r.Context().Value("user_id").(string)
-			if userID == "" {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+// NewResourceGuard creates a middleware that runs resolve for the authenticated
+// user and only calls the next handler when it returns nil.
+// It must run after AuthMiddleware.
+func NewResourceGuard(resolve ResourceResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(string)
+			if !ok || userID == "" {
+				writeError(w, http.StatusUnauthorized, "unauthenticated", "User not authenticated")
 				return
 			}
 
 			var scannedUserId pgtype.UUID
 			if err := scannedUserId.Scan(userID); err != nil {
-				http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+				writeError(w, http.StatusBadRequest, "invalid_request", "Invalid user ID format")
 				return
 			}
 
-			var scannedProjectId pgtype.UUID
-			if err := scannedProjectId.Scan(projectID); err != nil {
-				http.Error(w, "Invalid Project ID format", http.StatusBadRequest)
+			if err := resolve(r, scannedUserId); err != nil {
+				// Same bodies as the handlers' responses
+				var status int
+				var code string
+				switch {
+				case errors.Is(err, ErrInvalidResourceID):
+					status, code = http.StatusBadRequest, "invalid_id"
+				case errors.Is(err, ErrResourceNotFound):
+					status, code = http.StatusNotFound, "not_found"
+				case errors.Is(err, ErrResourceForbidden):
+					status, code = http.StatusForbidden, "forbidden"
+				default:
+					log.Printf("Resource guard failed: %v", err)
+					writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
+					return
+				}
+				var guard *guardError
+				if errors.As(err, &guard) && guard.code != "" {
+					code = guard.code
+				}
+				writeError(w, status, code, err.Error())
 				return
 			}
 
-			project, err := queries.GetProjectByID(r.Context(), scannedProjectId)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewOwnershipMiddleware creates a middleware that ensures the authenticated user owns the project.
+// This follows the standard middleware pattern used in the router.
+// The project may be addressed by its ID or its slug; a slug is replaced with
+// the project's ID, so the handler always sees an ID.
+func NewOwnershipMiddleware(queries *store.Queries) func(http.Handler) http.Handler {
+	return NewResourceGuard(func(r *http.Request, userID pgtype.UUID) error {
+		project, err := loadProject(r, queries, "id")
+		if err != nil {
+			return err
+		}
+		if project.OwnerID != userID {
+			return guardErr(ErrResourceForbidden, "forbidden", "Forbidden: you are not the owner of this project")
+		}
+		return nil
+	})
+}
+
+// NewTeamAdminMiddleware creates a middleware that ensures the authenticated
+// user is an owner or admin of the team addressed by {id}. Like
+// NewOwnershipMiddleware it accepts the team's slug in place of its ID.
+func NewTeamAdminMiddleware(queries *store.Queries) func(http.Handler) http.Handler {
+	return NewResourceGuard(func(r *http.Request, userID pgtype.UUID) error {
+		teamID, err := pathRef(r, "id", queries.GetTeamIDBySlug,
+			guardErr(ErrResourceNotFound, "team_not_found", "Team not found"), "Missing team ID", "Invalid team ID")
+		if err != nil {
+			return err
+		}
+
+		role, err := queries.GetTeamMemberRole(r.Context(), store.GetTeamMemberRoleParams{
+			TeamID: teamID,
+			UserID: userID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return guardErr(ErrResourceForbidden, "not_team_member", "Forbidden: you are not a member of this team")
+		}
+		if err != nil {
+			return err
+		}
+		if !permissions.CanManageTeam(role.String) {
+			return guardErr(ErrResourceForbidden, "forbidden", "Forbidden: only team admins can perform this action")
+		}
+		return nil
+	})
+}
+
+// NewIssueAccessMiddleware creates a middleware for ticket routes that ensures
+// the authenticated user can access the project addressed by {project_id},
+// either as its owner or as a member of its team. When the route also has an
+// {id} it must be an issue belonging to that project.
+func NewIssueAccessMiddleware(queries *store.Queries) func(http.Handler) http.Handler {
+	return NewResourceGuard(func(r *http.Request, userID pgtype.UUID) error {
+		project, err := loadProject(r, queries, "project_id")
+		if err != nil {
+			return err
+		}
+
+		if project.OwnerID != userID {
+			if !project.TeamID.Valid {
+				return guardErr(ErrResourceForbidden, "forbidden", "Forbidden: you don't have access to this project")
+			}
+			isMember, err := queries.CheckTeamMembership(r.Context(), store.CheckTeamMembershipParams{
+				TeamID: project.TeamID,
+				UserID: userID,
+			})
 			if err != nil {
-				http.Error(w, "Project not found", http.StatusNotFound)
-				return
+				return err
 			}
-
-			if project.OwnerID != scannedUserId {
-				http.Error(w, "Forbidden: you are not the owner of this project", http.StatusForbidden)
-				return
+			if !isMember {
+				return guardErr(ErrResourceForbidden, "forbidden", "Forbidden: you don't have access to this project")
 			}
+		}
 
-			next.ServeHTTP(w, r)
-		})
+		if r.PathValue("id") == "" {
+			return nil
+		}
+		issueID, err := pathUUID(r, "id", "Missing ticket ID", "Invalid ticket ID")
+		if err != nil {
+			return err
+		}
+		issue, err := queries.GetIssueByID(r.Context(), issueID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && issue.ProjectID != project.ID) {
+			return guardErr(ErrResourceNotFound, "ticket_not_found", "Ticket not found")
+		}
+		return err
+	})
+}
+
+// loadProject fetches the project whose ID or slug is in the named path
+// parameter
+func loadProject(r *http.Request, queries *store.Queries, param string) (*store.Project, error) {
+	projectID, err := pathRef(r, param, queries.GetProjectIDBySlug,
+		guardErr(ErrResourceNotFound, "project_not_found", "Project not found"), "Missing project ID", "Invalid project ID")
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := queries.GetProjectByID(r.Context(), projectID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, guardErr(ErrResourceNotFound, "project_not_found", "Project not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// pathRef reads a path parameter holding either a UUID or a slug. A slug is
+// looked up with bySlug, notFound is returned when nothing has it, and the
+// parameter is replaced with the ID it names for the handlers that follow.
+func pathRef(r *http.Request, param string, bySlug func(context.Context, string) (pgtype.UUID, error), notFound error, missingMsg, invalidMsg string) (pgtype.UUID, error) {
+	ref := r.PathValue(param)
+	if validator.IsUUID(ref) || !slug.Valid(ref) {
+		return pathUUID(r, param, missingMsg, invalidMsg)
+	}
+	id, err := bySlug(r.Context(), ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return id, notFound
+	}
+	if err != nil {
+		return id, err
 	}
+	r.SetPathValue(param, id.String())
+	return id, nil
+}
+
+// pathUUID reads and parses a UUID path parameter
+func pathUUID(r *http.Request, param, missingMsg, invalidMsg string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	value := r.PathValue(param)
+	if value == "" {
+		return id, guardErr(ErrInvalidResourceID, "invalid_id", missingMsg)
+	}
+	if err := id.Scan(value); err != nil {
+		return id, guardErr(ErrInvalidResourceID, "invalid_id", invalidMsg)
+	}
+	return id, nil
+}
+
+// writeError sends a JSON error in the format of router.Context.Error
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(router.ErrorResponse{Error: router.ErrorDetail{Code: code, Message: message}})
 }
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestResourceGuards(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		issue    = "66666666-6666-6666-6666-666666666666"
+		other    = "77777777-7777-7777-7777-777777777777"
+	)
+
+	projectRow := []any{
+		storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), storetest.MustUUID(t, team),
+		pgtype.Text{}, pgtype.Timestamp{}, pgtype.Timestamp{},
+	}
+	issueRow := func(projectID string) []any {
+		return []any{storetest.MustUUID(t, issue), storetest.MustUUID(t, projectID)}
+	}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectByID:" + project:                    projectRow,
+		"GetProjectIDBySlug":                           {storetest.MustUUID(t, project)},
+		"GetTeamIDBySlug":                              {storetest.MustUUID(t, team)},
+		"GetIssueByID:" + issue:                        issueRow(project),
+		"GetIssueByID:" + other:                        issueRow(other),
+		"GetTeamMemberRole:" + team + ":" + owner:      {pgtype.Text{String: "owner", Valid: true}},
+		"GetTeamMemberRole:" + team + ":" + member:     {pgtype.Text{String: "editor", Valid: true}},
+		"CheckTeamMembership:" + team + ":" + member:   {true},
+		"CheckTeamMembership:" + team + ":" + outsider: {false},
+		"CheckTeamMembership:" + team + ":" + owner:    {true},
+	}}
+	queries := store.New(db)
+
+	// Echoes {id} so slugs can be seen to reach the handler as IDs
+	ok := func(c *router.Context) { c.Write([]byte(c.Param("id"))) }
+	rg := router.NewRouter()
+	rg.PUT("/projects/{id}", ok, NewOwnershipMiddleware(queries))
+	rg.PUT("/teams/{id}", ok, NewTeamAdminMiddleware(queries))
+	rg.GET("/projects/{project_id}/tickets", ok, NewIssueAccessMiddleware(queries))
+	rg.GET("/projects/{project_id}/tickets/{id}", ok, NewIssueAccessMiddleware(queries))
+	mux := router.ServeMux(rg)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		want   int
+		code   string
+		id     string
+	}{
+		{"Project owner allowed", "PUT", "/projects/" + project, owner, http.StatusOK, "", ""},
+		{"Project non-owner forbidden", "PUT", "/projects/" + project, member, http.StatusForbidden, "forbidden", ""},
+		{"Project not found", "PUT", "/projects/" + other, owner, http.StatusNotFound, "project_not_found", ""},
+		{"Project invalid ID", "PUT", "/projects/Not_A_UUID", owner, http.StatusBadRequest, "invalid_id", ""},
+		{"Project slug allowed", "PUT", "/projects/tickit", owner, http.StatusOK, "", project},
+		{"Project slug non-owner forbidden", "PUT", "/projects/tickit", member, http.StatusForbidden, "forbidden", ""},
+		{"Missing user is unauthorized", "PUT", "/projects/" + project, "", http.StatusUnauthorized, "unauthenticated", ""},
+
+		{"Team owner allowed", "PUT", "/teams/" + team, owner, http.StatusOK, "", ""},
+		{"Team editor forbidden", "PUT", "/teams/" + team, member, http.StatusForbidden, "forbidden", ""},
+		{"Team non-member forbidden", "PUT", "/teams/" + team, outsider, http.StatusForbidden, "not_team_member", ""},
+
+		{"Tickets owner allowed", "GET", "/projects/" + project + "/tickets", owner, http.StatusOK, "", ""},
+		{"Tickets team member allowed", "GET", "/projects/" + project + "/tickets", member, http.StatusOK, "", ""},
+		{"Tickets outsider forbidden", "GET", "/projects/" + project + "/tickets", outsider, http.StatusForbidden, "forbidden", ""},
+		{"Ticket in project allowed", "GET", "/projects/" + project + "/tickets/" + issue, member, http.StatusOK, "", ""},
+		{"Ticket from another project not found", "GET", "/projects/" + project + "/tickets/" + other, member, http.StatusNotFound, "ticket_not_found", ""},
+		{"Ticket invalid ID", "GET", "/projects/" + project + "/tickets/42", member, http.StatusBadRequest, "invalid_id", ""},
+		{"Team invalid ID", "PUT", "/teams/Not_A_UUID", owner, http.StatusBadRequest, "invalid_id", ""},
+		{"Team slug allowed", "PUT", "/teams/platform", owner, http.StatusOK, "", team},
+		{"Tickets by project slug allowed", "GET", "/projects/tickit/tickets", member, http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tt.userID))
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("Expected status %d, got %d (%s)", tt.want, rr.Code, strings.TrimSpace(rr.Body.String()))
+			}
+			if tt.id != "" && rr.Body.String() != tt.id {
+				t.Errorf("Handler saw id %q, want %q", rr.Body.String(), tt.id)
+			}
+			if tt.code != "" {
+				var body router.ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || body.Error.Code != tt.code {
+					t.Errorf("Error response = %+v, %v, want code %s", body, err, tt.code)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceGuardsUnknownSlug(t *testing.T) {
+	queries := store.New(&storetest.DB{})
+
+	rg := router.NewRouter()
+	rg.PUT("/projects/{id}", func(c *router.Context) {
+		t.Error("Handler should not be called")
+	}, NewOwnershipMiddleware(queries))
+	rg.PUT("/teams/{id}", func(c *router.Context) {
+		t.Error("Handler should not be called")
+	}, NewTeamAdminMiddleware(queries))
+	mux := router.ServeMux(rg)
+
+	for path, code := range map[string]string{
+		"/projects/no-such-project": "project_not_found",
+		"/teams/no-such-team":       "team_not_found",
+	} {
+		req := httptest.NewRequest("PUT", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, "11111111-1111-1111-1111-111111111111"))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		var body router.ErrorResponse
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || rr.Code != http.StatusNotFound || body.Error.Code != code {
+			t.Errorf("%s: got %d %+v, want 404 %s", path, rr.Code, body, code)
+		}
+	}
+}
+
+func TestOwnershipAfterAuth(t *testing.T) {
+	t.Setenv("TICKIT_JWT_KEY", "test-signing-key")
+
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		other   = "22222222-2222-2222-2222-222222222222"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectByID:" + project: {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner)},
+	}}
+	queries := store.New(db)
+
+	rg := router.NewRouter()
+	projects := rg.Group("/projects", AuthMiddleware)
+	projects.PUT("/{id}", func(c *router.Context) {
+		userID, _ := c.Request.Context().Value(UserIDKey).(string)
+		c.Write([]byte(userID))
+	}, NewOwnershipMiddleware(queries))
+	// Misconfigured route: ownership check without authentication in front of it
+	rg.DELETE("/projects/{id}", func(c *router.Context) {
+		t.Error("Handler should not be called")
+	}, NewOwnershipMiddleware(queries))
+	mux := router.ServeMux(rg)
+
+	token := func(userID string) string {
+		tok, err := auth.GenerateToken(userID)
+		if err != nil {
+			t.Fatalf("GenerateToken failed: %v", err)
+		}
+		return "Bearer " + tok
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		auth   string
+		want   int
+	}{
+		{"Owner passes through to handler", "PUT", token(owner), http.StatusOK},
+		{"Other user is forbidden", "PUT", token(other), http.StatusForbidden},
+		{"Missing token is unauthorized", "PUT", "", http.StatusUnauthorized},
+		{"Ownership without auth returns 401", "DELETE", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/projects/"+project, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d (%s)", tt.want, rr.Code, strings.TrimSpace(rr.Body.String()))
+			}
+			if tt.want == http.StatusOK && rr.Body.String() != owner {
+				t.Errorf("Handler saw user ID %q, want %q", rr.Body.String(), owner)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SkipPaths wraps mw so it is bypassed for requests under any of prefixes,
+// e.g. keeping health checks out of the request log:
+//
+//	app.Use(middleware.SkipPaths(middleware.LoggerMiddleware, "/health", "/version"))
+//
+// A prefix matches the path itself and anything below it, so /health does
+// not match /healthz. Unlike Application.ExemptPaths, only mw is skipped.
+func SkipPaths(mw func(http.Handler) http.Handler, prefixes ...string) func(http.Handler) http.Handler {
+	return pathSwitch(mw, normalizePrefixes(prefixes), false)
+}
+
+// OnlyPaths wraps mw so it only runs for requests under one of prefixes,
+// matched as in SkipPaths
+func OnlyPaths(mw func(http.Handler) http.Handler, prefixes ...string) func(http.Handler) http.Handler {
+	return pathSwitch(mw, normalizePrefixes(prefixes), true)
+}
+
+// pathSwitch runs mw for requests whose path is under prefixes exactly when
+// under is true, and passes the rest straight to the next handler
+func pathSwitch(mw func(http.Handler) http.Handler, prefixes []string, under bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasPathPrefix(r.URL.Path, prefixes) == under {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func normalizePrefixes(prefixes []string) []string {
+	normalized := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		normalized[i] = "/" + strings.Trim(prefix, "/")
+	}
+	return normalized
+}
+
+// hasPathPrefix reports whether path is one of prefixes or below one
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathConditions(t *testing.T) {
+	// tag marks the requests it sees, so the handler can tell whether it ran
+	tag := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Tagged", "yes")
+			next.ServeHTTP(w, r)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	skip := SkipPaths(tag, "/health", "internal/")(ok)
+	only := OnlyPaths(tag, "/api/")(ok)
+
+	tests := []struct {
+		path       string
+		skipTagged bool
+		onlyTagged bool
+	}{
+		{"/health", false, false},
+		{"/health/db", false, false},
+		{"/healthz", true, false},
+		{"/internal/metrics", false, false},
+		{"/api", true, true},
+		{"/api/projects", true, true},
+		{"/apis", true, false},
+		{"/", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			for _, c := range []struct {
+				name    string
+				handler http.Handler
+				want    bool
+			}{
+				{"SkipPaths", skip, tt.skipTagged},
+				{"OnlyPaths", only, tt.onlyTagged},
+			} {
+				rr := httptest.NewRecorder()
+				c.handler.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+				if tagged := rr.Header().Get("X-Tagged") == "yes"; tagged != c.want {
+					t.Errorf("%s: middleware ran = %v, want %v", c.name, tagged, c.want)
+				}
+			}
+		})
+	}
+}
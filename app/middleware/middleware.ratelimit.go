@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/usage"
+)
+
+// NewCreationRateMiddleware counts successful creations of kind per user so
+// runaway clients show up in the top-creators report. When limitPerHour is
+// positive, users who have already created that many resources of kind in
+// the current hour get 429 until the hour rolls over.
+// It must run after AuthMiddleware.
+func NewCreationRateMiddleware(tracker *usage.Tracker, kind string, limitPerHour int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			if userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if limitPerHour > 0 {
+				count, err := tracker.CurrentHourCount(r.Context(), kind, userID)
+				if err != nil {
+					log.Printf("Failed to read %s creation count: %v", kind, err)
+				} else if count >= int64(limitPerHour) {
+					log.Printf("Throttling user %s: %d %s creations this hour", userID, count, kind)
+					w.Header().Set("Retry-After", "3600")
+					writeError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests: creation limit reached, try again later")
+					return
+				}
+			}
+
+			rec := router.RecordResponse(w)
+			next.ServeHTTP(rec, r)
+
+			if status := router.ResponseStatus(rec); status >= 200 && status < 300 {
+				if err := tracker.Record(r.Context(), kind, userID); err != nil {
+					log.Printf("Failed to record %s creation: %v", kind, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/usage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCreationRateMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer cache.Close()
+	tracker := usage.NewTracker(cache)
+
+	status := http.StatusCreated
+	handler := NewCreationRateMiddleware(tracker, usage.KindIssue, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	do := func() int {
+		req := httptest.NewRequest("POST", "/projects/1/tickets", nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, "user-1"))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	t.Run("Failed creations are not counted", func(t *testing.T) {
+		status = http.StatusBadRequest
+		do()
+		count, _ := tracker.CurrentHourCount(context.Background(), usage.KindIssue, "user-1")
+		if count != 0 {
+			t.Errorf("Expected 0 recorded creations, got %d", count)
+		}
+	})
+
+	t.Run("Throttles after the hourly limit", func(t *testing.T) {
+		status = http.StatusCreated
+		for i := 0; i < 2; i++ {
+			if code := do(); code != http.StatusCreated {
+				t.Fatalf("Request %d: expected 201, got %d", i+1, code)
+			}
+		}
+		if code := do(); code != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 after limit, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+)
+
+// ReadYourWritesMiddleware keeps a request's reads consistent with its own
+// writes when reads go to a replica. Once a request writes, its later reads
+// go to the primary. Requests that change data read from the primary
+// throughout, since they often check rows before acting on them, e.g. the
+// project a new issue goes in. Safe requests read from the replica until
+// they write.
+func ReadYourWritesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := store.TrackWrites(r.Context())
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			ctx = store.PinPrimary(ctx)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
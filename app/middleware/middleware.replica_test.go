@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestReadYourWritesMiddleware(t *testing.T) {
+	id := pgtype.UUID{Valid: true}
+
+	tests := []struct {
+		name    string
+		method  string
+		write   bool // Whether the handler writes before reading again
+		primary string
+		replica string
+	}{
+		{"Safe request reads from the replica", "GET", false, "", "GetIssueByID,GetIssueByID"},
+		{"Reads after a write use the primary", "GET", true, "UpdateIssueStatus,GetIssueByID", "GetIssueByID"},
+		{"Requests that change data read from the primary", "PATCH", true, "GetIssueByID,UpdateIssueStatus,GetIssueByID", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, replica := &storetest.DB{}, &storetest.DB{}
+			q := store.New(store.NewReplicaRouter(primary, replica))
+			handler := ReadYourWritesMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q.GetIssueByID(r.Context(), id)
+				if tt.write {
+					q.UpdateIssueStatus(r.Context(), store.UpdateIssueStatusParams{ID: id})
+				}
+				q.GetIssueByID(r.Context(), id)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/issues/1", nil))
+
+			if got := strings.Join(primary.Names(), ","); got != tt.primary {
+				t.Errorf("Primary ran %q, want %q", got, tt.primary)
+			}
+			if got := strings.Join(replica.Names(), ","); got != tt.replica {
+				t.Errorf("Replica ran %q, want %q", got, tt.replica)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/internal/auth"
+)
+
+// RequireScope creates a middleware that refuses requests whose principal
+// lacks scope. Login tokens have every scope; API keys only those they were
+// granted, where each scope includes the narrower ones (see auth.Scopes).
+// It must run after AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := r.Context().Value(ScopesKey).([]string)
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "unauthenticated", "Unauthorized: no token provided")
+				return
+			}
+			if !auth.HasScope(granted, scope) {
+				writeError(w, http.StatusForbidden, "insufficient_scope", "This API key needs the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
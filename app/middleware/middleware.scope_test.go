@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+)
+
+func TestRequireScope(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		readKey  = auth.APIKeyPrefix + "read"
+		writeKey = auth.APIKeyPrefix + "write"
+		adminKey = auth.APIKeyPrefix + "admin"
+	)
+	keys := fakeKeys{
+		readKey:  {ID: "k1", UserID: owner, Scopes: []string{auth.ScopeRead}},
+		writeKey: {ID: "k2", UserID: owner, Scopes: []string{auth.ScopeWrite}},
+		adminKey: {ID: "k3", UserID: owner, Scopes: []string{auth.ScopeAdmin}},
+	}
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{Secret: "in-test-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	auth.SetDefaultTokenManager(tokens)
+	token, err := tokens.Generate(owner)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	rg := router.NewRouter()
+	api := rg.Group("/api", NewAuthMiddleware(nil, nil, keys))
+	ok := func(c *router.Context) { c.Status(http.StatusOK, "ok") }
+	api.GET("/tickets", ok)
+	api.POST("/tickets", ok, RequireScope(auth.ScopeWrite))
+	admin := api.Group("/admin", RequireScope(auth.ScopeAdmin))
+	admin.GET("/users", ok)
+	mux := router.ServeMux(rg)
+
+	do := func(method, path, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		bearer string
+		status int
+	}{
+		{"Read key can read", "GET", "/api/tickets", readKey, http.StatusOK},
+		{"Read key can't write", "POST", "/api/tickets", readKey, http.StatusForbidden},
+		{"Write key can write", "POST", "/api/tickets", writeKey, http.StatusOK},
+		{"Write key can't administer", "GET", "/api/admin/users", writeKey, http.StatusForbidden},
+		{"Admin key can write", "POST", "/api/tickets", adminKey, http.StatusOK},
+		{"Admin key can administer", "GET", "/api/admin/users", adminKey, http.StatusOK},
+		{"Login token can write", "POST", "/api/tickets", token, http.StatusOK},
+		{"Login token can administer", "GET", "/api/admin/users", token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := do(tt.method, tt.path, tt.bearer); rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d (%s)", tt.status, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("Insufficient scope names the scope", func(t *testing.T) {
+		rr := do("POST", "/api/tickets", readKey)
+		if !strings.Contains(rr.Body.String(), `"insufficient_scope"`) || !strings.Contains(rr.Body.String(), "write scope") {
+			t.Errorf("Unexpected body: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Unauthenticated requests are refused", func(t *testing.T) {
+		h := RequireScope(auth.ScopeRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("Expected the request to be refused")
+		}))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(opts SecureHeadersOptions, overTLS bool) http.Header {
+		req := httptest.NewRequest("GET", "/projects", nil)
+		if overTLS {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rr := httptest.NewRecorder()
+		SecureHeaders(opts)(ok).ServeHTTP(rr, req)
+		return rr.Header()
+	}
+
+	defaults := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+	}
+
+	t.Run("Defaults over TLS", func(t *testing.T) {
+		h := serve(SecureHeadersOptions{}, true)
+		for k, v := range defaults {
+			if got := h.Get(k); got != v {
+				t.Errorf("Expected %s %q, got %q", k, v, got)
+			}
+		}
+		if got := h.Get("Strict-Transport-Security"); got != "max-age=31536000" {
+			t.Errorf("Expected a year of HSTS, got %q", got)
+		}
+	})
+
+	t.Run("No HSTS over plain HTTP", func(t *testing.T) {
+		h := serve(SecureHeadersOptions{}, false)
+		if got := h.Get("Strict-Transport-Security"); got != "" {
+			t.Errorf("Expected no HSTS, got %q", got)
+		}
+		for k, v := range defaults {
+			if got := h.Get(k); got != v {
+				t.Errorf("Expected %s %q, got %q", k, v, got)
+			}
+		}
+	})
+
+	t.Run("HSTS behind a trusted proxy", func(t *testing.T) {
+		t.Cleanup(func() { router.SetTrustedProxies() })
+		if err := router.SetTrustedProxies("10.0.0.0/8"); err != nil {
+			t.Fatalf("SetTrustedProxies failed: %v", err)
+		}
+		req := httptest.NewRequest("GET", "/projects", nil)
+		req.RemoteAddr = "10.0.0.5:80"
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+		SecureHeaders(SecureHeadersOptions{})(ok).ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Strict-Transport-Security"); got == "" {
+			t.Error("Expected HSTS when the proxy terminated TLS")
+		}
+	})
+
+	t.Run("Custom values", func(t *testing.T) {
+		h := serve(SecureHeadersOptions{
+			HSTSMaxAge:            24 * time.Hour,
+			HSTSIncludeSubdomains: true,
+			FrameOptions:          "SAMEORIGIN",
+			ReferrerPolicy:        "no-referrer",
+			ContentSecurityPolicy: "default-src 'self'",
+		}, true)
+
+		want := map[string]string{
+			"Strict-Transport-Security": "max-age=86400; includeSubDomains",
+			"X-Frame-Options":           "SAMEORIGIN",
+			"Referrer-Policy":           "no-referrer",
+			"Content-Security-Policy":   "default-src 'self'",
+			"X-Content-Type-Options":    "nosniff",
+		}
+		for k, v := range want {
+			if got := h.Get(k); got != v {
+				t.Errorf("Expected %s %q, got %q", k, v, got)
+			}
+		}
+	})
+
+	t.Run("Disabled headers", func(t *testing.T) {
+		h := serve(SecureHeadersOptions{
+			Disable: []string{"content-security-policy", "Strict-Transport-Security"},
+		}, true)
+
+		for _, k := range []string{"Content-Security-Policy", "Strict-Transport-Security"} {
+			if got := h.Get(k); got != "" {
+				t.Errorf("Expected no %s, got %q", k, got)
+			}
+		}
+		if got := h.Get("X-Frame-Options"); got != "DENY" {
+			t.Errorf("Expected other headers to stay, got X-Frame-Options %q", got)
+		}
+	})
+
+	t.Run("Handlers can override", func(t *testing.T) {
+		framed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		})
+		rr := httptest.NewRecorder()
+		SecureHeaders(SecureHeadersOptions{})(framed).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+		if got := rr.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+			t.Errorf("Expected the handler's X-Frame-Options, got %q", got)
+		}
+	})
+}
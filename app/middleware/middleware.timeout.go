@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+// TimeoutMiddleware cancels the request context after d and responds with a
+// 503 JSON error if the handler has not finished by then. Services pass the
+// context down to pgx, so in-flight queries are cancelled too.
+//
+// The handler's output is buffered and only copied to the client if it
+// completes in time; anything it writes after the deadline is discarded.
+//
+// Streaming handlers opt out by flushing (http.Flusher or
+// http.ResponseController): a flush before the deadline sends what has been
+// buffered, lifts the deadline and writes everything after it straight
+// through, as the status can no longer be changed to 503. Requests matching
+// one of the exempt route patterns, e.g. uploads whose bodies take longer
+// than d to read, pass through without a deadline at all.
+func TimeoutMiddleware(d time.Duration, exempt ...string) func(http.Handler) http.Handler {
+	patterns := make([]*router.Pattern, len(exempt))
+	for i, pattern := range exempt {
+		patterns[i] = router.NewPattern(pattern)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, pattern := range patterns {
+				if pattern.Matches(r.URL.Path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, ctx: ctx, header: make(http.Header), deadline: time.AfterFunc(d, cancel)}
+			defer tw.deadline.Stop()
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+			}
+
+			tw.mu.Lock()
+			if tw.streaming {
+				// Streaming lifted the deadline, so only the client going
+				// away ends the response early; the handler sees it and
+				// returns
+				tw.mu.Unlock()
+				select {
+				case p := <-panicChan:
+					panic(p)
+				case <-done:
+				}
+				return
+			}
+			defer tw.mu.Unlock()
+			if ctx.Err() != nil {
+				tw.timedOut = true
+				writeError(w, http.StatusServiceUnavailable, "request_timeout", "Request timed out")
+				return
+			}
+			tw.commit()
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response so it can be dropped if the
+// deadline passes first, until a flush switches it to streaming.
+type timeoutWriter struct {
+	mu        sync.Mutex
+	w         http.ResponseWriter
+	ctx       context.Context // The request's context, cancelled at the deadline
+	header    http.Header
+	buf       bytes.Buffer
+	status    int
+	deadline  *time.Timer // Cancels the request context when it fires
+	timedOut  bool
+	streaming bool // Flushed in time; writes go straight to w
+}
+
+// commit copies the buffered response to w. tw.mu must be held.
+func (tw *timeoutWriter) commit() {
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.status)
+	tw.w.Write(tw.buf.Bytes())
+	tw.buf.Reset()
+}
+
+// Flush sends the response so far and streams the rest without a deadline,
+// unless the deadline has already passed
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.streaming {
+		if tw.ctx.Err() != nil || !tw.deadline.Stop() {
+			return // Too late; the 503 is on its way
+		}
+		tw.streaming = true
+		tw.commit()
+	}
+	http.NewResponseController(tw.w).Flush()
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.streaming {
+		return tw.w.Write(b)
+	}
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
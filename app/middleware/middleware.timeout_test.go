@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	timeout := TimeoutMiddleware(20 * time.Millisecond)
+
+	t.Run("Handler past the deadline gets 503", func(t *testing.T) {
+		finished := make(chan error, 1)
+		handler := timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-r.Context().Done():
+			}
+			_, err := w.Write([]byte("late"))
+			finished <- err
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", ct)
+		}
+		if code := errorCode(t, rr); code != "request_timeout" {
+			t.Errorf("Code = %q, want request_timeout", code)
+		}
+		if err := <-finished; err != http.ErrHandlerTimeout {
+			t.Errorf("Expected late write to fail with ErrHandlerTimeout, got %v", err)
+		}
+		if strings.Contains(rr.Body.String(), "late") {
+			t.Errorf("Late write leaked into response: %q", rr.Body.String())
+		}
+	})
+
+	t.Run("Fast handler response is passed through", func(t *testing.T) {
+		handler := timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "yes")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("Expected status 201, got %d", rr.Code)
+		}
+		if rr.Header().Get("X-Test") != "yes" {
+			t.Error("Expected handler header to be copied")
+		}
+		if rr.Body.String() != "created" {
+			t.Errorf("Expected body %q, got %q", "created", rr.Body.String())
+		}
+	})
+
+	t.Run("Panics propagate to the caller", func(t *testing.T) {
+		handler := timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("Expected panic to propagate, got %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+
+	t.Run("Flushing streams past the deadline", func(t *testing.T) {
+		handler := timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"items":[`))
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("Flush failed: %v", err)
+			}
+			time.Sleep(40 * time.Millisecond)
+			if err := r.Context().Err(); err != nil {
+				t.Errorf("Expected the deadline to be lifted, got %v", err)
+			}
+			w.Write([]byte(`]}`))
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/teams/42/export", nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != `{"items":[]}` || !rr.Flushed {
+			t.Errorf("Expected a flushed 200 with the whole body, got %d %q (flushed %v)", rr.Code, rr.Body.String(), rr.Flushed)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected handler header to be sent, got %q", ct)
+		}
+	})
+
+	t.Run("Flushing after the deadline still gets 503", func(t *testing.T) {
+		handler := timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			http.NewResponseController(w).Flush()
+			w.Write([]byte("late"))
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+		if rr.Code != http.StatusServiceUnavailable || strings.Contains(rr.Body.String(), "late") {
+			t.Errorf("Expected a 503 without the late write, got %d %q", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Exempt routes stream without a deadline", func(t *testing.T) {
+		exempt := TimeoutMiddleware(20*time.Millisecond, "/teams/{id}/export")
+		handler := exempt(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(40 * time.Millisecond)
+			if r.Context().Err() == nil {
+				w.Write([]byte("done"))
+			}
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/teams/42/export", nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != "done" {
+			t.Errorf("Expected 200 done, got %d %q", rr.Code, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/teams/42/members", nil))
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected other routes to time out, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+func TestRecovererMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(RecovererMiddleware(mux))
+	defer srv.Close()
+
+	t.Run("Panic returns JSON 500", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", srv.URL+"/panic", nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON content type, got %q", ct)
+		}
+		if !resp.Close {
+			t.Error("Expected Connection: close")
+		}
+		var body router.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		if body.Error.Code != "internal_error" {
+			t.Errorf("Unexpected body: %+v", body)
+		}
+		if !strings.Contains(logs.String(), "request_id=req-123") || !strings.Contains(logs.String(), "goroutine") {
+			t.Errorf("Expected log with request ID and stack trace, got %q", logs.String())
+		}
+	})
+
+	t.Run("Server keeps serving after panic", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/ok")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrAbortHandler is not swallowed", func(t *testing.T) {
+		handler := RecovererMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("Expected ErrAbortHandler to propagate, got %v", err)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+// errorCode reads the code from a JSON error response
+func errorCode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body router.ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid error body %q: %v", rr.Body.String(), err)
+	}
+	return body.Error.Code
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// Validatable is implemented by request types that can check their own fields.
+// Validate records problems on v rather than returning on the first failure,
+// so clients see every invalid field at once.
+type Validatable interface {
+	Validate(v *validator.Validator)
+}
+
+// ValidationErrorResponse is the 422 body written when validation fails
+type ValidationErrorResponse struct {
+	Errors         map[string]string `json:"errors"`
+	NonFieldErrors []string          `json:"non_field_errors,omitempty"`
+}
+
+// BindJSON decodes the JSON request body into v
+func (c *Context) BindJSON(v interface{}) error {
+	return json.NewDecoder(c.Request.Body).Decode(v)
+}
+
+// BindAndValidate decodes the request body into v, a pointer to a struct,
+// and validates it: first against the validate tags on its fields (see
+// validator.CheckStruct), then with its Validate method if it is Validatable.
+// It responds with 400 for malformed JSON, 413 for a body over the limit set
+// by http.MaxBytesReader, or 422 with all field errors, and returns false;
+// handlers should return immediately in that case.
+func (c *Context) BindAndValidate(v any) bool {
+	if err := c.BindJSON(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.Error(http.StatusRequestEntityTooLarge, "payload_too_large", "Request body is too large")
+			return false
+		}
+		c.Error(http.StatusBadRequest, "invalid_request", "Invalid request format")
+		return false
+	}
+
+	return c.Validate(func(check *validator.Validator) {
+		check.CheckStruct(v)
+		if validatable, ok := v.(Validatable); ok {
+			validatable.Validate(check)
+		}
+	})
+}
+
+// Validate runs check and, if it recorded any errors, responds with 422 and
+// every field error, and returns false. Use it for inputs without a request
+// type of their own, e.g.
+//
+//	if !c.Validate(func(v *validator.Validator) {
+//		v.CheckField(validator.NotBlank(req.Email), "email", "email is required")
+//	}) {
+//		return
+//	}
+func (c *Context) Validate(check func(v *validator.Validator)) bool {
+	var v validator.Validator
+	check(&v)
+	if !v.Valid() {
+		c.validationFailed(&v)
+		return false
+	}
+	return true
+}
+
+// validationFailed writes the 422 response for a failed validator
+func (c *Context) validationFailed(v *validator.Validator) {
+	errs := v.FieldErrors
+	if errs == nil {
+		errs = map[string]string{}
+	}
+	c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+		Errors:         errs,
+		NonFieldErrors: v.NonFieldErrors,
+	})
+}
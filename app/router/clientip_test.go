@@ -0,0 +1,96 @@
+package router
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Cleanup(func() { SetTrustedProxies() })
+
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"No proxies configured ignores headers", nil, "203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"Untrusted peer cannot spoof X-Forwarded-For", []string{"10.0.0.0/8"}, "203.0.113.7:5000", "198.51.100.1", "", "203.0.113.7"},
+		{"Trusted proxy forwards client", []string{"10.0.0.0/8"}, "10.0.0.5:443", "198.51.100.1", "", "198.51.100.1"},
+		{"Spoofed leading hop is skipped", []string{"10.0.0.0/8"}, "10.0.0.5:443", "1.2.3.4, 198.51.100.1", "", "198.51.100.1"},
+		{"Chain of trusted proxies", []string{"10.0.0.0/8"}, "10.0.0.5:443", "198.51.100.1, 10.0.0.9", "", "198.51.100.1"},
+		{"X-Real-IP from trusted proxy", []string{"10.0.0.5"}, "10.0.0.5:443", "", "198.51.100.2", "198.51.100.2"},
+		{"Malformed header falls back to peer", []string{"10.0.0.0/8"}, "10.0.0.5:443", "not-an-ip", "", "10.0.0.5"},
+		{"IPv6 peer", []string{"fd00::/8"}, "[fd00::1]:443", "2001:db8::7", "", "2001:db8::7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTrustedProxies(tt.trusted...); err != nil {
+				t.Fatalf("SetTrustedProxies failed: %v", err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			c := &Context{Request: req}
+			if got := c.ClientIP(); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTrustedProxiesRejectsInvalid(t *testing.T) {
+	t.Cleanup(func() { SetTrustedProxies() })
+	if err := SetTrustedProxies("10.0.0.0/8", "not-a-cidr"); err == nil {
+		t.Error("Expected error for invalid proxy")
+	}
+}
+
+func TestIsHTTPS(t *testing.T) {
+	t.Cleanup(func() { SetTrustedProxies() })
+	if err := SetTrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		tls        bool
+		remoteAddr string
+		proto      string
+		want       bool
+	}{
+		{"Plain HTTP", false, "203.0.113.7:5000", "", false},
+		{"Direct TLS", true, "203.0.113.7:5000", "", true},
+		{"Trusted proxy terminating TLS", false, "10.0.0.5:80", "https", true},
+		{"Trusted proxy over HTTP", false, "10.0.0.5:80", "http", false},
+		{"Chain of proxies", false, "10.0.0.5:80", "HTTPS, http", true},
+		{"Untrusted peer cannot claim HTTPS", false, "203.0.113.7:5000", "https", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			if got := IsHTTPS(req); got != tt.want {
+				t.Errorf("IsHTTPS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewCookie creates a cookie with secure defaults: sent on every path, over
+// HTTPS only, hidden from JavaScript and withheld from cross-site subrequests.
+// A zero maxAge makes it a session cookie. Callers may relax the returned
+// cookie, e.g. clear HttpOnly for values scripts must read.
+func NewCookie(name, value string, maxAge time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Cookie returns the named cookie sent with the request, or
+// http.ErrNoCookie if there isn't one
+func (c *Context) Cookie(name string) (*http.Cookie, error) {
+	return c.Request.Cookie(name)
+}
+
+// SetCookie adds a Set-Cookie header to the response. Path defaults to / and
+// SameSite to Lax when unset; use NewCookie for the other secure defaults.
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(c, cookie)
+}
+
+// DeleteCookie tells the client to remove the named cookie
+func (c *Context) DeleteCookie(name string) {
+	c.SetCookie(&http.Cookie{Name: name, Value: "", MaxAge: -1, Expires: time.Unix(0, 0)})
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieRoundTrip(t *testing.T) {
+	rg := NewRouter()
+	rg.POST("/session", func(c *Context) {
+		c.SetCookie(NewCookie("session_hint", "abc123", time.Hour))
+		c.Status(http.StatusNoContent)
+	})
+	rg.GET("/session", func(c *Context) {
+		cookie, err := c.Cookie("session_hint")
+		if err != nil {
+			c.Status(http.StatusNotFound, "no cookie")
+			return
+		}
+		c.Write([]byte(cookie.Value))
+	})
+	rg.DELETE("/session", func(c *Context) {
+		c.DeleteCookie("session_hint")
+	})
+	mux := ServeMux(rg)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("POST", "/session", nil))
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected 1 cookie, got %d", len(cookies))
+	}
+	set := cookies[0]
+	if !set.HttpOnly || !set.Secure || set.SameSite != http.SameSiteLaxMode || set.Path != "/" || set.MaxAge != 3600 {
+		t.Errorf("Cookie missing secure defaults: %s", rr.Header().Get("Set-Cookie"))
+	}
+
+	req := httptest.NewRequest("GET", "/session", nil)
+	req.AddCookie(set)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "abc123" {
+		t.Errorf("Expected cookie value abc123, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/session", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without cookie, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("DELETE", "/session", nil))
+	if header := rr.Header().Get("Set-Cookie"); !strings.Contains(header, "Max-Age=0") {
+		t.Errorf("Expected cookie deletion, got %q", header)
+	}
+}
+
+func TestSetCookieDefaults(t *testing.T) {
+	rr := httptest.NewRecorder()
+	c := &Context{ResponseWriter: rr, Request: httptest.NewRequest("GET", "/", nil)}
+	c.SetCookie(&http.Cookie{Name: "plain", Value: "1"})
+
+	header := rr.Header().Get("Set-Cookie")
+	if !strings.Contains(header, "Path=/") || !strings.Contains(header, "SameSite=Lax") {
+		t.Errorf("Expected Path and SameSite defaults, got %q", header)
+	}
+}
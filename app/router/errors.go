@@ -0,0 +1,21 @@
+package router
+
+// ErrorResponse is the body written by Context.Error
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes a failed request. Code is a stable identifier clients
+// can match on, such as "project_not_found"; Message is meant for people and
+// may change.
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error sends a JSON error response:
+//
+//	{"error": {"code": "project_not_found", "message": "Project not found"}}
+func (c *Context) Error(status int, code, message string) {
+	c.JSON(status, ErrorResponse{Error: ErrorDetail{Code: code, Message: message}})
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+type thingRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *thingRequest) Validate(v *validator.Validator) {}
+
+func TestContextError(t *testing.T) {
+	rg := NewRouter()
+	rg.GET("/things/{id}", func(c *Context) {
+		c.Error(http.StatusNotFound, "thing_not_found", "Thing not found")
+	})
+	rg.POST("/things", func(c *Context) {
+		var body thingRequest
+		c.BindAndValidate(&body)
+	})
+	mux := ServeMux(rg)
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		status   int
+		wantCode string
+	}{
+		{"Handler error", httptest.NewRequest("GET", "/things/42", nil), http.StatusNotFound, "thing_not_found"},
+		{"Malformed body", httptest.NewRequest("POST", "/things", strings.NewReader("{")), http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, tt.req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Invalid error body %q: %v", rr.Body.String(), err)
+			}
+			if body.Error.Code != tt.wantCode || body.Error.Message == "" {
+				t.Errorf("Error = %+v, want code %q with a message", body.Error, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// NewETag returns a strong entity tag derived from parts, typically a
+// resource's ID and last modification time
+func NewETag(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// SetETag sets the ETag response header
+func (c *Context) SetETag(etag string) {
+	c.Header().Set("ETag", etag)
+}
+
+// CheckNotModified sets the ETag header and, if the request's If-None-Match
+// already names etag, responds 304 Not Modified. Handlers should return
+// without writing a body when it reports true.
+func (c *Context) CheckNotModified(etag string) bool {
+	c.SetETag(etag)
+
+	ifNoneMatch := c.Request.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !etagListContains(ifNoneMatch, etag) {
+		return false
+	}
+	c.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListContains reports whether a comma-separated If-None-Match value
+// matches etag, using the weak comparison RFC 9110 prescribes for GET
+func etagListContains(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	version := "1"
+	rg := NewRouter()
+	rg.GET("/things/{id}", func(c *Context) {
+		if c.CheckNotModified(NewETag(c.Param("id"), version)) {
+			return
+		}
+		c.JSON(http.StatusOK, map[string]string{"id": c.Param("id"), "version": version})
+	})
+	mux := ServeMux(rg)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/things/42", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d %q", rr.Code, etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"Current ETag", etag, http.StatusNotModified},
+		{"Weak form of current ETag", "W/" + etag, http.StatusNotModified},
+		{"ETag in a list", `"stale", ` + etag, http.StatusNotModified},
+		{"Wildcard", "*", http.StatusNotModified},
+		{"Stale ETag", `"stale"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(tt.ifNoneMatch)
+			if rr.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, rr.Code)
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("Expected empty 304 body, got %q", rr.Body.String())
+			}
+			if rr.Header().Get("ETag") != etag {
+				t.Errorf("Expected ETag %s, got %s", etag, rr.Header().Get("ETag"))
+			}
+		})
+	}
+
+	t.Run("Modification changes the ETag", func(t *testing.T) {
+		version = "2"
+		rr := get(etag)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200 after modification, got %d", rr.Code)
+		}
+		if rr.Header().Get("ETag") == etag {
+			t.Error("Expected a new ETag after modification")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ListResponse is the envelope every list endpoint responds with
+type ListResponse struct {
+	Data interface{} `json:"data"`
+	Meta ListMeta    `json:"meta"`
+}
+
+// ListMeta describes the items of a list response
+type ListMeta struct {
+	Count int       `json:"count"`           // Items in data; set by List
+	Page  *ListPage `json:"page,omitempty"`  // Set for cursor-paginated lists
+	Query string    `json:"query,omitempty"` // The search the items answer
+}
+
+// ListPage links a page of a cursor-paginated list to the next. NextCursor
+// is null on the last page.
+type ListPage struct {
+	NextCursor *string `json:"next_cursor"`
+}
+
+// CursorMeta returns the meta of a page of a cursor-paginated list, given
+// the cursor of the following page, or "" on the last page
+func CursorMeta(next string) ListMeta {
+	page := &ListPage{}
+	if next != "" {
+		page.NextCursor = &next
+	}
+	return ListMeta{Page: page}
+}
+
+// List responds with 200 and data, a slice, in the ListResponse envelope.
+// meta.Count is set from data, and a nil slice is written as [] rather than
+// null.
+func (c *Context) List(data interface{}, meta ListMeta) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("router: List needs a slice, got %T", data))
+	}
+	if v.IsNil() {
+		data = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+	meta.Count = v.Len()
+	c.JSON(http.StatusOK, ListResponse{Data: data, Meta: meta})
+}
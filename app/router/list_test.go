@@ -0,0 +1,52 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	type thing struct {
+		ID string `json:"id"`
+	}
+	tests := []struct {
+		name string
+		list func(c *Context)
+		want string
+	}{
+		{
+			"Items",
+			func(c *Context) { c.List([]thing{{"a"}, {"b"}}, ListMeta{}) },
+			`{"data":[{"id":"a"},{"id":"b"}],"meta":{"count":2}}`,
+		},
+		{
+			"Nil is empty",
+			func(c *Context) { c.List([]thing(nil), ListMeta{Query: "x"}) },
+			`{"data":[],"meta":{"count":0,"query":"x"}}`,
+		},
+		{
+			"Next page",
+			func(c *Context) { c.List([]thing{{"a"}}, CursorMeta("abc")) },
+			`{"data":[{"id":"a"}],"meta":{"count":1,"page":{"next_cursor":"abc"}}}`,
+		},
+		{
+			"Last page",
+			func(c *Context) { c.List([]thing{{"a"}}, CursorMeta("")) },
+			`{"data":[{"id":"a"}],"meta":{"count":1,"page":{"next_cursor":null}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.list(&Context{ResponseWriter: rr, Request: httptest.NewRequest("GET", "/things", nil)})
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rr.Code)
+			}
+			if got := strings.TrimSpace(rr.Body.String()); got != tt.want {
+				t.Errorf("Body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+type skipKey struct{}
+
+// Named labels middleware so individual routes can opt out of it with Skip,
+// e.g. a public route in a group that otherwise requires authentication:
+//
+//	requireAuth := router.Named("auth", middleware.NewAuthMiddleware(denylist, accounts, keys))
+//	api := r.Group("/api", requireAuth)
+//	api.GET("/status", Status).Skip("auth")
+//
+// Skipped middleware is bypassed entirely; the rest of the chain runs in its
+// usual order.
+func Named(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipped, _ := r.Context().Value(skipKey{}).(map[string]bool); skipped[name] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Skip bypasses the named middleware for the route most recently registered
+// on rg, including middleware inherited from its groups
+func (rg *RouterGroup) Skip(names ...string) *RouterGroup {
+	if len(rg.routes) == 0 {
+		panic("router: Skip called before any route was registered")
+	}
+	route := &rg.routes[len(rg.routes)-1]
+	skip := make(map[string]bool, len(route.skip)+len(names))
+	for name := range route.skip {
+		skip[name] = true
+	}
+	for _, name := range names {
+		skip[name] = true
+	}
+	route.skip = skip
+	return rg
+}
+
+// withSkips records the middleware the matched route skips on r
+func withSkips(r *http.Request, route *Route) *http.Request {
+	if len(route.skip) == 0 {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), skipKey{}, route.skip))
+}
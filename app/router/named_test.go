@@ -0,0 +1,97 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// requireToken rejects requests without an Authorization header
+func requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestSkipNamedMiddleware(t *testing.T) {
+	ok := func(c *Context) { c.WriteHeader(http.StatusOK) }
+
+	rg := NewRouter()
+	api := rg.Group("/api", Named("auth", requireToken))
+	api.GET("/status", ok).Skip("auth")
+	api.GET("/projects", ok)
+	api.Group("/teams").GET("/{id}", ok)
+	mux := ServeMux(rg)
+
+	tests := []struct {
+		url    string
+		token  bool
+		status int
+	}{
+		{"/api/status", false, http.StatusOK},
+		{"/api/projects", false, http.StatusUnauthorized},
+		{"/api/teams/42", false, http.StatusUnauthorized},
+		{"/api/projects", true, http.StatusOK},
+		{"/api/teams/42", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.token {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != tt.status {
+			t.Errorf("GET %s (token %v): got %v want %v", tt.url, tt.token, rr.Code, tt.status)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	record := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	rg := NewRouter()
+	api := rg.Group("/api", record("group"), Named("audit", record("audit")))
+	nested := api.Group("/teams", record("nested"))
+	nested.GET("/{id}", func(c *Context) { order = append(order, "handler") }, record("route"))
+	nested.GET("/{id}/members", func(c *Context) { order = append(order, "handler") }, record("route")).
+		Skip("audit")
+	mux := ServeMux(rg)
+
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{"/api/teams/1", []string{"group", "audit", "nested", "route", "handler"}},
+		{"/api/teams/1/members", []string{"group", "nested", "route", "handler"}},
+	}
+	for _, tt := range tests {
+		order = nil
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.url, nil))
+		if !reflect.DeepEqual(order, tt.expected) {
+			t.Errorf("GET %s: got %v want %v", tt.url, order, tt.expected)
+		}
+	}
+}
+
+func TestSkipWithoutRoute(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Skip on an empty group did not panic")
+		}
+	}()
+	NewRouter().Skip("auth")
+}
@@ -0,0 +1,269 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteDoc describes a route in the generated OpenAPI document. Everything is
+// optional; undocumented routes still appear with their method, path and path
+// parameters.
+type RouteDoc struct {
+	Summary  string
+	Auth     bool        // Requires a bearer token
+	Query    []string    // Query parameters the route reads
+	Request  interface{} // Value whose type is the JSON request body, e.g. CreateThingRequest{}
+	Response interface{} // Value whose type is the JSON success body
+	Status   int         // Success status, 200 if unset
+}
+
+// Describe documents the route most recently registered on rg, e.g.
+//
+//	rg.POST("/things", CreateThing).Describe(router.RouteDoc{Summary: "Create a thing"})
+func (rg *RouterGroup) Describe(doc RouteDoc) *RouterGroup {
+	if len(rg.routes) == 0 {
+		panic("router: Describe called before any route was registered")
+	}
+	rg.routes[len(rg.routes)-1].Doc = &doc
+	return rg
+}
+
+// OpenAPIInfo is the info object of an OpenAPI document
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIDocument is an OpenAPI 3 description of a router's routes
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components,omitempty"`
+}
+
+// Operation describes one method on a path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response for one status code
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Schema is a JSON schema
+type Schema map[string]interface{}
+
+// Components holds the document's security schemes
+type Components struct {
+	SecuritySchemes map[string]Schema `json:"securitySchemes,omitempty"`
+}
+
+// bearerAuth names the security scheme used by routes with RouteDoc.Auth
+const bearerAuth = "bearerAuth"
+
+// OpenAPI generates an OpenAPI 3 document for the routes in rg
+func OpenAPI(rg *RouterGroup, info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+	}
+
+	for _, route := range rg.Build() {
+		// Matching ignores trailing slashes, so "/teams/" is documented as
+		// "/teams". OpenAPI has no catchall syntax; {path...} becomes {path}.
+		path := strings.ReplaceAll("/"+strings.Join(route.Pattern.segments, "/"), "...}", "}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		method := strings.ToLower(route.Method)
+		if _, ok := doc.Paths[path][method]; ok {
+			continue // Duplicate registrations are documented once
+		}
+
+		op := &Operation{Responses: map[string]Response{}}
+		if tag := firstLiteral(route.Pattern); tag != "" {
+			op.Tags = []string{tag}
+		}
+		for _, name := range route.paramNames {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: Schema{"type": "string"},
+			})
+		}
+
+		status := http.StatusOK
+		success := Response{Description: http.StatusText(status)}
+		if d := route.Doc; d != nil {
+			op.Summary = d.Summary
+			for _, name := range d.Query {
+				op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: Schema{"type": "string"}})
+			}
+			if d.Request != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content:  map[string]MediaType{"application/json": {Schema: schemaOf(reflect.TypeOf(d.Request))}},
+				}
+			}
+			if d.Status != 0 {
+				status = d.Status
+				success.Description = http.StatusText(status)
+			}
+			if d.Response != nil {
+				success.Content = map[string]MediaType{"application/json": {Schema: responseSchema(reflect.TypeOf(d.Response))}}
+			}
+			if d.Auth {
+				op.Security = []map[string][]string{{bearerAuth: {}}}
+				doc.Components = &Components{SecuritySchemes: map[string]Schema{
+					bearerAuth: {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				}}
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = success
+
+		doc.Paths[path][method] = op
+	}
+	return doc
+}
+
+// OpenAPIHandler serves the OpenAPI document for rg. The document is built on
+// the first request, so it includes routes registered after this call.
+func OpenAPIHandler(rg *RouterGroup, info OpenAPIInfo) func(*Context) {
+	var (
+		once sync.Once
+		doc  *OpenAPIDocument
+	)
+	return func(c *Context) {
+		once.Do(func() { doc = OpenAPI(rg, info) })
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// firstLiteral returns the first non-parameter segment of p, used to group
+// operations by resource
+func firstLiteral(p *Pattern) string {
+	for _, seg := range p.segments {
+		if !strings.HasPrefix(seg, "{") {
+			return seg
+		}
+	}
+	return ""
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// responseSchema describes a success body of type t. Lists are sent in the
+// ListResponse envelope, so a slice is described as the envelope's data.
+func responseSchema(t reflect.Type) Schema {
+	schema := schemaOf(t)
+	if schema["type"] != "array" {
+		return schema
+	}
+	envelope := schemaOf(reflect.TypeOf(ListResponse{}))
+	envelope["properties"].(Schema)["data"] = schema
+	return envelope
+}
+
+// schemaOf derives a JSON schema from a Go type using its json tags. Types
+// with custom JSON encodings are described as any value.
+func schemaOf(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "format": "byte"}
+		}
+		return Schema{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := Schema{}
+		addProperties(t, properties)
+		return Schema{"type": "object", "properties": properties}
+	default:
+		return Schema{}
+	}
+}
+
+// addProperties adds the JSON fields of struct type t, including those of
+// embedded structs, to properties
+func addProperties(t reflect.Type, properties Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addProperties(ft, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type)
+	}
+}
@@ -0,0 +1,140 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type thingResponse struct {
+	ID        string    `json:"id"`
+	Count     int       `json:"count"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	internal  string
+}
+
+func TestOpenAPI(t *testing.T) {
+	noop := func(c *Context) {}
+
+	rg := NewRouter()
+	rg.GET("/health", noop)
+	api := rg.Group("/api")
+	things := api.Group("/projects/{project_id}/things")
+	things.GET("", noop).
+		Describe(RouteDoc{Summary: "List things", Auth: true, Query: []string{"limit"}, Response: []thingResponse{}})
+	things.POST("", noop).
+		Describe(RouteDoc{Summary: "Create a thing", Auth: true, Request: thingRequest{}, Response: thingResponse{}, Status: http.StatusCreated})
+	things.GET("/{id}", noop)
+	things.DELETE("/{id}", noop)
+	rg.GET("/openapi.json", OpenAPIHandler(rg, OpenAPIInfo{Title: "Test", Version: "1"}))
+
+	rr := httptest.NewRecorder()
+	ServeMux(rg).ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	var doc OpenAPIDocument
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+
+	t.Run("Lists every route", func(t *testing.T) {
+		want := map[string][]string{
+			"/health":                                {"get"},
+			"/openapi.json":                          {"get"},
+			"/api/projects/{project_id}/things":      {"get", "post"},
+			"/api/projects/{project_id}/things/{id}": {"get", "delete"},
+		}
+		if len(doc.Paths) != len(want) {
+			t.Errorf("got %d paths, want %d: %v", len(doc.Paths), len(want), doc.Paths)
+		}
+		for path, methods := range want {
+			for _, method := range methods {
+				if doc.Paths[path][method] == nil {
+					t.Errorf("missing %s %s", method, path)
+				}
+			}
+		}
+	})
+
+	t.Run("Path parameters", func(t *testing.T) {
+		op := doc.Paths["/api/projects/{project_id}/things/{id}"]["delete"]
+		if op == nil {
+			t.Fatal("missing operation")
+		}
+		var names []string
+		for _, p := range op.Parameters {
+			if p.In != "path" || !p.Required {
+				t.Errorf("parameter %s: in=%s required=%v", p.Name, p.In, p.Required)
+			}
+			names = append(names, p.Name)
+		}
+		if len(names) != 2 || names[0] != "project_id" || names[1] != "id" {
+			t.Errorf("parameters = %v, want [project_id id]", names)
+		}
+		if len(op.Tags) != 1 || op.Tags[0] != "api" {
+			t.Errorf("tags = %v, want [api]", op.Tags)
+		}
+	})
+
+	t.Run("Descriptions", func(t *testing.T) {
+		list := doc.Paths["/api/projects/{project_id}/things"]["get"]
+		if list.Summary != "List things" {
+			t.Errorf("summary = %q", list.Summary)
+		}
+		if last := list.Parameters[len(list.Parameters)-1]; last.Name != "limit" || last.In != "query" {
+			t.Errorf("query parameter = %+v, want limit", last)
+		}
+		if len(list.Security) != 1 || doc.Components == nil || doc.Components.SecuritySchemes[bearerAuth] == nil {
+			t.Error("authenticated route has no bearer security")
+		}
+
+		create := doc.Paths["/api/projects/{project_id}/things"]["post"]
+		if create.RequestBody == nil {
+			t.Fatal("missing request body")
+		}
+		props, _ := create.RequestBody.Content["application/json"].Schema["properties"].(map[string]interface{})
+		if _, ok := props["name"]; !ok {
+			t.Errorf("request schema = %v, want a name property", create.RequestBody.Content["application/json"].Schema)
+		}
+
+		created, ok := create.Responses["201"]
+		if !ok {
+			t.Fatalf("responses = %v, want 201", create.Responses)
+		}
+		props, _ = created.Content["application/json"].Schema["properties"].(map[string]interface{})
+		for _, name := range []string{"id", "count", "tags", "created_at"} {
+			if _, ok := props[name]; !ok {
+				t.Errorf("response schema is missing %s", name)
+			}
+		}
+		if _, ok := props["internal"]; ok {
+			t.Error("response schema includes an unexported field")
+		}
+
+		listed := doc.Paths["/api/projects/{project_id}/things"]["get"].Responses["200"].Content["application/json"].Schema
+		props, _ = listed["properties"].(map[string]interface{})
+		data, _ := props["data"].(map[string]interface{})
+		if _, ok := props["meta"]; !ok || data["type"] != "array" {
+			t.Errorf("list schema = %v, want the data and meta envelope", listed)
+		}
+
+		health := doc.Paths["/health"]["get"]
+		if _, ok := health.Responses["200"]; !ok || health.Security != nil {
+			t.Errorf("undocumented route = %+v, want a public 200", health)
+		}
+	})
+}
+
+func TestDescribeWithoutRoute(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Describe on an empty group did not panic")
+		}
+	}()
+	NewRouter().Describe(RouteDoc{Summary: "Nothing"})
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseRecorder wraps http.ResponseWriter and records the status code and
+// number of body bytes written so they can be inspected after the handler runs
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// newResponseRecorder wraps w, reusing it if it is already a recorder
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec
+	}
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader records the status code and forwards it to the underlying writer
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = code
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written and forwards them to the underlying writer
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer supports it
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if !r.wroteHeader {
+			r.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker so WebSocket upgrades keep working
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer for use with http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// RecordResponse wraps w so the final status code and byte count can be read
+// back with ResponseStatus and ResponseBytes. ServeMux reuses the wrapper when
+// it is handed one, so global middleware and handlers share a single recorder.
+func RecordResponse(w http.ResponseWriter) http.ResponseWriter {
+	return newResponseRecorder(w)
+}
+
+// ResponseStatus returns the status code recorded on a writer returned by RecordResponse
+func ResponseStatus(w http.ResponseWriter) int {
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec.status
+	}
+	return http.StatusOK
+}
+
+// ResponseBytes returns the byte count recorded on a writer returned by RecordResponse
+func ResponseBytes(w http.ResponseWriter) int {
+	if rec, ok := w.(*responseRecorder); ok {
+		return rec.bytes
+	}
+	return 0
+}
+
+// StatusCode returns the status code written by the handler.
+// It defaults to 200 when the handler never called WriteHeader.
+func (c *Context) StatusCode() int {
+	return ResponseStatus(c.ResponseWriter)
+}
+
+// BytesWritten returns the number of response body bytes written by the handler
+func (c *Context) BytesWritten() int {
+	return ResponseBytes(c.ResponseWriter)
+}
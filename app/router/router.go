@@ -1,7 +1,10 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sort"
@@ -21,6 +24,11 @@ func (c *Context) Param(key string) string {
 	return c.Params[key]
 }
 
+// Pattern returns the route pattern that matched the request, e.g. /users/{id}
+func (c *Context) Pattern() string {
+	return c.path
+}
+
 // Query returns a query parameter by key
 func (c *Context) Query(key string) string {
 	return c.Request.URL.Query().Get(key)
@@ -47,31 +55,69 @@ func (c *Context) Status(code int, message ...string) {
 	}
 }
 
-// Pattern represents a route pattern split into segments
+// Pattern represents a route pattern split into segments. A segment such as
+// {id} matches exactly one path segment; a final {path...} is a catchall that
+// matches one or more segments, slashes included.
 type Pattern struct {
 	segments []string
 }
 
-// NewPattern creates a Pattern from a path string
+// NewPattern creates a Pattern from a path string. It panics if a catchall is
+// not the last segment.
 func NewPattern(path string) *Pattern {
 	segments := strings.Split(strings.Trim(path, "/"), "/")
 	if len(segments) == 1 && segments[0] == "" {
 		segments = []string{}
 	}
+	for i, seg := range segments {
+		if isCatchall(seg) && i != len(segments)-1 {
+			panic("router: catchall " + seg + " must be the last segment of " + path)
+		}
+	}
 	return &Pattern{segments: segments}
 }
 
+// isParam reports whether seg is a parameter, either {name} or {name...}
+func isParam(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+// isCatchall reports whether seg is a catchall parameter such as {path...}
+func isCatchall(seg string) bool {
+	return isParam(seg) && strings.HasSuffix(seg, "...}")
+}
+
 // ParamNames extracts parameter names from the pattern
 func (p *Pattern) ParamNames() []string {
 	var names []string
 	for _, seg := range p.segments {
-		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
-			names = append(names, strings.Trim(seg, "{}"))
+		if isParam(seg) {
+			names = append(names, strings.TrimSuffix(strings.Trim(seg, "{}"), "..."))
 		}
 	}
 	return names
 }
 
+// Matches reports whether path matches the pattern on its own, without
+// considering other routes
+func (p *Pattern) Matches(path string) bool {
+	var segments []string
+	if trimmed := strings.Trim(path, "/"); trimmed != "" {
+		segments = strings.Split(trimmed, "/")
+	}
+	for i, seg := range p.segments {
+		switch {
+		case isCatchall(seg):
+			return len(segments) > i
+		case i >= len(segments):
+			return false
+		case !isParam(seg) && seg != segments[i]:
+			return false
+		}
+	}
+	return len(segments) == len(p.segments)
+}
+
 // LiteralCount returns the number of non-parameter segments for sorting precedence
 func (p *Pattern) LiteralCount() int {
 	count := 0
@@ -90,7 +136,9 @@ type Route struct {
 	Pattern    *Pattern
 	Handler    func(*Context)
 	Middleware []func(http.Handler) http.Handler
+	Doc        *RouteDoc // Set by Describe
 	paramNames []string
+	skip       map[string]bool // Named middleware to bypass, set by Skip
 }
 
 // RouterGroup holds routes and subgroups with a common prefix
@@ -99,6 +147,7 @@ type RouterGroup struct {
 	middleware []func(http.Handler) http.Handler
 	routes     []Route
 	groups     []*RouterGroup
+	fallback   http.Handler // Serves unmatched requests, set by SPAFallback
 }
 
 // NewRouter initializes a root router group
@@ -148,7 +197,7 @@ func (rg *RouterGroup) Handle(method, path string, handler func(*Context), middl
 
 // HTTP Method Helpers
 
-// GET registers a GET route. For overlapping paths with the same method, the first registered route takes precedence
+// GET registers a GET route. Registering the same method and pattern twice makes ServeMux panic.
 func (rg *RouterGroup) GET(path string, handler func(*Context), middleware ...func(http.Handler) http.Handler) *RouterGroup {
 	return rg.Handle("GET", path, handler, middleware...)
 }
@@ -177,6 +226,7 @@ func (rg *RouterGroup) PATCH(path string, handler func(*Context), middleware ...
 type TrieNode struct {
 	staticChildren map[string]*TrieNode
 	paramChild     *TrieNode
+	catchallChild  *TrieNode
 	routes         map[string]*Route
 }
 
@@ -187,127 +237,97 @@ type Trie struct {
 
 // NewTrie initializes a new Trie
 func NewTrie() *Trie {
-	return &Trie{
-		root: &TrieNode{
-			staticChildren: make(map[string]*TrieNode),
-			routes:         make(map[string]*Route),
-		},
-	}
+	return &Trie{root: newTrieNode()}
 }
 
-// Insert adds a route to the trie
-func (t *Trie) Insert(route *Route) {
+// Insert adds a route to the trie. It fails if a route for the same method
+// already resolves to the same pattern, since only one of them could ever be
+// called; parameter names don't matter, so /teams/{id} and /teams/{team_id}
+// conflict.
+func (t *Trie) Insert(route *Route) error {
 	node := t.root
 	for _, seg := range route.Pattern.segments {
-		isParam := strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
-		if isParam {
+		switch {
+		case isCatchall(seg):
+			if node.catchallChild == nil {
+				node.catchallChild = newTrieNode()
+			}
+			node = node.catchallChild
+		case isParam(seg):
 			if node.paramChild == nil {
-				node.paramChild = &TrieNode{
-					staticChildren: make(map[string]*TrieNode),
-					routes:         make(map[string]*Route),
-				}
+				node.paramChild = newTrieNode()
 			}
 			node = node.paramChild
-		} else {
-			if node.staticChildren == nil {
-				node.staticChildren = make(map[string]*TrieNode)
-			}
-			if child, ok := node.staticChildren[seg]; !ok {
-				child = &TrieNode{
-					staticChildren: make(map[string]*TrieNode),
-					routes:         make(map[string]*Route),
-				}
+		default:
+			child, ok := node.staticChildren[seg]
+			if !ok {
+				child = newTrieNode()
 				node.staticChildren[seg] = child
 			}
-			node = node.staticChildren[seg]
+			node = child
 		}
 	}
-	if _, ok := node.routes[route.Method]; !ok {
-		node.routes[route.Method] = route
+	if existing, ok := node.routes[route.Method]; ok {
+		return fmt.Errorf("router: %s %s conflicts with %s %s", route.Method, displayPath(route.Path), existing.Method, displayPath(existing.Path))
 	}
+	node.routes[route.Method] = route
+	return nil
 }
 
-// Match finds a matching route for a method and path
-func (t *Trie) Match(method, path string) (*Route, []string, bool) {
-	normalizedPath := strings.Trim(path, "/")
-	if normalizedPath == "" {
-		if route, ok := t.root.routes[method]; ok {
-			return route, []string{}, true
-		}
-		return nil, nil, false
+// displayPath formats a route path for messages
+func displayPath(path string) string {
+	if path == "" {
+		return "/"
 	}
-	segments := strings.Split(normalizedPath, "/")
+	return path
+}
 
-	if path == "/" && len(segments) == 1 && segments[0] == "" {
-		segments = []string{}
+// newTrieNode creates an empty trie node
+func newTrieNode() *TrieNode {
+	return &TrieNode{
+		staticChildren: make(map[string]*TrieNode),
+		routes:         make(map[string]*Route),
 	}
+}
 
-	// Special case for root path
-	if len(segments) == 0 {
-		if route, ok := t.root.routes[method]; ok {
-			return route, []string{}, true
-		}
-		return nil, nil, false
+// Match finds a matching route for a method and path. Static segments take
+// precedence over {param} segments, which take precedence over catchalls;
+// if a more specific branch dead-ends, the next one is tried.
+func (t *Trie) Match(method, path string) (*Route, []string, bool) {
+	var segments []string
+	if normalizedPath := strings.Trim(path, "/"); normalizedPath != "" {
+		segments = strings.Split(normalizedPath, "/")
 	}
+	route, paramValues := t.root.match(method, segments, []string{})
+	return route, paramValues, route != nil
+}
 
-	// Try standard matching first
-	node := t.root
-	var paramValues []string
-	var lastParamNode *TrieNode
-	var paramsSoFar []string
-
-	for i, seg := range segments {
-		// Remember last parameter node we encounter
-		if node.paramChild != nil {
-			lastParamNode = node
-			paramsSoFar = make([]string, len(paramValues))
-			copy(paramsSoFar, paramValues)
-		}
-
-		// Static match
-		if child, ok := node.staticChildren[seg]; ok {
-			node = child
-			continue
+// match resolves the remaining segments below n, collecting parameter values
+func (n *TrieNode) match(method string, segments, paramValues []string) (*Route, []string) {
+	if len(segments) == 0 {
+		if route, ok := n.routes[method]; ok {
+			return route, paramValues
 		}
+		return nil, nil
+	}
 
-		// Parameter match
-		if node.paramChild != nil {
-			node = node.paramChild
-			paramValues = append(paramValues, seg)
-			continue
+	if child, ok := n.staticChildren[segments[0]]; ok {
+		if route, values := child.match(method, segments[1:], paramValues); route != nil {
+			return route, values
 		}
-
-		// If we reach here, normal matching failed
-		// Check if we have a parameter that should capture all remaining segments
-		if lastParamNode != nil && lastParamNode.paramChild != nil {
-			if route, ok := lastParamNode.paramChild.routes[method]; ok {
-				// Find position of the last parameter
-				pattern := route.Pattern
-				if len(pattern.segments) > 0 {
-					lastSeg := pattern.segments[len(pattern.segments)-1]
-					if strings.HasPrefix(lastSeg, "{") && strings.HasSuffix(lastSeg, "}") {
-						// Last segment is a parameter - treat it as greedy
-						remainingSegs := segments[i-1:]
-						remainingPath := strings.Join(remainingSegs, "/")
-
-						// Use the parameters up to this point
-						result := append(paramsSoFar, remainingPath)
-						return route, result, true
-					}
-				}
-			}
+	}
+	if n.paramChild != nil {
+		values := append(paramValues[:len(paramValues):len(paramValues)], segments[0])
+		if route, values := n.paramChild.match(method, segments[1:], values); route != nil {
+			return route, values
 		}
-
-		// No match found
-		return nil, nil, false
 	}
-
-	// Normal match at the end of the path
-	if route, ok := node.routes[method]; ok {
-		return route, paramValues, true
+	if n.catchallChild != nil {
+		if route, ok := n.catchallChild.routes[method]; ok {
+			return route, append(paramValues, strings.Join(segments, "/"))
+		}
 	}
-
-	return nil, nil, false
+	return nil, nil
 }
 
 // Build flattens the router group into a list of routes
@@ -322,6 +342,37 @@ func (rg *RouterGroup) Build() []Route {
 	return routes
 }
 
+// RouteInfo summarises a registered route for debugging
+type RouteInfo struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Middlewares int    `json:"middlewares"` // Including those inherited from groups
+}
+
+// Routes lists every route with its full path, ordered by path and method
+func (rg *RouterGroup) Routes() []RouteInfo {
+	routes := rg.Build()
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, route := range routes {
+		path := route.Path
+		if path == "" {
+			path = "/"
+		}
+		infos = append(infos, RouteInfo{
+			Method:      route.Method,
+			Path:        path,
+			Middlewares: len(route.Middleware),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+	return infos
+}
+
 // buildRoutes recursively collects all routes with inherited middleware
 func (rg *RouterGroup) buildRoutes(parentMiddleware []func(http.Handler) http.Handler) []Route {
 	currentMiddleware := append(parentMiddleware, rg.middleware...)
@@ -337,31 +388,72 @@ func (rg *RouterGroup) buildRoutes(parentMiddleware []func(http.Handler) http.Ha
 	return result
 }
 
-// ServeMux creates an http.ServeMux with trie-based route matching
+type routePatternKey struct{}
+
+// CaptureRoutePattern returns a copy of r whose context will receive the route
+// pattern matched by ServeMux. This lets middleware wrapped around the mux
+// label requests by pattern rather than by raw URL.
+func CaptureRoutePattern(r *http.Request) (*http.Request, *string) {
+	pattern := new(string)
+	return r.WithContext(context.WithValue(r.Context(), routePatternKey{}, pattern)), pattern
+}
+
+// ServeMux creates an http.ServeMux with trie-based route matching. It panics
+// if two routes conflict; use ServeMuxE to handle that as an error.
 func ServeMux(rg *RouterGroup) *http.ServeMux {
+	mux, err := ServeMuxE(rg)
+	if err != nil {
+		panic(err)
+	}
+	return mux
+}
+
+// ServeMuxE is like ServeMux but reports conflicting routes as an error
+func ServeMuxE(rg *RouterGroup) (*http.ServeMux, error) {
 	routes := rg.Build()
 	trie := NewTrie()
+	var errs []error
 	for i := range routes {
-		trie.Insert(&routes[i])
+		if err := trie.Insert(&routes[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		route, paramValues, ok := trie.Match(r.Method, r.URL.Path)
 		if ok {
+			if pattern, ok := r.Context().Value(routePatternKey{}).(*string); ok {
+				*pattern = route.Path
+			}
+			w = newResponseRecorder(w)
+			r = withSkips(r, route)
 			c := &Context{
 				ResponseWriter: w,
 				Request:        r,
 				Params:         make(map[string]string),
 				path:           route.Path,
 			}
-			// Populate params from trie matching
+			// Populate params from trie matching. They are also set as path
+			// values so middleware can read them with r.PathValue.
 			if len(route.paramNames) == len(paramValues) {
 				for i, name := range route.paramNames {
 					c.Params[name] = paramValues[i]
+					r.SetPathValue(name, paramValues[i])
 				}
 			}
 
 			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Pick up any request context or writer wrapping added by
+				// middleware, and any path values it replaced, such as a slug
+				// resolved to an ID
+				c.Request = r
+				c.ResponseWriter = newResponseRecorder(w)
+				for name := range c.Params {
+					c.Params[name] = r.PathValue(name)
+				}
 				route.Handler(c)
 			})
 			for i := len(route.Middleware) - 1; i >= 0; i-- {
@@ -370,7 +462,11 @@ func ServeMux(rg *RouterGroup) *http.ServeMux {
 			handler.ServeHTTP(w, r)
 			return
 		}
+		if rg.fallback != nil {
+			rg.fallback.ServeHTTP(w, r)
+			return
+		}
 		http.NotFound(w, r)
 	})
-	return mux
+	return mux, nil
 }
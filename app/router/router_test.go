@@ -1,9 +1,14 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/validator"
 )
 
 func TestRouter(t *testing.T) {
@@ -121,7 +126,7 @@ func TestRouter(t *testing.T) {
 				},
 			},
 			{
-				path: "/files/{path}",
+				path: "/files/{path...}",
 				url:  "/files/images/logo.png",
 				expected: map[string]string{
 					"path": "images/logo.png",
@@ -201,7 +206,7 @@ func TestRouter(t *testing.T) {
 
 	t.Run("Catchall parameter parsing", func(t *testing.T) {
 		rg := NewRouter()
-		rg.GET("/drive/files/{path}", func(c *Context) {
+		rg.GET("/drive/files/{path...}", func(c *Context) {
 			c.Write([]byte(c.Param("path")))
 		})
 
@@ -238,7 +243,7 @@ func TestRouter(t *testing.T) {
 
 	t.Run("Greedy parameter parsing", func(t *testing.T) {
 		rg := NewRouter()
-		rg.GET("/api/{all}", func(c *Context) {
+		rg.GET("/api/{all...}", func(c *Context) {
 			c.Write([]byte(c.Param("all")))
 		})
 
@@ -273,6 +278,63 @@ func TestRouter(t *testing.T) {
 		}
 	})
 
+	t.Run("Single parameter does not span segments", func(t *testing.T) {
+		rg := NewRouter()
+		rg.GET("/users/{id}", func(c *Context) {
+			t.Errorf("/users/{id} matched with id %q", c.Param("id"))
+		})
+		rg.GET("/files/{name}", func(c *Context) {
+			t.Errorf("/files/{name} matched with name %q", c.Param("name"))
+		})
+
+		for _, url := range []string{"/users/123/profile", "/files/docs/report.pdf"} {
+			rr := httptest.NewRecorder()
+			ServeMux(rg).ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("%s: got %v want %v", url, rr.Code, http.StatusNotFound)
+			}
+		}
+	})
+
+	t.Run("Catchall precedence", func(t *testing.T) {
+		rg := NewRouter()
+		rg.GET("/files/{path...}", func(c *Context) {
+			c.Write([]byte("catchall:" + c.Param("path")))
+		})
+		rg.GET("/files/{id}/meta", func(c *Context) {
+			c.Write([]byte("meta:" + c.Param("id")))
+		})
+		rg.GET("/files/shared", func(c *Context) {
+			c.Write([]byte("shared"))
+		})
+
+		tests := []struct {
+			url      string
+			expected string
+		}{
+			{"/files/shared", "shared"},
+			{"/files/42/meta", "meta:42"},
+			{"/files/42/other", "catchall:42/other"},
+			{"/files/shared/report.pdf", "catchall:shared/report.pdf"},
+		}
+		for _, tt := range tests {
+			rr := httptest.NewRecorder()
+			ServeMux(rg).ServeHTTP(rr, httptest.NewRequest("GET", tt.url, nil))
+			if rr.Body.String() != tt.expected {
+				t.Errorf("%s: got %q want %q", tt.url, rr.Body.String(), tt.expected)
+			}
+		}
+	})
+
+	t.Run("Catchall must be last", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for a catchall before the last segment")
+			}
+		}()
+		NewRouter().GET("/files/{path...}/meta", func(c *Context) {})
+	})
+
 	t.Run("Trailing slash handling", func(t *testing.T) {
 		rg := NewRouter()
 		rg.GET("/users/{id}", func(c *Context) {
@@ -333,35 +395,35 @@ func TestRouter(t *testing.T) {
 		}
 	})
 
-	t.Run("Overlapping_parameterized_routes", func(t *testing.T) {
+	t.Run("Conflicting routes", func(t *testing.T) {
 		tests := []struct {
 			name     string
 			register func(*RouterGroup)
 			expected string
 		}{
 			{
-				name: "id first",
+				name: "Same path",
 				register: func(rg *RouterGroup) {
-					rg.GET("/users/{id}", func(c *Context) {
-						c.Write([]byte("id:" + c.Param("id")))
-					})
-					rg.GET("/users/{name}", func(c *Context) {
-						c.Write([]byte("name:" + c.Param("name")))
-					})
+					rg.GET("/users", func(c *Context) {})
+					rg.GET("/users/", func(c *Context) {})
 				},
-				expected: "id:123",
+				expected: "router: GET /users/ conflicts with GET /users",
 			},
 			{
-				name: "name first",
+				name: "Different parameter names",
 				register: func(rg *RouterGroup) {
-					rg.GET("/users/{name}", func(c *Context) {
-						c.Write([]byte("name:" + c.Param("name")))
-					})
-					rg.GET("/users/{id}", func(c *Context) {
-						c.Write([]byte("id:" + c.Param("id")))
-					})
+					rg.GET("/users/{id}", func(c *Context) {})
+					rg.GET("/users/{name}", func(c *Context) {})
 				},
-				expected: "name:123",
+				expected: "router: GET /users/{name} conflicts with GET /users/{id}",
+			},
+			{
+				name: "Across groups",
+				register: func(rg *RouterGroup) {
+					rg.Group("/api").GET("/teams/{id}", func(c *Context) {})
+					rg.Group("/api/teams").GET("/{team_id}", func(c *Context) {})
+				},
+				expected: "router: GET /api/teams/{team_id} conflicts with GET /api/teams/{id}",
 			},
 		}
 
@@ -369,16 +431,34 @@ func TestRouter(t *testing.T) {
 			t.Run(tt.name, func(t *testing.T) {
 				rg := NewRouter()
 				tt.register(rg)
-				req := httptest.NewRequest("GET", "/users/123", nil)
-				rr := httptest.NewRecorder()
-				ServeMux(rg).ServeHTTP(rr, req)
-				if rr.Body.String() != tt.expected {
-					t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expected)
+
+				_, err := ServeMuxE(rg)
+				if err == nil || err.Error() != tt.expected {
+					t.Errorf("Expected error %q, got %v", tt.expected, err)
 				}
+
+				defer func() {
+					if r := recover(); r == nil {
+						t.Error("Expected ServeMux to panic")
+					}
+				}()
+				ServeMux(rg)
 			})
 		}
 	})
 
+	t.Run("Same path with different methods", func(t *testing.T) {
+		rg := NewRouter()
+		rg.GET("/users/{id}", func(c *Context) {})
+		rg.DELETE("/users/{id}", func(c *Context) {})
+		rg.GET("/users/{id}/{field}", func(c *Context) {})
+		rg.GET("/users/{id...}", func(c *Context) {})
+
+		if _, err := ServeMuxE(rg); err != nil {
+			t.Errorf("Unexpected conflict: %v", err)
+		}
+	})
+
 	t.Run("Middleware short-circuit", func(t *testing.T) {
 		rg := NewRouter()
 		rg.GET("/test", func(c *Context) {
@@ -397,4 +477,219 @@ func TestRouter(t *testing.T) {
 			t.Errorf("handler returned wrong status: got %v want %v", rr.Code, http.StatusForbidden)
 		}
 	})
+
+	t.Run("Middleware request changes reach handler", func(t *testing.T) {
+		type ctxKey struct{}
+		rg := NewRouter()
+		rg.GET("/users/{id}", func(c *Context) {
+			v, _ := c.Request.Context().Value(ctxKey{}).(string)
+			c.Write([]byte(v))
+		}, func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Path-Value", r.PathValue("id"))
+				h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "from-middleware")))
+			})
+		})
+
+		req := httptest.NewRequest("GET", "/users/42", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if rr.Body.String() != "from-middleware" {
+			t.Errorf("handler did not see middleware context: got %q", rr.Body.String())
+		}
+		if got := rr.Header().Get("X-Path-Value"); got != "42" {
+			t.Errorf("middleware PathValue(id) = %q, want %q", got, "42")
+		}
+	})
+
+	t.Run("Middleware path values reach handler", func(t *testing.T) {
+		rg := NewRouter()
+		rg.GET("/users/{id}", func(c *Context) {
+			c.Write([]byte(c.Param("id")))
+		}, func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.SetPathValue("id", "42")
+				h.ServeHTTP(w, r)
+			})
+		})
+
+		req := httptest.NewRequest("GET", "/users/ada", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if rr.Body.String() != "42" {
+			t.Errorf("Param(id) = %q, want %q", rr.Body.String(), "42")
+		}
+	})
+}
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("Records status and bytes", func(t *testing.T) {
+		rg := NewRouter()
+		var ctx *Context
+		rg.POST("/items", func(c *Context) {
+			ctx = c
+			c.WriteHeader(http.StatusCreated)
+			c.Write([]byte("created"))
+		})
+
+		req := httptest.NewRequest("POST", "/items", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("handler returned wrong status: got %v want %v", rr.Code, http.StatusCreated)
+		}
+		if ctx.StatusCode() != http.StatusCreated {
+			t.Errorf("recorded wrong status: got %v want %v", ctx.StatusCode(), http.StatusCreated)
+		}
+		if ctx.BytesWritten() != len("created") {
+			t.Errorf("recorded wrong byte count: got %v want %v", ctx.BytesWritten(), len("created"))
+		}
+	})
+
+	t.Run("Implicit 200 on write", func(t *testing.T) {
+		rg := NewRouter()
+		var ctx *Context
+		rg.GET("/items", func(c *Context) {
+			ctx = c
+			c.Write([]byte("ok"))
+		})
+
+		req := httptest.NewRequest("GET", "/items", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if ctx.StatusCode() != http.StatusOK {
+			t.Errorf("recorded wrong status: got %v want %v", ctx.StatusCode(), http.StatusOK)
+		}
+		if ctx.BytesWritten() != 2 {
+			t.Errorf("recorded wrong byte count: got %v want %v", ctx.BytesWritten(), 2)
+		}
+	})
+
+	t.Run("Middleware observes status", func(t *testing.T) {
+		rg := NewRouter()
+		var observed int
+		rg.GET("/missing", func(c *Context) {
+			c.Status(http.StatusNotFound, "nope")
+		}, func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r)
+				if rec, ok := w.(*responseRecorder); ok {
+					observed = rec.status
+				}
+			})
+		})
+
+		req := httptest.NewRequest("GET", "/missing", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if observed != http.StatusNotFound {
+			t.Errorf("middleware observed wrong status: got %v want %v", observed, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Flusher passthrough", func(t *testing.T) {
+		rg := NewRouter()
+		rg.GET("/stream", func(c *Context) {
+			f, ok := c.ResponseWriter.(http.Flusher)
+			if !ok {
+				t.Fatal("ResponseWriter does not implement http.Flusher")
+			}
+			c.Write([]byte("chunk"))
+			f.Flush()
+		})
+
+		req := httptest.NewRequest("GET", "/stream", nil)
+		rr := httptest.NewRecorder()
+		ServeMux(rg).ServeHTTP(rr, req)
+
+		if !rr.Flushed {
+			t.Error("expected underlying writer to be flushed")
+		}
+	})
+}
+
+type bindRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (r *bindRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Name), "name", "name is required")
+	v.CheckField(validator.Matches(r.Email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+func TestBindAndValidate(t *testing.T) {
+	rg := NewRouter()
+	rg.POST("/users", func(c *Context) {
+		var req bindRequest
+		if !c.BindAndValidate(&req) {
+			return
+		}
+		c.JSON(http.StatusCreated, req)
+	})
+	mux := ServeMux(rg)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Valid body", func(t *testing.T) {
+		rr := post(`{"name":"Ada","email":"ada@example.com"}`)
+		if rr.Code != http.StatusCreated {
+			t.Errorf("Expected status 201, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Malformed JSON", func(t *testing.T) {
+		rr := post(`{"name":`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("All field errors reported", func(t *testing.T) {
+		rr := post(`{"name":"","email":"nope"}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d", rr.Code)
+		}
+		var body ValidationErrorResponse
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		if len(body.Errors) != 2 || body.Errors["name"] == "" || body.Errors["email"] == "" {
+			t.Errorf("Expected errors for name and email, got %v", body.Errors)
+		}
+	})
+}
+
+func TestRoutes(t *testing.T) {
+	noop := func(c *Context) {}
+	mw := func(next http.Handler) http.Handler { return next }
+
+	rg := NewRouter()
+	rg.GET("/health", noop)
+	api := rg.Group("/api", mw)
+	projects := api.Group("/projects/{project_id}")
+	projects.POST("/tickets/{id}/watch", noop, mw)
+
+	routes := rg.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %v", routes)
+	}
+
+	want := RouteInfo{Method: "POST", Path: "/api/projects/{project_id}/tickets/{id}/watch", Middlewares: 2}
+	if routes[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, routes[0])
+	}
+	if routes[1].Path != "/health" || routes[1].Middlewares != 0 {
+		t.Errorf("Expected /health without middleware, got %+v", routes[1])
+	}
 }
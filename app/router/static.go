@@ -0,0 +1,92 @@
+package router
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Static serves the files in dir under urlPrefix, e.g. Static("/assets", "./web/assets")
+// serves ./web/assets/app.js at /assets/app.js. Directory listings are not
+// served, and paths are cleaned so requests cannot escape dir.
+func (rg *RouterGroup) Static(urlPrefix, dir string) *RouterGroup {
+	files := http.FileServer(noListing{http.Dir(dir)})
+	handler := func(c *Context) {
+		r := c.Request.Clone(c.Request.Context())
+		r.URL.Path = path.Clean("/" + c.Param("filepath"))
+		r.URL.RawPath = ""
+		files.ServeHTTP(c.ResponseWriter, r)
+	}
+
+	pattern := strings.TrimRight(urlPrefix, "/") + "/{filepath...}"
+	rg.Handle("GET", pattern, handler)
+	rg.Handle("HEAD", pattern, handler)
+	return rg
+}
+
+// noListing hides directories from http.FileServer, so requests for them get
+// a 404 instead of a listing
+type noListing struct {
+	fs http.FileSystem
+}
+
+// Open opens name, refusing directories
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+// SPAFallback serves the file at indexPath for requests that match no route,
+// so a single-page app can handle its own client-side routes. Only GET and
+// HEAD requests from browsers (those accepting text/html) fall back; API
+// clients still get a 404, as does anything under an excluded prefix such as
+// "/api". Call it on the group passed to ServeMux.
+func (rg *RouterGroup) SPAFallback(indexPath string, exclude ...string) *RouterGroup {
+	rg.fallback = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			!strings.Contains(r.Header.Get("Accept"), "text/html") ||
+			underPrefix(r.URL.Path, exclude) {
+			http.NotFound(w, r)
+			return
+		}
+
+		f, err := os.Open(indexPath)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, path.Base(indexPath), info.ModTime(), f)
+	})
+	return rg
+}
+
+// underPrefix reports whether p is one of prefixes or below one of them
+func underPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = "/" + strings.Trim(prefix, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
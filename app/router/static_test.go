@@ -0,0 +1,76 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatic(t *testing.T) {
+	root := t.TempDir()
+	assets := filepath.Join(root, "assets")
+	if err := os.MkdirAll(filepath.Join(assets, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(assets, "app.js"):       "console.log('app')",
+		filepath.Join(assets, "css", "a.css"): "body {}",
+		filepath.Join(root, "secret.txt"):     "secret",
+		filepath.Join(root, "index.html"):     "<html>app</html>",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rg := NewRouter()
+	rg.GET("/api/projects", func(c *Context) { c.JSON(http.StatusOK, []string{}) })
+	rg.Static("/assets", assets)
+	rg.SPAFallback(filepath.Join(root, "index.html"), "/api")
+	mux := ServeMux(rg)
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		accept string
+		status int
+		body   string
+	}{
+		{"File", "GET", "/assets/app.js", "", http.StatusOK, "console.log('app')"},
+		{"Nested file", "GET", "/assets/css/a.css", "", http.StatusOK, "body {}"},
+		{"HEAD", "HEAD", "/assets/app.js", "", http.StatusOK, ""},
+		{"Missing file", "GET", "/assets/missing.js", "", http.StatusNotFound, ""},
+		{"Directory listing", "GET", "/assets/css/", "", http.StatusNotFound, ""},
+		{"Encoded traversal", "GET", "/assets/..%2fsecret.txt", "", http.StatusNotFound, ""},
+		{"SPA route", "GET", "/projects/42/board", "text/html,application/xhtml+xml", http.StatusOK, "<html>app</html>"},
+		{"API client", "GET", "/projects/42/board", "application/json", http.StatusNotFound, ""},
+		{"Excluded prefix", "GET", "/api/unknown", "text/html", http.StatusNotFound, ""},
+		{"Non-GET", "POST", "/projects/42/board", "text/html", http.StatusNotFound, ""},
+		{"Matched route", "GET", "/api/projects", "text/html", http.StatusOK, "[]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("got status %v want %v", rr.Code, tt.status)
+			}
+			if tt.body != "" && rr.Body.String() != tt.body {
+				t.Errorf("got body %q want %q", rr.Body.String(), tt.body)
+			}
+			if strings.Contains(rr.Body.String(), "secret") {
+				t.Error("response leaked a file outside the static directory")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	// defaultCertDir is where certificates are kept without a TLS_CACHE_DIR
+	defaultCertDir = "./certs"
+
+	// acmeChallengeAddr serves the ACME HTTP-01 challenge, which Let's Encrypt
+	// only ever sends to port 80
+	acmeChallengeAddr = ":80"
+)
+
+// WithAutoTLS configures the application to serve HTTPS with certificates for
+// domains obtained and renewed from Let's Encrypt. Certificates are kept in
+// TLS_CACHE_DIR so restarts don't request new ones. While serving, port 80
+// answers the ACME HTTP-01 challenge and redirects everything else to HTTPS;
+// see WithHTTPRedirect to change how. Without domains the application serves
+// plain HTTP.
+func (app *Application) WithAutoTLS(domains ...string) *TLSServer {
+	if len(domains) == 0 {
+		log.Println("No domains given for automatic TLS, serving plain HTTP")
+		return &TLSServer{app: app}
+	}
+
+	domains, err := validateDomains(domains)
+	if err != nil {
+		log.Fatalf("Invalid automatic TLS domains: %v", err)
+	}
+
+	dir := defaultCertDir
+	if app.Config != nil && app.Config.TLSCacheDir != "" {
+		dir = app.Config.TLSCacheDir
+	}
+
+	app.certManager = &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(dir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+	}
+	app.tlsConfig = app.certManager.TLSConfig()
+	return &TLSServer{app: app}
+}
+
+// validateDomains normalizes domains to lower case, dropping duplicates, and
+// checks that each is a fully qualified host name Let's Encrypt can issue a
+// certificate for over HTTP-01: no scheme, port, IP address or wildcard.
+func validateDomains(domains []string) ([]string, error) {
+	seen := make(map[string]bool, len(domains))
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if err := checkDomain(domain); err != nil {
+			return nil, err
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			result = append(result, domain)
+		}
+	}
+	return result, nil
+}
+
+func checkDomain(domain string) error {
+	switch {
+	case domain == "":
+		return fmt.Errorf("empty domain")
+	case len(domain) > 253:
+		return fmt.Errorf("%q is longer than 253 characters", domain)
+	case net.ParseIP(domain) != nil:
+		return fmt.Errorf("%q is an IP address; certificates are only issued for host names", domain)
+	case strings.Contains(domain, "*"):
+		return fmt.Errorf("%q is a wildcard, which needs a DNS challenge", domain)
+	case !strings.Contains(domain, "."):
+		return fmt.Errorf("%q is not a fully qualified domain", domain)
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("%q is not a valid host name", domain)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("%q is not a valid host name", domain)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/types"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestValidateDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []string
+		want    []string
+		wantErr bool
+	}{
+		{"Single", []string{"api.example.com"}, []string{"api.example.com"}, false},
+		{"Normalized", []string{" API.Example.com. ", "api.example.com", "www.example.com"}, []string{"api.example.com", "www.example.com"}, false},
+		{"Hyphens", []string{"my-app.example.co.uk"}, []string{"my-app.example.co.uk"}, false},
+		{"Empty", []string{"api.example.com", " "}, nil, true},
+		{"Scheme", []string{"https://api.example.com"}, nil, true},
+		{"Port", []string{"api.example.com:443"}, nil, true},
+		{"IP address", []string{"203.0.113.7"}, nil, true},
+		{"Wildcard", []string{"*.example.com"}, nil, true},
+		{"Not qualified", []string{"localhost"}, nil, true},
+		{"Empty label", []string{"api..example.com"}, nil, true},
+		{"Leading hyphen", []string{"-api.example.com"}, nil, true},
+		{"Underscore", []string{"my_app.example.com"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateDomains(tt.domains)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateDomains(%q) error = %v, wantErr %v", tt.domains, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateDomains(%q) = %q, want %q", tt.domains, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAutoTLS(t *testing.T) {
+	dir := t.TempDir()
+	app := NewApplication()
+	app.Config = &types.AppConfig{TLSCacheDir: dir}
+	app.WithAutoTLS("API.example.com")
+
+	if app.certManager == nil || app.tlsConfig == nil {
+		t.Fatal("Expected a certificate manager and TLS config")
+	}
+	if app.certManager.Cache != autocert.DirCache(dir) {
+		t.Errorf("Cache = %v, want %s", app.certManager.Cache, dir)
+	}
+	if app.tlsConfig.GetCertificate == nil {
+		t.Error("Expected certificates to come from the manager")
+	}
+	if !slices.Contains(app.tlsConfig.NextProtos, acme.ALPNProto) {
+		t.Errorf("NextProtos = %v, want it to include %s", app.tlsConfig.NextProtos, acme.ALPNProto)
+	}
+
+	t.Run("Other hosts", func(t *testing.T) {
+		_, err := app.tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.example.com"})
+		if err == nil {
+			t.Error("Expected no certificate for a host that wasn't configured")
+		}
+	})
+
+	challenges := app.plainServer().Handler
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantTarget string
+	}{
+		{"Redirects to HTTPS", "http://api.example.com/projects?limit=5", http.StatusFound, "https://api.example.com/projects?limit=5"},
+		{"Unknown challenge", "http://api.example.com/.well-known/acme-challenge/token", http.StatusNotFound, ""},
+		{"Challenge for other host", "http://evil.example.com/.well-known/acme-challenge/token", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			challenges.ServeHTTP(rr, httptest.NewRequest("GET", tt.url, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Location"); got != tt.wantTarget {
+				t.Errorf("Location = %q, want %q", got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestWithAutoTLSRedirect(t *testing.T) {
+	app := NewApplication()
+	app.Config = &types.AppConfig{AppPort: 443, TLSCacheDir: t.TempDir()}
+	app.WithAutoTLS("api.example.com").WithHTTPRedirect(8080)
+
+	plain := app.plainServer()
+	if plain.Addr != ":8080" {
+		t.Errorf("Addr = %q, want :8080", plain.Addr)
+	}
+
+	rr := httptest.NewRecorder()
+	plain.Handler.ServeHTTP(rr, httptest.NewRequest("GET", "http://api.example.com/projects", nil))
+	if rr.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected status 301, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	plain.Handler.ServeHTTP(rr, httptest.NewRequest("GET", "http://api.example.com/.well-known/acme-challenge/token", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected the challenge to be answered (404 for an unknown token), got %d", rr.Code)
+	}
+}
+
+func TestWithAutoTLSWithoutDomains(t *testing.T) {
+	app := NewApplication()
+	app.WithAutoTLS()
+
+	if app.certManager != nil || app.tlsConfig != nil {
+		t.Error("Expected plain HTTP without domains")
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithHTTPRedirect also listens for plain HTTP on port, permanently
+// redirecting every request to the same URL over HTTPS. It is started and
+// shut down along with the HTTPS server; a port of 0 leaves it off. With
+// WithAutoTLS it answers ACME challenges too, in place of the listener on
+// port 80, so port should be 80 or have port 80 forwarded to it.
+func (ts *TLSServer) WithHTTPRedirect(port int) *TLSServer {
+	ts.app.redirectPort = port
+	return ts
+}
+
+// plainServer returns the server listening for plain HTTP next to HTTPS, or
+// nil if there is none: it redirects to HTTPS when WithHTTPRedirect is used
+// and answers ACME challenges for WithAutoTLS.
+func (app *Application) plainServer() *http.Server {
+	if app.tlsConfig == nil {
+		return nil
+	}
+
+	addr := acmeChallengeAddr
+	var handler http.Handler
+	if app.redirectPort != 0 {
+		addr = ":" + strconv.Itoa(app.redirectPort)
+		handler = httpsRedirect(app.Config.AppPort)
+	}
+	if app.certManager != nil {
+		// Without a redirect handler, autocert redirects to HTTPS itself
+		handler = app.certManager.HTTPHandler(handler)
+	}
+	if handler == nil {
+		return nil
+	}
+
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  app.Config.ServerReadTimeout,
+		WriteTimeout: app.Config.ServerWriteTimeout,
+	}
+}
+
+// httpsRedirect sends requests to the same host, path and query over HTTPS
+// on httpsPort with 301 Moved Permanently
+func httpsRedirect(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 address
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/types"
+)
+
+func TestWithHTTPRedirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		method    string
+		url       string
+		want      string
+	}{
+		{"Path and query", 443, "GET", "http://tickit.example.com/projects/42?limit=5&cursor=abc", "https://tickit.example.com/projects/42?limit=5&cursor=abc"},
+		{"Root", 443, "GET", "http://tickit.example.com/", "https://tickit.example.com/"},
+		{"Port dropped", 443, "GET", "http://tickit.example.com:8080/health", "https://tickit.example.com/health"},
+		{"Other HTTPS port", 8443, "GET", "http://tickit.example.com:8080/health", "https://tickit.example.com:8443/health"},
+		{"Escaped path", 443, "GET", "http://tickit.example.com/files/a%20b?q=x%2Fy", "https://tickit.example.com/files/a%20b?q=x%2Fy"},
+		{"IPv6", 443, "GET", "http://[::1]:8080/health", "https://[::1]/health"},
+		{"POST", 443, "POST", "http://tickit.example.com/auth/login", "https://tickit.example.com/auth/login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewApplication()
+			app.Config = &types.AppConfig{AppPort: tt.httpsPort}
+			app.tlsConfig = &tls.Config{}
+			(&TLSServer{app: app}).WithHTTPRedirect(8080)
+
+			plain := app.plainServer()
+			if plain == nil || plain.Addr != ":8080" {
+				t.Fatalf("Expected a redirect server on :8080, got %+v", plain)
+			}
+
+			rr := httptest.NewRecorder()
+			plain.Handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("Expected status 301, got %d", rr.Code)
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithHTTPRedirectIsOptIn(t *testing.T) {
+	app := NewApplication()
+	app.Config = &types.AppConfig{AppPort: 443}
+	app.tlsConfig = &tls.Config{}
+	ts := &TLSServer{app: app}
+
+	if app.plainServer() != nil {
+		t.Error("Expected no plain HTTP server unless asked for")
+	}
+	if ts.WithHTTPRedirect(0); app.plainServer() != nil {
+		t.Error("Expected a port of 0 to leave the redirect off")
+	}
+
+	// Without TLS there is nothing to redirect to
+	app.tlsConfig = nil
+	if ts.WithHTTPRedirect(8080); app.plainServer() != nil {
+		t.Error("Expected no redirect server without TLS")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/Bethel-nz/tickit/internal/types"
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // Application holds application-wide dependencies and configuration.
@@ -24,10 +26,16 @@ type Application struct {
 	Config           *types.AppConfig
 	Mux              *http.ServeMux
 	DB               *pgxpool.Pool
+	ReplicaDB        *pgxpool.Pool // Optional read replica; nil when not configured
 	Store            *store.Queries
 	Cache            *redis.Client
 	GlobalMiddleware []func(http.Handler) http.Handler
-	tlsConfig        *tls.Config // New field for TLS configuration
+	exemptPaths      []string          // Path prefixes that bypass GlobalMiddleware
+	tlsConfig        *tls.Config       // New field for TLS configuration
+	certManager      *autocert.Manager // Obtains certificates when set by WithAutoTLS
+	redirectPort     int               // Plain HTTP port redirecting to HTTPS; 0 when off
+	internalAddr     string            // Address the internal routes listen on; set by WithInternalMux
+	internalHandler  http.Handler      // Internal routes, served apart from the public API
 }
 
 // NewApplication creates a new instance of Application with default middleware.
@@ -56,7 +64,32 @@ func (app *Application) WithConfig(cfg *types.AppConfig) *Application {
 	}
 
 	app.DB = pgxPool
-	app.Store = store.New(pgxPool)
+	var db store.DBTX = pgxPool
+
+	// Send read-only queries to the replica when one is configured
+	if cfg.DatabaseReplicaURL != "" {
+		replicaConfig, err := pgxpool.ParseConfig(cfg.DatabaseReplicaURL)
+		if err != nil {
+			log.Fatalf("Unable to parse replica DSN: %v", err)
+		}
+
+		replicaConfig.MaxConns = int32(cfg.MaxOpenConns)
+		replicaConfig.MaxConnIdleTime = cfg.MaxIdleTime
+
+		replicaPool, err := pgxpool.NewWithConfig(ctx, replicaConfig)
+		if err != nil {
+			log.Fatalf("Unable to create replica PGX pool: %v", err)
+		}
+
+		app.ReplicaDB = replicaPool
+		db = store.NewReplicaRouter(pgxPool, replicaPool)
+	}
+
+	// Retry reads that fail with serialization failures or dropped connections
+	app.Store = store.New(store.NewRetryDB(db, store.RetryPolicy{
+		MaxAttempts: cfg.DBRetryAttempts,
+		BaseDelay:   cfg.DBRetryDelay,
+	}))
 
 	return app
 }
@@ -75,21 +108,115 @@ func (app *Application) Use(middleware ...func(http.Handler) http.Handler) *Appl
 	return app
 }
 
+// ExemptPaths registers path prefixes whose requests skip the global middleware,
+// e.g. /health, which would otherwise flood the request log. A prefix matches
+// the path itself and anything below it, so /health does not match /healthz.
+func (app *Application) ExemptPaths(prefixes ...string) *Application {
+	for _, prefix := range prefixes {
+		app.exemptPaths = append(app.exemptPaths, "/"+strings.Trim(prefix, "/"))
+	}
+	return app
+}
+
 // WithMux registers application routes defined in a RouterGroup.
+// Global middleware registered with Use wraps every public route except
+// those under a prefix passed to ExemptPaths.
 func (app *Application) WithMux(routes *router.RouterGroup) *Application {
-	app.Mux = router.ServeMux(routes)
+	mux, err := router.ServeMuxE(routes)
+	if err != nil {
+		log.Fatalf("Invalid routes: %v", err)
+	}
+
+	handler := http.Handler(mux)
+	for i := len(app.GlobalMiddleware) - 1; i >= 0; i-- {
+		handler = app.GlobalMiddleware[i](handler)
+	}
+
+	if len(app.exemptPaths) > 0 {
+		wrapped := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if app.isExempt(r.URL.Path) {
+				mux.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+
+	app.rootMux().Handle("/", handler)
 
-	handler := http.Handler(app.Mux)
+	return app
+}
+
+// WithFiles serves handler under prefix, e.g. uploaded files on local disk.
+// The prefix is stripped before handler sees the request. Global middleware
+// applies as it does for routes registered with WithMux.
+func (app *Application) WithFiles(prefix string, handler http.Handler) *Application {
+	prefix = "/" + strings.Trim(prefix, "/")
+	handler = http.StripPrefix(prefix, handler)
 	for i := len(app.GlobalMiddleware) - 1; i >= 0; i-- {
 		handler = app.GlobalMiddleware[i](handler)
 	}
 
-	app.Mux = http.NewServeMux()
-	app.Mux.Handle("/", handler)
+	app.rootMux().Handle(prefix+"/", handler)
 
 	return app
 }
 
+// WithInternalMux serves operational routes (metrics, health) on their own
+// listener at addr, so they are never reachable through the public port.
+// These routes bypass the global middleware entirely, so public concerns such
+// as CORS never apply to them; only the middleware passed here is used. They
+// have no authentication: addr should be on localhost or a private network.
+func (app *Application) WithInternalMux(addr string, routes *router.RouterGroup, middleware ...func(http.Handler) http.Handler) *Application {
+	mux, err := router.ServeMuxE(routes)
+	if err != nil {
+		log.Fatalf("Invalid internal routes: %v", err)
+	}
+
+	handler := http.Handler(mux)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	app.internalAddr = addr
+	app.internalHandler = handler
+
+	return app
+}
+
+// internalServer returns the server for the routes passed to
+// WithInternalMux, or nil when there are none
+func (app *Application) internalServer() *http.Server {
+	if app.internalHandler == nil {
+		return nil
+	}
+	return &http.Server{
+		Addr:         app.internalAddr,
+		Handler:      app.internalHandler,
+		ReadTimeout:  app.Config.ServerReadTimeout,
+		WriteTimeout: app.Config.ServerWriteTimeout,
+	}
+}
+
+// isExempt reports whether path falls under one of the exempt prefixes.
+func (app *Application) isExempt(path string) bool {
+	for _, prefix := range app.exemptPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// rootMux returns the top-level mux, creating it on first use.
+func (app *Application) rootMux() *http.ServeMux {
+	if app.Mux == nil {
+		app.Mux = http.NewServeMux()
+	}
+	return app.Mux
+}
+
 // TLSServer represents an Application configured with TLS, restricting chaining to Serve.
 type TLSServer struct {
 	app *Application
@@ -107,7 +234,8 @@ func (app *Application) WithTLS(cfg *tls.Config) *TLSServer {
 
 // Serve starts the HTTP server and gracefully shuts it down on interrupt signals.
 // When called on Application, it starts an HTTP server.
-// When called on TLSServer, it starts an HTTPS server with TLS.
+// When called on TLSServer, it starts an HTTPS server with TLS, along with a
+// plain HTTP server when WithHTTPRedirect or WithAutoTLS needs one.
 func (app *Application) Serve() error {
 	server := &http.Server{
 		Addr:         ":" + strconv.Itoa(app.Config.AppPort),
@@ -121,7 +249,30 @@ func (app *Application) Serve() error {
 		server.TLSConfig = app.tlsConfig
 	}
 
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 3)
+
+	// Plain HTTP redirects to HTTPS or proves certificates from Let's Encrypt
+	plain := app.plainServer()
+	if plain != nil {
+		go func() {
+			log.Printf("Plain HTTP server starting on %s", plain.Addr)
+			if err := plain.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- fmt.Errorf("plain HTTP server: %w", err)
+			}
+		}()
+	}
+
+	// Operational routes listen apart from the public API
+	internal := app.internalServer()
+	if internal != nil {
+		go func() {
+			log.Printf("Internal server starting on %s", internal.Addr)
+			if err := internal.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- fmt.Errorf("internal server: %w", err)
+			}
+		}()
+	}
+
 	go func() {
 		if app.tlsConfig != nil {
 			log.Printf("Server starting with TLS on https://localhost:%d", app.Config.AppPort)
@@ -153,12 +304,28 @@ func (app *Application) Serve() error {
 		log.Println("Shutdown completed")
 	}
 
+	if plain != nil {
+		if err := plain.Shutdown(ctx); err != nil {
+			log.Printf("Plain HTTP server shutdown failed: %v", err)
+		}
+	}
+
+	if internal != nil {
+		if err := internal.Shutdown(ctx); err != nil {
+			log.Printf("Internal server shutdown failed: %v", err)
+		}
+	}
+
 	var shutdownErr error
 
 	if app.DB != nil {
 		app.DB.Close()
 	}
 
+	if app.ReplicaDB != nil {
+		app.ReplicaDB.Close()
+	}
+
 	if app.Cache != nil {
 		if err := app.Cache.Close(); err != nil {
 			shutdownErr = fmt.Errorf("cache close error: %v", err)
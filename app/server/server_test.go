@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/types"
+)
+
+func TestWithInternalMux(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	public := router.NewRouter()
+	public.GET("/projects", func(c *router.Context) { c.Status(http.StatusOK) })
+
+	internal := router.NewRouter()
+	internal.GET("/metrics", func(c *router.Context) { c.Status(http.StatusOK) })
+
+	app := NewApplication().Use(tag("global"))
+	app.Config = &types.AppConfig{}
+	app.WithMux(public).WithInternalMux("localhost:9090", internal, tag("internal"))
+	server := app.internalServer()
+	if server == nil || server.Addr != "localhost:9090" {
+		t.Fatalf("Expected an internal server on localhost:9090, got %+v", server)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		path       string
+		wantStatus int
+		wantTag    string
+	}{
+		{"Public route", app.Mux, "/projects", http.StatusOK, "global"},
+		{"Internal route", server.Handler, "/metrics", http.StatusOK, "internal"},
+		{"Internal route on the public port", app.Mux, "/metrics", http.StatusNotFound, "global"},
+		{"Public route on the internal port", server.Handler, "/projects", http.StatusNotFound, "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if got := rr.Header().Values("X-Middleware"); len(got) != 1 || got[0] != tt.wantTag {
+				t.Errorf("Expected only %q middleware, got %v", tt.wantTag, got)
+			}
+		})
+	}
+}
+
+func TestExemptPaths(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	routes := router.NewRouter()
+	ok := func(c *router.Context) { c.Status(http.StatusOK) }
+	routes.GET("/health", ok)
+	routes.GET("/healthz", ok)
+	routes.GET("/projects", ok)
+
+	app := NewApplication().
+		Use(middleware.LoggerMiddleware).
+		ExemptPaths("/health")
+	app.WithMux(routes)
+
+	tests := []struct {
+		path       string
+		wantLogged bool
+	}{
+		{"/health", false},
+		{"/healthz", true},
+		{"/projects", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			logs.Reset()
+			rr := httptest.NewRecorder()
+			app.Mux.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rr.Code)
+			}
+			if logged := strings.Contains(logs.String(), tt.path); logged != tt.wantLogged {
+				t.Errorf("Expected logged=%v, got log %q", tt.wantLogged, logs.String())
+			}
+		})
+	}
+}
@@ -2,40 +2,156 @@ package main
 
 import (
 	"log"
+	"net/url"
+	"time"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/app/server"
 	"github.com/Bethel-nz/tickit/handlers"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/config"
+	"github.com/Bethel-nz/tickit/internal/email"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/storage"
 )
 
 func main() {
 	// Load the unified configuration
-	appConfig := config.LoadConfig()
+	appConfig, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := appConfig.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	cors, err := middleware.NewCORS(middleware.CORSConfig{
+		AllowedOrigins:   appConfig.CORSAllowedOrigins,
+		AllowCredentials: appConfig.CORSAllowCredentials,
+		MaxAge:           appConfig.CORSMaxAge,
+	})
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{
+		Secret:       appConfig.JWTKey,
+		PreviousKeys: appConfig.JWTPreviousKeys,
+		Expiry:       appConfig.JWTExpiry,
+		Issuer:       appConfig.JWTIssuer,
+	})
+	if err != nil {
+		log.Fatalf("Invalid JWT configuration (is TICKIT_JWT_KEY set?): %v", err)
+	}
+	auth.SetDefaultTokenManager(tokens)
+
+	if err := router.SetTrustedProxies(appConfig.TrustedProxies...); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// Initialize the application with config, cache, and global middleware
 	app := server.NewApplication().
 		WithConfig(appConfig).
-		WithCache().
-		Use(middleware.LoggerMiddleware, middleware.RecovererMiddleware, middleware.CorsMiddleware)
+		WithCache()
+
+	// Maintenance mode makes the API read-only, e.g. during deploys; admins
+	// switch it through /admin/maintenance, which stays writable along with
+	// login so it can always be turned back off
+	maintenance := middleware.NewMaintenance(cache.NewRedis(app.Cache), time.Minute)
+	handlers.SetMaintenance(maintenance)
+
+	app.Use(middleware.MetricsMiddleware, middleware.LoggerMiddleware, middleware.RecovererMiddleware, cors,
+		middleware.SecureHeaders(middleware.SecureHeadersOptions{}),
+		middleware.SkipPaths(maintenance.Middleware, maintenanceRoutes...),
+		middleware.ReadYourWritesMiddleware,
+		middleware.BodyLimit(int64(appConfig.MaxBodyBytes), uploadRoutes...),
+		middleware.TimeoutMiddleware(appConfig.RequestTimeout, slowUploadRoutes...)).
+		ExemptPaths("/health")
+
+	// Send email over SMTP when configured, otherwise just log it
+	var sender email.EmailSender = email.MockSender{}
+	if appConfig.SMTPHost != "" {
+		sender = email.NewSMTPSender(email.SMTPConfig{
+			Host:     appConfig.SMTPHost,
+			Port:     appConfig.SMTPPort,
+			Username: appConfig.SMTPUsername,
+			Password: appConfig.SMTPPassword,
+			UseTLS:   appConfig.SMTPUseTLS,
+		})
+	}
+	emailService, err := email.NewEmailService(sender, appConfig.EmailFrom, appConfig.EmailFromName)
+	if err != nil {
+		log.Fatalf("Failed to initialize email service: %v", err)
+	}
+	emailService.WithRetry(appConfig.EmailMaxAttempts, appConfig.EmailRetryDelay).
+		WithDeadLetters(app.Cache)
 
 	// Initialize services and capture the result
-	svcs := services.InitServices(app.Store, app.Cache, nil) // Email service is nil for now
+	svcs := services.InitServices(app.Store, app.Cache, emailService, services.CacheTTLs{
+		User:         appConfig.CacheTTLUser,
+		Team:         appConfig.CacheTTLTeam,
+		TeamMembers:  appConfig.CacheTTLTeamMembers,
+		TeamList:     appConfig.CacheTTLTeamList,
+		Project:      appConfig.CacheTTLProject,
+		ProjectList:  appConfig.CacheTTLProjectList,
+		ProjectStats: appConfig.CacheTTLProjectStats,
+		CommentList:  appConfig.CacheTTLComments,
+		Account:      appConfig.CacheTTLAccount,
+		ResetToken:   appConfig.ResetTokenTTL,
+		EmailChange:  appConfig.EmailChangeTTL,
+	})
+
+	// Slugs are kept on rename unless SLUGS_FOLLOW_NAMES is set
+	svcs.ProjectService.SetSlugsFollowNames(appConfig.SlugsFollowNames)
+	svcs.TeamService.SetSlugsFollowNames(appConfig.SlugsFollowNames)
 
 	// Initialize handlers with the services struct
 	handlers.Init(svcs)
 
+	// Avatars are stored on local disk and served by this process
+	uploadsURL, err := url.Parse(appConfig.UploadBaseURL)
+	if err != nil {
+		log.Fatalf("Invalid UPLOAD_BASE_URL: %v", err)
+	}
+	uploads, err := storage.NewLocalStorage(appConfig.UploadDir, appConfig.UploadBaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize upload storage: %v", err)
+	}
+	handlers.SetAvatarStorage(uploads, int64(appConfig.AvatarMaxBytes))
+
+	// Attachments are only served through the API, which checks project
+	// access, so they live outside the public upload directory
+	attachments, err := storage.NewLocalStorage(appConfig.AttachmentDir, "")
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+	svcs.SetAttachmentStorage(attachments, int64(appConfig.AttachmentMaxBytes))
+
 	// Create router group and set up routes
 	routes := router.NewRouter()
-	setupMainRoutes(routes, app.Store)
+	setupMainRoutes(routes, app, svcs)
+
+	// Operational endpoints skip the public middleware chain and listen on
+	// INTERNAL_ADDR, apart from the public API
+	internalRoutes := router.NewRouter()
+	setupInternalRoutes(internalRoutes)
 
 	// Register routes with the application
-	app.WithMux(routes)
+	app.WithMux(routes).
+		WithFiles(uploadsURL.Path, uploads.Handler()).
+		WithInternalMux(appConfig.InternalAddr, internalRoutes, middleware.RecovererMiddleware)
 
-	// Start the server
-	if err := app.Serve(); err != nil {
+	// Start the server, over HTTPS when there are domains to get certificates for
+	if len(appConfig.TLSDomains) > 0 {
+		err = app.WithAutoTLS(appConfig.TLSDomains...).
+			WithHTTPRedirect(appConfig.HTTPRedirectPort).
+			Serve()
+	} else {
+		err = app.Serve()
+	}
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -1,69 +1,297 @@
 package main
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/graphql"
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/app/server"
 	"github.com/Bethel-nz/tickit/handlers"
-	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/buildinfo"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/usage"
 )
 
 // setupRoutes configures all application routes
-func setupRoutes(r *router.RouterGroup, queries *store.Queries) {
-	ownershipMiddleware := middleware.NewOwnershipMiddleware(queries)
+func setupRoutes(r *router.RouterGroup, app *server.Application, svcs *services.Services) {
+	// Named so public routes inside authenticated groups can Skip("auth").
+	// Machine clients can authenticate with an API key instead of a token.
+	requireAuth := router.Named("auth", middleware.NewAuthMiddleware(svcs.TokenDenylist, svcs.UserService, svcs.APIKeyService))
+	ownershipMiddleware := middleware.NewOwnershipMiddleware(app.Store)
+	teamAdminMiddleware := middleware.NewTeamAdminMiddleware(app.Store)
+	issueAccessMiddleware := middleware.NewIssueAccessMiddleware(app.Store)
+	adminMiddleware := middleware.NewAdminMiddleware(app.Store)
+	// Login tokens have every scope; API keys need write to change anything
+	// and admin for the admin routes
+	requireWrite := middleware.RequireScope(auth.ScopeWrite)
+	requireAdmin := middleware.RequireScope(auth.ScopeAdmin)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(app.Cache, 24*time.Hour)
+
+	// Creation counters feed the top-creators report and throttle runaway clients
+	creationLimit := app.Config.CreationRateLimit
+	projectCreations := middleware.NewCreationRateMiddleware(svcs.UsageTracker, usage.KindProject, creationLimit)
+	issueCreations := middleware.NewCreationRateMiddleware(svcs.UsageTracker, usage.KindIssue, creationLimit)
+	commentCreations := middleware.NewCreationRateMiddleware(svcs.UsageTracker, usage.KindComment, creationLimit)
 
 	// User routes
 	users := r.Group("/users")
 
 	// Public endpoints
-	users.POST("/register", handlers.RegisterUser)
-	users.POST("/login", handlers.LoginUser)
-	users.POST("/forgot-password", handlers.ForgotPassword)
-	users.POST("/reset-password/{token}", handlers.ResetPassword)
+	users.POST("/register", handlers.RegisterUser).
+		Describe(router.RouteDoc{Summary: "Register a user", Request: handlers.RegisterRequest{}, Status: http.StatusCreated})
+	users.POST("/login", handlers.LoginUser).
+		Describe(router.RouteDoc{Summary: "Log in and get a token", Request: handlers.LoginRequest{}})
+	users.POST("/forgot-password", handlers.ForgotPassword).
+		Describe(router.RouteDoc{Summary: "Email a password reset link", Request: handlers.ForgotPasswordRequest{}})
+	users.POST("/reset-password/{token}", handlers.ResetPassword).
+		Describe(router.RouteDoc{Summary: "Reset a password with an emailed token", Request: handlers.ResetPasswordRequest{}})
+	users.POST("/confirm-email/{token}", handlers.ConfirmEmailChange).
+		Describe(router.RouteDoc{Summary: "Confirm an email change"})
 
 	// Protected endpoints requiring authentication
-	authenticated := users.Group("", middleware.AuthMiddleware)
-	authenticated.GET("/me", handlers.GetUserProfile)
-	authenticated.PUT("/me", handlers.UpdateUserProfile)
-	authenticated.POST("/change-password", handlers.ChangePassword)
-	authenticated.DELETE("/me", handlers.DeleteAccount)
+	authenticated := users.Group("", requireAuth)
+	authenticated.POST("/logout", handlers.LogoutUser, requireWrite).
+		Describe(router.RouteDoc{Summary: "Log out and revoke the token", Auth: true})
+	authenticated.GET("/me", handlers.GetUserProfile).
+		Describe(router.RouteDoc{Summary: "Get your profile", Auth: true, Response: services.UserProfile{}})
+	authenticated.PUT("/me", handlers.UpdateUserProfile, requireWrite).
+		Describe(router.RouteDoc{Summary: "Update your profile", Auth: true, Request: services.UserProfileUpdate{}})
+	authenticated.POST("/me/avatar", handlers.UploadAvatar, requireWrite).
+		Describe(router.RouteDoc{Summary: "Upload an avatar image", Auth: true})
+	authenticated.POST("/me/email", handlers.RequestEmailChange, requireWrite).
+		Describe(router.RouteDoc{Summary: "Request an email change", Auth: true, Status: http.StatusAccepted})
+	authenticated.POST("/change-password", handlers.ChangePassword, requireWrite).
+		Describe(router.RouteDoc{Summary: "Change your password", Auth: true})
+	authenticated.DELETE("/me", handlers.DeleteAccount, requireWrite).
+		Describe(router.RouteDoc{Summary: "Delete your account", Auth: true})
+	authenticated.GET("/me/export", handlers.ExportUserData).
+		Describe(router.RouteDoc{Summary: "Download all your data", Auth: true})
+	authenticated.GET("/me/tickets", handlers.ListAssignedTickets).
+		Describe(router.RouteDoc{Summary: "List tickets assigned to you across projects", Auth: true, Query: []string{"status"}, Response: []services.AssignedIssueInfo{}})
+	authenticated.GET("/me/tasks", handlers.ListAssignedTasks).
+		Describe(router.RouteDoc{Summary: "List tasks assigned to you across projects", Auth: true, Query: []string{"status", "sort"}, Response: []services.TaskInfo{}})
+	authenticated.GET("/me/api-keys", handlers.ListAPIKeys).
+		Describe(router.RouteDoc{Summary: "List your API keys", Auth: true, Response: []services.APIKeyInfo{}})
+	authenticated.POST("/me/api-keys", handlers.CreateAPIKey, requireWrite).
+		Describe(router.RouteDoc{Summary: "Mint an API key", Auth: true, Request: handlers.APIKeyRequest{}, Response: services.APIKeyInfo{}, Status: http.StatusCreated})
+	authenticated.DELETE("/me/api-keys/{id}", handlers.RevokeAPIKey, requireWrite).
+		Describe(router.RouteDoc{Summary: "Revoke an API key", Auth: true})
+	authenticated.GET("/{username}", handlers.GetPublicProfile).
+		Describe(router.RouteDoc{Summary: "Get a user's public profile", Auth: true, Response: services.PublicProfile{}})
+
+	// Notification inbox for the authenticated user
+	notifications := r.Group("/notifications", requireAuth)
+	notifications.GET("/", handlers.ListNotifications).
+		Describe(router.RouteDoc{Summary: "List your notifications", Auth: true, Query: []string{"limit", "offset"}})
+	notifications.GET("/unread-count", handlers.UnreadNotificationCount).
+		Describe(router.RouteDoc{Summary: "Count unread notifications", Auth: true})
+	notifications.POST("/{id}/read", handlers.MarkNotificationRead, requireWrite).
+		Describe(router.RouteDoc{Summary: "Mark a notification read", Auth: true})
 
 	// Search route - accessible to authenticated users
-	r.GET("/search", handlers.SearchEntities, middleware.AuthMiddleware)
+	r.GET("/search", handlers.SearchEntities, requireAuth).
+		Describe(router.RouteDoc{Summary: "Search projects, tickets and teams", Auth: true, Query: []string{"q"}})
+
+	// Read-only GraphQL, for fetching related resources in one request
+	r.POST("/graphql", graphql.Handler(svcs), requireAuth).
+		Describe(router.RouteDoc{Summary: "Run a read-only GraphQL query", Auth: true, Request: graphql.Request{}})
+
+	// Account management for admins
+	admin := r.Group("/admin", requireAuth, requireAdmin, adminMiddleware)
+	admin.GET("/users", handlers.AdminListUsers).
+		Describe(router.RouteDoc{Summary: "List all users", Auth: true, Query: []string{"q", "limit", "cursor"}, Response: []services.AdminUserInfo{}})
+	admin.POST("/users/{id}/disable", handlers.AdminDisableUser).
+		Describe(router.RouteDoc{Summary: "Disable a user's account", Auth: true})
+	// Abuse detection: who is creating the most resources
+	admin.GET("/usage/top-creators", handlers.TopCreators).
+		Describe(router.RouteDoc{Summary: "List the users creating the most resources", Auth: true, Query: []string{"kind", "hours", "limit"}, Response: []usage.CreatorStat{}})
+	// Read-only mode for deploys
+	admin.GET("/maintenance", handlers.GetMaintenance).
+		Describe(router.RouteDoc{Summary: "Report whether maintenance mode is on", Auth: true, Response: middleware.MaintenanceStatus{}})
+	admin.PUT("/maintenance", handlers.SetMaintenanceMode).
+		Describe(router.RouteDoc{Summary: "Turn maintenance mode on or off", Auth: true, Request: handlers.MaintenanceRequest{}, Response: middleware.MaintenanceStatus{}})
+
+	// Audit log, for admins and, scoped to their team, team admins
+	r.GET("/audit", handlers.ListAuditLog, requireAuth).
+		Describe(router.RouteDoc{Summary: "List recorded sensitive actions", Auth: true, Query: []string{"team_id", "action", "since", "until", "limit", "cursor"}, Response: []services.AuditEntry{}})
+
+	// Team routes
+	teams := r.Group("/teams", requireAuth)
+	teams.GET("/", handlers.ListTeams).
+		Describe(router.RouteDoc{Summary: "List your teams", Auth: true})
+	teams.POST("/", handlers.CreateTeam, requireWrite).
+		Describe(router.RouteDoc{Summary: "Create a team", Auth: true, Request: handlers.TeamRequest{}, Status: http.StatusCreated})
+	teams.GET("/{id}", handlers.GetTeam).
+		Describe(router.RouteDoc{Summary: "Get a team by ID or slug", Auth: true})
+	teams.PUT("/{id}", handlers.UpdateTeam, requireWrite, teamAdminMiddleware).
+		Describe(router.RouteDoc{Summary: "Update a team", Auth: true, Request: handlers.TeamRequest{}})
+	teams.DELETE("/{id}", handlers.DeleteTeam, requireWrite, teamAdminMiddleware).
+		Describe(router.RouteDoc{Summary: "Delete a team", Auth: true})
+	teams.GET("/{id}/export", handlers.ExportTeamData, teamAdminMiddleware).
+		Describe(router.RouteDoc{Summary: "Download a team's data", Auth: true})
+	teams.GET("/{id}/members", handlers.ListTeamMembers).
+		Describe(router.RouteDoc{Summary: "List team members", Auth: true})
+	teams.POST("/{id}/members", handlers.AddTeamMember, requireWrite, teamAdminMiddleware).
+		Describe(router.RouteDoc{Summary: "Add a team member or change their role", Auth: true, Request: handlers.TeamMemberRequest{}})
+	teams.PATCH("/{id}/members/{user_id}/role", handlers.UpdateTeamMemberRole, requireWrite, teamAdminMiddleware).
+		Describe(router.RouteDoc{Summary: "Change a team member's role", Auth: true, Request: handlers.TeamMemberRoleRequest{}})
+	// Members may remove themselves
+	teams.DELETE("/{id}/members/{user_id}", handlers.RemoveTeamMember, requireWrite).
+		Describe(router.RouteDoc{Summary: "Remove a team member", Auth: true, Query: []string{"reassign_to"}})
 
 	// Project routes
-	projects := r.Group("/projects", middleware.AuthMiddleware)
-	projects.GET("/", handlers.ListProjects)
-	projects.POST("/", handlers.CreateProject)
-	projects.GET("/{id}", handlers.GetProject)
-	projects.PUT("/{id}", handlers.UpdateProject, ownershipMiddleware)
-	projects.DELETE("/{id}", handlers.DeleteProject, ownershipMiddleware)
+	projects := r.Group("/projects", requireAuth)
+	projects.GET("/", handlers.ListProjects).
+		Describe(router.RouteDoc{Summary: "List your projects", Auth: true, Query: []string{"limit", "cursor", "status", "include_archived"}})
+	projects.POST("/", handlers.CreateProject, requireWrite, idempotencyMiddleware, projectCreations).
+		Describe(router.RouteDoc{Summary: "Create a project", Auth: true, Request: handlers.CreateProjectRequest{}, Status: http.StatusCreated})
+	projects.GET("/{id}", handlers.GetProject).
+		Describe(router.RouteDoc{Summary: "Get a project by ID or slug", Auth: true})
+	projects.PUT("/{id}", handlers.UpdateProject, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Update a project", Auth: true, Request: handlers.UpdateProjectRequest{}})
+	projects.DELETE("/{id}", handlers.DeleteProject, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Delete a project", Auth: true})
+	projects.POST("/{id}/archive", handlers.ArchiveProject, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Archive a project", Auth: true})
+	projects.DELETE("/{id}/archive", handlers.UnarchiveProject, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Unarchive a project", Auth: true})
+	projects.GET("/{id}/comments", handlers.ListProjectComments).
+		Describe(router.RouteDoc{Summary: "List comments across a project", Auth: true})
+	projects.GET("/{id}/webhooks", handlers.ListWebhooks, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "List a project's webhooks", Auth: true, Response: []services.WebhookInfo{}})
+	projects.POST("/{id}/webhooks", handlers.CreateWebhook, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Add a webhook to a project", Auth: true, Request: handlers.WebhookRequest{}, Response: services.WebhookInfo{}, Status: http.StatusCreated})
+	projects.PUT("/{id}/webhooks/{webhook_id}", handlers.UpdateWebhook, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Change a webhook", Auth: true, Request: handlers.WebhookRequest{}, Response: services.WebhookInfo{}})
+	projects.DELETE("/{id}/webhooks/{webhook_id}", handlers.DeleteWebhook, requireWrite, ownershipMiddleware).
+		Describe(router.RouteDoc{Summary: "Remove a webhook", Auth: true})
 
 	// Ticket routes
 	tickets := projects.Group("/{project_id}/tickets")
-	tickets.GET("/", handlers.ListTickets)
-	tickets.POST("/", handlers.CreateTicket)
-	tickets.GET("/{id}", handlers.GetTicket)
-	tickets.PUT("/{id}", handlers.UpdateTicket)
-	tickets.DELETE("/{id}", handlers.DeleteTicket)
-	tickets.POST("/{id}/assign", handlers.AssignTicket)
+	tickets.GET("/", handlers.ListTickets, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "List tickets in a project", Auth: true, Query: []string{"limit", "cursor", "status", "priority", "due_after", "due_before", "overdue", "sort"}})
+	tickets.POST("/", handlers.CreateTicket, requireWrite, issueAccessMiddleware, idempotencyMiddleware, issueCreations).
+		Describe(router.RouteDoc{Summary: "Create a ticket", Auth: true, Request: handlers.TicketRequest{}, Status: http.StatusCreated})
+	tickets.GET("/{id}", handlers.GetTicket, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Get a ticket", Auth: true, Response: services.IssueInfo{}})
+	tickets.PUT("/{id}", handlers.UpdateTicket, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Update a ticket", Auth: true, Request: handlers.TicketRequest{}})
+	tickets.DELETE("/{id}", handlers.DeleteTicket, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Delete a ticket", Auth: true})
+	tickets.POST("/{id}/assign", handlers.AssignTicket, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Assign a ticket", Auth: true})
+	tickets.GET("/{id}/labels", handlers.ListTicketLabels, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "List a ticket's labels", Auth: true})
+	tickets.PUT("/{id}/labels/{label_id}", handlers.AddTicketLabel, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Add a label to a ticket", Auth: true})
+	tickets.DELETE("/{id}/labels/{label_id}", handlers.RemoveTicketLabel, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Remove a label from a ticket", Auth: true})
+	tickets.GET("/{id}/watchers", handlers.ListTicketWatchers, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "List a ticket's watchers", Auth: true})
+	tickets.POST("/{id}/watch", handlers.WatchTicket, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Watch a ticket", Auth: true})
+	tickets.DELETE("/{id}/watch", handlers.UnwatchTicket, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Stop watching a ticket", Auth: true})
+	tickets.GET("/{id}/attachments", handlers.ListTicketAttachments, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "List a ticket's attachments", Auth: true})
+	tickets.POST("/{id}/attachments", handlers.UploadTicketAttachment, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Attach a file to a ticket", Auth: true, Response: services.AttachmentInfo{}, Status: http.StatusCreated})
+	tickets.GET("/{id}/attachments/{attachment_id}", handlers.DownloadTicketAttachment, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Download a ticket attachment", Auth: true})
+	tickets.DELETE("/{id}/attachments/{attachment_id}", handlers.DeleteTicketAttachment, requireWrite, issueAccessMiddleware).
+		Describe(router.RouteDoc{Summary: "Delete a ticket attachment", Auth: true})
+
+	// Label routes
+	labels := projects.Group("/{project_id}/labels", issueAccessMiddleware)
+	labels.GET("/", handlers.ListLabels).
+		Describe(router.RouteDoc{Summary: "List a project's labels", Auth: true})
+	labels.POST("/", handlers.CreateLabel, requireWrite).
+		Describe(router.RouteDoc{Summary: "Create a label", Auth: true, Request: handlers.LabelRequest{}, Response: services.LabelInfo{}, Status: http.StatusCreated})
+	labels.DELETE("/{label_id}", handlers.DeleteLabel, requireWrite).
+		Describe(router.RouteDoc{Summary: "Delete a label", Auth: true})
 
 	// Comments under tickets (issues)
 	comments := tickets.Group("/{ticket_id}/comments")
-	comments.GET("/", handlers.ListComments)
-	comments.POST("/", handlers.CreateComment)
-	comments.PUT("/{id}", handlers.UpdateComment)    // Ownership handled by service
-	comments.DELETE("/{id}", handlers.DeleteComment) // Ownership handled by service
+	comments.GET("/", handlers.ListComments).
+		Describe(router.RouteDoc{Summary: "List a ticket's comments", Auth: true, Response: []services.CommentInfo{}})
+	comments.POST("/", handlers.CreateComment, requireWrite, idempotencyMiddleware, commentCreations).
+		Describe(router.RouteDoc{Summary: "Comment on a ticket", Auth: true, Request: handlers.CreateCommentRequest{}, Status: http.StatusCreated})
+	// Ownership of edits and deletes is handled by the service
+	comments.PUT("/{id}", handlers.UpdateComment, requireWrite).
+		Describe(router.RouteDoc{Summary: "Edit a comment", Auth: true, Request: handlers.UpdateCommentRequest{}})
+	comments.DELETE("/{id}", handlers.DeleteComment, requireWrite).
+		Describe(router.RouteDoc{Summary: "Delete a comment", Auth: true})
+	comments.GET("/{id}/history", handlers.GetCommentHistory).
+		Describe(router.RouteDoc{Summary: "List a comment's earlier versions", Auth: true, Response: []services.CommentRevisionInfo{}})
+	comments.POST("/{id}/reactions", handlers.ToggleCommentReaction, requireWrite).
+		Describe(router.RouteDoc{Summary: "Toggle a reaction on a comment", Auth: true, Request: handlers.ReactionRequest{}})
 
 	// Optional: If you have a separate tasks endpoint
 	tasks := projects.Group("/{project_id}/tasks")
-	tasks.GET("/{task_id}/comments", handlers.ListComments)
-	tasks.POST("/{task_id}/comments", handlers.CreateComment)
+	tasks.DELETE("/{task_id}", handlers.DeleteTask, requireWrite).
+		Describe(router.RouteDoc{Summary: "Delete a task with its comments and logged time", Auth: true})
+	tasks.GET("/{task_id}/comments", handlers.ListComments).
+		Describe(router.RouteDoc{Summary: "List a task's comments", Auth: true, Response: []services.CommentInfo{}})
+	tasks.POST("/{task_id}/comments", handlers.CreateComment, requireWrite, idempotencyMiddleware, commentCreations).
+		Describe(router.RouteDoc{Summary: "Comment on a task", Auth: true, Request: handlers.CreateCommentRequest{}, Status: http.StatusCreated})
+	tasks.GET("/{task_id}/time", handlers.ListTaskTime).
+		Describe(router.RouteDoc{Summary: "List the time logged on a task", Auth: true, Response: []services.TimeEntryInfo{}})
+	tasks.POST("/{task_id}/time", handlers.LogTaskTime, requireWrite).
+		Describe(router.RouteDoc{Summary: "Log time spent on a task", Auth: true, Request: handlers.TimeEntryRequest{}, Response: services.TimeEntryInfo{}, Status: http.StatusCreated})
+	tasks.GET("/{task_id}/time/total", handlers.GetTaskTimeTotal).
+		Describe(router.RouteDoc{Summary: "Total the time logged on a task", Auth: true, Response: services.TaskTimeTotal{}})
 }
 
 // setupMainRoutes configures main application routes
-func setupMainRoutes(r *router.RouterGroup, queries *store.Queries) {
-	setupRoutes(r, queries)
+func setupMainRoutes(r *router.RouterGroup, app *server.Application, svcs *services.Services) {
+	setupRoutes(r, app, svcs)
 
 	// Add health check endpoint
+	r.GET("/health", handlers.HealthCheck).
+		Describe(router.RouteDoc{Summary: "Health check"})
+	r.GET("/version", handlers.Version).
+		Describe(router.RouteDoc{Summary: "Get the running build's commit and build time", Response: buildinfo.Info{}})
+
+	// Machine-readable API description, generated from the routes above
+	r.GET("/openapi.json", router.OpenAPIHandler(r, router.OpenAPIInfo{Title: "Tickit API", Version: "1.0"}))
+
+	// Route table for tracking down unexpected 404s
+	if app.Config.DebugMode {
+		r.GET("/_routes", func(c *router.Context) {
+			c.JSON(http.StatusOK, r.Routes())
+		})
+	}
+}
+
+// slowUploadRoutes accept uploads whose bodies can take longer than
+// REQUEST_TIMEOUT to read, so the timeout middleware lets them through.
+// Downloads don't need listing: flushing their headers lifts the deadline.
+var slowUploadRoutes = []string{
+	"/projects/{project_id}/tickets/{id}/attachments",
+}
+
+// uploadRoutes accept files under limits of their own (AVATAR_MAX_BYTES and
+// ATTACHMENT_MAX_BYTES), so the body limit middleware lets them through
+var uploadRoutes = []string{
+	"/users/me/avatar",
+	"/projects/{project_id}/tickets/{id}/attachments",
+}
+
+// maintenanceRoutes stay writable in maintenance mode, so an admin whose
+// token has expired can still log in and turn it back off
+var maintenanceRoutes = []string{
+	"/admin/maintenance",
+	"/users/login",
+}
+
+// setupInternalRoutes configures operational endpoints such as metrics. They
+// have no authentication and are served on INTERNAL_ADDR, apart from the
+// public API and its middleware chain (no CORS).
+func setupInternalRoutes(r *router.RouterGroup) {
+	// Prometheus metrics endpoint
+	r.GET("/metrics", handlers.Metrics)
 	r.GET("/health", handlers.HealthCheck)
 }
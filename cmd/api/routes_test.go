@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/app/server"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/types"
+)
+
+func TestAdminRoutesRequireAuth(t *testing.T) {
+	routes := router.NewRouter()
+	setupMainRoutes(routes, &server.Application{Config: &types.AppConfig{}}, &services.Services{})
+	mux := router.ServeMux(routes)
+
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/admin/users"},
+		{"GET", "/admin/usage/top-creators?kind=issue"},
+		{"GET", "/admin/maintenance"},
+		{"PUT", "/admin/maintenance"},
+	} {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(route.method, route.path, nil))
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 without a token, got %d (%s)", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMaintenanceRoutesStayWritable(t *testing.T) {
+	maintenance := middleware.NewMaintenance(cache.NewMemory(), time.Minute)
+	if err := maintenance.Enable(context.Background(), 0); err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	handler := middleware.SkipPaths(maintenance.Middleware, maintenanceRoutes...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, route := range []struct {
+		method, path string
+		status       int
+	}{
+		{"POST", "/users/login", http.StatusOK},
+		{"PUT", "/admin/maintenance", http.StatusOK},
+		{"POST", "/users/register", http.StatusServiceUnavailable},
+		{"POST", "/projects", http.StatusServiceUnavailable},
+	} {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(route.method, route.path, nil))
+			if rr.Code != route.status {
+				t.Errorf("Expected status %d in maintenance mode, got %d", route.status, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// adminService is retrieved from the application's dependency container
+var adminService *services.AdminService
+
+// SetAdminService sets the admin service for handlers
+func SetAdminService(service *services.AdminService) {
+	adminService = service
+}
+
+// AdminListUsers pages through every account, optionally filtered by ?q=
+func AdminListUsers(c *router.Context) {
+	if adminService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Admin service not initialized")
+		return
+	}
+
+	users, next, err := adminService.ListUsers(c.Request.Context(), c.Query("q"), cursorPage(c))
+	if err != nil {
+		handleAdminError(c, err)
+		return
+	}
+
+	c.List(users, router.CursorMeta(next))
+}
+
+// AdminDisableUser disables an account, logging the user out everywhere
+func AdminDisableUser(c *router.Context) {
+	if adminService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Admin service not initialized")
+		return
+	}
+	adminID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || adminID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+	userID, ok := idParam(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	if err := adminService.DisableUser(c.Request.Context(), adminID, userID); err != nil {
+		handleAdminError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{"message": "User disabled"})
+}
+
+// handleAdminError maps admin service errors to responses
+func handleAdminError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrUserNotFound):
+		c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+	case errors.Is(err, services.ErrCannotDisableSelf):
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "You cannot disable your own account")
+	case errors.Is(err, services.ErrInvalidCursor):
+		c.Error(http.StatusBadRequest, codeInvalidCursor, "Invalid cursor")
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
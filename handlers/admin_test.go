@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// accessDB keeps each user's admin flag and disabled time for GetUserAccess
+// and DisableUser, and answers everything else from the embedded DB
+type accessDB struct {
+	*storetest.DB
+	access map[string][]any
+}
+
+func (db *accessDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if storetest.QueryName(sql) != "GetUserAccess" {
+		return db.DB.QueryRow(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+	id := args[0].(pgtype.UUID)
+	return storetest.Row(db.access[id.String()])
+}
+
+func (db *accessDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if storetest.QueryName(sql) != "DisableUser" {
+		return db.DB.Exec(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+	id := args[0].(pgtype.UUID)
+	row, ok := db.access[id.String()]
+	if !ok {
+		return pgconn.NewCommandTag("UPDATE 0"), nil
+	}
+	db.access[id.String()] = []any{row[0], pgtype.Timestamp{Time: time.Now(), Valid: true}}
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func TestAdminUsers(t *testing.T) {
+	const (
+		admin  = "11111111-1111-1111-1111-111111111111"
+		member = "22222222-2222-2222-2222-222222222222"
+	)
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{Secret: "in-test-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	auth.SetDefaultTokenManager(tokens)
+
+	salt, hash, err := auth.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	active := pgtype.Text{String: "active", Valid: true}
+	db := &accessDB{
+		DB: &storetest.DB{
+			Rows: map[string][]any{
+				"GetUserByEmail": {
+					storetest.MustUUID(t, member), "ada@example.com", salt + ":" + hash, pgtype.Text{}, pgtype.Text{}, pgtype.Text{},
+					pgtype.Text{}, pgtype.Bool{}, pgtype.Timestamp{}, active, pgtype.Timestamp{}, pgtype.Timestamp{},
+					false, pgtype.Timestamp{},
+				},
+			},
+			Lists: map[string][][]any{
+				"ListUsersPage": {
+					{storetest.MustUUID(t, member), "ada@example.com", pgtype.Text{String: "Ada", Valid: true}, pgtype.Text{}, pgtype.Bool{}, active, false},
+					{storetest.MustUUID(t, admin), "root@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Bool{}, active, true},
+				},
+			},
+		},
+		access: map[string][]any{
+			admin:  {true, pgtype.Timestamp{}},
+			member: {false, pgtype.Timestamp{}},
+		},
+	}
+	queries := store.New(db)
+
+	memory := cache.NewMemory()
+	users := services.NewUserService(queries, memory, nil, services.CacheTTLs{})
+	prevAdmin, prevUser := adminService, userService
+	t.Cleanup(func() { adminService, userService = prevAdmin, prevUser })
+	SetAdminService(services.NewAdminService(queries, memory))
+	SetUserService(users)
+
+	rg := router.NewRouter()
+	requireAuth := middleware.NewAuthMiddleware(nil, users, nil)
+	rg.POST("/users/login", LoginUser)
+	rg.GET("/users/me", func(c *router.Context) { c.Status(http.StatusOK) }, requireAuth)
+	admins := rg.Group("/admin", requireAuth, middleware.NewAdminMiddleware(queries))
+	admins.GET("/users", AdminListUsers)
+	admins.POST("/users/{id}/disable", AdminDisableUser)
+	mux := router.ServeMux(rg)
+
+	token := func(userID string) string {
+		tok, err := tokens.Generate(userID)
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return tok
+	}
+	adminToken, memberToken := token(admin), token(member)
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		if rr := do("GET", "/admin/users", memberToken); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 listing users, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if rr := do("POST", "/admin/users/"+admin+"/disable", memberToken); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 disabling a user, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("DisableUser"); ok {
+			t.Error("Expected no account to be disabled")
+		}
+	})
+
+	t.Run("Admin lists users", func(t *testing.T) {
+		rr := do("GET", "/admin/users?q=+ada+&limit=5", adminToken)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		var body struct {
+			Users []services.AdminUserInfo `json:"data"`
+			Meta  router.ListMeta          `json:"meta"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		if len(body.Users) != 2 || body.Users[0].Email != "ada@example.com" || !body.Users[1].IsAdmin {
+			t.Errorf("Users = %+v, want Ada then the admin", body.Users)
+		}
+		if body.Meta.Page == nil || body.Meta.Page.NextCursor != nil {
+			t.Errorf("Page = %+v, want a null next cursor on the last page", body.Meta.Page)
+		}
+
+		args, _ := db.Called("ListUsersPage")
+		if limit := args[3].(int32); limit != 6 {
+			t.Errorf("Limit = %d, want 6 (one extra to find the next page)", limit)
+		}
+		if search := args[0].(pgtype.Text); search.String != "ada" || !search.Valid {
+			t.Errorf("Search = %+v, want ada", search)
+		}
+	})
+
+	t.Run("Admin disables a user", func(t *testing.T) {
+		if rr := do("GET", "/users/me", memberToken); rr.Code != http.StatusOK {
+			t.Fatalf("Expected the token to work before disabling, got %d", rr.Code)
+		}
+
+		if rr := do("POST", "/admin/users/"+member+"/disable", adminToken); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("DisableUser")
+		if !ok {
+			t.Fatal("Expected the account to be disabled")
+		}
+		if id := args[0].(pgtype.UUID); id.String() != member {
+			t.Errorf("Disabled %s, want %s", id.String(), member)
+		}
+
+		// Rejected at once, though the account was cached as active
+		if rr := do("GET", "/users/me", memberToken); rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), codeAccountDisabled) {
+			t.Errorf("Expected the disabled user's token to be refused with %s, got %d (%s)", codeAccountDisabled, rr.Code, rr.Body.String())
+		}
+		if rr := do("GET", "/users/me", adminToken); rr.Code != http.StatusOK {
+			t.Errorf("Expected other users' tokens to be unaffected, got %d", rr.Code)
+		}
+
+		db.Rows["GetUserByEmail"][13] = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		req := httptest.NewRequest("POST", "/users/login", strings.NewReader(`{"email":"ada@example.com","password":"correct-horse"}`))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), codeAccountDisabled) {
+			t.Errorf("Expected login to be refused with %s, got %d (%s)", codeAccountDisabled, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Admin cannot disable themselves", func(t *testing.T) {
+		if rr := do("POST", "/admin/users/"+admin+"/disable", adminToken); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		if rr := do("POST", "/admin/users/33333333-3333-3333-3333-333333333333/disable", adminToken); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+}
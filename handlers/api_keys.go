@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// apiKeyService is retrieved from the application's dependency container
+var apiKeyService *services.APIKeyService
+
+// SetAPIKeyService sets the API key service for handlers
+func SetAPIKeyService(service *services.APIKeyService) {
+	apiKeyService = service
+}
+
+// APIKeyRequest represents a request to mint an API key
+type APIKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`               // read, write and/or admin
+	ExpiresAt string   `json:"expires_at,omitempty"` // RFC3339 format; omit for a key that doesn't expire
+}
+
+// Validate checks the name, scopes and expiry
+func (r *APIKeyRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Name), "name", "must be provided")
+	v.CheckField(validator.MaxChars(r.Name, 100), "name", "cannot exceed 100 characters")
+	v.CheckField(len(r.Scopes) > 0, "scopes", "must grant at least one scope")
+	for _, scope := range r.Scopes {
+		if !validator.PermittedValue(scope, auth.Scopes...) {
+			v.AddFieldError("scopes", "unknown scope "+scope)
+		}
+	}
+	if r.ExpiresAt != "" {
+		v.CheckField(validator.IsValidDate(r.ExpiresAt, time.RFC3339), "expires_at", "must be an RFC3339 timestamp")
+	}
+}
+
+// apiKeyUser returns the user managing their API keys. Keys are managed with
+// a login token, so a leaked key can't be used to mint more or to keep
+// itself alive.
+func apiKeyUser(c *router.Context) (string, bool) {
+	if apiKeyService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "API key service not initialized")
+		return "", false
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return "", false
+	}
+	if _, ok := c.Request.Context().Value(middleware.APIKeyKey).(*auth.APIKey); ok {
+		c.Error(http.StatusForbidden, codeForbidden, "API keys can only be managed when logged in")
+		return "", false
+	}
+	return userID, true
+}
+
+// ListAPIKeys returns the user's API keys, newest first. The keys themselves
+// are not included.
+func ListAPIKeys(c *router.Context) {
+	userID, ok := apiKeyUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := apiKeyService.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		handleAPIKeyError(c, err)
+		return
+	}
+
+	c.List(keys, router.ListMeta{})
+}
+
+// CreateAPIKey mints an API key for the user. The response is the only time
+// the key is shown.
+func CreateAPIKey(c *router.Context) {
+	userID, ok := apiKeyUser(c)
+	if !ok {
+		return
+	}
+
+	var req APIKeyRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	input := services.APIKeyInput{Name: req.Name, Scopes: req.Scopes}
+	if req.ExpiresAt != "" {
+		// Already validated
+		input.ExpiresAt, _ = time.Parse(time.RFC3339, req.ExpiresAt)
+	}
+
+	key, err := apiKeyService.CreateAPIKey(c.Request.Context(), userID, input)
+	if err != nil {
+		handleAPIKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey stops one of the user's API keys from working
+func RevokeAPIKey(c *router.Context) {
+	userID, ok := apiKeyUser(c)
+	if !ok {
+		return
+	}
+
+	if err := apiKeyService.RevokeAPIKey(c.Request.Context(), c.Param("id"), userID); err != nil {
+		handleAPIKeyError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "API key revoked successfully")
+}
+
+func handleAPIKeyError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.Error(http.StatusNotFound, codeAPIKeyNotFound, "API key not found")
+	case errors.Is(err, services.ErrInvalidAPIKeyInput):
+		c.Error(http.StatusBadRequest, codeInvalidAPIKey, err.Error())
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
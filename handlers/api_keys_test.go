@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+func TestMintAPIKey(t *testing.T) {
+	const (
+		user = "11111111-1111-1111-1111-111111111111"
+		key  = "77777777-7777-7777-7777-777777777777"
+	)
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{Secret: "in-test-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	auth.SetDefaultTokenManager(tokens)
+	token, err := tokens.Generate(user)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Every key presented resolves to the user's write key
+	keyRow := []any{storetest.MustUUID(t, key), storetest.MustUUID(t, user), "CI", "hash", "tk_0123abcd", []string{auth.ScopeWrite}}
+	db := &storetest.DB{Rows: map[string][]any{"CreateAPIKey": keyRow, "GetAPIKeyByHash": keyRow}}
+	keys := services.NewAPIKeyService(store.New(db))
+	prev := apiKeyService
+	SetAPIKeyService(keys)
+	t.Cleanup(func() { apiKeyService = prev })
+
+	rg := router.NewRouter()
+	users := rg.Group("/users", middleware.NewAuthMiddleware(nil, nil, keys))
+	users.POST("/me/api-keys", CreateAPIKey)
+	users.DELETE("/me/api-keys/{id}", RevokeAPIKey)
+	mux := router.ServeMux(rg)
+
+	do := func(method, path, authorization, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+authorization)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("POST", "/users/me/api-keys", token, `{"name": "CI", "scopes": ["write"], "expires_at": "2999-01-01T00:00:00Z"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var minted services.APIKeyInfo
+	if err := json.NewDecoder(rr.Body).Decode(&minted); err != nil || !auth.IsAPIKey(minted.Key) {
+		t.Fatalf("Expected the new key in the response, got %+v (%v)", minted, err)
+	}
+	args, ok := db.Called("CreateAPIKey")
+	if !ok {
+		t.Fatal("Expected CreateAPIKey to be executed")
+	}
+	if hash := args[2]; hash != auth.HashAPIKey(minted.Key) {
+		t.Errorf("Stored %v, want the key's hash", hash)
+	}
+
+	t.Run("Keys can't manage keys", func(t *testing.T) {
+		for _, req := range []struct{ method, path, body string }{
+			{"POST", "/users/me/api-keys", `{"name": "Another", "scopes": ["write"]}`},
+			{"DELETE", "/users/me/api-keys/" + key, ""},
+		} {
+			rr := do(req.method, req.path, minted.Key, req.body)
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("%s %s with a key: expected status 403, got %d (%s)", req.method, req.path, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("Invalid requests are rejected", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name": "CI", "scopes": []}`,
+			`{"name": "CI", "scopes": ["owner"]}`,
+			`{"name": "", "scopes": ["read"]}`,
+			`{"name": "CI", "scopes": ["read"], "expires_at": "tomorrow"}`,
+		} {
+			if rr := do("POST", "/users/me/api-keys", token, body); rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected status 422, got %d (%s)", body, rr.Code, rr.Body.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// ListTicketAttachments returns the files attached to a ticket
+func ListTicketAttachments(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	attachments, err := issueService.ListAttachments(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.List(attachments, router.ListMeta{})
+}
+
+// UploadTicketAttachment attaches the file sent as the "file" field of a
+// multipart/form-data request to a ticket
+func UploadTicketAttachment(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	maxBytes := issueService.AttachmentMaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.ResponseWriter, c.Request.Body, maxBytes+multipartOverhead)
+	if err := c.Request.ParseMultipartForm(multipartOverhead); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.Error(http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("Attachment must be at most %d bytes", maxBytes))
+			return
+		}
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Expected multipart/form-data with a file")
+		return
+	}
+	defer c.Request.MultipartForm.RemoveAll()
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := issueService.AddAttachment(c.Request.Context(), c.Param("id"), userID, services.AttachmentUpload{
+		Filename: header.Filename,
+		Size:     header.Size,
+		Content:  file,
+	})
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadTicketAttachment sends an attachment's contents. Files are always
+// served as downloads so an uploaded page can't run in the API's origin.
+func DownloadTicketAttachment(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	attachment, content, err := issueService.OpenAttachment(c.Request.Context(), c.Param("id"), c.Param("attachment_id"), userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header().Set("Content-Type", attachment.ContentType)
+	c.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	c.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Header().Set("X-Content-Type-Options", "nosniff")
+	c.WriteHeader(http.StatusOK)
+	// Flushing the headers streams the file past REQUEST_TIMEOUT
+	http.NewResponseController(c.ResponseWriter).Flush()
+
+	if _, err := io.Copy(c, content); err != nil {
+		log.Printf("Failed to send attachment %s: %v", attachment.ID, err)
+	}
+}
+
+// DeleteTicketAttachment removes an attachment from a ticket
+func DeleteTicketAttachment(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.DeleteAttachment(c.Request.Context(), c.Param("id"), c.Param("attachment_id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Attachment deleted successfully")
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// auditService is retrieved from the application's dependency container
+var auditService *services.AuditService
+
+// SetAuditService sets the audit service for handlers
+func SetAuditService(service *services.AuditService) {
+	auditService = service
+}
+
+// ListAuditLog pages through recorded sensitive actions. Admins see every
+// entry; team owners and admins see their team's by passing ?team_id=. The
+// list can be narrowed with ?action= and a ?since= / ?until= time range.
+func ListAuditLog(c *router.Context) {
+	if auditService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Audit service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	filter := services.AuditFilter{TeamID: c.Query("team_id"), Action: c.Query("action")}
+	if filter.Since, ok = timeQuery(c, "since"); !ok {
+		return
+	}
+	if filter.Until, ok = timeQuery(c, "until"); !ok {
+		return
+	}
+
+	entries, next, err := auditService.List(c.Request.Context(), userID, filter, cursorPage(c))
+	if err != nil {
+		handleAuditError(c, err)
+		return
+	}
+
+	c.List(entries, router.CursorMeta(next))
+}
+
+func handleAuditError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrInsufficientRoles):
+		c.Error(http.StatusForbidden, codeForbidden, "Only admins and team admins can read the audit log")
+	case errors.Is(err, services.ErrNotTeamMember):
+		c.Error(http.StatusForbidden, codeNotTeamMember, "You are not a member of this team")
+	case errors.Is(err, services.ErrInvalidCursor):
+		c.Error(http.StatusBadRequest, codeInvalidCursor, "Invalid cursor")
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
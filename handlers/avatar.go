@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/storage"
+)
+
+// avatarStorage holds uploaded avatars; uploads are disabled while it is nil
+var avatarStorage storage.Storage
+
+// avatarMaxBytes is the largest avatar file accepted
+var avatarMaxBytes int64 = 2 << 20
+
+// multipartOverhead leaves room for form boundaries and headers around the file
+const multipartOverhead = 64 << 10
+
+// avatarTypes maps accepted image types, as sniffed from the file contents,
+// to the extension used when storing them
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SetAvatarStorage sets where avatars are stored and the maximum file size
+func SetAvatarStorage(s storage.Storage, maxBytes int64) {
+	avatarStorage = s
+	if maxBytes > 0 {
+		avatarMaxBytes = maxBytes
+	}
+}
+
+// UploadAvatar stores an image sent as the "avatar" field of a
+// multipart/form-data request and sets it as the user's avatar
+func UploadAvatar(c *router.Context) {
+	if userService == nil || avatarStorage == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Avatar uploads not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	tooLarge := fmt.Sprintf("Avatar must be at most %d bytes", avatarMaxBytes)
+
+	c.Request.Body = http.MaxBytesReader(c.ResponseWriter, c.Request.Body, avatarMaxBytes+multipartOverhead)
+	if err := c.Request.ParseMultipartForm(avatarMaxBytes); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.Error(http.StatusRequestEntityTooLarge, codePayloadTooLarge, tooLarge)
+			return
+		}
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Expected multipart/form-data with an avatar file")
+		return
+	}
+	defer c.Request.MultipartForm.RemoveAll()
+
+	file, header, err := c.Request.FormFile("avatar")
+	if err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Avatar file is required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > avatarMaxBytes {
+		c.Error(http.StatusRequestEntityTooLarge, codePayloadTooLarge, tooLarge)
+		return
+	}
+
+	// Trust the file contents rather than the client-supplied Content-Type
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Avatar file is empty")
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := avatarTypes[contentType]
+	if !ok {
+		c.Error(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Avatar must be a PNG, JPEG, GIF or WebP image")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to read avatar")
+		return
+	}
+
+	name := make([]byte, 8)
+	if _, err := rand.Read(name); err != nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to store avatar")
+		return
+	}
+	key := fmt.Sprintf("avatars/%s/%s%s", userID, hex.EncodeToString(name), ext)
+
+	url, err := avatarStorage.Put(c.Request.Context(), key, file, contentType)
+	if err != nil {
+		log.Printf("Failed to store avatar: %v", err)
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to store avatar")
+		return
+	}
+
+	if err := userService.UpdateAvatar(c.Request.Context(), userID, url); err != nil {
+		if delErr := avatarStorage.Delete(c.Request.Context(), key); delErr != nil {
+			log.Printf("Failed to remove orphaned avatar: %v", delErr)
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+			return
+		}
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to update avatar")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"avatar_url": url,
+	})
+}
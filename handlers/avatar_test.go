@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func multipartAvatar(t *testing.T, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile failed: %v", err)
+	}
+	part.Write(data)
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+func TestUploadAvatar(t *testing.T) {
+	const userID = "11111111-1111-1111-1111-111111111111"
+
+	memory := cache.NewMemory()
+	db := &storetest.DB{}
+	dir := t.TempDir()
+	files, err := storage.NewLocalStorage(dir, "/uploads")
+	if err != nil {
+		t.Fatalf("NewLocalStorage failed: %v", err)
+	}
+
+	prevUsers, prevStorage, prevMax := userService, avatarStorage, avatarMaxBytes
+	t.Cleanup(func() { userService, avatarStorage, avatarMaxBytes = prevUsers, prevStorage, prevMax })
+	SetUserService(services.NewUserService(store.New(db), memory, nil, services.CacheTTLs{}))
+	SetAvatarStorage(files, 1024)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	oversized := append(img.Bytes(), make([]byte, 2048)...)
+
+	rg := router.NewRouter()
+	rg.POST("/users/me/avatar", UploadAvatar)
+	mux := router.ServeMux(rg)
+
+	post := func(data []byte) *httptest.ResponseRecorder {
+		body, contentType := multipartAvatar(t, data)
+		req := httptest.NewRequest("POST", "/users/me/avatar", body)
+		req.Header.Set("Content-Type", contentType)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("PNG is stored and persisted", func(t *testing.T) {
+		rr := post(img.Bytes())
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+
+		var resp map[string]string
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		url := resp["avatar_url"]
+		if !strings.HasPrefix(url, "/uploads/avatars/"+userID+"/") || !strings.HasSuffix(url, ".png") {
+			t.Fatalf("Unexpected avatar URL %q", url)
+		}
+
+		stored, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(url, "/uploads/")))
+		if err != nil {
+			t.Fatalf("Avatar not written to storage: %v", err)
+		}
+		if !bytes.Equal(stored, img.Bytes()) {
+			t.Error("Stored avatar does not match upload")
+		}
+
+		if len(db.Args("UpdateUserAvatar")) != 1 {
+			t.Fatalf("Expected 1 update, got %d", len(db.Args("UpdateUserAvatar")))
+		}
+		if got := db.Args("UpdateUserAvatar")[0][1].(pgtype.Text); got.String != url || !got.Valid {
+			t.Errorf("Persisted avatar_url %+v, want %q", got, url)
+		}
+	})
+
+	t.Run("Oversized file is rejected", func(t *testing.T) {
+		db.Calls = nil
+		rr := post(oversized)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("Expected status 413, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if len(db.Args("UpdateUserAvatar")) != 0 {
+			t.Error("Oversized upload should not update the profile")
+		}
+	})
+
+	t.Run("Non-image is rejected", func(t *testing.T) {
+		db.Calls = nil
+		rr := post([]byte("just some text"))
+		if rr.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("Expected status 415, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if len(db.Args("UpdateUserAvatar")) != 0 {
+			t.Error("Rejected upload should not update the profile")
+		}
+	})
+}
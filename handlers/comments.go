@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/database/store"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -18,11 +20,29 @@ type CreateCommentRequest struct {
 	TaskID  string `json:"task_id,omitempty"` // Optional, one of task_id or issue_id must be provided
 }
 
+// Validate checks the comment fields
+func (r *CreateCommentRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Content), "content", "comment content is required")
+	if r.TaskID != "" {
+		v.CheckField(validator.IsUUID(r.TaskID), "task_id", "must be a valid UUID")
+	}
+}
+
 // UpdateCommentRequest represents the input for updating a comment
 type UpdateCommentRequest struct {
 	Content string `json:"content"`
 }
 
+// ReactionRequest represents a reaction toggle
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// Validate checks the reaction fields
+func (r *ReactionRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Emoji), "emoji", "emoji is required")
+}
+
 // searchService is retrieved from the application's dependency container
 var commentService *services.CommentService
 
@@ -34,7 +54,7 @@ func SetCommentService(service *services.CommentService) {
 // ListComments returns all comments for a specific issue or task
 func ListComments(c *router.Context) {
 	if commentService == nil {
-		c.Status(http.StatusInternalServerError, "Comment service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
 		return
 	}
 
@@ -42,71 +62,110 @@ func ListComments(c *router.Context) {
 	taskID := c.Param("task_id") // Optional task_id from route or query
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	var comments []services.CommentInfo
 	var err error
 	if issueID != "" {
+		if issueID, ok = idParam(c, "ticket_id", "ticket"); !ok {
+			return
+		}
 		comments, err = commentService.GetIssueComments(c.Request.Context(), issueID, userID)
 	} else if taskID != "" {
+		if taskID, ok = idParam(c, "task_id", "task"); !ok {
+			return
+		}
 		comments, err = commentService.GetTaskComments(c.Request.Context(), taskID, userID)
 	} else {
-		c.Status(http.StatusBadRequest, "Issue ID or Task ID is required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Issue ID or Task ID is required")
 		return
 	}
 
 	if err != nil {
-		c.Status(http.StatusInternalServerError, "Failed to retrieve comments")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to retrieve comments")
 		return
 	}
 
-	c.JSON(http.StatusOK, comments)
+	c.List(comments, router.ListMeta{})
+}
+
+// ListProjectComments returns recent comments across all issues and tasks of a project
+func ListProjectComments(c *router.Context) {
+	if commentService == nil || projectService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	ref, ok := refParam(c, "id", "project")
+	if !ok {
+		return
+	}
+	projectID, err := projectService.ResolveProjectID(c.Request.Context(), ref)
+	if err != nil {
+		handleProjectError(c, err)
+		return
+	}
+
+	var page services.Pagination
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		page.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil {
+		page.Offset = offset
+	}
+
+	comments, err := commentService.GetProjectComments(c.Request.Context(), projectID, userID, page)
+	if err != nil {
+		handleProjectError(c, err)
+		return
+	}
+
+	c.List(comments, router.ListMeta{})
 }
 
 // CreateComment creates a new comment on an issue or task
 func CreateComment(c *router.Context) {
 	if commentService == nil {
-		c.Status(http.StatusInternalServerError, "Comment service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
 		return
 	}
 
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	var req CreateCommentRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
-		return
-	}
-
-	if req.Content == "" {
-		c.Status(http.StatusBadRequest, "Comment content is required")
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
-	issueID := c.Param("ticket_id") // From route, if under /tickets
 	var scannedIssueID, scannedTaskID pgtype.UUID
-	if issueID != "" {
-		if err := scannedIssueID.Scan(issueID); err != nil {
-			c.Status(http.StatusBadRequest, "Invalid issue ID format")
+	if c.Param("ticket_id") != "" { // From route, if under /tickets
+		issueID, ok := idParam(c, "ticket_id", "ticket")
+		if !ok {
 			return
 		}
+		scannedIssueID.Scan(issueID) // Already checked by idParam
 	}
 	if req.TaskID != "" {
 		if err := scannedTaskID.Scan(req.TaskID); err != nil {
-			c.Status(http.StatusBadRequest, "Invalid task ID format")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid task ID format")
 			return
 		}
 	}
 
 	// Ensure exactly one of issueID or taskID is provided
 	if (scannedIssueID.Valid && scannedTaskID.Valid) || (!scannedIssueID.Valid && !scannedTaskID.Valid) {
-		c.Status(http.StatusBadRequest, "Exactly one of issue ID or task ID must be provided")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Exactly one of issue ID or task ID must be provided")
 		return
 	}
 
@@ -119,10 +178,10 @@ func CreateComment(c *router.Context) {
 	comment, err := commentService.CreateComment(c.Request.Context(), params, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidCommentData) {
-			c.Status(http.StatusBadRequest, err.Error())
+			c.Error(http.StatusBadRequest, codeInvalidComment, err.Error())
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to create comment")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to create comment")
 		return
 	}
 
@@ -139,38 +198,34 @@ func CreateComment(c *router.Context) {
 // UpdateComment updates an existing comment
 func UpdateComment(c *router.Context) {
 	if commentService == nil {
-		c.Status(http.StatusInternalServerError, "Comment service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
 		return
 	}
 
-	commentID := c.Param("id")
-	if commentID == "" {
-		c.Status(http.StatusBadRequest, "Comment ID is required")
+	commentID, ok := idParam(c, "id", "comment")
+	if !ok {
 		return
 	}
 
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	var req UpdateCommentRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	if req.Content == "" {
-		c.Status(http.StatusBadRequest, "Comment content is required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Comment content is required")
 		return
 	}
 
 	var scannedCommentID pgtype.UUID
-	if err := scannedCommentID.Scan(commentID); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid comment ID format")
-		return
-	}
+	scannedCommentID.Scan(commentID) // Already checked by idParam
 
 	params := store.UpdateCommentParams{
 		ID:      scannedCommentID,
@@ -179,14 +234,14 @@ func UpdateComment(c *router.Context) {
 
 	if err := commentService.UpdateComment(c.Request.Context(), params, userID); err != nil {
 		if errors.Is(err, services.ErrInvalidCommentData) {
-			c.Status(http.StatusBadRequest, err.Error())
+			c.Error(http.StatusBadRequest, codeInvalidComment, err.Error())
 			return
 		}
 		if errors.Is(err, services.ErrNotCommentAuthor) {
-			c.Status(http.StatusForbidden, "Only the comment author can update this comment")
+			c.Error(http.StatusForbidden, codeForbidden, "Only the comment author can update this comment")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to update comment")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to update comment")
 		return
 	}
 
@@ -198,30 +253,115 @@ func UpdateComment(c *router.Context) {
 // DeleteComment deletes an existing comment
 func DeleteComment(c *router.Context) {
 	if commentService == nil {
-		c.Status(http.StatusInternalServerError, "Comment service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
 		return
 	}
 
-	commentID := c.Param("id")
-	if commentID == "" {
-		c.Status(http.StatusBadRequest, "Comment ID is required")
+	commentID, ok := idParam(c, "id", "comment")
+	if !ok {
 		return
 	}
 
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	if err := commentService.DeleteComment(c.Request.Context(), commentID, userID); err != nil {
 		if errors.Is(err, services.ErrNotCommentAuthor) {
-			c.Status(http.StatusForbidden, "Only the comment author or project owner can delete this comment")
+			c.Error(http.StatusForbidden, codeForbidden, "Only the comment author or project owner can delete this comment")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to delete comment")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to delete comment")
 		return
 	}
 
 	c.Status(http.StatusOK, "Comment deleted successfully")
 }
+
+// GetCommentHistory lists the earlier versions of an edited comment
+func GetCommentHistory(c *router.Context) {
+	if commentService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	commentID, ok := idParam(c, "id", "comment")
+	if !ok {
+		return
+	}
+
+	history, err := commentService.GetCommentHistory(c.Request.Context(), commentID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidID):
+			c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+		case errors.Is(err, services.ErrInvalidCommentData):
+			c.Error(http.StatusBadRequest, codeInvalidComment, err.Error())
+		case errors.Is(err, services.ErrCommentNotFound):
+			c.Error(http.StatusNotFound, codeCommentNotFound, "Comment not found")
+		case errors.Is(err, services.ErrNotProjectOwner):
+			c.Error(http.StatusForbidden, codeForbidden, "You don't have permission to access this project")
+		default:
+			c.Error(http.StatusInternalServerError, codeInternal, "Failed to get comment history")
+		}
+		return
+	}
+
+	c.List(history, router.ListMeta{})
+}
+
+// ToggleCommentReaction adds the user's emoji reaction to a comment, or
+// removes it if they already reacted with it
+func ToggleCommentReaction(c *router.Context) {
+	if commentService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Comment service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	commentID, ok := idParam(c, "id", "comment")
+	if !ok {
+		return
+	}
+
+	var req ReactionRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	reacted, reactions, err := commentService.ToggleReaction(c.Request.Context(), commentID, userID, req.Emoji)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidID):
+			c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+		case errors.Is(err, services.ErrInvalidCommentData):
+			c.Error(http.StatusBadRequest, codeInvalidComment, err.Error())
+		case errors.Is(err, services.ErrCommentNotFound):
+			c.Error(http.StatusNotFound, codeCommentNotFound, "Comment not found")
+		case errors.Is(err, services.ErrNotProjectOwner):
+			c.Error(http.StatusForbidden, codeForbidden, "You don't have permission to access this project")
+		default:
+			c.Error(http.StatusInternalServerError, codeInternal, "Failed to update reaction")
+		}
+		return
+	}
+
+	if reactions == nil {
+		reactions = []services.ReactionCount{}
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"reacted":   reacted,
+		"reactions": reactions,
+	})
+}
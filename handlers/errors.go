@@ -0,0 +1,46 @@
+package handlers
+
+// Error codes sent in the "code" field of error responses. Clients match on
+// them, so a published code must never change meaning.
+const (
+	codeInternal        = "internal_error"
+	codeUnauthenticated = "unauthenticated"
+	codeInvalidRequest  = "invalid_request" // Malformed body, missing or invalid parameters
+	codeInvalidID       = "invalid_id"      // A path ID that isn't a UUID
+	codeForbidden       = "forbidden"
+	codeUnavailable     = "service_unavailable" // A dependency is down; retry later
+
+	codeUserNotFound         = "user_not_found"
+	codeTeamNotFound         = "team_not_found"
+	codeProjectNotFound      = "project_not_found"
+	codeTicketNotFound       = "ticket_not_found"
+	codeTaskNotFound         = "task_not_found"
+	codeLabelNotFound        = "label_not_found"
+	codeNotificationNotFound = "notification_not_found"
+	codeAttachmentNotFound   = "attachment_not_found"
+	codeCommentNotFound      = "comment_not_found"
+	codeWebhookNotFound      = "webhook_not_found"
+	codeAPIKeyNotFound       = "api_key_not_found"
+
+	codeInvalidProfile = "invalid_profile"
+	codeInvalidTeam    = "invalid_team"
+	codeInvalidProject = "invalid_project"
+	codeInvalidTicket  = "invalid_ticket"
+	codeInvalidComment = "invalid_comment"
+	codeInvalidWebhook = "invalid_webhook"
+	codeInvalidAPIKey  = "invalid_api_key"
+	codeInvalidCursor  = "invalid_cursor"
+	codeInvalidVersion = "invalid_version"
+	codeInvalidToken   = "invalid_token"
+
+	codeInvalidCredentials      = "invalid_credentials"
+	codeAccountDisabled         = "account_disabled"
+	codeNotTeamMember           = "not_team_member"
+	codeEmailTaken              = "email_taken"
+	codeLabelExists             = "label_exists"
+	codeVersionConflict         = "version_conflict"
+	codeInvalidStatusTransition = "invalid_status_transition"
+	codeActiveProjects          = "active_projects" // Account deletion needs confirming
+	codePayloadTooLarge         = "payload_too_large"
+	codeUnsupportedMediaType    = "unsupported_media_type"
+)
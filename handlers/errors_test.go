@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		handle func(*router.Context, error)
+		err    error
+		status int
+		code   string
+	}{
+		{handleProjectError, services.ErrProjectNotFound, http.StatusNotFound, "project_not_found"},
+		{handleProjectError, services.ErrNotProjectOwner, http.StatusForbidden, "forbidden"},
+		{handleProjectError, services.ErrNotTeamMember, http.StatusForbidden, "not_team_member"},
+		{handleProjectError, services.ErrInvalidProjectData, http.StatusBadRequest, "invalid_project"},
+		{handleProjectError, services.ErrInvalidStatusTransition, http.StatusConflict, "invalid_status_transition"},
+		{handleProjectError, services.ErrConcurrentModification, http.StatusConflict, "version_conflict"},
+		{handleProjectError, services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+		{handleProjectError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleIssueError, services.ErrIssueNotFound, http.StatusNotFound, "ticket_not_found"},
+		{handleIssueError, services.ErrProjectNotFound, http.StatusNotFound, "project_not_found"},
+		{handleIssueError, services.ErrNotProjectOwner, http.StatusForbidden, "forbidden"},
+		{handleIssueError, services.ErrNotTeamMember, http.StatusForbidden, "not_team_member"},
+		{handleIssueError, services.ErrInvalidIssueData, http.StatusBadRequest, "invalid_ticket"},
+		{handleIssueError, services.ErrLabelNotFound, http.StatusNotFound, "label_not_found"},
+		{handleIssueError, services.ErrDuplicateLabel, http.StatusConflict, "label_exists"},
+		{handleIssueError, services.ErrConcurrentModification, http.StatusConflict, "version_conflict"},
+		{handleIssueError, services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+		{handleIssueError, services.ErrAttachmentNotFound, http.StatusNotFound, "attachment_not_found"},
+		{handleIssueError, services.ErrAttachmentTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+		{handleIssueError, services.ErrUnsupportedAttachment, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{handleIssueError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleAdminError, services.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+		{handleAdminError, services.ErrCannotDisableSelf, http.StatusBadRequest, "invalid_request"},
+		{handleAdminError, services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+
+		{handleTeamError, services.ErrTeamNotFound, http.StatusNotFound, "team_not_found"},
+		{handleTeamError, services.ErrUnauthorized, http.StatusForbidden, "forbidden"},
+		{handleTeamError, services.ErrInsufficientRoles, http.StatusForbidden, "forbidden"},
+		{handleTeamError, services.ErrNotMember, http.StatusForbidden, "not_team_member"},
+		{handleTeamError, services.ErrNotTeamMember, http.StatusForbidden, "not_team_member"},
+		{handleTeamError, services.ErrInvalidTeamData, http.StatusBadRequest, "invalid_team"},
+		{handleTeamError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleTaskError, services.ErrTaskNotFound, http.StatusNotFound, "task_not_found"},
+		{handleTaskError, services.ErrNotProjectOwner, http.StatusForbidden, "forbidden"},
+		{handleTaskError, services.ErrNotTeamMember, http.StatusForbidden, "not_team_member"},
+		{handleTaskError, services.ErrInvalidTimeEntry, http.StatusBadRequest, "invalid_request"},
+		{handleTaskError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleWebhookError, services.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+		{handleWebhookError, services.ErrNotProjectOwner, http.StatusForbidden, "forbidden"},
+		{handleWebhookError, services.ErrInvalidWebhook, http.StatusBadRequest, "invalid_webhook"},
+		{handleWebhookError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleAPIKeyError, services.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+		{handleAPIKeyError, services.ErrInvalidAPIKeyInput, http.StatusBadRequest, "invalid_api_key"},
+		{handleAPIKeyError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleAuditError, services.ErrInsufficientRoles, http.StatusForbidden, "forbidden"},
+		{handleAuditError, services.ErrNotTeamMember, http.StatusForbidden, "not_team_member"},
+		{handleAuditError, services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+		{handleAuditError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+
+		{handleNotificationError, services.ErrNotificationNotFound, http.StatusNotFound, "notification_not_found"},
+		{handleNotificationError, errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			c := &router.Context{ResponseWriter: rr, Request: httptest.NewRequest("GET", "/", nil)}
+
+			// Services wrap their errors with detail
+			tt.handle(c, fmt.Errorf("%w: detail", tt.err))
+
+			if rr.Code != tt.status {
+				t.Errorf("Status = %d, want %d", rr.Code, tt.status)
+			}
+			var body router.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Invalid error body %q: %v", rr.Body.String(), err)
+			}
+			if body.Error.Code != tt.code {
+				t.Errorf("Code = %q, want %q", body.Error.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// exportService is retrieved from the application's dependency container
+var exportService *services.ExportService
+
+// SetExportService sets the export service for handlers
+func SetExportService(service *services.ExportService) {
+	exportService = service
+}
+
+// ExportUserData streams everything stored about the current user as a JSON
+// download
+func ExportUserData(c *router.Context) {
+	if exportService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Export service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	export, err := exportService.UserExport(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+			return
+		}
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to export user data")
+		return
+	}
+
+	writeExport(c, export, "tickit-user-export")
+}
+
+// ExportTeamData streams a team's members, projects, issues and comments as a
+// JSON download. Only team admins and owners may export a team.
+func ExportTeamData(c *router.Context) {
+	if exportService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Export service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	export, err := exportService.TeamExport(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleTeamError(c, err)
+		return
+	}
+
+	writeExport(c, export, "tickit-team-export")
+}
+
+// writeExport sends an export as an attachment. Once streaming has started
+// the status can't change, so a failure part way through is logged and the
+// truncated document is left for the client to reject as invalid JSON.
+func writeExport(c *router.Context, export *services.Export, name string) {
+	filename := fmt.Sprintf("%s-%s.json", name, time.Now().UTC().Format("2006-01-02"))
+	c.Header().Set("Content-Type", "application/json")
+	c.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header().Set("Cache-Control", "no-store")
+	c.WriteHeader(http.StatusOK)
+	// Flushing the headers streams the export past REQUEST_TIMEOUT
+	http.NewResponseController(c.ResponseWriter).Flush()
+
+	if err := export.Write(c.Request.Context(), c); err != nil {
+		log.Printf("Export %s failed part way through: %v", name, err)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"net/http"
 
 	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/buildinfo"
 	"github.com/Bethel-nz/tickit/internal/env"
 )
 
 func HealthCheck(c *router.Context) {
 	c.JSON(http.StatusOK, map[string]string{
 		"status":      "healthy",
-		"version":     "1.0.0",
+		"version":     buildinfo.Get().Commit,
 		"environment": env.String("Environment", "development", env.Optional).Get(),
 	})
 }
+
+// Version reports which build is running, as stamped at build time
+func Version(c *router.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/buildinfo"
+)
+
+func TestVersion(t *testing.T) {
+	rg := router.NewRouter()
+	rg.GET("/version", Version)
+	mux := router.ServeMux(rg)
+
+	get := func(t *testing.T) buildinfo.Info {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/version", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rr.Code)
+		}
+		var info buildinfo.Info
+		if err := json.NewDecoder(rr.Body).Decode(&info); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return info
+	}
+
+	t.Run("Stamped build", func(t *testing.T) {
+		commit, buildTime, goVersion := buildinfo.Commit, buildinfo.BuildTime, buildinfo.GoVersion
+		t.Cleanup(func() { buildinfo.Commit, buildinfo.BuildTime, buildinfo.GoVersion = commit, buildTime, goVersion })
+		buildinfo.Commit, buildinfo.BuildTime, buildinfo.GoVersion = "3f2c1ab", "2024-05-01T09:00:00Z", "go1.24.2"
+
+		want := buildinfo.Info{Commit: "3f2c1ab", BuildTime: "2024-05-01T09:00:00Z", GoVersion: "go1.24.2"}
+		if info := get(t); info != want {
+			t.Errorf("Version = %+v, want %+v", info, want)
+		}
+	})
+
+	t.Run("Local build", func(t *testing.T) {
+		want := buildinfo.Info{Commit: "dev", BuildTime: "dev", GoVersion: "dev"}
+		if info := get(t); info != want {
+			t.Errorf("Version = %+v, want %+v", info, want)
+		}
+	})
+}
@@ -7,7 +7,16 @@ func Init(s *services.Services) {
 	SetUserService(s.UserService)
 	SetProjectService(s.ProjectService)
 	SetIssueService(s.IssueService)
+	SetTaskService(s.TaskService)
 	SetCommentService(s.CommentService)
+	SetNotificationService(s.NotificationService)
 	SetSearchService(s.SearchService)
 	SetTeamService(s.TeamService)
+	SetExportService(s.ExportService)
+	SetAdminService(s.AdminService)
+	SetAuditService(s.AuditService)
+	SetWebhookService(s.WebhookService)
+	SetAPIKeyService(s.APIKeyService)
+	SetUsageTracker(s.UsageTracker)
+	SetTokenDenylist(s.TokenDenylist)
 }
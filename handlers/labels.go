@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// LabelRequest represents label creation input
+type LabelRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Validate checks the label fields
+func (r *LabelRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Name), "name", "label name is required")
+	v.CheckField(validator.MaxChars(r.Name, 50), "name", "cannot exceed 50 characters")
+}
+
+// ListLabels returns the labels defined in a project
+func ListLabels(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	labels, err := issueService.GetProjectLabels(c.Request.Context(), c.Param("project_id"), userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.List(labels, router.ListMeta{})
+}
+
+// CreateLabel creates a label in a project
+func CreateLabel(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var req LabelRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	label, err := issueService.CreateLabel(c.Request.Context(), c.Param("project_id"), req.Name, req.Color, userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// DeleteLabel deletes a label and removes it from every ticket
+func DeleteLabel(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.DeleteLabel(c.Request.Context(), c.Param("project_id"), c.Param("label_id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Label deleted successfully")
+}
+
+// ListTicketLabels returns the labels attached to a ticket
+func ListTicketLabels(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	labels, err := issueService.GetIssueLabels(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.List(labels, router.ListMeta{})
+}
+
+// AddTicketLabel attaches a label to a ticket
+func AddTicketLabel(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.AddLabel(c.Request.Context(), c.Param("id"), c.Param("label_id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Label added successfully")
+}
+
+// RemoveTicketLabel detaches a label from a ticket
+func RemoveTicketLabel(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.RemoveLabel(c.Request.Context(), c.Param("id"), c.Param("label_id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Label removed successfully")
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestTicketLabels(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+		label   = "77777777-7777-7777-7777-777777777777"
+		foreign = "88888888-8888-8888-8888-888888888888"
+	)
+
+	setup := func(labelProject string) *storetest.DB {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetProjectByID":   {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner)},
+				"GetProjectAccess": {storetest.MustUUID(t, owner)},
+				"GetIssueByID":     {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetLabelByID":     {storetest.MustUUID(t, label), storetest.MustUUID(t, labelProject), "bug"},
+			},
+			Lists: map[string][][]any{
+				"GetProjectIssuesWithAllLabels": {{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"}},
+				"GetProjectIssuesWithAnyLabel":  {{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"}},
+			},
+		}
+		queries := store.New(db)
+		memory := cache.NewMemory()
+		projects := services.NewProjectService(queries, memory, nil, services.CacheTTLs{})
+		SetIssueService(services.NewIssueService(queries, memory, projects))
+		return db
+	}
+	prev := issueService
+	t.Cleanup(func() { issueService = prev })
+
+	rg := router.NewRouter()
+	rg.GET("/projects/{project_id}/tickets", ListTickets)
+	rg.PUT("/projects/{project_id}/tickets/{id}/labels/{label_id}", AddTicketLabel)
+	mux := router.ServeMux(rg)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, owner))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Assign label", func(t *testing.T) {
+		db := setup(project)
+		rr := do("PUT", "/projects/"+project+"/tickets/"+issue+"/labels/"+label)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("AddIssueLabel")
+		if !ok {
+			t.Fatal("Expected AddIssueLabel to be executed")
+		}
+		if args[0] != storetest.MustUUID(t, issue) || args[1] != storetest.MustUUID(t, label) {
+			t.Errorf("AddIssueLabel args = %v", args)
+		}
+	})
+
+	t.Run("Label from another project is not found", func(t *testing.T) {
+		db := setup(foreign)
+		rr := do("PUT", "/projects/"+project+"/tickets/"+issue+"/labels/"+label)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("AddIssueLabel"); ok {
+			t.Error("AddIssueLabel should not run for a foreign label")
+		}
+	})
+
+	filters := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Match all by default", "labels=Bug,%20urgent,bug", "GetProjectIssuesWithAllLabels"},
+		{"Match any", "labels=bug,urgent&match=any", "GetProjectIssuesWithAnyLabel"},
+	}
+	for _, tt := range filters {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setup(project)
+			rr := do("GET", "/projects/"+project+"/tickets?"+tt.query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+
+			args, ok := db.Called(tt.want)
+			if !ok {
+				t.Fatalf("Expected %s to be queried", tt.want)
+			}
+			if names := args[1].([]string); !reflect.DeepEqual(names, []string{"bug", "urgent"}) {
+				t.Errorf("Label names = %q, want normalized [bug urgent]", names)
+			}
+
+			var body router.ListResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || body.Meta.Count != 1 {
+				t.Errorf("Expected one ticket, got %d (%v)", body.Meta.Count, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/usage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestListEnvelope(t *testing.T) {
+	const (
+		owner   = ticketOwner
+		project = ticketProject
+		issue   = ticketIssue
+		team    = "44444444-4444-4444-4444-444444444444"
+		task    = "99999999-9999-9999-9999-999999999999"
+		comment = "33333333-3333-3333-3333-333333333333"
+	)
+
+	// The user owns the project and is a member of its team, so every list
+	// is theirs to see; the lists themselves are empty
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectAccess":    {storetest.MustUUID(t, owner)},
+		"GetProjectByID":      {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner)},
+		"GetIssueByID":        {storetest.MustUUID(t, issue), storetest.MustUUID(t, project)},
+		"GetTaskByID":         {storetest.MustUUID(t, task), storetest.MustUUID(t, project)},
+		"GetCommentByID":      {storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, owner), storetest.MustUUID(t, issue)},
+		"GetUserAccess":       {true, pgtype.Timestamp{}},
+		"CheckTeamMembership": {true},
+	}}
+	queries := store.New(db)
+	memory := cache.NewMemory()
+	teams := services.NewTeamService(queries, memory, services.CacheTTLs{})
+	projects := services.NewProjectService(queries, memory, teams, services.CacheTTLs{})
+
+	prev := services.Services{
+		UserService: userService, ProjectService: projectService, IssueService: issueService,
+		TaskService: taskService, CommentService: commentService, NotificationService: notificationService,
+		SearchService: searchService, TeamService: teamService, ExportService: exportService,
+		AdminService: adminService, AuditService: auditService, WebhookService: webhookService,
+		APIKeyService: apiKeyService, UsageTracker: usageTracker, TokenDenylist: tokenDenylist,
+	}
+	t.Cleanup(func() { Init(&prev) })
+	Init(&services.Services{
+		ProjectService:      projects,
+		IssueService:        services.NewIssueService(queries, memory, projects),
+		TaskService:         services.NewTaskService(queries, projects),
+		CommentService:      services.NewCommentService(queries, memory, projects, services.CacheTTLs{}),
+		NotificationService: services.NewNotificationService(queries),
+		SearchService:       services.NewSearchService(queries, memory),
+		TeamService:         teams,
+		AdminService:        services.NewAdminService(queries, memory),
+		AuditService:        services.NewAuditService(queries),
+		WebhookService:      services.NewWebhookService(queries, projects),
+		APIKeyService:       services.NewAPIKeyService(queries),
+		UsageTracker:        usage.NewTracker(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})),
+	})
+
+	lists := []struct {
+		pattern string
+		handler func(*router.Context)
+		path    string
+	}{
+		{"/users/me/api-keys", ListAPIKeys, "/users/me/api-keys"},
+		{"/users/me/tickets", ListAssignedTickets, "/users/me/tickets"},
+		{"/users/me/tasks", ListAssignedTasks, "/users/me/tasks"},
+		{"/notifications", ListNotifications, "/notifications"},
+		{"/search", SearchEntities, "/search?q=crash"},
+		{"/admin/users", AdminListUsers, "/admin/users"},
+		{"/admin/usage/top-creators", TopCreators, "/admin/usage/top-creators?kind=issue"},
+		{"/audit", ListAuditLog, "/audit"},
+		{"/teams", ListTeams, "/teams"},
+		{"/teams/{id}/members", ListTeamMembers, "/teams/" + team + "/members"},
+		{"/projects", ListProjects, "/projects"},
+		{"/projects/{id}/comments", ListProjectComments, "/projects/" + project + "/comments"},
+		{"/projects/{id}/webhooks", ListWebhooks, "/projects/" + project + "/webhooks"},
+		{"/projects/{project_id}/labels", ListLabels, "/projects/" + project + "/labels"},
+		{"/projects/{project_id}/tickets", ListTickets, "/projects/" + project + "/tickets"},
+		{"/projects/{project_id}/tickets/{id}/labels", ListTicketLabels, "/projects/" + project + "/tickets/" + issue + "/labels"},
+		{"/projects/{project_id}/tickets/{id}/watchers", ListTicketWatchers, "/projects/" + project + "/tickets/" + issue + "/watchers"},
+		{"/projects/{project_id}/tickets/{id}/attachments", ListTicketAttachments, "/projects/" + project + "/tickets/" + issue + "/attachments"},
+		{"/projects/{project_id}/tickets/{ticket_id}/comments", ListComments, "/projects/" + project + "/tickets/" + issue + "/comments"},
+		{"/projects/{project_id}/tickets/{ticket_id}/comments/{id}/history", GetCommentHistory, "/projects/" + project + "/tickets/" + issue + "/comments/" + comment + "/history"},
+		{"/projects/{project_id}/tasks/{task_id}/time", ListTaskTime, "/projects/" + project + "/tasks/" + task + "/time"},
+	}
+
+	rg := router.NewRouter()
+	for _, list := range lists {
+		rg.GET(list.pattern, list.handler)
+	}
+	mux := router.ServeMux(rg)
+
+	for _, list := range lists {
+		t.Run(list.pattern, func(t *testing.T) {
+			req := httptest.NewRequest("GET", list.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, owner))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+			var meta router.ListMeta
+			if len(body) != 2 || string(body["data"]) != "[]" || json.Unmarshal(body["meta"], &meta) != nil || meta.Count != 0 {
+				t.Errorf(`Expected {"data": [], "meta": {"count": 0, ...}}, got %s`, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// maintenance is retrieved from the application's dependency container
+var maintenance *middleware.Maintenance
+
+// SetMaintenance sets the maintenance mode switch for handlers
+func SetMaintenance(m *middleware.Maintenance) {
+	maintenance = m
+}
+
+// MaintenanceRequest turns maintenance mode on or off. Duration, e.g. "30m",
+// turns it back off automatically; without one it stays on until turned off.
+type MaintenanceRequest struct {
+	Enabled  bool   `json:"enabled"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Validate checks the duration
+func (r *MaintenanceRequest) Validate(v *validator.Validator) {
+	if r.Duration != "" {
+		d, err := time.ParseDuration(r.Duration)
+		v.CheckField(err == nil && d > 0, "duration", "must be a positive duration, e.g. 30m")
+	}
+}
+
+// GetMaintenance reports whether the API is in maintenance mode
+func GetMaintenance(c *router.Context) {
+	if maintenance == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Maintenance mode not initialized")
+		return
+	}
+
+	status, err := maintenance.Status(c.Request.Context())
+	if err != nil {
+		c.Error(http.StatusServiceUnavailable, codeUnavailable, "Failed to read maintenance mode")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SetMaintenanceMode turns maintenance mode on or off for every instance
+func SetMaintenanceMode(c *router.Context) {
+	if maintenance == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Maintenance mode not initialized")
+		return
+	}
+
+	var req MaintenanceRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	var err error
+	if req.Enabled {
+		d, _ := time.ParseDuration(req.Duration) // Validated; 0 when empty
+		err = maintenance.Enable(c.Request.Context(), d)
+	} else {
+		err = maintenance.Disable(c.Request.Context())
+	}
+	if err != nil {
+		c.Error(http.StatusServiceUnavailable, codeUnavailable, "Failed to change maintenance mode")
+		return
+	}
+
+	GetMaintenance(c)
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsHandler = promhttp.Handler()
+
+// Metrics exposes Prometheus metrics for scraping
+func Metrics(c *router.Context) {
+	metricsHandler.ServeHTTP(c.ResponseWriter, c.Request)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// notificationService is retrieved from the application's dependency container
+var notificationService *services.NotificationService
+
+// SetNotificationService sets the notification service for handlers
+func SetNotificationService(service *services.NotificationService) {
+	notificationService = service
+}
+
+// ListNotifications returns the authenticated user's notifications, newest first
+func ListNotifications(c *router.Context) {
+	if notificationService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Notification service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var page services.Pagination
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		page.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil {
+		page.Offset = offset
+	}
+
+	notifications, err := notificationService.ListNotifications(c.Request.Context(), userID, page)
+	if err != nil {
+		handleNotificationError(c, err)
+		return
+	}
+
+	c.List(notifications, router.ListMeta{})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func MarkNotificationRead(c *router.Context) {
+	if notificationService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Notification service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := notificationService.MarkRead(c.Request.Context(), c.Param("id"), userID); err != nil {
+		handleNotificationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Notification marked as read")
+}
+
+// UnreadNotificationCount returns how many notifications the user has not read
+func UnreadNotificationCount(c *router.Context) {
+	if notificationService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Notification service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	count, err := notificationService.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		handleNotificationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]int64{
+		"unread": count,
+	})
+}
+
+func handleNotificationError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrNotificationNotFound):
+		c.Error(http.StatusNotFound, codeNotificationNotFound, "Notification not found")
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
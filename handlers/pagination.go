@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+// cursorPage reads keyset pagination options from the ?limit= and ?cursor=
+// query parameters
+func cursorPage(c *router.Context) services.CursorPage {
+	page := services.CursorPage{After: c.Query("cursor")}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		page.Limit = limit
+	}
+	return page
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/slug"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// idParam reads the UUID path parameter key. When it isn't a UUID it sends a
+// 400 naming the kind of ID, e.g. "Invalid project ID", and returns false.
+func idParam(c *router.Context, key, name string) (string, bool) {
+	id := c.Param(key)
+	if !validator.IsUUID(id) {
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid "+name+" ID")
+		return "", false
+	}
+	return id, true
+}
+
+// refParam reads the path parameter key, which may be a UUID or a slug.
+// When it is neither it sends a 400 like idParam and returns false.
+func refParam(c *router.Context, key, name string) (string, bool) {
+	ref := c.Param(key)
+	if !validator.IsUUID(ref) && !slug.Valid(ref) {
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid "+name+" ID")
+		return "", false
+	}
+	return ref, true
+}
+
+// timeQuery reads an optional time from the named query parameter, given as
+// RFC 3339 or as a date meaning midnight UTC. It sends a 400 and returns
+// false for anything else.
+func timeQuery(c *router.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	c.Error(http.StatusBadRequest, codeInvalidRequest, name+" must be a date (2006-01-02) or an RFC 3339 time")
+	return time.Time{}, false
+}
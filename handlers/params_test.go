@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+func TestMalformedIDs(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		ticket  = "66666666-6666-6666-6666-666666666666"
+	)
+
+	prevProjects, prevIssues, prevTeams, prevComments := projectService, issueService, teamService, commentService
+	t.Cleanup(func() {
+		projectService, issueService, teamService, commentService = prevProjects, prevIssues, prevTeams, prevComments
+	})
+	db := &storetest.DB{}
+	queries := store.New(db)
+	memory := cache.NewMemory()
+	teams := services.NewTeamService(queries, memory, services.CacheTTLs{})
+	projects := services.NewProjectService(queries, memory, teams, services.CacheTTLs{})
+	SetTeamService(teams)
+	SetProjectService(projects)
+	SetIssueService(services.NewIssueService(queries, memory, projects))
+	SetCommentService(services.NewCommentService(queries, memory, projects, services.CacheTTLs{}))
+
+	rg := router.NewRouter()
+	rg.GET("/projects/{id}", GetProject)
+	rg.DELETE("/projects/{id}", DeleteProject)
+	rg.GET("/projects/{project_id}/tickets/", ListTickets)
+	rg.GET("/projects/{project_id}/tickets/{id}", GetTicket)
+	rg.GET("/projects/{project_id}/tickets/{id}/watchers", ListTicketWatchers)
+	rg.GET("/teams/{id}", GetTeam)
+	rg.DELETE("/teams/{id}/members/{user_id}", RemoveTeamMember)
+	rg.GET("/projects/{project_id}/tickets/{ticket_id}/comments/", ListComments)
+	rg.PUT("/projects/{project_id}/tickets/{ticket_id}/comments/{id}", UpdateComment)
+	rg.GET("/projects/{project_id}/tickets/{ticket_id}/comments/{id}/history", GetCommentHistory)
+	rg.POST("/projects/{project_id}/tickets/{ticket_id}/comments/{id}/reactions", ToggleCommentReaction)
+	mux := router.ServeMux(rg)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		message string
+	}{
+		{"Project", "GET", "/projects/Not_A_UUID", "Invalid project ID"},
+		{"Project delete", "DELETE", "/projects/42", "Invalid project ID"},
+		{"Ticket list", "GET", "/projects/not-a-uuid/tickets/", "Invalid project ID"},
+		{"Ticket", "GET", "/projects/" + project + "/tickets/not-a-uuid", "Invalid ticket ID"},
+		{"Ticket watchers", "GET", "/projects/" + project + "/tickets/not-a-uuid/watchers", "Invalid ID"},
+		{"Team", "GET", "/teams/Not_A_UUID", "Invalid team ID"},
+		{"Team member", "DELETE", "/teams/" + project + "/members/not-a-uuid", "Invalid member ID"},
+		{"Ticket comments", "GET", "/projects/" + project + "/tickets/not-a-uuid/comments/", "Invalid ticket ID"},
+		{"Comment", "PUT", "/projects/" + project + "/tickets/" + ticket + "/comments/not-a-uuid", "Invalid comment ID"},
+		{"Comment history", "GET", "/projects/" + project + "/tickets/" + ticket + "/comments/1234/history", "Invalid comment ID"},
+		{"Comment reaction", "POST", "/projects/" + project + "/tickets/" + ticket + "/comments/1234/reactions", "Invalid comment ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Calls = nil
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"content": "Edited"}`))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, owner))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			var body router.ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if body.Error.Code != codeInvalidID || body.Error.Message != tt.message {
+				t.Errorf("Error = %+v, want %s %q", body.Error, codeInvalidID, tt.message)
+			}
+			if len(db.Calls) != 0 {
+				t.Errorf("Expected no queries, got %v", db.Calls)
+			}
+		})
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/database/store"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -27,22 +30,32 @@ type CreateProjectRequest struct {
 	TeamID      string `json:"team_id,omitempty"`
 }
 
+// Validate checks the project creation fields
+func (r *CreateProjectRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Name), "name", "project name is required")
+	v.CheckField(validator.MaxChars(r.Name, 255), "name", "cannot exceed 255 characters")
+	if r.TeamID != "" {
+		v.CheckField(validator.IsUUID(r.TeamID), "team_id", "must be a valid UUID")
+	}
+}
+
 // UpdateProjectRequest represents project update input
 type UpdateProjectRequest struct {
 	Name        string `json:"name,omitempty"`
 	Description string `json:"description,omitempty"`
 	Status      string `json:"status,omitempty"`
+	Version     int32  `json:"version,omitempty"` // Expected version, see If-Match
 }
 
 // ListProjects returns all projects accessible to the authenticated user
 func ListProjects(c *router.Context) {
 	if projectService == nil {
-		c.Status(http.StatusInternalServerError, "Project service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
@@ -52,6 +65,7 @@ func ListProjects(c *router.Context) {
 
 	// Get projects for the user
 	var projects []services.ProjectInfo
+	var next string
 	var err error
 
 	if teamID != "" {
@@ -69,41 +83,32 @@ func ListProjects(c *router.Context) {
 			return
 		}
 	} else {
-		// Get all user projects
-		projects, err = projectService.GetUserProjects(c.Request.Context(), userID)
+		// Page through the user's and their teams' projects, skipping archived ones unless asked
+		includeArchived, _ := strconv.ParseBool(c.Query("include_archived"))
+		projects, next, err = projectService.GetUserProjectsPage(c.Request.Context(), userID, cursorPage(c), includeArchived)
 		if err != nil {
 			handleProjectError(c, err)
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"projects": projects,
-		"count":    len(projects),
-	})
+	c.List(projects, router.CursorMeta(next))
 }
 
 // CreateProject creates a new project
 func CreateProject(c *router.Context) {
 	if projectService == nil {
-		c.Status(http.StatusInternalServerError, "Project service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	var req CreateProjectRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
-		return
-	}
-
-	// Validate project name
-	if req.Name == "" {
-		c.Status(http.StatusBadRequest, "Project name is required")
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
@@ -116,7 +121,7 @@ func CreateProject(c *router.Context) {
 	if req.TeamID != "" {
 		var teamUUID pgtype.UUID
 		if err := teamUUID.Scan(req.TeamID); err != nil {
-			c.Status(http.StatusBadRequest, "Invalid team ID format")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid team ID format")
 			return
 		}
 		params.TeamID = pgtype.UUID{Bytes: teamUUID.Bytes, Valid: true}
@@ -131,22 +136,26 @@ func CreateProject(c *router.Context) {
 	c.JSON(http.StatusCreated, project)
 }
 
-// GetProject returns a specific project by ID
+// GetProject returns a specific project by ID or slug
 func GetProject(c *router.Context) {
 	if projectService == nil {
-		c.Status(http.StatusInternalServerError, "Project service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	// Get project ID from URL
-	projectID := c.Param("id")
-	if projectID == "" {
-		c.Status(http.StatusBadRequest, "Project ID is required")
+	// The project may be named by its ID or its slug
+	ref, ok := refParam(c, "id", "project")
+	if !ok {
+		return
+	}
+	projectID, err := projectService.ResolveProjectID(c.Request.Context(), ref)
+	if err != nil {
+		handleProjectError(c, err)
 		return
 	}
 
@@ -157,32 +166,41 @@ func GetProject(c *router.Context) {
 		return
 	}
 
+	if c.CheckNotModified(versionETag(project.ID.String(), project.UpdatedAt.Time.Format(time.RFC3339Nano), project.Version)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, project)
 }
 
 // UpdateProject updates a project's details
 func UpdateProject(c *router.Context) {
 	if projectService == nil {
-		c.Status(http.StatusInternalServerError, "Project service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	// Get project ID from URL
-	projectID := c.Param("id")
-	if projectID == "" {
-		c.Status(http.StatusBadRequest, "Project ID is required")
+	projectID, ok := idParam(c, "id", "project")
+	if !ok {
 		return
 	}
 
 	// Parse update request
 	var req UpdateProjectRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidVersion, "If-Match must be a project version")
 		return
 	}
 
@@ -191,6 +209,7 @@ func UpdateProject(c *router.Context) {
 		Name:        req.Name,
 		Description: req.Description,
 		Status:      req.Status,
+		Version:     version,
 	}
 
 	// Update project
@@ -215,19 +234,18 @@ func UpdateProject(c *router.Context) {
 // DeleteProject deletes a project
 func DeleteProject(c *router.Context) {
 	if projectService == nil {
-		c.Status(http.StatusInternalServerError, "Project service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	// Get project ID from URL
-	projectID := c.Param("id")
-	if projectID == "" {
-		c.Status(http.StatusBadRequest, "Project ID is required")
+	projectID, ok := idParam(c, "id", "project")
+	if !ok {
 		return
 	}
 
@@ -240,16 +258,73 @@ func DeleteProject(c *router.Context) {
 	c.Status(http.StatusOK, "Project deleted successfully")
 }
 
+// ArchiveProject hides a finished project from default listings
+func ArchiveProject(c *router.Context) {
+	setProjectArchived(c, true)
+}
+
+// UnarchiveProject restores an archived project to its earlier status
+func UnarchiveProject(c *router.Context) {
+	setProjectArchived(c, false)
+}
+
+func setProjectArchived(c *router.Context, archived bool) {
+	if projectService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Project service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	projectID, ok := idParam(c, "id", "project")
+	if !ok {
+		return
+	}
+
+	change, message := projectService.UnarchiveProject, "Project unarchived successfully"
+	if archived {
+		change, message = projectService.ArchiveProject, "Project archived successfully"
+	}
+	if err := change(c.Request.Context(), projectID, userID); err != nil {
+		handleProjectError(c, err)
+		return
+	}
+
+	project, err := projectService.GetProjectByID(c.Request.Context(), projectID, userID)
+	if err != nil {
+		handleProjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"message": message,
+		"project": project,
+	})
+}
+
 // Helper function to handle project errors
 func handleProjectError(c *router.Context, err error) {
 	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
 	case errors.Is(err, services.ErrProjectNotFound):
-		c.Status(http.StatusNotFound, "Project not found")
+		c.Error(http.StatusNotFound, codeProjectNotFound, "Project not found")
 	case errors.Is(err, services.ErrNotProjectOwner):
-		c.Status(http.StatusForbidden, "You don't have permission to access this project")
+		c.Error(http.StatusForbidden, codeForbidden, "You don't have permission to access this project")
+	case errors.Is(err, services.ErrNotTeamMember):
+		c.Error(http.StatusForbidden, codeNotTeamMember, "You are not a member of this team")
 	case errors.Is(err, services.ErrInvalidProjectData):
-		c.Status(http.StatusBadRequest, "Invalid project data")
+		c.Error(http.StatusBadRequest, codeInvalidProject, "Invalid project data")
+	case errors.Is(err, services.ErrInvalidStatusTransition):
+		c.Error(http.StatusConflict, codeInvalidStatusTransition, err.Error())
+	case errors.Is(err, services.ErrConcurrentModification):
+		c.Error(http.StatusConflict, codeVersionConflict, "Project was modified by someone else; reload and try again")
+	case errors.Is(err, services.ErrInvalidCursor):
+		c.Error(http.StatusBadRequest, codeInvalidCursor, "Invalid cursor")
 	default:
-		c.Status(http.StatusInternalServerError, "An error occurred processing your request")
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
 	}
 }
@@ -21,18 +21,18 @@ func SetSearchService(service *services.SearchService) {
 // SearchEntities performs a search across multiple entity types
 func SearchEntities(c *router.Context) {
 	if searchService == nil {
-		c.Status(http.StatusInternalServerError, "Search service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Search service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	query := c.Query("q")
 	if query == "" {
-		c.Status(http.StatusBadRequest, "Search query is required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Search query is required")
 		return
 	}
 
@@ -48,16 +48,12 @@ func SearchEntities(c *router.Context) {
 	results, err := searchService.SearchEntities(c.Request.Context(), userID, query, limit)
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidSearchQuery) {
-			c.Status(http.StatusBadRequest, "Invalid search query")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid search query")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to perform search")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to perform search")
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"results": results,
-		"count":   len(results),
-		"query":   query,
-	})
+	c.List(results, router.ListMeta{Query: query})
 }
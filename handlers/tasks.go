@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// taskService is retrieved from the application's dependency container
+var taskService *services.TaskService
+
+// SetTaskService sets the task service for handlers
+func SetTaskService(service *services.TaskService) {
+	taskService = service
+}
+
+// TimeEntryRequest represents time logged on a task
+type TimeEntryRequest struct {
+	Minutes int    `json:"minutes"`
+	Note    string `json:"note,omitempty"`
+}
+
+// Validate checks the minutes and note
+func (r *TimeEntryRequest) Validate(v *validator.Validator) {
+	v.CheckField(r.Minutes > 0, "minutes", "must be a positive number of minutes")
+	v.CheckField(r.Minutes <= 24*60, "minutes", "cannot exceed a day (1440 minutes)")
+	v.CheckField(validator.MaxChars(r.Note, 500), "note", "cannot exceed 500 characters")
+}
+
+// ListAssignedTasks returns the tasks assigned to the current user across all
+// projects. ?status= filters them and ?sort=-due_date lists the latest due
+// first instead of the soonest.
+func ListAssignedTasks(c *router.Context) {
+	if taskService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Task service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var latestDueFirst bool
+	switch c.Query("sort") {
+	case "", "due_date":
+	case "-due_date":
+		latestDueFirst = true
+	default:
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "sort must be due_date or -due_date")
+		return
+	}
+
+	tasks, err := taskService.GetAssignedTasks(c.Request.Context(), userID, c.Query("status"), latestDueFirst)
+	if err != nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to retrieve tasks")
+		return
+	}
+
+	c.List(tasks, router.ListMeta{})
+}
+
+// LogTaskTime records time the authenticated user spent on a task
+func LogTaskTime(c *router.Context) {
+	if taskService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Task service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var req TimeEntryRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	entry, err := taskService.LogTime(c.Request.Context(), c.Param("task_id"), userID, req.Minutes, req.Note)
+	if err != nil {
+		handleTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// ListTaskTime returns the time logged on a task, newest first
+func ListTaskTime(c *router.Context) {
+	if taskService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Task service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	entries, err := taskService.GetTimeEntries(c.Request.Context(), c.Param("task_id"), userID)
+	if err != nil {
+		handleTaskError(c, err)
+		return
+	}
+
+	c.List(entries, router.ListMeta{})
+}
+
+// GetTaskTimeTotal returns the total time logged on a task and how much each
+// user logged
+func GetTaskTimeTotal(c *router.Context) {
+	if taskService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Task service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	total, err := taskService.GetTaskTotalTime(c.Request.Context(), c.Param("task_id"), userID)
+	if err != nil {
+		handleTaskError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, total)
+}
+
+// DeleteTask deletes a task with its comments and logged time
+func DeleteTask(c *router.Context) {
+	if taskService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Task service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := taskService.DeleteTask(c.Request.Context(), c.Param("task_id"), userID); err != nil {
+		handleTaskError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Task deleted successfully")
+}
+
+func handleTaskError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrTaskNotFound):
+		c.Error(http.StatusNotFound, codeTaskNotFound, "Task not found")
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.Error(http.StatusNotFound, codeProjectNotFound, "Project not found")
+	case errors.Is(err, services.ErrNotProjectOwner):
+		c.Error(http.StatusForbidden, codeForbidden, "You don't have permission to access this project")
+	case errors.Is(err, services.ErrNotTeamMember):
+		c.Error(http.StatusForbidden, codeNotTeamMember, "You are not a member of this team")
+	case errors.Is(err, services.ErrInvalidTimeEntry):
+		c.Error(http.StatusBadRequest, codeInvalidRequest, err.Error())
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
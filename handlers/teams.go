@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/permissions"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -27,21 +30,60 @@ type TeamRequest struct {
 	AvatarURL   string `json:"avatar_url,omitempty"`
 }
 
-// TeamMemberRequest represents a request to add a member to a team
+// Validate checks the team fields
+func (r *TeamRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Name), "name", "team name is required")
+	v.CheckField(validator.MaxChars(r.Name, 100), "name", "cannot exceed 100 characters")
+	if r.AvatarURL != "" {
+		v.CheckField(validator.IsValidURL(r.AvatarURL), "avatar_url", "must be an http or https URL")
+	}
+}
+
+// TeamMemberRequest represents a request to add a member to a team. Role
+// defaults to viewer.
 type TeamMemberRequest struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
 }
 
+// Validate checks the user ID and, when given, the role
+func (r *TeamMemberRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.UserID), "user_id", "user_id is required")
+	if r.UserID != "" {
+		v.CheckField(validator.IsUUID(r.UserID), "user_id", "must be a valid UUID")
+	}
+	if r.Role != "" {
+		checkMemberRole(v, r.Role)
+	}
+}
+
+// TeamMemberRoleRequest represents a request to change a member's role
+type TeamMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// Validate checks the role can be given to a member
+func (r *TeamMemberRoleRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Role), "role", "role is required")
+	if r.Role != "" {
+		checkMemberRole(v, r.Role)
+	}
+}
+
+// checkMemberRole records an error unless role can be given to a member
+func checkMemberRole(v *validator.Validator, role string) {
+	v.CheckField(permissions.IsAssignableRole(role), "role", "must be admin, editor or viewer")
+}
+
 // ListTeams returns all teams a user is a member of
 func ListTeams(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
@@ -51,33 +93,23 @@ func ListTeams(c *router.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"teams": teams,
-		"count": len(teams),
-	})
+	c.List(teams, router.ListMeta{})
 }
 
 // CreateTeam creates a new team
 func CreateTeam(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	var req TeamRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
-		return
-	}
-
-	// Validate team name
-	if req.Name == "" {
-		c.Status(http.StatusBadRequest, "Team name is required")
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
@@ -98,21 +130,26 @@ func CreateTeam(c *router.Context) {
 	c.JSON(http.StatusCreated, team)
 }
 
-// GetTeam returns a specific team
+// GetTeam returns a specific team by ID or slug
 func GetTeam(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	// The team may be named by its ID or its slug
+	ref, ok := refParam(c, "id", "team")
+	if !ok {
+		return
+	}
+	teamID, err := teamService.ResolveTeamID(c.Request.Context(), ref)
+	if err != nil {
+		handleTeamError(c, err)
 		return
 	}
 
@@ -122,34 +159,44 @@ func GetTeam(c *router.Context) {
 		return
 	}
 
+	if c.CheckNotModified(router.NewETag(team.ID.String(), team.UpdatedAt.Time.Format(time.RFC3339Nano))) {
+		return
+	}
+
 	c.JSON(http.StatusOK, team)
 }
 
 // UpdateTeam updates a team
 func UpdateTeam(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	teamID, ok := idParam(c, "id", "team")
+	if !ok {
 		return
 	}
 
 	var req TeamRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	var teamUUID pgtype.UUID
+	if err := teamUUID.Scan(teamID); err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid team ID")
 		return
 	}
 
 	params := store.UpdateTeamParams{
+		ID:          teamUUID,
 		Name:        req.Name,
 		Description: pgtype.Text{String: req.Description, Valid: req.Description != ""},
 		AvatarUrl:   pgtype.Text{String: req.AvatarURL, Valid: req.AvatarURL != ""},
@@ -175,18 +222,17 @@ func UpdateTeam(c *router.Context) {
 // DeleteTeam deletes a team
 func DeleteTeam(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	teamID, ok := idParam(c, "id", "team")
+	if !ok {
 		return
 	}
 
@@ -201,34 +247,27 @@ func DeleteTeam(c *router.Context) {
 // AddTeamMember adds a user to a team
 func AddTeamMember(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	teamID, ok := idParam(c, "id", "team")
+	if !ok {
 		return
 	}
 
 	var req TeamMemberRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
-		return
-	}
-
-	if req.UserID == "" {
-		c.Status(http.StatusBadRequest, "User ID is required")
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
 	if req.Role == "" {
-		req.Role = "member" // Default role
+		req.Role = permissions.RoleViewer
 	}
 
 	if err := teamService.AddMember(c.Request.Context(), teamID, req.UserID, req.Role, userID); err != nil {
@@ -244,28 +283,39 @@ func AddTeamMember(c *router.Context) {
 // RemoveTeamMember removes a user from a team
 func RemoveTeamMember(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	// Members removing themselves don't pass the team admin guard, which
+	// resolves slugs for the other member routes
+	ref, ok := refParam(c, "id", "team")
+	if !ok {
+		return
+	}
+	teamID, err := teamService.ResolveTeamID(c.Request.Context(), ref)
+	if err != nil {
+		handleTeamError(c, err)
 		return
 	}
 
-	memberID := c.Param("user_id")
-	if memberID == "" {
-		c.Status(http.StatusBadRequest, "Member ID is required")
+	memberID, ok := idParam(c, "user_id", "member")
+	if !ok {
 		return
 	}
 
-	if err := teamService.RemoveMember(c.Request.Context(), teamID, memberID, userID); err != nil {
+	// Open items assigned to the member go to ?reassign_to, or are unassigned
+	var reassignTo *string
+	if id := c.Query("reassign_to"); id != "" {
+		reassignTo = &id
+	}
+
+	if err := teamService.RemoveMember(c.Request.Context(), teamID, memberID, userID, reassignTo); err != nil {
 		handleTeamError(c, err)
 		return
 	}
@@ -275,21 +325,69 @@ func RemoveTeamMember(c *router.Context) {
 	})
 }
 
+// UpdateTeamMemberRole changes a team member's role
+func UpdateTeamMemberRole(c *router.Context) {
+	if teamService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	teamID, ok := idParam(c, "id", "team")
+	if !ok {
+		return
+	}
+
+	memberID, ok := idParam(c, "user_id", "member")
+	if !ok {
+		return
+	}
+
+	var req TeamMemberRoleRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	err := teamService.UpdateTeamMemberRole(c.Request.Context(), teamID, memberID, userID, req.Role)
+	switch {
+	case errors.Is(err, services.ErrNotTeamMember):
+		// The team admin middleware already checked the caller, so it is the
+		// member being changed who isn't in the team
+		c.Error(http.StatusNotFound, codeUserNotFound, "User is not a member of this team")
+		return
+	case err != nil:
+		handleTeamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "Member role updated successfully",
+	})
+}
+
 // ListTeamMembers returns all members of a team
 func ListTeamMembers(c *router.Context) {
 	if teamService == nil {
-		c.Status(http.StatusInternalServerError, "Team service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Team service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	teamID := c.Param("id")
-	if teamID == "" {
-		c.Status(http.StatusBadRequest, "Team ID is required")
+	ref, ok := refParam(c, "id", "team")
+	if !ok {
+		return
+	}
+	teamID, err := teamService.ResolveTeamID(c.Request.Context(), ref)
+	if err != nil {
+		handleTeamError(c, err)
 		return
 	}
 
@@ -299,21 +397,22 @@ func ListTeamMembers(c *router.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"members": members,
-		"count":   len(members),
-	})
+	c.List(members, router.ListMeta{})
 }
 
 func handleTeamError(c *router.Context, err error) {
 	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
 	case errors.Is(err, services.ErrTeamNotFound):
-		c.Status(http.StatusNotFound, "Team not found")
-	case errors.Is(err, services.ErrUnauthorized):
-		c.Status(http.StatusForbidden, "Only team admins can perform this action")
-	case errors.Is(err, services.ErrNotMember):
-		c.Status(http.StatusForbidden, "You are not a member of this team")
+		c.Error(http.StatusNotFound, codeTeamNotFound, "Team not found")
+	case errors.Is(err, services.ErrUnauthorized), errors.Is(err, services.ErrInsufficientRoles):
+		c.Error(http.StatusForbidden, codeForbidden, "Only team admins can perform this action")
+	case errors.Is(err, services.ErrNotMember), errors.Is(err, services.ErrNotTeamMember):
+		c.Error(http.StatusForbidden, codeNotTeamMember, "You are not a member of this team")
+	case errors.Is(err, services.ErrInvalidTeamData):
+		c.Error(http.StatusBadRequest, codeInvalidTeam, "Invalid team data")
 	default:
-		c.Status(http.StatusInternalServerError, "An error occurred processing your request")
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
 	}
 }
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// roleDB answers team role and membership queries from each member's role,
+// and everything else from the embedded DB
+type roleDB struct {
+	*storetest.DB
+	roles map[string]string
+}
+
+func (db *roleDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	name := storetest.QueryName(sql)
+	if name != "GetTeamMemberRole" && name != "GetTeamMember" && name != "CheckTeamMembership" {
+		return db.DB.QueryRow(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+	role, ok := db.roles[args[1].(pgtype.UUID).String()]
+	roleText := pgtype.Text{String: role, Valid: true}
+	switch {
+	case name == "CheckTeamMembership":
+		return storetest.Row([]any{ok})
+	case !ok:
+		return storetest.Row(nil)
+	case name == "GetTeamMember":
+		return storetest.Row([]any{args[0], args[1], roleText})
+	}
+	return storetest.Row([]any{roleText})
+}
+
+func TestAddTeamMember(t *testing.T) {
+	const (
+		team   = "44444444-4444-4444-4444-444444444444"
+		owner  = "11111111-1111-1111-1111-111111111111"
+		editor = "33333333-3333-3333-3333-333333333333"
+		newbie = "55555555-5555-5555-5555-555555555555"
+	)
+	db := &roleDB{
+		DB:    &storetest.DB{Rows: map[string][]any{"GetTeamByID": {storetest.MustUUID(t, team), "Platform"}}},
+		roles: map[string]string{owner: "owner", editor: "editor"},
+	}
+	prev := teamService
+	t.Cleanup(func() { teamService = prev })
+	SetTeamService(services.NewTeamService(store.New(db), cache.NewMemory(), services.CacheTTLs{}))
+
+	rg := router.NewRouter()
+	rg.POST("/teams/{id}/members", AddTeamMember)
+	mux := router.ServeMux(rg)
+
+	post := func(actor, body string) *httptest.ResponseRecorder {
+		db.Calls = nil
+		req := httptest.NewRequest("POST", "/teams/"+team+"/members", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, actor))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Role defaults to viewer", func(t *testing.T) {
+		if rr := post(owner, `{"user_id":"`+newbie+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("AddUserToTeam")
+		if !ok {
+			t.Fatal("Expected the member to be added")
+		}
+		if role := args[2].(pgtype.Text); role.String != "viewer" {
+			t.Errorf("Added as %q, want viewer", role.String)
+		}
+	})
+
+	t.Run("Unknown roles are rejected", func(t *testing.T) {
+		for _, role := range []string{"member", "owner"} {
+			rr := post(owner, `{"user_id":"`+newbie+`","role":"`+role+`"}`)
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Role %q: expected status 422, got %d (%s)", role, rr.Code, rr.Body.String())
+			}
+			if len(db.Calls) != 0 {
+				t.Errorf("Role %q: expected no queries, got %v", role, db.Calls)
+			}
+		}
+	})
+
+	t.Run("Editors can't add members", func(t *testing.T) {
+		rr := post(editor, `{"user_id":"`+newbie+`","role":"viewer"}`)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), codeForbidden) {
+			t.Errorf("Expected status 403 with %s, got %d (%s)", codeForbidden, rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("AddUserToTeam"); ok {
+			t.Error("Expected no member to be added")
+		}
+	})
+}
+
+func TestUpdateTeamMemberRole(t *testing.T) {
+	const (
+		team   = "44444444-4444-4444-4444-444444444444"
+		owner  = "11111111-1111-1111-1111-111111111111"
+		admin  = "22222222-2222-2222-2222-222222222222"
+		editor = "33333333-3333-3333-3333-333333333333"
+	)
+	db := &roleDB{
+		DB:    &storetest.DB{Rows: map[string][]any{}},
+		roles: map[string]string{owner: "owner", admin: "admin", editor: "editor"},
+	}
+	queries := store.New(db)
+	prev := teamService
+	t.Cleanup(func() { teamService = prev })
+	SetTeamService(services.NewTeamService(queries, cache.NewMemory(), services.CacheTTLs{}))
+
+	rg := router.NewRouter()
+	rg.PATCH("/teams/{id}/members/{user_id}/role", UpdateTeamMemberRole, middleware.NewTeamAdminMiddleware(queries))
+	mux := router.ServeMux(rg)
+
+	patch := func(actor, member, body string) *httptest.ResponseRecorder {
+		db.Calls = nil
+		req := httptest.NewRequest("PATCH", "/teams/"+team+"/members/"+member+"/role", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, actor))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Admin changes a member's role", func(t *testing.T) {
+		rr := patch(admin, editor, `{"role":"viewer"}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("UpdateTeamMemberRole")
+		if !ok {
+			t.Fatal("Expected the role to be updated")
+		}
+		if member, role := args[1].(pgtype.UUID), args[2].(pgtype.Text); member.String() != editor || role.String != "viewer" {
+			t.Errorf("Updated %s to %q, want %s to viewer", member.String(), role.String, editor)
+		}
+	})
+
+	t.Run("Team addressed by slug", func(t *testing.T) {
+		db.Rows["GetTeamIDBySlug"] = []any{storetest.MustUUID(t, team)}
+		t.Cleanup(func() { delete(db.Rows, "GetTeamIDBySlug") })
+		db.Calls = nil
+		req := httptest.NewRequest("PATCH", "/teams/platform/members/"+editor+"/role", strings.NewReader(`{"role":"viewer"}`))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, admin))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("UpdateTeamMemberRole")
+		if !ok {
+			t.Fatal("Expected the role to be updated")
+		}
+		if teamID := args[0].(pgtype.UUID); teamID.String() != team {
+			t.Errorf("Updated team %s, want %s", teamID.String(), team)
+		}
+	})
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		if rr := patch(editor, admin, `{"role":"viewer"}`); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("UpdateTeamMemberRole"); ok {
+			t.Error("Expected no role to change")
+		}
+	})
+
+	t.Run("Admin can't demote the owner", func(t *testing.T) {
+		rr := patch(admin, owner, `{"role":"viewer"}`)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), codeForbidden) {
+			t.Errorf("Expected status 403 with %s, got %d (%s)", codeForbidden, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Owner can't demote themselves", func(t *testing.T) {
+		rr := patch(owner, owner, `{"role":"admin"}`)
+		if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), codeForbidden) {
+			t.Errorf("Expected status 403 with %s, got %d (%s)", codeForbidden, rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("UpdateTeamMemberRole"); ok {
+			t.Error("Expected no role to change")
+		}
+	})
+
+	t.Run("Not a member", func(t *testing.T) {
+		rr := patch(admin, "55555555-5555-5555-5555-555555555555", `{"role":"viewer"}`)
+		if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), codeUserNotFound) {
+			t.Errorf("Expected status 404 with %s, got %d (%s)", codeUserNotFound, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Ownership can't be given", func(t *testing.T) {
+		if rr := patch(owner, admin, `{"role":"owner"}`); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status 422, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("UpdateTeamMemberRole"); ok {
+			t.Error("Expected no role to change")
+		}
+	})
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/database/store"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -26,38 +29,97 @@ type TicketRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description,omitempty"`
 	Status      string `json:"status,omitempty"`
+	Priority    string `json:"priority,omitempty"` // low, medium, high or urgent
 	AssigneeID  string `json:"assignee_id,omitempty"`
 	DueDate     string `json:"due_date,omitempty"` // RFC3339 format
+	Version     int32  `json:"version,omitempty"`  // Expected version, see If-Match
 }
 
-// ListTickets returns all tickets for a project
+// Validate checks the ticket fields
+func (r *TicketRequest) Validate(v *validator.Validator) {
+	v.CheckField(validator.NotBlank(r.Title), "title", "title is required")
+	v.CheckField(validator.MaxChars(r.Title, 255), "title", "cannot exceed 255 characters")
+	if r.Priority != "" {
+		v.CheckField(services.IsValidIssuePriority(r.Priority), "priority", "must be low, medium, high or urgent")
+	}
+	if r.AssigneeID != "" {
+		v.CheckField(validator.IsUUID(r.AssigneeID), "assignee_id", "must be a valid UUID")
+	}
+	if r.DueDate != "" {
+		v.CheckField(validator.IsValidDate(r.DueDate, time.RFC3339), "due_date", "must be an RFC3339 timestamp")
+	}
+}
+
+// ListTickets returns all tickets for a project. ?labels= keeps tickets with
+// the given labels; otherwise ?status=, ?priority=, ?due_after=,
+// ?due_before= and ?overdue=true filter them and ?sort=priority lists the
+// most urgent first.
 func ListTickets(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	projectID := c.Param("project_id")
-	if projectID == "" {
-		c.Status(http.StatusBadRequest, "Project ID is required")
+	projectID, ok := idParam(c, "project_id", "project")
+	if !ok {
 		return
 	}
 
-	// Optional status filter
+	// Optional filters: status and priority, or labels=bug,urgent with
+	// match=all (default) or any
 	status := c.Query("status")
+	priority := c.Query("priority")
+	labels := c.Query("labels")
+
+	if priority != "" && !services.IsValidIssuePriority(priority) {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "priority must be low, medium, high or urgent")
+		return
+	}
+
+	dueAfter, ok := timeQuery(c, "due_after")
+	if !ok {
+		return
+	}
+	dueBefore, ok := timeQuery(c, "due_before")
+	if !ok {
+		return
+	}
+	overdue, _ := strconv.ParseBool(c.Query("overdue"))
+
+	var byPriority bool
+	switch c.Query("sort") {
+	case "":
+	case "priority":
+		byPriority = true
+	default:
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "sort must be priority")
+		return
+	}
 
 	var tickets []services.IssueInfo
+	var next string
 	var err error
 
-	if status != "" {
-		tickets, err = issueService.GetIssuesByStatus(c.Request.Context(), projectID, status, userID)
-	} else {
-		tickets, err = issueService.GetProjectIssues(c.Request.Context(), projectID, userID)
+	switch {
+	case labels != "":
+		matchAll := c.Query("match") != "any"
+		tickets, err = issueService.GetIssuesByLabels(c.Request.Context(), projectID, strings.Split(labels, ","), matchAll, userID)
+	case status != "" || priority != "" || !dueAfter.IsZero() || !dueBefore.IsZero() || overdue || byPriority:
+		tickets, err = issueService.GetFilteredIssues(c.Request.Context(), projectID, userID, services.IssueFilter{
+			Status:         status,
+			Priority:       priority,
+			DueAfter:       dueAfter,
+			DueBefore:      dueBefore,
+			Overdue:        overdue,
+			SortByPriority: byPriority,
+		})
+	default:
+		tickets, next, err = issueService.GetProjectIssuesPage(c.Request.Context(), projectID, userID, cursorPage(c))
 	}
 
 	if err != nil {
@@ -65,52 +127,63 @@ func ListTickets(c *router.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"tickets": tickets,
-		"count":   len(tickets),
-	})
+	c.List(tickets, router.CursorMeta(next))
 }
 
-// CreateTicket creates a new ticket
-func CreateTicket(c *router.Context) {
+// ListAssignedTickets returns the tickets assigned to the current user across
+// all projects, optionally filtered by ?status=
+func ListAssignedTickets(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	projectID := c.Param("project_id")
-	if projectID == "" {
-		c.Status(http.StatusBadRequest, "Project ID is required")
+	tickets, err := issueService.GetAssignedIssues(c.Request.Context(), userID, c.Query("status"))
+	if err != nil {
+		handleIssueError(c, err)
 		return
 	}
 
-	var req TicketRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+	c.List(tickets, router.ListMeta{})
+}
+
+// CreateTicket creates a new ticket
+func CreateTicket(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	projectID, ok := idParam(c, "project_id", "project")
+	if !ok {
 		return
 	}
 
-	// Validate required fields
-	if req.Title == "" {
-		c.Status(http.StatusBadRequest, "Title is required")
+	var req TicketRequest
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
 	// Create issue parameters
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid project ID format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid project ID format")
 		return
 	}
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid user ID format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid user ID format")
 		return
 	}
 
@@ -119,6 +192,7 @@ func CreateTicket(c *router.Context) {
 		Title:       req.Title,
 		Description: pgtype.Text{String: req.Description, Valid: req.Description != ""},
 		Status:      pgtype.Text{String: req.Status, Valid: req.Status != ""},
+		Priority:    pgtype.Text{String: req.Priority, Valid: req.Priority != ""},
 		ReporterID:  userUUID,
 	}
 
@@ -126,7 +200,7 @@ func CreateTicket(c *router.Context) {
 	if req.AssigneeID != "" {
 		var assigneeUUID pgtype.UUID
 		if err := assigneeUUID.Scan(req.AssigneeID); err != nil {
-			c.Status(http.StatusBadRequest, "Invalid assignee ID format")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid assignee ID format")
 			return
 		}
 		params.AssigneeID = assigneeUUID
@@ -136,7 +210,7 @@ func CreateTicket(c *router.Context) {
 	if req.DueDate != "" {
 		dueDate, err := time.Parse(time.RFC3339, req.DueDate)
 		if err != nil {
-			c.Status(http.StatusBadRequest, "Invalid due date format, use RFC3339")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid due date format, use RFC3339")
 			return
 		}
 		params.DueDate = pgtype.Timestamp{Time: dueDate, Valid: true}
@@ -155,54 +229,76 @@ func CreateTicket(c *router.Context) {
 	})
 }
 
-// GetTicket returns a specific ticket
+// GetTicket returns a specific ticket, by ID or by its number in the project
 func GetTicket(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	ticketID := c.Param("id")
-	if ticketID == "" {
-		c.Status(http.StatusBadRequest, "Ticket ID is required")
-		return
+	// Tickets can also be fetched by their number within the project
+	var ticket *services.IssueInfo
+	var err error
+	if number, convErr := strconv.Atoi(c.Param("id")); convErr == nil {
+		projectID, ok := idParam(c, "project_id", "project")
+		if !ok {
+			return
+		}
+		ticket, err = issueService.GetIssueByNumber(c.Request.Context(), projectID, number, userID)
+	} else {
+		ticketID, ok := idParam(c, "id", "ticket")
+		if !ok {
+			return
+		}
+		ticket, err = issueService.GetIssueByID(c.Request.Context(), ticketID, userID)
 	}
-
-	ticket, err := issueService.GetIssueByID(c.Request.Context(), ticketID, userID)
 	if err != nil {
 		handleIssueError(c, err)
 		return
 	}
 
+	var updatedAt string
+	if ticket.UpdatedAt != nil {
+		updatedAt = *ticket.UpdatedAt
+	}
+	if c.CheckNotModified(versionETag(ticket.ID, updatedAt, ticket.Version)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, ticket)
 }
 
 // UpdateTicket updates an existing ticket
 func UpdateTicket(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	ticketID := c.Param("id")
-	if ticketID == "" {
-		c.Status(http.StatusBadRequest, "Ticket ID is required")
+	ticketID, ok := idParam(c, "id", "ticket")
+	if !ok {
 		return
 	}
 
 	var req TicketRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidVersion, "If-Match must be a ticket version")
 		return
 	}
 
@@ -211,14 +307,16 @@ func UpdateTicket(c *router.Context) {
 		Title:       req.Title,
 		Description: req.Description,
 		Status:      req.Status,
+		Priority:    req.Priority,
 		AssigneeID:  req.AssigneeID,
+		Version:     version,
 	}
 
 	// Parse due date if provided
 	if req.DueDate != "" {
 		dueDate, err := time.Parse(time.RFC3339, req.DueDate)
 		if err != nil {
-			c.Status(http.StatusBadRequest, "Invalid due date format, use RFC3339")
+			c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid due date format, use RFC3339")
 			return
 		}
 		updates.DueDate = &dueDate
@@ -245,18 +343,17 @@ func UpdateTicket(c *router.Context) {
 // DeleteTicket deletes a ticket
 func DeleteTicket(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	ticketID := c.Param("id")
-	if ticketID == "" {
-		c.Status(http.StatusBadRequest, "Ticket ID is required")
+	ticketID, ok := idParam(c, "id", "ticket")
+	if !ok {
 		return
 	}
 
@@ -271,37 +368,44 @@ func DeleteTicket(c *router.Context) {
 // AssignTicket assigns a ticket to a user
 func AssignTicket(c *router.Context) {
 	if issueService == nil {
-		c.Status(http.StatusInternalServerError, "Issue service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
-	ticketID := c.Param("id")
-	if ticketID == "" {
-		c.Status(http.StatusBadRequest, "Ticket ID is required")
+	ticketID, ok := idParam(c, "id", "ticket")
+	if !ok {
 		return
 	}
 
 	var req struct {
 		AssigneeID string `json:"assignee_id"`
+		Version    int32  `json:"version,omitempty"`
 	}
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	if req.AssigneeID == "" {
-		c.Status(http.StatusBadRequest, "Assignee ID is required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Assignee ID is required")
+		return
+	}
+
+	version, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidVersion, "If-Match must be a ticket version")
 		return
 	}
 
 	// Create updates with just the assignee
 	updates := services.IssueUpdates{
 		AssigneeID: req.AssigneeID,
+		Version:    version,
 	}
 
 	if err := issueService.UpdateIssue(c.Request.Context(), ticketID, updates, userID); err != nil {
@@ -325,15 +429,35 @@ func AssignTicket(c *router.Context) {
 // Helper function to handle issue errors
 func handleIssueError(c *router.Context, err error) {
 	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
 	case errors.Is(err, services.ErrIssueNotFound):
-		c.Status(http.StatusNotFound, "Ticket not found")
+		c.Error(http.StatusNotFound, codeTicketNotFound, "Ticket not found")
 	case errors.Is(err, services.ErrProjectNotFound):
-		c.Status(http.StatusNotFound, "Project not found")
+		c.Error(http.StatusNotFound, codeProjectNotFound, "Project not found")
 	case errors.Is(err, services.ErrNotProjectOwner):
-		c.Status(http.StatusForbidden, "You don't have permission to access this project")
+		c.Error(http.StatusForbidden, codeForbidden, "You don't have permission to access this project")
+	case errors.Is(err, services.ErrNotTeamMember):
+		c.Error(http.StatusForbidden, codeNotTeamMember, "You are not a member of this team")
 	case errors.Is(err, services.ErrInvalidIssueData):
-		c.Status(http.StatusBadRequest, "Invalid ticket data")
+		c.Error(http.StatusBadRequest, codeInvalidTicket, "Invalid ticket data")
+	case errors.Is(err, services.ErrLabelNotFound):
+		c.Error(http.StatusNotFound, codeLabelNotFound, "Label not found")
+	case errors.Is(err, services.ErrDuplicateLabel):
+		c.Error(http.StatusConflict, codeLabelExists, "A label with this name already exists")
+	case errors.Is(err, services.ErrConcurrentModification):
+		c.Error(http.StatusConflict, codeVersionConflict, "Ticket was modified by someone else; reload and try again")
+	case errors.Is(err, services.ErrInvalidCursor):
+		c.Error(http.StatusBadRequest, codeInvalidCursor, "Invalid cursor")
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		c.Error(http.StatusNotFound, codeAttachmentNotFound, "Attachment not found")
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.Error(http.StatusRequestEntityTooLarge, codePayloadTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedAttachment):
+		c.Error(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Attachments must be images, PDFs, plain text or zip/gzip archives")
+	case errors.Is(err, services.ErrAttachmentsDisabled):
+		c.Error(http.StatusInternalServerError, codeInternal, "Attachments not initialized")
 	default:
-		c.Status(http.StatusInternalServerError, "An error occurred processing your request")
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
 	}
 }
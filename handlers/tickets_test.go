@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	ticketOwner   = "11111111-1111-1111-1111-111111111111"
+	ticketProject = "55555555-5555-5555-5555-555555555555"
+	ticketIssue   = "66666666-6666-6666-6666-666666666666"
+)
+
+// newTicketServer serves the ticket routes over a project with one open, high
+// priority ticket, number 42. setup resets the data and returns it.
+func newTicketServer(t *testing.T) (setup func() *storetest.DB, do func(method, path, body string) *httptest.ResponseRecorder) {
+	const (
+		owner   = ticketOwner
+		project = ticketProject
+		issue   = ticketIssue
+	)
+
+	high := pgtype.Text{String: "high", Valid: true}
+	setup = func() *storetest.DB {
+		issueRow := []any{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{}, pgtype.Text{String: "open", Valid: true},
+			storetest.MustUUID(t, owner), pgtype.UUID{}, pgtype.Timestamp{}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(1), high, int32(42)}
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetProjectAccess": {storetest.MustUUID(t, owner)},
+				"CreateIssue":      issueRow,
+				"GetIssueByID":     issueRow,
+				"GetIssueByNumber": issueRow,
+				"NextIssueNumber":  {int32(42)},
+			},
+			Lists: map[string][][]any{
+				"GetProjectIssuesFiltered": {issueRow},
+			},
+		}
+		queries := store.New(db)
+		memory := cache.NewMemory()
+		projects := services.NewProjectService(queries, memory, nil, services.CacheTTLs{})
+		SetIssueService(services.NewIssueService(queries, memory, projects))
+		return db
+	}
+	prev := issueService
+	t.Cleanup(func() { issueService = prev })
+
+	rg := router.NewRouter()
+	rg.GET("/projects/{project_id}/tickets", ListTickets)
+	rg.POST("/projects/{project_id}/tickets", CreateTicket)
+	rg.GET("/projects/{project_id}/tickets/{id}", GetTicket)
+	rg.PUT("/projects/{project_id}/tickets/{id}", UpdateTicket)
+	mux := router.ServeMux(rg)
+
+	do = func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, owner))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	return setup, do
+}
+
+func TestTicketPriority(t *testing.T) {
+	const (
+		project = ticketProject
+		issue   = ticketIssue
+	)
+	high := pgtype.Text{String: "high", Valid: true}
+	setup, do := newTicketServer(t)
+
+	t.Run("Create with a priority", func(t *testing.T) {
+		db := setup()
+		rr := do("POST", "/projects/"+project+"/tickets", `{"title": "Crash on login", "priority": "high"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("CreateIssue")
+		if !ok {
+			t.Fatal("Expected CreateIssue to be executed")
+		}
+		if got := args[7]; got != high { // $8, before the number
+			t.Errorf("Stored priority %v, want %v", got, high)
+		}
+
+		var body struct {
+			Ticket services.IssueInfo `json:"ticket"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || body.Ticket.Priority != "high" {
+			t.Errorf("Expected the ticket's priority in the response, got %+v (%v)", body.Ticket, err)
+		}
+	})
+
+	t.Run("Create with an unknown priority is rejected", func(t *testing.T) {
+		db := setup()
+		rr := do("POST", "/projects/"+project+"/tickets", `{"title": "Crash on login", "priority": "critical"}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("CreateIssue"); ok {
+			t.Error("CreateIssue should not run for an unknown priority")
+		}
+	})
+
+	t.Run("Update with an unknown priority is rejected", func(t *testing.T) {
+		db := setup()
+		rr := do("PUT", "/projects/"+project+"/tickets/"+issue, `{"priority": "critical"}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("UpdateIssueDetails"); ok {
+			t.Error("UpdateIssueDetails should not run for an unknown priority")
+		}
+	})
+
+	filters := []struct {
+		name       string
+		query      string
+		status     pgtype.Text
+		priority   pgtype.Text
+		byPriority bool
+	}{
+		{"Filter by priority", "priority=high", pgtype.Text{}, high, false},
+		{"Filter by status and priority", "status=open&priority=high", pgtype.Text{String: "open", Valid: true}, high, false},
+		{"Sort by priority", "sort=priority", pgtype.Text{}, pgtype.Text{}, true},
+	}
+	for _, tt := range filters {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setup()
+			rr := do("GET", "/projects/"+project+"/tickets?"+tt.query, "")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+
+			args, ok := db.Called("GetProjectIssuesFiltered")
+			if !ok {
+				t.Fatal("Expected GetProjectIssuesFiltered to be queried")
+			}
+			if byPriority := args[len(args)-1]; args[1] != tt.status || args[2] != tt.priority || byPriority != tt.byPriority {
+				t.Errorf("Filtered by status %v, priority %v, sorted by priority %v; want %v, %v, %v",
+					args[1], args[2], byPriority, tt.status, tt.priority, tt.byPriority)
+			}
+
+			var body struct {
+				Tickets []services.IssueInfo `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || len(body.Tickets) != 1 || body.Tickets[0].Priority != "high" {
+				t.Errorf("Expected the high priority ticket, got %+v (%v)", body.Tickets, err)
+			}
+		})
+	}
+
+	for _, query := range []string{"priority=critical", "sort=-created_at"} {
+		t.Run("Rejects "+query, func(t *testing.T) {
+			db := setup()
+			rr := do("GET", "/projects/"+project+"/tickets?"+query, "")
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if len(db.Calls) != 0 {
+				t.Errorf("Expected no queries, got %v", db.Calls)
+			}
+		})
+	}
+}
+
+func TestTicketDueDateFilters(t *testing.T) {
+	setup, do := newTicketServer(t)
+	at := func(value string) pgtype.Timestamp {
+		ts, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pgtype.Timestamp{Time: ts.UTC(), Valid: true}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		dueAfter  pgtype.Timestamp
+		dueBefore pgtype.Timestamp
+		overdue   bool
+	}{
+		{"Range of RFC 3339 times", "due_after=2024-05-01T09:00:00Z&due_before=2024-05-31T17:00:00Z",
+			at("2024-05-01T09:00:00Z"), at("2024-05-31T17:00:00Z"), false},
+		{"Offsets are converted to UTC", "due_before=2024-05-01T12:00:00%2B02:00", pgtype.Timestamp{}, at("2024-05-01T10:00:00Z"), false},
+		{"A date means midnight UTC", "due_after=2024-05-01", at("2024-05-01T00:00:00Z"), pgtype.Timestamp{}, false},
+		{"Overdue", "overdue=true", pgtype.Timestamp{}, pgtype.Timestamp{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setup()
+			rr := do("GET", "/projects/"+ticketProject+"/tickets?"+tt.query, "")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+			}
+
+			args, ok := db.Called("GetProjectIssuesFiltered")
+			if !ok {
+				t.Fatal("Expected GetProjectIssuesFiltered to be queried")
+			}
+			if args[3] != tt.dueAfter || args[4] != tt.dueBefore || args[5] != tt.overdue {
+				t.Errorf("Filtered due after %v, before %v, overdue %v; want %v, %v, %v",
+					args[3], args[4], args[5], tt.dueAfter, tt.dueBefore, tt.overdue)
+			}
+		})
+	}
+
+	for _, query := range []string{"due_after=yesterday", "due_before=2024-05-01T12:00"} {
+		t.Run("Rejects "+query, func(t *testing.T) {
+			db := setup()
+			rr := do("GET", "/projects/"+ticketProject+"/tickets?"+query, "")
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if len(db.Calls) != 0 {
+				t.Errorf("Expected no queries, got %v", db.Calls)
+			}
+		})
+	}
+}
+
+func TestTicketAssigneeAccess(t *testing.T) {
+	const outsider = "33333333-3333-3333-3333-333333333333"
+	setup, do := newTicketServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		write  string
+	}{
+		{"Create", "POST", "/projects/" + ticketProject + "/tickets", "CreateIssue"},
+		{"Update", "PUT", "/projects/" + ticketProject + "/tickets/" + ticketIssue, "UpdateIssueDetails"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" with an assignee outside the project is rejected", func(t *testing.T) {
+			db := setup()
+			rr := do(tt.method, tt.path, `{"title": "Crash on login", "assignee_id": "`+outsider+`"}`)
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), `"`+codeInvalidTicket+`"`) {
+				t.Errorf("Expected %s, got %s", codeInvalidTicket, rr.Body.String())
+			}
+			if _, ok := db.Called(tt.write); ok {
+				t.Errorf("%s should not run for an outsider", tt.write)
+			}
+		})
+	}
+
+	t.Run("The project owner can be assigned", func(t *testing.T) {
+		db := setup()
+		rr := do("POST", "/projects/"+ticketProject+"/tickets", `{"title": "Crash on login", "assignee_id": "`+ticketOwner+`"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("CreateIssue"); !ok {
+			t.Error("Expected CreateIssue to be executed")
+		}
+	})
+}
+
+func TestGetTicketByNumber(t *testing.T) {
+	setup, do := newTicketServer(t)
+
+	t.Run("Number within the project", func(t *testing.T) {
+		db := setup()
+		rr := do("GET", "/projects/"+ticketProject+"/tickets/42", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		args, ok := db.Called("GetIssueByNumber")
+		if !ok {
+			t.Fatal("Expected GetIssueByNumber to be queried")
+		}
+		if project, number := args[0].(pgtype.UUID), args[1]; project.String() != ticketProject || number != int32(42) {
+			t.Errorf("Looked up number %v in %s, want 42 in %s", number, project.String(), ticketProject)
+		}
+
+		var ticket services.IssueInfo
+		if err := json.NewDecoder(rr.Body).Decode(&ticket); err != nil || ticket.ID != ticketIssue || ticket.Number != 42 {
+			t.Errorf("Expected ticket 42, got %+v (%v)", ticket, err)
+		}
+	})
+
+	t.Run("UUIDs still work", func(t *testing.T) {
+		db := setup()
+		rr := do("GET", "/projects/"+ticketProject+"/tickets/"+ticketIssue, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("GetIssueByID"); !ok {
+			t.Error("Expected GetIssueByID to be queried")
+		}
+	})
+
+	t.Run("Numbers start at 1", func(t *testing.T) {
+		db := setup()
+		rr := do("GET", "/projects/"+ticketProject+"/tickets/0", "")
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if _, ok := db.Called("GetIssueByNumber"); ok {
+			t.Error("GetIssueByNumber should not run for 0")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/usage"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// usageTracker is retrieved from the application's dependency container
+var usageTracker *usage.Tracker
+
+// SetUsageTracker sets the creation-rate tracker for handlers
+func SetUsageTracker(tracker *usage.Tracker) {
+	usageTracker = tracker
+}
+
+// TopCreators lists the users creating the most resources of a kind,
+// e.g. /admin/usage/top-creators?kind=issue&hours=24&limit=10
+func TopCreators(c *router.Context) {
+	if usageTracker == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Usage tracker not initialized")
+		return
+	}
+
+	kind := c.Query("kind")
+	if !validator.IsOneOf(kind, usage.KindProject, usage.KindIssue, usage.KindComment) {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "kind must be one of project, issue, comment")
+		return
+	}
+
+	hours := usage.MaxWindowHours
+	if h, err := strconv.Atoi(c.Query("hours")); err == nil && h > 0 {
+		hours = h
+	}
+
+	limit := 10
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	creators, err := usageTracker.TopCreators(c.Request.Context(), kind, hours, limit)
+	if err != nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to load creation stats")
+		return
+	}
+
+	c.List(creators, router.ListMeta{})
+}
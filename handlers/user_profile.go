@@ -4,31 +4,53 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
 )
 
 // GetUserProfile returns the authenticated user's profile
 func GetUserProfile(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	profile, err := userService.GetUserProfile(c.Request.Context(), userID)
 	if err != nil {
 		if errors.Is(err, services.ErrUserNotFound) {
-			c.Status(http.StatusNotFound, "User not found")
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to retrieve user profile")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to retrieve user profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetPublicProfile returns another user's public profile by username
+func GetPublicProfile(c *router.Context) {
+	if userService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
+		return
+	}
+
+	profile, err := userService.GetPublicProfile(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+			return
+		}
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to retrieve user profile")
 		return
 	}
 
@@ -38,30 +60,39 @@ func GetUserProfile(c *router.Context) {
 // UpdateUserProfile updates the authenticated user's profile
 func UpdateUserProfile(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	// Get user ID from context
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
 	// Parse request body
 	var req services.UserProfileUpdate
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if req.Email != "" {
+		c.Error(http.StatusBadRequest, codeInvalidProfile, "Email changes must be confirmed; use POST /users/me/email")
 		return
 	}
 
 	// Update profile
 	if err := userService.UpdateUserProfile(c.Request.Context(), userID, req); err != nil {
 		if errors.Is(err, services.ErrUserNotFound) {
-			c.Status(http.StatusNotFound, "User not found")
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidUserData) {
+			c.Error(http.StatusBadRequest, codeInvalidProfile, "Invalid profile data")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to update profile")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to update profile")
 		return
 	}
 
@@ -70,16 +101,97 @@ func UpdateUserProfile(c *router.Context) {
 	})
 }
 
+// RequestEmailChange sends a confirmation link to the new address. The
+// account's email only changes once the link is followed.
+func RequestEmailChange(c *router.Context) {
+	if userService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if !c.Validate(func(v *validator.Validator) {
+		v.CheckField(validator.Matches(req.Email, validator.EmailRX), "email", "must be a valid email address")
+	}) {
+		return
+	}
+
+	if err := userService.RequestEmailChange(c.Request.Context(), userID, req.Email); err != nil {
+		switch {
+		case errors.Is(err, services.ErrDuplicateEmail):
+			c.Error(http.StatusConflict, codeEmailTaken, "Email already registered")
+		case errors.Is(err, services.ErrInvalidUserData):
+			c.Error(http.StatusBadRequest, codeInvalidProfile, "New email must differ from the current one")
+		case errors.Is(err, services.ErrUserNotFound):
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+		case errors.Is(err, services.ErrTokenStoreUnavailable):
+			tokenStoreUnavailable(c)
+		default:
+			c.Error(http.StatusInternalServerError, codeInternal, "Failed to request email change")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]string{
+		"message": "Check the new address for a link to confirm the change",
+	})
+}
+
+// ConfirmEmailChange applies a pending email change using the emailed token
+func ConfirmEmailChange(c *router.Context) {
+	if userService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
+		return
+	}
+	token := c.Param("token")
+	if token == "" {
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Confirmation token is required")
+		return
+	}
+
+	if err := userService.ConfirmEmailChange(c.Request.Context(), token); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEmailChangeToken):
+			c.Error(http.StatusBadRequest, codeInvalidToken, "Invalid or expired confirmation token")
+		case errors.Is(err, services.ErrDuplicateEmail):
+			c.Error(http.StatusConflict, codeEmailTaken, "Email already registered")
+		case errors.Is(err, services.ErrUserNotFound):
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+		case errors.Is(err, services.ErrTokenStoreUnavailable):
+			tokenStoreUnavailable(c)
+		default:
+			c.Error(http.StatusInternalServerError, codeInternal, "Failed to confirm email change")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "Email address updated successfully",
+	})
+}
+
 // ChangePassword handles password change for authenticated users
 func ChangePassword(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	// Get user ID from context
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
@@ -89,13 +201,14 @@ func ChangePassword(c *router.Context) {
 		NewPassword     string `json:"new_password"`
 	}
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	// Validate input
-	if req.CurrentPassword == "" || req.NewPassword == "" {
-		c.Status(http.StatusBadRequest, "Current password and new password are required")
+	if !c.Validate(func(v *validator.Validator) {
+		v.CheckField(validator.NotBlank(req.CurrentPassword), "current_password", "current password is required")
+		v.CheckField(validator.MinChars(req.NewPassword, 8), "new_password", "must be at least 8 characters")
+	}) {
 		return
 	}
 
@@ -103,10 +216,10 @@ func ChangePassword(c *router.Context) {
 	err := userService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidCredentials) {
-			c.Status(http.StatusUnauthorized, "Current password is incorrect")
+			c.Error(http.StatusUnauthorized, codeInvalidCredentials, "Current password is incorrect")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to change password")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to change password")
 		return
 	}
 
@@ -118,22 +231,28 @@ func ChangePassword(c *router.Context) {
 // DeleteAccount handles account deletion for authenticated users
 func DeleteAccount(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
-		c.Status(http.StatusUnauthorized, "User not authenticated")
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
 		return
 	}
 
+	// Projects that are still in use are only deleted once confirmed
+	force, _ := strconv.ParseBool(c.Query("force"))
+
 	// Delete account
-	if err := userService.DeleteAccount(c.Request.Context(), userID); err != nil {
-		if errors.Is(err, services.ErrUserNotFound) {
-			c.Status(http.StatusNotFound, "User not found")
-			return
+	if err := userService.DeleteAccount(c.Request.Context(), userID, force); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			c.Error(http.StatusNotFound, codeUserNotFound, "User not found")
+		case errors.Is(err, services.ErrActiveProjects):
+			c.Error(http.StatusConflict, codeActiveProjects, "You still own projects that aren't archived or cancelled; delete with ?force=true to remove them too")
+		default:
+			c.Error(http.StatusInternalServerError, codeInternal, "Failed to delete account")
 		}
-		c.Status(http.StatusInternalServerError, "Failed to delete account")
 		return
 	}
 
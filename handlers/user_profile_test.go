@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestGetPublicProfile(t *testing.T) {
+	const (
+		viewer = "11111111-1111-1111-1111-111111111111"
+		ada    = "22222222-2222-2222-2222-222222222222"
+	)
+
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetUserByUsername": {storetest.MustUUID(t, ada), "ada@example.com",
+			pgtype.Text{String: "Ada Lovelace", Valid: true}, pgtype.Text{String: "ada", Valid: true},
+			pgtype.Text{String: "https://example.com/ada.png", Valid: true}, pgtype.Text{String: "Engines", Valid: true}},
+	}}
+	prev := userService
+	SetUserService(services.NewUserService(store.New(db), cache.NewMemory(), nil, services.CacheTTLs{}))
+	t.Cleanup(func() { userService = prev })
+
+	rg := router.NewRouter()
+	rg.GET("/users/me", func(c *router.Context) { c.Status(http.StatusTeapot) })
+	rg.GET("/users/{username}", GetPublicProfile)
+	mux := router.ServeMux(rg)
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, viewer))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Only public fields are exposed", func(t *testing.T) {
+		rr := do("/users/ada")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		want := map[string]any{
+			"username":   "ada",
+			"name":       "Ada Lovelace",
+			"avatar_url": "https://example.com/ada.png",
+			"bio":        "Engines",
+		}
+		if len(body) != len(want) {
+			t.Errorf("Profile has fields %v, want only %v", body, want)
+		}
+		for field, value := range want {
+			if body[field] != value {
+				t.Errorf("%s = %v, want %v", field, body[field], value)
+			}
+		}
+	})
+
+	t.Run("Usernames match case-insensitively", func(t *testing.T) {
+		db.Calls = nil
+		rr := do("/users/ADA")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		args, ok := db.Called("GetUserByUsername")
+		if !ok || args[0] != "ADA" {
+			t.Errorf("Expected the lookup to receive the username as typed, got %v", args)
+		}
+		var body services.PublicProfile
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if body.Username != "ada" {
+			t.Errorf("Username = %q, want the stored spelling %q", body.Username, "ada")
+		}
+	})
+
+	t.Run("Unknown users are not found", func(t *testing.T) {
+		saved := db.Rows["GetUserByUsername"]
+		delete(db.Rows, "GetUserByUsername")
+		defer func() { db.Rows["GetUserByUsername"] = saved }()
+
+		for _, path := range []string{"/users/nobody", "/users/not%20a%20name"} {
+			if rr := do(path); rr.Code != http.StatusNotFound {
+				t.Errorf("GET %s: expected 404, got %d", path, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Own profile route still takes precedence", func(t *testing.T) {
+		if rr := do("/users/me"); rr.Code != http.StatusTeapot {
+			t.Errorf("Expected /users/me to reach its own handler, got %d", rr.Code)
+		}
+	})
+}
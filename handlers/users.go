@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/Bethel-nz/tickit/app/middleware"
 	"github.com/Bethel-nz/tickit/app/router"
 	"github.com/Bethel-nz/tickit/internal/auth"
 	"github.com/Bethel-nz/tickit/internal/database/store"
@@ -21,12 +22,20 @@ func SetUserService(service *services.UserService) {
 	userService = service
 }
 
+// tokenDenylist records tokens revoked by LogoutUser
+var tokenDenylist *auth.Denylist
+
+// SetTokenDenylist sets the denylist used to revoke tokens on logout
+func SetTokenDenylist(denylist *auth.Denylist) {
+	tokenDenylist = denylist
+}
+
 // RegisterRequest represents user registration input
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name,omitempty"`
-	Username string `json:"username,omitempty"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"min=8"`
+	Name     string `json:"name,omitempty" validate:"max=100"`
+	Username string `json:"username,omitempty" validate:"omitempty,max=50,alphanum"`
 }
 
 // LoginRequest represents login input
@@ -48,18 +57,11 @@ type ResetPasswordRequest struct {
 // RegisterUser handles user registration
 func RegisterUser(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	var req RegisterRequest
-	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
-		return
-	}
-
-	// Validate input
-	if err := validateRegisterRequest(req); err != nil {
-		c.Status(http.StatusBadRequest, err.Error())
+	if !c.BindAndValidate(&req) {
 		return
 	}
 
@@ -75,10 +77,10 @@ func RegisterUser(c *router.Context) {
 	user, err := userService.CreateUser(c.Request.Context(), params)
 	if err != nil {
 		if errors.Is(err, services.ErrDuplicateEmail) {
-			c.Status(http.StatusConflict, "Email already registered")
+			c.Error(http.StatusConflict, codeEmailTaken, "Email already registered")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Failed to create user")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to create user")
 		return
 	}
 
@@ -95,18 +97,18 @@ func RegisterUser(c *router.Context) {
 // LoginUser handles user login
 func LoginUser(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	var req LoginRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Validate credentials
 	if req.Email == "" || req.Password == "" {
-		c.Status(http.StatusBadRequest, "Email and password are required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Email and password are required")
 		return
 	}
 
@@ -114,17 +116,21 @@ func LoginUser(c *router.Context) {
 	user, err := userService.AuthenticateUser(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidCredentials) {
-			c.Status(http.StatusUnauthorized, "Invalid email or password")
+			c.Error(http.StatusUnauthorized, codeInvalidCredentials, "Invalid email or password")
 			return
 		}
-		c.Status(http.StatusInternalServerError, "Authentication failed")
+		if errors.Is(err, services.ErrAccountDisabled) {
+			c.Error(http.StatusForbidden, codeAccountDisabled, "This account has been disabled")
+			return
+		}
+		c.Error(http.StatusInternalServerError, codeInternal, "Authentication failed")
 		return
 	}
 
 	// Generate token
 	token, err := auth.GenerateToken(user.ID.String())
 	if err != nil {
-		c.Status(http.StatusInternalServerError, "Failed to generate token")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to generate token")
 		return
 	}
 
@@ -141,28 +147,59 @@ func LoginUser(c *router.Context) {
 	})
 }
 
+// LogoutUser revokes the token used to make the request
+func LogoutUser(c *router.Context) {
+	if tokenDenylist == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Token denylist not initialized")
+		return
+	}
+	claims, ok := c.Request.Context().Value(middleware.ClaimsKey).(*auth.Claims)
+	if !ok {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := tokenDenylist.Revoke(c.Request.Context(), claims); err != nil {
+		if errors.Is(err, auth.ErrTokenNotRevocable) {
+			c.Error(http.StatusBadRequest, codeInvalidToken, "Token cannot be revoked; it expires on its own")
+			return
+		}
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to log out")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
 // ForgotPassword initiates password reset
 func ForgotPassword(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	var req ForgotPasswordRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	if req.Email == "" || !validator.Matches(req.Email, validator.EmailRX) {
-		c.Status(http.StatusBadRequest, "Valid email is required")
+	if !c.Validate(func(v *validator.Validator) {
+		v.CheckField(validator.Matches(req.Email, validator.EmailRX), "email", "must be a valid email address")
+	}) {
 		return
 	}
 
 	// Call service to initiate password reset
 	err := userService.ForgotPassword(c.Request.Context(), req.Email)
+	if errors.Is(err, services.ErrTokenStoreUnavailable) {
+		tokenStoreUnavailable(c)
+		return
+	}
 	if err != nil {
 		// We don't reveal if the email exists or not for security reasons
-		c.Status(http.StatusInternalServerError, "Failed to process request")
+		c.Error(http.StatusInternalServerError, codeInternal, "Failed to process request")
 		return
 	}
 
@@ -175,32 +212,36 @@ func ForgotPassword(c *router.Context) {
 // ResetPassword completes password reset with token
 func ResetPassword(c *router.Context) {
 	if userService == nil {
-		c.Status(http.StatusInternalServerError, "User service not initialized")
+		c.Error(http.StatusInternalServerError, codeInternal, "User service not initialized")
 		return
 	}
 	// Get token from URL parameter
 	token := c.Param("token")
 	if token == "" {
-		c.Status(http.StatusBadRequest, "Reset token is required")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Reset token is required")
 		return
 	}
 
 	var req ResetPasswordRequest
 	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
-		c.Status(http.StatusBadRequest, "Invalid request format")
+		c.Error(http.StatusBadRequest, codeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	// Validate password
-	if req.NewPassword == "" || !validator.MinChars(req.NewPassword, 8) {
-		c.Status(http.StatusBadRequest, "Password must be at least 8 characters")
+	if !c.Validate(func(v *validator.Validator) {
+		v.CheckField(validator.MinChars(req.NewPassword, 8), "new_password", "must be at least 8 characters")
+	}) {
 		return
 	}
 
 	// Call service to reset password
 	err := userService.ResetPassword(c.Request.Context(), token, req.NewPassword)
+	if errors.Is(err, services.ErrTokenStoreUnavailable) {
+		tokenStoreUnavailable(c)
+		return
+	}
 	if err != nil {
-		c.Status(http.StatusBadRequest, "Invalid or expired reset token")
+		c.Error(http.StatusBadRequest, codeInvalidToken, "Invalid or expired reset token")
 		return
 	}
 
@@ -209,23 +250,9 @@ func ResetPassword(c *router.Context) {
 	})
 }
 
-// Helper function to validate registration data
-func validateRegisterRequest(req RegisterRequest) error {
-	if req.Email == "" || !validator.Matches(req.Email, validator.EmailRX) {
-		return errors.New("valid email address is required")
-	}
-
-	if req.Password == "" || !validator.MinChars(req.Password, 8) {
-		return errors.New("password must be at least 8 characters")
-	}
-
-	if req.Name != "" && !validator.MaxChars(req.Name, 100) {
-		return errors.New("name cannot exceed 100 characters")
-	}
-
-	if req.Username != "" && !validator.MaxChars(req.Username, 50) {
-		return errors.New("username cannot exceed 50 characters")
-	}
-
-	return nil
+// tokenStoreUnavailable reports that reset or confirmation tokens can't be
+// reached. The request is safe to retry once the cache is back.
+func tokenStoreUnavailable(c *router.Context) {
+	c.Header().Set("Retry-After", "30")
+	c.Error(http.StatusServiceUnavailable, codeUnavailable, "This is temporarily unavailable, please try again shortly")
 }
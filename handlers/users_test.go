@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestLogoutRevokesToken(t *testing.T) {
+	tokens, err := auth.NewTokenManager(auth.TokenConfig{Secret: "in-test-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	auth.SetDefaultTokenManager(tokens)
+
+	mr := miniredis.RunT(t)
+	denylist := auth.NewDenylist(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	prev := tokenDenylist
+	t.Cleanup(func() { tokenDenylist = prev })
+	SetTokenDenylist(denylist)
+
+	rg := router.NewRouter()
+	users := rg.Group("/users", middleware.NewAuthMiddleware(denylist, nil, nil))
+	users.POST("/logout", LogoutUser)
+	users.GET("/me", func(c *router.Context) { c.Status(http.StatusOK) })
+	mux := router.ServeMux(rg)
+
+	do := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	alice, err := tokens.Generate("11111111-1111-1111-1111-111111111111")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	bob, err := tokens.Generate("22222222-2222-2222-2222-222222222222")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if code := do("GET", "/users/me", alice); code != http.StatusOK {
+		t.Fatalf("Expected token to work before logout, got %d", code)
+	}
+	if code := do("POST", "/users/logout", alice); code != http.StatusOK {
+		t.Fatalf("Expected logout to succeed, got %d", code)
+	}
+
+	if code := do("GET", "/users/me", alice); code != http.StatusUnauthorized {
+		t.Errorf("Expected logged-out token to be rejected, got %d", code)
+	}
+	if code := do("GET", "/users/me", bob); code != http.StatusOK {
+		t.Errorf("Expected other user's token to be unaffected, got %d", code)
+	}
+
+	// The denylist entry expires with the token
+	claims, err := tokens.Validate(alice)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if ttl := mr.TTL("auth:revoked:" + claims.ID); ttl <= 0 {
+		t.Errorf("Expected denylist entry to have a TTL, got %s", ttl)
+	}
+	if revoked, err := denylist.IsRevoked(context.Background(), claims); err != nil || !revoked {
+		t.Errorf("IsRevoked = %v, %v; want true, nil", revoked, err)
+	}
+}
+
+func TestPasswordResetWithoutCache(t *testing.T) {
+	const user = "11111111-1111-1111-1111-111111111111"
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetUserByEmail": {storetest.MustUUID(t, user), "ada@example.com"},
+	}}
+	mr := miniredis.RunT(t)
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	prev := userService
+	SetUserService(services.NewUserService(store.New(db), cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), nil, services.CacheTTLs{}))
+	t.Cleanup(func() { userService = prev })
+
+	rg := router.NewRouter()
+	rg.POST("/users/forgot-password", ForgotPassword)
+	rg.POST("/users/reset-password/{token}", ResetPassword)
+	mux := router.ServeMux(rg)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"Forgot password", "/users/forgot-password", `{"email":"ada@example.com"}`},
+		{"Reset password", "/users/reset-password/abc123", `{"new_password":"correct-horse"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Fatalf("Expected 503, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			if rr.Header().Get("Retry-After") == "" {
+				t.Error("Expected a Retry-After header")
+			}
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Error.Code != codeUnavailable {
+				t.Errorf("Body = %s, want code %s", rr.Body.String(), codeUnavailable)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+)
+
+func TestValidationReportsEveryFieldError(t *testing.T) {
+	prevUsers, prevProjects := userService, projectService
+	t.Cleanup(func() { userService, projectService = prevUsers, prevProjects })
+	// Validation fails before either service is used
+	SetUserService(services.NewUserService(nil, nil, nil, services.CacheTTLs{}))
+	SetProjectService(services.NewProjectService(nil, nil, nil, services.CacheTTLs{}))
+
+	rg := router.NewRouter()
+	rg.POST("/users/register", RegisterUser)
+	rg.POST("/users/change-password", ChangePassword)
+	rg.POST("/projects", CreateProject)
+	mux := router.ServeMux(rg)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		fields []string
+	}{
+		{
+			"Register",
+			"/users/register",
+			`{"email": "nope", "password": "short", "username": "ada lovelace"}`,
+			[]string{"email", "password", "username"},
+		},
+		{
+			"Register without an email",
+			"/users/register",
+			`{"password": "long enough", "name": "` + strings.Repeat("a", 101) + `"}`,
+			[]string{"email", "name"},
+		},
+		{
+			"Create project",
+			"/projects",
+			`{"name": "", "team_id": "not-a-uuid"}`,
+			[]string{"name", "team_id"},
+		},
+		{
+			"Change password",
+			"/users/change-password",
+			`{"current_password": "", "new_password": "short"}`,
+			[]string{"current_password", "new_password"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "11111111-1111-1111-1111-111111111111"))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected status 422, got %d (%s)", rr.Code, rr.Body.String())
+			}
+			var body router.ValidationErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if len(body.Errors) != len(tt.fields) {
+				t.Errorf("Expected errors for %v, got %v", tt.fields, body.Errors)
+			}
+			for _, field := range tt.fields {
+				if body.Errors[field] == "" {
+					t.Errorf("Missing error for %s in %v", field, body.Errors)
+				}
+			}
+		})
+	}
+}
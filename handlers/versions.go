@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// versionETag returns the entity tag of a versioned resource. The version
+// leads the tag, so clients can send the ETag back in If-Match when updating.
+func versionETag(id, updatedAt string, version int32) string {
+	return fmt.Sprintf(`"%d-%s`, version, strings.TrimPrefix(router.NewETag(id, updatedAt), `"`))
+}
+
+// expectedVersion returns the version a client based its update on. The
+// If-Match header, either an ETag from versionETag or a bare version ("3",
+// W/"3" or 3), takes precedence over a version in the request body. Zero
+// means the client did not ask for a version check.
+func expectedVersion(c *router.Context, bodyVersion int32) (int32, error) {
+	ifMatch := strings.TrimSpace(c.Request.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return bodyVersion, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	tag, _, _ = strings.Cut(tag, "-")
+	version, err := strconv.ParseInt(tag, 10, 32)
+	if err != nil || version < 1 {
+		return 0, errInvalidIfMatch
+	}
+	return int32(version), nil
+}
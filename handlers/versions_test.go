@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestTicketVersions(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	memory := cache.NewMemory()
+
+	updated := pgtype.Timestamp{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectAccess": {storetest.MustUUID(t, owner)},
+	}}
+	setVersion := func(version int32) {
+		db.Rows["GetIssueByID"] = []any{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{},
+			pgtype.Text{String: "open", Valid: true}, storetest.MustUUID(t, owner), pgtype.UUID{},
+			pgtype.Timestamp{}, updated, updated, version}
+	}
+	setVersion(3)
+
+	queries := store.New(db)
+	projects := services.NewProjectService(queries, memory, nil, services.CacheTTLs{})
+	prev := issueService
+	SetIssueService(services.NewIssueService(queries, memory, projects))
+	t.Cleanup(func() { issueService = prev })
+
+	rg := router.NewRouter()
+	rg.GET("/projects/{project_id}/tickets/{id}", GetTicket)
+	rg.PUT("/projects/{project_id}/tickets/{id}", UpdateTicket)
+	mux := router.ServeMux(rg)
+
+	do := func(method, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/projects/"+project+"/tickets/"+issue, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, owner))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("GET", "", "", "")
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d %q", rr.Code, etag)
+	}
+
+	t.Run("Unchanged ticket is not modified", func(t *testing.T) {
+		rr := do("GET", "If-None-Match", etag, "")
+		if rr.Code != http.StatusNotModified {
+			t.Fatalf("Expected 304, got %d", rr.Code)
+		}
+		if rr.Body.Len() != 0 {
+			t.Errorf("Expected empty body, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("ETag is accepted as If-Match", func(t *testing.T) {
+		rr := do("PUT", "If-Match", etag, `{"title": "Crash on logout"}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Modified ticket gets a new ETag", func(t *testing.T) {
+		setVersion(4)
+		rr := do("GET", "If-None-Match", etag, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if rr.Header().Get("ETag") == etag {
+			t.Error("Expected the ETag to change with the version")
+		}
+	})
+
+	t.Run("Stale If-Match conflicts", func(t *testing.T) {
+		rr := do("PUT", "If-Match", etag, `{"title": "Crash on logout"}`)
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("Expected 409, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Malformed If-Match", func(t *testing.T) {
+		rr := do("PUT", "If-Match", `"latest"`, `{"title": "Crash on logout"}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+}
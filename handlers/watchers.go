@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+)
+
+// WatchTicket subscribes the authenticated user to a ticket's updates
+func WatchTicket(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.WatchIssue(c.Request.Context(), c.Param("id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "You are now watching this ticket")
+}
+
+// UnwatchTicket unsubscribes the authenticated user from a ticket
+func UnwatchTicket(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := issueService.UnwatchIssue(c.Request.Context(), c.Param("id"), userID); err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "You are no longer watching this ticket")
+}
+
+// ListTicketWatchers returns the users watching a ticket
+func ListTicketWatchers(c *router.Context) {
+	if issueService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Issue service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	watchers, err := issueService.GetWatchers(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleIssueError(c, err)
+		return
+	}
+
+	c.List(watchers, router.ListMeta{})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bethel-nz/tickit/app/middleware"
+	"github.com/Bethel-nz/tickit/app/router"
+	"github.com/Bethel-nz/tickit/internal/services"
+	"github.com/Bethel-nz/tickit/internal/validator"
+)
+
+// webhookService is retrieved from the application's dependency container
+var webhookService *services.WebhookService
+
+// SetWebhookService sets the webhook service for handlers
+func SetWebhookService(service *services.WebhookService) {
+	webhookService = service
+}
+
+// WebhookRequest represents a webhook's settings. On update, omitted fields
+// are left unchanged.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Type   string   `json:"type,omitempty"` // generic (the default) or slack
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+// Validate checks the URL, type, secret and events that were given
+func (r *WebhookRequest) Validate(v *validator.Validator) {
+	if r.URL != "" {
+		v.CheckField(validator.IsValidURL(r.URL), "url", "must be an http or https URL")
+	}
+	if r.Type != "" {
+		v.CheckField(validator.PermittedValue(r.Type, services.WebhookTypes...), "type", "must be generic or slack")
+	}
+	if r.Secret != "" {
+		v.CheckField(validator.MinChars(r.Secret, 16), "secret", "must be at least 16 characters")
+		v.CheckField(validator.MaxChars(r.Secret, 256), "secret", "cannot exceed 256 characters")
+	}
+	for _, event := range r.Events {
+		if !validator.PermittedValue(event, services.WebhookEvents...) {
+			v.AddFieldError("events", "unknown event "+event)
+		}
+	}
+}
+
+// ListWebhooks returns a project's webhooks. Secrets are not included.
+func ListWebhooks(c *router.Context) {
+	if webhookService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Webhook service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	hooks, err := webhookService.ListWebhooks(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		handleWebhookError(c, err)
+		return
+	}
+
+	c.List(hooks, router.ListMeta{})
+}
+
+// CreateWebhook adds a webhook to a project. The response is the only time
+// its secret is shown.
+func CreateWebhook(c *router.Context) {
+	if webhookService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Webhook service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var req WebhookRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	hook, err := webhookService.CreateWebhook(c.Request.Context(), c.Param("id"), userID, services.WebhookInput{
+		URL:    req.URL,
+		Type:   req.Type,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		handleWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// UpdateWebhook changes a webhook's URL, secret or events
+func UpdateWebhook(c *router.Context) {
+	if webhookService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Webhook service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	var req WebhookRequest
+	if !c.BindAndValidate(&req) {
+		return
+	}
+
+	hook, err := webhookService.UpdateWebhook(c.Request.Context(), c.Param("id"), c.Param("webhook_id"), userID, services.WebhookInput{
+		URL:    req.URL,
+		Type:   req.Type,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		handleWebhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook removes a webhook from a project
+func DeleteWebhook(c *router.Context) {
+	if webhookService == nil {
+		c.Error(http.StatusInternalServerError, codeInternal, "Webhook service not initialized")
+		return
+	}
+	userID, ok := c.Request.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		c.Error(http.StatusUnauthorized, codeUnauthenticated, "User not authenticated")
+		return
+	}
+
+	if err := webhookService.DeleteWebhook(c.Request.Context(), c.Param("id"), c.Param("webhook_id"), userID); err != nil {
+		handleWebhookError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK, "Webhook deleted successfully")
+}
+
+func handleWebhookError(c *router.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidID):
+		c.Error(http.StatusBadRequest, codeInvalidID, "Invalid ID")
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.Error(http.StatusNotFound, codeProjectNotFound, "Project not found")
+	case errors.Is(err, services.ErrNotProjectOwner):
+		c.Error(http.StatusForbidden, codeForbidden, "Only the project owner can manage its webhooks")
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.Error(http.StatusNotFound, codeWebhookNotFound, "Webhook not found")
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.Error(http.StatusBadRequest, codeInvalidWebhook, err.Error())
+	default:
+		c.Error(http.StatusInternalServerError, codeInternal, "An error occurred processing your request")
+	}
+}
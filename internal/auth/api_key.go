@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scopes a request can be granted. A read key can only fetch data, a write
+// key can also change it, and an admin key can also use the admin routes
+// (when its owner is an admin). Login tokens have every scope.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every scope, from least to most access. Each scope includes
+// the ones before it.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// APIKeyPrefix starts every API key, telling keys apart from JWTs
+const APIKeyPrefix = "tk_"
+
+// apiKeyBytes is how much randomness a key carries
+const apiKeyBytes = 32
+
+// ErrInvalidAPIKey is returned for a key that doesn't exist, has been
+// revoked or has expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKey is what a valid API key grants: which key it is, whose, and its
+// scopes
+type APIKey struct {
+	ID     string
+	UserID string
+	Scopes []string
+}
+
+// Allows reports whether the key has a scope, directly or through a broader
+// one
+func (k *APIKey) Allows(scope string) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+// HasScope reports whether granted includes scope, directly or through a
+// broader one. Unknown scopes are never granted.
+func HasScope(granted []string, scope string) bool {
+	need := slices.Index(Scopes, scope)
+	if need < 0 {
+		return false
+	}
+	for _, g := range granted {
+		if slices.Index(Scopes, g) >= need {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAPIKey creates a new API key, returning it along with the hash to
+// store in its place
+func GenerateAPIKey() (key, hash string, err error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex SHA-256 of a key. Keys are random enough that a
+// fast hash is safe to store, unlike passwords.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than a JWT
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
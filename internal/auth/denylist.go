@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrTokenNotRevocable is returned for tokens issued without a jti claim
+var ErrTokenNotRevocable = errors.New("token has no ID and cannot be revoked")
+
+// Denylist records revoked tokens in Redis until they would have expired
+type Denylist struct {
+	cache *redis.Client
+	now   func() time.Time
+}
+
+// NewDenylist creates a token denylist backed by Redis
+func NewDenylist(cache *redis.Client) *Denylist {
+	return &Denylist{cache: cache, now: time.Now}
+}
+
+func denylistKey(jti string) string {
+	return fmt.Sprintf("auth:revoked:%s", jti)
+}
+
+// Revoke denylists the token described by claims for the rest of its lifetime
+func (d *Denylist) Revoke(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" {
+		return ErrTokenNotRevocable
+	}
+
+	var ttl time.Duration // Tokens without an expiry stay revoked for good
+	if claims.ExpiresAt != nil {
+		ttl = claims.ExpiresAt.Sub(d.now())
+		if ttl <= 0 {
+			return nil // Already expired, nothing to revoke
+		}
+	}
+
+	if err := d.cache.Set(ctx, denylistKey(claims.ID), claims.UserID, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token described by claims has been revoked
+func (d *Denylist) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID == "" {
+		return false, nil
+	}
+
+	n, err := d.cache.Exists(ctx, denylistKey(claims.ID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return n > 0, nil
+}
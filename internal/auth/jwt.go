@@ -2,54 +2,134 @@ package auth
 
 import (
 	"errors"
-	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Bethel-nz/tickit/internal/env"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var secretKey = env.String("TICKIT_JWT_KEY", "", env.Require).Get()
+// Token defaults used when TokenConfig leaves them unset
+const (
+	DefaultTokenExpiry = 24 * time.Hour
+	DefaultTokenIssuer = "tickit-api"
+)
 
 type Claims struct {
 	UserID string `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a JWT token for the given user ID
-// This is the primary JWT generation function to use
-func GenerateToken(userID string) (string, error) {
+// TokenConfig configures a TokenManager
+type TokenConfig struct {
+	Secret       string        // Primary signing key
+	PreviousKeys []string      // Retired keys still accepted for verification
+	Expiry       time.Duration // Token lifetime
+	Issuer       string        // iss claim written to and required of tokens
+}
+
+// TokenManager issues and validates access tokens
+type TokenManager struct {
+	keys   *Keyring
+	expiry time.Duration
+	issuer string
+	now    func() time.Time
+}
+
+// NewTokenManager creates a token manager from config
+func NewTokenManager(config TokenConfig) (*TokenManager, error) {
+	keys, err := NewKeyring(config.Secret, config.PreviousKeys...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &TokenManager{
+		keys:   keys,
+		expiry: config.Expiry,
+		issuer: config.Issuer,
+		now:    time.Now,
+	}
+	if m.expiry <= 0 {
+		m.expiry = DefaultTokenExpiry
+	}
+	if m.issuer == "" {
+		m.issuer = DefaultTokenIssuer
+	}
+	return m, nil
+}
+
+// Generate creates a token for the given user ID
+func (m *TokenManager) Generate(userID string) (string, error) {
+	now := m.now()
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "tickit-api",
+			ID:        GenerateSecureToken(16), // jti, used to revoke the token on logout
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.expiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    m.issuer,
 		},
 	}
+	return m.keys.Sign(claims)
+}
 
-	// Create token with claims and sign with secret key
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+// Validate checks a token's signature, expiry and issuer and returns its claims
+func (m *TokenManager) Validate(tokenString string) (*Claims, error) {
+	claims, err := m.keys.Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyIssuer(m.issuer, true) {
+		return nil, errors.New("invalid JWT: unexpected issuer")
+	}
+	return claims, nil
 }
 
-// ValidateJWT validates a JWT token and returns the claims if valid
-func ValidateJWT(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
+var (
+	defaultOnce    sync.Once
+	defaultManager *TokenManager
+	defaultErr     error
+)
+
+// SetDefaultTokenManager sets the manager used by the package-level
+// GenerateToken and ValidateJWT. Call it once at startup.
+func SetDefaultTokenManager(m *TokenManager) {
+	defaultOnce.Do(func() {})
+	defaultManager, defaultErr = m, nil
+}
+
+// DefaultTokenManager returns the manager set with SetDefaultTokenManager or,
+// failing that, one built from TICKIT_JWT_KEY and TICKIT_JWT_PREVIOUS_KEYS the
+// first time it is needed, so importing this package never requires the
+// environment to be set.
+func DefaultTokenManager() (*TokenManager, error) {
+	defaultOnce.Do(func() {
+		defaultManager, defaultErr = NewTokenManager(TokenConfig{
+			Secret:       env.String("TICKIT_JWT_KEY", "", env.Optional).Get(),
+			PreviousKeys: env.StringSlice("TICKIT_JWT_PREVIOUS_KEYS", nil, ",", env.Optional).Get(),
+		})
 	})
+	return defaultManager, defaultErr
+}
+
+// GenerateToken creates a JWT token for the given user ID with the default
+// token manager
+func GenerateToken(userID string) (string, error) {
+	m, err := DefaultTokenManager()
 	if err != nil {
-		return nil, fmt.Errorf("invalid JWT: %w", err)
+		return "", err
 	}
+	return m.Generate(userID)
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
-	} else {
-		return nil, errors.New("invalid JWT claims")
+// ValidateJWT validates a JWT token with the default token manager and
+// returns the claims if valid
+func ValidateJWT(tokenString string) (*Claims, error) {
+	m, err := DefaultTokenManager()
+	if err != nil {
+		return nil, err
 	}
+	return m.Validate(tokenString)
 }
 
 // GenerateJWT is an alias for GenerateToken for backward compatibility
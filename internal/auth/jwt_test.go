@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestTokenManagerRoundTrip(t *testing.T) {
+	m, err := NewTokenManager(TokenConfig{Secret: "in-test-secret", Expiry: time.Hour, Issuer: "tickit-test"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+
+	token, err := m.Generate("user-1")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	claims, err := m.Validate(token)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if claims.UserID != "user-1" {
+		t.Errorf("UserID = %q, want user-1", claims.UserID)
+	}
+	if claims.ID == "" {
+		t.Error("Expected token to carry a jti")
+	}
+	if claims.Issuer != "tickit-test" {
+		t.Errorf("Issuer = %q, want tickit-test", claims.Issuer)
+	}
+	if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != time.Hour {
+		t.Errorf("Token lifetime = %s, want 1h", got)
+	}
+}
+
+func TestTokenManagerDefaults(t *testing.T) {
+	m, err := NewTokenManager(TokenConfig{Secret: "in-test-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	if m.expiry != DefaultTokenExpiry || m.issuer != DefaultTokenIssuer {
+		t.Errorf("Expected default expiry and issuer, got %s and %q", m.expiry, m.issuer)
+	}
+}
+
+func TestTokenManagerRejectsExpiredToken(t *testing.T) {
+	m, err := NewTokenManager(TokenConfig{Secret: "in-test-secret", Expiry: time.Hour})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	m.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+
+	token, err := m.Generate("user-1")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := m.Validate(token); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("Expected expired token error, got %v", err)
+	}
+}
+
+func TestTokenManagerRejectsOtherIssuer(t *testing.T) {
+	issuer, err := NewTokenManager(TokenConfig{Secret: "shared-secret", Issuer: "someone-else"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+	m, err := NewTokenManager(TokenConfig{Secret: "shared-secret"})
+	if err != nil {
+		t.Fatalf("NewTokenManager failed: %v", err)
+	}
+
+	token, err := issuer.Generate("user-1")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := m.Validate(token); err == nil {
+		t.Error("Expected token from another issuer to be rejected")
+	}
+}
+
+func TestNewTokenManagerRequiresSecret(t *testing.T) {
+	if _, err := NewTokenManager(TokenConfig{}); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Expected ErrNoSigningKey, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoSigningKey is returned when a Keyring is built without a primary key
+var ErrNoSigningKey = errors.New("no JWT signing key configured")
+
+// hmacKey is a signing secret and the ID written to the kid header of tokens
+// it signs
+type hmacKey struct {
+	id     string
+	secret []byte
+}
+
+func newHMACKey(secret string) hmacKey {
+	sum := sha256.Sum256([]byte(secret))
+	return hmacKey{id: hex.EncodeToString(sum[:4]), secret: []byte(secret)}
+}
+
+// Keyring signs tokens with a primary key and verifies them against the
+// primary and any previous keys. To rotate, make the new secret primary and
+// keep the old one as previous until the tokens it signed have expired.
+type Keyring struct {
+	primary hmacKey
+	keys    []hmacKey // primary first, then previous keys
+}
+
+// NewKeyring creates a keyring that signs with primary and also accepts
+// tokens signed with any of previous. Empty previous keys are ignored.
+func NewKeyring(primary string, previous ...string) (*Keyring, error) {
+	if primary == "" {
+		return nil, ErrNoSigningKey
+	}
+
+	k := &Keyring{primary: newHMACKey(primary)}
+	k.keys = append(k.keys, k.primary)
+	for _, secret := range previous {
+		if secret != "" && secret != primary {
+			k.keys = append(k.keys, newHMACKey(secret))
+		}
+	}
+	return k, nil
+}
+
+// Sign creates an HS256 token for claims with the primary key
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = k.primary.id
+	return token.SignedString(k.primary.secret)
+}
+
+// Verify parses and validates tokenString. Tokens carrying a kid header are
+// checked against that key only; older tokens without one are tried against
+// every key.
+func (k *Keyring) Verify(tokenString string) (*Claims, error) {
+	var lastErr error
+	for _, key := range k.keys {
+		claims, err := parseWithKey(tokenString, key)
+		if err == nil {
+			return claims, nil
+		}
+		lastErr = err
+		if !errors.Is(err, errWrongKey) {
+			break
+		}
+	}
+	return nil, fmt.Errorf("invalid JWT: %w", lastErr)
+}
+
+// errWrongKey means the token was signed by a different key in the ring
+var errWrongKey = errors.New("token signed with a different key")
+
+func parseWithKey(tokenString string, key hmacKey) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		if kid, ok := token.Header["kid"].(string); ok && kid != key.id {
+			return nil, errWrongKey
+		}
+		return key.secret, nil
+	})
+	if err != nil {
+		// Without a kid a bad signature may just mean another key signed it
+		if errors.Is(err, errWrongKey) || (errors.Is(err, jwt.ErrTokenSignatureInvalid) && token != nil && token.Header["kid"] == nil) {
+			return nil, errWrongKey
+		}
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid JWT claims")
+	}
+	return claims, nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func testClaims(userID string, ttl time.Duration) *Claims {
+	return &Claims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+func mustKeyring(t *testing.T, primary string, previous ...string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(primary, previous...)
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	return k
+}
+
+func TestKeyringRotation(t *testing.T) {
+	before := mustKeyring(t, "old-secret")
+	during := mustKeyring(t, "new-secret", "old-secret")
+	after := mustKeyring(t, "new-secret")
+
+	oldToken, err := before.Sign(testClaims("user-1", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	newToken, err := during.Sign(testClaims("user-2", time.Hour))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	claims, err := during.Verify(oldToken)
+	if err != nil {
+		t.Fatalf("Token signed with previous key should validate during rollover: %v", err)
+	}
+	if claims.UserID != "user-1" {
+		t.Errorf("UserID = %q, want user-1", claims.UserID)
+	}
+
+	if _, err := during.Verify(newToken); err != nil {
+		t.Errorf("Token signed with new key should validate: %v", err)
+	}
+	if _, err := before.Verify(newToken); err == nil {
+		t.Error("New tokens must be signed with the new key")
+	}
+	if _, err := after.Verify(oldToken); err == nil {
+		t.Error("Old tokens should be rejected once the previous key is dropped")
+	}
+}
+
+func TestKeyringLegacyTokenWithoutKid(t *testing.T) {
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims("user-1", time.Hour))
+	tokenString, err := legacy.SignedString([]byte("old-secret"))
+	if err != nil {
+		t.Fatalf("SignedString failed: %v", err)
+	}
+
+	if _, err := mustKeyring(t, "new-secret", "old-secret").Verify(tokenString); err != nil {
+		t.Errorf("Token without kid should be tried against previous keys: %v", err)
+	}
+	if _, err := mustKeyring(t, "new-secret").Verify(tokenString); err == nil {
+		t.Error("Token without kid signed by an unknown key should be rejected")
+	}
+}
+
+func TestKeyringRejectsExpiredToken(t *testing.T) {
+	keys := mustKeyring(t, "new-secret", "old-secret")
+	tokenString, err := keys.Sign(testClaims("user-1", -time.Minute))
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	_, err = keys.Verify(tokenString)
+	if !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("Expected expired token error, got %v", err)
+	}
+}
+
+func TestNewKeyringRequiresPrimary(t *testing.T) {
+	if _, err := NewKeyring("", "old-secret"); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Expected ErrNoSigningKey, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package buildinfo holds metadata stamped into the binary when it is built,
+// e.g.
+//
+//	go build -ldflags "-X github.com/Bethel-nz/tickit/internal/buildinfo.Commit=$(git rev-parse HEAD)" ./cmd/api
+package buildinfo
+
+// Unset is reported for anything not stamped, as in local builds
+const Unset = "dev"
+
+// Set at build time with -ldflags -X
+var (
+	Commit    string // Git commit the binary was built from
+	BuildTime string // When the binary was built, in RFC3339
+	GoVersion string // Go toolchain that built it
+)
+
+// Info describes the running build
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the stamped build metadata, with Unset for any that weren't
+func Get() Info {
+	return Info{
+		Commit:    orUnset(Commit),
+		BuildTime: orUnset(BuildTime),
+		GoVersion: orUnset(GoVersion),
+	}
+}
+
+func orUnset(s string) string {
+	if s == "" {
+		return Unset
+	}
+	return s
+}
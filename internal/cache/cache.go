@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrMiss is returned by Get when a key isn't cached or has expired
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores string values under keys until their TTL runs out. Redis backs
+// it in production; Memory serves tests and single-process development.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value under key. A ttl of zero keeps it until deleted.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX stores value under key only if the key isn't set, and reports
+	// whether it did
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Del removes keys; missing keys are ignored
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Redis is a Cache backed by a go-redis client
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a Cache that stores values in Redis
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrMiss
+	}
+	return value, err
+}
+
+func (r *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *Redis) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (r *Redis) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
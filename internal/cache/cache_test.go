@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// TestCaches runs the same checks against every implementation. advance moves
+// the implementation's clock forward.
+func TestCaches(t *testing.T) {
+	implementations := map[string]func(t *testing.T) (Cache, func(time.Duration)){
+		"Memory": func(t *testing.T) (Cache, func(time.Duration)) {
+			now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+			m := NewMemory()
+			m.now = func() time.Time { return now }
+			return m, func(d time.Duration) { now = now.Add(d) }
+		},
+		"Redis": func(t *testing.T) (Cache, func(time.Duration)) {
+			mr := miniredis.RunT(t)
+			return NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr.FastForward
+		},
+	}
+	ctx := context.Background()
+
+	for name, newCache := range implementations {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Get, set and delete", func(t *testing.T) {
+				c, _ := newCache(t)
+				if _, err := c.Get(ctx, "team:1"); !errors.Is(err, ErrMiss) {
+					t.Errorf("Get of a missing key = %v, want ErrMiss", err)
+				}
+
+				if err := c.Set(ctx, "team:1", `{"name":"Platform"}`, time.Minute); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+				if err := c.Set(ctx, "team:2", "Design", time.Minute); err != nil {
+					t.Fatalf("Set failed: %v", err)
+				}
+				if got, err := c.Get(ctx, "team:1"); err != nil || got != `{"name":"Platform"}` {
+					t.Errorf("Get = %q, %v", got, err)
+				}
+
+				if err := c.Del(ctx, "team:1", "team:2", "team:3"); err != nil {
+					t.Fatalf("Del failed: %v", err)
+				}
+				for _, key := range []string{"team:1", "team:2"} {
+					if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+						t.Errorf("Get(%s) after Del = %v, want ErrMiss", key, err)
+					}
+				}
+				if err := c.Del(ctx); err != nil {
+					t.Errorf("Del with no keys failed: %v", err)
+				}
+			})
+
+			t.Run("Entries expire after their TTL", func(t *testing.T) {
+				c, advance := newCache(t)
+				c.Set(ctx, "short", "a", time.Minute)
+				c.Set(ctx, "long", "b", time.Hour)
+				c.Set(ctx, "forever", "c", 0)
+
+				advance(59 * time.Second)
+				if _, err := c.Get(ctx, "short"); err != nil {
+					t.Errorf("Entry expired early: %v", err)
+				}
+
+				advance(time.Second)
+				if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrMiss) {
+					t.Errorf("Get after the TTL = %v, want ErrMiss", err)
+				}
+				if _, err := c.Get(ctx, "long"); err != nil {
+					t.Errorf("Longer-lived entry expired: %v", err)
+				}
+
+				advance(365 * 24 * time.Hour)
+				if got, err := c.Get(ctx, "forever"); err != nil || got != "c" {
+					t.Errorf("Entry without a TTL = %q, %v, want it kept", got, err)
+				}
+			})
+
+			t.Run("SetNX only sets missing keys", func(t *testing.T) {
+				c, advance := newCache(t)
+				if ok, err := c.SetNX(ctx, "key", "first", time.Minute); err != nil || !ok {
+					t.Fatalf("SetNX of a missing key = %v, %v", ok, err)
+				}
+				if ok, err := c.SetNX(ctx, "key", "second", time.Minute); err != nil || ok {
+					t.Errorf("SetNX of a set key = %v, %v, want false", ok, err)
+				}
+				if got, _ := c.Get(ctx, "key"); got != "first" {
+					t.Errorf("Get = %q, want the first value kept", got)
+				}
+
+				advance(time.Minute)
+				if ok, err := c.SetNX(ctx, "key", "third", time.Minute); err != nil || !ok {
+					t.Errorf("SetNX of an expired key = %v, %v", ok, err)
+				}
+			})
+
+			t.Run("Setting again replaces the value and TTL", func(t *testing.T) {
+				c, advance := newCache(t)
+				c.Set(ctx, "key", "old", time.Minute)
+				c.Set(ctx, "key", "new", time.Hour)
+
+				advance(2 * time.Minute)
+				if got, err := c.Get(ctx, "key"); err != nil || got != "new" {
+					t.Errorf("Get = %q, %v, want the replacement", got, err)
+				}
+			})
+		})
+	}
+}
+
+func TestLock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := NewMemory()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	unlock, err := Lock(ctx, c, "project:1:stats", time.Minute)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := Lock(ctx, c, "project:1:stats", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Errorf("Second Lock = %v, want ErrLocked", err)
+	}
+	if _, err := Lock(ctx, c, "project:2:stats", time.Minute); err != nil {
+		t.Errorf("Lock on another key failed: %v", err)
+	}
+
+	unlock()
+	again, err := Lock(ctx, c, "project:1:stats", time.Minute)
+	if err != nil {
+		t.Fatalf("Lock after unlock failed: %v", err)
+	}
+
+	// Once a lock expires and is taken over, the old holder can't release it
+	now = now.Add(time.Minute)
+	if _, err := Lock(ctx, c, "project:1:stats", time.Minute); err != nil {
+		t.Fatalf("Lock after expiry failed: %v", err)
+	}
+	again()
+	if _, err := Lock(ctx, c, "project:1:stats", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lock after a stale unlock = %v, want ErrLocked", err)
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLocked is returned by Lock when someone else holds the lock
+var ErrLocked = errors.New("lock is held")
+
+// Lock takes the lock named key, which is released by calling unlock or after
+// ttl, whichever comes first. The expiry means a holder that crashes can't keep
+// it forever, so ttl should comfortably cover the work being done. In Redis
+// this is a SET NX PX, so the lock is shared by every instance of the API.
+func Lock(ctx context.Context, c Cache, key string, ttl time.Duration) (unlock func(), err error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	lockKey := "lock:" + key
+	ok, err := c.SetNX(ctx, lockKey, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	return func() {
+		// If the lock expired someone else may hold it by now, so only
+		// release it while it's still ours
+		ctx := context.WithoutCancel(ctx)
+		if held, err := c.Get(ctx, lockKey); err == nil && held == token {
+			c.Del(ctx, lockKey)
+		}
+	}, nil
+}
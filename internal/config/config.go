@@ -1,24 +1,90 @@
 package config
 
 import (
+	"errors"
 	"time"
 
 	"github.com/Bethel-nz/tickit/internal/env"
 	"github.com/Bethel-nz/tickit/internal/types"
 )
 
+// loader reads environment variables, collecting every problem instead of
+// stopping at the first
+type loader struct {
+	errs []error
+}
+
+func get[T any](l *loader, e env.Env[T]) T {
+	value, err := e.Lookup()
+	if err != nil {
+		l.errs = append(l.errs, err)
+	}
+	return value
+}
+
 // LoadConfig reads environment variables and returns a populated AppConfig.
-func LoadConfig() *types.AppConfig {
-	return &types.AppConfig{
-		DatabaseURL:        env.String("DATABASE_URL", "postgres://admin:adminpassword@db:5432/tickit?sslmode=disable", env.Require).Get(),
-		AppPort:            env.Int("APP_PORT", 5479, env.Optional).Get(),
-		DebugMode:          env.Bool("DEBUG_MODE", false, env.Optional).Get(),
-		RequestTimeout:     env.Duration("REQUEST_TIMEOUT", 5*time.Second, env.Optional).Get(),
-		Threshold:          env.Float64("THRESHOLD", 0.75, env.Optional).Get(),
-		RedisURL:           env.String("REDIS_URL", "localhost:6379", env.Optional).Get(),
-		MaxOpenConns:       env.Int("MAX_OPEN_CONNS", 25, env.Optional).Get(),
-		MaxIdleTime:        env.Duration("MAX_IDLE_TIME", 5*time.Minute, env.Optional).Get(),
-		ServerReadTimeout:  env.Duration("SERVER_READ_TIMEOUT", 10*time.Second, env.Optional).Get(),
-		ServerWriteTimeout: env.Duration("SERVER_WRITE_TIMEOUT", 30*time.Second, env.Optional).Get(),
+// Missing required variables and values that can't be parsed are returned
+// together as one error.
+func LoadConfig() (*types.AppConfig, error) {
+	l := &loader{}
+	cfg := &types.AppConfig{
+		DatabaseURL:          get(l, env.String("DATABASE_URL", "postgres://admin:adminpassword@db:5432/tickit?sslmode=disable", env.Require)),
+		DatabaseReplicaURL:   get(l, env.String("DATABASE_REPLICA_URL", "", env.Optional)),
+		DBRetryAttempts:      get(l, env.Int("DB_RETRY_ATTEMPTS", 3, env.Optional)),
+		DBRetryDelay:         get(l, env.Duration("DB_RETRY_DELAY", 50*time.Millisecond, env.Optional)),
+		AppPort:              get(l, env.Int("APP_PORT", 5479, env.Optional)),
+		DebugMode:            get(l, env.Bool("DEBUG_MODE", false, env.Optional)),
+		RequestTimeout:       get(l, env.Duration("REQUEST_TIMEOUT", 5*time.Second, env.Optional)),
+		Threshold:            get(l, env.Float64("THRESHOLD", 0.75, env.Optional)),
+		RedisURL:             get(l, env.String("REDIS_URL", "localhost:6379", env.Optional)),
+		MaxOpenConns:         get(l, env.Int("MAX_OPEN_CONNS", 25, env.Optional)),
+		MaxIdleTime:          get(l, env.Duration("MAX_IDLE_TIME", 5*time.Minute, env.Optional)),
+		ServerReadTimeout:    get(l, env.Duration("SERVER_READ_TIMEOUT", 10*time.Second, env.Optional)),
+		ServerWriteTimeout:   get(l, env.Duration("SERVER_WRITE_TIMEOUT", 30*time.Second, env.Optional)),
+		TLSDomains:           get(l, env.StringSlice("TLS_DOMAINS", nil, ",", env.Optional)),
+		TLSCacheDir:          get(l, env.String("TLS_CACHE_DIR", "./certs", env.Optional)),
+		HTTPRedirectPort:     get(l, env.Int("HTTP_REDIRECT_PORT", 0, env.Optional)),
+		InternalAddr:         get(l, env.String("INTERNAL_ADDR", "localhost:9090", env.Optional)),
+		CORSAllowedOrigins:   get(l, env.StringSlice("CORS_ALLOWED_ORIGINS", []string{"*"}, ",", env.Optional)),
+		CORSAllowCredentials: get(l, env.Bool("CORS_ALLOW_CREDENTIALS", false, env.Optional)),
+		CORSMaxAge:           get(l, env.Duration("CORS_MAX_AGE", 10*time.Minute, env.Optional)),
+		MaxBodyBytes:         get(l, env.Int("MAX_BODY_BYTES", 1<<20, env.Optional)),
+		SlugsFollowNames:     get(l, env.Bool("SLUGS_FOLLOW_NAMES", false, env.Optional)),
+		CreationRateLimit:    get(l, env.Int("CREATION_RATE_LIMIT", 0, env.Optional)),
+		UploadDir:            get(l, env.String("UPLOAD_DIR", "./uploads", env.Optional)),
+		UploadBaseURL:        get(l, env.String("UPLOAD_BASE_URL", "/uploads", env.Optional)),
+		AvatarMaxBytes:       get(l, env.Int("AVATAR_MAX_BYTES", 2<<20, env.Optional)),
+		AttachmentDir:        get(l, env.String("ATTACHMENT_DIR", "./attachments", env.Optional)),
+		AttachmentMaxBytes:   get(l, env.Int("ATTACHMENT_MAX_BYTES", 10<<20, env.Optional)),
+		EmailFrom:            get(l, env.String("EMAIL_FROM", "noreply@tickit.local", env.Optional)),
+		EmailFromName:        get(l, env.String("EMAIL_FROM_NAME", "Tickit", env.Optional)),
+		SMTPHost:             get(l, env.String("SMTP_HOST", "", env.Optional)),
+		SMTPPort:             get(l, env.Int("SMTP_PORT", 587, env.Optional)),
+		SMTPUsername:         get(l, env.String("SMTP_USERNAME", "", env.Optional)),
+		SMTPPassword:         get(l, env.String("SMTP_PASSWORD", "", env.Optional)),
+		SMTPUseTLS:           get(l, env.Bool("SMTP_USE_TLS", false, env.Optional)),
+		EmailMaxAttempts:     get(l, env.Int("EMAIL_MAX_ATTEMPTS", 3, env.Optional)),
+		EmailRetryDelay:      get(l, env.Duration("EMAIL_RETRY_DELAY", time.Second, env.Optional)),
+		JWTKey:               get(l, env.String("TICKIT_JWT_KEY", "", env.Optional)),
+		JWTPreviousKeys:      get(l, env.StringSlice("TICKIT_JWT_PREVIOUS_KEYS", nil, ",", env.Optional)),
+		JWTExpiry:            get(l, env.Duration("JWT_EXPIRY", 24*time.Hour, env.Optional)),
+		JWTIssuer:            get(l, env.String("JWT_ISSUER", "tickit-api", env.Optional)),
+		TrustedProxies:       get(l, env.StringSlice("TRUSTED_PROXIES", nil, ",", env.Optional)),
+		CacheTTLUser:         get(l, env.Duration("CACHE_TTL_USER", time.Hour, env.Optional)),
+		CacheTTLTeam:         get(l, env.Duration("CACHE_TTL_TEAM", time.Hour, env.Optional)),
+		CacheTTLTeamMembers:  get(l, env.Duration("CACHE_TTL_TEAM_MEMBERS", 5*time.Minute, env.Optional)),
+		CacheTTLTeamList:     get(l, env.Duration("CACHE_TTL_TEAM_LIST", 10*time.Minute, env.Optional)),
+		CacheTTLProject:      get(l, env.Duration("CACHE_TTL_PROJECT", time.Hour, env.Optional)),
+		CacheTTLProjectList:  get(l, env.Duration("CACHE_TTL_PROJECT_LIST", 10*time.Minute, env.Optional)),
+		CacheTTLProjectStats: get(l, env.Duration("CACHE_TTL_PROJECT_STATS", 5*time.Minute, env.Optional)),
+		CacheTTLComments:     get(l, env.Duration("CACHE_TTL_COMMENTS", 10*time.Minute, env.Optional)),
+		CacheTTLAccount:      get(l, env.Duration("CACHE_TTL_ACCOUNT", 30*time.Second, env.Optional)),
+		ResetTokenTTL:        get(l, env.Duration("RESET_TOKEN_TTL", 24*time.Hour, env.Optional)),
+		EmailChangeTTL:       get(l, env.Duration("EMAIL_CHANGE_TTL", 24*time.Hour, env.Optional)),
+	}
+
+	if err := errors.Join(l.errs...); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
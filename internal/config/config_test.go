@@ -0,0 +1,32 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigReportsEveryProblem(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://admin:secret@db:5432/tickit")
+	t.Setenv("APP_PORT", "eighty")
+	t.Setenv("CACHE_TTL_USER", "an hour")
+
+	_, err := LoadConfig()
+	if err == nil {
+		t.Fatal("Expected invalid values to be reported")
+	}
+	for _, key := range []string{"APP_PORT", "CACHE_TTL_USER"} {
+		if !strings.Contains(err.Error(), "environment variable "+key+":") {
+			t.Errorf("Error %q doesn't mention %s", err, key)
+		}
+	}
+
+	t.Setenv("APP_PORT", "8080")
+	t.Setenv("CACHE_TTL_USER", "1h")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.AppPort != 8080 {
+		t.Errorf("AppPort = %d, want 8080", cfg.AppPort)
+	}
+}
@@ -0,0 +1,13 @@
+-- List ordering migration file
+-- Supports the deterministic (created_at, id) ordering used by list queries
+
+CREATE INDEX idx_users_created_id ON users(created_at DESC, id);
+
+CREATE INDEX idx_projects_owner_created ON projects(owner_id, created_at DESC, id);
+CREATE INDEX idx_projects_team_created ON projects(team_id, created_at DESC, id);
+
+CREATE INDEX idx_issues_project_created ON issues(project_id, created_at DESC, id);
+CREATE INDEX idx_tasks_project_created ON tasks(project_id, created_at DESC, id);
+
+CREATE INDEX idx_comments_issue_created ON comments(issue_id, created_at, id);
+CREATE INDEX idx_comments_task_created ON comments(task_id, created_at, id);
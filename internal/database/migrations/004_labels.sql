@@ -0,0 +1,20 @@
+-- Labels migration file
+-- Project-scoped labels (bug, feature, urgent, ...) that can be attached to issues
+
+CREATE TABLE labels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(7),
+    created_at TIMESTAMP DEFAULT now(),
+    UNIQUE (project_id, name)
+);
+
+CREATE TABLE issue_labels (
+    issue_id UUID REFERENCES issues(id) ON DELETE CASCADE,
+    label_id UUID REFERENCES labels(id) ON DELETE CASCADE,
+    PRIMARY KEY (issue_id, label_id),
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_issue_labels_label ON issue_labels(label_id);
@@ -0,0 +1,18 @@
+-- Issue watchers migration file
+-- Users following an issue are notified of status changes and new comments
+
+CREATE TABLE issue_watchers (
+    issue_id UUID REFERENCES issues(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (issue_id, user_id),
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_issue_watchers_user ON issue_watchers(user_id);
+
+-- Reporters and assignees of existing issues watch them, as new ones do
+INSERT INTO issue_watchers (issue_id, user_id)
+SELECT id, reporter_id FROM issues WHERE reporter_id IS NOT NULL
+UNION
+SELECT id, assignee_id FROM issues WHERE assignee_id IS NOT NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+-- Versions migration file
+-- Row versions for optimistic concurrency control on issues and projects.
+-- Every update bumps the version; conditional updates only apply when the
+-- version the client last read is still current.
+
+ALTER TABLE issues ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE projects ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Username lookup migration file
+-- Usernames are matched case-insensitively, so index the lowercased form.
+
+CREATE INDEX idx_users_username_lower ON users (lower(username));
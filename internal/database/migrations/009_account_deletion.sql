@@ -0,0 +1,18 @@
+-- Account deletion migration file
+-- Deleting a user keeps the issues, tasks and comments they touched: their
+-- references are cleared instead of blocking the delete. Comments survive as
+-- anonymous, so comments.user_id becomes nullable.
+
+ALTER TABLE comments ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE comments DROP CONSTRAINT comments_user_id_fkey,
+    ADD CONSTRAINT comments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE issues DROP CONSTRAINT issues_reporter_id_fkey,
+    ADD CONSTRAINT issues_reporter_id_fkey FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE issues DROP CONSTRAINT issues_assignee_id_fkey,
+    ADD CONSTRAINT issues_assignee_id_fkey FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE tasks DROP CONSTRAINT tasks_assignee_id_fkey,
+    ADD CONSTRAINT tasks_assignee_id_fkey FOREIGN KEY (assignee_id) REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,22 @@
+-- Attachments migration file
+-- Files attached to issues or tasks. The contents live in file storage under
+-- storage_key; this table only holds their metadata.
+
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    issue_id UUID REFERENCES issues(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    uploader_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT now(),
+    CHECK (
+        (issue_id IS NOT NULL AND task_id IS NULL) OR
+        (issue_id IS NULL AND task_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX idx_attachments_issue ON attachments(issue_id, created_at, id);
+CREATE INDEX idx_attachments_task ON attachments(task_id, created_at, id);
@@ -0,0 +1,12 @@
+-- Comment reactions migration file
+-- Emoji reactions on comments. A user can add each emoji to a comment once.
+
+CREATE TABLE comment_reactions (
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    PRIMARY KEY (comment_id, user_id, emoji)
+);
+
+CREATE INDEX idx_comment_reactions_user ON comment_reactions(user_id);
@@ -0,0 +1,15 @@
+-- Comment edits migration file
+-- edited_at is only set when the author changes a comment's content, unlike
+-- updated_at. Each edit keeps the content it replaced in comment_revisions.
+
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMP;
+
+CREATE TABLE comment_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_comment_revisions_comment ON comment_revisions(comment_id, created_at, id);
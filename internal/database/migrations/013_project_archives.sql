@@ -0,0 +1,10 @@
+-- Project archives migration file
+-- Archiving hides a project from default listings but keeps its data. The
+-- status the project had before is kept here so unarchiving can restore it.
+
+CREATE TABLE project_archives (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    previous_status VARCHAR(15),
+    archived_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    archived_at TIMESTAMP DEFAULT now()
+);
@@ -0,0 +1,5 @@
+-- Admin users migration file
+-- Admins can list every account and disable them. A disabled account has the
+-- 'suspended' account status and can no longer log in.
+
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+-- Disabled accounts migration file
+-- disabled_at is set when an admin disables an account; a disabled account
+-- can't log in and its tokens are rejected. Accounts suspended before this
+-- migration count as disabled from their last update.
+
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+UPDATE users SET disabled_at = COALESCE(updated_at, now()) WHERE account_status = 'suspended';
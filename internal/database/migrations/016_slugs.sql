@@ -0,0 +1,41 @@
+-- Slugs migration file
+-- Projects and teams get a URL-friendly slug generated from their name, so
+-- they can be fetched as /projects/my-project as well as by ID. Slugs are
+-- unique among projects and among teams.
+
+ALTER TABLE projects ADD COLUMN slug VARCHAR(80);
+ALTER TABLE teams ADD COLUMN slug VARCHAR(80);
+
+-- Existing rows keep ASCII letters and digits from their name. Where names
+-- share a slug the oldest keeps it and the rest get the start of their ID.
+WITH base AS (
+    SELECT id, created_at,
+           COALESCE(NULLIF(btrim(regexp_replace(lower(left(name, 60)), '[^a-z0-9]+', '-', 'g'), '-'), ''), 'project') AS slug
+    FROM projects
+), ranked AS (
+    SELECT id, slug, row_number() OVER (PARTITION BY slug ORDER BY created_at, id) AS n
+    FROM base
+)
+UPDATE projects p
+SET slug = CASE WHEN r.n = 1 THEN r.slug ELSE r.slug || '-' || left(p.id::text, 8) END
+FROM ranked r
+WHERE p.id = r.id;
+
+WITH base AS (
+    SELECT id, created_at,
+           COALESCE(NULLIF(btrim(regexp_replace(lower(left(name, 60)), '[^a-z0-9]+', '-', 'g'), '-'), ''), 'team') AS slug
+    FROM teams
+), ranked AS (
+    SELECT id, slug, row_number() OVER (PARTITION BY slug ORDER BY created_at, id) AS n
+    FROM base
+)
+UPDATE teams t
+SET slug = CASE WHEN r.n = 1 THEN r.slug ELSE r.slug || '-' || left(t.id::text, 8) END
+FROM ranked r
+WHERE t.id = r.id;
+
+ALTER TABLE projects ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE teams ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_projects_slug ON projects(slug);
+CREATE UNIQUE INDEX idx_teams_slug ON teams(slug);
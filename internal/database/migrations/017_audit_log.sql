@@ -0,0 +1,20 @@
+-- Audit log migration file
+-- A record of sensitive actions, such as deleting a project or changing a
+-- member's role, for security reviews. Entries outlive the users and teams
+-- they mention, so those columns aren't foreign keys. team_id is set on
+-- entries about a team, or about something in one, so team admins can read
+-- their team's log.
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id UUID NOT NULL,
+    team_id UUID,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at DESC, id DESC);
+CREATE INDEX idx_audit_log_team_id ON audit_log(team_id, created_at DESC);
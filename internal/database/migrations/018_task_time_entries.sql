@@ -0,0 +1,14 @@
+-- Task time tracking migration file
+-- Time spent on a task, logged a piece at a time. Entries by deleted users
+-- are kept, without their author, so task totals don't shrink.
+
+CREATE TABLE task_time_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    minutes INTEGER NOT NULL CHECK (minutes > 0),
+    note TEXT,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_task_time_entries_task ON task_time_entries(task_id, created_at DESC);
@@ -0,0 +1,7 @@
+-- Issue priority migration file
+-- Lets triage rank tickets, like tasks. Existing issues have no priority
+-- until someone sets one.
+
+ALTER TABLE issues ADD COLUMN priority VARCHAR(10) CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+
+CREATE INDEX idx_issues_project_priority ON issues(project_id, priority);
@@ -0,0 +1,31 @@
+-- Issue numbers migration file
+-- Gives every issue a number within its project, counting up from 1, so
+-- tickets can be referred to as PROJ-42 rather than by UUID. Each project's
+-- last number is kept in project_issue_counters; creating an issue bumps it in
+-- the same transaction, so concurrent creates queue on the counter's row and
+-- a rolled back create gives its number back.
+
+CREATE TABLE project_issue_counters (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
+
+ALTER TABLE issues ADD COLUMN number INTEGER;
+
+-- Existing issues are numbered in the order they were created
+UPDATE issues i
+SET number = numbered.number
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY project_id ORDER BY created_at, id) AS number
+    FROM issues
+) numbered
+WHERE i.id = numbered.id;
+
+INSERT INTO project_issue_counters (project_id, last_number)
+SELECT p.id, COALESCE(MAX(i.number), 0)
+FROM projects p
+LEFT JOIN issues i ON i.project_id = p.id
+GROUP BY p.id;
+
+ALTER TABLE issues ALTER COLUMN number SET NOT NULL;
+ALTER TABLE issues ADD CONSTRAINT issues_project_number_key UNIQUE (project_id, number);
@@ -0,0 +1,18 @@
+-- Webhooks migration file
+-- Integrations such as Slack or CI subscribe to a project's events by URL.
+-- Each delivery is signed with the webhook's secret, so the receiver can
+-- check it came from us. events lists the event types to send, e.g.
+-- {issue.created,comment.created}.
+
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_webhooks_project ON webhooks(project_id, created_at);
@@ -0,0 +1,6 @@
+-- Webhook type migration file
+-- A webhook's type picks the format its deliveries are sent in: the generic
+-- JSON payload, or a message for a Slack incoming webhook. Existing webhooks
+-- keep the generic format.
+
+ALTER TABLE webhooks ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'generic' CHECK (type IN ('generic', 'slack'));
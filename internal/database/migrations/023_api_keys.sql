@@ -0,0 +1,20 @@
+-- API keys migration file
+-- CI jobs and scripts act as a user with an API key rather than a login
+-- token. Only a SHA-256 hash of each key is stored; prefix is the start of
+-- the key, shown so people can tell their keys apart. Revoked keys are kept
+-- for the record.
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    prefix VARCHAR(16) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys(user_id, created_at);
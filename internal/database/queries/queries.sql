@@ -5,7 +5,7 @@ VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, email, name, username, avatar_url, bio, email_verified, created_at, updated_at;
 
 -- name: GetUserByEmail :one
-SELECT id, email, password, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at
+SELECT id, email, password, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at, is_admin, disabled_at
 FROM users
 WHERE email = $1;
 
@@ -15,9 +15,18 @@ FROM users
 WHERE id = $1;
 
 -- name: GetUserByUsername :one
+-- Usernames match case-insensitively. An exact match wins if two accounts
+-- differ only by case.
 SELECT id, email, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at
 FROM users
-WHERE username = $1;
+WHERE lower(username) = lower(sqlc.arg('username'))
+ORDER BY username = sqlc.arg('username') DESC
+LIMIT 1;
+
+-- name: GetUsersByUsernames :many
+SELECT id, email, name, username
+FROM users
+WHERE username = ANY($1::text[]);
 
 -- name: UpdateUserPassword :exec
 UPDATE users
@@ -28,21 +37,33 @@ WHERE id = $1;
 DELETE FROM users WHERE id = $1;
 
 -- name: GetActiveProjectsCount :one
-SELECT COUNT(*) 
-FROM projects 
-WHERE owner_id = $1 AND status = 'active';
+-- Projects the user owns that haven't been archived or cancelled
+SELECT COUNT(*)
+FROM projects
+WHERE owner_id = $1 AND COALESCE(status, 'planned') NOT IN ('archived', 'cancelled');
 
 -- name: UpdateUserProfile :exec
 UPDATE users
 SET 
   name = COALESCE($2, name),
-  email = COALESCE($3, email),
-  username = COALESCE($4, username),
-  avatar_url = COALESCE($5, avatar_url),
-  bio = COALESCE($6, bio),
+  username = COALESCE($3, username),
+  avatar_url = COALESCE($4, avatar_url),
+  bio = COALESCE($5, bio),
   updated_at = now()
 WHERE id = $1;
 
+-- name: UpdateUserAvatar :execrows
+UPDATE users
+SET avatar_url = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: UpdateUserEmail :execrows
+-- Applies a confirmed email change. The user proved they own the address by
+-- following the confirmation link, so it counts as verified.
+UPDATE users
+SET email = $2, email_verified = true, updated_at = now()
+WHERE id = $1;
+
 -- name: GetUserProfile :one
 SELECT id, email, name, username, avatar_url, bio, email_verified, created_at, updated_at
 FROM users
@@ -66,29 +87,73 @@ WHERE id = $1;
 -- name: ListUsers :many
 SELECT id, email, name, username, avatar_url, email_verified, account_status, created_at
 FROM users
-ORDER BY created_at DESC
+ORDER BY created_at DESC, id
 LIMIT $1 OFFSET $2;
 
+-- name: ListUsersPage :many
+-- Every account, newest first, for admins. Keyset pagination: pass the
+-- created_at and id of the last user on the previous page, or NULLs for the
+-- first page. A search matches email, name or username case-insensitively;
+-- NULL lists everyone.
+SELECT id, email, name, username, email_verified, account_status, is_admin, last_login_at, created_at, disabled_at
+FROM users
+WHERE (sqlc.narg('search')::text IS NULL
+       OR email ILIKE '%' || sqlc.narg('search') || '%'
+       OR name ILIKE '%' || sqlc.narg('search') || '%'
+       OR username ILIKE '%' || sqlc.narg('search') || '%')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetUserAccess :one
+-- What the user may do: admins manage other accounts, and disabled accounts
+-- can't do anything.
+SELECT is_admin, disabled_at
+FROM users
+WHERE id = $1;
+
+-- name: DisableUser :execrows
+-- Disabling an account twice keeps the time it was first disabled
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, now()), account_status = 'suspended', updated_at = now()
+WHERE id = $1;
+
 --------------------------------------------------------
 -- Teams
 -- name: CreateTeam :one
-INSERT INTO teams (name, description, avatar_url)
-VALUES ($1, $2, $3)
-RETURNING id, name, description, avatar_url, created_at, updated_at;
+INSERT INTO teams (name, description, avatar_url, slug)
+VALUES ($1, $2, $3, $4)
+RETURNING id, name, description, avatar_url, created_at, updated_at, slug;
 
 -- name: GetTeamByID :one
-SELECT id, name, description, avatar_url, created_at, updated_at
+SELECT id, name, description, avatar_url, created_at, updated_at, slug
 FROM teams
 WHERE id = $1;
 
 -- name: UpdateTeam :exec
 UPDATE teams
 SET 
-  name = COALESCE($2, name),
-  description = COALESCE($3, description),
-  avatar_url = COALESCE($4, avatar_url),
+  name = COALESCE(sqlc.arg('name'), name),
+  description = COALESCE(sqlc.arg('description'), description),
+  avatar_url = COALESCE(sqlc.arg('avatar_url'), avatar_url),
+  slug = COALESCE(sqlc.narg('slug'), slug),
   updated_at = now()
-WHERE id = $1;
+WHERE id = sqlc.arg('id');
+
+-- name: GetTeamIDBySlug :one
+SELECT id
+FROM teams
+WHERE slug = $1;
+
+-- name: ListTeamSlugs :many
+-- Slugs equal to base or base with a suffix, e.g. my-team-2, so a new slug
+-- can be picked that none of them have. exclude_id leaves out the team being
+-- renamed.
+SELECT slug
+FROM teams
+WHERE (slug = sqlc.arg('base')::text OR slug LIKE sqlc.arg('base')::text || '-%')
+  AND id IS DISTINCT FROM sqlc.narg('exclude_id')::uuid;
 
 -- name: DeleteTeam :exec
 DELETE FROM teams WHERE id = $1;
@@ -101,6 +166,22 @@ VALUES ($1, $2, $3);
 DELETE FROM team_members
 WHERE team_id = $1 AND user_id = $2;
 
+-- name: ReassignTeamMemberIssues :execrows
+-- Moves a member's open issues in the team's projects to another assignee,
+-- or unassigns them when the new assignee is NULL.
+UPDATE issues
+SET assignee_id = sqlc.narg('new_assignee_id'), updated_at = now(), version = version + 1
+WHERE assignee_id = sqlc.arg('assignee_id')
+  AND status IS DISTINCT FROM 'closed'
+  AND project_id IN (SELECT id FROM projects WHERE team_id = sqlc.arg('team_id'));
+
+-- name: ReassignTeamMemberTasks :execrows
+UPDATE tasks
+SET assignee_id = sqlc.narg('new_assignee_id'), updated_at = now()
+WHERE assignee_id = sqlc.arg('assignee_id')
+  AND status IS DISTINCT FROM 'done'
+  AND project_id IN (SELECT id FROM projects WHERE team_id = sqlc.arg('team_id'));
+
 -- name: GetTeamMember :one
 SELECT team_id, user_id, role, created_at
 FROM team_members
@@ -114,21 +195,27 @@ WHERE team_id = $1 AND user_id = $2;
 -- name: GetTeamAdmins :many
 SELECT user_id, role
 FROM team_members
-WHERE team_id = $1 AND role = 'admin';
+WHERE team_id = $1 AND role = 'admin'
+ORDER BY created_at, user_id;
 
 -- name: GetTeamMembers :many
 SELECT u.id, u.email, u.name, u.username, u.avatar_url, tm.role
 FROM team_members tm
 JOIN users u ON u.id = tm.user_id
 WHERE tm.team_id = $1
-ORDER BY tm.created_at;
+ORDER BY tm.created_at, u.id;
 
 -- name: GetUserTeams :many
-SELECT t.id, t.name, t.description, t.avatar_url, tm.role
+SELECT t.id, t.name, t.description, t.avatar_url, tm.role, t.created_at, t.updated_at,
+  (SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id) AS member_count,
+  (SELECT o.user_id FROM team_members o
+   WHERE o.team_id = t.id AND o.role = 'owner'
+   ORDER BY o.created_at LIMIT 1)::uuid AS owner_id,
+  t.slug
 FROM teams t
 JOIN team_members tm ON t.id = tm.team_id
 WHERE tm.user_id = $1
-ORDER BY t.name;
+ORDER BY t.name, t.id;
 
 -- name: CheckTeamMembership :one
 SELECT EXISTS (
@@ -144,33 +231,91 @@ WHERE team_id = $1 AND user_id = $2;
 --------------------------------------------------------
 -- Projects
 -- name: CreateProject :one
-INSERT INTO projects (name, description, owner_id, team_id, status)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, name, description, owner_id, team_id, status, created_at, updated_at;
+INSERT INTO projects (name, description, owner_id, team_id, status, slug)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug;
 
 -- name: GetUserProjects :many
-SELECT id, name, description, owner_id, team_id, status, created_at, updated_at
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
 FROM projects
 WHERE owner_id = $1
-ORDER BY updated_at DESC;
+ORDER BY created_at DESC, id;
+
+-- name: GetAccessibleProjects :many
+-- Projects the user owns or that belong to one of their teams, each listed
+-- once. Archived projects are left out unless include_archived is set.
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
+FROM projects
+WHERE (owner_id = sqlc.arg('user_id') OR team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.arg('user_id')))
+  AND (sqlc.arg('include_archived')::boolean OR status IS DISTINCT FROM 'archived')
+ORDER BY created_at DESC, id;
+
+-- name: GetUserProjectsPage :many
+-- The projects the user owns or that belong to one of their teams. Keyset
+-- pagination: pass the created_at and id of the last project on the previous
+-- page, or NULLs for the first page. Archived projects are left out unless
+-- include_archived is set.
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
+FROM projects
+WHERE (owner_id = sqlc.arg('user_id') OR team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.arg('user_id')))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+  AND (sqlc.arg('include_archived')::boolean OR status IS DISTINCT FROM 'archived')
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetProjectByID :one
-SELECT id, name, description, owner_id, team_id, status, created_at, updated_at
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
 FROM projects
 WHERE id = $1;
 
+-- name: GetProjectAccess :one
+SELECT owner_id, team_id
+FROM projects
+WHERE id = $1;
+
+-- name: GetProjectIDBySlug :one
+SELECT id
+FROM projects
+WHERE slug = $1;
+
+-- name: ListProjectSlugs :many
+-- Slugs equal to base or base with a suffix, e.g. my-project-2, so a new slug
+-- can be picked that none of them have. exclude_id leaves out the project
+-- being renamed.
+SELECT slug
+FROM projects
+WHERE (slug = sqlc.arg('base')::text OR slug LIKE sqlc.arg('base')::text || '-%')
+  AND id IS DISTINCT FROM sqlc.narg('exclude_id')::uuid;
+
 -- name: DeleteProject :exec
 DELETE FROM projects WHERE id = $1;
 
--- name: UpdateProjectDetails :exec
+-- name: UpdateProjectDetails :execrows
 UPDATE projects
 SET 
-  name = COALESCE($2, name),
-  description = COALESCE($3, description),
-  status = COALESCE($4, status),
-  team_id = COALESCE($5, team_id),
-  updated_at = now()
-WHERE id = $1;
+  name = COALESCE(sqlc.arg('name'), name),
+  description = COALESCE(sqlc.arg('description'), description),
+  status = COALESCE(sqlc.arg('status'), status),
+  team_id = COALESCE(sqlc.arg('team_id'), team_id),
+  slug = COALESCE(sqlc.narg('slug'), slug),
+  updated_at = now(),
+  version = version + 1
+WHERE id = sqlc.arg('id') AND version = sqlc.arg('version');
+
+-- name: SaveProjectArchive :exec
+INSERT INTO project_archives (project_id, previous_status, archived_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (project_id) DO UPDATE
+SET previous_status = EXCLUDED.previous_status, archived_by = EXCLUDED.archived_by, archived_at = now();
+
+-- name: GetProjectArchive :one
+SELECT project_id, previous_status, archived_by, archived_at
+FROM project_archives
+WHERE project_id = $1;
+
+-- name: DeleteProjectArchive :exec
+DELETE FROM project_archives WHERE project_id = $1;
 
 -- name: GetTeamProjects :many
 SELECT 
@@ -181,16 +326,18 @@ SELECT
   p.team_id,  -- Make sure TeamID is explicitly included
   p.status, 
   p.created_at, 
-  p.updated_at
+  p.updated_at,
+  p.version,
+  p.slug
 FROM projects p
 WHERE p.team_id = $1
-ORDER BY p.created_at DESC;
+ORDER BY p.created_at DESC, p.id;
 
 -- name: GetProjectsByStatus :many
 SELECT id, name, description, owner_id, team_id, created_at, updated_at , status
 FROM projects
 WHERE status = $1
-ORDER BY updated_at DESC
+ORDER BY created_at DESC, id
 LIMIT $2 OFFSET $3;
 
 -- name: GetProjectStats :one
@@ -206,10 +353,18 @@ SELECT
 
 --------------------------------------------------------
 -- Issues
+-- name: NextIssueNumber :one
+-- Takes the project's next issue number. The counter's row stays locked
+-- until the transaction ends, so run it in the one that creates the issue.
+INSERT INTO project_issue_counters (project_id, last_number)
+VALUES ($1, 1)
+ON CONFLICT (project_id) DO UPDATE SET last_number = project_issue_counters.last_number + 1
+RETURNING last_number;
+
 -- name: CreateIssue :one
-INSERT INTO issues (project_id, title, description, status, reporter_id, assignee_id, due_date)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at;
+INSERT INTO issues (project_id, title, description, status, reporter_id, assignee_id, due_date, priority, number)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number;
 
 -- name: GetProjectIssues :many
 SELECT 
@@ -222,28 +377,77 @@ SELECT
   i.assignee_id,
   i.due_date, 
   i.created_at, 
-  i.updated_at
+  i.updated_at,
+  i.version,
+  i.priority,
+  i.number
 FROM issues i
 WHERE i.project_id = $1
-ORDER BY i.created_at DESC;
+ORDER BY i.created_at DESC, i.id;
+
+-- name: GetProjectIssuesPage :many
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = sqlc.arg('project_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetProjectIssuesFiltered :many
+-- A project's issues, keeping only those with the given status and priority
+-- and due strictly between due_after and due_before, each filter skipped when
+-- NULL. overdue keeps issues past due that aren't closed. Newest first, or
+-- most urgent first when by_priority is set, with issues that have no
+-- priority last.
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = sqlc.arg('project_id')
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+  AND (sqlc.narg('priority')::text IS NULL OR priority = sqlc.narg('priority')::text)
+  AND (sqlc.narg('due_after')::timestamp IS NULL OR due_date > sqlc.narg('due_after')::timestamp)
+  AND (sqlc.narg('due_before')::timestamp IS NULL OR due_date < sqlc.narg('due_before')::timestamp)
+  AND (NOT sqlc.arg('overdue')::boolean OR (due_date < now() AND status IS DISTINCT FROM 'closed'))
+ORDER BY
+  CASE WHEN sqlc.arg('by_priority')::boolean THEN
+    CASE priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END
+  END,
+  created_at DESC, id DESC;
 
 -- name: UpdateIssueStatus :exec
 UPDATE issues
-SET status = $2, updated_at = now()
+SET status = $2, updated_at = now(), version = version + 1
 WHERE id = $1;
 
 -- name: DeleteIssue :exec
 DELETE FROM issues WHERE id = $1;
 
+-- name: DeleteIssueComments :exec
+DELETE FROM comments WHERE issue_id = $1;
+
+-- name: DeleteIssueAttachments :exec
+DELETE FROM attachments WHERE issue_id = $1;
+
+-- name: DeleteIssueWatchers :exec
+DELETE FROM issue_watchers WHERE issue_id = $1;
+
+-- name: DeleteIssueLabels :exec
+DELETE FROM issue_labels WHERE issue_id = $1;
+
 -- name: GetIssuesAssignedToUser :many
+-- Leaves out issues in projects the user can no longer access, as neither the
+-- owner nor a member of the project's team
 SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.due_date, 
-       i.created_at, i.updated_at, p.name AS project_name
+       i.created_at, i.updated_at, p.name AS project_name, i.priority, i.number
 FROM issues i
 JOIN projects p ON i.project_id = p.id
-WHERE i.assignee_id = $1
-ORDER BY i.due_date ASC NULLS LAST, i.created_at DESC;
+WHERE i.assignee_id = sqlc.arg('assignee_id')
+  AND (p.owner_id = sqlc.arg('assignee_id') OR EXISTS (
+    SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = sqlc.arg('assignee_id')))
+  AND (sqlc.narg('status')::text IS NULL OR i.status = sqlc.narg('status')::text)
+ORDER BY i.due_date ASC NULLS LAST, i.created_at DESC, i.id;
 
--- name: UpdateIssueDetails :exec
+-- name: UpdateIssueDetails :execrows
 UPDATE issues
 SET 
   title = COALESCE($2, title),
@@ -251,14 +455,21 @@ SET
   status = COALESCE($4, status),
   assignee_id = COALESCE($5, assignee_id),
   due_date = COALESCE($6, due_date),
-  updated_at = now()
-WHERE id = $1;
+  priority = COALESCE($7, priority),
+  updated_at = now(),
+  version = version + 1
+WHERE id = $1 AND version = $8;
 
 -- name: GetIssueByID :one
-SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
 FROM issues
 WHERE id = $1;
 
+-- name: GetIssueByNumber :one
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = $1 AND number = $2;
+
 -- name: GetIssuesByStatus :many
 SELECT 
   i.id, 
@@ -269,10 +480,12 @@ SELECT
   i.assignee_id, 
   i.due_date, 
   i.created_at, 
-  i.updated_at
+  i.updated_at,
+  i.priority,
+  i.number
 FROM issues i
 WHERE i.project_id = $1 AND i.status = $2
-ORDER BY i.created_at DESC;
+ORDER BY i.created_at DESC, i.id;
 
 -- name: GetRecentIssues :many
 SELECT i.id, i.project_id, i.title, i.status, i.due_date, p.name AS project_name
@@ -286,10 +499,119 @@ WHERE i.project_id IN (
     JOIN team_members tm ON t.id = tm.team_id
     WHERE tm.user_id = $1
 )
-ORDER BY i.created_at DESC
+ORDER BY i.created_at DESC, i.id
 LIMIT $2;
 
 --------------------------------------------------------
+-- Labels
+-- name: CreateLabel :one
+INSERT INTO labels (project_id, name, color)
+VALUES ($1, $2, $3)
+RETURNING id, project_id, name, color, created_at;
+
+-- name: GetLabelByID :one
+SELECT id, project_id, name, color, created_at
+FROM labels
+WHERE id = $1;
+
+-- name: GetProjectLabels :many
+SELECT id, project_id, name, color, created_at
+FROM labels
+WHERE project_id = $1
+ORDER BY name, id;
+
+-- name: DeleteLabel :exec
+DELETE FROM labels WHERE id = $1;
+
+-- name: AddIssueLabel :exec
+INSERT INTO issue_labels (issue_id, label_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveIssueLabel :exec
+DELETE FROM issue_labels WHERE issue_id = $1 AND label_id = $2;
+
+-- name: GetIssueLabels :many
+SELECT l.id, l.project_id, l.name, l.color, l.created_at
+FROM labels l
+JOIN issue_labels il ON il.label_id = l.id
+WHERE il.issue_id = $1
+ORDER BY l.name, l.id;
+
+-- name: GetProjectIssuesWithAllLabels :many
+SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.assignee_id,
+       i.due_date, i.created_at, i.updated_at, i.version, i.priority, i.number
+FROM issues i
+WHERE i.project_id = $1
+  AND i.id IN (
+    SELECT il.issue_id
+    FROM issue_labels il
+    JOIN labels l ON l.id = il.label_id
+    WHERE l.project_id = $1 AND l.name = ANY($2::text[])
+    GROUP BY il.issue_id
+    HAVING COUNT(DISTINCT l.name) = cardinality($2::text[])
+  )
+ORDER BY i.created_at DESC, i.id;
+
+-- name: GetProjectIssuesWithAnyLabel :many
+SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.assignee_id,
+       i.due_date, i.created_at, i.updated_at, i.version, i.priority, i.number
+FROM issues i
+WHERE i.project_id = $1
+  AND EXISTS (
+    SELECT 1
+    FROM issue_labels il
+    JOIN labels l ON l.id = il.label_id
+    WHERE il.issue_id = i.id AND l.name = ANY($2::text[])
+  )
+ORDER BY i.created_at DESC, i.id;
+
+--------------------------------------------------------
+-- Issue Watchers
+-- name: AddIssueWatcher :exec
+INSERT INTO issue_watchers (issue_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveIssueWatcher :exec
+DELETE FROM issue_watchers WHERE issue_id = $1 AND user_id = $2;
+
+-- name: GetIssueWatchers :many
+SELECT u.id, u.name, u.username, u.avatar_url, w.created_at AS watching_since
+FROM issue_watchers w
+JOIN users u ON u.id = w.user_id
+WHERE w.issue_id = $1
+ORDER BY w.created_at, u.id;
+
+-- Attachments
+-- name: CreateAttachment :one
+INSERT INTO attachments (issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at;
+
+-- name: GetAttachmentByID :one
+SELECT id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments
+WHERE id = $1;
+
+-- name: GetIssueAttachments :many
+SELECT id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments
+WHERE issue_id = $1
+ORDER BY created_at, id;
+
+-- name: DeleteAttachment :exec
+DELETE FROM attachments WHERE id = $1;
+
+-- name: GetProjectAttachmentKeys :many
+-- Where the files attached to a project's issues and tasks are stored, so they
+-- can be removed along with the project
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+WHERE i.project_id = $1 OR t.project_id = $1;
+
 -- Tasks
 -- name: CreateTask :one
 INSERT INTO tasks (project_id, assignee_id, title, description, status, priority, due_date)
@@ -297,12 +619,19 @@ VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING id, project_id, assignee_id, title, description, status, priority, due_date, created_at, updated_at;
 
 -- name: GetUserTasks :many
+-- Leaves out tasks in projects the user can no longer access, as neither the
+-- owner nor a member of the project's team
 SELECT t.id, t.project_id, t.title, t.description, t.status, t.priority, t.due_date, 
        t.created_at, t.updated_at, p.name AS project_name
 FROM tasks t
 JOIN projects p ON t.project_id = p.id
-WHERE t.assignee_id = $1
-ORDER BY t.due_date ASC NULLS LAST, t.priority DESC, t.created_at DESC;
+WHERE t.assignee_id = sqlc.arg('assignee_id')
+  AND (p.owner_id = sqlc.arg('assignee_id') OR EXISTS (
+    SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = sqlc.arg('assignee_id')))
+  AND (sqlc.narg('status')::text IS NULL OR t.status = sqlc.narg('status')::text)
+ORDER BY
+  CASE WHEN sqlc.arg('latest_due_first')::boolean THEN t.due_date END DESC NULLS LAST,
+  t.due_date ASC NULLS LAST, t.priority DESC, t.created_at DESC, t.id;
 
 -- name: UpdateTaskStatus :exec
 UPDATE tasks
@@ -312,11 +641,20 @@ WHERE id = $1;
 -- name: DeleteTask :exec
 DELETE FROM tasks WHERE id = $1;
 
+-- name: DeleteTaskComments :exec
+DELETE FROM comments WHERE task_id = $1;
+
+-- name: DeleteTaskAttachments :exec
+DELETE FROM attachments WHERE task_id = $1;
+
+-- name: DeleteTaskTimeEntries :exec
+DELETE FROM task_time_entries WHERE task_id = $1;
+
 -- name: GetProjectTasks :many
 SELECT id, assignee_id, title, description, status, priority, due_date, created_at, updated_at
 FROM tasks
 WHERE project_id = $1
-ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC;
+ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC, id;
 
 -- name: UpdateTaskDetails :exec
 UPDATE tasks
@@ -339,7 +677,7 @@ WHERE id = $1;
 SELECT id, project_id, assignee_id, title, description, priority, due_date, created_at, updated_at
 FROM tasks
 WHERE project_id = $1 AND status = $2
-ORDER BY priority DESC, due_date ASC NULLS LAST;
+ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC, id;
 
 -- name: GetOverdueTasks :many
 SELECT t.id, t.project_id, t.assignee_id, t.title, t.status, t.priority, t.due_date, 
@@ -347,35 +685,58 @@ SELECT t.id, t.project_id, t.assignee_id, t.title, t.status, t.priority, t.due_d
 FROM tasks t
 JOIN projects p ON t.project_id = p.id
 WHERE t.due_date < now() AND t.status != 'done' AND t.assignee_id = $1
-ORDER BY t.due_date ASC;
+ORDER BY t.due_date ASC, t.id;
+
+-- name: CreateTaskTimeEntry :one
+INSERT INTO task_time_entries (task_id, user_id, minutes, note)
+VALUES ($1, $2, $3, $4)
+RETURNING id, task_id, user_id, minutes, note, created_at;
+
+-- name: GetTaskTimeEntries :many
+-- Newest first, with the name of whoever logged each entry
+SELECT e.id, e.task_id, e.user_id, e.minutes, e.note, e.created_at, u.name, u.username
+FROM task_time_entries e
+LEFT JOIN users u ON u.id = e.user_id
+WHERE e.task_id = $1
+ORDER BY e.created_at DESC, e.id DESC;
+
+-- name: GetTaskTimeByUser :many
+-- Minutes logged on a task per user, most first. Entries by deleted users are
+-- totalled under a NULL user_id.
+SELECT e.user_id, u.name, u.username, SUM(e.minutes)::bigint AS minutes
+FROM task_time_entries e
+LEFT JOIN users u ON u.id = e.user_id
+WHERE e.task_id = $1
+GROUP BY e.user_id, u.name, u.username
+ORDER BY minutes DESC, e.user_id;
 
 --------------------------------------------------------
 -- Comments
 -- name: CreateComment :one
 INSERT INTO comments (content, user_id, issue_id, task_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, content, user_id, issue_id, task_id, created_at, updated_at;
+RETURNING id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at;
 
 
 -- name: GetIssueComments :many
-SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at,
-       u.email, u.name, u.username, u.avatar_url
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.issue_id = $1
-ORDER BY c.created_at ASC;
+ORDER BY c.created_at ASC, c.id;
 
 -- name: GetTaskComments :many
-SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at,
-       u.email, u.name, u.username, u.avatar_url
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.task_id = $1
-ORDER BY c.created_at ASC;
+ORDER BY c.created_at ASC, c.id;
 
 -- name: UpdateComment :exec
 UPDATE comments
-SET content = $2, updated_at = now()
+SET content = $2, updated_at = now(), edited_at = now()
 WHERE id = $1;
 
 -- name: DeleteComment :exec
@@ -386,17 +747,17 @@ WHERE id = $1;
 SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
        u.name AS user_name, u.username, u.avatar_url
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.issue_id = $1
-ORDER BY c.created_at ASC;
+ORDER BY c.created_at ASC, c.id;
 
 -- name: GetCommentsByTask :many
 SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
        u.name AS user_name, u.username, u.avatar_url
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.task_id = $1
-ORDER BY c.created_at ASC;
+ORDER BY c.created_at ASC, c.id;
 
 -- name: UpdateCommentContent :exec
 UPDATE comments
@@ -404,8 +765,20 @@ SET content = $2, updated_at = now()
 WHERE id = $1 AND user_id = $3;
 
 
+-- name: GetProjectComments :many
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url,
+       COALESCE(i.title, t.title)::text AS parent_title
+FROM comments c
+LEFT JOIN users u ON c.user_id = u.id
+LEFT JOIN issues i ON c.issue_id = i.id
+LEFT JOIN tasks t ON c.task_id = t.id
+WHERE i.project_id = $1 OR t.project_id = $1
+ORDER BY c.created_at DESC, c.id
+LIMIT $2 OFFSET $3;
+
 -- name: GetCommentByID :one
-SELECT id, content, user_id, issue_id, task_id, created_at, updated_at
+SELECT id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at
 FROM comments
 WHERE id = $1;
 
@@ -413,15 +786,70 @@ WHERE id = $1;
 SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at,
        u.name AS user_name, u.username
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.issue_id IN (
     SELECT id FROM issues WHERE issues.assignee_id = $1
 ) OR c.task_id IN (
     SELECT id FROM tasks WHERE tasks.assignee_id = $1
 )
-ORDER BY c.created_at DESC
+ORDER BY c.created_at DESC, c.id
 LIMIT $2;
 
+-- Comment Revisions
+-- name: CreateCommentRevision :exec
+-- Keeps a comment's current content before it is edited
+INSERT INTO comment_revisions (comment_id, content, edited_by)
+SELECT c.id, c.content, c.user_id
+FROM comments c
+WHERE c.id = $1;
+
+-- name: GetCommentRevisions :many
+SELECT id, comment_id, content, edited_by, created_at
+FROM comment_revisions
+WHERE comment_id = $1
+ORDER BY created_at, id;
+
+-- Comment Reactions
+-- name: AddCommentReaction :execrows
+INSERT INTO comment_reactions (comment_id, user_id, emoji)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveCommentReaction :execrows
+DELETE FROM comment_reactions
+WHERE comment_id = $1 AND user_id = $2 AND emoji = $3;
+
+-- name: GetCommentReactionCounts :many
+-- Counts each emoji on the given comments, in the order it was first used
+SELECT comment_id, emoji, COUNT(*) AS count
+FROM comment_reactions
+WHERE comment_id = ANY(sqlc.arg('comment_ids')::uuid[])
+GROUP BY comment_id, emoji
+ORDER BY comment_id, MIN(created_at), emoji;
+
+-- Notifications
+-- name: CreateNotification :one
+INSERT INTO notifications (user_id, actor_id, type, subject, message, issue_id, team_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, actor_id, type, subject, message, issue_id, team_id, read_at, created_at;
+
+-- name: ListNotifications :many
+SELECT id, user_id, actor_id, type, subject, message, issue_id, team_id, read_at, created_at
+FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountUnread :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
 --------------------------------------------------------
 -- Dashboard Queries
 -- name: GetUserDashboardStats :one
@@ -475,7 +903,7 @@ WITH user_activities AS (
   WHERE t.assignee_id = $1 AND t.updated_at > t.created_at
 )
 SELECT * FROM user_activities
-ORDER BY activity_time DESC
+ORDER BY activity_time DESC, entity_id
 LIMIT $2;
 
 -- name: SearchEntities :many
@@ -511,5 +939,189 @@ WITH search_results AS (
     AND (t.title ILIKE '%' || $2 || '%' OR t.description ILIKE '%' || $2 || '%')
 )
 SELECT * FROM search_results
-ORDER BY created_at DESC
+ORDER BY created_at DESC, entity_id
 LIMIT $3;
+
+-- Exports
+-- Exports read in keyset pages, oldest first: pass the created_at and id of
+-- the last row of the previous page, or NULLs for the first page.
+
+-- name: ExportUserIssues :many
+-- Issues the user reported or is assigned to
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE (reporter_id = sqlc.arg('user_id') OR assignee_id = sqlc.arg('user_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ExportUserComments :many
+SELECT id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at
+FROM comments
+WHERE user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ExportTeamIssues :many
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id IN (SELECT id FROM projects WHERE team_id = sqlc.arg('team_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ExportTeamComments :many
+-- Comments on issues and tasks in the team's projects
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at
+FROM comments c
+LEFT JOIN issues i ON c.issue_id = i.id
+LEFT JOIN tasks t ON c.task_id = t.id
+WHERE COALESCE(i.project_id, t.project_id) IN (SELECT id FROM projects WHERE team_id = sqlc.arg('team_id'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (c.created_at, c.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY c.created_at, c.id
+LIMIT sqlc.arg('limit');
+
+-- Account deletion
+
+-- name: GetTeamAttachmentKeys :many
+-- Where the files attached to issues and tasks in a team's projects are stored
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+JOIN projects p ON p.id = COALESCE(i.project_id, t.project_id)
+WHERE p.team_id = $1;
+
+-- name: GetUserProjectAttachmentKeys :many
+-- Where the files attached to issues and tasks in a user's projects are stored
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+JOIN projects p ON p.id = COALESCE(i.project_id, t.project_id)
+WHERE p.owner_id = $1;
+
+-- name: DeleteTeamProjects :exec
+DELETE FROM projects
+WHERE team_id = $1;
+
+-- name: TransferTeamProjects :execrows
+-- Hands a user's projects in a team over to another member
+UPDATE projects
+SET owner_id = sqlc.arg('new_owner_id'), updated_at = now(), version = version + 1
+WHERE owner_id = sqlc.arg('owner_id') AND team_id = sqlc.arg('team_id');
+
+-- name: DeleteUserProjects :execrows
+DELETE FROM projects
+WHERE owner_id = $1;
+
+-- name: DetachUserFromIssues :exec
+UPDATE issues
+SET reporter_id = NULLIF(reporter_id, $1),
+    assignee_id = NULLIF(assignee_id, $1),
+    updated_at = now(), version = version + 1
+WHERE reporter_id = $1 OR assignee_id = $1;
+
+-- name: UnassignUserTasks :exec
+UPDATE tasks
+SET assignee_id = NULL, updated_at = now()
+WHERE assignee_id = $1;
+
+-- name: AnonymizeUserComments :many
+-- Keeps the user's comments but drops the author, returning where they were
+-- so cached comment lists can be invalidated
+UPDATE comments
+SET user_id = NULL
+WHERE user_id = $1
+RETURNING issue_id, task_id;
+
+-- name: RemoveUserFromAllTeams :exec
+DELETE FROM team_members
+WHERE user_id = $1;
+
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (actor_id, action, target_type, target_id, team_id, metadata)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListAuditEntries :many
+-- Audit entries, newest first. Each filter is skipped when NULL: team_id
+-- keeps one team's entries, action one kind of action, and since and until a
+-- time range, until being exclusive. Keyset pagination: pass the created_at
+-- and id of the last entry on the previous page, or NULLs for the first page.
+SELECT id, actor_id, action, target_type, target_id, team_id, metadata, created_at
+FROM audit_log
+WHERE (sqlc.narg('team_id')::uuid IS NULL OR team_id = sqlc.narg('team_id'))
+  AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+--------------------------------------------------------
+-- Webhooks
+-- name: CreateWebhook :one
+INSERT INTO webhooks (project_id, url, secret, events, created_by, type)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, project_id, url, secret, events, created_by, created_at, updated_at, type;
+
+-- name: GetWebhookByID :one
+SELECT id, project_id, url, secret, events, created_by, created_at, updated_at, type
+FROM webhooks
+WHERE id = $1;
+
+-- name: GetProjectWebhooks :many
+SELECT id, project_id, url, secret, events, created_by, created_at, updated_at, type
+FROM webhooks
+WHERE project_id = $1
+ORDER BY created_at, id;
+
+-- name: UpdateWebhook :one
+-- Replaces whichever of the URL, secret, events and type aren't NULL
+UPDATE webhooks
+SET
+  url = COALESCE(sqlc.narg('url'), url),
+  secret = COALESCE(sqlc.narg('secret'), secret),
+  events = COALESCE(sqlc.narg('events')::text[], events),
+  type = COALESCE(sqlc.narg('type'), type),
+  updated_at = now()
+WHERE id = sqlc.arg('id')
+RETURNING id, project_id, url, secret, events, created_by, created_at, updated_at, type;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = $1;
+
+-- API keys
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, key_hash, prefix, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at;
+
+-- name: GetAPIKeyByHash :one
+SELECT id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+FROM api_keys
+WHERE key_hash = $1;
+
+-- name: GetUserAPIKeys :many
+SELECT id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+FROM api_keys
+WHERE user_id = $1
+ORDER BY created_at DESC, id;
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: TouchAPIKey :exec
+-- Records that a key was used, at most once a minute so a busy key doesn't
+-- write on every request
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
@@ -1,6 +1,6 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.28.0
+//   sqlc v1.29.0
 
 package store
 
@@ -8,6 +8,42 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiKey struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Name       string
+	KeyHash    string
+	Prefix     string
+	Scopes     []string
+	LastUsedAt pgtype.Timestamp
+	ExpiresAt  pgtype.Timestamp
+	RevokedAt  pgtype.Timestamp
+	CreatedAt  pgtype.Timestamp
+}
+
+type Attachment struct {
+	ID          pgtype.UUID
+	IssueID     pgtype.UUID
+	TaskID      pgtype.UUID
+	UploaderID  pgtype.UUID
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	StorageKey  string
+	CreatedAt   pgtype.Timestamp
+}
+
+type AuditLog struct {
+	ID         pgtype.UUID
+	ActorID    pgtype.UUID
+	Action     string
+	TargetType string
+	TargetID   pgtype.UUID
+	TeamID     pgtype.UUID
+	Metadata   []byte
+	CreatedAt  pgtype.Timestamp
+}
+
 type Comment struct {
 	ID        pgtype.UUID
 	Content   string
@@ -16,6 +52,22 @@ type Comment struct {
 	TaskID    pgtype.UUID
 	CreatedAt pgtype.Timestamp
 	UpdatedAt pgtype.Timestamp
+	EditedAt  pgtype.Timestamp
+}
+
+type CommentReaction struct {
+	CommentID pgtype.UUID
+	UserID    pgtype.UUID
+	Emoji     string
+	CreatedAt pgtype.Timestamp
+}
+
+type CommentRevision struct {
+	ID        pgtype.UUID
+	CommentID pgtype.UUID
+	Content   string
+	EditedBy  pgtype.UUID
+	CreatedAt pgtype.Timestamp
 }
 
 type Issue struct {
@@ -29,6 +81,42 @@ type Issue struct {
 	DueDate     pgtype.Timestamp
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
+	Version     int32
+	Priority    pgtype.Text
+	Number      int32
+}
+
+type IssueLabel struct {
+	IssueID   pgtype.UUID
+	LabelID   pgtype.UUID
+	CreatedAt pgtype.Timestamp
+}
+
+type IssueWatcher struct {
+	IssueID   pgtype.UUID
+	UserID    pgtype.UUID
+	CreatedAt pgtype.Timestamp
+}
+
+type Label struct {
+	ID        pgtype.UUID
+	ProjectID pgtype.UUID
+	Name      string
+	Color     pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
+type Notification struct {
+	ID        pgtype.UUID
+	UserID    pgtype.UUID
+	ActorID   pgtype.UUID
+	Type      string
+	Subject   string
+	Message   pgtype.Text
+	IssueID   pgtype.UUID
+	TeamID    pgtype.UUID
+	ReadAt    pgtype.Timestamp
+	CreatedAt pgtype.Timestamp
 }
 
 type Project struct {
@@ -40,6 +128,20 @@ type Project struct {
 	Status      pgtype.Text
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
+	Version     int32
+	Slug        string
+}
+
+type ProjectArchive struct {
+	ProjectID      pgtype.UUID
+	PreviousStatus pgtype.Text
+	ArchivedBy     pgtype.UUID
+	ArchivedAt     pgtype.Timestamp
+}
+
+type ProjectIssueCounter struct {
+	ProjectID  pgtype.UUID
+	LastNumber int32
 }
 
 type Task struct {
@@ -55,6 +157,15 @@ type Task struct {
 	UpdatedAt   pgtype.Timestamp
 }
 
+type TaskTimeEntry struct {
+	ID        pgtype.UUID
+	TaskID    pgtype.UUID
+	UserID    pgtype.UUID
+	Minutes   int32
+	Note      pgtype.Text
+	CreatedAt pgtype.Timestamp
+}
+
 type Team struct {
 	ID          pgtype.UUID
 	Name        string
@@ -62,6 +173,7 @@ type Team struct {
 	AvatarUrl   pgtype.Text
 	CreatedAt   pgtype.Timestamp
 	UpdatedAt   pgtype.Timestamp
+	Slug        string
 }
 
 type TeamMember struct {
@@ -85,4 +197,18 @@ type User struct {
 	AccountStatus pgtype.Text
 	CreatedAt     pgtype.Timestamp
 	UpdatedAt     pgtype.Timestamp
+	IsAdmin       bool
+	DisabledAt    pgtype.Timestamp
+}
+
+type Webhook struct {
+	ID        pgtype.UUID
+	ProjectID pgtype.UUID
+	Url       string
+	Secret    string
+	Events    []string
+	CreatedBy pgtype.UUID
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	Type      string
 }
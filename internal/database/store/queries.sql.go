@@ -1,6 +1,6 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.28.0
+//   sqlc v1.29.0
 // source: queries.sql
 
 package store
@@ -11,6 +11,61 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addCommentReaction = `-- name: AddCommentReaction :execrows
+INSERT INTO comment_reactions (comment_id, user_id, emoji)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type AddCommentReactionParams struct {
+	CommentID pgtype.UUID
+	UserID    pgtype.UUID
+	Emoji     string
+}
+
+// Comment Reactions
+func (q *Queries) AddCommentReaction(ctx context.Context, arg AddCommentReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addCommentReaction, arg.CommentID, arg.UserID, arg.Emoji)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const addIssueLabel = `-- name: AddIssueLabel :exec
+INSERT INTO issue_labels (issue_id, label_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddIssueLabelParams struct {
+	IssueID pgtype.UUID
+	LabelID pgtype.UUID
+}
+
+func (q *Queries) AddIssueLabel(ctx context.Context, arg AddIssueLabelParams) error {
+	_, err := q.db.Exec(ctx, addIssueLabel, arg.IssueID, arg.LabelID)
+	return err
+}
+
+const addIssueWatcher = `-- name: AddIssueWatcher :exec
+INSERT INTO issue_watchers (issue_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddIssueWatcherParams struct {
+	IssueID pgtype.UUID
+	UserID  pgtype.UUID
+}
+
+// ------------------------------------------------------
+// Issue Watchers
+func (q *Queries) AddIssueWatcher(ctx context.Context, arg AddIssueWatcherParams) error {
+	_, err := q.db.Exec(ctx, addIssueWatcher, arg.IssueID, arg.UserID)
+	return err
+}
+
 const addUserToTeam = `-- name: AddUserToTeam :exec
 INSERT INTO team_members (team_id, user_id, role)
 VALUES ($1, $2, $3)
@@ -27,6 +82,40 @@ func (q *Queries) AddUserToTeam(ctx context.Context, arg AddUserToTeamParams) er
 	return err
 }
 
+const anonymizeUserComments = `-- name: AnonymizeUserComments :many
+UPDATE comments
+SET user_id = NULL
+WHERE user_id = $1
+RETURNING issue_id, task_id
+`
+
+type AnonymizeUserCommentsRow struct {
+	IssueID pgtype.UUID
+	TaskID  pgtype.UUID
+}
+
+// Keeps the user's comments but drops the author, returning where they were
+// so cached comment lists can be invalidated
+func (q *Queries) AnonymizeUserComments(ctx context.Context, userID pgtype.UUID) ([]AnonymizeUserCommentsRow, error) {
+	rows, err := q.db.Query(ctx, anonymizeUserComments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AnonymizeUserCommentsRow
+	for rows.Next() {
+		var i AnonymizeUserCommentsRow
+		if err := rows.Scan(&i.IssueID, &i.TaskID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const checkTeamMembership = `-- name: CheckTeamMembership :one
 SELECT EXISTS (
   SELECT 1 FROM team_members
@@ -46,10 +135,132 @@ func (q *Queries) CheckTeamMembership(ctx context.Context, arg CheckTeamMembersh
 	return is_member, err
 }
 
+const countUnread = `-- name: CountUnread :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) CountUnread(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnread, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, key_hash, prefix, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+`
+
+type CreateAPIKeyParams struct {
+	UserID    pgtype.UUID
+	Name      string
+	KeyHash   string
+	Prefix    string
+	Scopes    []string
+	ExpiresAt pgtype.Timestamp
+}
+
+// API keys
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, createAPIKey,
+		arg.UserID,
+		arg.Name,
+		arg.KeyHash,
+		arg.Prefix,
+		arg.Scopes,
+		arg.ExpiresAt,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.Scopes,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments (issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at
+`
+
+type CreateAttachmentParams struct {
+	IssueID     pgtype.UUID
+	TaskID      pgtype.UUID
+	UploaderID  pgtype.UUID
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	StorageKey  string
+}
+
+// Attachments
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRow(ctx, createAttachment,
+		arg.IssueID,
+		arg.TaskID,
+		arg.UploaderID,
+		arg.Filename,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.StorageKey,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.IssueID,
+		&i.TaskID,
+		&i.UploaderID,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAuditEntry = `-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (actor_id, action, target_type, target_id, team_id, metadata)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateAuditEntryParams struct {
+	ActorID    pgtype.UUID
+	Action     string
+	TargetType string
+	TargetID   pgtype.UUID
+	TeamID     pgtype.UUID
+	Metadata   []byte
+}
+
+func (q *Queries) CreateAuditEntry(ctx context.Context, arg CreateAuditEntryParams) error {
+	_, err := q.db.Exec(ctx, createAuditEntry,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.TeamID,
+		arg.Metadata,
+	)
+	return err
+}
+
 const createComment = `-- name: CreateComment :one
 INSERT INTO comments (content, user_id, issue_id, task_id)
 VALUES ($1, $2, $3, $4)
-RETURNING id, content, user_id, issue_id, task_id, created_at, updated_at
+RETURNING id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at
 `
 
 type CreateCommentParams struct {
@@ -77,14 +288,29 @@ func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (C
 		&i.TaskID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
+const createCommentRevision = `-- name: CreateCommentRevision :exec
+INSERT INTO comment_revisions (comment_id, content, edited_by)
+SELECT c.id, c.content, c.user_id
+FROM comments c
+WHERE c.id = $1
+`
+
+// Comment Revisions
+// Keeps a comment's current content before it is edited
+func (q *Queries) CreateCommentRevision(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, createCommentRevision, id)
+	return err
+}
+
 const createIssue = `-- name: CreateIssue :one
-INSERT INTO issues (project_id, title, description, status, reporter_id, assignee_id, due_date)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at
+INSERT INTO issues (project_id, title, description, status, reporter_id, assignee_id, due_date, priority, number)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
 `
 
 type CreateIssueParams struct {
@@ -95,10 +321,10 @@ type CreateIssueParams struct {
 	ReporterID  pgtype.UUID
 	AssigneeID  pgtype.UUID
 	DueDate     pgtype.Timestamp
+	Priority    pgtype.Text
+	Number      int32
 }
 
-// ------------------------------------------------------
-// Issues
 func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue, error) {
 	row := q.db.QueryRow(ctx, createIssue,
 		arg.ProjectID,
@@ -108,6 +334,8 @@ func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue
 		arg.ReporterID,
 		arg.AssigneeID,
 		arg.DueDate,
+		arg.Priority,
+		arg.Number,
 	)
 	var i Issue
 	err := row.Scan(
@@ -121,14 +349,87 @@ func (q *Queries) CreateIssue(ctx context.Context, arg CreateIssueParams) (Issue
 		&i.DueDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.Priority,
+		&i.Number,
+	)
+	return i, err
+}
+
+const createLabel = `-- name: CreateLabel :one
+INSERT INTO labels (project_id, name, color)
+VALUES ($1, $2, $3)
+RETURNING id, project_id, name, color, created_at
+`
+
+type CreateLabelParams struct {
+	ProjectID pgtype.UUID
+	Name      string
+	Color     pgtype.Text
+}
+
+// ------------------------------------------------------
+// Labels
+func (q *Queries) CreateLabel(ctx context.Context, arg CreateLabelParams) (Label, error) {
+	row := q.db.QueryRow(ctx, createLabel, arg.ProjectID, arg.Name, arg.Color)
+	var i Label
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Name,
+		&i.Color,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createNotification = `-- name: CreateNotification :one
+INSERT INTO notifications (user_id, actor_id, type, subject, message, issue_id, team_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, actor_id, type, subject, message, issue_id, team_id, read_at, created_at
+`
+
+type CreateNotificationParams struct {
+	UserID  pgtype.UUID
+	ActorID pgtype.UUID
+	Type    string
+	Subject string
+	Message pgtype.Text
+	IssueID pgtype.UUID
+	TeamID  pgtype.UUID
+}
+
+// Notifications
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error) {
+	row := q.db.QueryRow(ctx, createNotification,
+		arg.UserID,
+		arg.ActorID,
+		arg.Type,
+		arg.Subject,
+		arg.Message,
+		arg.IssueID,
+		arg.TeamID,
+	)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ActorID,
+		&i.Type,
+		&i.Subject,
+		&i.Message,
+		&i.IssueID,
+		&i.TeamID,
+		&i.ReadAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const createProject = `-- name: CreateProject :one
-INSERT INTO projects (name, description, owner_id, team_id, status)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, name, description, owner_id, team_id, status, created_at, updated_at
+INSERT INTO projects (name, description, owner_id, team_id, status, slug)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
 `
 
 type CreateProjectParams struct {
@@ -137,6 +438,7 @@ type CreateProjectParams struct {
 	OwnerID     pgtype.UUID
 	TeamID      pgtype.UUID
 	Status      pgtype.Text
+	Slug        string
 }
 
 // ------------------------------------------------------
@@ -148,6 +450,7 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		arg.OwnerID,
 		arg.TeamID,
 		arg.Status,
+		arg.Slug,
 	)
 	var i Project
 	err := row.Scan(
@@ -159,6 +462,8 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.Slug,
 	)
 	return i, err
 }
@@ -179,7 +484,6 @@ type CreateTaskParams struct {
 	DueDate     pgtype.Timestamp
 }
 
-// ------------------------------------------------------
 // Tasks
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
 	row := q.db.QueryRow(ctx, createTask,
@@ -207,22 +511,60 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 	return i, err
 }
 
+const createTaskTimeEntry = `-- name: CreateTaskTimeEntry :one
+INSERT INTO task_time_entries (task_id, user_id, minutes, note)
+VALUES ($1, $2, $3, $4)
+RETURNING id, task_id, user_id, minutes, note, created_at
+`
+
+type CreateTaskTimeEntryParams struct {
+	TaskID  pgtype.UUID
+	UserID  pgtype.UUID
+	Minutes int32
+	Note    pgtype.Text
+}
+
+func (q *Queries) CreateTaskTimeEntry(ctx context.Context, arg CreateTaskTimeEntryParams) (TaskTimeEntry, error) {
+	row := q.db.QueryRow(ctx, createTaskTimeEntry,
+		arg.TaskID,
+		arg.UserID,
+		arg.Minutes,
+		arg.Note,
+	)
+	var i TaskTimeEntry
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.UserID,
+		&i.Minutes,
+		&i.Note,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createTeam = `-- name: CreateTeam :one
-INSERT INTO teams (name, description, avatar_url)
-VALUES ($1, $2, $3)
-RETURNING id, name, description, avatar_url, created_at, updated_at
+INSERT INTO teams (name, description, avatar_url, slug)
+VALUES ($1, $2, $3, $4)
+RETURNING id, name, description, avatar_url, created_at, updated_at, slug
 `
 
 type CreateTeamParams struct {
 	Name        string
 	Description pgtype.Text
 	AvatarUrl   pgtype.Text
+	Slug        string
 }
 
 // ------------------------------------------------------
 // Teams
 func (q *Queries) CreateTeam(ctx context.Context, arg CreateTeamParams) (Team, error) {
-	row := q.db.QueryRow(ctx, createTeam, arg.Name, arg.Description, arg.AvatarUrl)
+	row := q.db.QueryRow(ctx, createTeam,
+		arg.Name,
+		arg.Description,
+		arg.AvatarUrl,
+		arg.Slug,
+	)
 	var i Team
 	err := row.Scan(
 		&i.ID,
@@ -231,6 +573,7 @@ func (q *Queries) CreateTeam(ctx context.Context, arg CreateTeamParams) (Team, e
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
@@ -287,6 +630,56 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (project_id, url, secret, events, created_by, type)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, project_id, url, secret, events, created_by, created_at, updated_at, type
+`
+
+type CreateWebhookParams struct {
+	ProjectID pgtype.UUID
+	Url       string
+	Secret    string
+	Events    []string
+	CreatedBy pgtype.UUID
+	Type      string
+}
+
+// ------------------------------------------------------
+// Webhooks
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, createWebhook,
+		arg.ProjectID,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+		arg.CreatedBy,
+		arg.Type,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+	)
+	return i, err
+}
+
+const deleteAttachment = `-- name: DeleteAttachment :exec
+DELETE FROM attachments WHERE id = $1
+`
+
+func (q *Queries) DeleteAttachment(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteAttachment, id)
+	return err
+}
+
 const deleteComment = `-- name: DeleteComment :exec
 DELETE FROM comments
 WHERE id = $1
@@ -306,6 +699,51 @@ func (q *Queries) DeleteIssue(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteIssueAttachments = `-- name: DeleteIssueAttachments :exec
+DELETE FROM attachments WHERE issue_id = $1
+`
+
+func (q *Queries) DeleteIssueAttachments(ctx context.Context, issueID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIssueAttachments, issueID)
+	return err
+}
+
+const deleteIssueComments = `-- name: DeleteIssueComments :exec
+DELETE FROM comments WHERE issue_id = $1
+`
+
+func (q *Queries) DeleteIssueComments(ctx context.Context, issueID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIssueComments, issueID)
+	return err
+}
+
+const deleteIssueLabels = `-- name: DeleteIssueLabels :exec
+DELETE FROM issue_labels WHERE issue_id = $1
+`
+
+func (q *Queries) DeleteIssueLabels(ctx context.Context, issueID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIssueLabels, issueID)
+	return err
+}
+
+const deleteIssueWatchers = `-- name: DeleteIssueWatchers :exec
+DELETE FROM issue_watchers WHERE issue_id = $1
+`
+
+func (q *Queries) DeleteIssueWatchers(ctx context.Context, issueID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIssueWatchers, issueID)
+	return err
+}
+
+const deleteLabel = `-- name: DeleteLabel :exec
+DELETE FROM labels WHERE id = $1
+`
+
+func (q *Queries) DeleteLabel(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteLabel, id)
+	return err
+}
+
 const deleteProject = `-- name: DeleteProject :exec
 DELETE FROM projects WHERE id = $1
 `
@@ -315,6 +753,15 @@ func (q *Queries) DeleteProject(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteProjectArchive = `-- name: DeleteProjectArchive :exec
+DELETE FROM project_archives WHERE project_id = $1
+`
+
+func (q *Queries) DeleteProjectArchive(ctx context.Context, projectID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteProjectArchive, projectID)
+	return err
+}
+
 const deleteTask = `-- name: DeleteTask :exec
 DELETE FROM tasks WHERE id = $1
 `
@@ -324,6 +771,33 @@ func (q *Queries) DeleteTask(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteTaskAttachments = `-- name: DeleteTaskAttachments :exec
+DELETE FROM attachments WHERE task_id = $1
+`
+
+func (q *Queries) DeleteTaskAttachments(ctx context.Context, taskID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTaskAttachments, taskID)
+	return err
+}
+
+const deleteTaskComments = `-- name: DeleteTaskComments :exec
+DELETE FROM comments WHERE task_id = $1
+`
+
+func (q *Queries) DeleteTaskComments(ctx context.Context, taskID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTaskComments, taskID)
+	return err
+}
+
+const deleteTaskTimeEntries = `-- name: DeleteTaskTimeEntries :exec
+DELETE FROM task_time_entries WHERE task_id = $1
+`
+
+func (q *Queries) DeleteTaskTimeEntries(ctx context.Context, taskID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTaskTimeEntries, taskID)
+	return err
+}
+
 const deleteTeam = `-- name: DeleteTeam :exec
 DELETE FROM teams WHERE id = $1
 `
@@ -333,6 +807,16 @@ func (q *Queries) DeleteTeam(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteTeamProjects = `-- name: DeleteTeamProjects :exec
+DELETE FROM projects
+WHERE team_id = $1
+`
+
+func (q *Queries) DeleteTeamProjects(ctx context.Context, teamID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteTeamProjects, teamID)
+	return err
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1
 `
@@ -342,78 +826,995 @@ func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
-const getActiveProjectsCount = `-- name: GetActiveProjectsCount :one
-SELECT COUNT(*) 
-FROM projects 
-WHERE owner_id = $1 AND status = 'active'
+const deleteUserProjects = `-- name: DeleteUserProjects :execrows
+DELETE FROM projects
+WHERE owner_id = $1
 `
 
-func (q *Queries) GetActiveProjectsCount(ctx context.Context, ownerID pgtype.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, getActiveProjectsCount, ownerID)
-	var count int64
+func (q *Queries) DeleteUserProjects(ctx context.Context, ownerID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserProjects, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteWebhook, id)
+	return err
+}
+
+const detachUserFromIssues = `-- name: DetachUserFromIssues :exec
+UPDATE issues
+SET reporter_id = NULLIF(reporter_id, $1),
+    assignee_id = NULLIF(assignee_id, $1),
+    updated_at = now(), version = version + 1
+WHERE reporter_id = $1 OR assignee_id = $1
+`
+
+func (q *Queries) DetachUserFromIssues(ctx context.Context, reporterID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, detachUserFromIssues, reporterID)
+	return err
+}
+
+const disableUser = `-- name: DisableUser :execrows
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, now()), account_status = 'suspended', updated_at = now()
+WHERE id = $1
+`
+
+// Disabling an account twice keeps the time it was first disabled
+func (q *Queries) DisableUser(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, disableUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const exportTeamComments = `-- name: ExportTeamComments :many
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at
+FROM comments c
+LEFT JOIN issues i ON c.issue_id = i.id
+LEFT JOIN tasks t ON c.task_id = t.id
+WHERE COALESCE(i.project_id, t.project_id) IN (SELECT id FROM projects WHERE team_id = $1)
+  AND ($2::timestamp IS NULL
+       OR (c.created_at, c.id) > ($2::timestamp, $3::uuid))
+ORDER BY c.created_at, c.id
+LIMIT $4
+`
+
+type ExportTeamCommentsParams struct {
+	TeamID          pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+// Comments on issues and tasks in the team's projects
+func (q *Queries) ExportTeamComments(ctx context.Context, arg ExportTeamCommentsParams) ([]Comment, error) {
+	rows, err := q.db.Query(ctx, exportTeamComments,
+		arg.TeamID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.UserID,
+			&i.IssueID,
+			&i.TaskID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportTeamIssues = `-- name: ExportTeamIssues :many
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id IN (SELECT id FROM projects WHERE team_id = $1)
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ExportTeamIssuesParams struct {
+	TeamID          pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+func (q *Queries) ExportTeamIssues(ctx context.Context, arg ExportTeamIssuesParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, exportTeamIssues,
+		arg.TeamID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Issue
+	for rows.Next() {
+		var i Issue
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.ReporterID,
+			&i.AssigneeID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportUserComments = `-- name: ExportUserComments :many
+SELECT id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at
+FROM comments
+WHERE user_id = $1
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ExportUserCommentsParams struct {
+	UserID          pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+func (q *Queries) ExportUserComments(ctx context.Context, arg ExportUserCommentsParams) ([]Comment, error) {
+	rows, err := q.db.Query(ctx, exportUserComments,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.UserID,
+			&i.IssueID,
+			&i.TaskID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportUserIssues = `-- name: ExportUserIssues :many
+
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE (reporter_id = $1 OR assignee_id = $1)
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ExportUserIssuesParams struct {
+	UserID          pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+// Exports
+// Exports read in keyset pages, oldest first: pass the created_at and id of
+// the last row of the previous page, or NULLs for the first page.
+// Issues the user reported or is assigned to
+func (q *Queries) ExportUserIssues(ctx context.Context, arg ExportUserIssuesParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, exportUserIssues,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Issue
+	for rows.Next() {
+		var i Issue
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.ReporterID,
+			&i.AssigneeID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
+SELECT id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+FROM api_keys
+WHERE key_hash = $1
+`
+
+func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error) {
+	row := q.db.QueryRow(ctx, getAPIKeyByHash, keyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.Scopes,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAccessibleProjects = `-- name: GetAccessibleProjects :many
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
+FROM projects
+WHERE (owner_id = $1 OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $1))
+  AND ($2::boolean OR status IS DISTINCT FROM 'archived')
+ORDER BY created_at DESC, id
+`
+
+type GetAccessibleProjectsParams struct {
+	UserID          pgtype.UUID
+	IncludeArchived bool
+}
+
+// Projects the user owns or that belong to one of their teams, each listed
+// once. Archived projects are left out unless include_archived is set.
+func (q *Queries) GetAccessibleProjects(ctx context.Context, arg GetAccessibleProjectsParams) ([]Project, error) {
+	rows, err := q.db.Query(ctx, getAccessibleProjects, arg.UserID, arg.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.OwnerID,
+			&i.TeamID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveProjectsCount = `-- name: GetActiveProjectsCount :one
+SELECT COUNT(*)
+FROM projects
+WHERE owner_id = $1 AND COALESCE(status, 'planned') NOT IN ('archived', 'cancelled')
+`
+
+// Projects the user owns that haven't been archived or cancelled
+func (q *Queries) GetActiveProjectsCount(ctx context.Context, ownerID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, getActiveProjectsCount, ownerID)
+	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const getCommentByID = `-- name: GetCommentByID :one
-SELECT id, content, user_id, issue_id, task_id, created_at, updated_at
-FROM comments
+const getAttachmentByID = `-- name: GetAttachmentByID :one
+SELECT id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments
+WHERE id = $1
+`
+
+func (q *Queries) GetAttachmentByID(ctx context.Context, id pgtype.UUID) (Attachment, error) {
+	row := q.db.QueryRow(ctx, getAttachmentByID, id)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.IssueID,
+		&i.TaskID,
+		&i.UploaderID,
+		&i.Filename,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCommentByID = `-- name: GetCommentByID :one
+SELECT id, content, user_id, issue_id, task_id, created_at, updated_at, edited_at
+FROM comments
+WHERE id = $1
+`
+
+func (q *Queries) GetCommentByID(ctx context.Context, id pgtype.UUID) (Comment, error) {
+	row := q.db.QueryRow(ctx, getCommentByID, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.Content,
+		&i.UserID,
+		&i.IssueID,
+		&i.TaskID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const getCommentReactionCounts = `-- name: GetCommentReactionCounts :many
+SELECT comment_id, emoji, COUNT(*) AS count
+FROM comment_reactions
+WHERE comment_id = ANY($1::uuid[])
+GROUP BY comment_id, emoji
+ORDER BY comment_id, MIN(created_at), emoji
+`
+
+type GetCommentReactionCountsRow struct {
+	CommentID pgtype.UUID
+	Emoji     string
+	Count     int64
+}
+
+// Counts each emoji on the given comments, in the order it was first used
+func (q *Queries) GetCommentReactionCounts(ctx context.Context, commentIds []pgtype.UUID) ([]GetCommentReactionCountsRow, error) {
+	rows, err := q.db.Query(ctx, getCommentReactionCounts, commentIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentReactionCountsRow
+	for rows.Next() {
+		var i GetCommentReactionCountsRow
+		if err := rows.Scan(&i.CommentID, &i.Emoji, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentRevisions = `-- name: GetCommentRevisions :many
+SELECT id, comment_id, content, edited_by, created_at
+FROM comment_revisions
+WHERE comment_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetCommentRevisions(ctx context.Context, commentID pgtype.UUID) ([]CommentRevision, error) {
+	rows, err := q.db.Query(ctx, getCommentRevisions, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CommentRevision
+	for rows.Next() {
+		var i CommentRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.CommentID,
+			&i.Content,
+			&i.EditedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentsByIssue = `-- name: GetCommentsByIssue :many
+SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
+       u.name AS user_name, u.username, u.avatar_url
+FROM comments c
+LEFT JOIN users u ON c.user_id = u.id
+WHERE c.issue_id = $1
+ORDER BY c.created_at ASC, c.id
+`
+
+type GetCommentsByIssueRow struct {
+	ID        pgtype.UUID
+	Content   string
+	UserID    pgtype.UUID
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	UserName  pgtype.Text
+	Username  pgtype.Text
+	AvatarUrl pgtype.Text
+}
+
+func (q *Queries) GetCommentsByIssue(ctx context.Context, issueID pgtype.UUID) ([]GetCommentsByIssueRow, error) {
+	rows, err := q.db.Query(ctx, getCommentsByIssue, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentsByIssueRow
+	for rows.Next() {
+		var i GetCommentsByIssueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserName,
+			&i.Username,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentsByTask = `-- name: GetCommentsByTask :many
+SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
+       u.name AS user_name, u.username, u.avatar_url
+FROM comments c
+LEFT JOIN users u ON c.user_id = u.id
+WHERE c.task_id = $1
+ORDER BY c.created_at ASC, c.id
+`
+
+type GetCommentsByTaskRow struct {
+	ID        pgtype.UUID
+	Content   string
+	UserID    pgtype.UUID
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	UserName  pgtype.Text
+	Username  pgtype.Text
+	AvatarUrl pgtype.Text
+}
+
+func (q *Queries) GetCommentsByTask(ctx context.Context, taskID pgtype.UUID) ([]GetCommentsByTaskRow, error) {
+	rows, err := q.db.Query(ctx, getCommentsByTask, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentsByTaskRow
+	for rows.Next() {
+		var i GetCommentsByTaskRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserName,
+			&i.Username,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssueAttachments = `-- name: GetIssueAttachments :many
+SELECT id, issue_id, task_id, uploader_id, filename, content_type, size_bytes, storage_key, created_at
+FROM attachments
+WHERE issue_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetIssueAttachments(ctx context.Context, issueID pgtype.UUID) ([]Attachment, error) {
+	rows, err := q.db.Query(ctx, getIssueAttachments, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.IssueID,
+			&i.TaskID,
+			&i.UploaderID,
+			&i.Filename,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.StorageKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssueByID = `-- name: GetIssueByID :one
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE id = $1
+`
+
+func (q *Queries) GetIssueByID(ctx context.Context, id pgtype.UUID) (Issue, error) {
+	row := q.db.QueryRow(ctx, getIssueByID, id)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.ReporterID,
+		&i.AssigneeID,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.Priority,
+		&i.Number,
+	)
+	return i, err
+}
+
+const getIssueByNumber = `-- name: GetIssueByNumber :one
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = $1 AND number = $2
+`
+
+type GetIssueByNumberParams struct {
+	ProjectID pgtype.UUID
+	Number    int32
+}
+
+func (q *Queries) GetIssueByNumber(ctx context.Context, arg GetIssueByNumberParams) (Issue, error) {
+	row := q.db.QueryRow(ctx, getIssueByNumber, arg.ProjectID, arg.Number)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.ReporterID,
+		&i.AssigneeID,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.Priority,
+		&i.Number,
+	)
+	return i, err
+}
+
+const getIssueComments = `-- name: GetIssueComments :many
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url
+FROM comments c
+LEFT JOIN users u ON c.user_id = u.id
+WHERE c.issue_id = $1
+ORDER BY c.created_at ASC, c.id
+`
+
+type GetIssueCommentsRow struct {
+	ID        pgtype.UUID
+	Content   string
+	UserID    pgtype.UUID
+	IssueID   pgtype.UUID
+	TaskID    pgtype.UUID
+	CreatedAt pgtype.Timestamp
+	UpdatedAt pgtype.Timestamp
+	EditedAt  pgtype.Timestamp
+	Email     string
+	Name      pgtype.Text
+	Username  pgtype.Text
+	AvatarUrl pgtype.Text
+}
+
+func (q *Queries) GetIssueComments(ctx context.Context, issueID pgtype.UUID) ([]GetIssueCommentsRow, error) {
+	rows, err := q.db.Query(ctx, getIssueComments, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIssueCommentsRow
+	for rows.Next() {
+		var i GetIssueCommentsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.UserID,
+			&i.IssueID,
+			&i.TaskID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EditedAt,
+			&i.Email,
+			&i.Name,
+			&i.Username,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssueLabels = `-- name: GetIssueLabels :many
+SELECT l.id, l.project_id, l.name, l.color, l.created_at
+FROM labels l
+JOIN issue_labels il ON il.label_id = l.id
+WHERE il.issue_id = $1
+ORDER BY l.name, l.id
+`
+
+func (q *Queries) GetIssueLabels(ctx context.Context, issueID pgtype.UUID) ([]Label, error) {
+	rows, err := q.db.Query(ctx, getIssueLabels, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Label
+	for rows.Next() {
+		var i Label
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Name,
+			&i.Color,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssueWatchers = `-- name: GetIssueWatchers :many
+SELECT u.id, u.name, u.username, u.avatar_url, w.created_at AS watching_since
+FROM issue_watchers w
+JOIN users u ON u.id = w.user_id
+WHERE w.issue_id = $1
+ORDER BY w.created_at, u.id
+`
+
+type GetIssueWatchersRow struct {
+	ID            pgtype.UUID
+	Name          pgtype.Text
+	Username      pgtype.Text
+	AvatarUrl     pgtype.Text
+	WatchingSince pgtype.Timestamp
+}
+
+func (q *Queries) GetIssueWatchers(ctx context.Context, issueID pgtype.UUID) ([]GetIssueWatchersRow, error) {
+	rows, err := q.db.Query(ctx, getIssueWatchers, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIssueWatchersRow
+	for rows.Next() {
+		var i GetIssueWatchersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.WatchingSince,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssuesAssignedToUser = `-- name: GetIssuesAssignedToUser :many
+SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.due_date, 
+       i.created_at, i.updated_at, p.name AS project_name, i.priority, i.number
+FROM issues i
+JOIN projects p ON i.project_id = p.id
+WHERE i.assignee_id = $1
+  AND (p.owner_id = $1 OR EXISTS (
+    SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = $1))
+  AND ($2::text IS NULL OR i.status = $2::text)
+ORDER BY i.due_date ASC NULLS LAST, i.created_at DESC, i.id
+`
+
+type GetIssuesAssignedToUserParams struct {
+	AssigneeID pgtype.UUID
+	Status     pgtype.Text
+}
+
+type GetIssuesAssignedToUserRow struct {
+	ID          pgtype.UUID
+	ProjectID   pgtype.UUID
+	Title       string
+	Description pgtype.Text
+	Status      pgtype.Text
+	ReporterID  pgtype.UUID
+	DueDate     pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+	ProjectName string
+	Priority    pgtype.Text
+	Number      int32
+}
+
+// Leaves out issues in projects the user can no longer access, as neither the
+// owner nor a member of the project's team
+func (q *Queries) GetIssuesAssignedToUser(ctx context.Context, arg GetIssuesAssignedToUserParams) ([]GetIssuesAssignedToUserRow, error) {
+	rows, err := q.db.Query(ctx, getIssuesAssignedToUser, arg.AssigneeID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIssuesAssignedToUserRow
+	for rows.Next() {
+		var i GetIssuesAssignedToUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.ReporterID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectName,
+			&i.Priority,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIssuesByStatus = `-- name: GetIssuesByStatus :many
+SELECT 
+  i.id, 
+  i.project_id,  -- Make sure project_id is explicitly included
+  i.title, 
+  i.description, 
+  i.reporter_id,
+  i.assignee_id, 
+  i.due_date, 
+  i.created_at, 
+  i.updated_at,
+  i.priority,
+  i.number
+FROM issues i
+WHERE i.project_id = $1 AND i.status = $2
+ORDER BY i.created_at DESC, i.id
+`
+
+type GetIssuesByStatusParams struct {
+	ProjectID pgtype.UUID
+	Status    pgtype.Text
+}
+
+type GetIssuesByStatusRow struct {
+	ID          pgtype.UUID
+	ProjectID   pgtype.UUID
+	Title       string
+	Description pgtype.Text
+	ReporterID  pgtype.UUID
+	AssigneeID  pgtype.UUID
+	DueDate     pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+	Priority    pgtype.Text
+	Number      int32
+}
+
+func (q *Queries) GetIssuesByStatus(ctx context.Context, arg GetIssuesByStatusParams) ([]GetIssuesByStatusRow, error) {
+	rows, err := q.db.Query(ctx, getIssuesByStatus, arg.ProjectID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIssuesByStatusRow
+	for rows.Next() {
+		var i GetIssuesByStatusRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.ReporterID,
+			&i.AssigneeID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Priority,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLabelByID = `-- name: GetLabelByID :one
+SELECT id, project_id, name, color, created_at
+FROM labels
 WHERE id = $1
 `
 
-func (q *Queries) GetCommentByID(ctx context.Context, id pgtype.UUID) (Comment, error) {
-	row := q.db.QueryRow(ctx, getCommentByID, id)
-	var i Comment
+func (q *Queries) GetLabelByID(ctx context.Context, id pgtype.UUID) (Label, error) {
+	row := q.db.QueryRow(ctx, getLabelByID, id)
+	var i Label
 	err := row.Scan(
 		&i.ID,
-		&i.Content,
-		&i.UserID,
-		&i.IssueID,
-		&i.TaskID,
+		&i.ProjectID,
+		&i.Name,
+		&i.Color,
 		&i.CreatedAt,
-		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getCommentsByIssue = `-- name: GetCommentsByIssue :many
-SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
-       u.name AS user_name, u.username, u.avatar_url
-FROM comments c
-JOIN users u ON c.user_id = u.id
-WHERE c.issue_id = $1
-ORDER BY c.created_at ASC
+const getOverdueTasks = `-- name: GetOverdueTasks :many
+SELECT t.id, t.project_id, t.assignee_id, t.title, t.status, t.priority, t.due_date, 
+       p.name AS project_name
+FROM tasks t
+JOIN projects p ON t.project_id = p.id
+WHERE t.due_date < now() AND t.status != 'done' AND t.assignee_id = $1
+ORDER BY t.due_date ASC, t.id
 `
 
-type GetCommentsByIssueRow struct {
-	ID        pgtype.UUID
-	Content   string
-	UserID    pgtype.UUID
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
-	UserName  pgtype.Text
-	Username  pgtype.Text
-	AvatarUrl pgtype.Text
+type GetOverdueTasksRow struct {
+	ID          pgtype.UUID
+	ProjectID   pgtype.UUID
+	AssigneeID  pgtype.UUID
+	Title       string
+	Status      pgtype.Text
+	Priority    pgtype.Text
+	DueDate     pgtype.Timestamp
+	ProjectName string
 }
 
-func (q *Queries) GetCommentsByIssue(ctx context.Context, issueID pgtype.UUID) ([]GetCommentsByIssueRow, error) {
-	rows, err := q.db.Query(ctx, getCommentsByIssue, issueID)
+func (q *Queries) GetOverdueTasks(ctx context.Context, assigneeID pgtype.UUID) ([]GetOverdueTasksRow, error) {
+	rows, err := q.db.Query(ctx, getOverdueTasks, assigneeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetCommentsByIssueRow
+	var items []GetOverdueTasksRow
 	for rows.Next() {
-		var i GetCommentsByIssueRow
+		var i GetOverdueTasksRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.Content,
-			&i.UserID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserName,
-			&i.Username,
-			&i.AvatarUrl,
+			&i.ProjectID,
+			&i.AssigneeID,
+			&i.Title,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.ProjectName,
 		); err != nil {
 			return nil, err
 		}
@@ -425,48 +1826,65 @@ func (q *Queries) GetCommentsByIssue(ctx context.Context, issueID pgtype.UUID) (
 	return items, nil
 }
 
-const getCommentsByTask = `-- name: GetCommentsByTask :many
-SELECT c.id, c.content, c.user_id, c.created_at, c.updated_at, 
-       u.name AS user_name, u.username, u.avatar_url
-FROM comments c
-JOIN users u ON c.user_id = u.id
-WHERE c.task_id = $1
-ORDER BY c.created_at ASC
+const getProjectAccess = `-- name: GetProjectAccess :one
+SELECT owner_id, team_id
+FROM projects
+WHERE id = $1
 `
 
-type GetCommentsByTaskRow struct {
-	ID        pgtype.UUID
-	Content   string
-	UserID    pgtype.UUID
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
-	UserName  pgtype.Text
-	Username  pgtype.Text
-	AvatarUrl pgtype.Text
+type GetProjectAccessRow struct {
+	OwnerID pgtype.UUID
+	TeamID  pgtype.UUID
 }
 
-func (q *Queries) GetCommentsByTask(ctx context.Context, taskID pgtype.UUID) ([]GetCommentsByTaskRow, error) {
-	rows, err := q.db.Query(ctx, getCommentsByTask, taskID)
+func (q *Queries) GetProjectAccess(ctx context.Context, id pgtype.UUID) (GetProjectAccessRow, error) {
+	row := q.db.QueryRow(ctx, getProjectAccess, id)
+	var i GetProjectAccessRow
+	err := row.Scan(&i.OwnerID, &i.TeamID)
+	return i, err
+}
+
+const getProjectArchive = `-- name: GetProjectArchive :one
+SELECT project_id, previous_status, archived_by, archived_at
+FROM project_archives
+WHERE project_id = $1
+`
+
+func (q *Queries) GetProjectArchive(ctx context.Context, projectID pgtype.UUID) (ProjectArchive, error) {
+	row := q.db.QueryRow(ctx, getProjectArchive, projectID)
+	var i ProjectArchive
+	err := row.Scan(
+		&i.ProjectID,
+		&i.PreviousStatus,
+		&i.ArchivedBy,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const getProjectAttachmentKeys = `-- name: GetProjectAttachmentKeys :many
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+WHERE i.project_id = $1 OR t.project_id = $1
+`
+
+// Where the files attached to a project's issues and tasks are stored, so they
+// can be removed along with the project
+func (q *Queries) GetProjectAttachmentKeys(ctx context.Context, projectID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, getProjectAttachmentKeys, projectID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetCommentsByTaskRow
+	var items []string
 	for rows.Next() {
-		var i GetCommentsByTaskRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Content,
-			&i.UserID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserName,
-			&i.Username,
-			&i.AvatarUrl,
-		); err != nil {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, storage_key)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -474,62 +1892,74 @@ func (q *Queries) GetCommentsByTask(ctx context.Context, taskID pgtype.UUID) ([]
 	return items, nil
 }
 
-const getIssueByID = `-- name: GetIssueByID :one
-SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at
-FROM issues
+const getProjectByID = `-- name: GetProjectByID :one
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
+FROM projects
 WHERE id = $1
 `
 
-func (q *Queries) GetIssueByID(ctx context.Context, id pgtype.UUID) (Issue, error) {
-	row := q.db.QueryRow(ctx, getIssueByID, id)
-	var i Issue
+func (q *Queries) GetProjectByID(ctx context.Context, id pgtype.UUID) (Project, error) {
+	row := q.db.QueryRow(ctx, getProjectByID, id)
+	var i Project
 	err := row.Scan(
 		&i.ID,
-		&i.ProjectID,
-		&i.Title,
+		&i.Name,
 		&i.Description,
+		&i.OwnerID,
+		&i.TeamID,
 		&i.Status,
-		&i.ReporterID,
-		&i.AssigneeID,
-		&i.DueDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.Slug,
 	)
 	return i, err
 }
 
-const getIssueComments = `-- name: GetIssueComments :many
-SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at,
-       u.email, u.name, u.username, u.avatar_url
+const getProjectComments = `-- name: GetProjectComments :many
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url,
+       COALESCE(i.title, t.title)::text AS parent_title
 FROM comments c
-JOIN users u ON c.user_id = u.id
-WHERE c.issue_id = $1
-ORDER BY c.created_at ASC
+LEFT JOIN users u ON c.user_id = u.id
+LEFT JOIN issues i ON c.issue_id = i.id
+LEFT JOIN tasks t ON c.task_id = t.id
+WHERE i.project_id = $1 OR t.project_id = $1
+ORDER BY c.created_at DESC, c.id
+LIMIT $2 OFFSET $3
 `
 
-type GetIssueCommentsRow struct {
-	ID        pgtype.UUID
-	Content   string
-	UserID    pgtype.UUID
-	IssueID   pgtype.UUID
-	TaskID    pgtype.UUID
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
-	Email     string
-	Name      pgtype.Text
-	Username  pgtype.Text
-	AvatarUrl pgtype.Text
+type GetProjectCommentsParams struct {
+	ProjectID pgtype.UUID
+	Limit     int32
+	Offset    int32
 }
 
-func (q *Queries) GetIssueComments(ctx context.Context, issueID pgtype.UUID) ([]GetIssueCommentsRow, error) {
-	rows, err := q.db.Query(ctx, getIssueComments, issueID)
+type GetProjectCommentsRow struct {
+	ID          pgtype.UUID
+	Content     string
+	UserID      pgtype.UUID
+	IssueID     pgtype.UUID
+	TaskID      pgtype.UUID
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+	EditedAt    pgtype.Timestamp
+	Email       string
+	Name        pgtype.Text
+	Username    pgtype.Text
+	AvatarUrl   pgtype.Text
+	ParentTitle string
+}
+
+func (q *Queries) GetProjectComments(ctx context.Context, arg GetProjectCommentsParams) ([]GetProjectCommentsRow, error) {
+	rows, err := q.db.Query(ctx, getProjectComments, arg.ProjectID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetIssueCommentsRow
+	var items []GetProjectCommentsRow
 	for rows.Next() {
-		var i GetIssueCommentsRow
+		var i GetProjectCommentsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Content,
@@ -538,10 +1968,152 @@ func (q *Queries) GetIssueComments(ctx context.Context, issueID pgtype.UUID) ([]
 			&i.TaskID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Email,
-			&i.Name,
-			&i.Username,
-			&i.AvatarUrl,
+			&i.EditedAt,
+			&i.Email,
+			&i.Name,
+			&i.Username,
+			&i.AvatarUrl,
+			&i.ParentTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectIDBySlug = `-- name: GetProjectIDBySlug :one
+SELECT id
+FROM projects
+WHERE slug = $1
+`
+
+func (q *Queries) GetProjectIDBySlug(ctx context.Context, slug string) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getProjectIDBySlug, slug)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getProjectIssues = `-- name: GetProjectIssues :many
+SELECT 
+  i.id, 
+  i.project_id,
+  i.title, 
+  i.description, 
+  i.status, 
+  i.reporter_id,
+  i.assignee_id,
+  i.due_date, 
+  i.created_at, 
+  i.updated_at,
+  i.version,
+  i.priority,
+  i.number
+FROM issues i
+WHERE i.project_id = $1
+ORDER BY i.created_at DESC, i.id
+`
+
+func (q *Queries) GetProjectIssues(ctx context.Context, projectID pgtype.UUID) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, getProjectIssues, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Issue
+	for rows.Next() {
+		var i Issue
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.ReporterID,
+			&i.AssigneeID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProjectIssuesFiltered = `-- name: GetProjectIssuesFiltered :many
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = $1
+  AND ($2::text IS NULL OR status = $2::text)
+  AND ($3::text IS NULL OR priority = $3::text)
+  AND ($4::timestamp IS NULL OR due_date > $4::timestamp)
+  AND ($5::timestamp IS NULL OR due_date < $5::timestamp)
+  AND (NOT $6::boolean OR (due_date < now() AND status IS DISTINCT FROM 'closed'))
+ORDER BY
+  CASE WHEN $7::boolean THEN
+    CASE priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END
+  END,
+  created_at DESC, id DESC
+`
+
+type GetProjectIssuesFilteredParams struct {
+	ProjectID  pgtype.UUID
+	Status     pgtype.Text
+	Priority   pgtype.Text
+	DueAfter   pgtype.Timestamp
+	DueBefore  pgtype.Timestamp
+	Overdue    bool
+	ByPriority bool
+}
+
+// A project's issues, keeping only those with the given status and priority
+// and due strictly between due_after and due_before, each filter skipped when
+// NULL. overdue keeps issues past due that aren't closed. Newest first, or
+// most urgent first when by_priority is set, with issues that have no
+// priority last.
+func (q *Queries) GetProjectIssuesFiltered(ctx context.Context, arg GetProjectIssuesFilteredParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, getProjectIssuesFiltered,
+		arg.ProjectID,
+		arg.Status,
+		arg.Priority,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.ByPriority,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Issue
+	for rows.Next() {
+		var i Issue
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.ReporterID,
+			&i.AssigneeID,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -553,37 +2125,37 @@ func (q *Queries) GetIssueComments(ctx context.Context, issueID pgtype.UUID) ([]
 	return items, nil
 }
 
-const getIssuesAssignedToUser = `-- name: GetIssuesAssignedToUser :many
-SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.due_date, 
-       i.created_at, i.updated_at, p.name AS project_name
-FROM issues i
-JOIN projects p ON i.project_id = p.id
-WHERE i.assignee_id = $1
-ORDER BY i.due_date ASC NULLS LAST, i.created_at DESC
+const getProjectIssuesPage = `-- name: GetProjectIssuesPage :many
+SELECT id, project_id, title, description, status, reporter_id, assignee_id, due_date, created_at, updated_at, version, priority, number
+FROM issues
+WHERE project_id = $1
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
-type GetIssuesAssignedToUserRow struct {
-	ID          pgtype.UUID
-	ProjectID   pgtype.UUID
-	Title       string
-	Description pgtype.Text
-	Status      pgtype.Text
-	ReporterID  pgtype.UUID
-	DueDate     pgtype.Timestamp
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
-	ProjectName string
+type GetProjectIssuesPageParams struct {
+	ProjectID       pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
 }
 
-func (q *Queries) GetIssuesAssignedToUser(ctx context.Context, assigneeID pgtype.UUID) ([]GetIssuesAssignedToUserRow, error) {
-	rows, err := q.db.Query(ctx, getIssuesAssignedToUser, assigneeID)
+func (q *Queries) GetProjectIssuesPage(ctx context.Context, arg GetProjectIssuesPageParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, getProjectIssuesPage,
+		arg.ProjectID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetIssuesAssignedToUserRow
+	var items []Issue
 	for rows.Next() {
-		var i GetIssuesAssignedToUserRow
+		var i Issue
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProjectID,
@@ -591,10 +2163,13 @@ func (q *Queries) GetIssuesAssignedToUser(ctx context.Context, assigneeID pgtype
 			&i.Description,
 			&i.Status,
 			&i.ReporterID,
+			&i.AssigneeID,
 			&i.DueDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.ProjectName,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -606,58 +2181,50 @@ func (q *Queries) GetIssuesAssignedToUser(ctx context.Context, assigneeID pgtype
 	return items, nil
 }
 
-const getIssuesByStatus = `-- name: GetIssuesByStatus :many
-SELECT 
-  i.id, 
-  i.project_id,  -- Make sure project_id is explicitly included
-  i.title, 
-  i.description, 
-  i.reporter_id,
-  i.assignee_id, 
-  i.due_date, 
-  i.created_at, 
-  i.updated_at
+const getProjectIssuesWithAllLabels = `-- name: GetProjectIssuesWithAllLabels :many
+SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.assignee_id,
+       i.due_date, i.created_at, i.updated_at, i.version, i.priority, i.number
 FROM issues i
-WHERE i.project_id = $1 AND i.status = $2
-ORDER BY i.created_at DESC
+WHERE i.project_id = $1
+  AND i.id IN (
+    SELECT il.issue_id
+    FROM issue_labels il
+    JOIN labels l ON l.id = il.label_id
+    WHERE l.project_id = $1 AND l.name = ANY($2::text[])
+    GROUP BY il.issue_id
+    HAVING COUNT(DISTINCT l.name) = cardinality($2::text[])
+  )
+ORDER BY i.created_at DESC, i.id
 `
 
-type GetIssuesByStatusParams struct {
+type GetProjectIssuesWithAllLabelsParams struct {
 	ProjectID pgtype.UUID
-	Status    pgtype.Text
-}
-
-type GetIssuesByStatusRow struct {
-	ID          pgtype.UUID
-	ProjectID   pgtype.UUID
-	Title       string
-	Description pgtype.Text
-	ReporterID  pgtype.UUID
-	AssigneeID  pgtype.UUID
-	DueDate     pgtype.Timestamp
-	CreatedAt   pgtype.Timestamp
-	UpdatedAt   pgtype.Timestamp
+	Column2   []string
 }
 
-func (q *Queries) GetIssuesByStatus(ctx context.Context, arg GetIssuesByStatusParams) ([]GetIssuesByStatusRow, error) {
-	rows, err := q.db.Query(ctx, getIssuesByStatus, arg.ProjectID, arg.Status)
+func (q *Queries) GetProjectIssuesWithAllLabels(ctx context.Context, arg GetProjectIssuesWithAllLabelsParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, getProjectIssuesWithAllLabels, arg.ProjectID, arg.Column2)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetIssuesByStatusRow
+	var items []Issue
 	for rows.Next() {
-		var i GetIssuesByStatusRow
+		var i Issue
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProjectID,
 			&i.Title,
 			&i.Description,
+			&i.Status,
 			&i.ReporterID,
 			&i.AssigneeID,
 			&i.DueDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -669,44 +2236,48 @@ func (q *Queries) GetIssuesByStatus(ctx context.Context, arg GetIssuesByStatusPa
 	return items, nil
 }
 
-const getOverdueTasks = `-- name: GetOverdueTasks :many
-SELECT t.id, t.project_id, t.assignee_id, t.title, t.status, t.priority, t.due_date, 
-       p.name AS project_name
-FROM tasks t
-JOIN projects p ON t.project_id = p.id
-WHERE t.due_date < now() AND t.status != 'done' AND t.assignee_id = $1
-ORDER BY t.due_date ASC
+const getProjectIssuesWithAnyLabel = `-- name: GetProjectIssuesWithAnyLabel :many
+SELECT i.id, i.project_id, i.title, i.description, i.status, i.reporter_id, i.assignee_id,
+       i.due_date, i.created_at, i.updated_at, i.version, i.priority, i.number
+FROM issues i
+WHERE i.project_id = $1
+  AND EXISTS (
+    SELECT 1
+    FROM issue_labels il
+    JOIN labels l ON l.id = il.label_id
+    WHERE il.issue_id = i.id AND l.name = ANY($2::text[])
+  )
+ORDER BY i.created_at DESC, i.id
 `
 
-type GetOverdueTasksRow struct {
-	ID          pgtype.UUID
-	ProjectID   pgtype.UUID
-	AssigneeID  pgtype.UUID
-	Title       string
-	Status      pgtype.Text
-	Priority    pgtype.Text
-	DueDate     pgtype.Timestamp
-	ProjectName string
+type GetProjectIssuesWithAnyLabelParams struct {
+	ProjectID pgtype.UUID
+	Column2   []string
 }
 
-func (q *Queries) GetOverdueTasks(ctx context.Context, assigneeID pgtype.UUID) ([]GetOverdueTasksRow, error) {
-	rows, err := q.db.Query(ctx, getOverdueTasks, assigneeID)
+func (q *Queries) GetProjectIssuesWithAnyLabel(ctx context.Context, arg GetProjectIssuesWithAnyLabelParams) ([]Issue, error) {
+	rows, err := q.db.Query(ctx, getProjectIssuesWithAnyLabel, arg.ProjectID, arg.Column2)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetOverdueTasksRow
+	var items []Issue
 	for rows.Next() {
-		var i GetOverdueTasksRow
+		var i Issue
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProjectID,
-			&i.AssigneeID,
 			&i.Title,
+			&i.Description,
 			&i.Status,
-			&i.Priority,
+			&i.ReporterID,
+			&i.AssigneeID,
 			&i.DueDate,
-			&i.ProjectName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Priority,
+			&i.Number,
 		); err != nil {
 			return nil, err
 		}
@@ -718,65 +2289,28 @@ func (q *Queries) GetOverdueTasks(ctx context.Context, assigneeID pgtype.UUID) (
 	return items, nil
 }
 
-const getProjectByID = `-- name: GetProjectByID :one
-SELECT id, name, description, owner_id, team_id, status, created_at, updated_at
-FROM projects
-WHERE id = $1
-`
-
-func (q *Queries) GetProjectByID(ctx context.Context, id pgtype.UUID) (Project, error) {
-	row := q.db.QueryRow(ctx, getProjectByID, id)
-	var i Project
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.OwnerID,
-		&i.TeamID,
-		&i.Status,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const getProjectIssues = `-- name: GetProjectIssues :many
-SELECT 
-  i.id, 
-  i.project_id,
-  i.title, 
-  i.description, 
-  i.status, 
-  i.reporter_id,
-  i.assignee_id,
-  i.due_date, 
-  i.created_at, 
-  i.updated_at
-FROM issues i
-WHERE i.project_id = $1
-ORDER BY i.created_at DESC
+const getProjectLabels = `-- name: GetProjectLabels :many
+SELECT id, project_id, name, color, created_at
+FROM labels
+WHERE project_id = $1
+ORDER BY name, id
 `
 
-func (q *Queries) GetProjectIssues(ctx context.Context, projectID pgtype.UUID) ([]Issue, error) {
-	rows, err := q.db.Query(ctx, getProjectIssues, projectID)
+func (q *Queries) GetProjectLabels(ctx context.Context, projectID pgtype.UUID) ([]Label, error) {
+	rows, err := q.db.Query(ctx, getProjectLabels, projectID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Issue
+	var items []Label
 	for rows.Next() {
-		var i Issue
+		var i Label
 		if err := rows.Scan(
 			&i.ID,
 			&i.ProjectID,
-			&i.Title,
-			&i.Description,
-			&i.Status,
-			&i.ReporterID,
-			&i.AssigneeID,
-			&i.DueDate,
+			&i.Name,
+			&i.Color,
 			&i.CreatedAt,
-			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -831,7 +2365,7 @@ const getProjectTasks = `-- name: GetProjectTasks :many
 SELECT id, assignee_id, title, description, status, priority, due_date, created_at, updated_at
 FROM tasks
 WHERE project_id = $1
-ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC
+ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC, id
 `
 
 type GetProjectTasksRow struct {
@@ -876,11 +2410,48 @@ func (q *Queries) GetProjectTasks(ctx context.Context, projectID pgtype.UUID) ([
 	return items, nil
 }
 
+const getProjectWebhooks = `-- name: GetProjectWebhooks :many
+SELECT id, project_id, url, secret, events, created_by, created_at, updated_at, type
+FROM webhooks
+WHERE project_id = $1
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetProjectWebhooks(ctx context.Context, projectID pgtype.UUID) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, getProjectWebhooks, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getProjectsByStatus = `-- name: GetProjectsByStatus :many
 SELECT id, name, description, owner_id, team_id, created_at, updated_at , status
 FROM projects
 WHERE status = $1
-ORDER BY updated_at DESC
+ORDER BY created_at DESC, id
 LIMIT $2 OFFSET $3
 `
 
@@ -934,13 +2505,13 @@ const getRecentComments = `-- name: GetRecentComments :many
 SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at,
        u.name AS user_name, u.username
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.issue_id IN (
     SELECT id FROM issues WHERE issues.assignee_id = $1
 ) OR c.task_id IN (
     SELECT id FROM tasks WHERE tasks.assignee_id = $1
 )
-ORDER BY c.created_at DESC
+ORDER BY c.created_at DESC, c.id
 LIMIT $2
 `
 
@@ -1001,7 +2572,7 @@ WHERE i.project_id IN (
     JOIN team_members tm ON t.id = tm.team_id
     WHERE tm.user_id = $1
 )
-ORDER BY i.created_at DESC
+ORDER BY i.created_at DESC, i.id
 LIMIT $2
 `
 
@@ -1071,12 +2642,12 @@ func (q *Queries) GetTaskByID(ctx context.Context, id pgtype.UUID) (Task, error)
 }
 
 const getTaskComments = `-- name: GetTaskComments :many
-SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at,
-       u.email, u.name, u.username, u.avatar_url
+SELECT c.id, c.content, c.user_id, c.issue_id, c.task_id, c.created_at, c.updated_at, c.edited_at,
+       COALESCE(u.email, '') AS email, u.name, u.username, u.avatar_url
 FROM comments c
-JOIN users u ON c.user_id = u.id
+LEFT JOIN users u ON c.user_id = u.id
 WHERE c.task_id = $1
-ORDER BY c.created_at ASC
+ORDER BY c.created_at ASC, c.id
 `
 
 type GetTaskCommentsRow struct {
@@ -1087,6 +2658,7 @@ type GetTaskCommentsRow struct {
 	TaskID    pgtype.UUID
 	CreatedAt pgtype.Timestamp
 	UpdatedAt pgtype.Timestamp
+	EditedAt  pgtype.Timestamp
 	Email     string
 	Name      pgtype.Text
 	Username  pgtype.Text
@@ -1110,6 +2682,7 @@ func (q *Queries) GetTaskComments(ctx context.Context, taskID pgtype.UUID) ([]Ge
 			&i.TaskID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EditedAt,
 			&i.Email,
 			&i.Name,
 			&i.Username,
@@ -1125,11 +2698,103 @@ func (q *Queries) GetTaskComments(ctx context.Context, taskID pgtype.UUID) ([]Ge
 	return items, nil
 }
 
+const getTaskTimeByUser = `-- name: GetTaskTimeByUser :many
+SELECT e.user_id, u.name, u.username, SUM(e.minutes)::bigint AS minutes
+FROM task_time_entries e
+LEFT JOIN users u ON u.id = e.user_id
+WHERE e.task_id = $1
+GROUP BY e.user_id, u.name, u.username
+ORDER BY minutes DESC, e.user_id
+`
+
+type GetTaskTimeByUserRow struct {
+	UserID   pgtype.UUID
+	Name     pgtype.Text
+	Username pgtype.Text
+	Minutes  int64
+}
+
+// Minutes logged on a task per user, most first. Entries by deleted users are
+// totalled under a NULL user_id.
+func (q *Queries) GetTaskTimeByUser(ctx context.Context, taskID pgtype.UUID) ([]GetTaskTimeByUserRow, error) {
+	rows, err := q.db.Query(ctx, getTaskTimeByUser, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTaskTimeByUserRow
+	for rows.Next() {
+		var i GetTaskTimeByUserRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Name,
+			&i.Username,
+			&i.Minutes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTaskTimeEntries = `-- name: GetTaskTimeEntries :many
+SELECT e.id, e.task_id, e.user_id, e.minutes, e.note, e.created_at, u.name, u.username
+FROM task_time_entries e
+LEFT JOIN users u ON u.id = e.user_id
+WHERE e.task_id = $1
+ORDER BY e.created_at DESC, e.id DESC
+`
+
+type GetTaskTimeEntriesRow struct {
+	ID        pgtype.UUID
+	TaskID    pgtype.UUID
+	UserID    pgtype.UUID
+	Minutes   int32
+	Note      pgtype.Text
+	CreatedAt pgtype.Timestamp
+	Name      pgtype.Text
+	Username  pgtype.Text
+}
+
+// Newest first, with the name of whoever logged each entry
+func (q *Queries) GetTaskTimeEntries(ctx context.Context, taskID pgtype.UUID) ([]GetTaskTimeEntriesRow, error) {
+	rows, err := q.db.Query(ctx, getTaskTimeEntries, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTaskTimeEntriesRow
+	for rows.Next() {
+		var i GetTaskTimeEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.UserID,
+			&i.Minutes,
+			&i.Note,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTasksByStatus = `-- name: GetTasksByStatus :many
 SELECT id, project_id, assignee_id, title, description, priority, due_date, created_at, updated_at
 FROM tasks
 WHERE project_id = $1 AND status = $2
-ORDER BY priority DESC, due_date ASC NULLS LAST
+ORDER BY priority DESC, due_date ASC NULLS LAST, created_at DESC, id
 `
 
 type GetTasksByStatusParams struct {
@@ -1183,6 +2848,7 @@ const getTeamAdmins = `-- name: GetTeamAdmins :many
 SELECT user_id, role
 FROM team_members
 WHERE team_id = $1 AND role = 'admin'
+ORDER BY created_at, user_id
 `
 
 type GetTeamAdminsRow struct {
@@ -1210,8 +2876,40 @@ func (q *Queries) GetTeamAdmins(ctx context.Context, teamID pgtype.UUID) ([]GetT
 	return items, nil
 }
 
+const getTeamAttachmentKeys = `-- name: GetTeamAttachmentKeys :many
+
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+JOIN projects p ON p.id = COALESCE(i.project_id, t.project_id)
+WHERE p.team_id = $1
+`
+
+// Account deletion
+// Where the files attached to issues and tasks in a team's projects are stored
+func (q *Queries) GetTeamAttachmentKeys(ctx context.Context, teamID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, getTeamAttachmentKeys, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTeamByID = `-- name: GetTeamByID :one
-SELECT id, name, description, avatar_url, created_at, updated_at
+SELECT id, name, description, avatar_url, created_at, updated_at, slug
 FROM teams
 WHERE id = $1
 `
@@ -1226,10 +2924,24 @@ func (q *Queries) GetTeamByID(ctx context.Context, id pgtype.UUID) (Team, error)
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
 
+const getTeamIDBySlug = `-- name: GetTeamIDBySlug :one
+SELECT id
+FROM teams
+WHERE slug = $1
+`
+
+func (q *Queries) GetTeamIDBySlug(ctx context.Context, slug string) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getTeamIDBySlug, slug)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getTeamMember = `-- name: GetTeamMember :one
 SELECT team_id, user_id, role, created_at
 FROM team_members
@@ -1283,7 +2995,7 @@ SELECT u.id, u.email, u.name, u.username, u.avatar_url, tm.role
 FROM team_members tm
 JOIN users u ON u.id = tm.user_id
 WHERE tm.team_id = $1
-ORDER BY tm.created_at
+ORDER BY tm.created_at, u.id
 `
 
 type GetTeamMembersRow struct {
@@ -1331,10 +3043,12 @@ SELECT
   p.team_id,  -- Make sure TeamID is explicitly included
   p.status, 
   p.created_at, 
-  p.updated_at
+  p.updated_at,
+  p.version,
+  p.slug
 FROM projects p
 WHERE p.team_id = $1
-ORDER BY p.created_at DESC
+ORDER BY p.created_at DESC, p.id
 `
 
 func (q *Queries) GetTeamProjects(ctx context.Context, teamID pgtype.UUID) ([]Project, error) {
@@ -1355,6 +3069,8 @@ func (q *Queries) GetTeamProjects(ctx context.Context, teamID pgtype.UUID) ([]Pr
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
+			&i.Slug,
 		); err != nil {
 			return nil, err
 		}
@@ -1366,6 +3082,64 @@ func (q *Queries) GetTeamProjects(ctx context.Context, teamID pgtype.UUID) ([]Pr
 	return items, nil
 }
 
+const getUserAPIKeys = `-- name: GetUserAPIKeys :many
+SELECT id, user_id, name, key_hash, prefix, scopes, last_used_at, expires_at, revoked_at, created_at
+FROM api_keys
+WHERE user_id = $1
+ORDER BY created_at DESC, id
+`
+
+func (q *Queries) GetUserAPIKeys(ctx context.Context, userID pgtype.UUID) ([]ApiKey, error) {
+	rows, err := q.db.Query(ctx, getUserAPIKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKey
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.KeyHash,
+			&i.Prefix,
+			&i.Scopes,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAccess = `-- name: GetUserAccess :one
+SELECT is_admin, disabled_at
+FROM users
+WHERE id = $1
+`
+
+type GetUserAccessRow struct {
+	IsAdmin    bool
+	DisabledAt pgtype.Timestamp
+}
+
+// What the user may do: admins manage other accounts, and disabled accounts
+// can't do anything.
+func (q *Queries) GetUserAccess(ctx context.Context, id pgtype.UUID) (GetUserAccessRow, error) {
+	row := q.db.QueryRow(ctx, getUserAccess, id)
+	var i GetUserAccessRow
+	err := row.Scan(&i.IsAdmin, &i.DisabledAt)
+	return i, err
+}
+
 const getUserActivityFeed = `-- name: GetUserActivityFeed :many
 WITH user_activities AS (
   -- Projects created
@@ -1408,7 +3182,7 @@ WITH user_activities AS (
   WHERE t.assignee_id = $1 AND t.updated_at > t.created_at
 )
 SELECT activity_type, entity_id, entity_name, related_entity_id, related_entity_name, activity_time FROM user_activities
-ORDER BY activity_time DESC
+ORDER BY activity_time DESC, entity_id
 LIMIT $2
 `
 
@@ -1454,7 +3228,7 @@ func (q *Queries) GetUserActivityFeed(ctx context.Context, arg GetUserActivityFe
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at
+SELECT id, email, password, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at, is_admin, disabled_at
 FROM users
 WHERE email = $1
 `
@@ -1475,6 +3249,8 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.AccountStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.DisabledAt,
 	)
 	return i, err
 }
@@ -1521,7 +3297,9 @@ func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (GetUserByIDR
 const getUserByUsername = `-- name: GetUserByUsername :one
 SELECT id, email, name, username, avatar_url, bio, email_verified, last_login_at, account_status, created_at, updated_at
 FROM users
-WHERE username = $1
+WHERE lower(username) = lower($1)
+ORDER BY username = $1 DESC
+LIMIT 1
 `
 
 type GetUserByUsernameRow struct {
@@ -1538,7 +3316,9 @@ type GetUserByUsernameRow struct {
 	UpdatedAt     pgtype.Timestamp
 }
 
-func (q *Queries) GetUserByUsername(ctx context.Context, username pgtype.Text) (GetUserByUsernameRow, error) {
+// Usernames match case-insensitively. An exact match wins if two accounts
+// differ only by case.
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
 	row := q.db.QueryRow(ctx, getUserByUsername, username)
 	var i GetUserByUsernameRow
 	err := row.Scan(
@@ -1627,11 +3407,41 @@ func (q *Queries) GetUserProfile(ctx context.Context, id pgtype.UUID) (GetUserPr
 	return i, err
 }
 
+const getUserProjectAttachmentKeys = `-- name: GetUserProjectAttachmentKeys :many
+SELECT a.storage_key
+FROM attachments a
+LEFT JOIN issues i ON a.issue_id = i.id
+LEFT JOIN tasks t ON a.task_id = t.id
+JOIN projects p ON p.id = COALESCE(i.project_id, t.project_id)
+WHERE p.owner_id = $1
+`
+
+// Where the files attached to issues and tasks in a user's projects are stored
+func (q *Queries) GetUserProjectAttachmentKeys(ctx context.Context, ownerID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, getUserProjectAttachmentKeys, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserProjects = `-- name: GetUserProjects :many
-SELECT id, name, description, owner_id, team_id, status, created_at, updated_at
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
 FROM projects
 WHERE owner_id = $1
-ORDER BY updated_at DESC
+ORDER BY created_at DESC, id
 `
 
 func (q *Queries) GetUserProjects(ctx context.Context, ownerID pgtype.UUID) ([]Project, error) {
@@ -1652,6 +3462,68 @@ func (q *Queries) GetUserProjects(ctx context.Context, ownerID pgtype.UUID) ([]P
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserProjectsPage = `-- name: GetUserProjectsPage :many
+SELECT id, name, description, owner_id, team_id, status, created_at, updated_at, version, slug
+FROM projects
+WHERE (owner_id = $1 OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $1))
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+  AND ($4::boolean OR status IS DISTINCT FROM 'archived')
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type GetUserProjectsPageParams struct {
+	UserID          pgtype.UUID
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	IncludeArchived bool
+	Limit           int32
+}
+
+// The projects the user owns or that belong to one of their teams. Keyset
+// pagination: pass the created_at and id of the last project on the previous
+// page, or NULLs for the first page. Archived projects are left out unless
+// include_archived is set.
+func (q *Queries) GetUserProjectsPage(ctx context.Context, arg GetUserProjectsPageParams) ([]Project, error) {
+	rows, err := q.db.Query(ctx, getUserProjectsPage,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.IncludeArchived,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.OwnerID,
+			&i.TeamID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.Slug,
 		); err != nil {
 			return nil, err
 		}
@@ -1669,9 +3541,20 @@ SELECT t.id, t.project_id, t.title, t.description, t.status, t.priority, t.due_d
 FROM tasks t
 JOIN projects p ON t.project_id = p.id
 WHERE t.assignee_id = $1
-ORDER BY t.due_date ASC NULLS LAST, t.priority DESC, t.created_at DESC
+  AND (p.owner_id = $1 OR EXISTS (
+    SELECT 1 FROM team_members tm WHERE tm.team_id = p.team_id AND tm.user_id = $1))
+  AND ($2::text IS NULL OR t.status = $2::text)
+ORDER BY
+  CASE WHEN $3::boolean THEN t.due_date END DESC NULLS LAST,
+  t.due_date ASC NULLS LAST, t.priority DESC, t.created_at DESC, t.id
 `
 
+type GetUserTasksParams struct {
+	AssigneeID     pgtype.UUID
+	Status         pgtype.Text
+	LatestDueFirst bool
+}
+
 type GetUserTasksRow struct {
 	ID          pgtype.UUID
 	ProjectID   pgtype.UUID
@@ -1685,30 +3568,294 @@ type GetUserTasksRow struct {
 	ProjectName string
 }
 
-func (q *Queries) GetUserTasks(ctx context.Context, assigneeID pgtype.UUID) ([]GetUserTasksRow, error) {
-	rows, err := q.db.Query(ctx, getUserTasks, assigneeID)
+// Leaves out tasks in projects the user can no longer access, as neither the
+// owner nor a member of the project's team
+func (q *Queries) GetUserTasks(ctx context.Context, arg GetUserTasksParams) ([]GetUserTasksRow, error) {
+	rows, err := q.db.Query(ctx, getUserTasks, arg.AssigneeID, arg.Status, arg.LatestDueFirst)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserTasksRow
+	for rows.Next() {
+		var i GetUserTasksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserTeams = `-- name: GetUserTeams :many
+SELECT t.id, t.name, t.description, t.avatar_url, tm.role, t.created_at, t.updated_at,
+  (SELECT COUNT(*) FROM team_members m WHERE m.team_id = t.id) AS member_count,
+  (SELECT o.user_id FROM team_members o
+   WHERE o.team_id = t.id AND o.role = 'owner'
+   ORDER BY o.created_at LIMIT 1)::uuid AS owner_id,
+  t.slug
+FROM teams t
+JOIN team_members tm ON t.id = tm.team_id
+WHERE tm.user_id = $1
+ORDER BY t.name, t.id
+`
+
+type GetUserTeamsRow struct {
+	ID          pgtype.UUID
+	Name        string
+	Description pgtype.Text
+	AvatarUrl   pgtype.Text
+	Role        pgtype.Text
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+	MemberCount int64
+	OwnerID     pgtype.UUID
+	Slug        string
+}
+
+func (q *Queries) GetUserTeams(ctx context.Context, userID pgtype.UUID) ([]GetUserTeamsRow, error) {
+	rows, err := q.db.Query(ctx, getUserTeams, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserTeamsRow
+	for rows.Next() {
+		var i GetUserTeamsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.AvatarUrl,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MemberCount,
+			&i.OwnerID,
+			&i.Slug,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersByUsernames = `-- name: GetUsersByUsernames :many
+SELECT id, email, name, username
+FROM users
+WHERE username = ANY($1::text[])
+`
+
+type GetUsersByUsernamesRow struct {
+	ID       pgtype.UUID
+	Email    string
+	Name     pgtype.Text
+	Username pgtype.Text
+}
+
+func (q *Queries) GetUsersByUsernames(ctx context.Context, dollar_1 []string) ([]GetUsersByUsernamesRow, error) {
+	rows, err := q.db.Query(ctx, getUsersByUsernames, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByUsernamesRow
+	for rows.Next() {
+		var i GetUsersByUsernamesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookByID = `-- name: GetWebhookByID :one
+SELECT id, project_id, url, secret, events, created_by, created_at, updated_at, type
+FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookByID(ctx context.Context, id pgtype.UUID) (Webhook, error) {
+	row := q.db.QueryRow(ctx, getWebhookByID, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+	)
+	return i, err
+}
+
+const listAuditEntries = `-- name: ListAuditEntries :many
+SELECT id, actor_id, action, target_type, target_id, team_id, metadata, created_at
+FROM audit_log
+WHERE ($1::uuid IS NULL OR team_id = $1)
+  AND ($2::text IS NULL OR action = $2)
+  AND ($3::timestamp IS NULL OR created_at >= $3)
+  AND ($4::timestamp IS NULL OR created_at < $4)
+  AND ($5::timestamp IS NULL
+       OR (created_at, id) < ($5::timestamp, $6::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $7
+`
+
+type ListAuditEntriesParams struct {
+	TeamID          pgtype.UUID
+	Action          pgtype.Text
+	Since           pgtype.Timestamp
+	Until           pgtype.Timestamp
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+// Audit entries, newest first. Each filter is skipped when NULL: team_id
+// keeps one team's entries, action one kind of action, and since and until a
+// time range, until being exclusive. Keyset pagination: pass the created_at
+// and id of the last entry on the previous page, or NULLs for the first page.
+func (q *Queries) ListAuditEntries(ctx context.Context, arg ListAuditEntriesParams) ([]AuditLog, error) {
+	rows, err := q.db.Query(ctx, listAuditEntries,
+		arg.TeamID,
+		arg.Action,
+		arg.Since,
+		arg.Until,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorID,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.TeamID,
+			&i.Metadata,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotifications = `-- name: ListNotifications :many
+SELECT id, user_id, actor_id, type, subject, message, issue_id, team_id, read_at, created_at
+FROM notifications
+WHERE user_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3
+`
+
+type ListNotificationsParams struct {
+	UserID pgtype.UUID
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.Query(ctx, listNotifications, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Type,
+			&i.Subject,
+			&i.Message,
+			&i.IssueID,
+			&i.TeamID,
+			&i.ReadAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectSlugs = `-- name: ListProjectSlugs :many
+SELECT slug
+FROM projects
+WHERE (slug = $1::text OR slug LIKE $1::text || '-%')
+  AND id IS DISTINCT FROM $2::uuid
+`
+
+type ListProjectSlugsParams struct {
+	Base      string
+	ExcludeID pgtype.UUID
+}
+
+// Slugs equal to base or base with a suffix, e.g. my-project-2, so a new slug
+// can be picked that none of them have. exclude_id leaves out the project
+// being renamed.
+func (q *Queries) ListProjectSlugs(ctx context.Context, arg ListProjectSlugsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listProjectSlugs, arg.Base, arg.ExcludeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetUserTasksRow
+	var items []string
 	for rows.Next() {
-		var i GetUserTasksRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.ProjectID,
-			&i.Title,
-			&i.Description,
-			&i.Status,
-			&i.Priority,
-			&i.DueDate,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.ProjectName,
-		); err != nil {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, slug)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -1716,41 +3863,34 @@ func (q *Queries) GetUserTasks(ctx context.Context, assigneeID pgtype.UUID) ([]G
 	return items, nil
 }
 
-const getUserTeams = `-- name: GetUserTeams :many
-SELECT t.id, t.name, t.description, t.avatar_url, tm.role
-FROM teams t
-JOIN team_members tm ON t.id = tm.team_id
-WHERE tm.user_id = $1
-ORDER BY t.name
+const listTeamSlugs = `-- name: ListTeamSlugs :many
+SELECT slug
+FROM teams
+WHERE (slug = $1::text OR slug LIKE $1::text || '-%')
+  AND id IS DISTINCT FROM $2::uuid
 `
 
-type GetUserTeamsRow struct {
-	ID          pgtype.UUID
-	Name        string
-	Description pgtype.Text
-	AvatarUrl   pgtype.Text
-	Role        pgtype.Text
+type ListTeamSlugsParams struct {
+	Base      string
+	ExcludeID pgtype.UUID
 }
 
-func (q *Queries) GetUserTeams(ctx context.Context, userID pgtype.UUID) ([]GetUserTeamsRow, error) {
-	rows, err := q.db.Query(ctx, getUserTeams, userID)
+// Slugs equal to base or base with a suffix, e.g. my-team-2, so a new slug
+// can be picked that none of them have. exclude_id leaves out the team being
+// renamed.
+func (q *Queries) ListTeamSlugs(ctx context.Context, arg ListTeamSlugsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listTeamSlugs, arg.Base, arg.ExcludeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetUserTeamsRow
+	var items []string
 	for rows.Next() {
-		var i GetUserTeamsRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.AvatarUrl,
-			&i.Role,
-		); err != nil {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, slug)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -1761,7 +3901,7 @@ func (q *Queries) GetUserTeams(ctx context.Context, userID pgtype.UUID) ([]GetUs
 const listUsers = `-- name: ListUsers :many
 SELECT id, email, name, username, avatar_url, email_verified, account_status, created_at
 FROM users
-ORDER BY created_at DESC
+ORDER BY created_at DESC, id
 LIMIT $1 OFFSET $2
 `
 
@@ -1810,6 +3950,219 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 	return items, nil
 }
 
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT id, email, name, username, email_verified, account_status, is_admin, last_login_at, created_at, disabled_at
+FROM users
+WHERE ($1::text IS NULL
+       OR email ILIKE '%' || $1 || '%'
+       OR name ILIKE '%' || $1 || '%'
+       OR username ILIKE '%' || $1 || '%')
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListUsersPageParams struct {
+	Search          pgtype.Text
+	CursorCreatedAt pgtype.Timestamp
+	CursorID        pgtype.UUID
+	Limit           int32
+}
+
+type ListUsersPageRow struct {
+	ID            pgtype.UUID
+	Email         string
+	Name          pgtype.Text
+	Username      pgtype.Text
+	EmailVerified pgtype.Bool
+	AccountStatus pgtype.Text
+	IsAdmin       bool
+	LastLoginAt   pgtype.Timestamp
+	CreatedAt     pgtype.Timestamp
+	DisabledAt    pgtype.Timestamp
+}
+
+// Every account, newest first, for admins. Keyset pagination: pass the
+// created_at and id of the last user on the previous page, or NULLs for the
+// first page. A search matches email, name or username case-insensitively;
+// NULL lists everyone.
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]ListUsersPageRow, error) {
+	rows, err := q.db.Query(ctx, listUsersPage,
+		arg.Search,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersPageRow
+	for rows.Next() {
+		var i ListUsersPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Username,
+			&i.EmailVerified,
+			&i.AccountStatus,
+			&i.IsAdmin,
+			&i.LastLoginAt,
+			&i.CreatedAt,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, now())
+WHERE id = $1 AND user_id = $2
+`
+
+type MarkNotificationReadParams struct {
+	ID     pgtype.UUID
+	UserID pgtype.UUID
+}
+
+func (q *Queries) MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) (int64, error) {
+	result, err := q.db.Exec(ctx, markNotificationRead, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const nextIssueNumber = `-- name: NextIssueNumber :one
+INSERT INTO project_issue_counters (project_id, last_number)
+VALUES ($1, 1)
+ON CONFLICT (project_id) DO UPDATE SET last_number = project_issue_counters.last_number + 1
+RETURNING last_number
+`
+
+// ------------------------------------------------------
+// Issues
+// Takes the project's next issue number. The counter's row stays locked
+// until the transaction ends, so run it in the one that creates the issue.
+func (q *Queries) NextIssueNumber(ctx context.Context, projectID pgtype.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, nextIssueNumber, projectID)
+	var last_number int32
+	err := row.Scan(&last_number)
+	return last_number, err
+}
+
+const reassignTeamMemberIssues = `-- name: ReassignTeamMemberIssues :execrows
+UPDATE issues
+SET assignee_id = $1, updated_at = now(), version = version + 1
+WHERE assignee_id = $2
+  AND status IS DISTINCT FROM 'closed'
+  AND project_id IN (SELECT id FROM projects WHERE team_id = $3)
+`
+
+type ReassignTeamMemberIssuesParams struct {
+	NewAssigneeID pgtype.UUID
+	AssigneeID    pgtype.UUID
+	TeamID        pgtype.UUID
+}
+
+// Moves a member's open issues in the team's projects to another assignee,
+// or unassigns them when the new assignee is NULL.
+func (q *Queries) ReassignTeamMemberIssues(ctx context.Context, arg ReassignTeamMemberIssuesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reassignTeamMemberIssues, arg.NewAssigneeID, arg.AssigneeID, arg.TeamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const reassignTeamMemberTasks = `-- name: ReassignTeamMemberTasks :execrows
+UPDATE tasks
+SET assignee_id = $1, updated_at = now()
+WHERE assignee_id = $2
+  AND status IS DISTINCT FROM 'done'
+  AND project_id IN (SELECT id FROM projects WHERE team_id = $3)
+`
+
+type ReassignTeamMemberTasksParams struct {
+	NewAssigneeID pgtype.UUID
+	AssigneeID    pgtype.UUID
+	TeamID        pgtype.UUID
+}
+
+func (q *Queries) ReassignTeamMemberTasks(ctx context.Context, arg ReassignTeamMemberTasksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reassignTeamMemberTasks, arg.NewAssigneeID, arg.AssigneeID, arg.TeamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeCommentReaction = `-- name: RemoveCommentReaction :execrows
+DELETE FROM comment_reactions
+WHERE comment_id = $1 AND user_id = $2 AND emoji = $3
+`
+
+type RemoveCommentReactionParams struct {
+	CommentID pgtype.UUID
+	UserID    pgtype.UUID
+	Emoji     string
+}
+
+func (q *Queries) RemoveCommentReaction(ctx context.Context, arg RemoveCommentReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeCommentReaction, arg.CommentID, arg.UserID, arg.Emoji)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeIssueLabel = `-- name: RemoveIssueLabel :exec
+DELETE FROM issue_labels WHERE issue_id = $1 AND label_id = $2
+`
+
+type RemoveIssueLabelParams struct {
+	IssueID pgtype.UUID
+	LabelID pgtype.UUID
+}
+
+func (q *Queries) RemoveIssueLabel(ctx context.Context, arg RemoveIssueLabelParams) error {
+	_, err := q.db.Exec(ctx, removeIssueLabel, arg.IssueID, arg.LabelID)
+	return err
+}
+
+const removeIssueWatcher = `-- name: RemoveIssueWatcher :exec
+DELETE FROM issue_watchers WHERE issue_id = $1 AND user_id = $2
+`
+
+type RemoveIssueWatcherParams struct {
+	IssueID pgtype.UUID
+	UserID  pgtype.UUID
+}
+
+func (q *Queries) RemoveIssueWatcher(ctx context.Context, arg RemoveIssueWatcherParams) error {
+	_, err := q.db.Exec(ctx, removeIssueWatcher, arg.IssueID, arg.UserID)
+	return err
+}
+
+const removeUserFromAllTeams = `-- name: RemoveUserFromAllTeams :exec
+DELETE FROM team_members
+WHERE user_id = $1
+`
+
+func (q *Queries) RemoveUserFromAllTeams(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, removeUserFromAllTeams, userID)
+	return err
+}
+
 const removeUserFromTeam = `-- name: RemoveUserFromTeam :exec
 DELETE FROM team_members
 WHERE team_id = $1 AND user_id = $2
@@ -1825,6 +4178,43 @@ func (q *Queries) RemoveUserFromTeam(ctx context.Context, arg RemoveUserFromTeam
 	return err
 }
 
+const revokeAPIKey = `-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeAPIKeyParams struct {
+	ID     pgtype.UUID
+	UserID pgtype.UUID
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeAPIKey, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const saveProjectArchive = `-- name: SaveProjectArchive :exec
+INSERT INTO project_archives (project_id, previous_status, archived_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (project_id) DO UPDATE
+SET previous_status = EXCLUDED.previous_status, archived_by = EXCLUDED.archived_by, archived_at = now()
+`
+
+type SaveProjectArchiveParams struct {
+	ProjectID      pgtype.UUID
+	PreviousStatus pgtype.Text
+	ArchivedBy     pgtype.UUID
+}
+
+func (q *Queries) SaveProjectArchive(ctx context.Context, arg SaveProjectArchiveParams) error {
+	_, err := q.db.Exec(ctx, saveProjectArchive, arg.ProjectID, arg.PreviousStatus, arg.ArchivedBy)
+	return err
+}
+
 const searchEntities = `-- name: SearchEntities :many
 WITH search_results AS (
   -- Projects
@@ -1858,7 +4248,7 @@ WITH search_results AS (
     AND (t.title ILIKE '%' || $2 || '%' OR t.description ILIKE '%' || $2 || '%')
 )
 SELECT entity_type, entity_id, entity_name, entity_description, created_at, user_id, parent_id FROM search_results
-ORDER BY created_at DESC
+ORDER BY created_at DESC, entity_id
 LIMIT $3
 `
 
@@ -1906,9 +4296,54 @@ func (q *Queries) SearchEntities(ctx context.Context, arg SearchEntitiesParams)
 	return items, nil
 }
 
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+`
+
+// Records that a key was used, at most once a minute so a busy key doesn't
+// write on every request
+func (q *Queries) TouchAPIKey(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchAPIKey, id)
+	return err
+}
+
+const transferTeamProjects = `-- name: TransferTeamProjects :execrows
+UPDATE projects
+SET owner_id = $1, updated_at = now(), version = version + 1
+WHERE owner_id = $2 AND team_id = $3
+`
+
+type TransferTeamProjectsParams struct {
+	NewOwnerID pgtype.UUID
+	OwnerID    pgtype.UUID
+	TeamID     pgtype.UUID
+}
+
+// Hands a user's projects in a team over to another member
+func (q *Queries) TransferTeamProjects(ctx context.Context, arg TransferTeamProjectsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, transferTeamProjects, arg.NewOwnerID, arg.OwnerID, arg.TeamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const unassignUserTasks = `-- name: UnassignUserTasks :exec
+UPDATE tasks
+SET assignee_id = NULL, updated_at = now()
+WHERE assignee_id = $1
+`
+
+func (q *Queries) UnassignUserTasks(ctx context.Context, assigneeID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, unassignUserTasks, assigneeID)
+	return err
+}
+
 const updateComment = `-- name: UpdateComment :exec
 UPDATE comments
-SET content = $2, updated_at = now()
+SET content = $2, updated_at = now(), edited_at = now()
 WHERE id = $1
 `
 
@@ -1939,7 +4374,7 @@ func (q *Queries) UpdateCommentContent(ctx context.Context, arg UpdateCommentCon
 	return err
 }
 
-const updateIssueDetails = `-- name: UpdateIssueDetails :exec
+const updateIssueDetails = `-- name: UpdateIssueDetails :execrows
 UPDATE issues
 SET 
   title = COALESCE($2, title),
@@ -1947,8 +4382,10 @@ SET
   status = COALESCE($4, status),
   assignee_id = COALESCE($5, assignee_id),
   due_date = COALESCE($6, due_date),
-  updated_at = now()
-WHERE id = $1
+  priority = COALESCE($7, priority),
+  updated_at = now(),
+  version = version + 1
+WHERE id = $1 AND version = $8
 `
 
 type UpdateIssueDetailsParams struct {
@@ -1958,23 +4395,30 @@ type UpdateIssueDetailsParams struct {
 	Status      pgtype.Text
 	AssigneeID  pgtype.UUID
 	DueDate     pgtype.Timestamp
+	Priority    pgtype.Text
+	Version     int32
 }
 
-func (q *Queries) UpdateIssueDetails(ctx context.Context, arg UpdateIssueDetailsParams) error {
-	_, err := q.db.Exec(ctx, updateIssueDetails,
+func (q *Queries) UpdateIssueDetails(ctx context.Context, arg UpdateIssueDetailsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateIssueDetails,
 		arg.ID,
 		arg.Title,
 		arg.Description,
 		arg.Status,
 		arg.AssigneeID,
 		arg.DueDate,
+		arg.Priority,
+		arg.Version,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateIssueStatus = `-- name: UpdateIssueStatus :exec
 UPDATE issues
-SET status = $2, updated_at = now()
+SET status = $2, updated_at = now(), version = version + 1
 WHERE id = $1
 `
 
@@ -1988,34 +4432,43 @@ func (q *Queries) UpdateIssueStatus(ctx context.Context, arg UpdateIssueStatusPa
 	return err
 }
 
-const updateProjectDetails = `-- name: UpdateProjectDetails :exec
+const updateProjectDetails = `-- name: UpdateProjectDetails :execrows
 UPDATE projects
 SET 
-  name = COALESCE($2, name),
-  description = COALESCE($3, description),
-  status = COALESCE($4, status),
-  team_id = COALESCE($5, team_id),
-  updated_at = now()
-WHERE id = $1
+  name = COALESCE($1, name),
+  description = COALESCE($2, description),
+  status = COALESCE($3, status),
+  team_id = COALESCE($4, team_id),
+  slug = COALESCE($5, slug),
+  updated_at = now(),
+  version = version + 1
+WHERE id = $6 AND version = $7
 `
 
 type UpdateProjectDetailsParams struct {
-	ID          pgtype.UUID
 	Name        string
 	Description pgtype.Text
 	Status      pgtype.Text
 	TeamID      pgtype.UUID
+	Slug        pgtype.Text
+	ID          pgtype.UUID
+	Version     int32
 }
 
-func (q *Queries) UpdateProjectDetails(ctx context.Context, arg UpdateProjectDetailsParams) error {
-	_, err := q.db.Exec(ctx, updateProjectDetails,
-		arg.ID,
+func (q *Queries) UpdateProjectDetails(ctx context.Context, arg UpdateProjectDetailsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateProjectDetails,
 		arg.Name,
 		arg.Description,
 		arg.Status,
 		arg.TeamID,
+		arg.Slug,
+		arg.ID,
+		arg.Version,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateTaskDetails = `-- name: UpdateTaskDetails :exec
@@ -2073,26 +4526,29 @@ func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusPara
 const updateTeam = `-- name: UpdateTeam :exec
 UPDATE teams
 SET 
-  name = COALESCE($2, name),
-  description = COALESCE($3, description),
-  avatar_url = COALESCE($4, avatar_url),
+  name = COALESCE($1, name),
+  description = COALESCE($2, description),
+  avatar_url = COALESCE($3, avatar_url),
+  slug = COALESCE($4, slug),
   updated_at = now()
-WHERE id = $1
+WHERE id = $5
 `
 
 type UpdateTeamParams struct {
-	ID          pgtype.UUID
 	Name        string
 	Description pgtype.Text
 	AvatarUrl   pgtype.Text
+	Slug        pgtype.Text
+	ID          pgtype.UUID
 }
 
 func (q *Queries) UpdateTeam(ctx context.Context, arg UpdateTeamParams) error {
 	_, err := q.db.Exec(ctx, updateTeam,
-		arg.ID,
 		arg.Name,
 		arg.Description,
 		arg.AvatarUrl,
+		arg.Slug,
+		arg.ID,
 	)
 	return err
 }
@@ -2130,6 +4586,46 @@ func (q *Queries) UpdateUserAccountStatus(ctx context.Context, arg UpdateUserAcc
 	return err
 }
 
+const updateUserAvatar = `-- name: UpdateUserAvatar :execrows
+UPDATE users
+SET avatar_url = $2, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateUserAvatarParams struct {
+	ID        pgtype.UUID
+	AvatarUrl pgtype.Text
+}
+
+func (q *Queries) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserAvatar, arg.ID, arg.AvatarUrl)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :execrows
+UPDATE users
+SET email = $2, email_verified = true, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateUserEmailParams struct {
+	ID    pgtype.UUID
+	Email string
+}
+
+// Applies a confirmed email change. The user proved they own the address by
+// following the confirmation link, so it counts as verified.
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUserLastLogin = `-- name: UpdateUserLastLogin :exec
 UPDATE users
 SET last_login_at = now(), updated_at = now()
@@ -2161,10 +4657,9 @@ const updateUserProfile = `-- name: UpdateUserProfile :exec
 UPDATE users
 SET 
   name = COALESCE($2, name),
-  email = COALESCE($3, email),
-  username = COALESCE($4, username),
-  avatar_url = COALESCE($5, avatar_url),
-  bio = COALESCE($6, bio),
+  username = COALESCE($3, username),
+  avatar_url = COALESCE($4, avatar_url),
+  bio = COALESCE($5, bio),
   updated_at = now()
 WHERE id = $1
 `
@@ -2172,7 +4667,6 @@ WHERE id = $1
 type UpdateUserProfileParams struct {
 	ID        pgtype.UUID
 	Name      pgtype.Text
-	Email     string
 	Username  pgtype.Text
 	AvatarUrl pgtype.Text
 	Bio       pgtype.Text
@@ -2182,7 +4676,6 @@ func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfilePa
 	_, err := q.db.Exec(ctx, updateUserProfile,
 		arg.ID,
 		arg.Name,
-		arg.Email,
 		arg.Username,
 		arg.AvatarUrl,
 		arg.Bio,
@@ -2190,6 +4683,50 @@ func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfilePa
 	return err
 }
 
+const updateWebhook = `-- name: UpdateWebhook :one
+UPDATE webhooks
+SET
+  url = COALESCE($1, url),
+  secret = COALESCE($2, secret),
+  events = COALESCE($3::text[], events),
+  type = COALESCE($4, type),
+  updated_at = now()
+WHERE id = $5
+RETURNING id, project_id, url, secret, events, created_by, created_at, updated_at, type
+`
+
+type UpdateWebhookParams struct {
+	Url    pgtype.Text
+	Secret pgtype.Text
+	Events []string
+	Type   pgtype.Text
+	ID     pgtype.UUID
+}
+
+// Replaces whichever of the URL, secret, events and type aren't NULL
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRow(ctx, updateWebhook,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+		arg.Type,
+		arg.ID,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+	)
+	return i, err
+}
+
 const verifyUserEmail = `-- name: VerifyUserEmail :exec
 UPDATE users
 SET email_verified = true, updated_at = now()
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ReplicaRouter is a DBTX that sends read-only queries to a replica and
+// everything else, including transactions, to the primary. Replicas lag
+// behind the primary, so a read straight after a write may not see it:
+// reads made with a context from PinPrimary, or from TrackWrites once a write
+// has been made with it, go to the primary instead.
+type ReplicaRouter struct {
+	primary DBTX
+	replica DBTX
+}
+
+// NewReplicaRouter routes reads to replica. With a nil replica every query
+// goes to the primary.
+func NewReplicaRouter(primary, replica DBTX) *ReplicaRouter {
+	if replica == nil {
+		replica = primary
+	}
+	return &ReplicaRouter{primary: primary, replica: replica}
+}
+
+// Exec always runs on the primary
+func (r *ReplicaRouter) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	pinPrimary(ctx)
+	return r.primary.Exec(ctx, sql, args...)
+}
+
+// Query runs SELECTs on the replica and other statements on the primary
+func (r *ReplicaRouter) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return r.route(ctx, sql).Query(ctx, sql, args...)
+}
+
+// QueryRow runs SELECTs on the replica and other statements, such as
+// INSERT ... RETURNING, on the primary
+func (r *ReplicaRouter) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return r.route(ctx, sql).QueryRow(ctx, sql, args...)
+}
+
+// Begin starts a transaction on the primary, so WithTx keeps working
+func (r *ReplicaRouter) Begin(ctx context.Context) (pgx.Tx, error) {
+	db, ok := r.primary.(TxBeginner)
+	if !ok {
+		return nil, ErrTxUnsupported
+	}
+	pinPrimary(ctx)
+	return db.Begin(ctx)
+}
+
+func (r *ReplicaRouter) route(ctx context.Context, sql string) DBTX {
+	if !isReadOnly(sql) {
+		pinPrimary(ctx)
+		return r.primary
+	}
+	if pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok && pin.Load() {
+		return r.primary
+	}
+	return r.replica
+}
+
+// primaryPin is set once reads made with a context should go to the primary
+type primaryPin struct{ atomic.Bool }
+
+type primaryPinKey struct{}
+
+// TrackWrites returns a context whose reads go to the primary once a write
+// has been made with it, so that, e.g., a handler fetching the row it just
+// updated reads its own write rather than a lagging replica
+func TrackWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, primaryPinKey{}, &primaryPin{})
+}
+
+// PinPrimary returns a context whose reads all go to the primary, e.g. for
+// requests that change data and check it first
+func PinPrimary(ctx context.Context) context.Context {
+	ctx = TrackWrites(ctx)
+	pinPrimary(ctx)
+	return ctx
+}
+
+// pinPrimary sends ctx's later reads to the primary if it tracks writes
+func pinPrimary(ctx context.Context) {
+	if pin, ok := ctx.Value(primaryPinKey{}).(*primaryPin); ok {
+		pin.Store(true)
+	}
+}
+
+// isReadOnly reports whether sql is a plain SELECT. Leading comments, such as
+// sqlc's "-- name:" line, are skipped. CTEs and locking reads go to the
+// primary since they may write or need the latest rows.
+func isReadOnly(sql string) bool {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if !strings.EqualFold(fields[0], "SELECT") {
+			return false
+		}
+		upper := strings.ToUpper(sql)
+		return !strings.Contains(upper, "FOR UPDATE") && !strings.Contains(upper, "FOR SHARE")
+	}
+	return false
+}
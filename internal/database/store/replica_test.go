@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestReplicaRouter(t *testing.T) {
+	ctx := context.Background()
+	id := pgtype.UUID{Valid: true}
+
+	primary, replica := &storetest.DB{}, &storetest.DB{}
+	q := New(NewReplicaRouter(primary, replica))
+
+	// Reads
+	q.GetIssueByID(ctx, id)
+	q.GetProjectIssues(ctx, id)
+	// Writes, including one that returns rows
+	q.CreateProject(ctx, CreateProjectParams{Name: "Tickit"})
+	q.UpdateIssueStatus(ctx, UpdateIssueStatusParams{ID: id})
+	q.DeleteIssue(ctx, id)
+
+	if got, want := strings.Join(replica.Names(), ","), "GetIssueByID,GetProjectIssues"; got != want {
+		t.Errorf("Replica ran %s, want %s", got, want)
+	}
+	if got, want := strings.Join(primary.Names(), ","), "CreateProject,UpdateIssueStatus,DeleteIssue"; got != want {
+		t.Errorf("Primary ran %s, want %s", got, want)
+	}
+
+	t.Run("Reads after a write use the primary", func(t *testing.T) {
+		primary, replica := &storetest.DB{}, &storetest.DB{}
+		q := New(NewReplicaRouter(primary, replica))
+		ctx := TrackWrites(ctx)
+
+		q.GetIssueByID(ctx, id)
+		q.UpdateIssueStatus(ctx, UpdateIssueStatusParams{ID: id})
+		q.GetIssueByID(ctx, id)
+		q.GetProjectIssues(ctx, id)
+		// Other requests' reads are unaffected
+		q.GetIssueByID(context.Background(), id)
+
+		if got, want := strings.Join(replica.Names(), ","), "GetIssueByID,GetIssueByID"; got != want {
+			t.Errorf("Replica ran %s, want %s", got, want)
+		}
+		if got, want := strings.Join(primary.Names(), ","), "UpdateIssueStatus,GetIssueByID,GetProjectIssues"; got != want {
+			t.Errorf("Primary ran %s, want %s", got, want)
+		}
+	})
+
+	t.Run("Pinned reads use the primary", func(t *testing.T) {
+		primary, replica := &storetest.DB{}, &storetest.DB{}
+		q := New(NewReplicaRouter(primary, replica))
+		q.GetIssueByID(PinPrimary(ctx), id)
+		if strings.Join(replica.Names(), ",") != "" || strings.Join(primary.Names(), ",") != "GetIssueByID" {
+			t.Errorf("Replica ran %q and primary %q, want only the primary", strings.Join(replica.Names(), ","), strings.Join(primary.Names(), ","))
+		}
+	})
+
+	t.Run("Without a replica everything uses the primary", func(t *testing.T) {
+		primary := &storetest.DB{}
+		q := New(NewReplicaRouter(primary, nil))
+		q.GetIssueByID(ctx, id)
+		q.DeleteIssue(ctx, id)
+		if got, want := strings.Join(primary.Names(), ","), "GetIssueByID,DeleteIssue"; got != want {
+			t.Errorf("Primary ran %s, want %s", got, want)
+		}
+	})
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"-- name: GetUser :one\nSELECT * FROM users WHERE id = $1", true},
+		{"  select 1", true},
+		{"SELECT * FROM issues WHERE id = $1 FOR UPDATE", false},
+		{"INSERT INTO users (email) VALUES ($1) RETURNING id", false},
+		{"-- name: UpdateUser :exec\nUPDATE users SET name = $2", false},
+		{"WITH moved AS (DELETE FROM issues RETURNING *) SELECT * FROM moved", false},
+		{"-- only a comment", false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnly(tt.sql); got != tt.want {
+			t.Errorf("isReadOnly(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy controls how operations failing with transient errors are
+// retried. The delay doubles after each failed attempt.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// DefaultRetryPolicy tries an operation three times, waiting 50ms then 100ms
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond}
+
+// transientCodes are Postgres error codes after which running the same
+// statement again can succeed
+var transientCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+	"57P01": true, // admin_shutdown, e.g. a failover
+}
+
+// IsTransient reports whether err is worth retrying: a serialization failure,
+// deadlock or dropped connection rather than a problem with the query itself
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return transientCodes[pgErr.Code]
+	}
+	return pgconn.SafeToRetry(err) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Retry calls fn until it succeeds, fails with an error that isn't transient,
+// or the policy runs out of attempts. Waiting between attempts stops early if
+// ctx is done. fn must be safe to run more than once, e.g. a read or a whole
+// transaction.
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	delay := policy.BaseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !IsTransient(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+type retryWritesKey struct{}
+
+// AllowRetry marks the statements run with ctx as idempotent, so RetryDB
+// retries writes as well as reads
+func AllowRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryWritesKey{}, true)
+}
+
+// RetryDB is a DBTX that retries statements failing with transient errors.
+// Read-only queries are always retried; writes only when the context comes
+// from AllowRetry, since running a non-idempotent write twice is worse than
+// failing. Transactions are not retried statement by statement; wrap the
+// whole of WithTx in Retry instead.
+type RetryDB struct {
+	db     DBTX
+	policy RetryPolicy
+}
+
+// NewRetryDB wraps db with the given retry policy
+func NewRetryDB(db DBTX, policy RetryPolicy) *RetryDB {
+	return &RetryDB{db: db, policy: policy}
+}
+
+func (r *RetryDB) retryable(ctx context.Context, sql string) bool {
+	allowed, _ := ctx.Value(retryWritesKey{}).(bool)
+	return allowed || isReadOnly(sql)
+}
+
+// Exec runs a statement, retrying it if the context allows
+func (r *RetryDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if !r.retryable(ctx, sql) {
+		return r.db.Exec(ctx, sql, args...)
+	}
+	var tag pgconn.CommandTag
+	err := Retry(ctx, r.policy, func() error {
+		var err error
+		tag, err = r.db.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query runs a query, retrying failures reported before any rows are read
+func (r *RetryDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if !r.retryable(ctx, sql) {
+		return r.db.Query(ctx, sql, args...)
+	}
+	var rows pgx.Rows
+	err := Retry(ctx, r.policy, func() error {
+		var err error
+		rows, err = r.db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs a single-row query. The query is retried when Scan fails.
+func (r *RetryDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !r.retryable(ctx, sql) {
+		return r.db.QueryRow(ctx, sql, args...)
+	}
+	return &retryRow{ctx: ctx, r: r, sql: sql, args: args}
+}
+
+// Begin starts a transaction on the wrapped connection
+func (r *RetryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	db, ok := r.db.(TxBeginner)
+	if !ok {
+		return nil, ErrTxUnsupported
+	}
+	return db.Begin(ctx)
+}
+
+// retryRow defers a QueryRow until Scan, when errors surface, so the query
+// can be run again
+type retryRow struct {
+	ctx  context.Context
+	r    *RetryDB
+	sql  string
+	args []interface{}
+}
+
+func (row *retryRow) Scan(dest ...any) error {
+	return Retry(row.ctx, row.r.policy, func() error {
+		return row.r.db.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	})
+}
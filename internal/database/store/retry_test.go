@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// flakyDB fails its first n statements with err, where n is failures, then succeeds
+type flakyDB struct {
+	storetest.DB
+	failures int
+	err      error
+}
+
+func (db *flakyDB) fail() error {
+	if db.failures > 0 {
+		db.failures--
+		return db.err
+	}
+	return nil
+}
+
+func (db *flakyDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, _ := db.DB.Exec(ctx, sql, args...)
+	return tag, db.fail()
+}
+
+func (db *flakyDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.DB.QueryRow(ctx, sql, args...)
+	if err := db.fail(); err != nil {
+		return storetest.ErrRow(err)
+	}
+	return storetest.Row([]any{})
+}
+
+func TestRetryDB(t *testing.T) {
+	ctx := context.Background()
+	id := pgtype.UUID{Valid: true}
+	serialization := &pgconn.PgError{Code: "40001"}
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	t.Run("Read is retried after a serialization failure", func(t *testing.T) {
+		db := &flakyDB{failures: 1, err: serialization}
+		q := New(NewRetryDB(db, policy))
+		if _, err := q.GetProjectAccess(ctx, id); err != nil {
+			t.Fatalf("Expected the retry to succeed, got %v", err)
+		}
+		if len(db.Calls) != 2 {
+			t.Errorf("Expected 2 attempts, got %d", len(db.Calls))
+		}
+	})
+
+	t.Run("Write is not retried by default", func(t *testing.T) {
+		db := &flakyDB{failures: 1, err: serialization}
+		q := New(NewRetryDB(db, policy))
+		if err := q.DeleteIssue(ctx, id); !errors.Is(err, serialization) {
+			t.Fatalf("Expected the serialization failure, got %v", err)
+		}
+		if len(db.Calls) != 1 {
+			t.Errorf("Expected 1 attempt, got %d", len(db.Calls))
+		}
+	})
+
+	t.Run("Write is retried when allowed", func(t *testing.T) {
+		db := &flakyDB{failures: 1, err: serialization}
+		q := New(NewRetryDB(db, policy))
+		if err := q.DeleteIssue(AllowRetry(ctx), id); err != nil {
+			t.Fatalf("Expected the retry to succeed, got %v", err)
+		}
+		if len(db.Calls) != 2 {
+			t.Errorf("Expected 2 attempts, got %d", len(db.Calls))
+		}
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		db := &flakyDB{failures: 5, err: serialization}
+		q := New(NewRetryDB(db, policy))
+		if _, err := q.GetProjectAccess(ctx, id); !errors.Is(err, serialization) {
+			t.Fatalf("Expected the serialization failure, got %v", err)
+		}
+		if len(db.Calls) != policy.MaxAttempts {
+			t.Errorf("Expected %d attempts, got %d", policy.MaxAttempts, len(db.Calls))
+		}
+	})
+
+	t.Run("Other errors are not retried", func(t *testing.T) {
+		db := &flakyDB{failures: 1, err: &pgconn.PgError{Code: "23505"}}
+		q := New(NewRetryDB(db, policy))
+		if _, err := q.GetProjectAccess(ctx, id); err == nil {
+			t.Fatal("Expected the unique violation")
+		}
+		if len(db.Calls) != 1 {
+			t.Errorf("Expected 1 attempt, got %d", len(db.Calls))
+		}
+	})
+
+	t.Run("Stops waiting when the context is done", func(t *testing.T) {
+		db := &flakyDB{failures: 5, err: serialization}
+		q := New(NewRetryDB(db, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}))
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := q.GetProjectAccess(ctx, id); !errors.Is(err, serialization) {
+			t.Fatalf("Expected the serialization failure, got %v", err)
+		}
+		if len(db.Calls) != 1 {
+			t.Errorf("Expected 1 attempt, got %d", len(db.Calls))
+		}
+	})
+}
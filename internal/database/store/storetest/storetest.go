@@ -0,0 +1,239 @@
+// Package storetest provides a fake store.DBTX for testing code built on the
+// sqlc queries without a database.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DB is a store.DBTX answering sqlc queries from canned values. Rows are
+// keyed by query name followed by the query's valid UUID arguments, e.g.
+// "CheckTeamMembership:<team>:<user>"; a key of just the name matches any
+// arguments. A missing row scans as pgx.ErrNoRows and a missing list is
+// empty. Every call is recorded; calls made in a transaction are only
+// recorded once it commits.
+type DB struct {
+	Rows      map[string][]any   // :one queries
+	Lists     map[string][][]any // :many queries
+	Affected  map[string]int64   // :exec queries, default one row
+	Errs      map[string]error   // Queries that fail, by name
+	Calls     []Call
+	Commits   int
+	Rollbacks int
+}
+
+// Call is a query run against a DB
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+// QueryName returns the sqlc name of a query, e.g. GetIssueByID
+func QueryName(sql string) string {
+	return strings.Fields(sql)[2] // "-- name: GetIssueByID :one"
+}
+
+// Record records a call to sql and returns its name and row key, for fakes
+// that wrap DB to answer some queries themselves
+func (db *DB) Record(sql string, args []interface{}) (name, key string) {
+	name = QueryName(sql)
+	db.Calls = append(db.Calls, Call{name, args})
+	key = name
+	for _, arg := range args {
+		if id, ok := arg.(pgtype.UUID); ok && id.Valid {
+			key += ":" + id.String()
+		}
+	}
+	return name, key
+}
+
+func (db *DB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	name, key := db.Record(sql, args)
+	if err := db.Errs[name]; err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	n, ok := db.Affected[key]
+	if !ok {
+		n, ok = db.Affected[name]
+	}
+	if !ok {
+		n = 1
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", n)), nil
+}
+
+func (db *DB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name, key := db.Record(sql, args)
+	if err := db.Errs[name]; err != nil {
+		return nil, err
+	}
+	rows, ok := db.Lists[key]
+	if !ok {
+		rows = db.Lists[name]
+	}
+	return Rows(rows), nil
+}
+
+func (db *DB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	name, key := db.Record(sql, args)
+	if err := db.Errs[name]; err != nil {
+		return ErrRow(err)
+	}
+	row, ok := db.Rows[key]
+	if !ok {
+		row = db.Rows[name]
+	}
+	return Row(row)
+}
+
+// Begin starts a transaction answering from the same values
+func (db *DB) Begin(context.Context) (pgx.Tx, error) {
+	return &tx{
+		parent: db,
+		inner:  &DB{Rows: db.Rows, Lists: db.Lists, Affected: db.Affected, Errs: db.Errs},
+	}, nil
+}
+
+// tx records its queries separately and hands them to the parent on commit
+type tx struct {
+	pgx.Tx
+	inner  *DB
+	parent *DB
+	done   bool
+}
+
+func (tx *tx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.inner.Exec(ctx, sql, args...)
+}
+
+func (tx *tx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tx.inner.Query(ctx, sql, args...)
+}
+
+func (tx *tx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tx.inner.QueryRow(ctx, sql, args...)
+}
+
+func (tx *tx) Commit(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.parent.Calls = append(tx.parent.Calls, tx.inner.Calls...)
+	tx.parent.Commits++
+	return nil
+}
+
+func (tx *tx) Rollback(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.parent.Rollbacks++
+	return nil
+}
+
+// Called returns the arguments of the first call to the named query
+func (db *DB) Called(name string) ([]interface{}, bool) {
+	for _, call := range db.Calls {
+		if call.Name == name {
+			return call.Args, true
+		}
+	}
+	return nil, false
+}
+
+// Args returns the arguments of each call to the named query
+func (db *DB) Args(name string) [][]interface{} {
+	var calls [][]interface{}
+	for _, call := range db.Calls {
+		if call.Name == name {
+			calls = append(calls, call.Args)
+		}
+	}
+	return calls
+}
+
+// Count returns how many times the named query ran
+func (db *DB) Count(name string) int {
+	n := 0
+	for _, call := range db.Calls {
+		if call.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// Names lists the queries run, in order
+func (db *DB) Names() []string {
+	names := make([]string, len(db.Calls))
+	for i, call := range db.Calls {
+		names[i] = call.Name
+	}
+	return names
+}
+
+// Rows returns pgx.Rows over canned values. Values are assigned to the
+// leading scan destinations; the rest keep their zero value.
+func Rows(rows [][]any) pgx.Rows {
+	return &fakeRows{rows: rows}
+}
+
+// Row returns a pgx.Row scanning values like Rows; nil scans as
+// pgx.ErrNoRows
+func Row(values []any) pgx.Row {
+	return &fakeRows{rows: [][]any{values}, pos: 1}
+}
+
+// ErrRow returns a pgx.Row whose Scan fails with err
+func ErrRow(err error) pgx.Row {
+	return &fakeRows{err: err}
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	pos  int
+	err  error
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	values := r.rows[r.pos-1]
+	if values == nil {
+		return pgx.ErrNoRows
+	}
+	for i, v := range values {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func (r *fakeRows) Close()     {}
+func (r *fakeRows) Err() error { return nil }
+
+// MustUUID parses s as a UUID, failing the test if it isn't one
+func MustUUID(t testing.TB, s string) pgtype.UUID {
+	t.Helper()
+	var id pgtype.UUID
+	if err := id.Scan(s); err != nil {
+		t.Fatalf("invalid UUID %q: %v", s, err)
+	}
+	return id
+}
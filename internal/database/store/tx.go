@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTxUnsupported is returned by WithTx when the queries run on a connection
+// that cannot begin transactions.
+var ErrTxUnsupported = errors.New("store: connection does not support transactions")
+
+// TxBeginner is implemented by connections that can start a transaction,
+// such as *pgxpool.Pool, *pgx.Conn and pgx.Tx (as a savepoint).
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// WithTx runs fn in a transaction on q's connection. fn receives queries bound
+// to the transaction; the transaction is committed if fn returns nil and
+// rolled back otherwise. Called on queries that are already in a transaction,
+// fn runs in a nested savepoint.
+func WithTx(ctx context.Context, q *Queries, fn func(*Queries) error) error {
+	db, ok := q.db.(TxBeginner)
+	if !ok {
+		return ErrTxUnsupported
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// Rollback after a successful commit is a no-op
+	defer tx.Rollback(ctx)
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
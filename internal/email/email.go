@@ -1,45 +1,115 @@
 package email
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
-// EmailService handles sending emails
+// ErrTemplateNotFound is returned when a message names a template that does not exist
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// templateFS holds the message templates. Each template has an HTML version
+// (name.html) and a plain-text fallback (name.txt).
+//
+//go:embed templates/*.html templates/*.txt
+var templateFS embed.FS
+
+// EmailService renders and sends emails through an EmailSender
 type EmailService struct {
-	fromEmail string
-	fromName  string
-	enabled   bool
+	sender        EmailSender
+	fromEmail     string
+	fromName      string
+	htmlTemplates *htmltemplate.Template
+	textTemplates *texttemplate.Template
+	maxAttempts   int           // Delivery attempts before a message is dead-lettered
+	retryDelay    time.Duration // Delay before the first retry, doubled for each one after
+	sleep         func(time.Duration)
+	deadLetters   *redis.Client // Undeliverable messages are kept here when set
 }
 
-// NewEmailService creates a new email service
-func NewEmailService(fromEmail, fromName string, enabled bool) *EmailService {
-	return &EmailService{
-		fromEmail: fromEmail,
-		fromName:  fromName,
-		enabled:   enabled,
+// NewEmailService creates a new email service and parses the embedded templates
+func NewEmailService(sender EmailSender, fromEmail, fromName string) (*EmailService, error) {
+	// A typo in a template's data key should fail loudly rather than render "<no value>"
+	htmlTemplates, err := htmltemplate.New("").Option("missingkey=error").ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML email templates: %w", err)
 	}
+	textTemplates, err := texttemplate.New("").Option("missingkey=error").ParseFS(templateFS, "templates/*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text email templates: %w", err)
+	}
+
+	return &EmailService{
+		sender:        sender,
+		fromEmail:     fromEmail,
+		fromName:      fromName,
+		htmlTemplates: htmlTemplates,
+		textTemplates: textTemplates,
+		maxAttempts:   defaultMaxAttempts,
+		retryDelay:    defaultRetryDelay,
+		sleep:         time.Sleep,
+	}, nil
 }
 
 // Config for an email message
 type EmailConfig struct {
+	From     string // Set by SendEmail
 	To       string
 	Subject  string
 	Template string
 	Data     map[string]interface{}
+	HTMLBody string // Rendered from Template by SendEmail
+	TextBody string // Rendered from Template by SendEmail
 }
 
-// SendEmail sends an email
+// SendEmail renders the message template and hands the email to the sender
 func (s *EmailService) SendEmail(config EmailConfig) error {
-	if !s.enabled {
-		log.Printf("[MOCK EMAIL] To: %s, Subject: %s, Template: %s",
-			config.To, config.Subject, config.Template)
-		log.Printf("[MOCK EMAIL] Data: %v", config.Data)
-		return nil
+	config.From = (&mail.Address{Name: s.fromName, Address: s.fromEmail}).String()
+
+	if err := s.render(&config); err != nil {
+		return err
 	}
 
-	// In production, connect to a real email service like Sendgrid, Mailgun, etc.
-	// For now, we'll just log the email
-	log.Printf("Would send email to %s with subject '%s'", config.To, config.Subject)
+	attempts, err := s.deliver(config)
+	if err != nil {
+		s.deadLetter(context.Background(), DeadLetter{
+			Email:    config,
+			Error:    err.Error(),
+			Attempts: attempts,
+			FailedAt: time.Now(),
+		})
+		return fmt.Errorf("failed to send %s email after %d attempts: %w", config.Template, attempts, err)
+	}
+	return nil
+}
+
+// render fills in the HTML and text bodies of config from its template
+func (s *EmailService) render(config *EmailConfig) error {
+	htmlTemplate := s.htmlTemplates.Lookup(config.Template + ".html")
+	textTemplate := s.textTemplates.Lookup(config.Template + ".txt")
+	if htmlTemplate == nil || textTemplate == nil {
+		return fmt.Errorf("%w: %q", ErrTemplateNotFound, config.Template)
+	}
+
+	var html, text bytes.Buffer
+	if err := htmlTemplate.Execute(&html, config.Data); err != nil {
+		return fmt.Errorf("failed to render %s email: %w", config.Template, err)
+	}
+	if err := textTemplate.Execute(&text, config.Data); err != nil {
+		return fmt.Errorf("failed to render %s email: %w", config.Template, err)
+	}
+
+	config.HTMLBody = html.String()
+	config.TextBody = text.String()
 	return nil
 }
 
@@ -78,3 +148,28 @@ func (s *EmailService) SendAccountVerificationEmail(email, verificationLink stri
 		},
 	})
 }
+
+// SendEmailChangeEmail asks the owner of a new address to confirm an email change
+func (s *EmailService) SendEmailChangeEmail(email, confirmLink string) error {
+	return s.SendEmail(EmailConfig{
+		To:       email,
+		Subject:  "Confirm Your New Email Address",
+		Template: "email_change",
+		Data: map[string]interface{}{
+			"ConfirmLink": confirmLink,
+		},
+	})
+}
+
+// SendNotificationEmail sends an activity notification, such as a mention
+func (s *EmailService) SendNotificationEmail(email, subject, message string) error {
+	return s.SendEmail(EmailConfig{
+		To:       email,
+		Subject:  subject,
+		Template: "notification",
+		Data: map[string]interface{}{
+			"Subject": subject,
+			"Message": message,
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingSender keeps every message it is asked to send
+type recordingSender struct {
+	sent []EmailConfig
+}
+
+func (r *recordingSender) Send(config EmailConfig) error {
+	r.sent = append(r.sent, config)
+	return nil
+}
+
+func newTestService(t *testing.T, sender EmailSender) *EmailService {
+	t.Helper()
+	svc, err := NewEmailService(sender, "noreply@tickit.test", "Tickit")
+	if err != nil {
+		t.Fatalf("NewEmailService failed: %v", err)
+	}
+	return svc
+}
+
+func TestSendPasswordResetEmail(t *testing.T) {
+	sender := &recordingSender{}
+	svc := newTestService(t, sender)
+
+	const link = "https://tickit.test/reset-password?token=abc&next=/projects"
+	if err := svc.SendPasswordResetEmail("ada@example.com", link); err != nil {
+		t.Fatalf("SendPasswordResetEmail failed: %v", err)
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(sender.sent))
+	}
+	msg := sender.sent[0]
+	if want := `href="https://tickit.test/reset-password?token=abc&amp;next=/projects"`; !strings.Contains(msg.HTMLBody, want) {
+		t.Errorf("HTML body missing %s:\n%s", want, msg.HTMLBody)
+	}
+	if !strings.Contains(msg.TextBody, link) {
+		t.Errorf("Text body missing %s:\n%s", link, msg.TextBody)
+	}
+	if want := `"Tickit" <noreply@tickit.test>`; msg.From != want {
+		t.Errorf("From = %q, want %q", msg.From, want)
+	}
+}
+
+func TestSendWelcomeEmailEscapesData(t *testing.T) {
+	sender := &recordingSender{}
+	svc := newTestService(t, sender)
+
+	if err := svc.SendWelcomeEmail("ada@example.com", "Ada <script>"); err != nil {
+		t.Fatalf("SendWelcomeEmail failed: %v", err)
+	}
+	msg := sender.sent[0]
+	if !strings.Contains(msg.HTMLBody, "Ada &lt;script&gt;") {
+		t.Errorf("HTML body did not escape name:\n%s", msg.HTMLBody)
+	}
+	if !strings.Contains(msg.TextBody, "Ada <script>") {
+		t.Errorf("Text body missing name:\n%s", msg.TextBody)
+	}
+}
+
+func TestSendEmailUnknownTemplate(t *testing.T) {
+	sender := &recordingSender{}
+	svc := newTestService(t, sender)
+
+	err := svc.SendEmail(EmailConfig{To: "ada@example.com", Subject: "Hi", Template: "newsletter"})
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Error("Nothing should be sent for an unknown template")
+	}
+}
+
+func TestSendEmailMissingData(t *testing.T) {
+	svc := newTestService(t, &recordingSender{})
+
+	err := svc.SendEmail(EmailConfig{To: "ada@example.com", Template: "password_reset", Data: map[string]interface{}{}})
+	if err == nil {
+		t.Error("Expected error when template data is missing")
+	}
+}
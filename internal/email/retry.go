@@ -0,0 +1,148 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Retry defaults, overridable with WithRetry
+const (
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = time.Second
+)
+
+// deadLetterKey is the Redis list holding undeliverable messages, oldest first
+const deadLetterKey = "email:dead_letters"
+
+// DeadLetter is a message that could not be delivered after every retry
+type DeadLetter struct {
+	Email    EmailConfig `json:"email"`
+	Error    string      `json:"error"`
+	Attempts int         `json:"attempts"`
+	FailedAt time.Time   `json:"failed_at"`
+}
+
+// WithRetry sets how many times a message is attempted and the delay before
+// the first retry. The delay doubles after each failed attempt.
+func (s *EmailService) WithRetry(maxAttempts int, retryDelay time.Duration) *EmailService {
+	if maxAttempts > 0 {
+		s.maxAttempts = maxAttempts
+	}
+	if retryDelay >= 0 {
+		s.retryDelay = retryDelay
+	}
+	return s
+}
+
+// WithDeadLetters keeps messages that still fail after retrying in Redis so
+// they can be inspected with FailedEmails and resent with ReplayFailedEmails
+func (s *EmailService) WithDeadLetters(cache *redis.Client) *EmailService {
+	s.deadLetters = cache
+	return s
+}
+
+// deliver sends a rendered message, retrying with exponential backoff.
+// It returns the number of attempts made.
+func (s *EmailService) deliver(config EmailConfig) (int, error) {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		if err = s.sender.Send(config); err == nil {
+			return attempt, nil
+		}
+		if attempt < s.maxAttempts {
+			log.Printf("Email to %s failed (attempt %d/%d), retrying in %s: %v",
+				config.To, attempt, s.maxAttempts, delay, err)
+			s.sleep(delay)
+			delay *= 2
+		}
+	}
+	return s.maxAttempts, err
+}
+
+// deadLetter stores an undeliverable message, or logs it when no store is set
+func (s *EmailService) deadLetter(ctx context.Context, letter DeadLetter) {
+	if s.deadLetters == nil {
+		log.Printf("Dropping undeliverable email to %s (%s): %s", letter.Email.To, letter.Email.Template, letter.Error)
+		return
+	}
+
+	data, err := json.Marshal(letter)
+	if err == nil {
+		err = s.deadLetters.RPush(ctx, deadLetterKey, data).Err()
+	}
+	if err != nil {
+		log.Printf("Failed to store undeliverable email to %s: %v", letter.Email.To, err)
+	}
+}
+
+// FailedEmails returns the messages waiting in the dead-letter store
+func (s *EmailService) FailedEmails(ctx context.Context) ([]DeadLetter, error) {
+	if s.deadLetters == nil {
+		return nil, nil
+	}
+
+	items, err := s.deadLetters.LRange(ctx, deadLetterKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(items))
+	for _, item := range items {
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(item), &letter); err != nil {
+			log.Printf("Skipping malformed dead letter: %v", err)
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// ReplayFailedEmails tries to resend every dead-lettered message. Messages
+// that fail again go back on the list with their attempt count updated.
+// It returns how many messages were delivered.
+func (s *EmailService) ReplayFailedEmails(ctx context.Context) (int, error) {
+	if s.deadLetters == nil {
+		return 0, nil
+	}
+
+	// Only replay what is queued now, not messages re-queued below
+	pending, err := s.deadLetters.LLen(ctx, deadLetterKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	delivered := 0
+	for i := int64(0); i < pending; i++ {
+		item, err := s.deadLetters.LPop(ctx, deadLetterKey).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return delivered, fmt.Errorf("failed to read dead letter: %w", err)
+		}
+
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(item), &letter); err != nil {
+			log.Printf("Discarding malformed dead letter: %v", err)
+			continue
+		}
+
+		attempts, err := s.deliver(letter.Email)
+		if err != nil {
+			letter.Error = err.Error()
+			letter.Attempts += attempts
+			letter.FailedAt = time.Now()
+			s.deadLetter(ctx, letter)
+			continue
+		}
+		delivered++
+	}
+	return delivered, nil
+}
@@ -0,0 +1,109 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// flakySender fails the first failures calls, then succeeds
+type flakySender struct {
+	failures int
+	calls    int
+}
+
+func (f *flakySender) Send(EmailConfig) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func newRetryService(t *testing.T, sender EmailSender) (*EmailService, *[]time.Duration) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	svc := newTestService(t, sender).
+		WithRetry(3, 100*time.Millisecond).
+		WithDeadLetters(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	var delays []time.Duration
+	svc.sleep = func(d time.Duration) { delays = append(delays, d) }
+	return svc, &delays
+}
+
+func TestSendEmailRetriesWithBackoff(t *testing.T) {
+	sender := &flakySender{failures: 2}
+	svc, delays := newRetryService(t, sender)
+
+	if err := svc.SendWelcomeEmail("ada@example.com", "Ada"); err != nil {
+		t.Fatalf("SendWelcomeEmail failed: %v", err)
+	}
+
+	if sender.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", sender.calls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(*delays) != len(want) || (*delays)[0] != want[0] || (*delays)[1] != want[1] {
+		t.Errorf("Backoff delays = %v, want %v", *delays, want)
+	}
+
+	letters, err := svc.FailedEmails(context.Background())
+	if err != nil {
+		t.Fatalf("FailedEmails failed: %v", err)
+	}
+	if len(letters) != 0 {
+		t.Errorf("Expected no dead letters, got %d", len(letters))
+	}
+}
+
+func TestSendEmailDeadLettersAfterLastAttempt(t *testing.T) {
+	sender := &flakySender{failures: 100}
+	svc, _ := newRetryService(t, sender)
+	ctx := context.Background()
+
+	if err := svc.SendPasswordResetEmail("ada@example.com", "https://tickit.test/reset"); err == nil {
+		t.Fatal("Expected error when every attempt fails")
+	}
+	if sender.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", sender.calls)
+	}
+
+	letters, err := svc.FailedEmails(ctx)
+	if err != nil {
+		t.Fatalf("FailedEmails failed: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Email.To != "ada@example.com" || letter.Email.Template != "password_reset" || letter.Attempts != 3 {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+	if letter.Email.HTMLBody == "" || letter.Error != "connection reset" {
+		t.Errorf("Dead letter should keep the rendered message and last error, got %+v", letter)
+	}
+
+	// Still failing: the message stays queued with its attempts counted
+	if n, err := svc.ReplayFailedEmails(ctx); err != nil || n != 0 {
+		t.Fatalf("ReplayFailedEmails = %d, %v; want 0, nil", n, err)
+	}
+	letters, _ = svc.FailedEmails(ctx)
+	if len(letters) != 1 || letters[0].Attempts != 6 {
+		t.Fatalf("Expected 1 dead letter with 6 attempts, got %+v", letters)
+	}
+
+	// Provider recovers: replay delivers and empties the list
+	sender.failures = 0
+	if n, err := svc.ReplayFailedEmails(ctx); err != nil || n != 1 {
+		t.Fatalf("ReplayFailedEmails = %d, %v; want 1, nil", n, err)
+	}
+	letters, _ = svc.FailedEmails(ctx)
+	if len(letters) != 0 {
+		t.Errorf("Expected dead letters to be empty, got %d", len(letters))
+	}
+}
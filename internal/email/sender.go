@@ -0,0 +1,20 @@
+package email
+
+import "log"
+
+// EmailSender delivers a rendered message
+type EmailSender interface {
+	Send(config EmailConfig) error
+}
+
+// MockSender logs messages instead of delivering them. It is used in
+// development and whenever no SMTP server is configured.
+type MockSender struct{}
+
+// Send logs the message
+func (MockSender) Send(config EmailConfig) error {
+	log.Printf("[MOCK EMAIL] To: %s, Subject: %s, Template: %s",
+		config.To, config.Subject, config.Template)
+	log.Printf("[MOCK EMAIL] Data: %v", config.Data)
+	return nil
+}
@@ -0,0 +1,138 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig holds the connection settings for an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	UseTLS   bool // Connect over implicit TLS (usually port 465); otherwise STARTTLS is used when offered
+}
+
+// SMTPSender delivers messages through an SMTP server
+type SMTPSender struct {
+	config SMTPConfig
+	dial   func(network, addr string) (net.Conn, error)
+}
+
+// NewSMTPSender creates a sender for the given SMTP server
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return &SMTPSender{config: config, dial: dialer.Dial}
+}
+
+// Send delivers the message to config.To
+func (s *SMTPSender) Send(config EmailConfig) error {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(config.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	conn, err := s.dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+	if s.config.UseTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if !s.config.UseTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(buildMessage(from, to, config)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage formats the headers and body of an email. When both bodies are
+// set the message is multipart/alternative with the plain-text part first, so
+// clients that can't show HTML fall back to it.
+func buildMessage(from, to *mail.Address, config EmailConfig) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", config.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	switch {
+	case config.HTMLBody != "" && config.TextBody != "":
+		mw := multipart.NewWriter(&msg)
+		fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+		writePart(mw, "text/plain; charset=UTF-8", config.TextBody)
+		writePart(mw, "text/html; charset=UTF-8", config.HTMLBody)
+		mw.Close()
+	case config.HTMLBody != "":
+		msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+		msg.WriteString(config.HTMLBody)
+	default:
+		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		msg.WriteString(config.TextBody)
+	}
+	return msg.Bytes()
+}
+
+// writePart adds one body part to a multipart message
+func writePart(mw *multipart.Writer, contentType, body string) {
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return // Writes to a bytes.Buffer don't fail
+	}
+	part.Write([]byte(body))
+}
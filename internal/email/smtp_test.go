@@ -0,0 +1,137 @@
+package email
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one SMTP session and records the commands and
+// message data it receives
+type fakeSMTPServer struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := &fakeSMTPServer{listener: ln, done: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, line)
+
+		switch strings.ToUpper(strings.Fields(line)[0]) {
+		case "EHLO":
+			reply("250-fake.smtp")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 Authenticated")
+		case "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	addr := server.listener.Addr().(*net.TCPAddr)
+
+	sender := NewSMTPSender(SMTPConfig{
+		Host:     "localhost", // PlainAuth only allows unencrypted connections to localhost
+		Port:     addr.Port,
+		Username: "tickit",
+		Password: "secret",
+	})
+	sender.dial = func(network, _ string) (net.Conn, error) {
+		return net.Dial(network, addr.String())
+	}
+
+	err := sender.Send(EmailConfig{
+		From:     `"Tickit" <noreply@tickit.test>`,
+		To:       "ada@example.com",
+		Subject:  "Reset Your Password",
+		HTMLBody: `<a href="https://tickit.test/reset/abc">Reset</a>`,
+		TextBody: "Reset: https://tickit.test/reset/abc",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	<-server.done
+
+	commands := strings.Join(server.commands, "\n")
+	for _, want := range []string{"AUTH PLAIN", "MAIL FROM:<noreply@tickit.test>", "RCPT TO:<ada@example.com>", "QUIT"} {
+		if !strings.Contains(commands, want) {
+			t.Errorf("Expected command %q, got:\n%s", want, commands)
+		}
+	}
+	for _, want := range []string{
+		"Subject: Reset Your Password\r\n",
+		"To: <ada@example.com>\r\n",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+		"Content-Type: text/html; charset=UTF-8\r\n",
+		"Reset: https://tickit.test/reset/abc",
+		`<a href="https://tickit.test/reset/abc">Reset</a>`,
+	} {
+		if !strings.Contains(server.data, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, server.data)
+		}
+	}
+}
+
+func TestSMTPSenderRejectsInvalidRecipient(t *testing.T) {
+	sender := NewSMTPSender(SMTPConfig{Host: "localhost", Port: 25})
+	sender.dial = func(string, string) (net.Conn, error) {
+		t.Fatal("Should not connect with an invalid recipient")
+		return nil, nil
+	}
+	if err := sender.Send(EmailConfig{From: "noreply@tickit.test", To: "not an address"}); err == nil {
+		t.Error("Expected error for invalid recipient")
+	}
+}
@@ -1,8 +1,13 @@
 package env
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,72 +18,149 @@ const (
 	Require  Required = true
 )
 
+var (
+	// ErrNotSet is returned when a required variable is missing
+	ErrNotSet = errors.New("required but not set")
+	// ErrUnsupported is returned for types without a registered parser
+	ErrUnsupported = errors.New("unsupported type")
+)
+
+// Error is a problem reading an environment variable
+type Error struct {
+	Key string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return "environment variable " + e.Key + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 type Env[T any] struct {
 	Key      string
 	Fallback T
 	Required Required
+	Sep      string // Separates the items of a slice; a comma if empty
 }
 
-// Get retrieves an environment variable and converts it to the desired type
-func (e Env[T]) Get() T {
-	value, exists := os.LookupEnv(e.Key)
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[reflect.Type]func(string) (any, error){}
+)
+
+// Register adds a parser for variables of type T, replacing any existing one.
+// Slices of T are supported as well once T is registered.
+func Register[T any](parse func(string) (T, error)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[reflect.TypeFor[T]()] = func(value string) (any, error) {
+		return parse(value)
+	}
+}
+
+func init() {
+	Register(func(s string) (string, error) { return s, nil })
+	Register(strconv.Atoi)
+	Register(func(s string) (int64, error) { return strconv.ParseInt(s, 10, 64) })
+	Register(func(s string) (uint, error) {
+		v, err := strconv.ParseUint(s, 10, 0)
+		return uint(v), err
+	})
+	Register(strconv.ParseBool)
+	Register(func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+	Register(time.ParseDuration)
+}
 
-	if !exists && bool(e.Required) {
-		panic("Required environment variable " + e.Key + " is not set")
+// parserFor finds the parser for t. Types whose underlying type is a string,
+// like `type Level string`, are converted without needing one.
+func parserFor(t reflect.Type) (func(string) (any, error), bool) {
+	parsersMu.RLock()
+	parse, ok := parsers[t]
+	parsersMu.RUnlock()
+	if ok {
+		return parse, true
+	}
+	if t.Kind() == reflect.String {
+		return func(value string) (any, error) {
+			return reflect.ValueOf(value).Convert(t).Interface(), nil
+		}, true
 	}
+	return nil, false
+}
 
+// Lookup retrieves an environment variable and converts it to T. An unset
+// optional variable, or a slice without any items, gives the fallback. A
+// value that can't be converted is an error, returned with the fallback.
+func (e Env[T]) Lookup() (T, error) {
+	value, exists := os.LookupEnv(e.Key)
 	if !exists {
-		return e.Fallback
-	}
-
-	var result T
-	switch any(e.Fallback).(type) {
-	case string:
-		result = any(value).(T)
-	case int:
-		if v, err := strconv.Atoi(value); err == nil {
-			result = any(v).(T)
-		} else {
-			if bool(e.Required) {
-				panic("Failed to convert environment variable " + e.Key + " to int: " + err.Error())
-			}
-			result = e.Fallback
+		if bool(e.Required) {
+			return e.Fallback, &Error{e.Key, ErrNotSet}
 		}
-	case bool:
-		if v, err := strconv.ParseBool(value); err == nil {
-			result = any(v).(T)
-		} else {
-			if bool(e.Required) {
-				panic("Failed to convert environment variable " + e.Key + " to bool: " + err.Error())
-			}
-			result = e.Fallback
+		return e.Fallback, nil
+	}
+
+	t := reflect.TypeFor[T]()
+	if parse, ok := parserFor(t); ok {
+		v, err := parse(value)
+		if err != nil {
+			return e.Fallback, &Error{e.Key, fmt.Errorf("invalid %s: %w", t, err)}
 		}
-	case time.Duration:
-		if v, err := time.ParseDuration(value); err == nil {
-			result = any(v).(T)
-		} else {
-			if bool(e.Required) {
-				panic("Failed to convert environment variable " + e.Key + " to duration: " + err.Error())
-			}
-			result = e.Fallback
+		return v.(T), nil
+	}
+
+	if t.Kind() == reflect.Slice {
+		parse, ok := parserFor(t.Elem())
+		if !ok {
+			return e.Fallback, &Error{e.Key, fmt.Errorf("%w %s", ErrUnsupported, t)}
 		}
-	case float64:
-		if v, err := strconv.ParseFloat(value, 64); err == nil {
-			result = any(v).(T)
-		} else {
-			if bool(e.Required) {
-				panic("Failed to convert environment variable " + e.Key + " to float64: " + err.Error())
-			}
-			result = e.Fallback
+		items := e.split(value)
+		if len(items) == 0 {
+			return e.Fallback, nil
 		}
-	default:
-		if bool(e.Required) {
-			panic("Unsupported type for environment variable " + e.Key)
+
+		result := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			v, err := parse(item)
+			if err != nil {
+				return e.Fallback, &Error{e.Key, fmt.Errorf("invalid %s in list: %w", t.Elem(), err)}
+			}
+			result.Index(i).Set(reflect.ValueOf(v))
 		}
-		result = e.Fallback
+		return result.Interface().(T), nil
 	}
 
-	return result
+	return e.Fallback, &Error{e.Key, fmt.Errorf("%w %s", ErrUnsupported, t)}
+}
+
+// Get retrieves an environment variable and converts it to the desired type.
+// Problems with an optional variable give the fallback; with a required one
+// they panic. Use Lookup to report them instead.
+func (e Env[T]) Get() T {
+	value, err := e.Lookup()
+	if err != nil && bool(e.Required) {
+		panic(err.Error())
+	}
+	return value
+}
+
+// split breaks a slice value into its trimmed, non-empty items
+func (e Env[T]) split(value string) []string {
+	sep := e.Sep
+	if sep == "" {
+		sep = ","
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // String is a helper function to create a string environment variable configuration
@@ -125,3 +207,35 @@ func Float64(key string, fallback float64, required Required) Env[float64] {
 		Required: required,
 	}
 }
+
+// StringSlice is a helper function to create a []string environment variable
+// configuration. Items are split on sep and trimmed, e.g.
+// "https://a.example.com, https://b.example.com".
+func StringSlice(key string, fallback []string, sep string, required Required) Env[[]string] {
+	return Env[[]string]{
+		Key:      key,
+		Fallback: fallback,
+		Required: required,
+		Sep:      sep,
+	}
+}
+
+// IntSlice is a helper function to create an []int environment variable configuration
+func IntSlice(key string, fallback []int, sep string, required Required) Env[[]int] {
+	return Env[[]int]{
+		Key:      key,
+		Fallback: fallback,
+		Required: required,
+		Sep:      sep,
+	}
+}
+
+// DurationSlice is a helper function to create a []time.Duration environment variable configuration
+func DurationSlice(key string, fallback []time.Duration, sep string, required Required) Env[[]time.Duration] {
+	return Env[[]time.Duration]{
+		Key:      key,
+		Fallback: fallback,
+		Required: required,
+		Sep:      sep,
+	}
+}
@@ -0,0 +1,140 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSlices(t *testing.T) {
+	fallback := []string{"http://localhost:3000"}
+
+	tests := []struct {
+		name  string
+		value string
+		sep   string
+		want  []string
+	}{
+		{"Comma separated", "https://a.example.com,https://b.example.com", "", []string{"https://a.example.com", "https://b.example.com"}},
+		{"Whitespace is trimmed", "  https://a.example.com ,\thttps://b.example.com  ", ",", []string{"https://a.example.com", "https://b.example.com"}},
+		{"Empty items are dropped", "a,,b,", ",", []string{"a", "b"}},
+		{"Other separators", "10.0.0.1 | 10.0.0.2", "|", []string{"10.0.0.1", "10.0.0.2"}},
+		{"Empty value", "", ",", fallback},
+		{"Only separators", " , ", ",", fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_ORIGINS", tt.value)
+			if got := StringSlice("ALLOWED_ORIGINS", fallback, tt.sep, Optional).Get(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Unset uses the fallback", func(t *testing.T) {
+		if got := StringSlice("UNSET_ORIGINS", fallback, ",", Optional).Get(); !reflect.DeepEqual(got, fallback) {
+			t.Errorf("Get() = %q, want the fallback", got)
+		}
+	})
+
+	t.Run("Numbers and durations", func(t *testing.T) {
+		t.Setenv("RETRY_PORTS", "8080, 8081")
+		if got := IntSlice("RETRY_PORTS", nil, ",", Optional).Get(); !reflect.DeepEqual(got, []int{8080, 8081}) {
+			t.Errorf("IntSlice = %v", got)
+		}
+		t.Setenv("RETRY_DELAYS", "1s, 5s, 30s")
+		want := []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+		if got := DurationSlice("RETRY_DELAYS", nil, ",", Optional).Get(); !reflect.DeepEqual(got, want) {
+			t.Errorf("DurationSlice = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("An invalid item uses the fallback", func(t *testing.T) {
+		t.Setenv("RETRY_PORTS", "8080,http")
+		if got := IntSlice("RETRY_PORTS", []int{80}, ",", Optional).Get(); !reflect.DeepEqual(got, []int{80}) {
+			t.Errorf("IntSlice = %v, want the fallback", got)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a required variable with an invalid item to panic")
+			}
+		}()
+		IntSlice("RETRY_PORTS", nil, ",", Require).Get()
+	})
+}
+
+type logLevel string
+
+type port uint16
+
+func TestCustomTypes(t *testing.T) {
+	t.Run("String types need no parser", func(t *testing.T) {
+		t.Setenv("LOG_LEVEL", "debug")
+		if got := (Env[logLevel]{Key: "LOG_LEVEL", Fallback: "info"}).Get(); got != "debug" {
+			t.Errorf("Get() = %q, want debug", got)
+		}
+		t.Setenv("LOG_LEVELS", "debug, warn")
+		if got := (Env[[]logLevel]{Key: "LOG_LEVELS"}).Get(); !reflect.DeepEqual(got, []logLevel{"debug", "warn"}) {
+			t.Errorf("Get() = %q", got)
+		}
+	})
+
+	t.Run("Registered types", func(t *testing.T) {
+		p := Env[port]{Key: "METRICS_PORT", Fallback: 9090}
+		Register(func(s string) (port, error) {
+			v, err := strconv.ParseUint(s, 10, 16)
+			if err == nil && v == 0 {
+				err = fmt.Errorf("port must not be zero")
+			}
+			return port(v), err
+		})
+		t.Setenv("METRICS_PORT", "9100")
+		if got, err := p.Lookup(); err != nil || got != 9100 {
+			t.Errorf("Lookup() = %v, %v, want 9100", got, err)
+		}
+		t.Setenv("METRICS_PORT", "70000")
+		if got, err := p.Lookup(); err == nil || got != 9090 {
+			t.Errorf("Lookup() of an out of range port = %v, %v, want the fallback and an error", got, err)
+		}
+		t.Setenv("METRICS_PORTS", "9100,9101")
+		if got := (Env[[]port]{Key: "METRICS_PORTS"}).Get(); !reflect.DeepEqual(got, []port{9100, 9101}) {
+			t.Errorf("Slice of a registered type = %v", got)
+		}
+	})
+}
+
+func TestLookupErrors(t *testing.T) {
+	t.Run("Missing required variable", func(t *testing.T) {
+		_, err := String("UNSET_DATABASE_URL", "", Require).Lookup()
+		var envErr *Error
+		if !errors.As(err, &envErr) || envErr.Key != "UNSET_DATABASE_URL" || !errors.Is(err, ErrNotSet) {
+			t.Fatalf("Lookup() = %v, want ErrNotSet for the key", err)
+		}
+		if got := err.Error(); got != "environment variable UNSET_DATABASE_URL: required but not set" {
+			t.Errorf("Error() = %q", got)
+		}
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		t.Setenv("APP_PORT", "eighty")
+		got, err := Int("APP_PORT", 5479, Optional).Lookup()
+		if !errors.Is(err, strconv.ErrSyntax) || got != 5479 {
+			t.Errorf("Lookup() = %v, %v, want the fallback and a syntax error", got, err)
+		}
+		// Get keeps the fallback for an optional variable
+		if got := Int("APP_PORT", 5479, Optional).Get(); got != 5479 {
+			t.Errorf("Get() = %v, want the fallback", got)
+		}
+	})
+
+	t.Run("Unsupported types", func(t *testing.T) {
+		t.Setenv("ORIGIN_MAP", "a=b")
+		if _, err := (Env[map[string]string]{Key: "ORIGIN_MAP"}).Lookup(); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Lookup() = %v, want ErrUnsupported", err)
+		}
+	})
+}
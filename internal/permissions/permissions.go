@@ -0,0 +1,55 @@
+package permissions
+
+// Team roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// RoleRank orders roles by authority so an owner always outranks an admin.
+// Unknown roles, including the empty role of a non-member, rank 0.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsAssignableRole reports whether a member can be given role. Ownership is
+// set when the team is created and can't be handed out.
+func IsAssignableRole(role string) bool {
+	return role != RoleOwner && RoleRank(role) > 0
+}
+
+// CanManageTeam reports whether role may change a team's settings
+func CanManageTeam(role string) bool {
+	return RoleRank(role) >= RoleRank(RoleAdmin)
+}
+
+// CanDeleteTeam reports whether role may delete a team
+func CanDeleteTeam(role string) bool {
+	return role == RoleOwner
+}
+
+// CanManageMembers reports whether role may add, remove or change the roles
+// of team members
+func CanManageMembers(role string) bool {
+	return RoleRank(role) >= RoleRank(RoleAdmin)
+}
+
+// CanManageMember reports whether a member with role actor may change or
+// remove a member with role target. Nobody can act on someone who outranks
+// them, so admins can't demote or remove the owner.
+func CanManageMember(actor, target string) bool {
+	return CanManageMembers(actor) && RoleRank(actor) >= RoleRank(target)
+}
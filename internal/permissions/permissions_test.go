@@ -0,0 +1,61 @@
+package permissions
+
+import "testing"
+
+func TestPermissionMatrix(t *testing.T) {
+	tests := []struct {
+		role          string
+		rank          int
+		assignable    bool
+		manageTeam    bool
+		deleteTeam    bool
+		manageMembers bool
+	}{
+		{RoleOwner, 4, false, true, true, true},
+		{RoleAdmin, 3, true, true, false, true},
+		{RoleEditor, 2, true, false, false, false},
+		{RoleViewer, 1, true, false, false, false},
+		{"member", 0, false, false, false, false},
+		{"", 0, false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			if got := RoleRank(tt.role); got != tt.rank {
+				t.Errorf("RoleRank = %d, want %d", got, tt.rank)
+			}
+			if got := IsAssignableRole(tt.role); got != tt.assignable {
+				t.Errorf("IsAssignableRole = %v, want %v", got, tt.assignable)
+			}
+			if got := CanManageTeam(tt.role); got != tt.manageTeam {
+				t.Errorf("CanManageTeam = %v, want %v", got, tt.manageTeam)
+			}
+			if got := CanDeleteTeam(tt.role); got != tt.deleteTeam {
+				t.Errorf("CanDeleteTeam = %v, want %v", got, tt.deleteTeam)
+			}
+			if got := CanManageMembers(tt.role); got != tt.manageMembers {
+				t.Errorf("CanManageMembers = %v, want %v", got, tt.manageMembers)
+			}
+		})
+	}
+}
+
+func TestCanManageMember(t *testing.T) {
+	roles := []string{RoleOwner, RoleAdmin, RoleEditor, RoleViewer}
+
+	// allowed[actor] lists the roles actor may change or remove
+	allowed := map[string]map[string]bool{
+		RoleOwner:  {RoleOwner: true, RoleAdmin: true, RoleEditor: true, RoleViewer: true},
+		RoleAdmin:  {RoleAdmin: true, RoleEditor: true, RoleViewer: true},
+		RoleEditor: {},
+		RoleViewer: {},
+	}
+
+	for _, actor := range roles {
+		for _, target := range roles {
+			if got, want := CanManageMember(actor, target), allowed[actor][target]; got != want {
+				t.Errorf("CanManageMember(%s, %s) = %v, want %v", actor, target, got, want)
+			}
+		}
+	}
+}
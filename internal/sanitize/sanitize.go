@@ -0,0 +1,54 @@
+// Package sanitize strips HTML from user-supplied text before it is stored.
+//
+// Names, titles, descriptions and comments are plain text, but clients may
+// render them as HTML. Rather than escape them on every read, services pass
+// them through Text on write, so what is stored and returned is inert either
+// way: tags, HTML comments and the contents of script-like elements are
+// removed, and everything else is kept exactly as typed, including character
+// references such as "&lt;".
+package sanitize
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hiddenContents are elements whose contents would never be shown as text
+var hiddenContents = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Noembed:  true,
+	atom.Noframes: true,
+	atom.Noscript: true,
+}
+
+// Text removes HTML markup from s. Anything that parses as a tag is removed,
+// so "a<b" loses "<b" while "a < b" is kept.
+func Text(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	hidden := false // Inside an element from hiddenContents
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// The only error reading from a string is io.EOF
+			return b.String()
+		case html.TextToken:
+			if !hidden {
+				b.Write(z.Raw())
+			}
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			hidden = hiddenContents[atom.Lookup(name)]
+		case html.EndTagToken:
+			hidden = false
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package sanitize
+
+import "testing"
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"Plain text", "Crash on login", "Crash on login"},
+		{"Script", `Looks good<script>alert("hi")</script>`, "Looks good"},
+		{"Unclosed script", "Hi <script>alert(1)", "Hi "},
+		{"Style", "<style>body{display:none}</style>Hidden", "Hidden"},
+		{"Formatting kept as text", "<b>Bold</b> and <i>italic</i>", "Bold and italic"},
+		{"Event handler", `<img src=x onerror="alert(1)">Picture`, "Picture"},
+		{"Link", `<a href="javascript:alert(1)">Click</a>`, "Click"},
+		{"HTML comment", "Before<!-- hidden -->After", "BeforeAfter"},
+		{"Comparison", "a < b && b > c", "a < b && b > c"},
+		{"Heart", "Thanks <3", "Thanks <3"},
+		{"Escaped tag kept", "Use &lt;script&gt; carefully", "Use &lt;script&gt; carefully"},
+		{"Mention", "@ada can you <em>check</em> this?", "@ada can you check this?"},
+		{"Only markup", "<script>alert(1)</script>", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.in); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
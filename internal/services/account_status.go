@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrAccountDisabled is returned when a disabled account tries to log in
+var ErrAccountDisabled = errors.New("account disabled")
+
+// accountKey caches whether a user's account is disabled, as "1" or "0"
+func accountKey(userID string) string {
+	return fmt.Sprintf("user:%s:disabled", userID)
+}
+
+// IsAccountDisabled reports whether the user's account has been disabled or
+// no longer exists. It runs on every authenticated request, so the answer is
+// cached briefly; accounts disabled through AdminService take effect at once.
+func (s *UserService) IsAccountDisabled(ctx context.Context, userID string) (bool, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return false, invalidID("user ID", err)
+	}
+
+	key := accountKey(userID)
+	if cached, err := s.cache.Get(ctx, key); err == nil {
+		return cached == "1", nil
+	}
+
+	disabled := true
+	access, err := s.queries.GetUserAccess(ctx, userUUID)
+	switch {
+	case err == nil:
+		disabled = access.DisabledAt.Valid
+	case !errors.Is(err, pgx.ErrNoRows):
+		return false, fmt.Errorf("failed to get account status: %w", err)
+	}
+
+	value := "0"
+	if disabled {
+		value = "1"
+	}
+	if err := s.cache.Set(ctx, key, value, s.ttls.Account); err != nil {
+		log.Printf("Failed to cache account status: %v", err)
+	}
+	return disabled, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestAccountDisabled(t *testing.T) {
+	const (
+		user    = "11111111-1111-1111-1111-111111111111"
+		missing = "22222222-2222-2222-2222-222222222222"
+	)
+	salt, hash, err := auth.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	disabledAt := pgtype.Timestamp{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetUserAccess:" + user:    {false, pgtype.Timestamp{}},
+		"GetUserAccess:" + missing: nil,
+	}}
+	mr := miniredis.RunT(t)
+	queries := store.New(db)
+	svc := NewUserService(queries, cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), nil, CacheTTLs{Account: 30 * time.Second})
+	ctx := context.Background()
+
+	isDisabled := func(t *testing.T, userID string) bool {
+		t.Helper()
+		disabled, err := svc.IsAccountDisabled(ctx, userID)
+		if err != nil {
+			t.Fatalf("IsAccountDisabled failed: %v", err)
+		}
+		return disabled
+	}
+
+	t.Run("Active account is cached briefly", func(t *testing.T) {
+		if isDisabled(t, user) || isDisabled(t, user) {
+			t.Fatal("Expected the account to be active")
+		}
+		if n := db.Count("GetUserAccess"); n != 1 {
+			t.Errorf("Expected the second check to be cached, got %d queries", n)
+		}
+		if ttl := mr.TTL("user:" + user + ":disabled"); ttl != 30*time.Second {
+			t.Errorf("Cached with TTL %v, want 30s", ttl)
+		}
+	})
+
+	t.Run("Disabled in the database once the cache expires", func(t *testing.T) {
+		db.Rows["GetUserAccess:"+user] = []any{false, disabledAt}
+		if isDisabled(t, user) {
+			t.Fatal("Expected the cached status until it expires")
+		}
+		mr.FastForward(30 * time.Second)
+		if !isDisabled(t, user) {
+			t.Error("Expected the account to be disabled")
+		}
+	})
+
+	t.Run("Disabled through the admin service at once", func(t *testing.T) {
+		other := "33333333-3333-3333-3333-333333333333"
+		db.Rows["GetUserAccess:"+other] = []any{false, pgtype.Timestamp{}}
+		if isDisabled(t, other) {
+			t.Fatal("Expected the account to be active")
+		}
+		db.Rows["GetUserAccess:"+other] = []any{false, disabledAt}
+		admin := NewAdminService(queries, svc.cache)
+		if err := admin.DisableUser(ctx, user, other); err != nil {
+			t.Fatalf("DisableUser failed: %v", err)
+		}
+		if !isDisabled(t, other) {
+			t.Error("Expected the account to be disabled without waiting for the cache")
+		}
+	})
+
+	t.Run("Deleted account", func(t *testing.T) {
+		if !isDisabled(t, missing) {
+			t.Error("Expected a deleted account to count as disabled")
+		}
+	})
+
+	t.Run("Database failure", func(t *testing.T) {
+		db.Errs = map[string]error{"GetUserAccess": errors.New("connection refused")}
+		defer func() { db.Errs = nil }()
+		if _, err := svc.IsAccountDisabled(ctx, "44444444-4444-4444-4444-444444444444"); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Login is refused", func(t *testing.T) {
+		row := []any{
+			storetest.MustUUID(t, user), "ada@example.com", salt + ":" + hash, pgtype.Text{}, pgtype.Text{}, pgtype.Text{},
+			pgtype.Text{}, pgtype.Bool{}, pgtype.Timestamp{}, pgtype.Text{}, pgtype.Timestamp{}, pgtype.Timestamp{},
+			false, disabledAt,
+		}
+		db.Rows["GetUserByEmail"] = row
+		if _, err := svc.AuthenticateUser(ctx, "ada@example.com", "correct-horse"); !errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected ErrAccountDisabled, got %v", err)
+		}
+		if _, err := svc.AuthenticateUser(ctx, "ada@example.com", "wrong"); err == nil || errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected the wrong password to fail without revealing the status, got %v", err)
+		}
+
+		row[13] = pgtype.Timestamp{}
+		if _, err := svc.AuthenticateUser(ctx, "ada@example.com", "correct-horse"); err != nil {
+			t.Errorf("Expected an active account to log in, got %v", err)
+		}
+	})
+}
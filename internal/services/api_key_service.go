@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// API key service errors. Keys that fail authentication are reported with
+// auth.ErrInvalidAPIKey.
+var (
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrInvalidAPIKeyInput = errors.New("invalid API key settings")
+)
+
+// apiKeyPrefixLength is how much of a key is kept in the clear to identify
+// it: "tk_" and 8 hex digits
+const apiKeyPrefixLength = len(auth.APIKeyPrefix) + 8
+
+// APIKeyInfo represents an API key returned to clients. Key, the key itself,
+// is only included when it is minted.
+type APIKeyInfo struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"` // The start of the key, to tell keys apart
+	Scopes     []string `json:"scopes"`
+	Key        string   `json:"key,omitempty"`
+	LastUsedAt *string  `json:"last_used_at"`
+	ExpiresAt  *string  `json:"expires_at"`
+	RevokedAt  *string  `json:"revoked_at"`
+	CreatedAt  *string  `json:"created_at"`
+}
+
+// APIKeyInput holds a new key's settings. A zero ExpiresAt means the key
+// doesn't expire.
+type APIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// APIKeyService mints and revokes users' API keys and authenticates requests
+// made with them
+type APIKeyService struct {
+	queries *store.Queries
+	audit   *AuditService
+	now     func() time.Time
+}
+
+// NewAPIKeyService creates an API key service
+func NewAPIKeyService(queries *store.Queries) *APIKeyService {
+	return &APIKeyService{queries: queries, now: time.Now}
+}
+
+// CreateAPIKey mints a key for a user. The returned info holds the key
+// itself; only its hash is stored, so it can't be shown again.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID string, input APIKeyInput) (*APIKeyInfo, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+	scopes, err := apiKeyScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt pgtype.Timestamp
+	if !input.ExpiresAt.IsZero() {
+		if !input.ExpiresAt.After(s.now()) {
+			return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPIKeyInput)
+		}
+		expiresAt = pgtype.Timestamp{Time: input.ExpiresAt.UTC(), Valid: true}
+	}
+
+	key, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.queries.CreateAPIKey(ctx, store.CreateAPIKeyParams{
+		UserID:    userUUID,
+		Name:      input.Name,
+		KeyHash:   hash,
+		Prefix:    key[:apiKeyPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.audit.Record(ctx, userID, AuditAPIKeyCreated, AuditTargetUser, userID, map[string]any{
+		"api_key_id": created.ID.String(),
+		"scopes":     scopes,
+	})
+
+	info := apiKeyToInfo(created)
+	info.Key = key
+	return &info, nil
+}
+
+// ListAPIKeys lists a user's keys, newest first, including revoked ones
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]APIKeyInfo, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	keys, err := s.queries.GetUserAPIKeys(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	result := make([]APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, apiKeyToInfo(key))
+	}
+	return result, nil
+}
+
+// RevokeAPIKey stops one of the user's keys from working. Revoking a key
+// twice, or someone else's, is ErrAPIKeyNotFound.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, keyID, userID string) error {
+	var keyUUID, userUUID pgtype.UUID
+	if err := keyUUID.Scan(keyID); err != nil {
+		return invalidID("API key ID", err)
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	rows, err := s.queries.RevokeAPIKey(ctx, store.RevokeAPIKeyParams{ID: keyUUID, UserID: userUUID})
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	s.audit.Record(ctx, userID, AuditAPIKeyRevoked, AuditTargetUser, userID, map[string]any{
+		"api_key_id": keyUUID.String(),
+	})
+	return nil
+}
+
+// AuthenticateAPIKey resolves a key presented with a request to its owner
+// and scopes, and notes that it was used. It returns auth.ErrInvalidAPIKey
+// for keys that don't exist, have been revoked or have expired.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKey, error) {
+	if !auth.IsAPIKey(key) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	found, err := s.queries.GetAPIKeyByHash(ctx, auth.HashAPIKey(key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if found.RevokedAt.Valid || (found.ExpiresAt.Valid && !found.ExpiresAt.Time.After(s.now())) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	// Best-effort: the request is authenticated either way
+	if err := s.queries.TouchAPIKey(ctx, found.ID); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", found.ID.String(), err)
+	}
+
+	return &auth.APIKey{
+		ID:     found.ID.String(),
+		UserID: found.UserID.String(),
+		Scopes: found.Scopes,
+	}, nil
+}
+
+// apiKeyScopes checks that scopes is a non-empty list of known scopes,
+// dropping repeats
+func apiKeyScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: grant at least one scope", ErrInvalidAPIKeyInput)
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyInput, scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+func apiKeyToInfo(key store.ApiKey) APIKeyInfo {
+	return APIKeyInfo{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		LastUsedAt: FormatTimestamp(key.LastUsedAt),
+		ExpiresAt:  FormatTimestamp(key.ExpiresAt),
+		RevokedAt:  FormatTimestamp(key.RevokedAt),
+		CreatedAt:  FormatTimestamp(key.CreatedAt),
+	}
+}
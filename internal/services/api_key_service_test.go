@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// keyDB keeps the keys minted through it like the api_keys table, so they
+// can be looked up by hash and revoked
+type keyDB struct {
+	*storetest.DB
+	keys []store.ApiKey
+}
+
+func keyRow(k store.ApiKey) []any {
+	return []any{k.ID, k.UserID, k.Name, k.KeyHash, k.Prefix, k.Scopes, k.LastUsedAt, k.ExpiresAt, k.RevokedAt}
+}
+
+func (db *keyDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	switch strings.Fields(sql)[2] {
+	case "CreateAPIKey":
+		db.Record(sql, args)
+		k := store.ApiKey{UserID: args[0].(pgtype.UUID), Name: args[1].(string), KeyHash: args[2].(string),
+			Prefix: args[3].(string), Scopes: args[4].([]string), ExpiresAt: args[5].(pgtype.Timestamp)}
+		k.ID.Bytes[0], k.ID.Valid = byte(len(db.keys)+1), true
+		db.keys = append(db.keys, k)
+		return storetest.Row(keyRow(k))
+	case "GetAPIKeyByHash":
+		db.Record(sql, args)
+		for _, k := range db.keys {
+			if k.KeyHash == args[0].(string) {
+				return storetest.Row(keyRow(k))
+			}
+		}
+		return storetest.Row(nil)
+	}
+	return db.DB.QueryRow(ctx, sql, args...)
+}
+
+func (db *keyDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if strings.Fields(sql)[2] != "RevokeAPIKey" {
+		return db.DB.Exec(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	var n int
+	for i, k := range db.keys {
+		if k.ID == args[0].(pgtype.UUID) && k.UserID == args[1].(pgtype.UUID) && !k.RevokedAt.Valid {
+			db.keys[i].RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			n++
+		}
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", n)), nil
+}
+
+func TestAPIKeys(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		stranger = "33333333-3333-3333-3333-333333333333"
+	)
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	setup := func() (*keyDB, *APIKeyService) {
+		db := &keyDB{DB: &storetest.DB{}}
+		svc := NewAPIKeyService(store.New(db))
+		svc.now = func() time.Time { return now }
+		return db, svc
+	}
+	ctx := context.Background()
+	input := APIKeyInput{Name: "CI", Scopes: []string{auth.ScopeRead, auth.ScopeRead}}
+
+	t.Run("Minting returns the key once and stores its hash", func(t *testing.T) {
+		db, svc := setup()
+		info, err := svc.CreateAPIKey(ctx, owner, input)
+		if err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+		if !strings.HasPrefix(info.Key, auth.APIKeyPrefix) || !strings.HasPrefix(info.Key, info.Prefix) || len(info.Prefix) != 11 {
+			t.Errorf("Key %q with prefix %q, want a tk_ key starting with its prefix", info.Key, info.Prefix)
+		}
+		if len(info.Scopes) != 1 || info.Scopes[0] != auth.ScopeRead {
+			t.Errorf("Scopes = %v, want repeats dropped", info.Scopes)
+		}
+		if len(db.keys) != 1 || db.keys[0].KeyHash != auth.HashAPIKey(info.Key) || strings.Contains(db.keys[0].KeyHash, info.Key) {
+			t.Fatalf("Stored %+v, want only the key's hash", db.keys)
+		}
+
+		db.Lists = map[string][][]any{"GetUserAPIKeys": {keyRow(db.keys[0])}}
+		keys, err := svc.ListAPIKeys(ctx, owner)
+		if err != nil {
+			t.Fatalf("ListAPIKeys failed: %v", err)
+		}
+		if len(keys) != 1 || keys[0].Key != "" || keys[0].Prefix != info.Prefix {
+			t.Errorf("Listed %+v, want the key's prefix but not the key", keys)
+		}
+	})
+
+	t.Run("Authenticating resolves the owner and scopes", func(t *testing.T) {
+		db, svc := setup()
+		info, err := svc.CreateAPIKey(ctx, owner, APIKeyInput{Name: "Deploys", Scopes: []string{auth.ScopeWrite}})
+		if err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+
+		key, err := svc.AuthenticateAPIKey(ctx, info.Key)
+		if err != nil {
+			t.Fatalf("AuthenticateAPIKey failed: %v", err)
+		}
+		if key.ID != info.ID || key.UserID != owner || !key.Allows(auth.ScopeWrite) || !key.Allows(auth.ScopeRead) {
+			t.Errorf("Key = %+v, want %s's write key", key, owner)
+		}
+		if db.Count("TouchAPIKey") != 1 {
+			t.Error("Expected the key's use to be recorded")
+		}
+	})
+
+	t.Run("Unknown keys and JWTs are refused", func(t *testing.T) {
+		db, svc := setup()
+		for _, key := range []string{auth.APIKeyPrefix + strings.Repeat("0", 64), "eyJhbGciOiJIUzI1NiJ9.e30.sig", ""} {
+			if _, err := svc.AuthenticateAPIKey(ctx, key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+				t.Errorf("AuthenticateAPIKey(%q) = %v, want ErrInvalidAPIKey", key, err)
+			}
+		}
+		if db.Count("GetAPIKeyByHash") != 1 {
+			t.Errorf("Expected only the tk_ key to be looked up, got %d lookups", db.Count("GetAPIKeyByHash"))
+		}
+	})
+
+	t.Run("Revoked keys stop working", func(t *testing.T) {
+		db, svc := setup()
+		info, err := svc.CreateAPIKey(ctx, owner, input)
+		if err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+
+		if err := svc.RevokeAPIKey(ctx, info.ID, stranger); !errors.Is(err, ErrAPIKeyNotFound) {
+			t.Errorf("Revoking someone else's key = %v, want ErrAPIKeyNotFound", err)
+		}
+		if _, err := svc.AuthenticateAPIKey(ctx, info.Key); err != nil {
+			t.Fatalf("Expected the key to survive a stranger's revoke, got %v", err)
+		}
+
+		if err := svc.RevokeAPIKey(ctx, info.ID, owner); err != nil {
+			t.Fatalf("RevokeAPIKey failed: %v", err)
+		}
+		if _, err := svc.AuthenticateAPIKey(ctx, info.Key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+			t.Errorf("AuthenticateAPIKey after revoking = %v, want ErrInvalidAPIKey", err)
+		}
+		if err := svc.RevokeAPIKey(ctx, info.ID, owner); !errors.Is(err, ErrAPIKeyNotFound) {
+			t.Errorf("Revoking twice = %v, want ErrAPIKeyNotFound", err)
+		}
+		if db.Count("TouchAPIKey") != 1 {
+			t.Errorf("Expected only the successful use to be recorded, got %d", db.Count("TouchAPIKey"))
+		}
+	})
+
+	t.Run("Keys expire", func(t *testing.T) {
+		_, svc := setup()
+		info, err := svc.CreateAPIKey(ctx, owner, APIKeyInput{Name: "Nightly", Scopes: []string{auth.ScopeRead}, ExpiresAt: now.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("CreateAPIKey failed: %v", err)
+		}
+		if info.ExpiresAt == nil || *info.ExpiresAt != "2024-05-01T10:00:00Z" {
+			t.Errorf("ExpiresAt = %v, want an hour from now", info.ExpiresAt)
+		}
+		if _, err := svc.AuthenticateAPIKey(ctx, info.Key); err != nil {
+			t.Fatalf("Expected the key to work before it expires, got %v", err)
+		}
+
+		now = now.Add(time.Hour)
+		defer func() { now = now.Add(-time.Hour) }()
+		if _, err := svc.AuthenticateAPIKey(ctx, info.Key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+			t.Errorf("AuthenticateAPIKey once expired = %v, want ErrInvalidAPIKey", err)
+		}
+	})
+
+	invalid := []struct {
+		name  string
+		input APIKeyInput
+	}{
+		{"No scopes", APIKeyInput{Name: "CI"}},
+		{"Unknown scope", APIKeyInput{Name: "CI", Scopes: []string{"owner"}}},
+		{"Expiry in the past", APIKeyInput{Name: "CI", Scopes: []string{auth.ScopeRead}, ExpiresAt: now.Add(-time.Minute)}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			db, svc := setup()
+			if _, err := svc.CreateAPIKey(ctx, owner, tt.input); !errors.Is(err, ErrInvalidAPIKeyInput) {
+				t.Errorf("CreateAPIKey = %v, want ErrInvalidAPIKeyInput", err)
+			}
+			if len(db.keys) != 0 {
+				t.Error("Expected no key to be minted")
+			}
+		})
+	}
+}
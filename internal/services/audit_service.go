@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/permissions"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Audited actions
+const (
+	AuditProjectDeleted    = "project.deleted"
+	AuditTeamDeleted       = "team.deleted"
+	AuditMemberRemoved     = "team.member_removed"
+	AuditMemberRoleChanged = "team.member_role_changed"
+	AuditPasswordChanged   = "user.password_changed"
+	AuditPasswordReset     = "user.password_reset"
+	AuditAccountDeleted    = "user.deleted"
+	AuditAccountDisabled   = "user.disabled"
+	AuditAPIKeyCreated     = "user.api_key_created"
+	AuditAPIKeyRevoked     = "user.api_key_revoked"
+)
+
+// Kinds of thing an audit entry can be about
+const (
+	AuditTargetProject = "project"
+	AuditTargetTeam    = "team"
+	AuditTargetUser    = "user"
+)
+
+// auditTimeout bounds how long recording an entry can hold up the action it
+// describes
+const auditTimeout = 2 * time.Second
+
+// AuditEntry is one recorded action. ActorID is empty when the actor
+// couldn't be identified, e.g. for a password reset by emailed link.
+type AuditEntry struct {
+	ID         string         `json:"id"`
+	ActorID    string         `json:"actor_id,omitempty"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   string         `json:"target_id"`
+	TeamID     string         `json:"team_id,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	CreatedAt  *string        `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log listing. Empty fields don't filter.
+type AuditFilter struct {
+	TeamID string
+	Action string
+	Since  time.Time // Inclusive
+	Until  time.Time // Exclusive
+}
+
+// AuditService records sensitive actions, such as deleting a project or
+// changing a member's role, and lists them for admins
+type AuditService struct {
+	queries *store.Queries
+}
+
+// NewAuditService creates an audit service
+func NewAuditService(queries *store.Queries) *AuditService {
+	return &AuditService{queries: queries}
+}
+
+// Record adds an entry saying actorID did action to the targetType named
+// targetID. The entry belongs to a team's log when the target is a team or
+// metadata has a "team_id".
+//
+// Recording is best-effort: the action has already happened, so a failure is
+// logged rather than returned, and a slow database can only hold the caller
+// up for auditTimeout. A nil *AuditService records nothing.
+func (s *AuditService) Record(ctx context.Context, actorID, action, targetType, targetID string, metadata map[string]any) {
+	if s == nil {
+		return
+	}
+
+	params := store.CreateAuditEntryParams{Action: action, TargetType: targetType}
+	if err := params.TargetID.Scan(targetID); err != nil {
+		log.Printf("Failed to record %s audit entry: invalid target ID %q", action, targetID)
+		return
+	}
+	if actorID != "" {
+		if err := params.ActorID.Scan(actorID); err != nil {
+			log.Printf("Failed to record %s audit entry: invalid actor ID %q", action, actorID)
+			return
+		}
+	}
+	teamID, _ := metadata["team_id"].(string)
+	if targetType == AuditTargetTeam {
+		teamID = targetID
+	}
+	if teamID != "" {
+		// Best-effort too: an entry is still worth keeping without its team
+		params.TeamID.Scan(teamID)
+	}
+
+	params.Metadata = []byte("{}")
+	if len(metadata) > 0 {
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			log.Printf("Failed to encode %s audit metadata: %v", action, err)
+		} else {
+			params.Metadata = raw
+		}
+	}
+
+	// The action went through even if the request that made it is now being
+	// cancelled, so the entry is written regardless
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	if err := s.queries.CreateAuditEntry(ctx, params); err != nil {
+		log.Printf("Failed to record %s audit entry for %s %s: %v", action, targetType, targetID, err)
+	}
+}
+
+// List retrieves one page of audit entries, newest first, along with the
+// cursor of the next page ("" on the last page). Admins can list every entry;
+// a team's owners and admins can list that team's, so for them
+// filter.TeamID is required.
+func (s *AuditService) List(ctx context.Context, userID string, filter AuditFilter, page CursorPage) ([]AuditEntry, string, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, "", invalidID("user ID", err)
+	}
+
+	var teamUUID pgtype.UUID
+	if filter.TeamID != "" {
+		if err := teamUUID.Scan(filter.TeamID); err != nil {
+			return nil, "", invalidID("team ID", err)
+		}
+	}
+
+	if err := s.requireAuditAccess(ctx, userUUID, teamUUID); err != nil {
+		return nil, "", err
+	}
+
+	page = page.normalize()
+	createdAt, id, err := page.keyset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	rows, err := s.queries.ListAuditEntries(ctx, store.ListAuditEntriesParams{
+		Limit:           int32(page.Limit + 1),
+		TeamID:          teamUUID,
+		Action:          pgtype.Text{String: filter.Action, Valid: filter.Action != ""},
+		Since:           pgtype.Timestamp{Time: filter.Since, Valid: !filter.Since.IsZero()},
+		Until:           pgtype.Timestamp{Time: filter.Until, Valid: !filter.Until.IsZero()},
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list audit entries: %w", err)
+	}
+
+	fetched := len(rows)
+	if fetched > page.Limit {
+		rows = rows[:page.Limit]
+	}
+
+	entries := make([]AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := AuditEntry{
+			ID:         row.ID.String(),
+			ActorID:    row.ActorID.String(),
+			Action:     row.Action,
+			TargetType: row.TargetType,
+			TargetID:   row.TargetID.String(),
+			TeamID:     row.TeamID.String(),
+			CreatedAt:  FormatTimestamp(row.CreatedAt),
+		}
+		if err := json.Unmarshal(row.Metadata, &entry.Metadata); err != nil {
+			log.Printf("Invalid metadata on audit entry %s: %v", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	var next string
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		next = nextCursor(fetched, page.Limit, last.CreatedAt, last.ID)
+	}
+	return entries, next, nil
+}
+
+// requireAuditAccess returns ErrInsufficientRoles unless the user is an
+// admin, or an owner or admin of teamID when it is set
+func (s *AuditService) requireAuditAccess(ctx context.Context, userID, teamID pgtype.UUID) error {
+	access, err := s.queries.GetUserAccess(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check admin access: %w", err)
+	}
+	if err == nil && access.IsAdmin && !access.DisabledAt.Valid {
+		return nil
+	}
+	if !teamID.Valid {
+		return ErrInsufficientRoles
+	}
+
+	role, err := s.queries.GetTeamMemberRole(ctx, store.GetTeamMemberRoleParams{TeamID: teamID, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotTeamMember
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check team role: %w", err)
+	}
+	if !permissions.CanManageTeam(role.String) {
+		return ErrInsufficientRoles
+	}
+	return nil
+}
+
+// SetAuditLog records project deletions in audit
+func (s *ProjectService) SetAuditLog(audit *AuditService) {
+	s.audit = audit
+}
+
+// SetAuditLog records team deletions and member removals and role changes
+// in audit
+func (s *TeamService) SetAuditLog(audit *AuditService) {
+	s.audit = audit
+}
+
+// SetAuditLog records password changes and resets and account deletions in
+// audit
+func (s *UserService) SetAuditLog(audit *AuditService) {
+	s.audit = audit
+}
+
+// SetAuditLog records disabled accounts in audit
+func (s *AdminService) SetAuditLog(audit *AuditService) {
+	s.audit = audit
+}
+
+// SetAuditLog records minted and revoked keys in audit
+func (s *APIKeyService) SetAuditLog(audit *AuditService) {
+	s.audit = audit
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDeleteProjectIsAudited(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		team    = "44444444-4444-4444-4444-444444444444"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	setup := func() (*storetest.DB, *ProjectService) {
+		db := &storetest.DB{Rows: map[string][]any{
+			"GetProjectByID": {storetest.MustUUID(t, project), "Launch", pgtype.Text{}, storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+		}, Errs: map[string]error{}}
+		queries := store.New(db)
+		projects := NewProjectService(queries, cache.NewMemory(), nil, CacheTTLs{})
+		projects.SetAuditLog(NewAuditService(queries))
+		return db, projects
+	}
+
+	t.Run("Entry names the actor and the project", func(t *testing.T) {
+		db, projects := setup()
+		if err := projects.DeleteProject(context.Background(), project, owner); err != nil {
+			t.Fatalf("DeleteProject failed: %v", err)
+		}
+
+		calls := db.Args("CreateAuditEntry")
+		if len(calls) != 1 {
+			t.Fatalf("Expected one audit entry, got %d", len(calls))
+		}
+		args := calls[0]
+		if actor := args[0].(pgtype.UUID); actor.String() != owner {
+			t.Errorf("Actor = %s, want %s", actor.String(), owner)
+		}
+		if action, targetType := args[1].(string), args[2].(string); action != AuditProjectDeleted || targetType != AuditTargetProject {
+			t.Errorf("Recorded %s on a %s, want %s on a %s", action, targetType, AuditProjectDeleted, AuditTargetProject)
+		}
+		if target := args[3].(pgtype.UUID); target.String() != project {
+			t.Errorf("Target = %s, want %s", target.String(), project)
+		}
+		if teamID := args[4].(pgtype.UUID); teamID.String() != team {
+			t.Errorf("Team = %s, want %s so team admins can see it", teamID.String(), team)
+		}
+		var metadata map[string]any
+		if err := json.Unmarshal(args[5].([]byte), &metadata); err != nil || metadata["name"] != "Launch" {
+			t.Errorf("Metadata = %s, want the project's name", args[5])
+		}
+	})
+
+	t.Run("A failed write doesn't fail the deletion", func(t *testing.T) {
+		db, projects := setup()
+		db.Errs["CreateAuditEntry"] = errors.New("connection reset")
+		if err := projects.DeleteProject(context.Background(), project, owner); err != nil {
+			t.Fatalf("DeleteProject = %v, want the project deleted anyway", err)
+		}
+		if db.Count("DeleteProject") != 1 {
+			t.Error("Expected the project to be deleted")
+		}
+	})
+
+	t.Run("Nothing is recorded when the deletion is refused", func(t *testing.T) {
+		db, projects := setup()
+		err := projects.DeleteProject(context.Background(), project, "22222222-2222-2222-2222-222222222222")
+		if err == nil {
+			t.Fatal("Expected a non-owner to be refused")
+		}
+		if db.Count("CreateAuditEntry") != 0 {
+			t.Error("Expected no audit entry")
+		}
+	})
+}
+
+func TestAuditListAccess(t *testing.T) {
+	const (
+		admin     = "11111111-1111-1111-1111-111111111111"
+		teamAdmin = "22222222-2222-2222-2222-222222222222"
+		editor    = "33333333-3333-3333-3333-333333333333"
+		outsider  = "66666666-6666-6666-6666-666666666666"
+		team      = "44444444-4444-4444-4444-444444444444"
+	)
+
+	role := func(r string) []any { return []any{pgtype.Text{String: r, Valid: true}} }
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"GetUserAccess:" + admin:                      {true},
+			"GetUserAccess":                               {false},
+			"GetTeamMemberRole:" + team + ":" + teamAdmin: role("admin"),
+			"GetTeamMemberRole:" + team + ":" + editor:    role("editor"),
+		},
+		Lists: map[string][][]any{
+			"ListAuditEntries": {{storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), storetest.MustUUID(t, admin), AuditProjectDeleted, AuditTargetProject,
+				storetest.MustUUID(t, "55555555-5555-5555-5555-555555555555"), storetest.MustUUID(t, team), []byte(`{"name":"Launch"}`)}},
+		},
+	}
+	audit := NewAuditService(store.New(db))
+
+	tests := []struct {
+		name    string
+		user    string
+		teamID  string
+		wantErr error
+	}{
+		{"Admin lists everything", admin, "", nil},
+		{"Admin lists a team", admin, team, nil},
+		{"Team admin lists their team", teamAdmin, team, nil},
+		{"Team admin can't list everything", teamAdmin, "", ErrInsufficientRoles},
+		{"Editor can't list their team", editor, team, ErrInsufficientRoles},
+		{"Outsider can't list a team", outsider, team, ErrNotTeamMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Calls = nil
+			entries, _, err := audit.List(context.Background(), tt.user, AuditFilter{TeamID: tt.teamID}, CursorPage{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("List = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if db.Count("ListAuditEntries") != 0 {
+					t.Error("Expected no entries to be read")
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Metadata["name"] != "Launch" {
+				t.Errorf("Entries = %+v, want the recorded deletion", entries)
+			}
+			if teamID := db.Args("ListAuditEntries")[0][0].(pgtype.UUID); teamID.String() != tt.teamID {
+				t.Errorf("Listed team %q, want %q", teamID.String(), tt.teamID)
+			}
+		})
+	}
+}
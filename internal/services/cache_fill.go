@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+)
+
+const (
+	// rebuildLockTTL bounds how long one request may hold the lock on a
+	// cache key while rebuilding it, and so how long others wait for it
+	rebuildLockTTL = 5 * time.Second
+	// rebuildPoll is how often waiting requests check for the rebuilt value
+	rebuildPoll = 20 * time.Millisecond
+)
+
+// loadCached returns the value cached as JSON under key, calling build on a
+// miss. When a popular key expires only one request rebuilds it; the others
+// wait for its result instead of all querying the database at once. If the
+// cache is down every request builds its own value.
+func loadCached[T any](ctx context.Context, c cache.Cache, key string, ttl time.Duration, build func() (T, error)) (T, error) {
+	if value, ok := cachedJSON[T](ctx, c, key); ok {
+		return value, nil
+	}
+
+	for {
+		unlock, err := cache.Lock(ctx, c, key, rebuildLockTTL)
+		if errors.Is(err, cache.ErrLocked) {
+			select {
+			case <-ctx.Done():
+				var zero T
+				return zero, ctx.Err()
+			case <-time.After(rebuildPoll):
+			}
+			if value, ok := cachedJSON[T](ctx, c, key); ok {
+				return value, nil
+			}
+			continue
+		}
+		if err == nil {
+			defer unlock()
+			// Another request may have finished rebuilding between our miss
+			// and taking the lock
+			if value, ok := cachedJSON[T](ctx, c, key); ok {
+				return value, nil
+			}
+		}
+		break
+	}
+
+	value, err := build()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		if err := c.Set(ctx, key, string(data), ttl); err != nil {
+			log.Printf("Failed to cache %s: %v", key, err)
+		}
+	}
+	return value, nil
+}
+
+// cachedJSON reads and decodes the value under key, reporting whether there
+// was a usable one
+func cachedJSON[T any](ctx context.Context, c cache.Cache, key string) (T, bool) {
+	var value T
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return value, false
+	}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
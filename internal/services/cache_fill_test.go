@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// slowDB lets a storetest.DB be queried concurrently, and makes the named
+// queries take a while so that requests pile up behind them
+type slowDB struct {
+	mu   sync.Mutex
+	db   *storetest.DB
+	slow map[string]bool
+}
+
+func (s *slowDB) wait(sql string) {
+	if s.slow[strings.Fields(sql)[2]] {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (s *slowDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	s.wait(sql)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Exec(ctx, sql, args...)
+}
+
+func (s *slowDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	s.wait(sql)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Query(ctx, sql, args...)
+}
+
+func (s *slowDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	s.wait(sql)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.QueryRow(ctx, sql, args...)
+}
+
+func (s *slowDB) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Count(name)
+}
+
+func TestConcurrentMissesRebuildOnce(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		member  = "22222222-2222-2222-2222-222222222222"
+		team    = "44444444-4444-4444-4444-444444444444"
+		project = "55555555-5555-5555-5555-555555555555"
+		readers = 20
+	)
+
+	db := &slowDB{
+		db: &storetest.DB{
+			Rows: map[string][]any{
+				"GetProjectByID":      {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner)},
+				"GetProjectStats":     {int64(12), int64(5)},
+				"CheckTeamMembership": {true},
+			},
+			Lists: map[string][][]any{
+				"GetTeamMembers": {
+					{storetest.MustUUID(t, owner), "owner@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, pgtype.Text{String: "owner", Valid: true}},
+					{storetest.MustUUID(t, member), "member@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, pgtype.Text{String: "member", Valid: true}},
+				},
+			},
+		},
+		slow: map[string]bool{"GetProjectStats": true, "GetTeamMembers": true},
+	}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	teams := NewTeamService(queries, memory, CacheTTLs{})
+	projects := NewProjectService(queries, memory, teams, CacheTTLs{})
+	ctx := context.Background()
+
+	// concurrently calls read from every reader at once and returns the results
+	concurrently := func(t *testing.T, read func() (any, error)) []any {
+		t.Helper()
+		results := make([]any, readers)
+		errs := make([]error, readers)
+		var start, done sync.WaitGroup
+		start.Add(1)
+		for i := 0; i < readers; i++ {
+			done.Add(1)
+			go func(i int) {
+				defer done.Done()
+				start.Wait()
+				results[i], errs[i] = read()
+			}(i)
+		}
+		start.Done()
+		done.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("Reader %d failed: %v", i, err)
+			}
+		}
+		return results
+	}
+
+	t.Run("Project stats", func(t *testing.T) {
+		results := concurrently(t, func() (any, error) {
+			return projects.GetProjectStats(ctx, project, owner)
+		})
+		if n := db.count("GetProjectStats"); n != 1 {
+			t.Errorf("Stats were counted %d times, want once", n)
+		}
+		for _, r := range results {
+			if stats := r.(*ProjectStats); stats.TotalIssues != 12 || stats.OpenIssues != 5 {
+				t.Fatalf("Stats = %+v, want the counted values", stats)
+			}
+		}
+	})
+
+	t.Run("Team members", func(t *testing.T) {
+		results := concurrently(t, func() (any, error) {
+			return teams.GetTeamMembers(ctx, team, member)
+		})
+		if n := db.count("GetTeamMembers"); n != 1 {
+			t.Errorf("Members were listed %d times, want once", n)
+		}
+		for _, r := range results {
+			if !reflect.DeepEqual(r, results[0]) || len(r.([]TeamMemberInfo)) != 2 {
+				t.Fatalf("Members = %+v, want the same two for every reader", r)
+			}
+		}
+	})
+
+	t.Run("A failed rebuild lets the next request try", func(t *testing.T) {
+		memory.Del(ctx, projectStatsKey(project))
+		db.db.Errs = map[string]error{"GetProjectStats": pgx.ErrNoRows}
+		if _, err := projects.GetProjectStats(ctx, project, owner); err == nil {
+			t.Fatal("Expected the failed rebuild to return an error")
+		}
+
+		db.db.Errs = nil
+		stats, err := projects.GetProjectStats(ctx, project, owner)
+		if err != nil {
+			t.Fatalf("GetProjectStats after a failure = %v", err)
+		}
+		if stats.TotalIssues != 12 {
+			t.Errorf("Stats = %+v, want them rebuilt", stats)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package services
+
+import "time"
+
+// CacheTTLs are how long services keep cached values and short-lived tokens
+// in Redis. A zero duration uses the default.
+type CacheTTLs struct {
+	User         time.Duration // A user and their profile
+	Team         time.Duration // A team's details
+	TeamMembers  time.Duration // A team's member list
+	TeamList     time.Duration // The teams a user belongs to
+	Project      time.Duration // A project's details
+	ProjectList  time.Duration // A user's or a team's projects
+	ProjectStats time.Duration // A project's issue counts
+	CommentList  time.Duration // The comments on an issue or task
+	Account      time.Duration // Whether a user's account is disabled, checked on every request
+	ResetToken   time.Duration // How long a password reset link stays valid
+	EmailChange  time.Duration // How long an email change confirmation link stays valid
+}
+
+// DefaultCacheTTLs returns the TTLs used when none are configured
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		User:         time.Hour,
+		Team:         time.Hour,
+		TeamMembers:  5 * time.Minute,
+		TeamList:     10 * time.Minute,
+		Project:      time.Hour,
+		ProjectList:  10 * time.Minute,
+		ProjectStats: 5 * time.Minute,
+		CommentList:  10 * time.Minute,
+		Account:      30 * time.Second,
+		ResetToken:   24 * time.Hour,
+		EmailChange:  24 * time.Hour,
+	}
+}
+
+// withDefaults fills in the default for every TTL that isn't set
+func (t CacheTTLs) withDefaults() CacheTTLs {
+	d := DefaultCacheTTLs()
+	for _, f := range []struct{ ttl, def *time.Duration }{
+		{&t.User, &d.User},
+		{&t.Team, &d.Team},
+		{&t.TeamMembers, &d.TeamMembers},
+		{&t.TeamList, &d.TeamList},
+		{&t.Project, &d.Project},
+		{&t.ProjectList, &d.ProjectList},
+		{&t.ProjectStats, &d.ProjectStats},
+		{&t.CommentList, &d.CommentList},
+		{&t.Account, &d.Account},
+		{&t.ResetToken, &d.ResetToken},
+		{&t.EmailChange, &d.EmailChange},
+	} {
+		if *f.ttl <= 0 {
+			*f.ttl = *f.def
+		}
+	}
+	return t
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCacheTTLs(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+	ctx := context.Background()
+
+	t.Run("Comment lists expire after the configured TTL", func(t *testing.T) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID":     {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}},
+			},
+			Lists: map[string][][]any{
+				"GetIssueComments": {{storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), "Looks good", storetest.MustUUID(t, owner), storetest.MustUUID(t, issue)}},
+			},
+		}
+		mr := miniredis.RunT(t)
+		redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		queries := store.New(db)
+		ttls := CacheTTLs{CommentList: time.Minute}
+		svc := NewCommentService(queries, redisCache, NewProjectService(queries, redisCache, NewTeamService(queries, redisCache, ttls), ttls), ttls)
+
+		for i := 0; i < 2; i++ {
+			if _, err := svc.GetIssueComments(ctx, issue, owner); err != nil {
+				t.Fatalf("GetIssueComments failed: %v", err)
+			}
+		}
+		if n := db.Count("GetIssueComments"); n != 1 {
+			t.Fatalf("Expected the second read to be cached, got %d queries", n)
+		}
+		if ttl := mr.TTL("issue:" + issue + ":comments"); ttl != time.Minute {
+			t.Errorf("Cached with TTL %v, want 1m", ttl)
+		}
+
+		mr.FastForward(time.Minute)
+		if _, err := svc.GetIssueComments(ctx, issue, owner); err != nil {
+			t.Fatalf("GetIssueComments failed: %v", err)
+		}
+		if n := db.Count("GetIssueComments"); n != 2 {
+			t.Errorf("Expected the expired list to be read again, got %d queries", n)
+		}
+	})
+
+	t.Run("Reset tokens expire after the configured TTL", func(t *testing.T) {
+		db := &storetest.DB{Rows: map[string][]any{
+			"GetUserByEmail": {storetest.MustUUID(t, owner), "owner@example.com"},
+		}}
+		mr := miniredis.RunT(t)
+		redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		svc := NewUserService(store.New(db), redisCache, nil, CacheTTLs{ResetToken: 15 * time.Minute})
+
+		if err := svc.ForgotPassword(ctx, "owner@example.com"); err != nil {
+			t.Fatalf("ForgotPassword failed: %v", err)
+		}
+		keys := mr.Keys()
+		if len(keys) != 1 || !strings.HasPrefix(keys[0], "password_reset:") {
+			t.Fatalf("Expected one reset token, got keys %v", keys)
+		}
+		mr.FastForward(14 * time.Minute)
+		if !mr.Exists(keys[0]) {
+			t.Error("Reset token expired early")
+		}
+		mr.FastForward(time.Minute)
+		if mr.Exists(keys[0]) {
+			t.Error("Reset token outlived its TTL")
+		}
+	})
+
+	t.Run("Unset TTLs use the defaults", func(t *testing.T) {
+		got := CacheTTLs{Team: time.Second}.withDefaults()
+		want := DefaultCacheTTLs()
+		want.Team = time.Second
+		if got != want {
+			t.Errorf("withDefaults() = %+v, want %+v", got, want)
+		}
+	})
+}
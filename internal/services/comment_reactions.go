@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReactionEmoji are the reactions users can leave on comments
+var ReactionEmoji = []string{"👍", "👎", "😄", "🎉", "😕", "❤️", "🚀", "👀"}
+
+// reactionsByBase maps each allowed emoji, without variation selectors, to
+// its canonical form, so "❤" and "❤️" are the same reaction
+var reactionsByBase = func() map[string]string {
+	m := make(map[string]string, len(ReactionEmoji))
+	for _, emoji := range ReactionEmoji {
+		m[stripVariationSelectors(emoji)] = emoji
+	}
+	return m
+}()
+
+// ReactionCount is how many users reacted to a comment with an emoji
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+}
+
+// NormalizeReaction returns the canonical form of an allowed reaction emoji,
+// or false if it isn't one
+func NormalizeReaction(emoji string) (string, bool) {
+	canonical, ok := reactionsByBase[stripVariationSelectors(strings.TrimSpace(emoji))]
+	return canonical, ok
+}
+
+func stripVariationSelectors(s string) string {
+	return strings.ReplaceAll(s, "\uFE0F", "")
+}
+
+// ToggleReaction adds the user's emoji reaction to a comment, or removes it
+// if they already reacted with it. It reports whether the user now has the
+// reaction and returns the comment's updated counts.
+func (s *CommentService) ToggleReaction(ctx context.Context, commentID, userID, emoji string) (bool, []ReactionCount, error) {
+	emoji, ok := NormalizeReaction(emoji)
+	if !ok {
+		return false, nil, fmt.Errorf("%w: reaction must be one of %s", ErrInvalidCommentData, strings.Join(ReactionEmoji, " "))
+	}
+
+	var commentUUID pgtype.UUID
+	if err := commentUUID.Scan(commentID); err != nil {
+		return false, nil, invalidID("comment ID", err)
+	}
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return false, nil, invalidID("user ID", err)
+	}
+
+	comment, err := s.queries.GetCommentByID(ctx, commentUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	projectID, err := s.commentProjectID(ctx, &comment)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := s.projectService.requireProjectAccess(ctx, projectID.String(), userID); err != nil {
+		return false, nil, err
+	}
+
+	reaction := store.RemoveCommentReactionParams{CommentID: comment.ID, UserID: userUUID, Emoji: emoji}
+	removed, err := s.queries.RemoveCommentReaction(ctx, reaction)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	reacted := removed == 0
+	if reacted {
+		// Adding an existing reaction is a no-op, so a concurrent toggle
+		// can't count the same user twice
+		if _, err := s.queries.AddCommentReaction(ctx, store.AddCommentReactionParams(reaction)); err != nil {
+			return false, nil, fmt.Errorf("failed to add reaction: %w", err)
+		}
+	}
+
+	if comment.IssueID.Valid {
+		s.invalidateCommentsCache(ctx, "issue", comment.IssueID.String())
+	} else if comment.TaskID.Valid {
+		s.invalidateCommentsCache(ctx, "task", comment.TaskID.String())
+	}
+
+	counts, err := s.reactionCounts(ctx, []pgtype.UUID{comment.ID})
+	if err != nil {
+		return false, nil, err
+	}
+	return reacted, counts[comment.ID.String()], nil
+}
+
+// commentProjectID returns the project of the issue or task a comment is on
+func (s *CommentService) commentProjectID(ctx context.Context, comment *store.Comment) (pgtype.UUID, error) {
+	if comment.IssueID.Valid {
+		issue, err := s.queries.GetIssueByID(ctx, comment.IssueID)
+		if err != nil {
+			return pgtype.UUID{}, fmt.Errorf("failed to get issue: %w", err)
+		}
+		return issue.ProjectID, nil
+	}
+	task, err := s.queries.GetTaskByID(ctx, comment.TaskID)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to get task: %w", err)
+	}
+	return task.ProjectID, nil
+}
+
+// attachReactions fills in the reaction counts of a page of comments
+func (s *CommentService) attachReactions(ctx context.Context, comments []CommentInfo) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]pgtype.UUID, 0, len(comments))
+	for _, c := range comments {
+		var id pgtype.UUID
+		if err := id.Scan(c.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	counts, err := s.reactionCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Reactions = counts[comments[i].ID]
+	}
+	return nil
+}
+
+// reactionCounts returns the reaction counts of each comment, keyed by ID
+func (s *CommentService) reactionCounts(ctx context.Context, commentIDs []pgtype.UUID) (map[string][]ReactionCount, error) {
+	rows, err := s.queries.GetCommentReactionCounts(ctx, commentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	counts := make(map[string][]ReactionCount)
+	for _, row := range rows {
+		id := row.CommentID.String()
+		counts[id] = append(counts[id], ReactionCount{Emoji: row.Emoji, Count: row.Count})
+	}
+	return counts, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// reactionDB keeps comment reactions in memory, like the comment_reactions
+// table, and answers everything else from the embedded DB
+type reactionDB struct {
+	*storetest.DB
+	reactions []store.CommentReaction // In the order they were added
+}
+
+func (db *reactionDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	name := strings.Fields(sql)[2]
+	if name != "AddCommentReaction" && name != "RemoveCommentReaction" {
+		return db.DB.Exec(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	r := store.CommentReaction{CommentID: args[0].(pgtype.UUID), UserID: args[1].(pgtype.UUID), Emoji: args[2].(string)}
+	for i, existing := range db.reactions {
+		if existing.CommentID == r.CommentID && existing.UserID == r.UserID && existing.Emoji == r.Emoji {
+			if name == "RemoveCommentReaction" {
+				db.reactions = append(db.reactions[:i], db.reactions[i+1:]...)
+				return pgconn.NewCommandTag("DELETE 1"), nil
+			}
+			return pgconn.NewCommandTag("INSERT 0 0"), nil
+		}
+	}
+	if name == "RemoveCommentReaction" {
+		return pgconn.NewCommandTag("DELETE 0"), nil
+	}
+	db.reactions = append(db.reactions, r)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (db *reactionDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if strings.Fields(sql)[2] != "GetCommentReactionCounts" {
+		return db.DB.Query(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	var rows [][]any
+	index := map[string]int{}
+	for _, id := range args[0].([]pgtype.UUID) {
+		for _, r := range db.reactions {
+			if r.CommentID != id {
+				continue
+			}
+			key := id.String() + r.Emoji
+			if i, ok := index[key]; ok {
+				rows[i][2] = rows[i][2].(int64) + 1
+				continue
+			}
+			index[key] = len(rows)
+			rows = append(rows, []any{r.CommentID, r.Emoji, int64(1)})
+		}
+	}
+	return storetest.Rows(rows), nil
+}
+
+func TestCommentReactions(t *testing.T) {
+	const (
+		author   = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		issue    = "66666666-6666-6666-6666-666666666666"
+		comment  = "77777777-7777-7777-7777-777777777777"
+	)
+
+	newService := func(t *testing.T) (*CommentService, *reactionDB) {
+		db := &reactionDB{DB: &storetest.DB{
+			Rows: map[string][]any{
+				"GetCommentByID:" + comment:                    {storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, author), storetest.MustUUID(t, issue), pgtype.UUID{}},
+				"GetCommentByID":                               nil,
+				"GetIssueByID":                                 {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetProjectAccess":                             {storetest.MustUUID(t, author), storetest.MustUUID(t, team)},
+				"CheckTeamMembership:" + team + ":" + member:   {true},
+				"CheckTeamMembership:" + team + ":" + outsider: {false},
+			},
+			Lists: map[string][][]any{
+				"GetIssueComments": {{storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, author), storetest.MustUUID(t, issue), pgtype.UUID{}}},
+			},
+		}}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return NewCommentService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}), CacheTTLs{}), db
+	}
+	ctx := context.Background()
+	toggle := func(t *testing.T, svc *CommentService, user, emoji string) (bool, []ReactionCount) {
+		t.Helper()
+		reacted, counts, err := svc.ToggleReaction(ctx, comment, user, emoji)
+		if err != nil {
+			t.Fatalf("ToggleReaction(%s, %s) failed: %v", user, emoji, err)
+		}
+		return reacted, counts
+	}
+
+	t.Run("Toggling on and off", func(t *testing.T) {
+		svc, db := newService(t)
+
+		if reacted, counts := toggle(t, svc, member, "👍"); !reacted || !reflect.DeepEqual(counts, []ReactionCount{{"👍", 1}}) {
+			t.Errorf("First toggle = %v %v, want the reaction added", reacted, counts)
+		}
+		if reacted, counts := toggle(t, svc, member, "👍"); reacted || len(counts) != 0 {
+			t.Errorf("Second toggle = %v %v, want the reaction removed", reacted, counts)
+		}
+		if len(db.reactions) != 0 {
+			t.Errorf("Reactions left behind: %v", db.reactions)
+		}
+
+		// Toggling twice always comes back to where it started
+		toggle(t, svc, member, "🎉")
+		toggle(t, svc, member, "🎉")
+		toggle(t, svc, member, "🎉")
+		if len(db.reactions) != 1 {
+			t.Errorf("Expected one reaction after three toggles, got %v", db.reactions)
+		}
+	})
+
+	t.Run("Counts aggregate across users", func(t *testing.T) {
+		svc, _ := newService(t)
+		toggle(t, svc, member, "👍")
+		toggle(t, svc, author, "❤") // Same reaction as ❤️
+		toggle(t, svc, author, "👍")
+		_, counts := toggle(t, svc, member, "❤️")
+
+		want := []ReactionCount{{"👍", 2}, {"❤️", 2}}
+		if !reflect.DeepEqual(counts, want) {
+			t.Errorf("Counts = %v, want %v", counts, want)
+		}
+
+		comments, err := svc.GetIssueComments(ctx, issue, member)
+		if err != nil {
+			t.Fatalf("GetIssueComments failed: %v", err)
+		}
+		if len(comments) != 1 || !reflect.DeepEqual(comments[0].Reactions, want) {
+			t.Errorf("Listed comments = %+v, want reactions %v", comments, want)
+		}
+
+		// The cached list is dropped when reactions change, and an emoji is
+		// ordered by its oldest remaining reaction
+		toggle(t, svc, member, "👍")
+		comments, _ = svc.GetIssueComments(ctx, issue, member)
+		if want := []ReactionCount{{"❤️", 2}, {"👍", 1}}; !reflect.DeepEqual(comments[0].Reactions, want) {
+			t.Errorf("Reactions after a change = %v, want %v", comments[0].Reactions, want)
+		}
+	})
+
+	t.Run("Rejected reactions", func(t *testing.T) {
+		missing := "99999999-9999-9999-9999-999999999999"
+		tests := []struct {
+			name    string
+			comment string
+			user    string
+			emoji   string
+			want    error
+		}{
+			{"Emoji not allowed", comment, member, "💩", ErrInvalidCommentData},
+			{"Text", comment, member, "+1", ErrInvalidCommentData},
+			{"Outsider", comment, outsider, "👍", ErrNotProjectOwner},
+			{"Unknown comment", missing, member, "👍", ErrCommentNotFound},
+			{"Malformed comment ID", "not-a-uuid", member, "👍", ErrInvalidID},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				svc, db := newService(t)
+				if _, _, err := svc.ToggleReaction(ctx, tt.comment, tt.user, tt.emoji); !errors.Is(err, tt.want) {
+					t.Errorf("Expected %v, got %v", tt.want, err)
+				}
+				if n := db.Count("AddCommentReaction"); n != 0 {
+					t.Errorf("Reaction was added %d times", n)
+				}
+			})
+		}
+	})
+}
+
+func TestNormalizeReaction(t *testing.T) {
+	for _, emoji := range ReactionEmoji {
+		if got, ok := NormalizeReaction(emoji); !ok || got != emoji {
+			t.Errorf("NormalizeReaction(%q) = %q, %v", emoji, got, ok)
+		}
+	}
+	if got, ok := NormalizeReaction(" ❤ "); !ok || got != "❤️" {
+		t.Errorf("Heart without a variation selector = %q, %v", got, ok)
+	}
+	if _, ok := NormalizeReaction(fmt.Sprint("👍", "👍")); ok {
+		t.Error("Two emoji were accepted as one reaction")
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"time"
 
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bethel-nz/tickit/internal/sanitize"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -22,37 +23,61 @@ var (
 
 // CommentInfo represents comment information returned to clients
 type CommentInfo struct {
-	ID        string `json:"id"`
-	Content   string `json:"content"`
-	UserID    string `json:"user_id"`
-	IssueID   string `json:"issue_id,omitempty"`
-	TaskID    string `json:"task_id,omitempty"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at,omitempty"`
+	ID        string  `json:"id"`
+	Content   string  `json:"content"`
+	UserID    string  `json:"user_id"`
+	IssueID   string  `json:"issue_id,omitempty"`
+	TaskID    string  `json:"task_id,omitempty"`
+	CreatedAt *string `json:"created_at"`
+	UpdatedAt *string `json:"updated_at"`
+	// Edited is set once the author has changed the comment's content
+	Edited   bool    `json:"edited"`
+	EditedAt *string `json:"edited_at"`
 	// Additional user info for display
 	UserName     string `json:"user_name,omitempty"`
 	UserEmail    string `json:"user_email,omitempty"`
 	UserUsername string `json:"user_username,omitempty"`
 	UserAvatar   string `json:"user_avatar,omitempty"`
+	// Title of the issue or task the comment belongs to
+	ParentTitle string `json:"parent_title,omitempty"`
+	// Reaction counts, in the order each emoji was first used
+	Reactions []ReactionCount `json:"reactions,omitempty"`
 }
 
 type CommentService struct {
 	queries        *store.Queries
-	cache          *redis.Client
+	cache          cache.Cache
+	ttls           CacheTTLs
 	projectService *ProjectService
+	notifier       Notifier
+	webhooks       *WebhookService
 }
 
-func NewCommentService(queries *store.Queries, cache *redis.Client, projectService *ProjectService) *CommentService {
+func NewCommentService(queries *store.Queries, cache cache.Cache, projectService *ProjectService, ttls CacheTTLs) *CommentService {
 	return &CommentService{
 		queries:        queries,
 		cache:          cache,
+		ttls:           ttls.withDefaults(),
 		projectService: projectService,
 	}
 }
 
+// SetNotifier sets where mention notifications are sent. Without one,
+// mentions are not delivered.
+func (s *CommentService) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetWebhooks sets where new comments are announced. Without it, projects'
+// webhooks don't hear about comments.
+func (s *CommentService) SetWebhooks(webhooks *WebhookService) {
+	s.webhooks = webhooks
+}
+
 // CreateComment creates a new comment for an issue or task
 func (s *CommentService) CreateComment(ctx context.Context, params store.CreateCommentParams, userID string) (*store.Comment, error) {
 	// Validate comment data
+	params.Content = sanitize.Text(params.Content)
 	if params.Content == "" {
 		return nil, fmt.Errorf("%w: comment content is required", ErrInvalidCommentData)
 	}
@@ -60,12 +85,13 @@ func (s *CommentService) CreateComment(ctx context.Context, params store.CreateC
 	// Make sure user ID matches
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 	params.UserID = userUUID
 
 	// Verify the user has access to the issue or task being commented on
-	if err := s.verifyCommentableAccess(ctx, params.IssueID, params.TaskID, userID); err != nil {
+	target, err := s.verifyCommentableAccess(ctx, params.IssueID, params.TaskID, userID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -82,6 +108,9 @@ func (s *CommentService) CreateComment(ctx context.Context, params store.CreateC
 		s.invalidateCommentsCache(ctx, "task", comment.TaskID.String())
 	}
 
+	s.notifyComment(ctx, &comment, target)
+	s.publishComment(ctx, &comment, target)
+
 	return &comment, nil
 }
 
@@ -89,7 +118,7 @@ func (s *CommentService) CreateComment(ctx context.Context, params store.CreateC
 func (s *CommentService) GetIssueComments(ctx context.Context, issueID string, userID string) ([]CommentInfo, error) {
 	var issueUUID pgtype.UUID
 	if err := issueUUID.Scan(issueID); err != nil {
-		return nil, fmt.Errorf("invalid issue ID: %w", err)
+		return nil, invalidID("issue ID", err)
 	}
 
 	// Verify the user has access to the issue
@@ -99,13 +128,13 @@ func (s *CommentService) GetIssueComments(ctx context.Context, issueID string, u
 	}
 
 	// Check access to the project this issue belongs to
-	if err := s.projectService.verifyProjectAccess(ctx, &store.Project{ID: issue.ProjectID}, userID); err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, issue.ProjectID.String(), userID); err != nil {
 		return nil, err
 	}
 
 	// Try to get from cache
 	cacheKey := fmt.Sprintf("issue:%s:comments", issueID)
-	cachedComments, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedComments, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var comments []CommentInfo
 		if err := json.Unmarshal([]byte(cachedComments), &comments); err == nil {
@@ -127,8 +156,10 @@ func (s *CommentService) GetIssueComments(ctx context.Context, issueID string, u
 			Content:      c.Content,
 			UserID:       c.UserID.String(),
 			IssueID:      issueID,
-			CreatedAt:    c.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:    c.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:    FormatTimestamp(c.CreatedAt),
+			UpdatedAt:    FormatTimestamp(c.UpdatedAt),
+			Edited:       c.EditedAt.Valid,
+			EditedAt:     FormatTimestamp(c.EditedAt),
 			UserName:     c.Name.String,
 			UserEmail:    c.Email,
 			UserUsername: c.Username.String,
@@ -136,10 +167,14 @@ func (s *CommentService) GetIssueComments(ctx context.Context, issueID string, u
 		}
 	}
 
+	if err := s.attachReactions(ctx, comments); err != nil {
+		return nil, err
+	}
+
 	// Cache the result
 	commentsJSON, err := json.Marshal(comments)
 	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, commentsJSON, 10*time.Minute).Err(); err != nil {
+		if err := s.cache.Set(ctx, cacheKey, string(commentsJSON), s.ttls.CommentList); err != nil {
 			log.Printf("Failed to cache issue comments: %v", err)
 		}
 	}
@@ -151,7 +186,7 @@ func (s *CommentService) GetIssueComments(ctx context.Context, issueID string, u
 func (s *CommentService) GetTaskComments(ctx context.Context, taskID string, userID string) ([]CommentInfo, error) {
 	var taskUUID pgtype.UUID
 	if err := taskUUID.Scan(taskID); err != nil {
-		return nil, fmt.Errorf("invalid task ID: %w", err)
+		return nil, invalidID("task ID", err)
 	}
 
 	// Verify the user has access to the task
@@ -161,13 +196,13 @@ func (s *CommentService) GetTaskComments(ctx context.Context, taskID string, use
 	}
 
 	// Check access to the project this task belongs to
-	if err := s.projectService.verifyProjectAccess(ctx, &store.Project{ID: task.ProjectID}, userID); err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, task.ProjectID.String(), userID); err != nil {
 		return nil, err
 	}
 
 	// Try to get from cache
 	cacheKey := fmt.Sprintf("task:%s:comments", taskID)
-	cachedComments, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedComments, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var comments []CommentInfo
 		if err := json.Unmarshal([]byte(cachedComments), &comments); err == nil {
@@ -189,8 +224,10 @@ func (s *CommentService) GetTaskComments(ctx context.Context, taskID string, use
 			Content:      c.Content,
 			UserID:       c.UserID.String(),
 			TaskID:       taskID,
-			CreatedAt:    c.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:    c.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:    FormatTimestamp(c.CreatedAt),
+			UpdatedAt:    FormatTimestamp(c.UpdatedAt),
+			Edited:       c.EditedAt.Valid,
+			EditedAt:     FormatTimestamp(c.EditedAt),
 			UserName:     c.Name.String,
 			UserEmail:    c.Email,
 			UserUsername: c.Username.String,
@@ -198,10 +235,14 @@ func (s *CommentService) GetTaskComments(ctx context.Context, taskID string, use
 		}
 	}
 
+	if err := s.attachReactions(ctx, comments); err != nil {
+		return nil, err
+	}
+
 	// Cache the result
 	commentsJSON, err := json.Marshal(comments)
 	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, commentsJSON, 10*time.Minute).Err(); err != nil {
+		if err := s.cache.Set(ctx, cacheKey, string(commentsJSON), s.ttls.CommentList); err != nil {
 			log.Printf("Failed to cache task comments: %v", err)
 		}
 	}
@@ -209,9 +250,60 @@ func (s *CommentService) GetTaskComments(ctx context.Context, taskID string, use
 	return comments, nil
 }
 
+// GetProjectComments retrieves comments across all issues and tasks of a project, most recent first
+func (s *CommentService) GetProjectComments(ctx context.Context, projectID string, userID string, page Pagination) ([]CommentInfo, error) {
+	// Verify the user has access to the project
+	project, err := s.projectService.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page = page.normalize()
+
+	dbComments, err := s.queries.GetProjectComments(ctx, store.GetProjectCommentsParams{
+		ProjectID: project.ID,
+		Limit:     int32(page.Limit),
+		Offset:    int32(page.Offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project comments: %w", err)
+	}
+
+	comments := make([]CommentInfo, len(dbComments))
+	for i, c := range dbComments {
+		comments[i] = CommentInfo{
+			ID:           c.ID.String(),
+			Content:      c.Content,
+			UserID:       c.UserID.String(),
+			CreatedAt:    FormatTimestamp(c.CreatedAt),
+			UpdatedAt:    FormatTimestamp(c.UpdatedAt),
+			Edited:       c.EditedAt.Valid,
+			EditedAt:     FormatTimestamp(c.EditedAt),
+			UserName:     c.Name.String,
+			UserEmail:    c.Email,
+			UserUsername: c.Username.String,
+			UserAvatar:   c.AvatarUrl.String,
+			ParentTitle:  c.ParentTitle,
+		}
+		if c.IssueID.Valid {
+			comments[i].IssueID = c.IssueID.String()
+		}
+		if c.TaskID.Valid {
+			comments[i].TaskID = c.TaskID.String()
+		}
+	}
+
+	if err := s.attachReactions(ctx, comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
 // UpdateComment updates a comment
 func (s *CommentService) UpdateComment(ctx context.Context, params store.UpdateCommentParams, userID string) error {
 	// Validate comment content
+	params.Content = sanitize.Text(params.Content)
 	if params.Content == "" {
 		return fmt.Errorf("%w: comment content is required", ErrInvalidCommentData)
 	}
@@ -225,16 +317,30 @@ func (s *CommentService) UpdateComment(ctx context.Context, params store.UpdateC
 	// Verify the user is the author of the comment
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	if comment.UserID != userUUID {
 		return ErrNotCommentAuthor
 	}
 
-	// Update the comment
-	if err := s.queries.UpdateComment(ctx, params); err != nil {
-		return fmt.Errorf("failed to update comment: %w", err)
+	// Saving the same content again isn't an edit
+	if params.Content == comment.Content {
+		return nil
+	}
+
+	// Keep the content being replaced, then update the comment
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		if err := q.CreateCommentRevision(ctx, comment.ID); err != nil {
+			return fmt.Errorf("failed to save comment revision: %w", err)
+		}
+		if err := q.UpdateComment(ctx, params); err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Invalidate comments list cache
@@ -247,11 +353,62 @@ func (s *CommentService) UpdateComment(ctx context.Context, params store.UpdateC
 	return nil
 }
 
+// CommentRevisionInfo is an earlier version of a comment
+type CommentRevisionInfo struct {
+	ID       string `json:"id"`
+	Content  string `json:"content"`
+	EditedBy string `json:"edited_by,omitempty"`
+	// When this content was replaced
+	EditedAt *string `json:"edited_at"`
+}
+
+// GetCommentHistory returns the earlier versions of a comment, oldest first.
+// Anyone who can see the comment can see its history.
+func (s *CommentService) GetCommentHistory(ctx context.Context, commentID string, userID string) ([]CommentRevisionInfo, error) {
+	var commentUUID pgtype.UUID
+	if err := commentUUID.Scan(commentID); err != nil {
+		return nil, invalidID("comment ID", err)
+	}
+
+	comment, err := s.queries.GetCommentByID(ctx, commentUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	projectID, err := s.commentProjectID(ctx, &comment)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.projectService.requireProjectAccess(ctx, projectID.String(), userID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.queries.GetCommentRevisions(ctx, comment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment history: %w", err)
+	}
+
+	history := make([]CommentRevisionInfo, len(revisions))
+	for i, r := range revisions {
+		history[i] = CommentRevisionInfo{
+			ID:       r.ID.String(),
+			Content:  r.Content,
+			EditedAt: FormatTimestamp(r.CreatedAt),
+		}
+		if r.EditedBy.Valid {
+			history[i].EditedBy = r.EditedBy.String()
+		}
+	}
+	return history, nil
+}
+
 // DeleteComment deletes a comment
 func (s *CommentService) DeleteComment(ctx context.Context, commentID string, userID string) error {
 	var commentUUID pgtype.UUID
 	if err := commentUUID.Scan(commentID); err != nil {
-		return fmt.Errorf("invalid comment ID: %w", err)
+		return invalidID("comment ID", err)
 	}
 
 	// Get the comment to check ownership and get the related issue/task ID
@@ -263,7 +420,7 @@ func (s *CommentService) DeleteComment(ctx context.Context, commentID string, us
 	// Verify the user is the author of the comment
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	if comment.UserID != userUUID {
@@ -308,6 +465,49 @@ func (s *CommentService) DeleteComment(ctx context.Context, commentID string, us
 	return nil
 }
 
+// notifyComment tells mentioned users and the issue's watchers about a new
+// comment. Failures are logged; they never fail the comment.
+func (s *CommentService) notifyComment(ctx context.Context, comment *store.Comment, target *commentTarget) {
+	if s.notifier == nil {
+		return
+	}
+
+	actor := actorName(ctx, s.queries, comment.UserID)
+	mentioned := s.notifyMentions(ctx, comment, target, actor)
+
+	// Mentioned watchers already heard about this comment
+	if comment.IssueID.Valid {
+		notifyWatchers(ctx, s.queries, s.notifier, comment.IssueID, mentioned, Notification{
+			ActorID: comment.UserID.String(),
+			Type:    NotificationComment,
+			Subject: fmt.Sprintf("%s commented on %q", actor, target.title),
+			Message: excerpt(comment.Content, 280),
+		})
+	}
+}
+
+// publishComment announces a new comment to its project's webhooks
+func (s *CommentService) publishComment(ctx context.Context, comment *store.Comment, target *commentTarget) {
+	if s.webhooks == nil {
+		return
+	}
+
+	info := CommentInfo{
+		ID:          comment.ID.String(),
+		Content:     comment.Content,
+		UserID:      comment.UserID.String(),
+		CreatedAt:   FormatTimestamp(comment.CreatedAt),
+		ParentTitle: target.title,
+	}
+	if comment.IssueID.Valid {
+		info.IssueID = comment.IssueID.String()
+	}
+	if comment.TaskID.Valid {
+		info.TaskID = comment.TaskID.String()
+	}
+	s.webhooks.Dispatch(ctx, target.project.ID, EventCommentCreated, info.UserID, info)
+}
+
 // Helper method to invalidate comments cache
 func (s *CommentService) invalidateCommentsCache(_ context.Context, entityType string, entityID string) {
 	if s.cache == nil {
@@ -315,35 +515,44 @@ func (s *CommentService) invalidateCommentsCache(_ context.Context, entityType s
 	}
 
 	cacheKey := fmt.Sprintf("%s:%s:comments", entityType, entityID)
-	if err := s.cache.Del(context.Background(), cacheKey).Err(); err != nil {
+	if err := s.cache.Del(context.Background(), cacheKey); err != nil {
 		log.Printf("Failed to invalidate comments cache: %v", err)
 	}
 }
 
+// commentTarget is the issue or task a comment is attached to
+type commentTarget struct {
+	project *store.Project
+	title   string
+}
+
 // Helper method to verify access to the entity being commented on
-func (s *CommentService) verifyCommentableAccess(ctx context.Context, issueID, taskID pgtype.UUID, userID string) error {
+func (s *CommentService) verifyCommentableAccess(ctx context.Context, issueID, taskID pgtype.UUID, userID string) (*commentTarget, error) {
 	// Verify that exactly one of issueID or taskID is provided
 	if (issueID.Valid && taskID.Valid) || (!issueID.Valid && !taskID.Valid) {
-		return fmt.Errorf("%w: exactly one of issue ID or task ID must be provided", ErrInvalidCommentData)
+		return nil, fmt.Errorf("%w: exactly one of issue ID or task ID must be provided", ErrInvalidCommentData)
 	}
 
+	var projectID pgtype.UUID
+	var title string
 	if issueID.Valid {
-		// Get the issue and verify access
 		issue, err := s.queries.GetIssueByID(ctx, issueID)
 		if err != nil {
-			return fmt.Errorf("failed to get issue: %w", err)
+			return nil, fmt.Errorf("failed to get issue: %w", err)
 		}
-
-		// Check access to the project this issue belongs to
-		return s.projectService.verifyProjectAccess(ctx, &store.Project{ID: issue.ProjectID}, userID)
+		projectID, title = issue.ProjectID, issue.Title
 	} else {
-		// Get the task and verify access
 		task, err := s.queries.GetTaskByID(ctx, taskID)
 		if err != nil {
-			return fmt.Errorf("failed to get task: %w", err)
+			return nil, fmt.Errorf("failed to get task: %w", err)
 		}
+		projectID, title = task.ProjectID, task.Title
+	}
 
-		// Check access to the project this task belongs to
-		return s.projectService.verifyProjectAccess(ctx, &store.Project{ID: task.ProjectID}, userID)
+	// Check access to the project the issue or task belongs to
+	project, err := s.projectService.GetProjectByID(ctx, projectID.String(), userID)
+	if err != nil {
+		return nil, err
 	}
+	return &commentTarget{project: project, title: title}, nil
 }
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCommentEdits(t *testing.T) {
+	const (
+		author   = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		issue    = "66666666-6666-6666-6666-666666666666"
+		comment  = "77777777-7777-7777-7777-777777777777"
+		revision = "88888888-8888-8888-8888-888888888888"
+	)
+	editedAt := pgtype.Timestamp{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
+	newService := func(t *testing.T) (*CommentService, *storetest.DB) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetCommentByID:" + comment:                    {storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, author), storetest.MustUUID(t, issue), pgtype.UUID{}},
+				"GetCommentByID":                               nil,
+				"GetIssueByID":                                 {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetProjectAccess":                             {storetest.MustUUID(t, author), storetest.MustUUID(t, team)},
+				"CheckTeamMembership:" + team + ":" + member:   {true},
+				"CheckTeamMembership:" + team + ":" + outsider: {false},
+			},
+			Lists: map[string][][]any{
+				"GetIssueComments": {
+					{storetest.MustUUID(t, comment), "Looks great", storetest.MustUUID(t, author), storetest.MustUUID(t, issue), pgtype.UUID{}, pgtype.Timestamp{}, pgtype.Timestamp{}, editedAt},
+					{storetest.MustUUID(t, "99999999-9999-9999-9999-999999999999"), "Agreed", storetest.MustUUID(t, member), storetest.MustUUID(t, issue), pgtype.UUID{}},
+				},
+				"GetCommentRevisions:" + comment: {{storetest.MustUUID(t, revision), storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, author), editedAt}},
+			},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return NewCommentService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}), CacheTTLs{}), db
+	}
+	ctx := context.Background()
+	edit := func(content string) store.UpdateCommentParams {
+		return store.UpdateCommentParams{ID: storetest.MustUUID(t, comment), Content: content}
+	}
+
+	t.Run("Editing keeps the prior content", func(t *testing.T) {
+		svc, db := newService(t)
+		if err := svc.UpdateComment(ctx, edit("Looks great"), author); err != nil {
+			t.Fatalf("UpdateComment failed: %v", err)
+		}
+		if db.Commits != 1 {
+			t.Errorf("Expected the edit to commit once, got %d commits", db.Commits)
+		}
+
+		// The revision is taken from the stored comment before it changes
+		var order []string
+		for _, call := range db.Calls {
+			if call.Name == "CreateCommentRevision" || call.Name == "UpdateComment" {
+				order = append(order, call.Name)
+			}
+		}
+		if len(order) != 2 || order[0] != "CreateCommentRevision" {
+			t.Errorf("Queries ran in order %v, want the revision saved before the update", order)
+		}
+		if args := db.Args("CreateCommentRevision"); len(args) != 1 || args[0][0] != storetest.MustUUID(t, comment) {
+			t.Errorf("CreateCommentRevision ran with %v", args)
+		}
+		if args := db.Args("UpdateComment"); len(args) != 1 || args[0][1] != "Looks great" {
+			t.Errorf("UpdateComment ran with %v", args)
+		}
+	})
+
+	t.Run("Only the author's changes are edits", func(t *testing.T) {
+		svc, db := newService(t)
+		if err := svc.UpdateComment(ctx, edit("Looks bad"), member); !errors.Is(err, ErrNotCommentAuthor) {
+			t.Errorf("Expected ErrNotCommentAuthor, got %v", err)
+		}
+		if err := svc.UpdateComment(ctx, edit("Looks good"), author); err != nil {
+			t.Errorf("Saving unchanged content failed: %v", err)
+		}
+		if n := db.Count("CreateCommentRevision") + db.Count("UpdateComment"); n != 0 {
+			t.Errorf("Expected no edits to be saved, got %d queries", n)
+		}
+	})
+
+	t.Run("Edited comments are flagged", func(t *testing.T) {
+		svc, _ := newService(t)
+		comments, err := svc.GetIssueComments(ctx, issue, member)
+		if err != nil {
+			t.Fatalf("GetIssueComments failed: %v", err)
+		}
+		if len(comments) != 2 {
+			t.Fatalf("Got %d comments, want 2", len(comments))
+		}
+		if !comments[0].Edited || comments[0].EditedAt == nil || *comments[0].EditedAt != "2024-05-01T12:00:00Z" {
+			t.Errorf("Edited comment = %+v, want it flagged", comments[0])
+		}
+		if comments[1].Edited || comments[1].EditedAt != nil {
+			t.Errorf("Unedited comment = %+v, want it unflagged", comments[1])
+		}
+	})
+
+	t.Run("History", func(t *testing.T) {
+		svc, _ := newService(t)
+		history, err := svc.GetCommentHistory(ctx, comment, member)
+		if err != nil {
+			t.Fatalf("GetCommentHistory failed: %v", err)
+		}
+		if len(history) != 1 || history[0].Content != "Looks good" || history[0].EditedBy != author {
+			t.Errorf("History = %+v, want the original content", history)
+		}
+
+		if _, err := svc.GetCommentHistory(ctx, comment, outsider); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("Outsider history = %v, want ErrNotProjectOwner", err)
+		}
+		if _, err := svc.GetCommentHistory(ctx, "99999999-9999-9999-9999-999999999999", member); !errors.Is(err, ErrCommentNotFound) {
+			t.Errorf("Unknown comment history = %v, want ErrCommentNotFound", err)
+		}
+		if _, err := svc.GetCommentHistory(ctx, "not-a-uuid", member); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Malformed comment ID history = %v, want ErrInvalidID", err)
+		}
+	})
+}
+
+// commentDB stores created comments as given, so they come back the way the
+// service saved them
+type commentDB struct {
+	*storetest.DB
+}
+
+func (db *commentDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !strings.Contains(sql, "-- name: CreateComment ") {
+		return db.DB.QueryRow(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+	return storetest.Row([]any{pgtype.UUID{Bytes: [16]byte{7}, Valid: true}, args[0], args[1], args[2], args[3]})
+}
+
+func TestCommentContentSanitized(t *testing.T) {
+	const (
+		author  = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+		comment = "77777777-7777-7777-7777-777777777777"
+	)
+	db := &commentDB{&storetest.DB{Rows: map[string][]any{
+		"GetIssueByID":   {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+		"GetProjectByID": {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, author)},
+		"GetCommentByID": {storetest.MustUUID(t, comment), "Looks good", storetest.MustUUID(t, author), storetest.MustUUID(t, issue), pgtype.UUID{}},
+	}}}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	svc := NewCommentService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}), CacheTTLs{})
+	ctx := context.Background()
+	create := func(content string) (*store.Comment, error) {
+		return svc.CreateComment(ctx, store.CreateCommentParams{Content: content, IssueID: storetest.MustUUID(t, issue)}, author)
+	}
+
+	t.Run("Script is removed on create", func(t *testing.T) {
+		created, err := create(`Looks good<script>alert("hi")</script>`)
+		if err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+		if created.Content != "Looks good" {
+			t.Errorf("Content = %q, want the script removed", created.Content)
+		}
+	})
+
+	t.Run("Script is removed on edit", func(t *testing.T) {
+		err := svc.UpdateComment(ctx, store.UpdateCommentParams{ID: storetest.MustUUID(t, comment), Content: "<b>Fixed</b><script>alert(1)</script>"}, author)
+		if err != nil {
+			t.Fatalf("UpdateComment failed: %v", err)
+		}
+		if args := db.Args("UpdateComment"); len(args) != 1 || args[0][1] != "Fixed" {
+			t.Errorf("UpdateComment ran with %v, want the markup removed", args)
+		}
+	})
+
+	t.Run("Nothing left", func(t *testing.T) {
+		if _, err := create("<script>alert(1)</script>"); !errors.Is(err, ErrInvalidCommentData) {
+			t.Errorf("Expected ErrInvalidCommentData, got %v", err)
+		}
+	})
+}
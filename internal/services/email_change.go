@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInvalidEmailChangeToken is returned when a confirmation link is unknown,
+// expired, already used or superseded by a newer request
+var ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+
+// pendingEmailChange is stored under the confirmation token until it is used
+type pendingEmailChange struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func emailChangeKey(token string) string {
+	return fmt.Sprintf("email_change:%s", token)
+}
+
+// pendingEmailChangeKey points at the user's latest confirmation token, so a
+// new request invalidates the link sent for the previous one
+func pendingEmailChangeKey(userID string) string {
+	return fmt.Sprintf("email_change:user:%s", userID)
+}
+
+// RequestEmailChange starts changing a user's email address. The new address
+// is held as pending and a confirmation link is sent to it; the account keeps
+// its current email until ConfirmEmailChange is called with that link's token.
+func (s *UserService) RequestEmailChange(ctx context.Context, userID, newEmail string) error {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if !validator.Matches(newEmail, validator.EmailRX) {
+		return fmt.Errorf("%w: email must be a valid email address", ErrInvalidUserData)
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userUUID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return fmt.Errorf("%w: email is unchanged", ErrInvalidUserData)
+	}
+
+	if _, err := s.queries.GetUserByEmail(ctx, newEmail); err == nil {
+		return ErrDuplicateEmail
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+
+	pending, err := json.Marshal(pendingEmailChange{UserID: userID, Email: newEmail})
+	if err != nil {
+		return fmt.Errorf("failed to marshal email change: %w", err)
+	}
+
+	token := auth.GenerateSecureToken(32)
+
+	pointerKey := pendingEmailChangeKey(userID)
+	if previous, err := s.cache.Get(ctx, pointerKey); err == nil {
+		if err := s.cache.Del(ctx, emailChangeKey(previous)); err != nil {
+			log.Printf("Failed to discard previous email change token: %v", err)
+		}
+	}
+
+	if err := s.cache.Set(ctx, emailChangeKey(token), string(pending), s.ttls.EmailChange); err != nil {
+		return fmt.Errorf("%w: failed to store email change token: %v", ErrTokenStoreUnavailable, err)
+	}
+	// Without the pointer a newer request couldn't revoke this token, so
+	// don't leave it usable
+	if err := s.cache.Set(ctx, pointerKey, token, s.ttls.EmailChange); err != nil {
+		s.cache.Del(ctx, emailChangeKey(token))
+		return fmt.Errorf("%w: failed to store email change token: %v", ErrTokenStoreUnavailable, err)
+	}
+
+	confirmLink := fmt.Sprintf("https://acme.example.com/confirm-email?token=%s", token)
+
+	if s.emailService != nil {
+		// Sending may retry for several seconds, so don't hold up the request
+		go func() {
+			if err := s.emailService.SendEmailChangeEmail(newEmail, confirmLink); err != nil {
+				log.Printf("Failed to send email change confirmation: %v", err)
+			}
+		}()
+	} else {
+		log.Printf("Email change confirmation link for %s: %s", newEmail, confirmLink)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange applies the pending email change a token was issued for.
+// Each token works once.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) error {
+	key := emailChangeKey(token)
+	data, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrMiss) {
+		return ErrInvalidEmailChangeToken
+	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to read email change token: %v", ErrTokenStoreUnavailable, err)
+	}
+
+	var pending pendingEmailChange
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(pending.UserID); err != nil {
+		return fmt.Errorf("invalid user ID in token: %w", err)
+	}
+
+	// The address may have been registered since the link was sent
+	rows, err := s.queries.UpdateUserEmail(ctx, store.UpdateUserEmailParams{
+		ID:    userUUID,
+		Email: pending.Email,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := s.cache.Del(ctx, key, pendingEmailChangeKey(pending.UserID), fmt.Sprintf("user:%s", pending.UserID)); err != nil {
+		log.Printf("Failed to clear email change state: %v", err)
+	}
+
+	return nil
+}
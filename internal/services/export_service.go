@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/permissions"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportBatchSize is how many issues or comments an export reads at a time
+const exportBatchSize = 500
+
+// ExportService gathers everything stored about a user or team into a single
+// JSON document, for data portability requests and team backups
+type ExportService struct {
+	queries        *store.Queries
+	teamService    *TeamService
+	projectService *ProjectService
+}
+
+func NewExportService(queries *store.Queries, teamService *TeamService, projectService *ProjectService) *ExportService {
+	return &ExportService{
+		queries:        queries,
+		teamService:    teamService,
+		projectService: projectService,
+	}
+}
+
+// Export is a prepared export. The small sections are loaded up front, so
+// access and lookup errors surface before anything is written; issues and
+// comments are read in batches while the document is written.
+type Export struct {
+	sections []exportSection
+}
+
+// exportSection is one top-level field of an export. Either value is set or
+// stream produces the elements of an array.
+type exportSection struct {
+	name   string
+	value  interface{}
+	stream func(ctx context.Context, emit func(interface{}) error) error
+}
+
+// UserExport prepares an export of the user's profile, team memberships,
+// owned projects, the issues they reported or are assigned to, and the
+// comments they wrote
+func (s *ExportService) UserExport(ctx context.Context, userID string) (*Export, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	teams, err := s.teamService.GetUserTeams(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.queries.GetUserProjects(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	return &Export{sections: []exportSection{
+		{name: "exported_at", value: time.Now().UTC().Format(time.RFC3339)},
+		{name: "profile", value: UserProfile{
+			ID:        user.ID,
+			Email:     user.Email,
+			Name:      user.Name.String,
+			Username:  user.Username.String,
+			AvatarURL: user.AvatarUrl.String,
+			Bio:       user.Bio.String,
+			CreatedAt: FormatTimestamp(user.CreatedAt),
+			UpdatedAt: FormatTimestamp(user.UpdatedAt),
+		}},
+		{name: "teams", value: teams},
+		{name: "projects", value: s.projectInfos(projects)},
+		{name: "issues", stream: func(ctx context.Context, emit func(interface{}) error) error {
+			return exportIssues(emit, func(createdAt pgtype.Timestamp, id pgtype.UUID) ([]store.Issue, error) {
+				return s.queries.ExportUserIssues(ctx, store.ExportUserIssuesParams{
+					UserID:          userUUID,
+					Limit:           exportBatchSize,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+				})
+			})
+		}},
+		{name: "comments", stream: func(ctx context.Context, emit func(interface{}) error) error {
+			return exportComments(emit, func(createdAt pgtype.Timestamp, id pgtype.UUID) ([]store.Comment, error) {
+				return s.queries.ExportUserComments(ctx, store.ExportUserCommentsParams{
+					UserID:          userUUID,
+					Limit:           exportBatchSize,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+				})
+			})
+		}},
+	}}, nil
+}
+
+// TeamExport prepares an export of a team, its members, its projects and the
+// issues and comments in them. Only team admins and owners may export a team.
+func (s *ExportService) TeamExport(ctx context.Context, teamID, userID string) (*Export, error) {
+	role, err := s.teamService.requireMemberRole(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !permissions.CanManageTeam(role) {
+		return nil, ErrInsufficientRoles
+	}
+
+	team, err := s.teamService.GetTeamByID(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.teamService.GetTeamMembers(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.queries.GetTeamProjects(ctx, team.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team projects: %w", err)
+	}
+
+	return &Export{sections: []exportSection{
+		{name: "exported_at", value: time.Now().UTC().Format(time.RFC3339)},
+		{name: "team", value: TeamInfo{
+			ID:          team.ID.String(),
+			Name:        team.Name,
+			Description: team.Description.String,
+			AvatarURL:   team.AvatarUrl.String,
+			MemberCount: len(members),
+			CreatedAt:   FormatTimestamp(team.CreatedAt),
+			UpdatedAt:   FormatTimestamp(team.UpdatedAt),
+		}},
+		{name: "members", value: members},
+		{name: "projects", value: s.projectInfos(projects)},
+		{name: "issues", stream: func(ctx context.Context, emit func(interface{}) error) error {
+			return exportIssues(emit, func(createdAt pgtype.Timestamp, id pgtype.UUID) ([]store.Issue, error) {
+				return s.queries.ExportTeamIssues(ctx, store.ExportTeamIssuesParams{
+					TeamID:          team.ID,
+					Limit:           exportBatchSize,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+				})
+			})
+		}},
+		{name: "comments", stream: func(ctx context.Context, emit func(interface{}) error) error {
+			return exportComments(emit, func(createdAt pgtype.Timestamp, id pgtype.UUID) ([]store.Comment, error) {
+				return s.queries.ExportTeamComments(ctx, store.ExportTeamCommentsParams{
+					TeamID:          team.ID,
+					Limit:           exportBatchSize,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+				})
+			})
+		}},
+	}}, nil
+}
+
+// projectInfos converts projects for export
+func (s *ExportService) projectInfos(projects []store.Project) []ProjectInfo {
+	infos := make([]ProjectInfo, 0, len(projects))
+	for _, p := range projects {
+		infos = append(infos, s.projectService.projectToInfo(p))
+	}
+	return infos
+}
+
+// exportIssues emits issues page by page, starting each page after the last
+// issue of the previous one
+func exportIssues(emit func(interface{}) error, page func(pgtype.Timestamp, pgtype.UUID) ([]store.Issue, error)) error {
+	var createdAt pgtype.Timestamp
+	var id pgtype.UUID
+	for {
+		issues, err := page(createdAt, id)
+		if err != nil {
+			return fmt.Errorf("failed to export issues: %w", err)
+		}
+		for _, issue := range issues {
+			if err := emit(issueToInfo(issue)); err != nil {
+				return err
+			}
+		}
+		if len(issues) < exportBatchSize {
+			return nil
+		}
+		last := issues[len(issues)-1]
+		createdAt, id = last.CreatedAt, last.ID
+	}
+}
+
+// exportComments emits comments page by page, like exportIssues
+func exportComments(emit func(interface{}) error, page func(pgtype.Timestamp, pgtype.UUID) ([]store.Comment, error)) error {
+	var createdAt pgtype.Timestamp
+	var id pgtype.UUID
+	for {
+		comments, err := page(createdAt, id)
+		if err != nil {
+			return fmt.Errorf("failed to export comments: %w", err)
+		}
+		for _, c := range comments {
+			info := CommentInfo{
+				ID:        c.ID.String(),
+				Content:   c.Content,
+				UserID:    c.UserID.String(),
+				CreatedAt: FormatTimestamp(c.CreatedAt),
+				UpdatedAt: FormatTimestamp(c.UpdatedAt),
+				Edited:    c.EditedAt.Valid,
+				EditedAt:  FormatTimestamp(c.EditedAt),
+			}
+			if c.IssueID.Valid {
+				info.IssueID = c.IssueID.String()
+			}
+			if c.TaskID.Valid {
+				info.TaskID = c.TaskID.String()
+			}
+			if err := emit(info); err != nil {
+				return err
+			}
+		}
+		if len(comments) < exportBatchSize {
+			return nil
+		}
+		last := comments[len(comments)-1]
+		createdAt, id = last.CreatedAt, last.ID
+	}
+}
+
+// Write streams the export to w as a JSON object. An error part way through
+// leaves the document truncated, so callers should abort the response.
+func (e *Export) Write(ctx context.Context, w io.Writer) error {
+	out := &jsonStreamWriter{w: w}
+	out.write("{")
+	for i, section := range e.sections {
+		if i > 0 {
+			out.write(",")
+		}
+		out.encode(section.name)
+		out.write(":")
+
+		if section.stream == nil {
+			out.encode(section.value)
+			continue
+		}
+
+		out.write("[")
+		n := 0
+		err := section.stream(ctx, func(v interface{}) error {
+			if n > 0 {
+				out.write(",")
+			}
+			n++
+			out.encode(v)
+			return out.err
+		})
+		if err != nil {
+			return err
+		}
+		out.write("]")
+	}
+	out.write("}\n")
+	return out.err
+}
+
+// jsonStreamWriter writes JSON piece by piece, remembering the first error
+type jsonStreamWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (j *jsonStreamWriter) write(s string) {
+	if j.err == nil {
+		_, j.err = io.WriteString(j.w, s)
+	}
+}
+
+func (j *jsonStreamWriter) encode(v interface{}) {
+	if j.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		j.err = err
+		return
+	}
+	_, j.err = j.w.Write(b)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportDocument is the shape of a decoded export
+type exportDocument struct {
+	Profile  map[string]interface{} `json:"profile"`
+	Team     map[string]interface{} `json:"team"`
+	Teams    []TeamInfo             `json:"teams"`
+	Members  []TeamMemberInfo       `json:"members"`
+	Projects []ProjectInfo          `json:"projects"`
+	Issues   []IssueInfo            `json:"issues"`
+	Comments []CommentInfo          `json:"comments"`
+}
+
+func newExportService(t *testing.T, db *storetest.DB) *ExportService {
+	queries := store.New(db)
+	memory := cache.NewMemory()
+	teamService := NewTeamService(queries, memory, CacheTTLs{})
+	return NewExportService(queries, teamService, NewProjectService(queries, memory, teamService, CacheTTLs{}))
+}
+
+// writeExport renders an export and decodes it, failing on invalid JSON
+func writeExport(t *testing.T, export *Export) (exportDocument, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := export.Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var doc exportDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Export is not valid JSON: %v\n%s", err, buf.String())
+	}
+	return doc, buf.String()
+}
+
+func TestUserExport(t *testing.T) {
+	const (
+		me      = "11111111-1111-1111-1111-111111111111"
+		other   = "22222222-2222-2222-2222-222222222222"
+		team    = "33333333-3333-3333-3333-333333333333"
+		project = "44444444-4444-4444-4444-444444444444"
+		issue   = "55555555-5555-5555-5555-555555555555"
+	)
+	issueID := func(i int) pgtype.UUID {
+		return storetest.MustUUID(t, fmt.Sprintf("00000000-0000-0000-0000-%012d", i))
+	}
+
+	// A full first page of issues forces a second, cursor-based read
+	firstPage := make([][]any, exportBatchSize)
+	for i := range firstPage {
+		firstPage[i] = []any{issueID(i), storetest.MustUUID(t, project), fmt.Sprintf("Issue %d", i)}
+	}
+	lastID := issueID(exportBatchSize - 1)
+
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"GetUserByID:" + me:    {storetest.MustUUID(t, me), "me@example.com", pgtype.Text{String: "Me", Valid: true}},
+			"GetUserByID:" + other: {storetest.MustUUID(t, other), "other@example.com"},
+		},
+		Lists: map[string][][]any{
+			"GetUserTeams:" + me:                             {{storetest.MustUUID(t, team), "Platform", pgtype.Text{}, pgtype.Text{}, pgtype.Text{String: "editor", Valid: true}}},
+			"GetUserProjects:" + me:                          {{storetest.MustUUID(t, project), "Roadmap", pgtype.Text{}, storetest.MustUUID(t, me)}},
+			"ExportUserIssues:" + me:                         firstPage,
+			"ExportUserIssues:" + me + ":" + lastID.String(): {{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Last issue"}},
+			"ExportUserComments:" + me:                       {{storetest.MustUUID(t, "66666666-6666-6666-6666-666666666666"), "My comment", storetest.MustUUID(t, me), storetest.MustUUID(t, issue)}},
+			"ExportUserComments:" + other:                    {{storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), "Someone else's secret", storetest.MustUUID(t, other), storetest.MustUUID(t, issue)}},
+		},
+	}
+
+	export, err := newExportService(t, db).UserExport(context.Background(), me)
+	if err != nil {
+		t.Fatalf("UserExport failed: %v", err)
+	}
+	doc, raw := writeExport(t, export)
+
+	if doc.Profile["email"] != "me@example.com" || doc.Profile["name"] != "Me" {
+		t.Errorf("Profile = %v", doc.Profile)
+	}
+	if len(doc.Teams) != 1 || doc.Teams[0].Role != "editor" {
+		t.Errorf("Teams = %+v, want the editor membership", doc.Teams)
+	}
+	if len(doc.Projects) != 1 || doc.Projects[0].Name != "Roadmap" {
+		t.Errorf("Projects = %+v", doc.Projects)
+	}
+	if len(doc.Issues) != exportBatchSize+1 || doc.Issues[exportBatchSize].Title != "Last issue" {
+		t.Errorf("Got %d issues, want %d ending with the second page", len(doc.Issues), exportBatchSize+1)
+	}
+	if len(doc.Comments) != 1 || doc.Comments[0].Content != "My comment" || doc.Comments[0].IssueID != issue {
+		t.Errorf("Comments = %+v", doc.Comments)
+	}
+
+	if strings.Contains(raw, "other@example.com") || strings.Contains(raw, "secret") {
+		t.Error("Export includes another user's data")
+	}
+	for _, name := range []string{"ExportUserIssues", "ExportUserComments", "GetUserProjects", "GetUserTeams"} {
+		for _, args := range db.Args(name) {
+			if args[0] != storetest.MustUUID(t, me) {
+				t.Errorf("%s ran for %v, want only the exporting user", name, args[0])
+			}
+		}
+	}
+	if n := db.Count("ExportUserIssues"); n != 2 {
+		t.Errorf("Expected two pages of issues, got %d reads", n)
+	}
+
+	t.Run("Unknown user", func(t *testing.T) {
+		db := &storetest.DB{Rows: map[string][]any{"GetUserByID": nil}}
+		if _, err := newExportService(t, db).UserExport(context.Background(), me); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
+func TestTeamExport(t *testing.T) {
+	const (
+		admin   = "11111111-1111-1111-1111-111111111111"
+		viewer  = "22222222-2222-2222-2222-222222222222"
+		team    = "33333333-3333-3333-3333-333333333333"
+		project = "44444444-4444-4444-4444-444444444444"
+		issue   = "55555555-5555-5555-5555-555555555555"
+	)
+	role := func(r string) pgtype.Text { return pgtype.Text{String: r, Valid: true} }
+
+	newDB := func() *storetest.DB {
+		return &storetest.DB{
+			Rows: map[string][]any{
+				"GetTeamMember:" + team + ":" + admin:  {storetest.MustUUID(t, team), storetest.MustUUID(t, admin), role("admin")},
+				"GetTeamMember:" + team + ":" + viewer: {storetest.MustUUID(t, team), storetest.MustUUID(t, viewer), role("viewer")},
+				"GetTeamByID:" + team:                  {storetest.MustUUID(t, team), "Platform"},
+				"CheckTeamMembership":                  {true},
+			},
+			Lists: map[string][][]any{
+				"GetTeamMembers:" + team: {
+					{storetest.MustUUID(t, admin), "admin@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, role("admin")},
+					{storetest.MustUUID(t, viewer), "viewer@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, role("viewer")},
+				},
+				"GetTeamProjects:" + team:    {{storetest.MustUUID(t, project), "Roadmap", pgtype.Text{}, storetest.MustUUID(t, admin), storetest.MustUUID(t, team)}},
+				"ExportTeamIssues:" + team:   {{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Team issue"}},
+				"ExportTeamComments:" + team: {{storetest.MustUUID(t, "66666666-6666-6666-6666-666666666666"), "Looks good", storetest.MustUUID(t, viewer), storetest.MustUUID(t, issue)}},
+			},
+		}
+	}
+
+	t.Run("Admin exports the team", func(t *testing.T) {
+		export, err := newExportService(t, newDB()).TeamExport(context.Background(), team, admin)
+		if err != nil {
+			t.Fatalf("TeamExport failed: %v", err)
+		}
+		doc, _ := writeExport(t, export)
+
+		if doc.Team["name"] != "Platform" || doc.Team["member_count"] != float64(2) {
+			t.Errorf("Team = %v", doc.Team)
+		}
+		if len(doc.Members) != 2 || len(doc.Projects) != 1 {
+			t.Errorf("Got %d members and %d projects, want 2 and 1", len(doc.Members), len(doc.Projects))
+		}
+		if len(doc.Issues) != 1 || doc.Issues[0].Title != "Team issue" {
+			t.Errorf("Issues = %+v", doc.Issues)
+		}
+		if len(doc.Comments) != 1 || doc.Comments[0].UserID != viewer {
+			t.Errorf("Comments = %+v", doc.Comments)
+		}
+	})
+
+	t.Run("Viewers cannot export", func(t *testing.T) {
+		db := newDB()
+		if _, err := newExportService(t, db).TeamExport(context.Background(), team, viewer); !errors.Is(err, ErrInsufficientRoles) {
+			t.Errorf("Expected ErrInsufficientRoles, got %v", err)
+		}
+		if n := db.Count("ExportTeamIssues") + db.Count("GetTeamMembers"); n != 0 {
+			t.Errorf("Expected nothing to be read for a viewer, got %d queries", n)
+		}
+	})
+
+	t.Run("Non-members cannot export", func(t *testing.T) {
+		outsider := "99999999-9999-9999-9999-999999999999"
+		db := newDB()
+		db.Rows["GetTeamMember:"+team+":"+outsider] = nil
+		if _, err := newExportService(t, db).TeamExport(context.Background(), team, outsider); !errors.Is(err, ErrNotMember) {
+			t.Errorf("Expected ErrNotMember, got %v", err)
+		}
+	})
+}
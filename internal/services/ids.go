@@ -0,0 +1,14 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidID is returned when an ID passed to a service is not a UUID
+var ErrInvalidID = errors.New("invalid ID")
+
+// invalidID wraps the error from scanning the named ID so it matches ErrInvalidID
+func invalidID(name string, err error) error {
+	return fmt.Errorf("%w (%s): %v", ErrInvalidID, name, err)
+}
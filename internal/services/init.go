@@ -1,47 +1,118 @@
 package services
 
 import (
+	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
 	"github.com/Bethel-nz/tickit/internal/email"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/Bethel-nz/tickit/internal/usage"
 	"github.com/go-redis/redis/v8"
 )
 
 // Services holds all the service instances
 type Services struct {
-	UserService    *UserService
-	ProjectService *ProjectService
-	IssueService   *IssueService
-	CommentService *CommentService
-	SearchService  *SearchService
-	TeamService    *TeamService
+	UserService         *UserService
+	ProjectService      *ProjectService
+	IssueService        *IssueService
+	TaskService         *TaskService
+	CommentService      *CommentService
+	NotificationService *NotificationService
+	SearchService       *SearchService
+	TeamService         *TeamService
+	ExportService       *ExportService
+	AdminService        *AdminService
+	AuditService        *AuditService
+	WebhookService      *WebhookService
+	APIKeyService       *APIKeyService
+	UsageTracker        *usage.Tracker
+	TokenDenylist       *auth.Denylist
 }
 
-// InitServices initializes all services with their dependencies
-func InitServices(queries *store.Queries, cache *redis.Client, emailService *email.EmailService) *Services {
+// InitServices initializes all services with their dependencies. Cached
+// values expire after ttls.
+func InitServices(queries *store.Queries, redisClient *redis.Client, emailService *email.EmailService, ttls CacheTTLs) *Services {
+	// Services only need a key-value cache; usage tracking and the token
+	// denylist use Redis directly
+	serviceCache := cache.NewRedis(redisClient)
+
+	// Notifications go to the in-app inbox, and by email when it is configured
+	notificationService := NewNotificationService(queries)
+	notifier := Notifiers{notificationService}
+	if emailService != nil {
+		notifier = append(notifier, NewEmailNotifier(queries, emailService))
+	}
+
+	// Sensitive actions across services are recorded in the audit log
+	auditService := NewAuditService(queries)
+
 	// Initialize team service first as it's a dependency for project service
-	teamService := NewTeamService(queries, cache)
+	teamService := NewTeamService(queries, serviceCache, ttls)
+	teamService.SetNotifier(notifier)
+	teamService.SetAuditLog(auditService)
 
 	// Initialize project service with team service dependency
-	projectService := NewProjectService(queries, cache, teamService)
+	projectService := NewProjectService(queries, serviceCache, teamService, ttls)
+	projectService.SetAuditLog(auditService)
+
+	// Project webhooks hear about issues and comments as they change
+	webhookService := NewWebhookService(queries, projectService)
 
 	// Initialize issue service with project service dependency
-	issueService := NewIssueService(queries, cache, projectService)
+	issueService := NewIssueService(queries, serviceCache, projectService)
+	issueService.SetNotifier(notifier)
+	issueService.SetWebhooks(webhookService)
+
+	// Initialize task service with project service dependency
+	taskService := NewTaskService(queries, projectService)
 
 	// Initialize comment service with project service dependency
-	commentService := NewCommentService(queries, cache, projectService)
+	commentService := NewCommentService(queries, serviceCache, projectService, ttls)
+	commentService.SetNotifier(notifier)
+	commentService.SetWebhooks(webhookService)
 
 	// Initialize search service
-	searchService := NewSearchService(queries, cache)
+	searchService := NewSearchService(queries, serviceCache)
 
 	// Initialize user service
-	userService := NewUserService(queries, cache, emailService)
+	userService := NewUserService(queries, serviceCache, emailService, ttls)
+	userService.SetAuditLog(auditService)
+
+	// Initialize export service, which reads across teams and projects
+	exportService := NewExportService(queries, teamService, projectService)
+
+	// Initialize admin service, which shares the user service's account status cache
+	adminService := NewAdminService(queries, serviceCache)
+	adminService.SetAuditLog(auditService)
+
+	// API keys let machine clients act as a user
+	apiKeyService := NewAPIKeyService(queries)
+	apiKeyService.SetAuditLog(auditService)
 
 	return &Services{
-		UserService:    userService,
-		ProjectService: projectService,
-		IssueService:   issueService,
-		CommentService: commentService,
-		SearchService:  searchService,
-		TeamService:    teamService,
+		UserService:         userService,
+		ProjectService:      projectService,
+		IssueService:        issueService,
+		TaskService:         taskService,
+		CommentService:      commentService,
+		NotificationService: notificationService,
+		SearchService:       searchService,
+		TeamService:         teamService,
+		ExportService:       exportService,
+		AdminService:        adminService,
+		AuditService:        auditService,
+		WebhookService:      webhookService,
+		APIKeyService:       apiKeyService,
+		UsageTracker:        usage.NewTracker(redisClient),
+		TokenDenylist:       auth.NewDenylist(redisClient),
 	}
 }
+
+// SetAttachmentStorage sets where attachment contents are stored and the
+// largest file accepted. Deleting projects, directly or with an account,
+// removes their files from it too.
+func (s *Services) SetAttachmentStorage(files storage.Storage, maxBytes int64) {
+	s.IssueService.SetAttachmentStorage(files, maxBytes)
+	s.ProjectService.attachments = files
+	s.UserService.attachments = files
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Attachment errors
+var (
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrAttachmentTooLarge    = errors.New("attachment too large")
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type")
+	ErrAttachmentsDisabled   = errors.New("attachments are not configured")
+)
+
+// DefaultAttachmentMaxBytes is the largest attachment accepted when
+// SetAttachmentStorage is given no limit
+const DefaultAttachmentMaxBytes = 10 << 20
+
+// maxFilenameLength matches the attachments.filename column
+const maxFilenameLength = 255
+
+// attachmentTypes are the file types that can be attached, as sniffed from
+// the file contents: screenshots, logs and documents
+var attachmentTypes = map[string]bool{
+	"image/png":          true,
+	"image/jpeg":         true,
+	"image/gif":          true,
+	"image/webp":         true,
+	"text/plain":         true,
+	"application/pdf":    true,
+	"application/zip":    true,
+	"application/x-gzip": true,
+}
+
+// AttachmentInfo represents an attachment returned to clients
+type AttachmentInfo struct {
+	ID          string  `json:"id"`
+	IssueID     string  `json:"issue_id"`
+	Filename    string  `json:"filename"`
+	ContentType string  `json:"content_type"`
+	Size        int64   `json:"size"`
+	UploaderID  string  `json:"uploader_id,omitempty"`
+	CreatedAt   *string `json:"created_at"`
+}
+
+// AttachmentUpload is a file to attach to an issue
+type AttachmentUpload struct {
+	Filename string
+	Size     int64 // Size declared by the client; the stored size is counted
+	Content  io.Reader
+}
+
+// SetAttachmentStorage sets where attachment contents are stored and the
+// largest file accepted. Attachments are disabled until it is called.
+func (s *IssueService) SetAttachmentStorage(files storage.Storage, maxBytes int64) {
+	s.attachments = files
+	s.attachmentMaxBytes = maxBytes
+	if maxBytes <= 0 {
+		s.attachmentMaxBytes = DefaultAttachmentMaxBytes
+	}
+}
+
+// AttachmentMaxBytes is the largest attachment accepted
+func (s *IssueService) AttachmentMaxBytes() int64 {
+	return s.attachmentMaxBytes
+}
+
+// AddAttachment stores a file and attaches it to an issue. The type is
+// sniffed from the contents rather than trusted from the client.
+func (s *IssueService) AddAttachment(ctx context.Context, issueID, userID string, upload AttachmentUpload) (*AttachmentInfo, error) {
+	if s.attachments == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	if upload.Size > s.attachmentMaxBytes {
+		return nil, fmt.Errorf("%w: must be at most %d bytes", ErrAttachmentTooLarge, s.attachmentMaxBytes)
+	}
+	filename := cleanFilename(upload.Filename)
+	if filename == "" {
+		return nil, fmt.Errorf("%w: attachment filename is required", ErrInvalidIssueData)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: attachment is empty", ErrInvalidIssueData)
+	}
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if mediaType, _, _ := mime.ParseMediaType(contentType); !attachmentTypes[mediaType] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAttachment, contentType)
+	}
+
+	key, err := attachmentKey(issue.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count what is actually stored, and never store more than the limit
+	// whatever size the client declared
+	content := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), upload.Content), s.attachmentMaxBytes+1)}
+	if _, err := s.attachments.Put(ctx, key, content, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if content.n > s.attachmentMaxBytes {
+		s.removeAttachmentFile(ctx, key)
+		return nil, fmt.Errorf("%w: must be at most %d bytes", ErrAttachmentTooLarge, s.attachmentMaxBytes)
+	}
+
+	attachment, err := s.queries.CreateAttachment(ctx, store.CreateAttachmentParams{
+		IssueID:     issue.ID,
+		UploaderID:  userUUID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   content.n,
+		StorageKey:  key,
+	})
+	if err != nil {
+		s.removeAttachmentFile(ctx, key)
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	info := attachmentToInfo(attachment)
+	return &info, nil
+}
+
+// ListAttachments lists the files attached to an issue, oldest first
+func (s *IssueService) ListAttachments(ctx context.Context, issueID, userID string) ([]AttachmentInfo, error) {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.queries.GetIssueAttachments(ctx, issue.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	result := make([]AttachmentInfo, 0, len(attachments))
+	for _, a := range attachments {
+		result = append(result, attachmentToInfo(a))
+	}
+	return result, nil
+}
+
+// OpenAttachment returns an attachment and its contents. The caller must
+// close the contents.
+func (s *IssueService) OpenAttachment(ctx context.Context, issueID, attachmentID, userID string) (*AttachmentInfo, io.ReadCloser, error) {
+	if s.attachments == nil {
+		return nil, nil, ErrAttachmentsDisabled
+	}
+
+	attachment, err := s.issueAttachment(ctx, issueID, attachmentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.attachments.Open(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+
+	info := attachmentToInfo(*attachment)
+	return &info, content, nil
+}
+
+// DeleteAttachment removes an attachment and its stored file
+func (s *IssueService) DeleteAttachment(ctx context.Context, issueID, attachmentID, userID string) error {
+	if s.attachments == nil {
+		return ErrAttachmentsDisabled
+	}
+
+	attachment, err := s.issueAttachment(ctx, issueID, attachmentID, userID)
+	if err != nil {
+		return err
+	}
+
+	// Drop the row first: a leftover file is invisible, a row without its
+	// file is a broken download
+	if err := s.queries.DeleteAttachment(ctx, attachment.ID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	s.removeAttachmentFile(ctx, attachment.StorageKey)
+	return nil
+}
+
+// issueAttachment loads an attachment, checking the user can access the issue
+// and that the attachment belongs to it
+func (s *IssueService) issueAttachment(ctx context.Context, issueID, attachmentID, userID string) (*store.Attachment, error) {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachmentUUID pgtype.UUID
+	if err := attachmentUUID.Scan(attachmentID); err != nil {
+		return nil, fmt.Errorf("%w: invalid attachment ID", ErrInvalidIssueData)
+	}
+
+	attachment, err := s.queries.GetAttachmentByID(ctx, attachmentUUID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && attachment.IssueID != issue.ID) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return &attachment, nil
+}
+
+// removeAttachmentFile deletes a stored file. Failures only leave an
+// unreferenced file behind, so they are logged rather than returned.
+func (s *IssueService) removeAttachmentFile(ctx context.Context, key string) {
+	removeAttachmentFiles(ctx, s.attachments, []string{key})
+}
+
+// removeAttachmentFiles deletes stored files once the attachments pointing
+// at them are gone, as when a whole project is deleted. It does nothing when
+// attachments aren't configured.
+func removeAttachmentFiles(ctx context.Context, files storage.Storage, keys []string) {
+	if files == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := files.Delete(ctx, key); err != nil {
+			log.Printf("Failed to remove attachment file %s: %v", key, err)
+		}
+	}
+}
+
+// attachmentKey picks an unguessable storage key for a new file on an issue
+func attachmentKey(issueID pgtype.UUID) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", fmt.Errorf("failed to name attachment: %w", err)
+	}
+	return fmt.Sprintf("attachments/%s/%s", issueID.String(), hex.EncodeToString(name)), nil
+}
+
+// cleanFilename keeps the last element of a client-supplied file name, drops
+// control characters and trims it to fit the column
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func attachmentToInfo(a store.Attachment) AttachmentInfo {
+	return AttachmentInfo{
+		ID:          a.ID.String(),
+		IssueID:     a.IssueID.String(),
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.SizeBytes,
+		UploaderID:  a.UploaderID.String(),
+		CreatedAt:   FormatTimestamp(a.CreatedAt),
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIssueAttachments(t *testing.T) {
+	const (
+		owner      = "11111111-1111-1111-1111-111111111111"
+		outsider   = "22222222-2222-2222-2222-222222222222"
+		project    = "55555555-5555-5555-5555-555555555555"
+		issue      = "66666666-6666-6666-6666-666666666666"
+		otherIssue = "77777777-7777-7777-7777-777777777777"
+		attachment = "88888888-8888-8888-8888-888888888888"
+	)
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	storedKey := "attachments/" + issue + "/screenshot"
+
+	newService := func(t *testing.T) (*IssueService, *storetest.DB, *storage.LocalStorage, string) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID:" + issue: {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetProjectAccess":      {storetest.MustUUID(t, owner), pgtype.UUID{}},
+				"CreateAttachment":      {storetest.MustUUID(t, attachment), storetest.MustUUID(t, issue), pgtype.UUID{}, storetest.MustUUID(t, owner), "screenshot.png", "image/png", int64(len(png))},
+				"GetAttachmentByID:" + attachment: {storetest.MustUUID(t, attachment), storetest.MustUUID(t, issue), pgtype.UUID{}, storetest.MustUUID(t, owner),
+					"screenshot.png", "image/png", int64(len(png)), storedKey},
+			},
+			Lists: map[string][][]any{
+				"GetIssueAttachments:" + issue: {{storetest.MustUUID(t, attachment), storetest.MustUUID(t, issue), pgtype.UUID{}, storetest.MustUUID(t, owner),
+					"screenshot.png", "image/png", int64(len(png)), storedKey}},
+			},
+		}
+		dir := t.TempDir()
+		files, err := storage.NewLocalStorage(dir, "")
+		if err != nil {
+			t.Fatalf("NewLocalStorage failed: %v", err)
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		svc := NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+		svc.SetAttachmentStorage(files, 1024)
+		return svc, db, files, dir
+	}
+	// storedFiles lists the files under the storage directory
+	storedFiles := func(t *testing.T, dir string) []string {
+		t.Helper()
+		var names []string
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				names = append(names, path)
+			}
+			return err
+		})
+		return names
+	}
+	upload := func(name string, content []byte) AttachmentUpload {
+		return AttachmentUpload{Filename: name, Size: int64(len(content)), Content: bytes.NewReader(content)}
+	}
+	ctx := context.Background()
+
+	t.Run("Upload stores the file and records it", func(t *testing.T) {
+		svc, db, files, _ := newService(t)
+		info, err := svc.AddAttachment(ctx, issue, owner, upload(`C:\Users\me\screenshot.png`, png))
+		if err != nil {
+			t.Fatalf("AddAttachment failed: %v", err)
+		}
+		if info.ID != attachment {
+			t.Errorf("Got attachment %+v", info)
+		}
+
+		args := db.Args("CreateAttachment")
+		if len(args) != 1 {
+			t.Fatalf("Expected one CreateAttachment call, got %d", len(args))
+		}
+		if args[0][3] != "screenshot.png" || args[0][4] != "image/png" || args[0][5] != int64(len(png)) {
+			t.Errorf("CreateAttachment ran with %v, want the cleaned name, sniffed type and size", args[0])
+		}
+		key := args[0][6].(string)
+		if !strings.HasPrefix(key, "attachments/"+issue+"/") {
+			t.Errorf("Stored under %q, want a key under the issue", key)
+		}
+		stored, err := files.Open(ctx, key)
+		if err != nil {
+			t.Fatalf("File was not stored: %v", err)
+		}
+		defer stored.Close()
+		if got, _ := io.ReadAll(stored); !bytes.Equal(got, png) {
+			t.Error("Stored file does not match the upload")
+		}
+	})
+
+	t.Run("Rejected uploads store nothing", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			user   string
+			upload AttachmentUpload
+			want   error
+		}{
+			{"Outsider", outsider, upload("screenshot.png", png), ErrNotProjectOwner},
+			{"Unsupported type", owner, upload("page.png", []byte("<html><script>alert(1)</script></html>")), ErrUnsupportedAttachment},
+			{"Declared too large", owner, upload("big.png", bytes.Repeat(png, 20)), ErrAttachmentTooLarge},
+			{"Larger than declared", owner, AttachmentUpload{Filename: "big.png", Size: 10, Content: bytes.NewReader(bytes.Repeat(png, 20))}, ErrAttachmentTooLarge},
+			{"Empty", owner, upload("empty.txt", nil), ErrInvalidIssueData},
+			{"No filename", owner, upload("", png), ErrInvalidIssueData},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				svc, db, _, dir := newService(t)
+				if _, err := svc.AddAttachment(ctx, issue, tt.user, tt.upload); !errors.Is(err, tt.want) {
+					t.Errorf("Expected %v, got %v", tt.want, err)
+				}
+				if n := db.Count("CreateAttachment"); n != 0 {
+					t.Errorf("Attachment was recorded %d times", n)
+				}
+				if files := storedFiles(t, dir); len(files) != 0 {
+					t.Errorf("Files were left in storage: %v", files)
+				}
+			})
+		}
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		svc, _, files, _ := newService(t)
+		if _, err := files.Put(ctx, storedKey, bytes.NewReader(png), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		info, content, err := svc.OpenAttachment(ctx, issue, attachment, owner)
+		if err != nil {
+			t.Fatalf("OpenAttachment failed: %v", err)
+		}
+		defer content.Close()
+		if got, _ := io.ReadAll(content); !bytes.Equal(got, png) || info.Filename != "screenshot.png" {
+			t.Errorf("Downloaded %q (%d bytes)", info.Filename, len(got))
+		}
+
+		if _, _, err := svc.OpenAttachment(ctx, issue, attachment, outsider); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("Outsider download = %v, want ErrNotProjectOwner", err)
+		}
+	})
+
+	t.Run("Attachments are scoped to their issue", func(t *testing.T) {
+		svc, db, _, _ := newService(t)
+		db.Rows["GetIssueByID:"+otherIssue] = []any{storetest.MustUUID(t, otherIssue), storetest.MustUUID(t, project), "Another issue"}
+		if _, _, err := svc.OpenAttachment(ctx, otherIssue, attachment, owner); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+		}
+		if err := svc.DeleteAttachment(ctx, otherIssue, attachment, owner); !errors.Is(err, ErrAttachmentNotFound) {
+			t.Errorf("Expected ErrAttachmentNotFound, got %v", err)
+		}
+		if n := db.Count("DeleteAttachment"); n != 0 {
+			t.Errorf("Attachment was deleted through another issue")
+		}
+	})
+
+	t.Run("Delete removes the row and the file", func(t *testing.T) {
+		svc, db, files, dir := newService(t)
+		if _, err := files.Put(ctx, storedKey, bytes.NewReader(png), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := svc.DeleteAttachment(ctx, issue, attachment, outsider); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("Outsider delete = %v, want ErrNotProjectOwner", err)
+		}
+		if err := svc.DeleteAttachment(ctx, issue, attachment, owner); err != nil {
+			t.Fatalf("DeleteAttachment failed: %v", err)
+		}
+		if args := db.Args("DeleteAttachment"); len(args) != 1 || args[0][0] != storetest.MustUUID(t, attachment) {
+			t.Errorf("DeleteAttachment ran with %v", args)
+		}
+		if files := storedFiles(t, dir); len(files) != 0 {
+			t.Errorf("Files were left in storage: %v", files)
+		}
+	})
+
+	t.Run("Deleting the issue removes its files", func(t *testing.T) {
+		svc, _, files, dir := newService(t)
+		if _, err := files.Put(ctx, storedKey, bytes.NewReader(png), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.DeleteIssue(ctx, issue, owner); err != nil {
+			t.Fatalf("DeleteIssue failed: %v", err)
+		}
+		if files := storedFiles(t, dir); len(files) != 0 {
+			t.Errorf("Files were left in storage: %v", files)
+		}
+	})
+}
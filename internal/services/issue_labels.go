@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Label errors
+var (
+	ErrLabelNotFound  = errors.New("label not found")
+	ErrDuplicateLabel = errors.New("label already exists")
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint failure
+const uniqueViolation = "23505"
+
+// labelColor matches #rrggbb colours
+var labelColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// LabelInfo represents a label returned to clients
+type LabelInfo struct {
+	ID        string  `json:"id"`
+	ProjectID string  `json:"project_id"`
+	Name      string  `json:"name"`
+	Color     string  `json:"color,omitempty"`
+	CreatedAt *string `json:"created_at"`
+}
+
+// NormalizeLabel returns the canonical form of a label name. Names are
+// compared case-insensitively, so "Bug" and "bug" are the same label.
+func NormalizeLabel(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CreateLabel creates a label in a project
+func (s *IssueService) CreateLabel(ctx context.Context, projectID, name, color, userID string) (*LabelInfo, error) {
+	project, err := s.projectService.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name = NormalizeLabel(name)
+	if name == "" || len(name) > 50 {
+		return nil, fmt.Errorf("%w: label name must be 1-50 characters", ErrInvalidIssueData)
+	}
+	if color != "" && !labelColor.MatchString(color) {
+		return nil, fmt.Errorf("%w: label color must be a hex colour like #d73a4a", ErrInvalidIssueData)
+	}
+
+	label, err := s.queries.CreateLabel(ctx, store.CreateLabelParams{
+		ProjectID: project.ID,
+		Name:      name,
+		Color:     pgtype.Text{String: color, Valid: color != ""},
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateLabel, name)
+		}
+		return nil, fmt.Errorf("failed to create label: %w", err)
+	}
+
+	info := labelToInfo(label)
+	return &info, nil
+}
+
+// GetProjectLabels lists the labels defined in a project
+func (s *IssueService) GetProjectLabels(ctx context.Context, projectID, userID string) ([]LabelInfo, error) {
+	project, err := s.projectService.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := s.queries.GetProjectLabels(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project labels: %w", err)
+	}
+	return labelsToInfo(labels), nil
+}
+
+// DeleteLabel deletes a label from a project, removing it from every issue
+func (s *IssueService) DeleteLabel(ctx context.Context, projectID, labelID, userID string) error {
+	project, err := s.projectService.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	label, err := s.projectLabel(ctx, project.ID, labelID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.queries.DeleteLabel(ctx, label.ID); err != nil {
+		return fmt.Errorf("failed to delete label: %w", err)
+	}
+	return nil
+}
+
+// AddLabel attaches a label to an issue. The label must belong to the
+// issue's project. Adding a label twice is a no-op.
+func (s *IssueService) AddLabel(ctx context.Context, issueID, labelID, userID string) error {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return err
+	}
+
+	label, err := s.projectLabel(ctx, issue.ProjectID, labelID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.queries.AddIssueLabel(ctx, store.AddIssueLabelParams{
+		IssueID: issue.ID,
+		LabelID: label.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to add label: %w", err)
+	}
+	return nil
+}
+
+// RemoveLabel detaches a label from an issue
+func (s *IssueService) RemoveLabel(ctx context.Context, issueID, labelID, userID string) error {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return err
+	}
+
+	label, err := s.projectLabel(ctx, issue.ProjectID, labelID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.queries.RemoveIssueLabel(ctx, store.RemoveIssueLabelParams{
+		IssueID: issue.ID,
+		LabelID: label.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to remove label: %w", err)
+	}
+	return nil
+}
+
+// GetIssueLabels lists the labels attached to an issue
+func (s *IssueService) GetIssueLabels(ctx context.Context, issueID, userID string) ([]LabelInfo, error) {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := s.queries.GetIssueLabels(ctx, issue.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue labels: %w", err)
+	}
+	return labelsToInfo(labels), nil
+}
+
+// GetIssuesByLabels lists a project's issues carrying the named labels.
+// With matchAll an issue needs every label, otherwise any one of them.
+func (s *IssueService) GetIssuesByLabels(ctx context.Context, projectID string, labels []string, matchAll bool, userID string) ([]IssueInfo, error) {
+	project, err := s.projectService.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := normalizeLabels(labels)
+	if len(names) == 0 {
+		return s.GetProjectIssues(ctx, projectID, userID)
+	}
+
+	var issues []store.Issue
+	if matchAll {
+		issues, err = s.queries.GetProjectIssuesWithAllLabels(ctx, store.GetProjectIssuesWithAllLabelsParams{
+			ProjectID: project.ID,
+			Column2:   names,
+		})
+	} else {
+		issues, err = s.queries.GetProjectIssuesWithAnyLabel(ctx, store.GetProjectIssuesWithAnyLabelParams{
+			ProjectID: project.ID,
+			Column2:   names,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issues by label: %w", err)
+	}
+
+	result := make([]IssueInfo, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, issueToInfo(issue))
+	}
+	return result, nil
+}
+
+// accessibleIssue loads an issue and checks the user can access its project
+func (s *IssueService) accessibleIssue(ctx context.Context, issueID, userID string) (*store.Issue, error) {
+	var issueUUID pgtype.UUID
+	if err := issueUUID.Scan(issueID); err != nil {
+		return nil, invalidID("issue ID", err)
+	}
+
+	issue, err := s.queries.GetIssueByID(ctx, issueUUID)
+	if err != nil {
+		return nil, ErrIssueNotFound
+	}
+
+	if err := s.projectService.requireProjectAccess(ctx, issue.ProjectID.String(), userID); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// projectLabel loads a label and checks it belongs to the project
+func (s *IssueService) projectLabel(ctx context.Context, projectID pgtype.UUID, labelID string) (*store.Label, error) {
+	var labelUUID pgtype.UUID
+	if err := labelUUID.Scan(labelID); err != nil {
+		return nil, fmt.Errorf("%w: invalid label ID", ErrInvalidIssueData)
+	}
+
+	label, err := s.queries.GetLabelByID(ctx, labelUUID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && label.ProjectID != projectID) {
+		return nil, ErrLabelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label: %w", err)
+	}
+	return &label, nil
+}
+
+// normalizeLabels canonicalises label names and drops blanks and duplicates
+func normalizeLabels(labels []string) []string {
+	seen := make(map[string]bool, len(labels))
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		name := NormalizeLabel(label)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func labelToInfo(label store.Label) LabelInfo {
+	return LabelInfo{
+		ID:        label.ID.String(),
+		ProjectID: label.ProjectID.String(),
+		Name:      label.Name,
+		Color:     label.Color.String,
+		CreatedAt: FormatTimestamp(label.CreatedAt),
+	}
+}
+
+func labelsToInfo(labels []store.Label) []LabelInfo {
+	result := make([]LabelInfo, 0, len(labels))
+	for _, label := range labels {
+		result = append(result, labelToInfo(label))
+	}
+	return result
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"time"
 
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bethel-nz/tickit/internal/sanitize"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -17,18 +22,46 @@ var (
 	ErrInvalidIssueData = errors.New("invalid issue data")
 )
 
+// Issue priorities, from least to most pressing. Issues may also have none.
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// IsValidIssuePriority reports whether priority is a known issue priority
+func IsValidIssuePriority(priority string) bool {
+	switch priority {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
 // IssueInfo represents issue information returned to clients
 type IssueInfo struct {
 	ID          string     `json:"id"`
+	Number      int32      `json:"number"` // Counts up from 1 within the project
 	ProjectID   string     `json:"project_id"`
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
 	Status      string     `json:"status"`
+	Priority    string     `json:"priority,omitempty"`
 	ReporterID  string     `json:"reporter_id"`
 	AssigneeID  string     `json:"assignee_id,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
-	CreatedAt   string     `json:"created_at"`
-	UpdatedAt   string     `json:"updated_at,omitempty"`
+	Overdue     bool       `json:"overdue"` // Past its due date and not closed
+	CreatedAt   *string    `json:"created_at"`
+	UpdatedAt   *string    `json:"updated_at"`
+	Version     int32      `json:"version,omitempty"`
+}
+
+// AssignedIssueInfo is an issue assigned to the user, with the name of its
+// project for context
+type AssignedIssueInfo struct {
+	IssueInfo
+	ProjectName string `json:"project_name"`
 }
 
 // IssueUpdates contains fields that can be updated for an issue
@@ -36,35 +69,63 @@ type IssueUpdates struct {
 	Title       string
 	Description string
 	Status      string
+	Priority    string
 	AssigneeID  string
 	DueDate     *time.Time
+	Version     int32 // Version the client last saw; 0 updates whatever is current
+}
+
+// IssueFilter narrows a project's issues. Empty fields don't filter.
+type IssueFilter struct {
+	Status         string
+	Priority       string
+	DueAfter       time.Time // Exclusive
+	DueBefore      time.Time // Exclusive
+	Overdue        bool      // Only issues past due that aren't closed
+	SortByPriority bool      // Most urgent first, rather than newest first
 }
 
 type IssueService struct {
 	queries        *store.Queries
-	cache          *redis.Client
+	cache          cache.Cache
 	projectService *ProjectService
+	notifier       Notifier
+	webhooks       *WebhookService
+
+	attachments        storage.Storage // Where attachment contents are kept; nil disables attachments
+	attachmentMaxBytes int64
 }
 
-func NewIssueService(queries *store.Queries, cache *redis.Client, projectService *ProjectService) *IssueService {
+func NewIssueService(queries *store.Queries, cache cache.Cache, projectService *ProjectService) *IssueService {
 	return &IssueService{
-		queries:        queries,
-		cache:          cache,
-		projectService: projectService,
+		queries:            queries,
+		cache:              cache,
+		projectService:     projectService,
+		attachmentMaxBytes: DefaultAttachmentMaxBytes,
 	}
 }
 
+// SetNotifier sets where assignment notifications are sent
+func (s *IssueService) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetWebhooks sets where issue events are sent. Without it, projects'
+// webhooks don't hear about issues.
+func (s *IssueService) SetWebhooks(webhooks *WebhookService) {
+	s.webhooks = webhooks
+}
+
 // GetProjectIssues retrieves all issues for a project
 func (s *IssueService) GetProjectIssues(ctx context.Context, projectID string, userID string) ([]IssueInfo, error) {
 	// Verify project access
-	_, err := s.projectService.GetProjectByID(ctx, projectID, userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, projectID, userID); err != nil {
 		return nil, err
 	}
 
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return nil, fmt.Errorf("invalid project ID: %w", err)
+		return nil, invalidID("project ID", err)
 	}
 
 	issues, err := s.queries.GetProjectIssues(ctx, projectUUID)
@@ -76,13 +137,16 @@ func (s *IssueService) GetProjectIssues(ctx context.Context, projectID string, u
 	for _, issue := range issues {
 		info := IssueInfo{
 			ID:          issue.ID.String(),
+			Number:      issue.Number,
 			ProjectID:   issue.ProjectID.String(),
 			Title:       issue.Title,
 			Description: issue.Description.String,
 			Status:      issue.Status.String,
+			Priority:    issue.Priority.String,
 			ReporterID:  issue.ReporterID.String(),
-			CreatedAt:   issue.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:   issue.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:   FormatTimestamp(issue.CreatedAt),
+			UpdatedAt:   FormatTimestamp(issue.UpdatedAt),
+			Version:     issue.Version,
 		}
 
 		if issue.AssigneeID.Valid {
@@ -93,6 +157,7 @@ func (s *IssueService) GetProjectIssues(ctx context.Context, projectID string, u
 			dueDate := issue.DueDate.Time
 			info.DueDate = &dueDate
 		}
+		info.Overdue = isOverdue(info.DueDate, info.Status, time.Now())
 
 		result = append(result, info)
 	}
@@ -100,17 +165,63 @@ func (s *IssueService) GetProjectIssues(ctx context.Context, projectID string, u
 	return result, nil
 }
 
+// GetProjectIssuesPage retrieves one page of a project's issues, newest first,
+// along with the cursor of the next page ("" on the last page)
+func (s *IssueService) GetProjectIssuesPage(ctx context.Context, projectID, userID string, page CursorPage) ([]IssueInfo, string, error) {
+	if err := s.projectService.requireProjectAccess(ctx, projectID, userID); err != nil {
+		return nil, "", err
+	}
+
+	var projectUUID pgtype.UUID
+	if err := projectUUID.Scan(projectID); err != nil {
+		return nil, "", invalidID("project ID", err)
+	}
+
+	page = page.normalize()
+	createdAt, id, err := page.keyset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	issues, err := s.queries.GetProjectIssuesPage(ctx, store.GetProjectIssuesPageParams{
+		ProjectID:       projectUUID,
+		Limit:           int32(page.Limit + 1),
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get project issues: %w", err)
+	}
+
+	fetched := len(issues)
+	if fetched > page.Limit {
+		issues = issues[:page.Limit]
+	}
+
+	result := make([]IssueInfo, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, issueToInfo(issue))
+	}
+
+	var next string
+	if len(issues) > 0 {
+		last := issues[len(issues)-1]
+		next = nextCursor(fetched, page.Limit, last.CreatedAt, last.ID)
+	}
+	return result, next, nil
+}
+
 // GetIssuesByStatus retrieves issues with a specific status for a project
 func (s *IssueService) GetIssuesByStatus(ctx context.Context, projectID, status, userID string) ([]IssueInfo, error) {
 	// Verify project access
-	_, err := s.projectService.GetProjectByID(ctx, projectID, userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, projectID, userID); err != nil {
 		return nil, err
 	}
 
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return nil, fmt.Errorf("invalid project ID: %w", err)
+		return nil, invalidID("project ID", err)
 	}
 
 	var statusText pgtype.Text
@@ -130,13 +241,15 @@ func (s *IssueService) GetIssuesByStatus(ctx context.Context, projectID, status,
 	for _, issue := range issues {
 		info := IssueInfo{
 			ID:          issue.ID.String(),
+			Number:      issue.Number,
 			ProjectID:   issue.ProjectID.String(),
 			Title:       issue.Title,
 			Description: issue.Description.String,
 			Status:      status,
+			Priority:    issue.Priority.String,
 			ReporterID:  issue.ReporterID.String(),
-			CreatedAt:   issue.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:   issue.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:   FormatTimestamp(issue.CreatedAt),
+			UpdatedAt:   FormatTimestamp(issue.UpdatedAt),
 		}
 
 		if issue.AssigneeID.Valid {
@@ -147,6 +260,7 @@ func (s *IssueService) GetIssuesByStatus(ctx context.Context, projectID, status,
 			dueDate := issue.DueDate.Time
 			info.DueDate = &dueDate
 		}
+		info.Overdue = isOverdue(info.DueDate, info.Status, time.Now())
 
 		result = append(result, info)
 	}
@@ -154,20 +268,135 @@ func (s *IssueService) GetIssuesByStatus(ctx context.Context, projectID, status,
 	return result, nil
 }
 
+// GetFilteredIssues retrieves a project's issues matching filter, newest
+// first unless sorted by priority
+func (s *IssueService) GetFilteredIssues(ctx context.Context, projectID, userID string, filter IssueFilter) ([]IssueInfo, error) {
+	if filter.Priority != "" && !IsValidIssuePriority(filter.Priority) {
+		return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidIssueData, filter.Priority)
+	}
+
+	if err := s.projectService.requireProjectAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	var projectUUID pgtype.UUID
+	if err := projectUUID.Scan(projectID); err != nil {
+		return nil, invalidID("project ID", err)
+	}
+
+	issues, err := s.queries.GetProjectIssuesFiltered(ctx, store.GetProjectIssuesFilteredParams{
+		ProjectID:  projectUUID,
+		Status:     pgtype.Text{String: filter.Status, Valid: filter.Status != ""},
+		Priority:   pgtype.Text{String: filter.Priority, Valid: filter.Priority != ""},
+		DueAfter:   pgtype.Timestamp{Time: filter.DueAfter, Valid: !filter.DueAfter.IsZero()},
+		DueBefore:  pgtype.Timestamp{Time: filter.DueBefore, Valid: !filter.DueBefore.IsZero()},
+		Overdue:    filter.Overdue,
+		ByPriority: filter.SortByPriority,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project issues: %w", err)
+	}
+
+	result := make([]IssueInfo, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, issueToInfo(issue))
+	}
+	return result, nil
+}
+
+// GetAssignedIssues retrieves the issues assigned to the user across all
+// projects, soonest due first. An empty status returns issues of any status.
+func (s *IssueService) GetAssignedIssues(ctx context.Context, userID, status string) ([]AssignedIssueInfo, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	issues, err := s.queries.GetIssuesAssignedToUser(ctx, store.GetIssuesAssignedToUserParams{
+		AssigneeID: userUUID,
+		Status:     pgtype.Text{String: status, Valid: status != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assigned issues: %w", err)
+	}
+
+	result := make([]AssignedIssueInfo, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, AssignedIssueInfo{
+			IssueInfo: issueToInfo(store.Issue{
+				ID:          issue.ID,
+				Number:      issue.Number,
+				ProjectID:   issue.ProjectID,
+				Title:       issue.Title,
+				Description: issue.Description,
+				Status:      issue.Status,
+				Priority:    issue.Priority,
+				ReporterID:  issue.ReporterID,
+				AssigneeID:  userUUID,
+				DueDate:     issue.DueDate,
+				CreatedAt:   issue.CreatedAt,
+				UpdatedAt:   issue.UpdatedAt,
+			}),
+			ProjectName: issue.ProjectName,
+		})
+	}
+
+	return result, nil
+}
+
 // CreateIssue creates a new issue
 func (s *IssueService) CreateIssue(ctx context.Context, params store.CreateIssueParams, userID string) (*IssueInfo, error) {
+	params.Title = sanitize.Text(params.Title)
+	params.Description.String = sanitize.Text(params.Description.String)
+	if params.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidIssueData)
+	}
+	if params.Priority.Valid && !IsValidIssuePriority(params.Priority.String) {
+		return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidIssueData, params.Priority.String)
+	}
+
 	// Verify project access
-	_, err := s.projectService.GetProjectByID(ctx, params.ProjectID.String(), userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, params.ProjectID.String(), userID); err != nil {
 		return nil, err
 	}
 
-	issue, err := s.queries.CreateIssue(ctx, params)
+	if params.AssigneeID.Valid {
+		if err := s.verifyAssignee(ctx, params.ProjectID.String(), params.AssigneeID.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Taking the number in the same transaction as the insert queues
+	// concurrent creates on the project's counter, and a failed insert gives
+	// its number back, so numbers are never skipped or repeated
+	var issue store.Issue
+	err := store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		number, err := q.NextIssueNumber(ctx, params.ProjectID)
+		if err != nil {
+			return fmt.Errorf("failed to number issue: %w", err)
+		}
+		params.Number = number
+
+		issue, err = q.CreateIssue(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to create issue: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create issue: %w", err)
+		return nil, err
+	}
+	s.projectService.invalidateStats(ctx, issue.ProjectID.String())
+
+	// The reporter and assignee follow the issue from the start
+	s.autoWatch(ctx, issue.ID, issue.ReporterID)
+	if issue.AssigneeID.Valid {
+		s.autoWatch(ctx, issue.ID, issue.AssigneeID)
+		s.notifyAssignee(ctx, &issue, userID)
 	}
 
 	info := issueToInfo(issue)
+	s.webhooks.Dispatch(ctx, issue.ProjectID, EventIssueCreated, userID, info)
 	return &info, nil
 }
 
@@ -175,7 +404,7 @@ func (s *IssueService) CreateIssue(ctx context.Context, params store.CreateIssue
 func (s *IssueService) GetIssueByID(ctx context.Context, issueID, userID string) (*IssueInfo, error) {
 	var issueUUID pgtype.UUID
 	if err := issueUUID.Scan(issueID); err != nil {
-		return nil, fmt.Errorf("invalid issue ID: %w", err)
+		return nil, invalidID("issue ID", err)
 	}
 
 	issue, err := s.queries.GetIssueByID(ctx, issueUUID)
@@ -184,10 +413,35 @@ func (s *IssueService) GetIssueByID(ctx context.Context, issueID, userID string)
 	}
 
 	// Verify project access
-	_, err = s.projectService.GetProjectByID(ctx, issue.ProjectID.String(), userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, issue.ProjectID.String(), userID); err != nil {
+		return nil, err
+	}
+
+	info := issueToInfo(issue)
+	return &info, nil
+}
+
+// GetIssueByNumber retrieves an issue by its number within a project
+func (s *IssueService) GetIssueByNumber(ctx context.Context, projectID string, number int, userID string) (*IssueInfo, error) {
+	var projectUUID pgtype.UUID
+	if err := projectUUID.Scan(projectID); err != nil {
+		return nil, invalidID("project ID", err)
+	}
+
+	if err := s.projectService.requireProjectAccess(ctx, projectID, userID); err != nil {
 		return nil, err
 	}
+	if number < 1 || number > math.MaxInt32 {
+		return nil, ErrIssueNotFound
+	}
+
+	issue, err := s.queries.GetIssueByNumber(ctx, store.GetIssueByNumberParams{ProjectID: projectUUID, Number: int32(number)})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIssueNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
 
 	info := issueToInfo(issue)
 	return &info, nil
@@ -197,7 +451,7 @@ func (s *IssueService) GetIssueByID(ctx context.Context, issueID, userID string)
 func (s *IssueService) UpdateIssue(ctx context.Context, issueID string, updates IssueUpdates, userID string) error {
 	var issueUUID pgtype.UUID
 	if err := issueUUID.Scan(issueID); err != nil {
-		return fmt.Errorf("invalid issue ID: %w", err)
+		return invalidID("issue ID", err)
 	}
 
 	// Get the issue to verify project access
@@ -207,32 +461,50 @@ func (s *IssueService) UpdateIssue(ctx context.Context, issueID string, updates
 	}
 
 	// Verify project access
-	_, err = s.projectService.GetProjectByID(ctx, issue.ProjectID.String(), userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, issue.ProjectID.String(), userID); err != nil {
 		return err
 	}
 
-	// Prepare update parameters
+	if updates.Version != 0 && updates.Version != issue.Version {
+		return ErrConcurrentModification
+	}
+
+	// Prepare update parameters. The version guards against writes that
+	// land between reading the issue and updating it.
 	params := store.UpdateIssueDetailsParams{
-		ID: issueUUID,
+		ID:      issueUUID,
+		Version: issue.Version,
 	}
 
 	if updates.Title != "" {
-		params.Title = updates.Title
+		params.Title = sanitize.Text(updates.Title)
+		if params.Title == "" {
+			return fmt.Errorf("%w: title cannot be empty", ErrInvalidIssueData)
+		}
 	}
 
 	if updates.Description != "" {
-		params.Description = pgtype.Text{String: updates.Description, Valid: true}
+		params.Description = pgtype.Text{String: sanitize.Text(updates.Description), Valid: true}
 	}
 
 	if updates.Status != "" {
 		params.Status = pgtype.Text{String: updates.Status, Valid: true}
 	}
 
+	if updates.Priority != "" {
+		if !IsValidIssuePriority(updates.Priority) {
+			return fmt.Errorf("%w: unknown priority %q", ErrInvalidIssueData, updates.Priority)
+		}
+		params.Priority = pgtype.Text{String: updates.Priority, Valid: true}
+	}
+
 	if updates.AssigneeID != "" {
 		var assigneeUUID pgtype.UUID
 		if err := assigneeUUID.Scan(updates.AssigneeID); err != nil {
-			return fmt.Errorf("invalid assignee ID: %w", err)
+			return invalidID("assignee ID", err)
+		}
+		if err := s.verifyAssignee(ctx, issue.ProjectID.String(), updates.AssigneeID); err != nil {
+			return err
 		}
 		params.AssigneeID = assigneeUUID
 	}
@@ -241,18 +513,45 @@ func (s *IssueService) UpdateIssue(ctx context.Context, issueID string, updates
 		params.DueDate = pgtype.Timestamp{Time: *updates.DueDate, Valid: true}
 	}
 
-	if err := s.queries.UpdateIssueDetails(ctx, params); err != nil {
+	rows, err := s.queries.UpdateIssueDetails(ctx, params)
+	if err != nil {
 		return fmt.Errorf("failed to update issue: %w", err)
 	}
+	if rows == 0 {
+		return ErrConcurrentModification
+	}
+	s.projectService.invalidateStats(ctx, issue.ProjectID.String())
+
+	if params.Title != "" {
+		issue.Title = params.Title
+	}
+
+	if s.notifier != nil && params.Status.Valid && params.Status != issue.Status {
+		notifyWatchers(ctx, s.queries, s.notifier, issue.ID, nil, Notification{
+			ActorID: userID,
+			Type:    NotificationStatusChanged,
+			Subject: fmt.Sprintf("%s moved %q to %s", s.actorName(ctx, userID), issue.Title, params.Status.String),
+		})
+	}
+
+	if params.AssigneeID.Valid && params.AssigneeID != issue.AssigneeID {
+		issue.AssigneeID = params.AssigneeID
+		s.autoWatch(ctx, issue.ID, issue.AssigneeID)
+		s.notifyAssignee(ctx, &issue, userID)
+	}
+
+	closed := params.Status.String == "closed" && issue.Status.String != "closed"
+	s.publishUpdate(ctx, issue.ID, closed, userID)
 
 	return nil
 }
 
-// DeleteIssue deletes an issue
+// DeleteIssue deletes an issue along with its comments, attachments, watchers
+// and labels
 func (s *IssueService) DeleteIssue(ctx context.Context, issueID, userID string) error {
 	var issueUUID pgtype.UUID
 	if err := issueUUID.Scan(issueID); err != nil {
-		return fmt.Errorf("invalid issue ID: %w", err)
+		return invalidID("issue ID", err)
 	}
 
 	// Get the issue to verify project access
@@ -262,29 +561,121 @@ func (s *IssueService) DeleteIssue(ctx context.Context, issueID, userID string)
 	}
 
 	// Verify project access
-	_, err = s.projectService.GetProjectByID(ctx, issue.ProjectID.String(), userID)
-	if err != nil {
+	if err := s.projectService.requireProjectAccess(ctx, issue.ProjectID.String(), userID); err != nil {
 		return err
 	}
 
-	if err := s.queries.DeleteIssue(ctx, issueUUID); err != nil {
-		return fmt.Errorf("failed to delete issue: %w", err)
+	// Attachment files aren't part of the transaction, so they're only
+	// removed once the rows are gone
+	var attachments []store.Attachment
+	if s.attachments != nil {
+		attachments, err = s.queries.GetIssueAttachments(ctx, issueUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get attachments: %w", err)
+		}
+	}
+
+	// Everything hanging off the issue is removed with it, all or nothing.
+	// Reactions and revisions belong to their comments and cascade from them.
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		if err := q.DeleteIssueComments(ctx, issueUUID); err != nil {
+			return fmt.Errorf("failed to delete comments: %w", err)
+		}
+		if err := q.DeleteIssueAttachments(ctx, issueUUID); err != nil {
+			return fmt.Errorf("failed to delete attachments: %w", err)
+		}
+		if err := q.DeleteIssueWatchers(ctx, issueUUID); err != nil {
+			return fmt.Errorf("failed to delete watchers: %w", err)
+		}
+		if err := q.DeleteIssueLabels(ctx, issueUUID); err != nil {
+			return fmt.Errorf("failed to delete labels: %w", err)
+		}
+		if err := q.DeleteIssue(ctx, issueUUID); err != nil {
+			return fmt.Errorf("failed to delete issue: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.projectService.invalidateStats(ctx, issue.ProjectID.String())
+	for _, a := range attachments {
+		s.removeAttachmentFile(ctx, a.StorageKey)
 	}
 
 	return nil
 }
 
+// verifyAssignee checks that the assignee can access the issue's project
+func (s *IssueService) verifyAssignee(ctx context.Context, projectID, assigneeID string) error {
+	ok, err := s.projectService.CanAccessProject(ctx, projectID, assigneeID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: assignee does not have access to this project", ErrInvalidIssueData)
+	}
+	return nil
+}
+
+// notifyAssignee tells the issue's assignee they were assigned, unless they
+// assigned themselves
+func (s *IssueService) notifyAssignee(ctx context.Context, issue *store.Issue, userID string) {
+	if s.notifier == nil || issue.AssigneeID.String() == userID {
+		return
+	}
+
+	notify(ctx, s.notifier, Notification{
+		UserID:  issue.AssigneeID.String(),
+		ActorID: userID,
+		Type:    NotificationAssigned,
+		IssueID: issue.ID.String(),
+		Subject: fmt.Sprintf("%s assigned you %q", s.actorName(ctx, userID), issue.Title),
+	})
+}
+
+// publishUpdate sends an updated issue to its project's webhooks, and says
+// it was closed when the update closed it
+func (s *IssueService) publishUpdate(ctx context.Context, issueID pgtype.UUID, closed bool, userID string) {
+	if s.webhooks == nil {
+		return
+	}
+
+	issue, err := s.queries.GetIssueByID(ctx, issueID)
+	if err != nil {
+		log.Printf("Failed to load issue %s for webhooks: %v", issueID.String(), err)
+		return
+	}
+	info := issueToInfo(issue)
+	s.webhooks.Dispatch(ctx, issue.ProjectID, EventIssueUpdated, userID, info)
+	if closed {
+		s.webhooks.Dispatch(ctx, issue.ProjectID, EventIssueClosed, userID, info)
+	}
+}
+
+// actorName returns how notifications refer to the acting user
+func (s *IssueService) actorName(ctx context.Context, userID string) string {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return "Someone"
+	}
+	return actorName(ctx, s.queries, userUUID)
+}
+
 // Helper function to convert issue to info
 func issueToInfo(issue store.Issue) IssueInfo {
 	info := IssueInfo{
 		ID:          issue.ID.String(),
+		Number:      issue.Number,
 		ProjectID:   issue.ProjectID.String(),
 		Title:       issue.Title,
 		Description: issue.Description.String,
 		Status:      issue.Status.String,
+		Priority:    issue.Priority.String,
 		ReporterID:  issue.ReporterID.String(),
-		CreatedAt:   issue.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt:   issue.UpdatedAt.Time.Format(time.RFC3339),
+		CreatedAt:   FormatTimestamp(issue.CreatedAt),
+		UpdatedAt:   FormatTimestamp(issue.UpdatedAt),
+		Version:     issue.Version,
 	}
 
 	if issue.AssigneeID.Valid {
@@ -295,6 +686,13 @@ func issueToInfo(issue store.Issue) IssueInfo {
 		dueDate := issue.DueDate.Time
 		info.DueDate = &dueDate
 	}
+	info.Overdue = isOverdue(info.DueDate, info.Status, time.Now())
 
 	return info
 }
+
+// isOverdue reports whether an issue with the given due date and status is
+// overdue at now: it has a due date before now and isn't closed
+func isOverdue(dueDate *time.Time, status string, now time.Time) bool {
+	return dueDate != nil && dueDate.Before(now) && status != "closed"
+}
@@ -0,0 +1,602 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestUpdateIssueVersionCheck(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	newService := func() (*storetest.DB, *IssueService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID": {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{},
+					pgtype.Text{String: "open", Valid: true}, storetest.MustUUID(t, owner), pgtype.UUID{},
+					pgtype.Timestamp{}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(3)},
+				"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}},
+			},
+			Affected: map[string]int64{},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+	}
+	ctx := context.Background()
+
+	t.Run("Fresh version is applied", func(t *testing.T) {
+		db, svc := newService()
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{Title: "Crash on logout", Version: 3}, owner); err != nil {
+			t.Fatalf("UpdateIssue failed: %v", err)
+		}
+		calls := db.Args("UpdateIssueDetails")
+		if len(calls) != 1 {
+			t.Fatalf("Expected one UpdateIssueDetails call, got %d", len(calls))
+		}
+		if got := calls[0][len(calls[0])-1]; got != int32(3) {
+			t.Errorf("Expected update guarded by version 3, got %v", got)
+		}
+	})
+
+	t.Run("Stale version is rejected", func(t *testing.T) {
+		db, svc := newService()
+		err := svc.UpdateIssue(ctx, issue, IssueUpdates{Title: "Crash on logout", Version: 2}, owner)
+		if !errors.Is(err, ErrConcurrentModification) {
+			t.Fatalf("Expected ErrConcurrentModification, got %v", err)
+		}
+		if n := db.Count("UpdateIssueDetails"); n != 0 {
+			t.Errorf("Expected no update, got %d", n)
+		}
+	})
+
+	t.Run("Write between read and update is rejected", func(t *testing.T) {
+		db, svc := newService()
+		db.Affected["UpdateIssueDetails"] = 0
+		err := svc.UpdateIssue(ctx, issue, IssueUpdates{Title: "Crash on logout"}, owner)
+		if !errors.Is(err, ErrConcurrentModification) {
+			t.Fatalf("Expected ErrConcurrentModification, got %v", err)
+		}
+	})
+}
+
+func TestIssueChangesInvalidateProjectStats(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	issueRow := []any{storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{},
+		pgtype.Text{String: "open", Valid: true}, storetest.MustUUID(t, owner), pgtype.UUID{},
+		pgtype.Timestamp{}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(1)}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectByID":   {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner)},
+		"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}},
+		"GetIssueByID":     issueRow,
+		"CreateIssue":      issueRow,
+		"NextIssueNumber":  {int32(2)},
+	}}
+	setStats := func(total, open int64) {
+		db.Rows["GetProjectStats"] = []any{total, open}
+	}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	projects := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	svc := NewIssueService(queries, memory, projects)
+	ctx := context.Background()
+
+	stats := func() (total, open int) {
+		t.Helper()
+		stats, err := projects.GetProjectStats(ctx, project, owner)
+		if err != nil {
+			t.Fatalf("GetProjectStats failed: %v", err)
+		}
+		return stats.TotalIssues, stats.OpenIssues
+	}
+
+	setStats(1, 1)
+	if total, _ := stats(); total != 1 {
+		t.Fatalf("TotalIssues = %d, want 1", total)
+	}
+
+	// Each mutation changes what the database reports; the next read must see it
+	mutations := []struct {
+		name        string
+		total, open int64
+		mutate      func() error
+	}{
+		{"Create", 2, 2, func() error {
+			_, err := svc.CreateIssue(ctx, store.CreateIssueParams{ProjectID: storetest.MustUUID(t, project), Title: "Crash on logout"}, owner)
+			return err
+		}},
+		{"Update", 2, 1, func() error {
+			return svc.UpdateIssue(ctx, issue, IssueUpdates{Status: "closed"}, owner)
+		}},
+		{"Delete", 1, 0, func() error {
+			return svc.DeleteIssue(ctx, issue, owner)
+		}},
+	}
+	for _, m := range mutations {
+		t.Run(m.name, func(t *testing.T) {
+			setStats(m.total, m.open)
+			if err := m.mutate(); err != nil {
+				t.Fatalf("%s failed: %v", m.name, err)
+			}
+			total, open := stats()
+			if total != int(m.total) || open != int(m.open) {
+				t.Errorf("Stats after %s = %d total, %d open; want %d, %d", m.name, total, open, m.total, m.open)
+			}
+		})
+	}
+}
+
+func TestAssigneeMustAccessProject(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		issue    = "66666666-6666-6666-6666-666666666666"
+	)
+
+	newService := func() (*storetest.DB, *IssueService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID": {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{},
+					pgtype.Text{String: "open", Valid: true}, storetest.MustUUID(t, owner)},
+				"CreateIssue":      {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"NextIssueNumber":  {int32(1)},
+				"GetProjectAccess": {storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+				"CheckTeamMembership:" + team + ":" + member:   {true},
+				"CheckTeamMembership:" + team + ":" + outsider: {false},
+			},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+	}
+	create := func(assignee string) store.CreateIssueParams {
+		return store.CreateIssueParams{ProjectID: storetest.MustUUID(t, project), Title: "Crash on login", AssigneeID: storetest.MustUUID(t, assignee)}
+	}
+	ctx := context.Background()
+
+	t.Run("Team members can be assigned", func(t *testing.T) {
+		db, svc := newService()
+		if _, err := svc.CreateIssue(ctx, create(member), owner); err != nil {
+			t.Errorf("CreateIssue failed: %v", err)
+		}
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: member}, owner); err != nil {
+			t.Errorf("UpdateIssue failed: %v", err)
+		}
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: owner}, owner); err != nil {
+			t.Errorf("Assigning the project owner failed: %v", err)
+		}
+		if db.Count("CreateIssue") != 1 || db.Count("UpdateIssueDetails") != 2 {
+			t.Errorf("Expected the assignments to be saved, got calls %v", db.Calls)
+		}
+	})
+
+	t.Run("Outsiders are rejected", func(t *testing.T) {
+		db, svc := newService()
+		if _, err := svc.CreateIssue(ctx, create(outsider), owner); !errors.Is(err, ErrInvalidIssueData) {
+			t.Errorf("CreateIssue = %v, want ErrInvalidIssueData", err)
+		}
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: outsider}, owner); !errors.Is(err, ErrInvalidIssueData) {
+			t.Errorf("UpdateIssue = %v, want ErrInvalidIssueData", err)
+		}
+		if n := db.Count("CreateIssue") + db.Count("UpdateIssueDetails"); n != 0 {
+			t.Errorf("Expected nothing saved, got %d writes", n)
+		}
+	})
+}
+
+// assignedDB answers GetIssuesAssignedToUser from its issues, like the issues
+// table joined to projects: it keeps the assignee's issues in projects they
+// can access. Everything else is answered from the embedded DB.
+type assignedDB struct {
+	*storetest.DB
+	projectAccess
+	issues []assignedIssue
+}
+
+type assignedIssue struct {
+	store.GetIssuesAssignedToUserRow
+	AssigneeID pgtype.UUID
+}
+
+func (db *assignedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if strings.Fields(sql)[2] != "GetIssuesAssignedToUser" {
+		return db.DB.Query(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	var rows [][]any
+	status := args[1].(pgtype.Text)
+	for _, i := range db.issues {
+		user := args[0].(pgtype.UUID)
+		if i.AssigneeID != user || !db.canAccess(user, i.ProjectID) || (status.Valid && i.Status != status) {
+			continue
+		}
+		rows = append(rows, []any{i.ID, i.ProjectID, i.Title, i.Description, i.Status, i.ReporterID,
+			i.DueDate, i.CreatedAt, i.UpdatedAt, i.ProjectName})
+	}
+	return storetest.Rows(rows), nil
+}
+
+func TestGetAssignedIssues(t *testing.T) {
+	const (
+		user     = "11111111-1111-1111-1111-111111111111"
+		other    = "22222222-2222-2222-2222-222222222222"
+		team     = "44444444-4444-4444-4444-444444444444"
+		oldTeam  = "99999999-9999-9999-9999-999999999999"
+		platform = "55555555-5555-5555-5555-555555555555" // Owned by the user
+		mobile   = "66666666-6666-6666-6666-666666666666" // The user's team's
+		legacy   = "88888888-8888-8888-8888-888888888888" // A team the user was removed from
+	)
+	open := pgtype.Text{String: "open", Valid: true}
+	closed := pgtype.Text{String: "closed", Valid: true}
+	issue := func(n int, project, projectName, assignee string, status pgtype.Text) assignedIssue {
+		return assignedIssue{
+			GetIssuesAssignedToUserRow: store.GetIssuesAssignedToUserRow{
+				ID:          storetest.MustUUID(t, fmt.Sprintf("77777777-7777-7777-7777-%012d", n)),
+				ProjectID:   storetest.MustUUID(t, project),
+				Title:       fmt.Sprintf("Issue %d", n),
+				Status:      status,
+				ProjectName: projectName,
+			},
+			AssigneeID: storetest.MustUUID(t, assignee),
+		}
+	}
+
+	db := &assignedDB{
+		DB: &storetest.DB{},
+		issues: []assignedIssue{
+			issue(1, platform, "Platform", user, open),
+			issue(2, platform, "Platform", other, open),
+			issue(3, mobile, "Mobile", user, closed),
+			issue(4, mobile, "Mobile", other, closed),
+			issue(5, mobile, "Mobile", user, open),
+			issue(6, legacy, "Legacy", user, closed), // Still assigned after the user left the team
+		},
+		projectAccess: projectAccess{
+			projects: map[pgtype.UUID]taskProject{
+				storetest.MustUUID(t, platform): {owner: storetest.MustUUID(t, user)},
+				storetest.MustUUID(t, mobile):   {owner: storetest.MustUUID(t, other), team: storetest.MustUUID(t, team)},
+				storetest.MustUUID(t, legacy):   {owner: storetest.MustUUID(t, other), team: storetest.MustUUID(t, oldTeam)},
+			},
+			members: map[pgtype.UUID][]pgtype.UUID{
+				storetest.MustUUID(t, team):    {storetest.MustUUID(t, user), storetest.MustUUID(t, other)},
+				storetest.MustUUID(t, oldTeam): {storetest.MustUUID(t, other)},
+			},
+		},
+	}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	svc := NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+	ctx := context.Background()
+
+	titles := func(issues []AssignedIssueInfo) []string {
+		var got []string
+		for _, i := range issues {
+			got = append(got, i.ProjectName+": "+i.Title)
+		}
+		return got
+	}
+
+	issues, err := svc.GetAssignedIssues(ctx, user, "")
+	if err != nil {
+		t.Fatalf("GetAssignedIssues failed: %v", err)
+	}
+	if got, want := titles(issues), []string{"Platform: Issue 1", "Mobile: Issue 3", "Mobile: Issue 5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Assigned issues = %v, want %v", got, want)
+	}
+	for _, i := range issues {
+		if i.AssigneeID != user {
+			t.Errorf("Issue %s is assigned to %s", i.Title, i.AssigneeID)
+		}
+	}
+
+	issues, err = svc.GetAssignedIssues(ctx, user, "open")
+	if err != nil {
+		t.Fatalf("GetAssignedIssues failed: %v", err)
+	}
+	if got, want := titles(issues), []string{"Platform: Issue 1", "Mobile: Issue 5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Open assigned issues = %v, want %v", got, want)
+	}
+
+	if _, err := svc.GetAssignedIssues(ctx, "not-a-uuid", ""); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Invalid user = %v, want ErrInvalidID", err)
+	}
+}
+
+func TestIsOverdue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+
+	tests := []struct {
+		name    string
+		due     *time.Time
+		status  string
+		overdue bool
+	}{
+		{"Due a moment ago", at(-time.Second), "open", true},
+		{"Due a moment ago, in progress", at(-time.Second), "in_progress", true},
+		{"Due a moment ago, closed", at(-time.Second), "closed", false},
+		{"Due right now", at(0), "open", false},
+		{"Due in a moment", at(time.Second), "open", false},
+		{"No due date", nil, "open", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOverdue(tt.due, tt.status, now); got != tt.overdue {
+				t.Errorf("isOverdue = %v, want %v", got, tt.overdue)
+			}
+		})
+	}
+
+	t.Run("Issues are flagged against the current time", func(t *testing.T) {
+		issue := store.Issue{
+			Status:  pgtype.Text{String: "open", Valid: true},
+			DueDate: pgtype.Timestamp{Time: time.Now().Add(-time.Minute), Valid: true},
+		}
+		if !issueToInfo(issue).Overdue {
+			t.Error("Expected an open issue due a minute ago to be overdue")
+		}
+		issue.DueDate.Time = time.Now().Add(time.Hour)
+		if issueToInfo(issue).Overdue {
+			t.Error("Expected an issue due in an hour not to be overdue")
+		}
+	})
+}
+
+func TestDeleteIssueRemovesComments(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	setup := func() (*storetest.DB, *IssueService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID":     {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+				"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}},
+			},
+			Errs: map[string]error{},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewIssueService(queries, memory, NewProjectService(queries, memory, nil, CacheTTLs{}))
+	}
+	ctx := context.Background()
+	deletes := []string{"DeleteIssueComments", "DeleteIssueAttachments", "DeleteIssueWatchers", "DeleteIssueLabels", "DeleteIssue"}
+
+	t.Run("Comments are deleted with the issue", func(t *testing.T) {
+		db, svc := setup()
+		if err := svc.DeleteIssue(ctx, issue, owner); err != nil {
+			t.Fatalf("DeleteIssue failed: %v", err)
+		}
+		if db.Commits != 1 {
+			t.Fatalf("Expected one committed transaction, got %d", db.Commits)
+		}
+		for _, name := range deletes {
+			calls := db.Args(name)
+			if len(calls) != 1 {
+				t.Errorf("Expected %s to run once, got %d", name, len(calls))
+				continue
+			}
+			if id := calls[0][0].(pgtype.UUID); id.String() != issue {
+				t.Errorf("%s ran for %s, want %s", name, id.String(), issue)
+			}
+		}
+	})
+
+	t.Run("A failure part way rolls everything back", func(t *testing.T) {
+		db, svc := setup()
+		db.Errs["DeleteIssue"] = errors.New("connection reset")
+		if err := svc.DeleteIssue(ctx, issue, owner); err == nil {
+			t.Fatal("Expected DeleteIssue to fail")
+		}
+		if db.Rollbacks != 1 || db.Commits != 0 {
+			t.Errorf("Got %d commits and %d rollbacks, want the transaction rolled back", db.Commits, db.Rollbacks)
+		}
+		for _, name := range deletes {
+			if db.Count(name) != 0 {
+				t.Errorf("Expected %s to be rolled back", name)
+			}
+		}
+	})
+}
+
+// numberDB numbers issues like project_issue_counters: NextIssueNumber holds
+// the counter until its transaction ends, the way the counter's row lock
+// does, and only a commit keeps the numbers taken. CreateIssue returns the
+// issue it was given. Other queries are answered by the embedded DB, one at a
+// time so that creates can run concurrently.
+type numberDB struct {
+	*storetest.DB
+	mu      sync.Mutex // Guards DB and last
+	counter sync.Mutex // Held by the transaction numbering an issue
+	last    int32
+}
+
+func (db *numberDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.DB.Exec(ctx, sql, args...)
+}
+
+func (db *numberDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.DB.Query(ctx, sql, args...)
+}
+
+func (db *numberDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.DB.QueryRow(ctx, sql, args...)
+}
+
+func (db *numberDB) Begin(context.Context) (pgx.Tx, error) {
+	return &numberTx{db: db, inner: &storetest.DB{Rows: db.Rows, Errs: db.Errs}}, nil
+}
+
+type numberTx struct {
+	pgx.Tx
+	db     *numberDB
+	inner  *storetest.DB
+	locked bool
+	last   int32
+	done   bool
+}
+
+func (tx *numberTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.inner.Exec(ctx, sql, args...)
+}
+
+func (tx *numberTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	switch strings.Fields(sql)[2] {
+	case "NextIssueNumber":
+		tx.inner.Record(sql, args)
+		if !tx.locked {
+			tx.db.counter.Lock()
+			tx.locked, tx.last = true, tx.db.last
+		}
+		tx.last++
+		return storetest.Row([]any{tx.last})
+	case "CreateIssue":
+		name, _ := tx.inner.Record(sql, args)
+		if err := tx.inner.Errs[name]; err != nil {
+			return storetest.ErrRow(err)
+		}
+		number := args[8].(int32)
+		id := pgtype.UUID{Bytes: [16]byte{byte(number)}, Valid: true}
+		return storetest.Row([]any{id, args[0], args[1], args[2], args[3], args[4], args[5], args[6],
+			pgtype.Timestamp{}, pgtype.Timestamp{}, int32(1), args[7], number})
+	}
+	return tx.inner.QueryRow(ctx, sql, args...)
+}
+
+func (tx *numberTx) Commit(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.db.mu.Lock()
+	tx.db.Calls = append(tx.db.Calls, tx.inner.Calls...)
+	tx.db.Commits++
+	tx.db.mu.Unlock()
+	if tx.locked {
+		tx.db.last = tx.last
+		tx.db.counter.Unlock()
+	}
+	return nil
+}
+
+func (tx *numberTx) Rollback(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.db.mu.Lock()
+	tx.db.Rollbacks++
+	tx.db.mu.Unlock()
+	if tx.locked {
+		tx.db.counter.Unlock()
+	}
+	return nil
+}
+
+func TestIssueNumbers(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	setup := func() (*numberDB, *IssueService) {
+		db := &numberDB{DB: &storetest.DB{
+			Rows: map[string][]any{"GetProjectAccess": {storetest.MustUUID(t, owner), pgtype.UUID{}}},
+			Errs: map[string]error{},
+		}}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewIssueService(queries, memory, NewProjectService(queries, memory, nil, CacheTTLs{}))
+	}
+	ctx := context.Background()
+	params := store.CreateIssueParams{ProjectID: storetest.MustUUID(t, project), Title: "Crash on login", ReporterID: storetest.MustUUID(t, owner)}
+
+	t.Run("Concurrent creates get distinct sequential numbers", func(t *testing.T) {
+		_, svc := setup()
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		numbers := make([]int32, 2)
+		errs := make([]error, 2)
+		for i := range numbers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				issue, err := svc.CreateIssue(ctx, params, owner)
+				if errs[i] = err; err == nil {
+					numbers[i] = issue.Number
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("CreateIssue failed: %v", err)
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+		if numbers[0] != 1 || numbers[1] != 2 {
+			t.Errorf("Numbers = %v, want 1 and 2", numbers)
+		}
+	})
+
+	t.Run("A failed create gives its number back", func(t *testing.T) {
+		db, svc := setup()
+		db.Errs["CreateIssue"] = errors.New("connection reset")
+		if _, err := svc.CreateIssue(ctx, params, owner); err == nil {
+			t.Fatal("Expected CreateIssue to fail")
+		}
+		if db.Rollbacks != 1 {
+			t.Errorf("Expected the transaction to be rolled back, got %d rollbacks", db.Rollbacks)
+		}
+
+		delete(db.Errs, "CreateIssue")
+		issue, err := svc.CreateIssue(ctx, params, owner)
+		if err != nil {
+			t.Fatalf("CreateIssue failed: %v", err)
+		}
+		if issue.Number != 1 {
+			t.Errorf("Number = %d, want 1 with no gap", issue.Number)
+		}
+	})
+}
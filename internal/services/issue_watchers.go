@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WatcherInfo represents a user following an issue
+type WatcherInfo struct {
+	UserID        string  `json:"user_id"`
+	Name          string  `json:"name,omitempty"`
+	Username      string  `json:"username,omitempty"`
+	AvatarURL     string  `json:"avatar_url,omitempty"`
+	WatchingSince *string `json:"watching_since"`
+}
+
+// WatchIssue subscribes the user to changes on an issue. Watching an issue
+// twice is a no-op.
+func (s *IssueService) WatchIssue(ctx context.Context, issueID, userID string) error {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return err
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	if err := s.queries.AddIssueWatcher(ctx, store.AddIssueWatcherParams{
+		IssueID: issue.ID,
+		UserID:  userUUID,
+	}); err != nil {
+		return fmt.Errorf("failed to watch issue: %w", err)
+	}
+	return nil
+}
+
+// UnwatchIssue unsubscribes the user from an issue
+func (s *IssueService) UnwatchIssue(ctx context.Context, issueID, userID string) error {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return err
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	if err := s.queries.RemoveIssueWatcher(ctx, store.RemoveIssueWatcherParams{
+		IssueID: issue.ID,
+		UserID:  userUUID,
+	}); err != nil {
+		return fmt.Errorf("failed to unwatch issue: %w", err)
+	}
+	return nil
+}
+
+// GetWatchers lists the users watching an issue
+func (s *IssueService) GetWatchers(ctx context.Context, issueID, userID string) ([]WatcherInfo, error) {
+	issue, err := s.accessibleIssue(ctx, issueID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	watchers, err := s.queries.GetIssueWatchers(ctx, issue.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchers: %w", err)
+	}
+
+	result := make([]WatcherInfo, 0, len(watchers))
+	for _, w := range watchers {
+		result = append(result, WatcherInfo{
+			UserID:        w.ID.String(),
+			Name:          w.Name.String,
+			Username:      w.Username.String,
+			AvatarURL:     w.AvatarUrl.String,
+			WatchingSince: FormatTimestamp(w.WatchingSince),
+		})
+	}
+	return result, nil
+}
+
+// autoWatch subscribes a user, typically the reporter or assignee, to an issue
+func (s *IssueService) autoWatch(ctx context.Context, issueID, userID pgtype.UUID) {
+	if !userID.Valid {
+		return
+	}
+	if err := s.queries.AddIssueWatcher(ctx, store.AddIssueWatcherParams{
+		IssueID: issueID,
+		UserID:  userID,
+	}); err != nil {
+		log.Printf("Failed to subscribe %s to issue %s: %v", userID.String(), issueID.String(), err)
+	}
+}
+
+// notifyWatchers sends n to everyone watching the issue except the actor and
+// users in skip, who have already been told about this event.
+func notifyWatchers(ctx context.Context, queries *store.Queries, notifier Notifier, issueID pgtype.UUID, skip map[string]bool, n Notification) {
+	if notifier == nil {
+		return
+	}
+
+	watchers, err := queries.GetIssueWatchers(ctx, issueID)
+	if err != nil {
+		log.Printf("Failed to get watchers of issue %s: %v", issueID.String(), err)
+		return
+	}
+
+	n.IssueID = issueID.String()
+	for _, w := range watchers {
+		userID := w.ID.String()
+		if userID == n.ActorID || skip[userID] {
+			continue
+		}
+		n.UserID = userID
+		notify(ctx, notifier, n)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIssueWatchers(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		member  = "22222222-2222-2222-2222-222222222222"
+		watcher = "33333333-3333-3333-3333-333333333333"
+		team    = "44444444-4444-4444-4444-444444444444"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	newService := func() (*storetest.DB, *IssueService, *[]Notification) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetIssueByID": {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login", pgtype.Text{},
+					pgtype.Text{String: "open", Valid: true}},
+				"GetProjectAccess": {storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+				"GetUserByID":      {storetest.MustUUID(t, owner), "ada@example.com", pgtype.Text{String: "Ada", Valid: true}},
+				"CheckTeamMembership:" + team + ":" + member:  {true},
+				"CheckTeamMembership:" + team + ":" + watcher: {true},
+			},
+			Lists: map[string][][]any{
+				"GetIssueWatchers": {
+					{storetest.MustUUID(t, owner)},
+					{storetest.MustUUID(t, member)},
+					{storetest.MustUUID(t, watcher)},
+				},
+			},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		svc := NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+
+		sent := &[]Notification{}
+		svc.SetNotifier(NotifierFunc(func(_ context.Context, n Notification) error {
+			*sent = append(*sent, n)
+			return nil
+		}))
+		return db, svc, sent
+	}
+	ctx := context.Background()
+
+	t.Run("Watch adds the user to the watchers", func(t *testing.T) {
+		db, svc, _ := newService()
+		if err := svc.WatchIssue(ctx, issue, watcher); err != nil {
+			t.Fatalf("WatchIssue failed: %v", err)
+		}
+		calls := db.Args("AddIssueWatcher")
+		if len(calls) != 1 || calls[0][0] != storetest.MustUUID(t, issue) || calls[0][1] != storetest.MustUUID(t, watcher) {
+			t.Errorf("Unexpected AddIssueWatcher calls %v", calls)
+		}
+
+		if err := svc.UnwatchIssue(ctx, issue, watcher); err != nil {
+			t.Fatalf("UnwatchIssue failed: %v", err)
+		}
+		if calls := db.Args("RemoveIssueWatcher"); len(calls) != 1 || calls[0][1] != storetest.MustUUID(t, watcher) {
+			t.Errorf("Unexpected RemoveIssueWatcher calls %v", calls)
+		}
+	})
+
+	t.Run("Outsider cannot watch", func(t *testing.T) {
+		db, svc, _ := newService()
+		outsider := "77777777-7777-7777-7777-777777777777"
+		db.Rows["CheckTeamMembership:"+team+":"+outsider] = []any{false}
+		if err := svc.WatchIssue(ctx, issue, outsider); err == nil {
+			t.Error("Expected outsider to be refused")
+		}
+		if db.Count("AddIssueWatcher") != 0 {
+			t.Error("AddIssueWatcher should not run for an outsider")
+		}
+	})
+
+	t.Run("Status change notifies every watcher once", func(t *testing.T) {
+		_, svc, sent := newService()
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{Status: "done"}, owner); err != nil {
+			t.Fatalf("UpdateIssue failed: %v", err)
+		}
+
+		var recipients []string
+		for _, n := range *sent {
+			if n.Type != NotificationStatusChanged || n.IssueID != issue {
+				t.Errorf("Unexpected notification %+v", n)
+			}
+			recipients = append(recipients, n.UserID)
+		}
+		sort.Strings(recipients)
+		// The owner made the change, so only the other watchers hear about it
+		if len(recipients) != 2 || recipients[0] != member || recipients[1] != watcher {
+			t.Errorf("Notified %v, want each of [%s %s] once", recipients, member, watcher)
+		}
+	})
+
+	t.Run("Unchanged status notifies nobody", func(t *testing.T) {
+		_, svc, sent := newService()
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{Status: "open"}, owner); err != nil {
+			t.Fatalf("UpdateIssue failed: %v", err)
+		}
+		if len(*sent) != 0 {
+			t.Errorf("Expected no notifications, got %+v", *sent)
+		}
+	})
+
+	t.Run("New assignee starts watching", func(t *testing.T) {
+		db, svc, _ := newService()
+		if err := svc.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: member}, owner); err != nil {
+			t.Fatalf("UpdateIssue failed: %v", err)
+		}
+		if calls := db.Args("AddIssueWatcher"); len(calls) != 1 || calls[0][1] != storetest.MustUUID(t, member) {
+			t.Errorf("Expected assignee to be subscribed, got %v", calls)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+)
+
+// maxMentions caps how many users a single comment can notify
+const maxMentions = 20
+
+// mentionPattern matches @username tokens that start a word, so email
+// addresses such as bob@example.com are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.-]+)`)
+
+// parseMentions returns the distinct usernames mentioned in content, in the
+// order they first appear. Trailing punctuation ("@bob.") is not part of a name.
+func parseMentions(content string) []string {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+// notifyMentions notifies users mentioned in a new comment and returns the
+// IDs of those notified. Mentions of unknown users, of the author, or of users
+// who cannot access the project are ignored.
+func (s *CommentService) notifyMentions(ctx context.Context, comment *store.Comment, target *commentTarget, actor string) map[string]bool {
+	notified := make(map[string]bool)
+
+	usernames := parseMentions(comment.Content)
+	if len(usernames) == 0 {
+		return notified
+	}
+
+	users, err := s.queries.GetUsersByUsernames(ctx, usernames)
+	if err != nil {
+		log.Printf("Failed to resolve comment mentions: %v", err)
+		return notified
+	}
+
+	author := comment.UserID.String()
+	for _, user := range users {
+		userID := user.ID.String()
+		if userID == author {
+			continue
+		}
+		if err := s.projectService.verifyProjectAccess(ctx, target.project, userID); err != nil {
+			continue
+		}
+
+		notification := Notification{
+			UserID:  userID,
+			ActorID: author,
+			Type:    NotificationMention,
+			Subject: fmt.Sprintf("%s mentioned you in %q", actor, target.title),
+			Message: excerpt(comment.Content, 280),
+		}
+		if comment.IssueID.Valid {
+			notification.IssueID = comment.IssueID.String()
+		}
+		notify(ctx, s.notifier, notification)
+		notified[userID] = true
+	}
+	return notified
+}
+
+// displayName picks the friendliest available name for a user
+func displayName(name, username, email string) string {
+	switch {
+	case name != "":
+		return name
+	case username != "":
+		return "@" + username
+	default:
+		return email
+	}
+}
+
+// excerpt shortens s to at most n runes, marking any cut with an ellipsis
+func excerpt(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"@alice can you look?", []string{"alice"}},
+		{"cc @bob, @carol.", []string{"bob", "carol"}},
+		{"@bob @bob again", []string{"bob"}},
+		{"(@dave) and @j.doe-2", []string{"dave", "j.doe-2"}},
+		{"mail bob@example.com", nil},
+		{"@@alice or @", nil},
+	}
+	for _, tt := range tests {
+		if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMentions(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestCommentMentionsNotifyProjectMembers(t *testing.T) {
+	const (
+		author   = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		issue    = "66666666-6666-6666-6666-666666666666"
+	)
+
+	user := func(id, username string) []any {
+		return []any{storetest.MustUUID(t, id), username + "@example.com", pgtype.Text{}, pgtype.Text{String: username, Valid: true}}
+	}
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"GetIssueByID":   {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+			"GetProjectByID": {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, author), storetest.MustUUID(t, team)},
+			"CreateComment": {
+				pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+				"@member please check, @outsider FYI, thanks @author",
+				storetest.MustUUID(t, author), storetest.MustUUID(t, issue),
+			},
+			"GetUserByID": {storetest.MustUUID(t, author), "author@example.com", pgtype.Text{String: "Ada", Valid: true}},
+			"CheckTeamMembership:" + team + ":" + member:   {true},
+			"CheckTeamMembership:" + team + ":" + outsider: {false},
+		},
+		Lists: map[string][][]any{
+			"GetUsersByUsernames": {user(member, "member"), user(outsider, "outsider"), user(author, "author")},
+		},
+	}
+
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	projects := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	comments := NewCommentService(queries, memory, projects, CacheTTLs{})
+
+	var sent []Notification
+	comments.SetNotifier(NotifierFunc(func(_ context.Context, n Notification) error {
+		sent = append(sent, n)
+		return nil
+	}))
+
+	_, err := comments.CreateComment(context.Background(), store.CreateCommentParams{
+		Content: "@member please check, @outsider FYI, thanks @author",
+		IssueID: storetest.MustUUID(t, issue),
+	}, author)
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("Expected exactly one notification, got %+v", sent)
+	}
+	n := sent[0]
+	if n.UserID != member || n.ActorID != author || n.Type != NotificationMention || n.IssueID != issue {
+		t.Errorf("Unexpected notification %+v", n)
+	}
+	if n.Subject != `Ada mentioned you in "Crash on login"` {
+		t.Errorf("Subject = %q", n.Subject)
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Notification service errors
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// NotificationInfo represents an inbox entry returned to clients
+type NotificationInfo struct {
+	ID        string  `json:"id"`
+	Type      string  `json:"type"`
+	Subject   string  `json:"subject"`
+	Message   string  `json:"message,omitempty"`
+	ActorID   string  `json:"actor_id,omitempty"`
+	IssueID   string  `json:"issue_id,omitempty"`
+	TeamID    string  `json:"team_id,omitempty"`
+	Read      bool    `json:"read"`
+	ReadAt    *string `json:"read_at"`
+	CreatedAt *string `json:"created_at"`
+}
+
+// NotificationService stores notifications in each user's in-app inbox
+type NotificationService struct {
+	queries *store.Queries
+}
+
+func NewNotificationService(queries *store.Queries) *NotificationService {
+	return &NotificationService{
+		queries: queries,
+	}
+}
+
+// Notify adds a notification to the recipient's inbox
+func (s *NotificationService) Notify(ctx context.Context, n Notification) error {
+	var params store.CreateNotificationParams
+	if err := params.UserID.Scan(n.UserID); err != nil {
+		return invalidID("user ID", err)
+	}
+	for _, id := range []struct {
+		dest  *pgtype.UUID
+		value string
+	}{
+		{&params.ActorID, n.ActorID},
+		{&params.IssueID, n.IssueID},
+		{&params.TeamID, n.TeamID},
+	} {
+		if id.value == "" {
+			continue
+		}
+		if err := id.dest.Scan(id.value); err != nil {
+			return invalidID("notification reference", err)
+		}
+	}
+	params.Type = n.Type
+	params.Subject = n.Subject
+	params.Message = pgtype.Text{String: n.Message, Valid: n.Message != ""}
+
+	if _, err := s.queries.CreateNotification(ctx, params); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications returns a user's notifications, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, userID string, page Pagination) ([]NotificationInfo, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	page = page.normalize()
+
+	notifications, err := s.queries.ListNotifications(ctx, store.ListNotificationsParams{
+		UserID: userUUID,
+		Limit:  int32(page.Limit),
+		Offset: int32(page.Offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	result := make([]NotificationInfo, 0, len(notifications))
+	for _, n := range notifications {
+		result = append(result, notificationToInfo(n))
+	}
+	return result, nil
+}
+
+// MarkRead marks one of the user's notifications as read. Marking a
+// notification that is already read succeeds and keeps the original time.
+func (s *NotificationService) MarkRead(ctx context.Context, notificationID, userID string) error {
+	var notificationUUID pgtype.UUID
+	if err := notificationUUID.Scan(notificationID); err != nil {
+		return ErrNotificationNotFound
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	rows, err := s.queries.MarkNotificationRead(ctx, store.MarkNotificationReadParams{
+		ID:     notificationUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// UnreadCount returns how many of the user's notifications are unread
+func (s *NotificationService) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return 0, invalidID("user ID", err)
+	}
+
+	count, err := s.queries.CountUnread(ctx, userUUID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func notificationToInfo(n store.Notification) NotificationInfo {
+	info := NotificationInfo{
+		ID:        n.ID.String(),
+		Type:      n.Type,
+		Subject:   n.Subject,
+		Message:   n.Message.String,
+		Read:      n.ReadAt.Valid,
+		ReadAt:    FormatTimestamp(n.ReadAt),
+		CreatedAt: FormatTimestamp(n.CreatedAt),
+	}
+	if n.ActorID.Valid {
+		info.ActorID = n.ActorID.String()
+	}
+	if n.IssueID.Valid {
+		info.IssueID = n.IssueID.String()
+	}
+	if n.TeamID.Valid {
+		info.TeamID = n.TeamID.String()
+	}
+	return info
+}
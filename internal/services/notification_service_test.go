@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNotificationInbox(t *testing.T) {
+	const (
+		user     = "11111111-1111-1111-1111-111111111111"
+		actor    = "22222222-2222-2222-2222-222222222222"
+		stranger = "33333333-3333-3333-3333-333333333333"
+		issue    = "66666666-6666-6666-6666-666666666666"
+		first    = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+		second   = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"
+	)
+	ctx := context.Background()
+	readAt := pgtype.Timestamp{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"CreateNotification":   {storetest.MustUUID(t, first)},
+			"CountUnread:" + user:  {int64(1)},
+			"CountUnread:" + actor: {int64(0)},
+		},
+		Lists: map[string][][]any{
+			"ListNotifications:" + user: {
+				{storetest.MustUUID(t, second), storetest.MustUUID(t, user), storetest.MustUUID(t, actor), NotificationAssigned, "Ada assigned you \"Crash\"",
+					pgtype.Text{}, storetest.MustUUID(t, issue), pgtype.UUID{}, pgtype.Timestamp{}},
+				{storetest.MustUUID(t, first), storetest.MustUUID(t, user), storetest.MustUUID(t, actor), NotificationMention, "Ada mentioned you",
+					pgtype.Text{String: "@bob look", Valid: true}, storetest.MustUUID(t, issue), pgtype.UUID{}, readAt},
+			},
+		},
+		Affected: map[string]int64{
+			"MarkNotificationRead:" + first + ":" + stranger: 0,
+		},
+	}
+	svc := NewNotificationService(store.New(db))
+
+	t.Run("Notify stores the notification", func(t *testing.T) {
+		err := svc.Notify(ctx, Notification{
+			UserID: user, ActorID: actor, Type: NotificationMention, IssueID: issue,
+			Subject: "Ada mentioned you", Message: "@bob look",
+		})
+		if err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+		args := db.Args("CreateNotification")[0]
+		if args[0] != storetest.MustUUID(t, user) || args[1] != storetest.MustUUID(t, actor) || args[5] != storetest.MustUUID(t, issue) {
+			t.Errorf("Unexpected CreateNotification args %v", args)
+		}
+		if team := args[6].(pgtype.UUID); team.Valid {
+			t.Errorf("Expected no team ID, got %v", team)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		list, err := svc.ListNotifications(ctx, user, Pagination{Limit: 1000})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("Expected 2 notifications, got %d", len(list))
+		}
+		if list[0].ID != second || list[0].Read || list[0].ReadAt != nil {
+			t.Errorf("Expected newest notification to be unread, got %+v", list[0])
+		}
+		if !list[1].Read || list[1].ReadAt == nil || *list[1].ReadAt != "2025-01-02T03:04:05Z" {
+			t.Errorf("Expected older notification to be read, got %+v", list[1])
+		}
+		if limit := db.Args("ListNotifications")[0][1]; limit != int32(MaxPageSize) {
+			t.Errorf("Expected limit to be clamped to %d, got %v", MaxPageSize, limit)
+		}
+	})
+
+	t.Run("Unread count", func(t *testing.T) {
+		if n, err := svc.UnreadCount(ctx, user); err != nil || n != 1 {
+			t.Errorf("UnreadCount(user) = %d, %v; want 1", n, err)
+		}
+		if n, err := svc.UnreadCount(ctx, actor); err != nil || n != 0 {
+			t.Errorf("UnreadCount(actor) = %d, %v; want 0", n, err)
+		}
+	})
+
+	t.Run("Mark read is idempotent", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := svc.MarkRead(ctx, first, user); err != nil {
+				t.Fatalf("MarkRead attempt %d failed: %v", i+1, err)
+			}
+		}
+	})
+
+	t.Run("Mark read of another user's notification", func(t *testing.T) {
+		if err := svc.MarkRead(ctx, first, stranger); !errors.Is(err, ErrNotificationNotFound) {
+			t.Errorf("Expected ErrNotificationNotFound, got %v", err)
+		}
+		if err := svc.MarkRead(ctx, "not-a-uuid", user); !errors.Is(err, ErrNotificationNotFound) {
+			t.Errorf("Expected ErrNotificationNotFound for a bad ID, got %v", err)
+		}
+	})
+}
+
+func TestAssignmentNotifiesAssignee(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		member  = "22222222-2222-2222-2222-222222222222"
+		team    = "44444444-4444-4444-4444-444444444444"
+		project = "55555555-5555-5555-5555-555555555555"
+		issue   = "66666666-6666-6666-6666-666666666666"
+	)
+
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetIssueByID":     {storetest.MustUUID(t, issue), storetest.MustUUID(t, project), "Crash on login"},
+		"GetProjectAccess": {storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+		"GetUserByID":      {storetest.MustUUID(t, owner), "ada@example.com", pgtype.Text{String: "Ada", Valid: true}},
+		"CheckTeamMembership:" + team + ":" + member: {true},
+	}}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	issues := NewIssueService(queries, memory, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{}))
+
+	var sent []Notification
+	issues.SetNotifier(Notifiers{NotifierFunc(func(_ context.Context, n Notification) error {
+		sent = append(sent, n)
+		return nil
+	})})
+
+	ctx := context.Background()
+	if err := issues.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: member}, owner); err != nil {
+		t.Fatalf("UpdateIssue failed: %v", err)
+	}
+	if err := issues.UpdateIssue(ctx, issue, IssueUpdates{AssigneeID: owner}, owner); err != nil {
+		t.Fatalf("UpdateIssue failed: %v", err)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("Expected one notification, got %+v", sent)
+	}
+	n := sent[0]
+	if n.UserID != member || n.Type != NotificationAssigned || n.IssueID != issue || n.Subject != `Ada assigned you "Crash on login"` {
+		t.Errorf("Unexpected notification %+v", n)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/email"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Notification types
+const (
+	NotificationMention       = "mention"
+	NotificationAssigned      = "assigned"
+	NotificationTeamAdded     = "team_added"
+	NotificationStatusChanged = "status_changed"
+	NotificationComment       = "comment"
+)
+
+// Notification describes an event a user should hear about
+type Notification struct {
+	UserID  string // Recipient
+	ActorID string // User who caused the event
+	Type    string
+	IssueID string
+	TeamID  string
+	Subject string
+	Message string
+}
+
+// Notifier delivers notifications to users. Implementations should not block
+// on slow transports; services call Notify while handling a request.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f(ctx, n)
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// Notifiers fans a notification out to several notifiers, e.g. the in-app
+// inbox and email. Every notifier is tried; their errors are joined.
+type Notifiers []Notifier
+
+// Notify delivers n through each notifier in turn
+func (ns Notifiers) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range ns {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notify delivers n if a notifier is configured. Notifications are a side
+// effect, so failures are logged rather than returned.
+func notify(ctx context.Context, notifier Notifier, n Notification) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		log.Printf("Failed to send %s notification to %s: %v", n.Type, n.UserID, err)
+	}
+}
+
+// actorName returns how a notification refers to the user who acted
+func actorName(ctx context.Context, queries *store.Queries, userID pgtype.UUID) string {
+	actor, err := queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return "Someone"
+	}
+	return displayName(actor.Name.String, actor.Username.String, actor.Email)
+}
+
+// EmailNotifier delivers notifications by email
+type EmailNotifier struct {
+	queries      *store.Queries
+	emailService *email.EmailService
+}
+
+// NewEmailNotifier creates a notifier that emails the recipient's address
+func NewEmailNotifier(queries *store.Queries, emailService *email.EmailService) *EmailNotifier {
+	return &EmailNotifier{
+		queries:      queries,
+		emailService: emailService,
+	}
+}
+
+// Notify looks up the recipient and sends the email in the background
+func (n *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(notification.UserID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	user, err := n.queries.GetUserByID(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+
+	// Sending may retry for several seconds, so don't hold up the request
+	go func() {
+		if err := n.emailService.SendNotificationEmail(user.Email, notification.Subject, notification.Message); err != nil {
+			log.Printf("Failed to send %s notification email: %v", notification.Type, err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Default and maximum page sizes for paginated listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by EncodeCursor
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Pagination holds limit/offset options for list queries
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+// normalize clamps the pagination values to sane bounds
+func (p Pagination) normalize() Pagination {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	if p.Limit > MaxPageSize {
+		p.Limit = MaxPageSize
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// CursorPage holds keyset pagination options for list queries ordered newest
+// first. Unlike offsets, cursors stay cheap on large tables and don't skip or
+// repeat rows when new ones are inserted between requests.
+type CursorPage struct {
+	Limit int
+	After string // Cursor returned with the previous page; empty for the first page
+}
+
+// normalize clamps the page size to sane bounds
+func (p CursorPage) normalize() CursorPage {
+	p.Limit = Pagination{Limit: p.Limit}.normalize().Limit
+	return p
+}
+
+// Cursor identifies a row by its position in a created_at DESC, id DESC listing
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// EncodeCursor returns an opaque cursor pointing just past c
+func EncodeCursor(c Cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var uuid pgtype.UUID
+	if err := uuid.Scan(id); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// keyset converts the page's cursor into query arguments. Both are NULL on the
+// first page.
+func (p CursorPage) keyset() (createdAt pgtype.Timestamp, id pgtype.UUID, err error) {
+	if p.After == "" {
+		return createdAt, id, nil
+	}
+	c, err := DecodeCursor(p.After)
+	if err != nil {
+		return createdAt, id, err
+	}
+	if err := id.Scan(c.ID); err != nil {
+		return createdAt, id, ErrInvalidCursor
+	}
+	return pgtype.Timestamp{Time: c.CreatedAt, Valid: true}, id, nil
+}
+
+// nextCursor returns the cursor for the page after one ending with the given
+// row, or "" when the query returned no more than a page (there is no next page).
+func nextCursor(fetched, limit int, createdAt pgtype.Timestamp, id pgtype.UUID) string {
+	if fetched <= limit {
+		return ""
+	}
+	return EncodeCursor(Cursor{CreatedAt: createdAt.Time, ID: id.String()})
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC),
+		ID:        "66666666-6666-6666-6666-666666666666",
+	}
+	got, err := DecodeCursor(EncodeCursor(want))
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("Round trip = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"", "not base64!", "bm8tY29tbWE", EncodeCursor(Cursor{ID: "nope"})} {
+		if _, err := DecodeCursor(bad); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+// keysetDB answers GetUserProjectsPage from seeded projects the way Postgres
+// would, so tests can page through them
+type keysetDB struct {
+	*storetest.DB
+	projects []store.Project
+}
+
+func (db *keysetDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.DB.Query(ctx, sql, args...) // record the call
+	limit := int(args[4].(int32))
+	cursorAt, cursorID := args[1].(pgtype.Timestamp), args[2].(pgtype.UUID)
+
+	var rows [][]any
+	for _, p := range db.projects {
+		if cursorAt.Valid && !before(p, cursorAt.Time, cursorID) {
+			continue
+		}
+		if len(rows) == limit {
+			break
+		}
+		rows = append(rows, []any{p.ID, p.Name, p.Description, p.OwnerID, p.TeamID,
+			p.Status, p.CreatedAt, p.UpdatedAt, p.Version})
+	}
+	return storetest.Rows(rows), nil
+}
+
+// before reports whether (p.created_at, p.id) < (at, id)
+func before(p store.Project, at time.Time, id pgtype.UUID) bool {
+	if !p.CreatedAt.Time.Equal(at) {
+		return p.CreatedAt.Time.Before(at)
+	}
+	return bytes.Compare(p.ID.Bytes[:], id.Bytes[:]) < 0
+}
+
+func TestGetUserProjectsPageVisitsEveryProjectOnce(t *testing.T) {
+	const owner = "11111111-1111-1111-1111-111111111111"
+
+	// Several projects share a creation time so the id tie-break matters
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db := &keysetDB{DB: &storetest.DB{}}
+	for i := 0; i < 23; i++ {
+		db.projects = append(db.projects, store.Project{
+			ID:        storetest.MustUUID(t, fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1)),
+			Name:      fmt.Sprintf("Project %d", i+1),
+			OwnerID:   storetest.MustUUID(t, owner),
+			CreatedAt: pgtype.Timestamp{Time: base.Add(time.Duration(i/3) * time.Minute), Valid: true},
+			Version:   1,
+		})
+	}
+	sort.Slice(db.projects, func(i, j int) bool {
+		a, b := db.projects[i], db.projects[j]
+		if !a.CreatedAt.Time.Equal(b.CreatedAt.Time) {
+			return a.CreatedAt.Time.After(b.CreatedAt.Time)
+		}
+		return bytes.Compare(a.ID.Bytes[:], b.ID.Bytes[:]) > 0
+	})
+
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	svc := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	ctx := context.Background()
+
+	seen := make(map[string]bool)
+	var order []string
+	page := CursorPage{Limit: 5}
+	for pages := 0; ; pages++ {
+		if pages > len(db.projects) {
+			t.Fatal("Pagination did not terminate")
+		}
+		projects, next, err := svc.GetUserProjectsPage(ctx, owner, page, false)
+		if err != nil {
+			t.Fatalf("GetUserProjectsPage failed: %v", err)
+		}
+		if len(projects) > page.Limit {
+			t.Fatalf("Page has %d projects, limit is %d", len(projects), page.Limit)
+		}
+		for _, p := range projects {
+			if seen[p.ID] {
+				t.Errorf("Project %s returned twice", p.ID)
+			}
+			seen[p.ID] = true
+			order = append(order, p.ID)
+		}
+		if next == "" {
+			break
+		}
+		page.After = next
+	}
+
+	if len(seen) != len(db.projects) {
+		t.Errorf("Visited %d projects, want %d", len(seen), len(db.projects))
+	}
+	for i, p := range db.projects {
+		if i < len(order) && order[i] != p.ID.String() {
+			t.Errorf("Project %d is %s, want %s", i, order[i], p.ID.String())
+			break
+		}
+	}
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		_, _, err := svc.GetUserProjectsPage(ctx, owner, CursorPage{After: "garbage"}, false)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
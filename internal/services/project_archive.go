@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const statusArchived = "archived"
+
+// unarchivedStatus is given to unarchived projects whose earlier status is
+// unknown, such as those archived by setting their status directly
+const unarchivedStatus = "completed"
+
+// ArchiveProject hides a finished project from default listings while keeping
+// its data. Only completed or cancelled projects can be archived; archiving an
+// archived project does nothing.
+func (s *ProjectService) ArchiveProject(ctx context.Context, projectID, userID string) error {
+	project, err := s.loadOwnedProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if project.Status.String == statusArchived {
+		return nil
+	}
+	if err := checkStatusTransition(project.Status.String, statusArchived); err != nil {
+		return err
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		if err := setProjectStatus(ctx, q, project, statusArchived); err != nil {
+			return err
+		}
+		return q.SaveProjectArchive(ctx, store.SaveProjectArchiveParams{
+			ProjectID:      project.ID,
+			PreviousStatus: project.Status,
+			ArchivedBy:     userUUID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetProject(ctx, project)
+	return nil
+}
+
+// UnarchiveProject returns an archived project to the status it had before
+// it was archived. Unarchiving a project that isn't archived does nothing.
+func (s *ProjectService) UnarchiveProject(ctx context.Context, projectID, userID string) error {
+	project, err := s.loadOwnedProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if project.Status.String != statusArchived {
+		return nil
+	}
+
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		status := unarchivedStatus
+		archive, err := q.GetProjectArchive(ctx, project.ID)
+		switch {
+		case err == nil:
+			if archive.PreviousStatus.Valid {
+				status = archive.PreviousStatus.String
+			}
+		case !errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("failed to get project archive: %w", err)
+		}
+
+		if err := setProjectStatus(ctx, q, project, status); err != nil {
+			return err
+		}
+		return q.DeleteProjectArchive(ctx, project.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetProject(ctx, project)
+	return nil
+}
+
+// loadOwnedProject fetches a project, checking that the user owns it
+func (s *ProjectService) loadOwnedProject(ctx context.Context, projectID, userID string) (*store.Project, error) {
+	var projectUUID pgtype.UUID
+	if err := projectUUID.Scan(projectID); err != nil {
+		return nil, invalidID("project ID", err)
+	}
+
+	project, err := s.queries.GetProjectByID(ctx, projectUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	if err := s.verifyProjectOwnership(&project, userID); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// setProjectStatus changes the status of the project as it was read,
+// returning ErrConcurrentModification if it has changed since
+func setProjectStatus(ctx context.Context, q *store.Queries, project *store.Project, status string) error {
+	rows, err := q.UpdateProjectDetails(ctx, store.UpdateProjectDetailsParams{
+		ID:      project.ID,
+		Name:    project.Name, // An empty name would replace it
+		Status:  pgtype.Text{String: status, Valid: true},
+		Version: project.Version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+	if rows == 0 {
+		return ErrConcurrentModification
+	}
+	return nil
+}
+
+// forgetProject drops the cached project and the cached team list it appears in
+func (s *ProjectService) forgetProject(ctx context.Context, project *store.Project) {
+	keys := []string{fmt.Sprintf("project:%s", project.ID.String())}
+	if project.TeamID.Valid {
+		keys = append(keys, fmt.Sprintf("team:%s:projects", project.TeamID.String()))
+	}
+	if err := s.cache.Del(ctx, keys...); err != nil {
+		log.Printf("Failed to invalidate project caches: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestArchiveProject(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		other    = "22222222-2222-2222-2222-222222222222"
+		finished = "55555555-5555-5555-5555-555555555555"
+		ongoing  = "66666666-6666-6666-6666-666666666666"
+	)
+	created := pgtype.Timestamp{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	project := func(id, name, status string) *store.Project {
+		return &store.Project{
+			ID:        storetest.MustUUID(t, id),
+			Name:      name,
+			OwnerID:   storetest.MustUUID(t, owner),
+			Status:    pgtype.Text{String: status, Valid: true},
+			CreatedAt: created,
+			Version:   1,
+		}
+	}
+
+	db := &projectDB{
+		DB: &storetest.DB{},
+		projects: []*store.Project{
+			project(ongoing, "Ongoing", "active"),
+			project(finished, "Finished", "cancelled"),
+		},
+		archives: map[pgtype.UUID]store.ProjectArchive{},
+	}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	svc := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	ctx := context.Background()
+
+	listed := func(t *testing.T, includeArchived bool) map[string]string {
+		t.Helper()
+		page, _, err := svc.GetUserProjectsPage(ctx, owner, CursorPage{}, includeArchived)
+		if err != nil {
+			t.Fatalf("GetUserProjectsPage failed: %v", err)
+		}
+		all, err := svc.GetUserProjects(ctx, owner, includeArchived)
+		if err != nil {
+			t.Fatalf("GetUserProjects failed: %v", err)
+		}
+		if len(all) != len(page) {
+			t.Errorf("GetUserProjects listed %d projects, the first page %d", len(all), len(page))
+		}
+		statuses := map[string]string{}
+		for _, p := range page {
+			statuses[p.Name] = p.Status
+		}
+		return statuses
+	}
+
+	t.Run("Hidden once archived", func(t *testing.T) {
+		if err := svc.ArchiveProject(ctx, finished, owner); err != nil {
+			t.Fatalf("ArchiveProject failed: %v", err)
+		}
+		if status := db.project(storetest.MustUUID(t, finished)).Status.String; status != statusArchived {
+			t.Errorf("Status = %q, want %q", status, statusArchived)
+		}
+
+		if got := listed(t, false); len(got) != 1 || got["Ongoing"] == "" {
+			t.Errorf("Default listing = %v, want only Ongoing", got)
+		}
+		if got := listed(t, true); got["Finished"] != statusArchived || got["Ongoing"] == "" {
+			t.Errorf("Listing with archived = %v, want Finished archived and Ongoing", got)
+		}
+	})
+
+	t.Run("Archiving twice does nothing", func(t *testing.T) {
+		updates := db.Count("UpdateProjectDetails")
+		if err := svc.ArchiveProject(ctx, finished, owner); err != nil {
+			t.Fatalf("ArchiveProject failed: %v", err)
+		}
+		if n := db.Count("UpdateProjectDetails"); n != updates {
+			t.Errorf("Expected no update, got %d", n-updates)
+		}
+	})
+
+	t.Run("Unarchive restores the previous status", func(t *testing.T) {
+		if err := svc.UnarchiveProject(ctx, finished, owner); err != nil {
+			t.Fatalf("UnarchiveProject failed: %v", err)
+		}
+		if got := listed(t, false); got["Finished"] != "cancelled" {
+			t.Errorf("Default listing = %v, want Finished cancelled", got)
+		}
+		if len(db.archives) != 0 {
+			t.Errorf("Expected the archive record to be removed, got %v", db.archives)
+		}
+	})
+
+	t.Run("Unknown previous status", func(t *testing.T) {
+		p := db.project(storetest.MustUUID(t, finished))
+		p.Status = pgtype.Text{String: statusArchived, Valid: true}
+		if err := svc.UnarchiveProject(ctx, finished, owner); err != nil {
+			t.Fatalf("UnarchiveProject failed: %v", err)
+		}
+		if p.Status.String != unarchivedStatus {
+			t.Errorf("Status = %q, want %q", p.Status.String, unarchivedStatus)
+		}
+	})
+
+	t.Run("Active project", func(t *testing.T) {
+		if err := svc.ArchiveProject(ctx, ongoing, owner); !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Errorf("Expected ErrInvalidStatusTransition, got %v", err)
+		}
+	})
+
+	t.Run("Not the owner", func(t *testing.T) {
+		if err := svc.ArchiveProject(ctx, finished, other); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("Expected ErrNotProjectOwner, got %v", err)
+		}
+		if err := svc.UnarchiveProject(ctx, finished, other); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("Expected ErrNotProjectOwner, got %v", err)
+		}
+	})
+
+	t.Run("Missing project", func(t *testing.T) {
+		if err := svc.ArchiveProject(ctx, "77777777-7777-7777-7777-777777777777", owner); !errors.Is(err, ErrProjectNotFound) {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+	})
+}
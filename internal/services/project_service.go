@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"time"
 
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bethel-nz/tickit/internal/sanitize"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -19,6 +21,12 @@ var (
 	ErrInvalidProjectData = errors.New("invalid project data")
 	ErrNotProjectOwner    = errors.New("user is not the project owner")
 	ErrNotTeamProject     = errors.New("project is not associated with this team")
+
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+	// ErrConcurrentModification is returned when an update was based on a
+	// stale version of an issue or project
+	ErrConcurrentModification = errors.New("resource was modified by another request")
 )
 
 // ProjectStats represents project statistics
@@ -35,14 +43,16 @@ type ProjectStats struct {
 
 // ProjectInfo represents project information returned to clients
 type ProjectInfo struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	OwnerID     string `json:"owner_id"`
-	TeamID      string `json:"team_id,omitempty"`
-	Status      string `json:"status"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at,omitempty"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	OwnerID     string  `json:"owner_id"`
+	TeamID      string  `json:"team_id,omitempty"`
+	Status      string  `json:"status"`
+	CreatedAt   *string `json:"created_at"`
+	UpdatedAt   *string `json:"updated_at"`
+	Version     int32   `json:"version,omitempty"`
+	Slug        string  `json:"slug,omitempty"`
 }
 
 // ProjectUpdates contains fields that can be updated for a project
@@ -50,24 +60,33 @@ type ProjectUpdates struct {
 	Name        string
 	Description string
 	Status      string
+	Version     int32 // Version the client last saw; 0 updates whatever is current
 }
 
 type ProjectService struct {
 	queries     *store.Queries
-	cache       *redis.Client
+	cache       cache.Cache
+	ttls        CacheTTLs
 	teamService *TeamService
+	audit       *AuditService
+	attachments storage.Storage // Where attachment contents are kept, so they go with the project
+
+	slugsFollowNames bool // Renaming a project changes its slug
 }
 
-func NewProjectService(queries *store.Queries, cache *redis.Client, teamService *TeamService) *ProjectService {
+func NewProjectService(queries *store.Queries, cache cache.Cache, teamService *TeamService, ttls CacheTTLs) *ProjectService {
 	return &ProjectService{
 		queries:     queries,
 		cache:       cache,
+		ttls:        ttls.withDefaults(),
 		teamService: teamService,
 	}
 }
 
 // CreateProject creates a new project with the provided information
 func (s *ProjectService) CreateProject(ctx context.Context, params store.CreateProjectParams, userID string) (*store.Project, error) {
+	params.Name = sanitize.Text(params.Name)
+	params.Description.String = sanitize.Text(params.Description.String)
 	if params.Name == "" {
 		return nil, fmt.Errorf("%w: project name is required", ErrInvalidProjectData)
 	}
@@ -84,12 +103,20 @@ func (s *ProjectService) CreateProject(ctx context.Context, params store.CreateP
 
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID format: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 
 	params.OwnerID = scannedUserId
 
-	project, err := s.queries.CreateProject(ctx, params)
+	var project store.Project
+	err := retrySlug(func() error {
+		var err error
+		if params.Slug, err = s.projectSlug(ctx, params.Name, pgtype.UUID{}); err != nil {
+			return err
+		}
+		project, err = s.queries.CreateProject(ctx, params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create project: %w", err)
 	}
@@ -102,11 +129,11 @@ func (s *ProjectService) CreateProject(ctx context.Context, params store.CreateP
 func (s *ProjectService) GetProjectByID(ctx context.Context, projectID string, userID string) (*store.Project, error) {
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return nil, fmt.Errorf("invalid project ID: %w", err)
+		return nil, invalidID("project ID", err)
 	}
 
 	cacheKey := fmt.Sprintf("project:%s", projectID)
-	cachedProject, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedProject, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var project store.Project
 		if err := json.Unmarshal([]byte(cachedProject), &project); err == nil {
@@ -132,23 +159,19 @@ func (s *ProjectService) GetProjectByID(ctx context.Context, projectID string, u
 	return &project, nil
 }
 
-// GetUserProjects retrieves all projects owned by or accessible to a user
-func (s *ProjectService) GetUserProjects(ctx context.Context, userID string) ([]ProjectInfo, error) {
+// GetUserProjects retrieves all projects a user owns or can see through one
+// of their teams. Archived projects are left out unless includeArchived is
+// set. The list isn't cached, as it changes whenever a teammate adds a project.
+func (s *ProjectService) GetUserProjects(ctx context.Context, userID string, includeArchived bool) ([]ProjectInfo, error) {
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
-	}
-
-	cacheKey := fmt.Sprintf("user:%s:projects", userID)
-	cachedProjects, err := s.cache.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var projects []ProjectInfo
-		if err := json.Unmarshal([]byte(cachedProjects), &projects); err == nil {
-			return projects, nil
-		}
+		return nil, invalidID("user ID", err)
 	}
 
-	dbProjects, err := s.queries.GetUserProjects(ctx, userUUID)
+	dbProjects, err := s.queries.GetAccessibleProjects(ctx, store.GetAccessibleProjectsParams{
+		UserID:          userUUID,
+		IncludeArchived: includeArchived,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user projects: %w", err)
 	}
@@ -162,26 +185,66 @@ func (s *ProjectService) GetUserProjects(ctx context.Context, userID string) ([]
 			OwnerID:     p.OwnerID.String(),
 			TeamID:      p.TeamID.String(),
 			Status:      p.Status.String,
-			CreatedAt:   p.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:   p.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:   FormatTimestamp(p.CreatedAt),
+			UpdatedAt:   FormatTimestamp(p.UpdatedAt),
+			Version:     p.Version,
+			Slug:        p.Slug,
 		}
 	}
 
-	projectsJSON, err := json.Marshal(projects)
-	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, projectsJSON, 10*time.Minute).Err(); err != nil {
-			log.Printf("Failed to cache user projects: %v", err)
-		}
+	return projects, nil
+}
+
+// GetUserProjectsPage retrieves one page of the projects a user owns or can
+// see through one of their teams, newest first, along with the cursor of the next page ("" on the last page).
+// Archived projects are left out unless includeArchived is set.
+func (s *ProjectService) GetUserProjectsPage(ctx context.Context, userID string, page CursorPage, includeArchived bool) ([]ProjectInfo, string, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, "", invalidID("user ID", err)
 	}
 
-	return projects, nil
+	page = page.normalize()
+	createdAt, id, err := page.keyset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	dbProjects, err := s.queries.GetUserProjectsPage(ctx, store.GetUserProjectsPageParams{
+		UserID:          userUUID,
+		Limit:           int32(page.Limit + 1),
+		CursorCreatedAt: createdAt,
+		CursorID:        id,
+		IncludeArchived: includeArchived,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user projects: %w", err)
+	}
+
+	fetched := len(dbProjects)
+	if fetched > page.Limit {
+		dbProjects = dbProjects[:page.Limit]
+	}
+
+	projects := make([]ProjectInfo, 0, len(dbProjects))
+	for _, p := range dbProjects {
+		projects = append(projects, s.projectToInfo(p))
+	}
+
+	var next string
+	if len(dbProjects) > 0 {
+		last := dbProjects[len(dbProjects)-1]
+		next = nextCursor(fetched, page.Limit, last.CreatedAt, last.ID)
+	}
+	return projects, next, nil
 }
 
 // GetTeamProjects retrieves all projects associated with a team
 func (s *ProjectService) GetTeamProjects(ctx context.Context, teamID string, userID string) ([]ProjectInfo, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return nil, fmt.Errorf("invalid team ID: %w", err)
+		return nil, invalidID("team ID", err)
 	}
 
 	isMember, err := s.teamService.CheckTeamMembership(ctx, teamID, userID)
@@ -193,7 +256,7 @@ func (s *ProjectService) GetTeamProjects(ctx context.Context, teamID string, use
 	}
 
 	cacheKey := fmt.Sprintf("team:%s:projects", teamID)
-	cachedProjects, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedProjects, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var projects []ProjectInfo
 		if err := json.Unmarshal([]byte(cachedProjects), &projects); err == nil {
@@ -216,15 +279,17 @@ func (s *ProjectService) GetTeamProjects(ctx context.Context, teamID string, use
 			OwnerID:     p.OwnerID.String(),
 			TeamID:      p.TeamID.String(),
 			Status:      p.Status.String,
-			CreatedAt:   p.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:   p.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:   FormatTimestamp(p.CreatedAt),
+			UpdatedAt:   FormatTimestamp(p.UpdatedAt),
+			Version:     p.Version,
+			Slug:        p.Slug,
 		}
 	}
 
 	// Cache the result
 	projectsJSON, err := json.Marshal(projects)
 	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, projectsJSON, 10*time.Minute).Err(); err != nil {
+		if err := s.cache.Set(ctx, cacheKey, string(projectsJSON), s.ttls.ProjectList); err != nil {
 			log.Printf("Failed to cache team projects: %v", err)
 		}
 	}
@@ -236,7 +301,7 @@ func (s *ProjectService) GetTeamProjects(ctx context.Context, teamID string, use
 func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, updates ProjectUpdates, userID string) error {
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return fmt.Errorf("invalid project ID: %w", err)
+		return invalidID("project ID", err)
 	}
 
 	project, err := s.queries.GetProjectByID(ctx, projectUUID)
@@ -248,40 +313,63 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, up
 		return err
 	}
 
+	if updates.Version != 0 && updates.Version != project.Version {
+		return ErrConcurrentModification
+	}
+
 	params := store.UpdateProjectDetailsParams{
-		ID: projectUUID,
+		ID:      projectUUID,
+		Version: project.Version,
 	}
 
 	if updates.Name != "" {
-		params.Name = updates.Name
+		params.Name = sanitize.Text(updates.Name)
+		if params.Name == "" {
+			return fmt.Errorf("%w: project name cannot be empty", ErrInvalidProjectData)
+		}
 	}
 
 	if updates.Description != "" {
-		params.Description = pgtype.Text{String: updates.Description, Valid: true}
+		params.Description = pgtype.Text{String: sanitize.Text(updates.Description), Valid: true}
 	}
 
 	if updates.Status != "" {
 		if !isValidStatus(updates.Status) {
 			return fmt.Errorf("%w: invalid status", ErrInvalidProjectData)
 		}
+		if err := checkStatusTransition(project.Status.String, updates.Status); err != nil {
+			return err
+		}
 		params.Status = pgtype.Text{String: updates.Status, Valid: true}
 	}
 
-	if err := s.queries.UpdateProjectDetails(ctx, params); err != nil {
+	renamed := s.slugsFollowNames && params.Name != "" && params.Name != project.Name
+	var rows int64
+	err = retrySlug(func() error {
+		if renamed {
+			newSlug, err := s.projectSlug(ctx, params.Name, projectUUID)
+			if err != nil {
+				return err
+			}
+			params.Slug = pgtype.Text{String: newSlug, Valid: true}
+		}
+		var err error
+		rows, err = s.queries.UpdateProjectDetails(ctx, params)
+		return err
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
+	if rows == 0 {
+		// Someone else updated the project after we read it
+		return ErrConcurrentModification
+	}
 
 	cacheKey := fmt.Sprintf("project:%s", projectID)
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
+	if err := s.cache.Del(ctx, cacheKey); err != nil {
 		log.Printf("Failed to invalidate project cache: %v", err)
 	}
 
-	var userUUID pgtype.UUID
-	if err := userUUID.Scan(userID); err == nil {
-		userCacheKey := fmt.Sprintf("user:%s:projects", userID)
-		s.cache.Del(ctx, userCacheKey)
-	}
-
 	if project.TeamID.Valid {
 		teamCacheKey := fmt.Sprintf("team:%s:projects", project.TeamID.String())
 		s.cache.Del(ctx, teamCacheKey)
@@ -294,7 +382,7 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, up
 func (s *ProjectService) DeleteProject(ctx context.Context, projectID string, userID string) error {
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return fmt.Errorf("invalid project ID: %w", err)
+		return invalidID("project ID", err)
 	}
 
 	project, err := s.queries.GetProjectByID(ctx, projectUUID)
@@ -306,23 +394,37 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID string, us
 		return err
 	}
 
+	// Deleting the project cascades to its attachments but not to the stored
+	// files, so they're looked up while the rows still exist
+	var attachmentKeys []string
+	if s.attachments != nil {
+		attachmentKeys, err = s.queries.GetProjectAttachmentKeys(ctx, projectUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get attachments: %w", err)
+		}
+	}
+
 	if err := s.queries.DeleteProject(ctx, projectUUID); err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
+	removeAttachmentFiles(ctx, s.attachments, attachmentKeys)
 
 	cacheKey := fmt.Sprintf("project:%s", projectID)
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
+	if err := s.cache.Del(ctx, cacheKey); err != nil {
 		log.Printf("Failed to invalidate project cache: %v", err)
 	}
 
-	userCacheKey := fmt.Sprintf("user:%s:projects", userID)
-	s.cache.Del(ctx, userCacheKey)
-
 	if project.TeamID.Valid {
 		teamCacheKey := fmt.Sprintf("team:%s:projects", project.TeamID.String())
 		s.cache.Del(ctx, teamCacheKey)
 	}
 
+	metadata := map[string]any{"name": project.Name}
+	if project.TeamID.Valid {
+		metadata["team_id"] = project.TeamID.String()
+	}
+	s.audit.Record(ctx, userID, AuditProjectDeleted, AuditTargetProject, projectID, metadata)
+
 	return nil
 }
 
@@ -330,7 +432,7 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID string, us
 func (s *ProjectService) GetProjectStats(ctx context.Context, projectID string, userID string) (*ProjectStats, error) {
 	var projectUUID pgtype.UUID
 	if err := projectUUID.Scan(projectID); err != nil {
-		return nil, fmt.Errorf("invalid project ID: %w", err)
+		return nil, invalidID("project ID", err)
 	}
 
 	// Get the project to check access
@@ -343,39 +445,25 @@ func (s *ProjectService) GetProjectStats(ctx context.Context, projectID string,
 		return nil, err
 	}
 
-	cacheKey := fmt.Sprintf("project:%s:stats", projectID)
-	cachedStats, err := s.cache.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var stats ProjectStats
-		if err := json.Unmarshal([]byte(cachedStats), &stats); err == nil {
-			return &stats, nil
+	// Counting issues and tasks is expensive on big projects, so a single
+	// request rebuilds the stats when they expire
+	return loadCached(ctx, s.cache, projectStatsKey(projectID), s.ttls.ProjectStats, func() (*ProjectStats, error) {
+		dbStats, err := s.queries.GetProjectStats(ctx, projectUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project stats: %w", err)
 		}
-	}
-
-	dbStats, err := s.queries.GetProjectStats(ctx, projectUUID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get project stats: %w", err)
-	}
-
-	stats := &ProjectStats{
-		TotalIssues:      int(dbStats.TotalIssues),
-		OpenIssues:       int(dbStats.OpenIssues),
-		InProgressIssues: int(dbStats.InProgressIssues),
-		ClosedIssues:     int(dbStats.ClosedIssues),
-		TotalTasks:       int(dbStats.TotalTasks),
-		TodoTasks:        int(dbStats.TodoTasks),
-		InProgressTasks:  int(dbStats.InProgressTasks),
-		DoneTasks:        int(dbStats.DoneTasks),
-	}
 
-	statsJSON, err := json.Marshal(stats)
-	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, statsJSON, 5*time.Minute).Err(); err != nil {
-			log.Printf("Failed to cache project stats: %v", err)
-		}
-	}
-
-	return stats, nil
+		return &ProjectStats{
+			TotalIssues:      int(dbStats.TotalIssues),
+			OpenIssues:       int(dbStats.OpenIssues),
+			InProgressIssues: int(dbStats.InProgressIssues),
+			ClosedIssues:     int(dbStats.ClosedIssues),
+			TotalTasks:       int(dbStats.TotalTasks),
+			TodoTasks:        int(dbStats.TodoTasks),
+			InProgressTasks:  int(dbStats.InProgressTasks),
+			DoneTasks:        int(dbStats.DoneTasks),
+		}, nil
+	})
 }
 
 // Helper method to cache a project
@@ -391,7 +479,7 @@ func (s *ProjectService) cacheProject(ctx context.Context, project *store.Projec
 	}
 
 	cacheKey := fmt.Sprintf("project:%s", project.ID.String())
-	if err := s.cache.Set(ctx, cacheKey, projectJSON, time.Hour).Err(); err != nil {
+	if err := s.cache.Set(ctx, cacheKey, string(projectJSON), s.ttls.Project); err != nil {
 		log.Printf("Failed to cache project: %v", err)
 	}
 }
@@ -400,7 +488,7 @@ func (s *ProjectService) cacheProject(ctx context.Context, project *store.Projec
 func (s *ProjectService) verifyProjectOwnership(project *store.Project, userID string) error {
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	if project.OwnerID != userUUID {
@@ -414,7 +502,7 @@ func (s *ProjectService) verifyProjectOwnership(project *store.Project, userID s
 func (s *ProjectService) verifyProjectAccess(ctx context.Context, project *store.Project, userID string) error {
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	if project.OwnerID == userUUID {
@@ -436,6 +524,71 @@ func (s *ProjectService) verifyProjectAccess(ctx context.Context, project *store
 	return nil
 }
 
+// CanAccessProject reports whether a user owns the project or belongs to its
+// team. It reads only the project's owner and team, so it is cheaper than
+// GetProjectByID on paths that don't need the project itself.
+func (s *ProjectService) CanAccessProject(ctx context.Context, projectID, userID string) (bool, error) {
+	var projectUUID pgtype.UUID
+	if err := projectUUID.Scan(projectID); err != nil {
+		return false, invalidID("project ID", err)
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return false, invalidID("user ID", err)
+	}
+
+	access, err := s.queries.GetProjectAccess(ctx, projectUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrProjectNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	if access.OwnerID == userUUID {
+		return true, nil
+	}
+	if !access.TeamID.Valid {
+		return false, nil
+	}
+
+	isMember, err := s.queries.CheckTeamMembership(ctx, store.CheckTeamMembershipParams{
+		TeamID: access.TeamID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check team membership: %w", err)
+	}
+	return isMember, nil
+}
+
+// requireProjectAccess is CanAccessProject for callers that only need to stop
+// when access is denied
+func (s *ProjectService) requireProjectAccess(ctx context.Context, projectID, userID string) error {
+	ok, err := s.CanAccessProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotProjectOwner
+	}
+	return nil
+}
+
+// projectStatsKey is where a project's stats are cached
+func projectStatsKey(projectID string) string {
+	return fmt.Sprintf("project:%s:stats", projectID)
+}
+
+// invalidateStats drops a project's cached stats. Anything that creates,
+// deletes or changes the status of a project's issues or tasks must call it.
+func (s *ProjectService) invalidateStats(ctx context.Context, projectID string) {
+	if err := s.cache.Del(ctx, projectStatsKey(projectID)); err != nil {
+		log.Printf("Failed to invalidate project stats cache: %v", err)
+	}
+}
+
 // projectToInfo converts a store.Project to a ProjectInfo
 func (s *ProjectService) projectToInfo(p store.Project) ProjectInfo {
 	return ProjectInfo{
@@ -445,8 +598,10 @@ func (s *ProjectService) projectToInfo(p store.Project) ProjectInfo {
 		OwnerID:     p.OwnerID.String(),
 		TeamID:      p.TeamID.String(),
 		Status:      p.Status.String,
-		CreatedAt:   p.CreatedAt.Time.Format(time.RFC3339),
-		UpdatedAt:   p.UpdatedAt.Time.Format(time.RFC3339),
+		CreatedAt:   FormatTimestamp(p.CreatedAt),
+		UpdatedAt:   FormatTimestamp(p.UpdatedAt),
+		Version:     p.Version,
+		Slug:        p.Slug,
 	}
 }
 
@@ -459,7 +614,7 @@ func (s *ProjectService) GetProjectsByStatus(ctx context.Context, status string,
 
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID format: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 
 	var statusText pgtype.Text
@@ -484,23 +639,10 @@ func (s *ProjectService) GetProjectsByStatus(ctx context.Context, status string,
 			OwnerID:     p.OwnerID.String(),
 			TeamID:      p.TeamID.String(),
 			Status:      p.Status.String,
-			CreatedAt:   p.CreatedAt.Time.Format(time.RFC3339),
-			UpdatedAt:   p.UpdatedAt.Time.Format(time.RFC3339),
+			CreatedAt:   FormatTimestamp(p.CreatedAt),
+			UpdatedAt:   FormatTimestamp(p.UpdatedAt),
 		})
 	}
 
 	return result, nil
 }
-
-func isValidStatus(status string) bool {
-	validStatuses := map[string]bool{
-		"planned":   true,
-		"active":    true,
-		"completed": true,
-		"on_hold":   true,
-		"cancelled": true,
-		"archived":  true,
-	}
-
-	return validStatuses[status]
-}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCanAccessProjectMatchesGetProjectByID(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		shared   = "55555555-5555-5555-5555-555555555555" // team project
+		personal = "66666666-6666-6666-6666-666666666666" // no team
+		missing  = "77777777-7777-7777-7777-777777777777"
+	)
+
+	project := func(id string, teamID pgtype.UUID) []any {
+		return []any{storetest.MustUUID(t, id), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), teamID}
+	}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetProjectByID:" + shared:                     project(shared, storetest.MustUUID(t, team)),
+		"GetProjectByID:" + personal:                   project(personal, pgtype.UUID{}),
+		"GetProjectAccess:" + shared:                   {storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+		"GetProjectAccess:" + personal:                 {storetest.MustUUID(t, owner), pgtype.UUID{}},
+		"CheckTeamMembership:" + team + ":" + member:   {true},
+		"CheckTeamMembership:" + team + ":" + outsider: {false},
+	}}
+	mr := miniredis.RunT(t)
+	redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	queries := store.New(db)
+	svc := NewProjectService(queries, redisCache, NewTeamService(queries, redisCache, CacheTTLs{}), CacheTTLs{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		projectID string
+		userID    string
+		want      bool
+	}{
+		{"Owner of team project", shared, owner, true},
+		{"Member of project team", shared, member, true},
+		{"Outsider to project team", shared, outsider, false},
+		{"Owner of personal project", personal, owner, true},
+		{"Other user on personal project", personal, member, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr.FlushAll()
+			got, err := svc.CanAccessProject(ctx, tt.projectID, tt.userID)
+			if err != nil {
+				t.Fatalf("CanAccessProject failed: %v", err)
+			}
+			_, fullErr := svc.GetProjectByID(ctx, tt.projectID, tt.userID)
+
+			if got != tt.want {
+				t.Errorf("CanAccessProject = %v, want %v", got, tt.want)
+			}
+			if got != (fullErr == nil) {
+				t.Errorf("CanAccessProject = %v but GetProjectByID returned %v", got, fullErr)
+			}
+		})
+	}
+
+	t.Run("Missing project", func(t *testing.T) {
+		if _, err := svc.CanAccessProject(ctx, missing, owner); !errors.Is(err, ErrProjectNotFound) {
+			t.Errorf("Expected ErrProjectNotFound, got %v", err)
+		}
+		if _, err := svc.GetProjectByID(ctx, missing, owner); err == nil {
+			t.Error("Expected GetProjectByID to fail too")
+		}
+	})
+
+	t.Run("Does not load the full project", func(t *testing.T) {
+		db.Calls = nil
+		if _, err := svc.CanAccessProject(ctx, shared, member); err != nil {
+			t.Fatalf("CanAccessProject failed: %v", err)
+		}
+		if n := db.Count("GetProjectByID"); n != 0 {
+			t.Errorf("Expected no GetProjectByID queries, got %d", n)
+		}
+	})
+}
+
+func TestUpdateProjectVersionCheck(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	newService := func() (*storetest.DB, *ProjectService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetProjectByID": {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), pgtype.UUID{},
+					pgtype.Text{String: "active", Valid: true}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(5)},
+			},
+			Affected: map[string]int64{},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	}
+	ctx := context.Background()
+
+	t.Run("Fresh version is applied", func(t *testing.T) {
+		db, svc := newService()
+		if err := svc.UpdateProject(ctx, project, ProjectUpdates{Name: "Tickit v2", Version: 5}, owner); err != nil {
+			t.Fatalf("UpdateProject failed: %v", err)
+		}
+		calls := db.Args("UpdateProjectDetails")
+		if len(calls) != 1 {
+			t.Fatalf("Expected one UpdateProjectDetails call, got %d", len(calls))
+		}
+		if got := calls[0][6]; got != int32(5) {
+			t.Errorf("Expected update guarded by version 5, got %v", got)
+		}
+	})
+
+	t.Run("Stale version is rejected", func(t *testing.T) {
+		db, svc := newService()
+		err := svc.UpdateProject(ctx, project, ProjectUpdates{Name: "Tickit v2", Version: 4}, owner)
+		if !errors.Is(err, ErrConcurrentModification) {
+			t.Fatalf("Expected ErrConcurrentModification, got %v", err)
+		}
+		if n := db.Count("UpdateProjectDetails"); n != 0 {
+			t.Errorf("Expected no update, got %d", n)
+		}
+	})
+
+	t.Run("Write between read and update is rejected", func(t *testing.T) {
+		db, svc := newService()
+		db.Affected["UpdateProjectDetails"] = 0
+		err := svc.UpdateProject(ctx, project, ProjectUpdates{Name: "Tickit v2"}, owner)
+		if !errors.Is(err, ErrConcurrentModification) {
+			t.Fatalf("Expected ErrConcurrentModification, got %v", err)
+		}
+	})
+}
+
+func TestDeleteProjectRemovesAttachmentFiles(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+	keys := []string{"attachments/issue-1/a", "attachments/issue-2/b"}
+
+	newService := func(t *testing.T) (*storetest.DB, *ProjectService, *storage.LocalStorage) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetProjectByID": {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), pgtype.UUID{},
+					pgtype.Text{String: "active", Valid: true}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(1)},
+			},
+			Lists: map[string][][]any{
+				"GetProjectAttachmentKeys:" + project: {{keys[0]}, {keys[1]}},
+			},
+		}
+		files, err := storage.NewLocalStorage(t.TempDir(), "")
+		if err != nil {
+			t.Fatalf("NewLocalStorage failed: %v", err)
+		}
+		for _, key := range keys {
+			if _, err := files.Put(context.Background(), key, strings.NewReader("contents"), "text/plain"); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		svc := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+		svc.attachments = files
+		return db, svc, files
+	}
+	stored := func(files *storage.LocalStorage, key string) bool {
+		r, err := files.Open(context.Background(), key)
+		if err == nil {
+			r.Close()
+		}
+		return err == nil
+	}
+	ctx := context.Background()
+
+	t.Run("Files go with the project", func(t *testing.T) {
+		_, svc, files := newService(t)
+		if err := svc.DeleteProject(ctx, project, owner); err != nil {
+			t.Fatalf("DeleteProject failed: %v", err)
+		}
+		for _, key := range keys {
+			if stored(files, key) {
+				t.Errorf("File %s survived the project", key)
+			}
+		}
+	})
+
+	t.Run("Files stay when the project does", func(t *testing.T) {
+		db, svc, files := newService(t)
+		db.Errs = map[string]error{"DeleteProject": errors.New("connection reset")}
+		if err := svc.DeleteProject(ctx, project, owner); err == nil {
+			t.Fatal("Expected an error")
+		}
+		for _, key := range keys {
+			if !stored(files, key) {
+				t.Errorf("File %s was removed though the project wasn't", key)
+			}
+		}
+	})
+}
+
+func TestProjectStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{"planned", "active", true},
+		{"active", "completed", true},
+		{"active", "on_hold", true},
+		{"on_hold", "active", true},
+		{"planned", "cancelled", true},
+		{"completed", "archived", true},
+		{"cancelled", "archived", true},
+		{"active", "active", true},
+		{"", "active", true},
+		{"archived", "active", false},
+		{"cancelled", "completed", false},
+		{"planned", "completed", false},
+		{"completed", "active", false},
+		{"on_hold", "completed", false},
+		{"archived", "cancelled", false},
+	}
+	for _, tt := range tests {
+		err := checkStatusTransition(tt.from, tt.to)
+		if tt.allowed && err != nil {
+			t.Errorf("%s → %s rejected: %v", tt.from, tt.to, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Errorf("%s → %s = %v, want ErrInvalidStatusTransition", tt.from, tt.to, err)
+		}
+	}
+
+	t.Run("Every target is a known status", func(t *testing.T) {
+		for from, targets := range projectStatusTransitions {
+			for _, to := range targets {
+				if !isValidStatus(to) {
+					t.Errorf("%s → %s targets an unknown status", from, to)
+				}
+			}
+		}
+	})
+
+	t.Run("UpdateProject rejects an illegal transition", func(t *testing.T) {
+		const (
+			owner   = "11111111-1111-1111-1111-111111111111"
+			project = "55555555-5555-5555-5555-555555555555"
+		)
+		db := &storetest.DB{Rows: map[string][]any{
+			"GetProjectByID": {storetest.MustUUID(t, project), "Tickit", pgtype.Text{}, storetest.MustUUID(t, owner), pgtype.UUID{},
+				pgtype.Text{String: "archived", Valid: true}},
+		}}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		svc := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+
+		err := svc.UpdateProject(context.Background(), project, ProjectUpdates{Status: "active"}, owner)
+		if !errors.Is(err, ErrInvalidStatusTransition) {
+			t.Fatalf("Expected ErrInvalidStatusTransition, got %v", err)
+		}
+		if db.Count("UpdateProjectDetails") != 0 {
+			t.Error("Illegal transition was saved")
+		}
+	})
+}
+
+func TestTeamMembersSeeTeamProjects(t *testing.T) {
+	const (
+		lead     = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		outsider = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+	)
+	project := func(n int, name, owner, teamID string) *store.Project {
+		p := &store.Project{
+			ID:      storetest.MustUUID(t, fmt.Sprintf("55555555-5555-5555-5555-%012d", n)),
+			Name:    name,
+			OwnerID: storetest.MustUUID(t, owner),
+			Version: 1,
+		}
+		if teamID != "" {
+			p.TeamID = storetest.MustUUID(t, teamID)
+		}
+		return p
+	}
+
+	db := &projectDB{
+		DB: &storetest.DB{},
+		projects: []*store.Project{
+			project(1, "Roadmap", lead, team),
+			project(2, "Lead's own", lead, ""),
+			project(3, "Member's team project", member, team),
+			project(4, "Elsewhere", outsider, ""),
+		},
+		members: map[pgtype.UUID][]pgtype.UUID{
+			storetest.MustUUID(t, team): {storetest.MustUUID(t, lead), storetest.MustUUID(t, member)},
+		},
+	}
+	memory := cache.NewMemory()
+	queries := store.New(db)
+	svc := NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	ctx := context.Background()
+
+	names := func(projects []ProjectInfo) []string {
+		var names []string
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name string
+		user string
+		want []string
+	}{
+		{"Lead", lead, []string{"Roadmap", "Lead's own", "Member's team project"}},
+		{"Member", member, []string{"Roadmap", "Member's team project"}},
+		{"Outsider", outsider, []string{"Elsewhere"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all, err := svc.GetUserProjects(ctx, tt.user, false)
+			if err != nil {
+				t.Fatalf("GetUserProjects failed: %v", err)
+			}
+			if got := names(all); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserProjects = %v, want %v", got, tt.want)
+			}
+
+			page, _, err := svc.GetUserProjectsPage(ctx, tt.user, CursorPage{}, false)
+			if err != nil {
+				t.Fatalf("GetUserProjectsPage failed: %v", err)
+			}
+			if got := names(page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserProjectsPage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Member who owns nothing", func(t *testing.T) {
+		db.projects = db.projects[:2]
+		page, _, err := svc.GetUserProjectsPage(ctx, member, CursorPage{}, false)
+		if err != nil {
+			t.Fatalf("GetUserProjectsPage failed: %v", err)
+		}
+		if got, want := names(page), []string{"Roadmap"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetUserProjectsPage = %v, want %v", got, want)
+		}
+	})
+}
+
+// projectDB keeps projects, their teams' members and archive records in
+// memory, answering the queries used to list, archive and unarchive projects
+// as Postgres would. Projects are listed in the order they're kept.
+type projectDB struct {
+	*storetest.DB
+	projects []*store.Project
+	members  map[pgtype.UUID][]pgtype.UUID // Team members by team
+	archives map[pgtype.UUID]store.ProjectArchive
+}
+
+// canSee reports whether the user owns the project or belongs to its team
+func (db *projectDB) canSee(user pgtype.UUID, p *store.Project) bool {
+	if p.OwnerID == user {
+		return true
+	}
+	for _, member := range db.members[p.TeamID] {
+		if p.TeamID.Valid && member == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *projectDB) project(id pgtype.UUID) *store.Project {
+	for _, p := range db.projects {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func projectRow(p *store.Project) []any {
+	return []any{p.ID, p.Name, p.Description, p.OwnerID, p.TeamID, p.Status, p.CreatedAt, p.UpdatedAt, p.Version}
+}
+
+func (db *projectDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	switch name, _ := db.Record(sql, args); name {
+	case "GetProjectByID":
+		if p := db.project(args[0].(pgtype.UUID)); p != nil {
+			return storetest.Row(projectRow(p))
+		}
+	case "GetProjectArchive":
+		if a, ok := db.archives[args[0].(pgtype.UUID)]; ok {
+			return storetest.Row([]any{a.ProjectID, a.PreviousStatus, a.ArchivedBy, a.ArchivedAt})
+		}
+	}
+	return storetest.Row(nil)
+}
+
+func (db *projectDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	switch name, _ := db.Record(sql, args); name {
+	case "UpdateProjectDetails":
+		p := db.project(args[5].(pgtype.UUID))
+		if p == nil || p.Version != args[6].(int32) {
+			return pgconn.NewCommandTag("UPDATE 0"), nil
+		}
+		if status := args[2].(pgtype.Text); status.Valid {
+			p.Status = status
+		}
+		p.Version++
+	case "SaveProjectArchive":
+		id := args[0].(pgtype.UUID)
+		db.archives[id] = store.ProjectArchive{
+			ProjectID:      id,
+			PreviousStatus: args[1].(pgtype.Text),
+			ArchivedBy:     args[2].(pgtype.UUID),
+		}
+	case "DeleteProjectArchive":
+		delete(db.archives, args[0].(pgtype.UUID))
+	}
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (db *projectDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var includeArchived bool
+	switch name, _ := db.Record(sql, args); name {
+	case "GetAccessibleProjects":
+		includeArchived = args[1].(bool)
+	case "GetUserProjectsPage":
+		includeArchived = args[3].(bool)
+	default:
+		return nil, fmt.Errorf("unexpected query %s", name)
+	}
+
+	var rows [][]any
+	for _, p := range db.projects {
+		if db.canSee(args[0].(pgtype.UUID), p) && (includeArchived || p.Status.String != statusArchived) {
+			rows = append(rows, projectRow(p))
+		}
+	}
+	return storetest.Rows(rows), nil
+}
+
+// Begin runs transactions straight against the in-memory tables
+func (db *projectDB) Begin(context.Context) (pgx.Tx, error) {
+	return &projectTx{db: db}, nil
+}
+
+type projectTx struct {
+	pgx.Tx
+	db *projectDB
+}
+
+func (tx *projectTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *projectTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tx.db.Query(ctx, sql, args...)
+}
+
+func (tx *projectTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tx.db.QueryRow(ctx, sql, args...)
+}
+
+func (tx *projectTx) Commit(context.Context) error {
+	tx.db.Commits++
+	return nil
+}
+
+func (tx *projectTx) Rollback(context.Context) error {
+	tx.db.Rollbacks++
+	return nil
+}
+
+func TestProjectSlugs(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	newService := func() (*storetest.DB, *ProjectService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"CreateProject": {storetest.MustUUID(t, project), "My Project"},
+				"GetProjectByID": {storetest.MustUUID(t, project), "My Project", pgtype.Text{}, storetest.MustUUID(t, owner), pgtype.UUID{},
+					pgtype.Text{String: "active", Valid: true}, pgtype.Timestamp{}, pgtype.Timestamp{}, int32(1), "my-project"},
+				"GetProjectIDBySlug": {storetest.MustUUID(t, project)},
+			},
+			Lists: map[string][][]any{
+				"ListProjectSlugs": {{"my-project"}, {"my-project-3"}},
+			},
+			Errs: map[string]error{},
+		}
+		memory := cache.NewMemory()
+		queries := store.New(db)
+		return db, NewProjectService(queries, memory, NewTeamService(queries, memory, CacheTTLs{}), CacheTTLs{})
+	}
+	ctx := context.Background()
+	// The slug is the last value a project is created with and the fifth it
+	// is updated with
+	slugArg := func(call []any) any { return call[len(call)-1] }
+	updatedSlug := func(call []any) any { return call[4] }
+
+	t.Run("Duplicate names get a suffix", func(t *testing.T) {
+		db, svc := newService()
+		if _, err := svc.CreateProject(ctx, store.CreateProjectParams{Name: "My Project!"}, owner); err != nil {
+			t.Fatalf("CreateProject failed: %v", err)
+		}
+		if args := db.Args("ListProjectSlugs"); len(args) != 1 || args[0][0] != "my-project" {
+			t.Errorf("ListProjectSlugs ran with %v, want base my-project", args)
+		}
+		if args := db.Args("CreateProject"); len(args) != 1 || slugArg(args[0]) != "my-project-2" {
+			t.Errorf("CreateProject ran with %v, want slug my-project-2", args)
+		}
+	})
+
+	t.Run("Slug taken meanwhile", func(t *testing.T) {
+		db, svc := newService()
+		db.Errs["CreateProject"] = &pgconn.PgError{Code: uniqueViolation}
+		if _, err := svc.CreateProject(ctx, store.CreateProjectParams{Name: "My Project"}, owner); err == nil {
+			t.Fatal("Expected CreateProject to fail while every slug clashes")
+		}
+		if n := db.Count("CreateProject"); n != slugAttempts {
+			t.Errorf("Expected %d attempts, got %d", slugAttempts, n)
+		}
+	})
+
+	t.Run("Slug is kept on rename", func(t *testing.T) {
+		db, svc := newService()
+		if err := svc.UpdateProject(ctx, project, ProjectUpdates{Name: "Renamed"}, owner); err != nil {
+			t.Fatalf("UpdateProject failed: %v", err)
+		}
+		if args := db.Args("UpdateProjectDetails"); len(args) != 1 || updatedSlug(args[0]).(pgtype.Text).Valid {
+			t.Errorf("UpdateProjectDetails ran with %v, want the slug left alone", args)
+		}
+	})
+
+	t.Run("Slug follows the name when configured", func(t *testing.T) {
+		db, svc := newService()
+		svc.SetSlugsFollowNames(true)
+		db.Lists["ListProjectSlugs"] = nil
+		if err := svc.UpdateProject(ctx, project, ProjectUpdates{Name: "Renamed"}, owner); err != nil {
+			t.Fatalf("UpdateProject failed: %v", err)
+		}
+		if args := db.Args("ListProjectSlugs"); len(args) != 1 || args[0][1] != storetest.MustUUID(t, project) {
+			t.Errorf("ListProjectSlugs ran with %v, want the project itself excluded", args)
+		}
+		if args := db.Args("UpdateProjectDetails"); len(args) != 1 || updatedSlug(args[0]) != (pgtype.Text{String: "renamed", Valid: true}) {
+			t.Errorf("UpdateProjectDetails ran with %v, want slug renamed", args)
+		}
+	})
+
+	t.Run("Resolve by ID or slug", func(t *testing.T) {
+		db, svc := newService()
+		if id, err := svc.ResolveProjectID(ctx, project); err != nil || id != project {
+			t.Errorf("ResolveProjectID(ID) = %q, %v", id, err)
+		}
+		if n := db.Count("GetProjectIDBySlug"); n != 0 {
+			t.Errorf("Expected IDs to be used as they are, got %d slug lookups", n)
+		}
+		if id, err := svc.ResolveProjectID(ctx, "my-project"); err != nil || id != project {
+			t.Errorf("ResolveProjectID(slug) = %q, %v, want %s", id, err, project)
+		}
+
+		db.Rows["GetProjectIDBySlug"] = nil
+		if _, err := svc.ResolveProjectID(ctx, "missing"); !errors.Is(err, ErrProjectNotFound) {
+			t.Errorf("Unknown slug = %v, want ErrProjectNotFound", err)
+		}
+	})
+}
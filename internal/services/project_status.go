@@ -0,0 +1,43 @@
+package services
+
+import "fmt"
+
+// projectStatusTransitions lists, for each project status, the statuses a
+// project may move to next. It is the single source of truth for which
+// statuses exist and how projects flow through them:
+//
+//	planned → active → completed → archived
+//	active ⇄ on_hold
+//	planned, active, on_hold → cancelled → archived
+//
+// Archived projects stay archived until unarchived, which returns them to the
+// status they had before; see ArchiveProject.
+var projectStatusTransitions = map[string][]string{
+	"planned":   {"active", "cancelled"},
+	"active":    {"on_hold", "completed", "cancelled"},
+	"on_hold":   {"active", "cancelled"},
+	"completed": {"archived"},
+	"cancelled": {"archived"},
+	"archived":  {},
+}
+
+// isValidStatus reports whether status is a known project status
+func isValidStatus(status string) bool {
+	_, ok := projectStatusTransitions[status]
+	return ok
+}
+
+// checkStatusTransition returns ErrInvalidStatusTransition, naming both
+// statuses, unless a project may move from one to the other. Keeping the
+// same status is always allowed, as is setting one on a project without.
+func checkStatusTransition(from, to string) error {
+	if from == "" || from == to {
+		return nil
+	}
+	for _, next := range projectStatusTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: a project cannot move from %s to %s", ErrInvalidStatusTransition, from, to)
+}
@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
-	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -17,20 +17,20 @@ var (
 
 // SearchResult represents a generic search result
 type SearchResult struct {
-	Type        string `json:"type"`
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	ParentID    string `json:"parent_id,omitempty"`
-	CreatedAt   string `json:"created_at"`
+	Type        string  `json:"type"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	ParentID    string  `json:"parent_id,omitempty"`
+	CreatedAt   *string `json:"created_at"`
 }
 
 type SearchService struct {
 	queries *store.Queries
-	cache   *redis.Client
+	cache   cache.Cache
 }
 
-func NewSearchService(queries *store.Queries, cache *redis.Client) *SearchService {
+func NewSearchService(queries *store.Queries, cache cache.Cache) *SearchService {
 	return &SearchService{
 		queries: queries,
 		cache:   cache,
@@ -49,7 +49,7 @@ func (s *SearchService) SearchEntities(ctx context.Context, userID, query string
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 
 	var queryText pgtype.Text
@@ -74,7 +74,7 @@ func (s *SearchService) SearchEntities(ctx context.Context, userID, query string
 			ID:          r.EntityID.String(),
 			Name:        r.EntityName,
 			Description: r.EntityDescription.String,
-			CreatedAt:   r.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			CreatedAt:   FormatTimestamp(r.CreatedAt),
 		}
 
 		if r.ParentID.Valid {
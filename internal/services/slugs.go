@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/slug"
+	"github.com/Bethel-nz/tickit/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// slugAttempts is how many times a project or team is saved with a freshly
+// picked slug before giving up. Two created at once with similar names can
+// pick the same slug; the unique index turns one away and it picks again.
+const slugAttempts = 3
+
+// retrySlug runs save until it succeeds, fails for a reason other than a
+// duplicate slug, or slugAttempts are used up
+func retrySlug(save func() error) error {
+	var err error
+	for range slugAttempts {
+		err = save()
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+			return err
+		}
+	}
+	return err
+}
+
+// SetSlugsFollowNames makes renaming a project change its slug too. By
+// default slugs are kept on rename, so links to the old one keep working.
+func (s *ProjectService) SetSlugsFollowNames(follow bool) {
+	s.slugsFollowNames = follow
+}
+
+// projectSlug picks a slug for a project called name that no other project
+// has. excludeID is the project being renamed, if any, so it doesn't count
+// its own slug as taken.
+func (s *ProjectService) projectSlug(ctx context.Context, name string, excludeID pgtype.UUID) (string, error) {
+	base := slug.Make(name, "project")
+	taken, err := s.queries.ListProjectSlugs(ctx, store.ListProjectSlugsParams{Base: base, ExcludeID: excludeID})
+	if err != nil {
+		return "", fmt.Errorf("failed to check project slugs: %w", err)
+	}
+	return slug.Unique(base, taken), nil
+}
+
+// ResolveProjectID returns the ID of the project ref names, which may be its
+// ID or its slug
+func (s *ProjectService) ResolveProjectID(ctx context.Context, ref string) (string, error) {
+	if validator.IsUUID(ref) {
+		return ref, nil
+	}
+	id, err := s.queries.GetProjectIDBySlug(ctx, ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrProjectNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up project slug: %w", err)
+	}
+	return id.String(), nil
+}
+
+// SetSlugsFollowNames makes renaming a team change its slug too. By default
+// slugs are kept on rename, so links to the old one keep working.
+func (s *TeamService) SetSlugsFollowNames(follow bool) {
+	s.slugsFollowNames = follow
+}
+
+// teamSlug picks a slug for a team called name that no other team has.
+// excludeID is the team being renamed, if any, so it doesn't count its own
+// slug as taken.
+func (s *TeamService) teamSlug(ctx context.Context, name string, excludeID pgtype.UUID) (string, error) {
+	base := slug.Make(name, "team")
+	taken, err := s.queries.ListTeamSlugs(ctx, store.ListTeamSlugsParams{Base: base, ExcludeID: excludeID})
+	if err != nil {
+		return "", fmt.Errorf("failed to check team slugs: %w", err)
+	}
+	return slug.Unique(base, taken), nil
+}
+
+// ResolveTeamID returns the ID of the team ref names, which may be its ID or
+// its slug
+func (s *TeamService) ResolveTeamID(ctx context.Context, ref string) (string, error) {
+	if validator.IsUUID(ref) {
+		return ref, nil
+	}
+	id, err := s.queries.GetTeamIDBySlug(ctx, ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrTeamNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up team slug: %w", err)
+	}
+	return id.String(), nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrInvalidTimeEntry = errors.New("invalid time entry")
+)
+
+// TaskInfo represents a task assigned to the user, with the name of its
+// project for context
+type TaskInfo struct {
+	ID          string     `json:"id"`
+	ProjectID   string     `json:"project_id"`
+	ProjectName string     `json:"project_name"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	CreatedAt   *string    `json:"created_at"`
+	UpdatedAt   *string    `json:"updated_at"`
+}
+
+// TaskService handles task business logic
+type TaskService struct {
+	queries        *store.Queries
+	projectService *ProjectService
+}
+
+func NewTaskService(queries *store.Queries, projectService *ProjectService) *TaskService {
+	return &TaskService{
+		queries:        queries,
+		projectService: projectService,
+	}
+}
+
+// GetAssignedTasks retrieves the tasks assigned to the user in projects they
+// can still access. Tasks are ordered soonest due first, or latest due first
+// when latestDueFirst is set; tasks without a due date come last either way.
+// An empty status returns tasks of any status.
+func (s *TaskService) GetAssignedTasks(ctx context.Context, userID, status string, latestDueFirst bool) ([]TaskInfo, error) {
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	tasks, err := s.queries.GetUserTasks(ctx, store.GetUserTasksParams{
+		AssigneeID:     userUUID,
+		Status:         pgtype.Text{String: status, Valid: status != ""},
+		LatestDueFirst: latestDueFirst,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assigned tasks: %w", err)
+	}
+
+	result := make([]TaskInfo, 0, len(tasks))
+	for _, task := range tasks {
+		info := TaskInfo{
+			ID:          task.ID.String(),
+			ProjectID:   task.ProjectID.String(),
+			ProjectName: task.ProjectName,
+			Title:       task.Title,
+			Description: task.Description.String,
+			Status:      task.Status.String,
+			Priority:    task.Priority.String,
+			CreatedAt:   FormatTimestamp(task.CreatedAt),
+			UpdatedAt:   FormatTimestamp(task.UpdatedAt),
+		}
+
+		if task.DueDate.Valid {
+			dueDate := task.DueDate.Time
+			info.DueDate = &dueDate
+		}
+
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+// DeleteTask deletes a task along with its comments, attachments and logged
+// time. Anyone who can access the task's project can delete it.
+func (s *TaskService) DeleteTask(ctx context.Context, taskID, userID string) error {
+	task, err := s.accessibleTask(ctx, taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	// Reactions and revisions belong to their comments and cascade from them
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		if err := q.DeleteTaskComments(ctx, task.ID); err != nil {
+			return fmt.Errorf("failed to delete comments: %w", err)
+		}
+		if err := q.DeleteTaskAttachments(ctx, task.ID); err != nil {
+			return fmt.Errorf("failed to delete attachments: %w", err)
+		}
+		if err := q.DeleteTaskTimeEntries(ctx, task.ID); err != nil {
+			return fmt.Errorf("failed to delete time entries: %w", err)
+		}
+		if err := q.DeleteTask(ctx, task.ID); err != nil {
+			return fmt.Errorf("failed to delete task: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.projectService.invalidateStats(ctx, task.ProjectID.String())
+	return nil
+}
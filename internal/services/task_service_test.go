@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// taskDB answers GetUserTasks from its tasks like the tasks table joined to
+// projects: it keeps the assignee's tasks in projects they own or whose team
+// they belong to, and sorts them by due date with undated tasks last
+type taskDB struct {
+	*storetest.DB
+	projectAccess
+	tasks []assignedTask
+}
+
+type assignedTask struct {
+	store.GetUserTasksRow
+	AssigneeID pgtype.UUID
+}
+
+// projectAccess is who may access each project: its owner and the members
+// of its team
+type projectAccess struct {
+	projects map[pgtype.UUID]taskProject
+	members  map[pgtype.UUID][]pgtype.UUID // Team members by team
+}
+
+type taskProject struct {
+	owner pgtype.UUID
+	team  pgtype.UUID
+}
+
+func (a projectAccess) canAccess(user, project pgtype.UUID) bool {
+	p := a.projects[project]
+	if p.owner == user {
+		return true
+	}
+	for _, member := range a.members[p.team] {
+		if member == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *taskDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if strings.Fields(sql)[2] != "GetUserTasks" {
+		return db.DB.Query(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	user, status, latestDueFirst := args[0].(pgtype.UUID), args[1].(pgtype.Text), args[2].(bool)
+	var tasks []assignedTask
+	for _, t := range db.tasks {
+		if t.AssigneeID == user && db.canAccess(user, t.ProjectID) && (!status.Valid || t.Status == status) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].DueDate, tasks[j].DueDate
+		if !a.Valid || !b.Valid {
+			return a.Valid && !b.Valid
+		}
+		if latestDueFirst {
+			return a.Time.After(b.Time)
+		}
+		return a.Time.Before(b.Time)
+	})
+
+	var rows [][]any
+	for _, t := range tasks {
+		rows = append(rows, []any{t.ID, t.ProjectID, t.Title, t.Description, t.Status, t.Priority, t.DueDate,
+			t.CreatedAt, t.UpdatedAt, t.ProjectName})
+	}
+	return storetest.Rows(rows), nil
+}
+
+func TestGetAssignedTasks(t *testing.T) {
+	const (
+		user     = "11111111-1111-1111-1111-111111111111"
+		other    = "22222222-2222-2222-2222-222222222222"
+		team     = "44444444-4444-4444-4444-444444444444"
+		platform = "55555555-5555-5555-5555-555555555555" // Owned by the user
+		mobile   = "66666666-6666-6666-6666-666666666666" // The user's team's
+		legacy   = "77777777-7777-7777-7777-777777777777" // Someone else's
+	)
+	day := func(d int) pgtype.Timestamp {
+		return pgtype.Timestamp{Time: time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	todo := pgtype.Text{String: "todo", Valid: true}
+	done := pgtype.Text{String: "done", Valid: true}
+	task := func(n int, project, assignee string, status pgtype.Text, due pgtype.Timestamp) assignedTask {
+		return assignedTask{
+			GetUserTasksRow: store.GetUserTasksRow{
+				ID:        storetest.MustUUID(t, fmt.Sprintf("88888888-8888-8888-8888-%012d", n)),
+				ProjectID: storetest.MustUUID(t, project),
+				Title:     fmt.Sprintf("Task %d", n),
+				Status:    status,
+				DueDate:   due,
+			},
+			AssigneeID: storetest.MustUUID(t, assignee),
+		}
+	}
+
+	db := &taskDB{
+		DB: &storetest.DB{},
+		tasks: []assignedTask{
+			task(1, platform, user, todo, day(20)),
+			task(2, mobile, user, done, day(5)),
+			task(3, platform, user, todo, pgtype.Timestamp{}),
+			task(4, mobile, user, todo, day(10)),
+			task(5, mobile, other, todo, day(1)),
+			task(6, legacy, user, todo, day(2)), // The user has lost access
+		},
+		projectAccess: projectAccess{
+			projects: map[pgtype.UUID]taskProject{
+				storetest.MustUUID(t, platform): {owner: storetest.MustUUID(t, user)},
+				storetest.MustUUID(t, mobile):   {owner: storetest.MustUUID(t, other), team: storetest.MustUUID(t, team)},
+				storetest.MustUUID(t, legacy):   {owner: storetest.MustUUID(t, other)},
+			},
+			members: map[pgtype.UUID][]pgtype.UUID{
+				storetest.MustUUID(t, team): {storetest.MustUUID(t, user), storetest.MustUUID(t, other)},
+			},
+		},
+	}
+	svc := NewTaskService(store.New(db), nil)
+	ctx := context.Background()
+
+	list := func(t *testing.T, status string, latestDueFirst bool) []string {
+		t.Helper()
+		tasks, err := svc.GetAssignedTasks(ctx, user, status, latestDueFirst)
+		if err != nil {
+			t.Fatalf("GetAssignedTasks failed: %v", err)
+		}
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	t.Run("Soonest due first", func(t *testing.T) {
+		want := []string{"Task 2", "Task 4", "Task 1", "Task 3"}
+		if got := list(t, "", false); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tasks = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Latest due first", func(t *testing.T) {
+		want := []string{"Task 1", "Task 4", "Task 2", "Task 3"}
+		if got := list(t, "", true); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tasks = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Filtered by status", func(t *testing.T) {
+		if got, want := list(t, "todo", false), []string{"Task 4", "Task 1", "Task 3"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("To-do tasks = %v, want %v", got, want)
+		}
+		if got, want := list(t, "done", false), []string{"Task 2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Done tasks = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Invalid user", func(t *testing.T) {
+		if _, err := svc.GetAssignedTasks(ctx, "not-a-uuid", "", false); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Expected ErrInvalidID, got %v", err)
+		}
+	})
+}
+
+func TestDeleteTask(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		stranger = "33333333-3333-3333-3333-333333333333"
+		project  = "55555555-5555-5555-5555-555555555555"
+		task     = "66666666-6666-6666-6666-666666666666"
+	)
+
+	setup := func() (*storetest.DB, *TaskService) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetTaskByID:" + task: {storetest.MustUUID(t, task), storetest.MustUUID(t, project)},
+				"GetProjectAccess":    {storetest.MustUUID(t, owner), pgtype.UUID{}},
+			},
+			Errs: map[string]error{},
+		}
+		queries := store.New(db)
+		return db, NewTaskService(queries, NewProjectService(queries, cache.NewMemory(), nil, CacheTTLs{}))
+	}
+	ctx := context.Background()
+	deletes := []string{"DeleteTaskComments", "DeleteTaskAttachments", "DeleteTaskTimeEntries", "DeleteTask"}
+
+	t.Run("Comments and logged time are deleted with the task", func(t *testing.T) {
+		db, tasks := setup()
+		if err := tasks.DeleteTask(ctx, task, owner); err != nil {
+			t.Fatalf("DeleteTask failed: %v", err)
+		}
+		if db.Commits != 1 {
+			t.Fatalf("Expected one committed transaction, got %d", db.Commits)
+		}
+		for _, name := range deletes {
+			calls := db.Args(name)
+			if len(calls) != 1 || calls[0][0].(pgtype.UUID).String() != task {
+				t.Errorf("Expected %s to run once for %s, got %v", name, task, calls)
+			}
+		}
+	})
+
+	t.Run("A failure part way rolls everything back", func(t *testing.T) {
+		db, tasks := setup()
+		db.Errs["DeleteTask"] = errors.New("connection reset")
+		if err := tasks.DeleteTask(ctx, task, owner); err == nil {
+			t.Fatal("Expected DeleteTask to fail")
+		}
+		if db.Rollbacks != 1 || db.Commits != 0 {
+			t.Errorf("Got %d commits and %d rollbacks, want the transaction rolled back", db.Commits, db.Rollbacks)
+		}
+		for _, name := range deletes {
+			if db.Count(name) != 0 {
+				t.Errorf("Expected %s to be rolled back", name)
+			}
+		}
+	})
+
+	t.Run("Strangers can't delete tasks", func(t *testing.T) {
+		db, tasks := setup()
+		if err := tasks.DeleteTask(ctx, task, stranger); !errors.Is(err, ErrNotProjectOwner) {
+			t.Fatalf("DeleteTask = %v, want ErrNotProjectOwner", err)
+		}
+		if db.Commits+db.Rollbacks != 0 {
+			t.Error("Expected no transaction for a stranger")
+		}
+	})
+}
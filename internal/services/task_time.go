@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/sanitize"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxLoggedMinutes caps a single time entry at a day; longer stretches are
+// logged as several entries
+const maxLoggedMinutes = 24 * 60
+
+// TimeEntryInfo represents time logged on a task. UserID is empty once the
+// user who logged it has deleted their account.
+type TimeEntryInfo struct {
+	ID        string  `json:"id"`
+	TaskID    string  `json:"task_id"`
+	UserID    string  `json:"user_id,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Username  string  `json:"username,omitempty"`
+	Minutes   int     `json:"minutes"`
+	Note      string  `json:"note,omitempty"`
+	CreatedAt *string `json:"created_at"`
+}
+
+// TaskTimeTotal is the time logged on a task, overall and per user
+type TaskTimeTotal struct {
+	TaskID       string          `json:"task_id"`
+	TotalMinutes int64           `json:"total_minutes"`
+	Users        []UserTimeTotal `json:"users"`
+}
+
+// UserTimeTotal is the time one user logged on a task. Time logged by deleted
+// accounts is totalled under an empty UserID.
+type UserTimeTotal struct {
+	UserID   string `json:"user_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	Minutes  int64  `json:"minutes"`
+}
+
+// LogTime records that the user spent minutes on a task, with an optional
+// note. Anyone who can access the task's project can log time on it.
+func (s *TaskService) LogTime(ctx context.Context, taskID, userID string, minutes int, note string) (*TimeEntryInfo, error) {
+	if minutes <= 0 {
+		return nil, fmt.Errorf("%w: minutes must be positive", ErrInvalidTimeEntry)
+	}
+	if minutes > maxLoggedMinutes {
+		return nil, fmt.Errorf("%w: minutes cannot exceed %d", ErrInvalidTimeEntry, maxLoggedMinutes)
+	}
+
+	task, err := s.accessibleTask(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	note = strings.TrimSpace(sanitize.Text(note))
+	entry, err := s.queries.CreateTaskTimeEntry(ctx, store.CreateTaskTimeEntryParams{
+		TaskID:  task.ID,
+		UserID:  userUUID,
+		Minutes: int32(minutes),
+		Note:    pgtype.Text{String: note, Valid: note != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to log time: %w", err)
+	}
+
+	return &TimeEntryInfo{
+		ID:        entry.ID.String(),
+		TaskID:    entry.TaskID.String(),
+		UserID:    userID,
+		Minutes:   int(entry.Minutes),
+		Note:      entry.Note.String,
+		CreatedAt: FormatTimestamp(entry.CreatedAt),
+	}, nil
+}
+
+// GetTimeEntries lists the time logged on a task, newest first
+func (s *TaskService) GetTimeEntries(ctx context.Context, taskID, userID string) ([]TimeEntryInfo, error) {
+	task, err := s.accessibleTask(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.queries.GetTaskTimeEntries(ctx, task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+
+	result := make([]TimeEntryInfo, 0, len(entries))
+	for _, e := range entries {
+		info := TimeEntryInfo{
+			ID:        e.ID.String(),
+			TaskID:    e.TaskID.String(),
+			Name:      e.Name.String,
+			Username:  e.Username.String,
+			Minutes:   int(e.Minutes),
+			Note:      e.Note.String,
+			CreatedAt: FormatTimestamp(e.CreatedAt),
+		}
+		if e.UserID.Valid {
+			info.UserID = e.UserID.String()
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// GetTaskTotalTime adds up the time logged on a task, with a breakdown by
+// user listing whoever logged the most first
+func (s *TaskService) GetTaskTotalTime(ctx context.Context, taskID, userID string) (*TaskTimeTotal, error) {
+	task, err := s.accessibleTask(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.GetTaskTimeByUser(ctx, task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total logged time: %w", err)
+	}
+
+	total := &TaskTimeTotal{TaskID: task.ID.String(), Users: make([]UserTimeTotal, 0, len(rows))}
+	for _, row := range rows {
+		user := UserTimeTotal{
+			Name:     row.Name.String,
+			Username: row.Username.String,
+			Minutes:  row.Minutes,
+		}
+		if row.UserID.Valid {
+			user.UserID = row.UserID.String()
+		}
+		total.TotalMinutes += row.Minutes
+		total.Users = append(total.Users, user)
+	}
+	return total, nil
+}
+
+// accessibleTask loads a task the user can access through its project
+func (s *TaskService) accessibleTask(ctx context.Context, taskID, userID string) (*store.Task, error) {
+	var taskUUID pgtype.UUID
+	if err := taskUUID.Scan(taskID); err != nil {
+		return nil, invalidID("task ID", err)
+	}
+
+	task, err := s.queries.GetTaskByID(ctx, taskUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if err := s.projectService.requireProjectAccess(ctx, task.ProjectID.String(), userID); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
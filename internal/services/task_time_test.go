@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// timeDB keeps the time entries logged through it like the task_time_entries
+// table, and totals them per user for GetTaskTimeByUser
+type timeDB struct {
+	*storetest.DB
+	entries []store.TaskTimeEntry
+	names   map[pgtype.UUID]string // Usernames by user
+}
+
+func (db *timeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if strings.Fields(sql)[2] != "CreateTaskTimeEntry" {
+		return db.DB.QueryRow(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	entry := store.TaskTimeEntry{TaskID: args[0].(pgtype.UUID), UserID: args[1].(pgtype.UUID), Minutes: args[2].(int32), Note: args[3].(pgtype.Text)}
+	entry.ID.Bytes[0], entry.ID.Valid = byte(len(db.entries)+1), true
+	db.entries = append(db.entries, entry)
+	return storetest.Row([]any{entry.ID, entry.TaskID, entry.UserID, entry.Minutes, entry.Note})
+}
+
+func (db *timeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if strings.Fields(sql)[2] != "GetTaskTimeByUser" {
+		return db.DB.Query(ctx, sql, args...)
+	}
+	db.Record(sql, args)
+
+	minutes := map[pgtype.UUID]int64{}
+	for _, e := range db.entries {
+		if e.TaskID == args[0].(pgtype.UUID) {
+			minutes[e.UserID] += int64(e.Minutes)
+		}
+	}
+	var rows [][]any
+	for user, total := range minutes {
+		rows = append(rows, []any{user, pgtype.Text{}, pgtype.Text{String: db.names[user], Valid: true}, total})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][3].(int64) > rows[j][3].(int64) })
+	return storetest.Rows(rows), nil
+}
+
+func TestTaskTime(t *testing.T) {
+	const (
+		owner    = "11111111-1111-1111-1111-111111111111"
+		member   = "22222222-2222-2222-2222-222222222222"
+		stranger = "33333333-3333-3333-3333-333333333333"
+		team     = "44444444-4444-4444-4444-444444444444"
+		project  = "55555555-5555-5555-5555-555555555555"
+		task     = "66666666-6666-6666-6666-666666666666"
+		other    = "77777777-7777-7777-7777-777777777777"
+	)
+
+	setup := func() (*timeDB, *TaskService) {
+		db := &timeDB{
+			DB: &storetest.DB{Rows: map[string][]any{
+				"GetTaskByID:" + task:                          {storetest.MustUUID(t, task), storetest.MustUUID(t, project)},
+				"GetProjectAccess":                             {storetest.MustUUID(t, owner), storetest.MustUUID(t, team)},
+				"CheckTeamMembership:" + team + ":" + member:   {true},
+				"CheckTeamMembership:" + team + ":" + stranger: {false},
+			}},
+			names: map[pgtype.UUID]string{storetest.MustUUID(t, owner): "ada", storetest.MustUUID(t, member): "grace"},
+		}
+		queries := store.New(db)
+		projects := NewProjectService(queries, cache.NewMemory(), nil, CacheTTLs{})
+		return db, NewTaskService(queries, projects)
+	}
+	ctx := context.Background()
+
+	t.Run("Logging time records who spent it", func(t *testing.T) {
+		db, tasks := setup()
+		entry, err := tasks.LogTime(ctx, task, member, 45, "  Reproduced the <b>crash</b> ")
+		if err != nil {
+			t.Fatalf("LogTime failed: %v", err)
+		}
+		if entry.TaskID != task || entry.UserID != member || entry.Minutes != 45 || entry.Note != "Reproduced the crash" {
+			t.Errorf("Entry = %+v, want 45 minutes by %s with a plain-text note", entry, member)
+		}
+		if len(db.entries) != 1 || db.entries[0].UserID.String() != member {
+			t.Errorf("Stored %+v, want one entry by %s", db.entries, member)
+		}
+	})
+
+	t.Run("Minutes must be positive and at most a day", func(t *testing.T) {
+		db, tasks := setup()
+		for _, minutes := range []int{0, -30, maxLoggedMinutes + 1} {
+			if _, err := tasks.LogTime(ctx, task, owner, minutes, ""); !errors.Is(err, ErrInvalidTimeEntry) {
+				t.Errorf("LogTime(%d) = %v, want ErrInvalidTimeEntry", minutes, err)
+			}
+		}
+		if len(db.Calls) != 0 {
+			t.Errorf("Expected invalid entries to be refused before any query, got %d", len(db.Calls))
+		}
+	})
+
+	t.Run("Totals add up time across users", func(t *testing.T) {
+		_, tasks := setup()
+		for _, logged := range []struct {
+			user    string
+			minutes int
+		}{{owner, 30}, {member, 45}, {owner, 60}, {member, 15}} {
+			if _, err := tasks.LogTime(ctx, task, logged.user, logged.minutes, ""); err != nil {
+				t.Fatalf("LogTime failed: %v", err)
+			}
+		}
+
+		total, err := tasks.GetTaskTotalTime(ctx, task, member)
+		if err != nil {
+			t.Fatalf("GetTaskTotalTime failed: %v", err)
+		}
+		if total.TaskID != task || total.TotalMinutes != 150 {
+			t.Errorf("Total = %d minutes on %s, want 150 on %s", total.TotalMinutes, total.TaskID, task)
+		}
+		want := []UserTimeTotal{{UserID: owner, Username: "ada", Minutes: 90}, {UserID: member, Username: "grace", Minutes: 60}}
+		if len(total.Users) != len(want) || total.Users[0] != want[0] || total.Users[1] != want[1] {
+			t.Errorf("Users = %+v, want %+v", total.Users, want)
+		}
+	})
+
+	t.Run("Only people with project access can log or read time", func(t *testing.T) {
+		db, tasks := setup()
+		if _, err := tasks.LogTime(ctx, task, stranger, 30, ""); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("LogTime = %v, want ErrNotProjectOwner", err)
+		}
+		if _, err := tasks.GetTimeEntries(ctx, task, stranger); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("GetTimeEntries = %v, want ErrNotProjectOwner", err)
+		}
+		if _, err := tasks.GetTaskTotalTime(ctx, task, stranger); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("GetTaskTotalTime = %v, want ErrNotProjectOwner", err)
+		}
+		for _, query := range []string{"CreateTaskTimeEntry", "GetTaskTimeEntries", "GetTaskTimeByUser"} {
+			if db.Count(query) != 0 {
+				t.Errorf("Expected %s not to run for a stranger", query)
+			}
+		}
+	})
+
+	t.Run("Unknown tasks aren't found", func(t *testing.T) {
+		_, tasks := setup()
+		if _, err := tasks.LogTime(ctx, other, owner, 30, ""); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("LogTime = %v, want ErrTaskNotFound", err)
+		}
+		if _, err := tasks.GetTaskTotalTime(ctx, "not-a-uuid", owner); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("GetTaskTotalTime = %v, want ErrInvalidID", err)
+		}
+	})
+}
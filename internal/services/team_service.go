@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"time"
 
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bethel-nz/tickit/internal/permissions"
+	"github.com/Bethel-nz/tickit/internal/sanitize"
+	"github.com/Bethel-nz/tickit/internal/validator"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -41,25 +43,40 @@ type TeamInfo struct {
 	AvatarURL   string `json:"avatar_url,omitempty"`
 	MemberCount int    `json:"member_count,omitempty"`
 	Role        string `json:"role,omitempty"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at,omitempty"`
+	OwnerID     string `json:"owner_id,omitempty"`
+	CreatedAt   *string `json:"created_at"`
+	UpdatedAt   *string `json:"updated_at"`
+	Slug        string `json:"slug,omitempty"`
 }
 
 type TeamService struct {
-	queries *store.Queries
-	cache   *redis.Client
+	queries  *store.Queries
+	cache    cache.Cache
+	ttls     CacheTTLs
+	notifier Notifier
+	audit    *AuditService
+
+	slugsFollowNames bool // Renaming a team changes its slug
 }
 
-func NewTeamService(queries *store.Queries, cache *redis.Client) *TeamService {
+func NewTeamService(queries *store.Queries, cache cache.Cache, ttls CacheTTLs) *TeamService {
 	return &TeamService{
 		queries: queries,
 		cache:   cache,
+		ttls:    ttls.withDefaults(),
 	}
 }
 
+// SetNotifier sets where new members are notified they were added
+func (s *TeamService) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
 // CreateTeam creates a new team with the provided information
 func (s *TeamService) CreateTeam(ctx context.Context, params store.CreateTeamParams, ownerID string) (*store.Team, error) {
 
+	params.Name = sanitize.Text(params.Name)
+	params.Description.String = sanitize.Text(params.Description.String)
 	if params.Name == "" {
 		return nil, fmt.Errorf("%w: team name is required", ErrInvalidTeamData)
 	}
@@ -68,29 +85,49 @@ func (s *TeamService) CreateTeam(ctx context.Context, params store.CreateTeamPar
 		return nil, fmt.Errorf("%w: team name cannot exceed 100 characters", ErrInvalidTeamData)
 	}
 
-	team, err := s.queries.CreateTeam(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create team: %w", err)
+	if params.AvatarUrl.Valid && !validator.IsValidURL(params.AvatarUrl.String) {
+		return nil, fmt.Errorf("%w: avatar_url must be an http or https URL", ErrInvalidTeamData)
 	}
 
 	var ownerUUID pgtype.UUID
 	if err := ownerUUID.Scan(ownerID); err != nil {
-		return nil, fmt.Errorf("invalid owner ID: %w", err)
+		return nil, invalidID("owner ID", err)
 	}
 
-	err = s.queries.AddUserToTeam(ctx, store.AddUserToTeamParams{
-		TeamID: team.ID,
-		UserID: ownerUUID,
-		Role:   pgtype.Text{String: "owner", Valid: true},
+	// Create the team and its owner membership together so a failure
+	// can't leave a team nobody belongs to
+	var team store.Team
+	err := retrySlug(func() error {
+		var err error
+		if params.Slug, err = s.teamSlug(ctx, params.Name, pgtype.UUID{}); err != nil {
+			return err
+		}
+		return store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+			var err error
+			team, err = q.CreateTeam(ctx, params)
+			if err != nil {
+				return fmt.Errorf("failed to create team: %w", err)
+			}
+
+			err = q.AddUserToTeam(ctx, store.AddUserToTeamParams{
+				TeamID: team.ID,
+				UserID: ownerUUID,
+				Role:   pgtype.Text{String: permissions.RoleOwner, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add owner to team: %w", err)
+			}
+			return nil
+		})
 	})
 	if err != nil {
-		if delErr := s.queries.DeleteTeam(ctx, team.ID); delErr != nil {
-			log.Printf("Failed to delete team after adding owner failed: %v", delErr)
-		}
-		return nil, fmt.Errorf("failed to add owner to team: %w", err)
+		return nil, err
 	}
 
 	s.cacheTeam(ctx, &team)
+	if err := s.cache.Del(ctx, fmt.Sprintf("user:%s:teams", ownerID)); err != nil {
+		log.Printf("Failed to invalidate user teams cache: %v", err)
+	}
 
 	return &team, nil
 }
@@ -98,11 +135,11 @@ func (s *TeamService) CreateTeam(ctx context.Context, params store.CreateTeamPar
 func (s *TeamService) GetTeamByID(ctx context.Context, teamID string) (*store.Team, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return nil, fmt.Errorf("invalid team ID: %w", err)
+		return nil, invalidID("team ID", err)
 	}
 
 	cacheKey := fmt.Sprintf("team:%s", teamID)
-	cachedTeam, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedTeam, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var team store.Team
 		if err := json.Unmarshal([]byte(cachedTeam), &team); err == nil {
@@ -123,13 +160,25 @@ func (s *TeamService) GetTeamByID(ctx context.Context, teamID string) (*store.Te
 // UpdateTeam updates team information
 func (s *TeamService) UpdateTeam(ctx context.Context, params store.UpdateTeamParams, userID string) error {
 
+	if params.Name != "" {
+		params.Name = sanitize.Text(params.Name)
+		if params.Name == "" {
+			return fmt.Errorf("%w: team name cannot be empty", ErrInvalidTeamData)
+		}
+	}
+	params.Description.String = sanitize.Text(params.Description.String)
+
 	if params.Name != "" && len(params.Name) > 100 {
 		return fmt.Errorf("%w: team name cannot exceed 100 characters", ErrInvalidTeamData)
 	}
 
+	if params.AvatarUrl.Valid && !validator.IsValidURL(params.AvatarUrl.String) {
+		return fmt.Errorf("%w: avatar_url must be an http or https URL", ErrInvalidTeamData)
+	}
+
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	role, err := s.queries.GetTeamMemberRole(ctx, store.GetTeamMemberRoleParams{
@@ -140,18 +189,34 @@ func (s *TeamService) UpdateTeam(ctx context.Context, params store.UpdateTeamPar
 		return fmt.Errorf("%w: user is not a member of this team", ErrNotTeamMember)
 	}
 
-	if role.String != "owner" && role.String != "admin" {
+	if !permissions.CanManageTeam(role.String) {
 		return ErrInsufficientRoles
 	}
 
-	if err := s.queries.UpdateTeam(ctx, params); err != nil {
+	renamed := false
+	if s.slugsFollowNames && params.Name != "" {
+		team, err := s.GetTeamByID(ctx, params.ID.String())
+		if err != nil {
+			return err
+		}
+		renamed = params.Name != team.Name
+	}
+	err = retrySlug(func() error {
+		if renamed {
+			newSlug, err := s.teamSlug(ctx, params.Name, params.ID)
+			if err != nil {
+				return err
+			}
+			params.Slug = pgtype.Text{String: newSlug, Valid: true}
+		}
+		return s.queries.UpdateTeam(ctx, params)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update team: %w", err)
 	}
 
-	cacheKey := fmt.Sprintf("team:%s", params.ID.String())
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
-		log.Printf("Failed to invalidate team cache: %v", err)
-	}
+	// Members' team lists show the name and description too
+	s.invalidateTeamCaches(ctx, s.teamCacheKeys(ctx, params.ID))
 
 	return nil
 }
@@ -160,12 +225,12 @@ func (s *TeamService) UpdateTeam(ctx context.Context, params store.UpdateTeamPar
 func (s *TeamService) DeleteTeam(ctx context.Context, teamID, userID string) error {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
+		return invalidID("team ID", err)
 	}
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	role, err := s.queries.GetTeamMemberRole(ctx, store.GetTeamMemberRoleParams{
@@ -176,85 +241,19 @@ func (s *TeamService) DeleteTeam(ctx context.Context, teamID, userID string) err
 		return fmt.Errorf("%w: user is not a member of this team", ErrNotTeamMember)
 	}
 
-	if role.String != "owner" {
+	if !permissions.CanDeleteTeam(role.String) {
 		return ErrInsufficientRoles
 	}
 
+	// The members can only be looked up before the team is gone
+	staleKeys := append(s.teamCacheKeys(ctx, teamUUID), fmt.Sprintf("team:%s:projects", teamID))
+
 	if err := s.queries.DeleteTeam(ctx, teamUUID); err != nil {
 		return fmt.Errorf("failed to delete team: %w", err)
 	}
 
-	cacheKey := fmt.Sprintf("team:%s", teamID)
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
-		log.Printf("Failed to invalidate team cache: %v", err)
-	}
-
-	return nil
-}
-
-// AddUserToTeam adds a user to a team
-func (s *TeamService) AddUserToTeam(ctx context.Context, teamID, userIDToAdd, adderUserID, role string) error {
-	var teamUUID pgtype.UUID
-	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
-	}
-
-	var userToAddUUID pgtype.UUID
-	if err := userToAddUUID.Scan(userIDToAdd); err != nil {
-		return fmt.Errorf("invalid user ID to add: %w", err)
-	}
-
-	var adderUserUUID pgtype.UUID
-	if err := adderUserUUID.Scan(adderUserID); err != nil {
-		return fmt.Errorf("invalid adder user ID: %w", err)
-	}
-
-	adderRole, err := s.queries.GetTeamMemberRole(ctx, store.GetTeamMemberRoleParams{
-		TeamID: teamUUID,
-		UserID: adderUserUUID,
-	})
-	if err != nil {
-		return fmt.Errorf("%w: adder is not a member of this team", ErrNotTeamMember)
-	}
-
-	if adderRole.String != "owner" && adderRole.String != "admin" {
-		return ErrInsufficientRoles
-	}
-
-	validRoles := map[string]bool{
-		"admin":  true,
-		"editor": true,
-		"viewer": true,
-	}
-
-	if !validRoles[role] {
-		return fmt.Errorf("%w: invalid role '%s'", ErrInvalidTeamData, role)
-	}
-
-	isMember, err := s.queries.CheckTeamMembership(ctx, store.CheckTeamMembershipParams{
-		TeamID: teamUUID,
-		UserID: userToAddUUID,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to check team membership: %w", err)
-	}
-
-	if isMember {
-		return s.queries.UpdateTeamMemberRole(ctx, store.UpdateTeamMemberRoleParams{
-			TeamID: teamUUID,
-			UserID: userToAddUUID,
-			Role:   pgtype.Text{String: role, Valid: true},
-		})
-	}
-
-	err = s.queries.AddUserToTeam(ctx, store.AddUserToTeamParams{
-		TeamID: teamUUID,
-		UserID: userToAddUUID,
-		Role:   pgtype.Text{String: role, Valid: true},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add user to team: %w", err)
-	}
+	s.invalidateTeamCaches(ctx, staleKeys)
+	s.audit.Record(ctx, userID, AuditTeamDeleted, AuditTargetTeam, teamID, nil)
 
 	return nil
 }
@@ -263,17 +262,17 @@ func (s *TeamService) AddUserToTeam(ctx context.Context, teamID, userIDToAdd, ad
 func (s *TeamService) RemoveUserFromTeam(ctx context.Context, teamID, userIDToRemove, removerUserID string) error {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
+		return invalidID("team ID", err)
 	}
 
 	var userToRemoveUUID pgtype.UUID
 	if err := userToRemoveUUID.Scan(userIDToRemove); err != nil {
-		return fmt.Errorf("invalid user ID to remove: %w", err)
+		return invalidID("user ID to remove", err)
 	}
 
 	var removerUserUUID pgtype.UUID
 	if err := removerUserUUID.Scan(removerUserID); err != nil {
-		return fmt.Errorf("invalid remover user ID: %w", err)
+		return invalidID("remover user ID", err)
 	}
 
 	isMember, err := s.queries.CheckTeamMembership(ctx, store.CheckTeamMembershipParams{
@@ -305,11 +304,7 @@ func (s *TeamService) RemoveUserFromTeam(ctx context.Context, teamID, userIDToRe
 			return fmt.Errorf("failed to get user role: %w", err)
 		}
 
-		if userToRemoveRole.String == "owner" && removerRole.String != "owner" {
-			return ErrInsufficientRoles
-		}
-
-		if removerRole.String != "owner" && removerRole.String != "admin" {
+		if !permissions.CanManageMember(removerRole.String, userToRemoveRole.String) {
 			return ErrInsufficientRoles
 		}
 	}
@@ -322,32 +317,28 @@ func (s *TeamService) RemoveUserFromTeam(ctx context.Context, teamID, userIDToRe
 		return fmt.Errorf("failed to remove user from team: %w", err)
 	}
 
+	s.invalidateTeamCaches(ctx, s.teamCacheKeys(ctx, teamUUID, userIDToRemove))
+
 	return nil
 }
 
 func (s *TeamService) UpdateTeamMemberRole(ctx context.Context, teamID, userIDToUpdate, updaterUserID, newRole string) error {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
+		return invalidID("team ID", err)
 	}
 
 	var userToUpdateUUID pgtype.UUID
 	if err := userToUpdateUUID.Scan(userIDToUpdate); err != nil {
-		return fmt.Errorf("invalid user ID to update: %w", err)
+		return invalidID("user ID to update", err)
 	}
 
 	var updaterUserUUID pgtype.UUID
 	if err := updaterUserUUID.Scan(updaterUserID); err != nil {
-		return fmt.Errorf("invalid updater user ID: %w", err)
-	}
-
-	validRoles := map[string]bool{
-		"admin":  true,
-		"editor": true,
-		"viewer": true,
+		return invalidID("updater user ID", err)
 	}
 
-	if newRole == "owner" || !validRoles[newRole] {
+	if !permissions.IsAssignableRole(newRole) {
 		return fmt.Errorf("%w: invalid role '%s'", ErrInvalidTeamData, newRole)
 	}
 
@@ -379,13 +370,13 @@ func (s *TeamService) UpdateTeamMemberRole(ctx context.Context, teamID, userIDTo
 		return fmt.Errorf("failed to get user role: %w", err)
 	}
 
-	if currentRole.String == "owner" && updaterRole.String != "owner" {
+	if !permissions.CanManageMember(updaterRole.String, currentRole.String) {
 		return ErrInsufficientRoles
 	}
-
-	// Only owner or admin can update roles
-	if updaterRole.String != "owner" && updaterRole.String != "admin" {
-		return ErrInsufficientRoles
+	// Not even by the owner themselves, which would leave the team without
+	// an owner and so impossible to delete
+	if currentRole.String == permissions.RoleOwner {
+		return fmt.Errorf("%w: the owner's role can't be changed", ErrInsufficientRoles)
 	}
 
 	// Update role
@@ -398,6 +389,11 @@ func (s *TeamService) UpdateTeamMemberRole(ctx context.Context, teamID, userIDTo
 		return fmt.Errorf("failed to update team member role: %w", err)
 	}
 
+	s.invalidateTeamCaches(ctx, s.teamCacheKeys(ctx, teamUUID, userIDToUpdate))
+	s.audit.Record(ctx, updaterUserID, AuditMemberRoleChanged, AuditTargetTeam, teamID, map[string]any{
+		"user_id": userIDToUpdate, "from": currentRole.String, "to": newRole,
+	})
+
 	return nil
 }
 
@@ -405,12 +401,12 @@ func (s *TeamService) UpdateTeamMemberRole(ctx context.Context, teamID, userIDTo
 func (s *TeamService) GetTeamMembers(ctx context.Context, teamID, requestorID string) ([]TeamMemberInfo, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return nil, fmt.Errorf("invalid team ID: %w", err)
+		return nil, invalidID("team ID", err)
 	}
 
 	var requestorUUID pgtype.UUID
 	if err := requestorUUID.Scan(requestorID); err != nil {
-		return nil, fmt.Errorf("invalid requestor ID: %w", err)
+		return nil, invalidID("requestor ID", err)
 	}
 
 	// Check if requestor is a team member
@@ -426,53 +422,37 @@ func (s *TeamService) GetTeamMembers(ctx context.Context, teamID, requestorID st
 		return nil, fmt.Errorf("%w: requestor is not a member of this team", ErrNotTeamMember)
 	}
 
-	// Try to get from cache
 	cacheKey := fmt.Sprintf("team:%s:members", teamID)
-	cachedMembers, err := s.cache.Get(ctx, cacheKey).Result()
-	if err == nil {
-		
-		var members []TeamMemberInfo
-		if err := json.Unmarshal([]byte(cachedMembers), &members); err == nil {
-			return members, nil
-		}
-	}
-
-	dbMembers, err := s.queries.GetTeamMembers(ctx, teamUUID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get team members: %w", err)
-	}
-
-	members := make([]TeamMemberInfo, len(dbMembers))
-	for i, m := range dbMembers {
-		members[i] = TeamMemberInfo{
-			UserID:    m.ID.String(),
-			Email:     m.Email,
-			Name:      m.Name.String,
-			Username:  m.Username.String,
-			AvatarURL: m.AvatarUrl.String,
-			Role:      m.Role.String,
+	return loadCached(ctx, s.cache, cacheKey, s.ttls.TeamMembers, func() ([]TeamMemberInfo, error) {
+		dbMembers, err := s.queries.GetTeamMembers(ctx, teamUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get team members: %w", err)
 		}
-	}
 
-	membersJSON, err := json.Marshal(members)
-	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, membersJSON, 5*time.Minute).Err(); err != nil {
-			log.Printf("Failed to cache team members: %v", err)
+		members := make([]TeamMemberInfo, len(dbMembers))
+		for i, m := range dbMembers {
+			members[i] = TeamMemberInfo{
+				UserID:    m.ID.String(),
+				Email:     m.Email,
+				Name:      m.Name.String,
+				Username:  m.Username.String,
+				AvatarURL: m.AvatarUrl.String,
+				Role:      m.Role.String,
+			}
 		}
-	}
-
-	return members, nil
+		return members, nil
+	})
 }
 
 // GetUserTeams retrieves all teams a user is a member of
 func (s *TeamService) GetUserTeams(ctx context.Context, userID string) ([]TeamInfo, error) {
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 
 	cacheKey := fmt.Sprintf("user:%s:teams", userID)
-	cachedTeams, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedTeams, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var teams []TeamInfo
 		if err := json.Unmarshal([]byte(cachedTeams), &teams); err == nil {
@@ -492,14 +472,21 @@ func (s *TeamService) GetUserTeams(ctx context.Context, userID string) ([]TeamIn
 			Name:        t.Name,
 			Description: t.Description.String,
 			AvatarURL:   t.AvatarUrl.String,
+			MemberCount: int(t.MemberCount),
 			Role:        t.Role.String,
+			CreatedAt:   FormatTimestamp(t.CreatedAt),
+			UpdatedAt:   FormatTimestamp(t.UpdatedAt),
+			Slug:        t.Slug,
+		}
+		if t.OwnerID.Valid {
+			teams[i].OwnerID = t.OwnerID.String()
 		}
 	}
 
 	// Cache the result
 	teamsJSON, err := json.Marshal(teams)
 	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, teamsJSON, 10*time.Minute).Err(); err != nil {
+		if err := s.cache.Set(ctx, cacheKey, string(teamsJSON), s.ttls.TeamList); err != nil {
 			log.Printf("Failed to cache user teams: %v", err)
 		}
 	}
@@ -511,12 +498,12 @@ func (s *TeamService) GetUserTeams(ctx context.Context, userID string) ([]TeamIn
 func (s *TeamService) CheckTeamMembership(ctx context.Context, teamID, userID string) (bool, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return false, fmt.Errorf("invalid team ID: %w", err)
+		return false, invalidID("team ID", err)
 	}
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return false, fmt.Errorf("invalid user ID: %w", err)
+		return false, invalidID("user ID", err)
 	}
 
 	return s.queries.CheckTeamMembership(ctx, store.CheckTeamMembershipParams{
@@ -529,12 +516,12 @@ func (s *TeamService) CheckTeamMembership(ctx context.Context, teamID, userID st
 func (s *TeamService) GetTeamMemberRole(ctx context.Context, teamID, userID string) (string, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return "", fmt.Errorf("invalid team ID: %w", err)
+		return "", invalidID("team ID", err)
 	}
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return "", fmt.Errorf("invalid user ID: %w", err)
+		return "", invalidID("user ID", err)
 	}
 
 	role, err := s.queries.GetTeamMemberRole(ctx, store.GetTeamMemberRoleParams{
@@ -561,52 +548,92 @@ func (s *TeamService) cacheTeam(_ context.Context, team *store.Team) {
 	}
 
 	cacheKey := fmt.Sprintf("team:%s", team.ID.String())
-	if err := s.cache.Set(context.Background(), cacheKey, teamJSON, time.Hour).Err(); err != nil {
+	if err := s.cache.Set(context.Background(), cacheKey, string(teamJSON), s.ttls.Team); err != nil {
 		log.Printf("Failed to cache team: %v", err)
 	}
 }
 
-// AddMember adds a new member to a team with the specified role
+// teamCacheKeys returns the cache keys that depend on who is in a team: the
+// team, its member list, and the team list of every member, which shows their
+// role and the member count. The users a change is about are passed as
+// userIDs, so that they are covered even if they just left.
+func (s *TeamService) teamCacheKeys(ctx context.Context, teamID pgtype.UUID, userIDs ...string) []string {
+	keys := []string{
+		fmt.Sprintf("team:%s", teamID.String()),
+		fmt.Sprintf("team:%s:members", teamID.String()),
+	}
+	for _, userID := range userIDs {
+		keys = append(keys, fmt.Sprintf("user:%s:teams", userID))
+	}
+
+	members, err := s.queries.GetTeamMembers(ctx, teamID)
+	if err != nil {
+		log.Printf("Failed to list team members to invalidate: %v", err)
+		return keys
+	}
+	for _, m := range members {
+		keys = append(keys, fmt.Sprintf("user:%s:teams", m.ID.String()))
+	}
+	return keys
+}
+
+// invalidateTeamCaches drops keys from teamCacheKeys
+func (s *TeamService) invalidateTeamCaches(ctx context.Context, keys []string) {
+	if err := s.cache.Del(ctx, keys...); err != nil {
+		log.Printf("Failed to invalidate team caches: %v", err)
+	}
+}
+
+// AddMember adds a user to a team with role, or changes the role of an
+// existing member. The requester must be able to manage members and, like
+// UpdateTeamMemberRole, can't change the role of someone who outranks them.
+// Only admin, editor and viewer can be given; see permissions.IsAssignableRole.
 func (s *TeamService) AddMember(ctx context.Context, teamID, userToAddID, role, requestingUserID string) error {
 	
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
+		return invalidID("team ID", err)
 	}
 
-	if _, err := s.queries.GetTeamByID(ctx, teamUUID); err != nil {
+	team, err := s.queries.GetTeamByID(ctx, teamUUID)
+	if err != nil {
 		return ErrTeamNotFound
 	}
 
 	var requestingUserUUID pgtype.UUID
 	if err := requestingUserUUID.Scan(requestingUserID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
-	isAdmin, err := s.isTeamAdmin(ctx, teamID, requestingUserID)
+	requesterRole, err := s.requireMemberRole(ctx, teamID, requestingUserID)
 	if err != nil {
 		return err
 	}
 
-	if !isAdmin {
-		return ErrUnauthorized
+	if !permissions.CanManageMembers(requesterRole) {
+		return ErrInsufficientRoles
 	}
 
 	var userToAddUUID pgtype.UUID
 	if err := userToAddUUID.Scan(userToAddID); err != nil {
-		return fmt.Errorf("invalid user ID for new member: %w", err)
+		return invalidID("user ID for new member", err)
 	}
 
-	var roleText pgtype.Text
-	if err := roleText.Scan(role); err != nil {
-		return fmt.Errorf("invalid role: %w", err)
+	if !permissions.IsAssignableRole(role) {
+		return fmt.Errorf("%w: invalid role '%s'", ErrInvalidTeamData, role)
 	}
+	roleText := pgtype.Text{String: role, Valid: true}
 
-	isMember, err := s.CheckTeamMembership(ctx, teamID, userToAddID)
+	isMember, currentRole, err := s.GetMemberRole(ctx, teamID, userToAddID)
 	if err != nil {
 		return fmt.Errorf("failed to check team membership: %w", err)
 	}
 
+	// Re-adding an existing member changes their role
+	if isMember && !permissions.CanManageMember(requesterRole, currentRole) {
+		return ErrInsufficientRoles
+	}
+
 	if isMember {
 		err = s.queries.UpdateTeamMemberRole(ctx, store.UpdateTeamMemberRoleParams{
 			TeamID: teamUUID,
@@ -625,15 +652,35 @@ func (s *TeamService) AddMember(ctx context.Context, teamID, userToAddID, role,
 		return fmt.Errorf("failed to add team member: %w", err)
 	}
 
+	s.invalidateTeamCaches(ctx, s.teamCacheKeys(ctx, teamUUID, userToAddID))
+
+	if isMember {
+		s.audit.Record(ctx, requestingUserID, AuditMemberRoleChanged, AuditTargetTeam, teamID, map[string]any{
+			"user_id": userToAddID, "from": currentRole, "to": role,
+		})
+	}
+
+	if !isMember && userToAddID != requestingUserID {
+		notify(ctx, s.notifier, Notification{
+			UserID:  userToAddID,
+			ActorID: requestingUserID,
+			Type:    NotificationTeamAdded,
+			TeamID:  teamID,
+			Subject: fmt.Sprintf("%s added you to the team %q", actorName(ctx, s.queries, requestingUserUUID), team.Name),
+		})
+	}
+
 	return nil
 }
 
-// RemoveMember removes a user from a team
-func (s *TeamService) RemoveMember(ctx context.Context, teamID, memberID, requestingUserID string) error {
+// RemoveMember removes a user from a team. The member's open issues and tasks
+// in the team's projects are handed to reassignTo, another member, or left
+// unassigned when reassignTo is nil.
+func (s *TeamService) RemoveMember(ctx context.Context, teamID, memberID, requestingUserID string, reassignTo *string) error {
 
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return fmt.Errorf("invalid team ID: %w", err)
+		return invalidID("team ID", err)
 	}
 
 	if _, err := s.queries.GetTeamByID(ctx, teamUUID); err != nil {
@@ -642,21 +689,25 @@ func (s *TeamService) RemoveMember(ctx context.Context, teamID, memberID, reques
 
 	var requestingUserUUID pgtype.UUID
 	if err := requestingUserUUID.Scan(requestingUserID); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return invalidID("user ID", err)
 	}
 
-	isAdmin, err := s.isTeamAdmin(ctx, teamID, requestingUserID)
+	requesterRole, err := s.requireMemberRole(ctx, teamID, requestingUserID)
 	if err != nil {
 		return err
 	}
 
 	isSelf := requestingUserID == memberID
 
-	if !isAdmin && !isSelf {
-		return ErrUnauthorized
-	}
+	if !isSelf {
+		_, memberRole, err := s.GetMemberRole(ctx, teamID, memberID)
+		if err != nil {
+			return err
+		}
+		if !permissions.CanManageMember(requesterRole, memberRole) {
+			return ErrUnauthorized
+		}
 
-	if isAdmin && memberID != requestingUserID {
 		isLastAdmin, err := s.isLastAdmin(ctx, teamID, memberID)
 		if err != nil {
 			return fmt.Errorf("failed to check admin status: %w", err)
@@ -668,15 +719,66 @@ func (s *TeamService) RemoveMember(ctx context.Context, teamID, memberID, reques
 
 	var memberUUID pgtype.UUID
 	if err := memberUUID.Scan(memberID); err != nil {
-		return fmt.Errorf("invalid member ID: %w", err)
+		return invalidID("member ID", err)
 	}
 
-	if err := s.queries.RemoveUserFromTeam(ctx, store.RemoveUserFromTeamParams{
-		TeamID: teamUUID,
-		UserID: memberUUID,
-	}); err != nil {
-		return fmt.Errorf("failed to remove team member: %w", err)
+	var newAssignee pgtype.UUID
+	if reassignTo != nil {
+		if *reassignTo == memberID {
+			return fmt.Errorf("%w: cannot reassign items to the member being removed", ErrInvalidTeamData)
+		}
+		if err := newAssignee.Scan(*reassignTo); err != nil {
+			return fmt.Errorf("%w: invalid reassignment user ID", ErrInvalidTeamData)
+		}
+		isMember, err := s.queries.CheckTeamMembership(ctx, store.CheckTeamMembershipParams{
+			TeamID: teamUUID,
+			UserID: newAssignee,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check team membership: %w", err)
+		}
+		if !isMember {
+			return fmt.Errorf("%w: items can only be reassigned to a team member", ErrInvalidTeamData)
+		}
+	}
+
+	// Reassign first so a failed removal doesn't leave items pointing at
+	// someone who is no longer on the team, and vice versa
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		if _, err := q.ReassignTeamMemberIssues(ctx, store.ReassignTeamMemberIssuesParams{
+			NewAssigneeID: newAssignee,
+			AssigneeID:    memberUUID,
+			TeamID:        teamUUID,
+		}); err != nil {
+			return fmt.Errorf("failed to reassign issues: %w", err)
+		}
+		if _, err := q.ReassignTeamMemberTasks(ctx, store.ReassignTeamMemberTasksParams{
+			NewAssigneeID: newAssignee,
+			AssigneeID:    memberUUID,
+			TeamID:        teamUUID,
+		}); err != nil {
+			return fmt.Errorf("failed to reassign tasks: %w", err)
+		}
+
+		if err := q.RemoveUserFromTeam(ctx, store.RemoveUserFromTeamParams{
+			TeamID: teamUUID,
+			UserID: memberUUID,
+		}); err != nil {
+			return fmt.Errorf("failed to remove team member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateTeamCaches(ctx, s.teamCacheKeys(ctx, teamUUID, memberID))
+
+	metadata := map[string]any{"user_id": memberID}
+	if reassignTo != nil {
+		metadata["reassigned_to"] = *reassignTo
 	}
+	s.audit.Record(ctx, requestingUserID, AuditMemberRemoved, AuditTargetTeam, teamID, metadata)
 
 	return nil
 }
@@ -685,7 +787,7 @@ func (s *TeamService) RemoveMember(ctx context.Context, teamID, memberID, reques
 func (s *TeamService) isLastAdmin(ctx context.Context, teamID, userID string) (bool, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return false, fmt.Errorf("invalid team ID: %w", err)
+		return false, invalidID("team ID", err)
 	}
 
 	admins, err := s.queries.GetTeamAdmins(ctx, teamUUID)
@@ -706,28 +808,29 @@ func (s *TeamService) isLastAdmin(ctx context.Context, teamID, userID string) (b
 	return false, nil
 }
 
-func (s *TeamService) isTeamAdmin(ctx context.Context, teamID, userID string) (bool, error) {
+// requireMemberRole returns the user's role in the team, or ErrNotMember
+func (s *TeamService) requireMemberRole(ctx context.Context, teamID, userID string) (string, error) {
 	isMember, role, err := s.GetMemberRole(ctx, teamID, userID)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
 	if !isMember {
-		return false, ErrNotMember
+		return "", ErrNotMember
 	}
 
-	return role == "admin", nil
+	return role, nil
 }
 
 func (s *TeamService) GetMemberRole(ctx context.Context, teamID, userID string) (bool, string, error) {
 	var teamUUID pgtype.UUID
 	if err := teamUUID.Scan(teamID); err != nil {
-		return false, "", fmt.Errorf("invalid team ID: %w", err)
+		return false, "", invalidID("team ID", err)
 	}
 
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
-		return false, "", fmt.Errorf("invalid user ID: %w", err)
+		return false, "", invalidID("user ID", err)
 	}
 
 	member, err := s.queries.GetTeamMember(ctx, store.GetTeamMemberParams{
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCreateTeamIsAtomic(t *testing.T) {
+	const (
+		owner = "11111111-1111-1111-1111-111111111111"
+		team  = "44444444-4444-4444-4444-444444444444"
+	)
+
+	newService := func(db *storetest.DB) *TeamService {
+		return NewTeamService(store.New(db), cache.NewMemory(), CacheTTLs{})
+	}
+	params := store.CreateTeamParams{Name: "Platform"}
+
+	t.Run("Owner membership failure rolls back the team", func(t *testing.T) {
+		db := &storetest.DB{
+			Rows: map[string][]any{"CreateTeam": {storetest.MustUUID(t, team), "Platform"}},
+			Errs: map[string]error{"AddUserToTeam": errors.New("connection reset")},
+		}
+
+		if _, err := newService(db).CreateTeam(context.Background(), params, owner); err == nil {
+			t.Fatal("Expected CreateTeam to fail")
+		}
+
+		if db.Rollbacks != 1 || db.Commits != 0 {
+			t.Errorf("Expected the transaction to roll back, got %d commits and %d rollbacks", db.Commits, db.Rollbacks)
+		}
+		if n := db.Count("CreateTeam"); n != 0 {
+			t.Errorf("Team insert was committed %d times, want it rolled back", n)
+		}
+		if n := db.Count("DeleteTeam"); n != 0 {
+			t.Errorf("Expected no compensating DeleteTeam, got %d", n)
+		}
+	})
+
+	t.Run("Success commits team and owner together", func(t *testing.T) {
+		db := &storetest.DB{Rows: map[string][]any{"CreateTeam": {storetest.MustUUID(t, team), "Platform"}}}
+
+		created, err := newService(db).CreateTeam(context.Background(), params, owner)
+		if err != nil {
+			t.Fatalf("CreateTeam failed: %v", err)
+		}
+		if created.ID != storetest.MustUUID(t, team) {
+			t.Errorf("Created team ID = %s, want %s", created.ID.String(), team)
+		}
+
+		if db.Commits != 1 {
+			t.Errorf("Expected one commit, got %d", db.Commits)
+		}
+		args := db.Args("AddUserToTeam")
+		if db.Count("CreateTeam") != 1 || len(args) != 1 || args[0][1] != storetest.MustUUID(t, owner) {
+			t.Errorf("Expected team and owner membership to be committed, got calls %v", db.Calls)
+		}
+	})
+}
+
+func TestGetUserTeamsIncludesCountsAndTimestamps(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		team    = "44444444-4444-4444-4444-444444444444"
+		members = 4
+	)
+
+	created := pgtype.Timestamp{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	db := &storetest.DB{Lists: map[string][][]any{
+		"GetUserTeams": {{storetest.MustUUID(t, team), "Platform", pgtype.Text{}, pgtype.Text{},
+			pgtype.Text{String: "owner", Valid: true}, created, created, int64(members), storetest.MustUUID(t, owner)}},
+	}}
+	svc := NewTeamService(store.New(db), cache.NewMemory(), CacheTTLs{})
+	ctx := context.Background()
+
+	// The second call is answered from the memory, which must keep every field
+	for _, source := range []string{"database", "cache"} {
+		teams, err := svc.GetUserTeams(ctx, owner)
+		if err != nil {
+			t.Fatalf("GetUserTeams from %s failed: %v", source, err)
+		}
+		if len(teams) != 1 {
+			t.Fatalf("Expected 1 team from %s, got %d", source, len(teams))
+		}
+		got := teams[0]
+		if got.MemberCount != members {
+			t.Errorf("MemberCount from %s = %d, want %d", source, got.MemberCount, members)
+		}
+		if got.CreatedAt == nil || got.UpdatedAt == nil {
+			t.Errorf("Timestamps from %s missing: %+v", source, got)
+		}
+		if got.OwnerID != owner {
+			t.Errorf("OwnerID from %s = %q, want %q", source, got.OwnerID, owner)
+		}
+	}
+	if n := db.Count("GetUserTeams"); n != 1 {
+		t.Errorf("Expected 1 GetUserTeams query, got %d", n)
+	}
+}
+
+func TestRemoveMemberReassignsOpenItems(t *testing.T) {
+	const (
+		admin  = "11111111-1111-1111-1111-111111111111"
+		member = "22222222-2222-2222-2222-222222222222"
+		other  = "33333333-3333-3333-3333-333333333333"
+		team   = "44444444-4444-4444-4444-444444444444"
+	)
+
+	newDB := func() *storetest.DB {
+		return &storetest.DB{
+			Rows: map[string][]any{
+				"GetTeamByID": {storetest.MustUUID(t, team)},
+				"GetTeamMember:" + team + ":" + admin: {storetest.MustUUID(t, team), storetest.MustUUID(t, admin),
+					pgtype.Text{String: "admin", Valid: true}},
+				"CheckTeamMembership:" + team + ":" + other: {true},
+				"CheckTeamMembership":                       {false},
+			},
+			Affected: map[string]int64{"ReassignTeamMemberIssues": 3},
+		}
+	}
+	newService := func(db *storetest.DB) *TeamService {
+		return NewTeamService(store.New(db), cache.NewMemory(), CacheTTLs{})
+	}
+
+	// assignees returns the new assignee each reassignment query was given
+	assignees := func(t *testing.T, db *storetest.DB) []pgtype.UUID {
+		t.Helper()
+		var got []pgtype.UUID
+		for _, name := range []string{"ReassignTeamMemberIssues", "ReassignTeamMemberTasks"} {
+			args := db.Args(name)
+			if len(args) != 1 {
+				t.Fatalf("Expected one %s call, got %d", name, len(args))
+			}
+			if args[0][1] != storetest.MustUUID(t, member) || args[0][2] != storetest.MustUUID(t, team) {
+				t.Errorf("%s targeted %v, want member %s in team %s", name, args[0][1:], member, team)
+			}
+			got = append(got, args[0][0].(pgtype.UUID))
+		}
+		return got
+	}
+
+	t.Run("Items are unassigned by default", func(t *testing.T) {
+		db := newDB()
+		if err := newService(db).RemoveMember(context.Background(), team, member, admin, nil); err != nil {
+			t.Fatalf("RemoveMember failed: %v", err)
+		}
+
+		for _, got := range assignees(t, db) {
+			if got.Valid {
+				t.Errorf("New assignee = %s, want NULL", got.String())
+			}
+		}
+		if db.Commits != 1 || db.Count("RemoveUserFromTeam") != 1 {
+			t.Errorf("Expected removal committed in one transaction, got %d commits and calls %v", db.Commits, db.Calls)
+		}
+	})
+
+	t.Run("Items move to another member", func(t *testing.T) {
+		db := newDB()
+		to := other
+		if err := newService(db).RemoveMember(context.Background(), team, member, admin, &to); err != nil {
+			t.Fatalf("RemoveMember failed: %v", err)
+		}
+
+		for _, got := range assignees(t, db) {
+			if got != storetest.MustUUID(t, other) {
+				t.Errorf("New assignee = %s, want %s", got.String(), other)
+			}
+		}
+		if db.Commits != 1 || db.Count("RemoveUserFromTeam") != 1 {
+			t.Errorf("Expected removal committed in one transaction, got %d commits and calls %v", db.Commits, db.Calls)
+		}
+	})
+
+	t.Run("Reassigning outside the team is rejected", func(t *testing.T) {
+		for name, to := range map[string]string{
+			"non-member":     "55555555-5555-5555-5555-555555555555",
+			"removed member": member,
+		} {
+			db := newDB()
+			err := newService(db).RemoveMember(context.Background(), team, member, admin, &to)
+			if !errors.Is(err, ErrInvalidTeamData) {
+				t.Errorf("%s: expected ErrInvalidTeamData, got %v", name, err)
+			}
+			if db.Count("RemoveUserFromTeam") != 0 || db.Count("ReassignTeamMemberIssues") != 0 {
+				t.Errorf("%s: expected nothing to change, got calls %v", name, db.Calls)
+			}
+		}
+	})
+
+	t.Run("Failed removal rolls back the reassignment", func(t *testing.T) {
+		db := newDB()
+		db.Errs = map[string]error{"RemoveUserFromTeam": errors.New("connection reset")}
+		if err := newService(db).RemoveMember(context.Background(), team, member, admin, nil); err == nil {
+			t.Fatal("Expected RemoveMember to fail")
+		}
+		if db.Rollbacks != 1 || db.Count("ReassignTeamMemberIssues") != 0 {
+			t.Errorf("Expected the reassignment to roll back, got %d rollbacks and calls %v", db.Rollbacks, db.Calls)
+		}
+	})
+}
+
+func TestAddMemberPermissions(t *testing.T) {
+	const (
+		owner  = "11111111-1111-1111-1111-111111111111"
+		admin  = "22222222-2222-2222-2222-222222222222"
+		editor = "33333333-3333-3333-3333-333333333333"
+		newbie = "55555555-5555-5555-5555-555555555555"
+		team   = "44444444-4444-4444-4444-444444444444"
+	)
+
+	member := func(user, role string) []any {
+		return []any{storetest.MustUUID(t, team), storetest.MustUUID(t, user), pgtype.Text{String: role, Valid: true}}
+	}
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetTeamByID":                          {storetest.MustUUID(t, team), "Platform"},
+		"GetTeamMember:" + team + ":" + owner:  member(owner, "owner"),
+		"GetTeamMember:" + team + ":" + admin:  member(admin, "admin"),
+		"GetTeamMember:" + team + ":" + editor: member(editor, "editor"),
+	}}
+	for user, role := range map[string]string{owner: "owner", admin: "admin", editor: "editor"} {
+		db.Rows["GetTeamMemberRole:"+team+":"+user] = []any{pgtype.Text{String: role, Valid: true}}
+		db.Rows["CheckTeamMembership:"+team+":"+user] = []any{true}
+	}
+	teams := NewTeamService(store.New(db), cache.NewMemory(), CacheTTLs{})
+
+	tests := []struct {
+		name      string
+		requester string
+		target    string
+		role      string
+		wantErr   error
+	}{
+		{"Owner adds a member", owner, newbie, "editor", nil},
+		{"Admin adds a member", admin, newbie, "viewer", nil},
+		{"Editor cannot add members", editor, newbie, "viewer", ErrInsufficientRoles},
+		{"Owner promotes an editor", owner, editor, "admin", nil},
+		{"Admin cannot demote the owner", admin, owner, "viewer", ErrInsufficientRoles},
+		{"Ownership cannot be granted", owner, newbie, "owner", ErrInvalidTeamData},
+		{"Unknown roles are rejected", owner, newbie, "member", ErrInvalidTeamData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Calls = nil
+			err := teams.AddMember(context.Background(), team, tt.target, tt.role, tt.requester)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddMember = %v, want %v", err, tt.wantErr)
+			}
+			writes := db.Count("AddUserToTeam") + db.Count("UpdateTeamMemberRole")
+			if tt.wantErr == nil && writes != 1 {
+				t.Errorf("Expected the membership to be saved, got %d writes", writes)
+			}
+			if tt.wantErr != nil && writes != 0 {
+				t.Errorf("Expected no membership change, got %d writes", writes)
+			}
+
+			// Changing an existing member's role is held to the same rules
+			// whichever endpoint it comes through
+			if tt.target == newbie {
+				return
+			}
+			if err := teams.UpdateTeamMemberRole(context.Background(), team, tt.target, tt.requester, tt.role); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateTeamMemberRole = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMembershipChangesInvalidateCaches(t *testing.T) {
+	const (
+		owner  = "11111111-1111-1111-1111-111111111111"
+		editor = "33333333-3333-3333-3333-333333333333"
+		newbie = "55555555-5555-5555-5555-555555555555"
+		team   = "44444444-4444-4444-4444-444444444444"
+	)
+
+	role := func(r string) pgtype.Text { return pgtype.Text{String: r, Valid: true} }
+	member := func(user, r string) []any {
+		return []any{storetest.MustUUID(t, user), user + "@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, role(r)}
+	}
+	userTeam := func(r string, members int64) [][]any {
+		return [][]any{{storetest.MustUUID(t, team), "Platform", pgtype.Text{}, pgtype.Text{}, role(r), pgtype.Timestamp{}, pgtype.Timestamp{}, members}}
+	}
+	db := &storetest.DB{
+		Rows: map[string][]any{
+			"GetTeamByID":                          {storetest.MustUUID(t, team), "Platform"},
+			"GetTeamMember:" + team + ":" + owner:  {storetest.MustUUID(t, team), storetest.MustUUID(t, owner), role("owner")},
+			"GetTeamMember:" + team + ":" + editor: {storetest.MustUUID(t, team), storetest.MustUUID(t, editor), role("editor")},
+			"CheckTeamMembership":                  {true},
+		},
+		Lists: map[string][][]any{
+			"GetTeamMembers:" + team: {member(owner, "owner"), member(editor, "editor")},
+			"GetUserTeams:" + owner:  userTeam("owner", 2),
+			"GetUserTeams:" + editor: userTeam("editor", 2),
+		},
+	}
+	svc := NewTeamService(store.New(db), cache.NewMemory(), CacheTTLs{})
+	ctx := context.Background()
+
+	// read warms the caches and returns the member list and each user's teams
+	read := func(t *testing.T) (members []TeamMemberInfo, teams map[string][]TeamInfo) {
+		t.Helper()
+		members, err := svc.GetTeamMembers(ctx, team, owner)
+		if err != nil {
+			t.Fatalf("GetTeamMembers failed: %v", err)
+		}
+		teams = map[string][]TeamInfo{}
+		for _, user := range []string{owner, editor, newbie} {
+			if teams[user], err = svc.GetUserTeams(ctx, user); err != nil {
+				t.Fatalf("GetUserTeams failed: %v", err)
+			}
+		}
+		return members, teams
+	}
+	read(t)
+
+	t.Run("Adding a member", func(t *testing.T) {
+		if err := svc.AddMember(ctx, team, newbie, "viewer", owner); err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+		db.Lists["GetTeamMembers:"+team] = append(db.Lists["GetTeamMembers:"+team], member(newbie, "viewer"))
+		db.Lists["GetUserTeams:"+owner] = userTeam("owner", 3)
+		db.Lists["GetUserTeams:"+editor] = userTeam("editor", 3)
+		db.Lists["GetUserTeams:"+newbie] = userTeam("viewer", 3)
+
+		members, teams := read(t)
+		if len(members) != 3 {
+			t.Errorf("Members = %+v, want the new member listed", members)
+		}
+		if len(teams[newbie]) != 1 || teams[newbie][0].Role != "viewer" {
+			t.Errorf("New member's teams = %+v, want the team", teams[newbie])
+		}
+		if teams[owner][0].MemberCount != 3 || teams[editor][0].MemberCount != 3 {
+			t.Errorf("Existing members' teams = %+v, %+v, want the new member count", teams[owner], teams[editor])
+		}
+	})
+
+	t.Run("Leaving a team", func(t *testing.T) {
+		if err := svc.RemoveMember(ctx, team, editor, editor, nil); err != nil {
+			t.Fatalf("RemoveMember failed: %v", err)
+		}
+		db.Lists["GetTeamMembers:"+team] = [][]any{member(owner, "owner"), member(newbie, "viewer")}
+		db.Lists["GetUserTeams:"+owner] = userTeam("owner", 2)
+		db.Lists["GetUserTeams:"+editor] = nil
+
+		members, teams := read(t)
+		if len(members) != 2 {
+			t.Errorf("Members = %+v, want the leaver gone", members)
+		}
+		if len(teams[editor]) != 0 {
+			t.Errorf("Former member's teams = %+v, want none", teams[editor])
+		}
+		if teams[owner][0].MemberCount != 2 {
+			t.Errorf("Owner's teams = %+v, want the new member count", teams[owner])
+		}
+	})
+}
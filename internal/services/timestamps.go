@@ -0,0 +1,18 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FormatTimestamp renders a stored timestamp for clients as RFC3339, or nil,
+// sent as null, when it isn't set. Every Info struct formats its timestamps
+// with it.
+func FormatTimestamp(ts pgtype.Timestamp) *string {
+	if !ts.Valid || ts.Time.IsZero() {
+		return nil
+	}
+	formatted := ts.Time.Format(time.RFC3339)
+	return &formatted
+}
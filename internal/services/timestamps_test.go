@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	set := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		ts   pgtype.Timestamp
+		want string // "" for null
+	}{
+		{"Unset", pgtype.Timestamp{}, ""},
+		{"Zero", pgtype.Timestamp{Valid: true}, ""},
+		{"Set", pgtype.Timestamp{Time: set, Valid: true}, "2024-05-01T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatTimestamp(tt.ts)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("Expected nil, got %q", *got)
+			case tt.want != "" && (got == nil || *got != tt.want):
+				t.Errorf("Expected %q, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Unset timestamps serialize to null", func(t *testing.T) {
+		team := TeamInfo{
+			CreatedAt: FormatTimestamp(pgtype.Timestamp{Time: set, Valid: true}),
+			UpdatedAt: FormatTimestamp(pgtype.Timestamp{Valid: true}),
+		}
+		body, err := json.Marshal(team)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if !strings.Contains(string(body), `"created_at":"2024-05-01T12:00:00Z"`) || !strings.Contains(string(body), `"updated_at":null`) {
+			t.Errorf("Unexpected JSON: %s", body)
+		}
+	})
+}
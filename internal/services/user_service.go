@@ -10,9 +10,13 @@ import (
 	"time"
 
 	"github.com/Bethel-nz/tickit/internal/auth"
+	"github.com/Bethel-nz/tickit/internal/cache"
 	"github.com/Bethel-nz/tickit/internal/database/store"
 	"github.com/Bethel-nz/tickit/internal/email"
-	"github.com/go-redis/redis/v8"
+	"github.com/Bethel-nz/tickit/internal/permissions"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/Bethel-nz/tickit/internal/validator"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -22,24 +26,45 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrDuplicateEmail     = errors.New("email already in use")
 	ErrInvalidUserData    = errors.New("invalid user data")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrActiveProjects     = errors.New("user still owns active projects")
+	// ErrTokenStoreUnavailable is returned when reset or confirmation tokens
+	// can't be read or written. Unlike cached data, tokens only live in the
+	// cache, so there is nothing to fall back to.
+	ErrTokenStoreUnavailable = errors.New("token store unavailable")
+)
+
+// Profile field limits
+const (
+	maxUsernameLength = 50
+	maxBioLength      = 500
 )
 
 // UserProfile represents the user profile data returned to clients
 type UserProfile struct {
-	ID        pgtype.UUID      `json:"id"`
-	Email     string           `json:"email"`
-	Name      string           `json:"name,omitempty"`
-	Username  string           `json:"username,omitempty"`
-	AvatarURL string           `json:"avatar_url,omitempty"`
-	Bio       string           `json:"bio,omitempty"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at,omitempty"`
+	ID        pgtype.UUID `json:"id"`
+	Email     string      `json:"email"`
+	Name      string      `json:"name,omitempty"`
+	Username  string      `json:"username,omitempty"`
+	AvatarURL string      `json:"avatar_url,omitempty"`
+	Bio       string      `json:"bio,omitempty"`
+	CreatedAt *string     `json:"created_at"`
+	UpdatedAt *string     `json:"updated_at"`
+}
+
+// PublicProfile is what other users can see about a user. It never includes
+// the email address or anything else private to the account.
+type PublicProfile struct {
+	Username  string `json:"username"`
+	Name      string `json:"name,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	Bio       string `json:"bio,omitempty"`
 }
 
 // UserProfileUpdate contains fields that can be updated
 type UserProfileUpdate struct {
 	Name      string `json:"name,omitempty"`
-	Email     string `json:"email,omitempty"`
+	Email     string `json:"email,omitempty"` // Rejected; see RequestEmailChange
 	Username  string `json:"username,omitempty"`
 	AvatarURL string `json:"avatar_url,omitempty"`
 	Bio       string `json:"bio,omitempty"`
@@ -47,14 +72,18 @@ type UserProfileUpdate struct {
 
 type UserService struct {
 	queries      *store.Queries
-	cache        *redis.Client
+	cache        cache.Cache
+	ttls         CacheTTLs
 	emailService *email.EmailService
+	audit        *AuditService
+	attachments  storage.Storage // Where attachment contents are kept, so they go with deleted projects
 }
 
-func NewUserService(queries *store.Queries, cache *redis.Client, emailService *email.EmailService) *UserService {
+func NewUserService(queries *store.Queries, cache cache.Cache, emailService *email.EmailService, ttls CacheTTLs) *UserService {
 	return &UserService{
 		queries:      queries,
 		cache:        cache,
+		ttls:         ttls.withDefaults(),
 		emailService: emailService,
 	}
 }
@@ -93,13 +122,13 @@ func (s *UserService) CreateUser(ctx context.Context, params store.CreateUserPar
 
 	// Cache the user
 	userJSON, err := json.Marshal(struct {
-		ID        string `json:"id"`
-		Email     string `json:"email"`
-		Name      string `json:"name,omitempty"`
-		Username  string `json:"username,omitempty"`
-		AvatarUrl string `json:"avatar_url,omitempty"`
-		Bio       string `json:"bio,omitempty"`
-		CreatedAt string `json:"created"`
+		ID        string  `json:"id"`
+		Email     string  `json:"email"`
+		Name      string  `json:"name,omitempty"`
+		Username  string  `json:"username,omitempty"`
+		AvatarUrl string  `json:"avatar_url,omitempty"`
+		Bio       string  `json:"bio,omitempty"`
+		CreatedAt *string `json:"created"`
 	}{
 		ID:        user.ID.String(),
 		Email:     user.Email,
@@ -107,25 +136,36 @@ func (s *UserService) CreateUser(ctx context.Context, params store.CreateUserPar
 		Username:  user.Username.String,
 		AvatarUrl: user.AvatarUrl.String,
 		Bio:       user.Bio.String,
-		CreatedAt: user.CreatedAt.Time.Format(time.RFC3339),
+		CreatedAt: FormatTimestamp(user.CreatedAt),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal user: %w", err)
 	}
 
 	cacheKey := fmt.Sprintf("user:%s", user.ID.String())
-	if err := s.cache.Set(ctx, cacheKey, userJSON, time.Hour).Err(); err != nil {
+	if err := s.cache.Set(ctx, cacheKey, string(userJSON), s.ttls.User); err != nil {
 		log.Printf("Failed to cache user: %v", err)
 	}
 
 	return &user, nil
 }
 
-// DeleteAccount removes a user account and related data
-func (s *UserService) DeleteAccount(ctx context.Context, userID string) error {
+// DeleteAccount removes a user account in a single transaction, leaving
+// nothing that points at the deleted user:
+//   - teams the user is the only member of are deleted along with their projects
+//   - in other teams, a sole owner hands ownership to the highest-ranked,
+//     longest-standing remaining member, and the user's projects in the team
+//     pass to the team's owner
+//   - personal projects are deleted
+//   - issues and tasks are kept but no longer reported by or assigned to the user
+//   - comments are kept but anonymized
+//
+// Unless force is set, an account that still owns projects which haven't been
+// archived or cancelled is not deleted and ErrActiveProjects is returned.
+func (s *UserService) DeleteAccount(ctx context.Context, userID string, force bool) error {
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	user, err := s.queries.GetUserByID(ctx, scannedUserId)
@@ -134,34 +174,178 @@ func (s *UserService) DeleteAccount(ctx context.Context, userID string) error {
 	}
 
 	activeProjects, err := s.queries.GetActiveProjectsCount(ctx, scannedUserId)
-	if err == nil && activeProjects > 0 {
-		log.Printf("Warning: Deleting user with %d active projects", activeProjects)
+	if err != nil {
+		return fmt.Errorf("failed to count active projects: %w", err)
+	}
+	if activeProjects > 0 && !force {
+		return fmt.Errorf("%w: %d active projects", ErrActiveProjects, activeProjects)
 	}
 
-	if err := s.queries.DeleteUser(ctx, scannedUserId); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+	// Cache entries that may describe the user or what they leave behind
+	staleKeys := []string{
+		fmt.Sprintf("user:%s", userID),
+		fmt.Sprintf("user:%s:teams", userID),
+		accountKey(userID),
 	}
 
-	cacheKey := fmt.Sprintf("user:%s", userID)
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
-		log.Printf("Failed to remove user from cache: %v", err)
+	// Stored files of attachments in deleted projects, removed once the
+	// deletion is committed
+	var attachmentKeys []string
+
+	err = store.WithTx(ctx, s.queries, func(q *store.Queries) error {
+		projects, err := q.GetUserProjects(ctx, scannedUserId)
+		if err != nil {
+			return fmt.Errorf("failed to get projects: %w", err)
+		}
+		for _, project := range projects {
+			staleKeys = append(staleKeys, fmt.Sprintf("project:%s", project.ID.String()))
+		}
+
+		teams, err := q.GetUserTeams(ctx, scannedUserId)
+		if err != nil {
+			return fmt.Errorf("failed to get teams: %w", err)
+		}
+		for _, team := range teams {
+			teamID := team.ID.String()
+			staleKeys = append(staleKeys,
+				fmt.Sprintf("team:%s", teamID),
+				fmt.Sprintf("team:%s:members", teamID),
+				fmt.Sprintf("team:%s:projects", teamID),
+			)
+
+			if team.MemberCount <= 1 {
+				if s.attachments != nil {
+					keys, err := q.GetTeamAttachmentKeys(ctx, team.ID)
+					if err != nil {
+						return fmt.Errorf("failed to get attachments of team %s: %w", teamID, err)
+					}
+					attachmentKeys = append(attachmentKeys, keys...)
+				}
+				if err := q.DeleteTeamProjects(ctx, team.ID); err != nil {
+					return fmt.Errorf("failed to delete projects of team %s: %w", teamID, err)
+				}
+				if err := q.DeleteTeam(ctx, team.ID); err != nil {
+					return fmt.Errorf("failed to delete team %s: %w", teamID, err)
+				}
+				continue
+			}
+
+			ownerID, err := handOverTeam(ctx, q, team.ID, scannedUserId)
+			if err != nil {
+				return err
+			}
+			staleKeys = append(staleKeys,
+				fmt.Sprintf("user:%s:teams", ownerID.String()),
+			)
+
+			if _, err := q.TransferTeamProjects(ctx, store.TransferTeamProjectsParams{
+				NewOwnerID: ownerID,
+				OwnerID:    scannedUserId,
+				TeamID:     team.ID,
+			}); err != nil {
+				return fmt.Errorf("failed to transfer projects of team %s: %w", teamID, err)
+			}
+		}
+
+		if s.attachments != nil {
+			keys, err := q.GetUserProjectAttachmentKeys(ctx, scannedUserId)
+			if err != nil {
+				return fmt.Errorf("failed to get attachments: %w", err)
+			}
+			attachmentKeys = append(attachmentKeys, keys...)
+		}
+		if _, err := q.DeleteUserProjects(ctx, scannedUserId); err != nil {
+			return fmt.Errorf("failed to delete projects: %w", err)
+		}
+		if err := q.DetachUserFromIssues(ctx, scannedUserId); err != nil {
+			return fmt.Errorf("failed to detach user from issues: %w", err)
+		}
+		if err := q.UnassignUserTasks(ctx, scannedUserId); err != nil {
+			return fmt.Errorf("failed to unassign tasks: %w", err)
+		}
+
+		comments, err := q.AnonymizeUserComments(ctx, scannedUserId)
+		if err != nil {
+			return fmt.Errorf("failed to anonymize comments: %w", err)
+		}
+		for _, c := range comments {
+			if c.IssueID.Valid {
+				staleKeys = append(staleKeys, fmt.Sprintf("issue:%s:comments", c.IssueID.String()))
+			}
+			if c.TaskID.Valid {
+				staleKeys = append(staleKeys, fmt.Sprintf("task:%s:comments", c.TaskID.String()))
+			}
+		}
+
+		if err := q.RemoveUserFromAllTeams(ctx, scannedUserId); err != nil {
+			return fmt.Errorf("failed to remove team memberships: %w", err)
+		}
+		if err := q.DeleteUser(ctx, scannedUserId); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	if err := s.cache.Del(ctx, staleKeys...); err != nil {
+		log.Printf("Failed to invalidate caches for deleted user: %v", err)
+	}
+	removeAttachmentFiles(ctx, s.attachments, attachmentKeys)
+
 	log.Printf("User account deleted - ID: %s, Email: %s, Time: %s",
 		userID, user.Email, time.Now().Format(time.RFC3339))
+	s.audit.Record(ctx, userID, AuditAccountDeleted, AuditTargetUser, userID, nil)
 
 	return nil
 }
 
+// handOverTeam makes sure a team keeps an owner once userID leaves it and
+// returns that owner. The longest-standing other owner is kept if there is
+// one; otherwise the highest-ranked remaining member, earliest joined first,
+// is promoted.
+func handOverTeam(ctx context.Context, q *store.Queries, teamID, userID pgtype.UUID) (pgtype.UUID, error) {
+	members, err := q.GetTeamMembers(ctx, teamID)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to get team members: %w", err)
+	}
+
+	var successor *store.GetTeamMembersRow
+	for i, m := range members {
+		if m.ID == userID {
+			continue
+		}
+		if m.Role.String == permissions.RoleOwner {
+			return m.ID, nil
+		}
+		if successor == nil || permissions.RoleRank(m.Role.String) > permissions.RoleRank(successor.Role.String) {
+			successor = &members[i]
+		}
+	}
+	if successor == nil {
+		return pgtype.UUID{}, fmt.Errorf("team %s has no member to hand over to", teamID.String())
+	}
+
+	if err := q.UpdateTeamMemberRole(ctx, store.UpdateTeamMemberRoleParams{
+		TeamID: teamID,
+		UserID: successor.ID,
+		Role:   pgtype.Text{String: permissions.RoleOwner, Valid: true},
+	}); err != nil {
+		return pgtype.UUID{}, fmt.Errorf("failed to promote new team owner: %w", err)
+	}
+	return successor.ID, nil
+}
+
 // GetUserProfile retrieves user profile information
 func (s *UserService) GetUserProfile(ctx context.Context, userID string) (*UserProfile, error) {
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return nil, fmt.Errorf("invalid user ID format: %w", err)
+		return nil, invalidID("user ID", err)
 	}
 
 	cacheKey := fmt.Sprintf("user:%s", userID)
-	cachedUser, err := s.cache.Get(ctx, cacheKey).Result()
+	cachedUser, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var profile UserProfile
 		if err := json.Unmarshal([]byte(cachedUser), &profile); err == nil {
@@ -181,13 +365,13 @@ func (s *UserService) GetUserProfile(ctx context.Context, userID string) (*UserP
 		Username:  user.Username.String,
 		AvatarURL: user.AvatarUrl.String,
 		Bio:       user.Bio.String,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		CreatedAt: FormatTimestamp(user.CreatedAt),
+		UpdatedAt: FormatTimestamp(user.UpdatedAt),
 	}
 
 	profileJSON, err := json.Marshal(profile)
 	if err == nil {
-		if err := s.cache.Set(ctx, cacheKey, profileJSON, time.Hour).Err(); err != nil {
+		if err := s.cache.Set(ctx, cacheKey, string(profileJSON), s.ttls.User); err != nil {
 			log.Printf("Failed to cache user profile: %v", err)
 		}
 	}
@@ -195,11 +379,54 @@ func (s *UserService) GetUserProfile(ctx context.Context, userID string) (*UserP
 	return profile, nil
 }
 
+// GetPublicProfile looks up a user's public profile by username. Usernames
+// are matched case-insensitively.
+func (s *UserService) GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error) {
+	if username == "" || !validator.IsAlphanumeric(username) {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.queries.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &PublicProfile{
+		Username:  user.Username.String,
+		Name:      user.Name.String,
+		AvatarURL: user.AvatarUrl.String,
+		Bio:       user.Bio.String,
+	}, nil
+}
+
 // UpdateUserProfile updates user profile information
 func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, updates UserProfileUpdate) error {
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
+		return invalidID("user ID", err)
+	}
+
+	if updates.Email != "" {
+		return fmt.Errorf("%w: email changes must be confirmed through /users/me/email", ErrInvalidUserData)
+	}
+
+	if updates.AvatarURL != "" && !validator.IsValidURL(updates.AvatarURL) {
+		return fmt.Errorf("%w: avatar_url must be an http or https URL", ErrInvalidUserData)
+	}
+
+	if updates.Username != "" && !validator.IsAlphanumeric(updates.Username) {
+		return fmt.Errorf("%w: username may only contain letters and digits", ErrInvalidUserData)
+	}
+
+	if !validator.MaxChars(updates.Username, maxUsernameLength) {
+		return fmt.Errorf("%w: username cannot exceed %d characters", ErrInvalidUserData, maxUsernameLength)
+	}
+
+	if !validator.MaxChars(updates.Bio, maxBioLength) {
+		return fmt.Errorf("%w: bio cannot exceed %d characters", ErrInvalidUserData, maxBioLength)
 	}
 
 	_, err := s.queries.GetUserByID(ctx, scannedUserId)
@@ -210,7 +437,6 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, upda
 	if err := s.queries.UpdateUserProfile(ctx, store.UpdateUserProfileParams{
 		ID:        scannedUserId,
 		Name:      pgtype.Text{String: updates.Name, Valid: updates.Name != ""},
-		Email:     updates.Email,
 		Username:  pgtype.Text{String: updates.Username, Valid: updates.Username != ""},
 		AvatarUrl: pgtype.Text{String: updates.AvatarURL, Valid: updates.AvatarURL != ""},
 		Bio:       pgtype.Text{String: updates.Bio, Valid: updates.Bio != ""},
@@ -219,7 +445,33 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, upda
 	}
 
 	cacheKey := fmt.Sprintf("user:%s", userID)
-	if err := s.cache.Del(ctx, cacheKey).Err(); err != nil {
+	if err := s.cache.Del(ctx, cacheKey); err != nil {
+		log.Printf("Failed to invalidate user cache: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateAvatar points the user's avatar_url at an uploaded file
+func (s *UserService) UpdateAvatar(ctx context.Context, userID, avatarURL string) error {
+	var scannedUserId pgtype.UUID
+	if err := scannedUserId.Scan(userID); err != nil {
+		return invalidID("user ID", err)
+	}
+
+	rows, err := s.queries.UpdateUserAvatar(ctx, store.UpdateUserAvatarParams{
+		ID:        scannedUserId,
+		AvatarUrl: pgtype.Text{String: avatarURL, Valid: avatarURL != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	cacheKey := fmt.Sprintf("user:%s", userID)
+	if err := s.cache.Del(ctx, cacheKey); err != nil {
 		log.Printf("Failed to invalidate user cache: %v", err)
 	}
 
@@ -230,7 +482,7 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID string, upda
 func (s *UserService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
 	var scannedUserId pgtype.UUID
 	if err := scannedUserId.Scan(userID); err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
+		return invalidID("user ID", err)
 	}
 
 	user, err := s.queries.GetUserByEmail(ctx, userID)
@@ -263,6 +515,8 @@ func (s *UserService) ChangePassword(ctx context.Context, userID, currentPasswor
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	s.audit.Record(ctx, userID, AuditPasswordChanged, AuditTargetUser, userID, nil)
+
 	return nil
 }
 
@@ -278,16 +532,19 @@ func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
 	token := auth.GenerateSecureToken(32)
 
 	resetKey := fmt.Sprintf("password_reset:%s", token)
-	if err := s.cache.Set(ctx, resetKey, user.ID.String(), 24*time.Hour).Err(); err != nil {
-		return fmt.Errorf("failed to store reset token: %w", err)
+	if err := s.cache.Set(ctx, resetKey, user.ID.String(), s.ttls.ResetToken); err != nil {
+		return fmt.Errorf("%w: failed to store reset token: %v", ErrTokenStoreUnavailable, err)
 	}
 
 	resetLink := fmt.Sprintf("https://acme.example.com/reset-password?token=%s", token)
 
 	if s.emailService != nil {
-		if err := s.emailService.SendPasswordResetEmail(email, resetLink); err != nil {
-			log.Printf("Failed to send password reset email: %v", err)
-		}
+		// Sending may retry for several seconds, so don't hold up the request
+		go func() {
+			if err := s.emailService.SendPasswordResetEmail(email, resetLink); err != nil {
+				log.Printf("Failed to send password reset email: %v", err)
+			}
+		}()
 	} else {
 		log.Printf("Password reset link for %s: %s", email, resetLink)
 	}
@@ -299,9 +556,12 @@ func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
 func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
 
 	resetKey := fmt.Sprintf("password_reset:%s", token)
-	userID, err := s.cache.Get(ctx, resetKey).Result()
+	userID, err := s.cache.Get(ctx, resetKey)
+	if errors.Is(err, cache.ErrMiss) {
+		return ErrInvalidResetToken
+	}
 	if err != nil {
-		return errors.New("invalid or expired reset token")
+		return fmt.Errorf("%w: failed to read reset token: %v", ErrTokenStoreUnavailable, err)
 	}
 
 	var scannedUserId pgtype.UUID
@@ -323,10 +583,13 @@ func (s *UserService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	if err := s.cache.Del(ctx, resetKey).Err(); err != nil {
+	if err := s.cache.Del(ctx, resetKey); err != nil {
 		log.Printf("Failed to delete reset token: %v", err)
 	}
 
+	// Whoever held the emailed token did this, so there's no known actor
+	s.audit.Record(ctx, "", AuditPasswordReset, AuditTargetUser, userID, nil)
+
 	return nil
 }
 
@@ -354,5 +617,10 @@ func (s *UserService) AuthenticateUser(ctx context.Context, email, password stri
 		return nil, ErrInvalidCredentials
 	}
 
+	// Checked after the password so the status isn't revealed without it
+	if user.DisabledAt.Valid {
+		return nil, ErrAccountDisabled
+	}
+
 	return &user, nil
 }
@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/Bethel-nz/tickit/internal/storage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestProfileAndTeamUpdateValidation(t *testing.T) {
+	const (
+		user = "11111111-1111-1111-1111-111111111111"
+		team = "44444444-4444-4444-4444-444444444444"
+	)
+
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetUserByID":       {storetest.MustUUID(t, user), "ada@example.com"},
+		"GetTeamMemberRole": {pgtype.Text{String: "owner", Valid: true}},
+	}}
+	mr := miniredis.RunT(t)
+	redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	queries := store.New(db)
+	users := NewUserService(queries, redisCache, nil, CacheTTLs{})
+	teams := NewTeamService(queries, redisCache, CacheTTLs{})
+	ctx := context.Background()
+
+	profiles := []struct {
+		name    string
+		update  UserProfileUpdate
+		wantErr error
+	}{
+		{"Valid profile", UserProfileUpdate{Username: "ada42", AvatarURL: "https://example.com/ada.png", Bio: "Engineer"}, nil},
+		{"Script avatar URL", UserProfileUpdate{AvatarURL: "javascript:alert(1)"}, ErrInvalidUserData},
+		{"Garbage avatar URL", UserProfileUpdate{AvatarURL: "not a url"}, ErrInvalidUserData},
+		{"Username with symbols", UserProfileUpdate{Username: "ada<script>"}, ErrInvalidUserData},
+		{"Username with spaces", UserProfileUpdate{Username: "ada lovelace"}, ErrInvalidUserData},
+		{"Username too long", UserProfileUpdate{Username: strings.Repeat("a", maxUsernameLength+1)}, ErrInvalidUserData},
+		{"Bio too long", UserProfileUpdate{Bio: strings.Repeat("x", maxBioLength+1)}, ErrInvalidUserData},
+		{"Direct email change", UserProfileUpdate{Email: "eve@example.com"}, ErrInvalidUserData},
+	}
+	for _, tt := range profiles {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Calls = nil
+			err := users.UpdateUserProfile(ctx, user, tt.update)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUserProfile = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && db.Count("UpdateUserProfile") != 0 {
+				t.Error("Invalid profile was saved")
+			}
+		})
+	}
+
+	teamUpdates := []struct {
+		name      string
+		avatarURL string
+		wantErr   error
+	}{
+		{"Valid team avatar", "https://example.com/team.png", nil},
+		{"Script team avatar", "javascript:alert(1)", ErrInvalidTeamData},
+		{"Garbage team avatar", "ftp//nope", ErrInvalidTeamData},
+	}
+	for _, tt := range teamUpdates {
+		t.Run(tt.name, func(t *testing.T) {
+			db.Calls = nil
+			err := teams.UpdateTeam(ctx, store.UpdateTeamParams{
+				ID:        storetest.MustUUID(t, team),
+				Name:      "Platform",
+				AvatarUrl: pgtype.Text{String: tt.avatarURL, Valid: true},
+			}, user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateTeam = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && db.Count("UpdateTeam") != 0 {
+				t.Error("Invalid team was saved")
+			}
+		})
+	}
+}
+
+func TestEmailChangeLifecycle(t *testing.T) {
+	const (
+		user     = "11111111-1111-1111-1111-111111111111"
+		newEmail = "ada@new.example.com"
+	)
+
+	newService := func(t *testing.T) (*UserService, *storetest.DB, *miniredis.Miniredis) {
+		db := &storetest.DB{Rows: map[string][]any{
+			"GetUserByID": {storetest.MustUUID(t, user), "ada@example.com"},
+		}}
+		mr := miniredis.RunT(t)
+		users := NewUserService(store.New(db), cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), nil, CacheTTLs{})
+		return users, db, mr
+	}
+	// pendingToken returns the confirmation token waiting for the user
+	pendingToken := func(t *testing.T, mr *miniredis.Miniredis) string {
+		t.Helper()
+		token, err := mr.Get(pendingEmailChangeKey(user))
+		if err != nil {
+			t.Fatalf("No pending email change: %v", err)
+		}
+		return token
+	}
+	ctx := context.Background()
+
+	t.Run("Email only changes after confirmation", func(t *testing.T) {
+		users, db, mr := newService(t)
+
+		if err := users.RequestEmailChange(ctx, user, newEmail); err != nil {
+			t.Fatalf("RequestEmailChange failed: %v", err)
+		}
+		if n := db.Count("UpdateUserEmail") + db.Count("UpdateUserProfile"); n != 0 {
+			t.Fatalf("Email was written before confirmation (%d updates)", n)
+		}
+
+		token := pendingToken(t, mr)
+		if err := users.ConfirmEmailChange(ctx, token); err != nil {
+			t.Fatalf("ConfirmEmailChange failed: %v", err)
+		}
+		args := db.Args("UpdateUserEmail")
+		if len(args) != 1 || args[0][0] != storetest.MustUUID(t, user) || args[0][1] != newEmail {
+			t.Fatalf("Expected the new email to be saved once, got %v", args)
+		}
+
+		if err := users.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrInvalidEmailChangeToken) {
+			t.Errorf("Reusing the token = %v, want ErrInvalidEmailChangeToken", err)
+		}
+		if mr.Exists(pendingEmailChangeKey(user)) {
+			t.Error("Pending change was not cleared after confirmation")
+		}
+	})
+
+	t.Run("A new request supersedes the previous link", func(t *testing.T) {
+		users, db, mr := newService(t)
+
+		if err := users.RequestEmailChange(ctx, user, "typo@new.example.com"); err != nil {
+			t.Fatalf("RequestEmailChange failed: %v", err)
+		}
+		first := pendingToken(t, mr)
+		if err := users.RequestEmailChange(ctx, user, newEmail); err != nil {
+			t.Fatalf("RequestEmailChange failed: %v", err)
+		}
+
+		if err := users.ConfirmEmailChange(ctx, first); !errors.Is(err, ErrInvalidEmailChangeToken) {
+			t.Errorf("Confirming the superseded link = %v, want ErrInvalidEmailChangeToken", err)
+		}
+		if err := users.ConfirmEmailChange(ctx, pendingToken(t, mr)); err != nil {
+			t.Fatalf("ConfirmEmailChange failed: %v", err)
+		}
+		if args := db.Args("UpdateUserEmail"); len(args) != 1 || args[0][1] != newEmail {
+			t.Errorf("Expected only the latest address to be saved, got %v", args)
+		}
+	})
+
+	t.Run("Registered addresses are rejected", func(t *testing.T) {
+		users, db, mr := newService(t)
+		db.Rows["GetUserByEmail"] = []any{storetest.MustUUID(t, "22222222-2222-2222-2222-222222222222"), newEmail}
+
+		if err := users.RequestEmailChange(ctx, user, newEmail); !errors.Is(err, ErrDuplicateEmail) {
+			t.Fatalf("RequestEmailChange = %v, want ErrDuplicateEmail", err)
+		}
+		if mr.Exists(pendingEmailChangeKey(user)) {
+			t.Error("A pending change was stored for a registered address")
+		}
+	})
+
+	t.Run("Unknown tokens are rejected", func(t *testing.T) {
+		users, db, _ := newService(t)
+
+		if err := users.ConfirmEmailChange(ctx, "nope"); !errors.Is(err, ErrInvalidEmailChangeToken) {
+			t.Errorf("ConfirmEmailChange = %v, want ErrInvalidEmailChangeToken", err)
+		}
+		if db.Count("UpdateUserEmail") != 0 {
+			t.Error("Email was updated without a valid token")
+		}
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	const (
+		me      = "11111111-1111-1111-1111-111111111111"
+		viewer  = "22222222-2222-2222-2222-222222222222"
+		admin   = "33333333-3333-3333-3333-333333333333"
+		admin2  = "44444444-4444-4444-4444-444444444444"
+		owner   = "55555555-5555-5555-5555-555555555555"
+		solo    = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa" // only me
+		shared  = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb" // I'm the sole owner
+		joined  = "cccccccc-cccc-cccc-cccc-cccccccccccc" // someone else owns it
+		project = "dddddddd-dddd-dddd-dddd-dddddddddddd"
+		issue   = "eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee"
+	)
+	role := func(r string) pgtype.Text { return pgtype.Text{String: r, Valid: true} }
+	team := func(id, r string, members int64) []any {
+		return []any{storetest.MustUUID(t, id), "Team", pgtype.Text{}, pgtype.Text{}, role(r), pgtype.Timestamp{}, pgtype.Timestamp{}, members}
+	}
+	member := func(id, r string) []any {
+		return []any{storetest.MustUUID(t, id), id + "@example.com", pgtype.Text{}, pgtype.Text{}, pgtype.Text{}, role(r)}
+	}
+
+	newService := func(t *testing.T) (*UserService, *storetest.DB, *miniredis.Miniredis) {
+		db := &storetest.DB{
+			Rows: map[string][]any{
+				"GetUserByID:" + me:            {storetest.MustUUID(t, me), "me@example.com"},
+				"GetActiveProjectsCount:" + me: {int64(2)},
+			},
+			Lists: map[string][][]any{
+				"GetUserProjects:" + me: {{storetest.MustUUID(t, project), "Personal"}},
+				"GetUserTeams:" + me: {
+					team(solo, "owner", 1),
+					team(shared, "owner", 4),
+					team(joined, "editor", 2),
+				},
+				"GetTeamMembers:" + shared: {
+					member(me, "owner"),
+					member(viewer, "viewer"),
+					member(admin, "admin"),
+					member(admin2, "admin"),
+				},
+				"GetTeamMembers:" + joined: {
+					member(owner, "owner"),
+					member(me, "editor"),
+				},
+				"AnonymizeUserComments:" + me: {{storetest.MustUUID(t, issue), pgtype.UUID{}}},
+			},
+		}
+		mr := miniredis.RunT(t)
+		for _, key := range []string{"user:" + me, "team:" + shared, "project:" + project, "issue:" + issue + ":comments"} {
+			mr.Set(key, "cached")
+		}
+		return NewUserService(store.New(db), cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), nil, CacheTTLs{}), db, mr
+	}
+	ctx := context.Background()
+
+	t.Run("Sole owner with projects", func(t *testing.T) {
+		users, db, mr := newService(t)
+		if err := users.DeleteAccount(ctx, me, true); err != nil {
+			t.Fatalf("DeleteAccount failed: %v", err)
+		}
+		if db.Commits != 1 || db.Rollbacks != 0 {
+			t.Fatalf("Expected one committed transaction, got %d commits and %d rollbacks", db.Commits, db.Rollbacks)
+		}
+
+		// Only the team with no one else in it goes, projects first
+		for _, name := range []string{"DeleteTeamProjects", "DeleteTeam"} {
+			if args := db.Args(name); len(args) != 1 || args[0][0] != storetest.MustUUID(t, solo) {
+				t.Errorf("%s ran with %v, want only the solo team", name, args)
+			}
+		}
+
+		// The earliest-joined admin inherits the team I was the only owner of
+		promoted := db.Args("UpdateTeamMemberRole")
+		if len(promoted) != 1 || promoted[0][0] != storetest.MustUUID(t, shared) ||
+			promoted[0][1] != storetest.MustUUID(t, admin) || promoted[0][2] != role("owner") {
+			t.Errorf("UpdateTeamMemberRole ran with %v, want %s promoted to owner", promoted, admin)
+		}
+
+		// My projects in remaining teams go to each team's owner
+		transfers := db.Args("TransferTeamProjects")
+		if len(transfers) != 2 {
+			t.Fatalf("Expected projects to be transferred in two teams, got %v", transfers)
+		}
+		want := map[pgtype.UUID]pgtype.UUID{storetest.MustUUID(t, shared): storetest.MustUUID(t, admin), storetest.MustUUID(t, joined): storetest.MustUUID(t, owner)}
+		for _, args := range transfers {
+			if args[1] != storetest.MustUUID(t, me) || want[args[2].(pgtype.UUID)] != args[0] {
+				t.Errorf("TransferTeamProjects ran with %v", args)
+			}
+		}
+
+		for _, name := range []string{"DeleteUserProjects", "DetachUserFromIssues", "UnassignUserTasks", "AnonymizeUserComments", "RemoveUserFromAllTeams", "DeleteUser"} {
+			if args := db.Args(name); len(args) != 1 || args[0][0] != storetest.MustUUID(t, me) {
+				t.Errorf("%s ran with %v, want once for the deleted user", name, args)
+			}
+		}
+		if last := db.Calls[len(db.Calls)-1].Name; last != "DeleteUser" {
+			t.Errorf("Last query was %s, want DeleteUser once nothing references the user", last)
+		}
+
+		for _, key := range []string{"user:" + me, "team:" + shared, "project:" + project, "issue:" + issue + ":comments"} {
+			if mr.Exists(key) {
+				t.Errorf("Cache entry %s survived the deletion", key)
+			}
+		}
+	})
+
+	t.Run("Active projects need confirming", func(t *testing.T) {
+		users, db, mr := newService(t)
+		err := users.DeleteAccount(ctx, me, false)
+		if !errors.Is(err, ErrActiveProjects) {
+			t.Fatalf("DeleteAccount = %v, want ErrActiveProjects", err)
+		}
+		if !strings.Contains(err.Error(), "2 active projects") {
+			t.Errorf("Error %q doesn't say how many projects are active", err)
+		}
+		if args := db.Args("GetActiveProjectsCount"); len(args) != 1 || args[0][0] != storetest.MustUUID(t, me) {
+			t.Errorf("GetActiveProjectsCount ran with %v", args)
+		}
+		if db.Commits != 0 || db.Count("DeleteUser") != 0 || !mr.Exists("user:"+me) {
+			t.Error("Account was deleted without confirmation")
+		}
+
+		// Nothing to confirm once they are all archived or cancelled
+		db.Rows["GetActiveProjectsCount:"+me] = []any{int64(0)}
+		if err := users.DeleteAccount(ctx, me, false); err != nil {
+			t.Fatalf("DeleteAccount without active projects failed: %v", err)
+		}
+		if db.Count("DeleteUser") != 1 {
+			t.Error("Expected the account to be deleted")
+		}
+	})
+
+	t.Run("Files of deleted projects are removed", func(t *testing.T) {
+		keys := []string{"attachments/solo-issue/a", "attachments/personal-issue/b"}
+		newFiles := func(t *testing.T) *storage.LocalStorage {
+			files, err := storage.NewLocalStorage(t.TempDir(), "")
+			if err != nil {
+				t.Fatalf("NewLocalStorage failed: %v", err)
+			}
+			for _, key := range keys {
+				if _, err := files.Put(ctx, key, strings.NewReader("contents"), "text/plain"); err != nil {
+					t.Fatalf("Put failed: %v", err)
+				}
+			}
+			return files
+		}
+		stored := func(files *storage.LocalStorage) []string {
+			var left []string
+			for _, key := range keys {
+				if r, err := files.Open(ctx, key); err == nil {
+					r.Close()
+					left = append(left, key)
+				}
+			}
+			return left
+		}
+
+		users, db, _ := newService(t)
+		files := newFiles(t)
+		users.attachments = files
+		db.Lists["GetTeamAttachmentKeys:"+solo] = [][]any{{keys[0]}}
+		db.Lists["GetUserProjectAttachmentKeys:"+me] = [][]any{{keys[1]}}
+		if err := users.DeleteAccount(ctx, me, true); err != nil {
+			t.Fatalf("DeleteAccount failed: %v", err)
+		}
+		if left := stored(files); len(left) != 0 {
+			t.Errorf("Files %v survived the deletion", left)
+		}
+		if args := db.Args("GetTeamAttachmentKeys"); len(args) != 1 || args[0][0] != storetest.MustUUID(t, solo) {
+			t.Errorf("GetTeamAttachmentKeys ran with %v, want only the solo team", args)
+		}
+
+		// Nothing is removed unless the deletion goes through
+		users, db, _ = newService(t)
+		files = newFiles(t)
+		users.attachments = files
+		db.Lists["GetUserProjectAttachmentKeys:"+me] = [][]any{{keys[1]}}
+		db.Errs = map[string]error{"DeleteUser": errors.New("connection reset")}
+		if err := users.DeleteAccount(ctx, me, true); err == nil {
+			t.Fatal("Expected an error")
+		}
+		if left := stored(files); len(left) != len(keys) {
+			t.Errorf("Only %v are left after a failed deletion", left)
+		}
+	})
+
+	t.Run("Failure rolls everything back", func(t *testing.T) {
+		users, db, mr := newService(t)
+		db.Errs = map[string]error{"DeleteUser": errors.New("connection reset")}
+
+		if err := users.DeleteAccount(ctx, me, true); err == nil {
+			t.Fatal("Expected an error")
+		}
+		if db.Commits != 0 || db.Rollbacks != 1 {
+			t.Errorf("Expected a rollback, got %d commits and %d rollbacks", db.Commits, db.Rollbacks)
+		}
+		if n := db.Count("DeleteTeam") + db.Count("TransferTeamProjects") + db.Count("AnonymizeUserComments"); n != 0 {
+			t.Errorf("%d changes were applied despite the failure", n)
+		}
+		if !mr.Exists("user:" + me) {
+			t.Error("Cache was cleared for a user that still exists")
+		}
+	})
+}
+
+func TestCacheOutage(t *testing.T) {
+	const user = "11111111-1111-1111-1111-111111111111"
+	db := &storetest.DB{Rows: map[string][]any{
+		"GetUserByID":    {storetest.MustUUID(t, user), "ada@example.com"},
+		"GetUserByEmail": {storetest.MustUUID(t, user), "ada@example.com"},
+	}}
+	mr := miniredis.RunT(t)
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	svc := NewUserService(store.New(db), cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), nil, CacheTTLs{})
+	ctx := context.Background()
+
+	t.Run("Reads fall back to the database", func(t *testing.T) {
+		profile, err := svc.GetUserProfile(ctx, user)
+		if err != nil {
+			t.Fatalf("GetUserProfile failed: %v", err)
+		}
+		if profile.Email != "ada@example.com" {
+			t.Errorf("Profile = %+v, want it read from the database", profile)
+		}
+	})
+
+	t.Run("Tokens can't be issued or redeemed", func(t *testing.T) {
+		if err := svc.ForgotPassword(ctx, "ada@example.com"); !errors.Is(err, ErrTokenStoreUnavailable) {
+			t.Errorf("ForgotPassword = %v, want ErrTokenStoreUnavailable", err)
+		}
+		if err := svc.ResetPassword(ctx, "token", "new-password"); !errors.Is(err, ErrTokenStoreUnavailable) {
+			t.Errorf("ResetPassword = %v, want ErrTokenStoreUnavailable", err)
+		}
+		if err := svc.ConfirmEmailChange(ctx, "token"); !errors.Is(err, ErrTokenStoreUnavailable) {
+			t.Errorf("ConfirmEmailChange = %v, want ErrTokenStoreUnavailable", err)
+		}
+		if n := db.Count("UpdateUserPassword") + db.Count("UpdateUserEmail"); n != 0 {
+			t.Errorf("Expected no changes without a token, got %d queries", n)
+		}
+	})
+
+	t.Run("Unknown tokens are still rejected", func(t *testing.T) {
+		mr.SetError("")
+		if err := svc.ResetPassword(ctx, "token", "new-password"); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("ResetPassword = %v, want ErrInvalidResetToken", err)
+		}
+	})
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/validator"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Webhook service errors
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
+
+// Events a webhook can subscribe to. An issue that is closed sends both
+// issue.updated and issue.closed.
+const (
+	EventIssueCreated   = "issue.created"
+	EventIssueUpdated   = "issue.updated"
+	EventIssueClosed    = "issue.closed"
+	EventCommentCreated = "comment.created"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{EventIssueCreated, EventIssueUpdated, EventIssueClosed, EventCommentCreated}
+
+// Webhook types pick the format deliveries are sent in. Generic webhooks get
+// a WebhookPayload; Slack webhooks get a SlackMessage.
+const (
+	WebhookTypeGeneric = "generic"
+	WebhookTypeSlack   = "slack"
+)
+
+// WebhookTypes lists every webhook type
+var WebhookTypes = []string{WebhookTypeGeneric, WebhookTypeSlack}
+
+// webhookFormatters encode a payload for each webhook type
+var webhookFormatters = map[string]func(WebhookPayload) ([]byte, error){
+	WebhookTypeGeneric: func(payload WebhookPayload) ([]byte, error) { return json.Marshal(payload) },
+	WebhookTypeSlack:   formatSlack,
+}
+
+// Headers sent with every delivery. The signature is "sha256=" followed by
+// the hex HMAC-SHA256 of the body keyed with the webhook's secret; see
+// SignWebhookPayload.
+const (
+	WebhookSignatureHeader = "X-Tickit-Signature"
+	WebhookEventHeader     = "X-Tickit-Event"
+	WebhookDeliveryHeader  = "X-Tickit-Delivery"
+)
+
+// Delivery defaults, overridable with WithRetry
+const (
+	defaultWebhookAttempts   = 3
+	defaultWebhookRetryDelay = time.Second
+)
+
+// webhookTimeout bounds a single delivery attempt
+const webhookTimeout = 10 * time.Second
+
+// errBlockedAddress refuses connections to addresses inside our network
+var errBlockedAddress = errors.New("webhook address is not public")
+
+// WebhookInfo represents a webhook returned to clients. The secret is only
+// included when the webhook is created.
+type WebhookInfo struct {
+	ID        string   `json:"id"`
+	ProjectID string   `json:"project_id"`
+	URL       string   `json:"url"`
+	Type      string   `json:"type"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedBy string   `json:"created_by,omitempty"`
+	CreatedAt *string  `json:"created_at"`
+	UpdatedAt *string  `json:"updated_at"`
+}
+
+// WebhookInput holds a webhook's settings. When creating a webhook an empty
+// Secret is generated and an empty Type means generic; when updating one,
+// empty fields are left unchanged.
+type WebhookInput struct {
+	URL    string
+	Type   string
+	Secret string
+	Events []string
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook. ID identifies the
+// event and stays the same across retries, so receivers can drop repeats.
+type WebhookPayload struct {
+	ID         string `json:"id"`
+	Event      string `json:"event"`
+	ProjectID  string `json:"project_id"`
+	ActorID    string `json:"actor_id,omitempty"`
+	OccurredAt string `json:"occurred_at"`
+	Data       any    `json:"data"`
+}
+
+// WebhookService manages a project's webhooks and delivers its events to them
+type WebhookService struct {
+	queries        *store.Queries
+	projectService *ProjectService
+	client         *http.Client
+	lookupIP       func(ctx context.Context, host string) ([]netip.Addr, error)
+	maxAttempts    int
+	retryDelay     time.Duration
+	sleep          func(time.Duration)
+	spawn          func(func()) // Runs a delivery, in the background
+}
+
+// NewWebhookService creates a webhook service
+func NewWebhookService(queries *store.Queries, projectService *ProjectService) *WebhookService {
+	return &WebhookService{
+		queries:        queries,
+		projectService: projectService,
+		client:         newWebhookClient(),
+		lookupIP:       resolveHost,
+		maxAttempts:    defaultWebhookAttempts,
+		retryDelay:     defaultWebhookRetryDelay,
+		sleep:          time.Sleep,
+		spawn:          func(deliver func()) { go deliver() },
+	}
+}
+
+// WithRetry sets how many times a delivery is attempted and the delay before
+// the first retry. The delay doubles after each failed attempt.
+func (s *WebhookService) WithRetry(maxAttempts int, retryDelay time.Duration) *WebhookService {
+	if maxAttempts > 0 {
+		s.maxAttempts = maxAttempts
+	}
+	if retryDelay >= 0 {
+		s.retryDelay = retryDelay
+	}
+	return s
+}
+
+// SignWebhookPayload returns the signature header value for body: "sha256="
+// followed by the hex HMAC-SHA256 of body keyed with secret
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook adds a webhook to a project. Only the project's owner can
+// manage its webhooks.
+func (s *WebhookService) CreateWebhook(ctx context.Context, projectID, userID string, input WebhookInput) (*WebhookInfo, error) {
+	project, err := s.projectService.loadOwnedProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateWebhookURL(ctx, input.URL); err != nil {
+		return nil, err
+	}
+	events, err := webhookEvents(input.Events)
+	if err != nil {
+		return nil, err
+	}
+	webhookType := input.Type
+	if webhookType == "" {
+		webhookType = WebhookTypeGeneric
+	}
+	if err := validateWebhookType(webhookType); err != nil {
+		return nil, err
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = randomHex(32); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, invalidID("user ID", err)
+	}
+
+	hook, err := s.queries.CreateWebhook(ctx, store.CreateWebhookParams{
+		ProjectID: project.ID,
+		Url:       input.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: userUUID,
+		Type:      webhookType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	info := webhookToInfo(hook)
+	info.Secret = hook.Secret
+	return &info, nil
+}
+
+// ListWebhooks lists a project's webhooks, oldest first
+func (s *WebhookService) ListWebhooks(ctx context.Context, projectID, userID string) ([]WebhookInfo, error) {
+	project, err := s.projectService.loadOwnedProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.queries.GetProjectWebhooks(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	result := make([]WebhookInfo, 0, len(hooks))
+	for _, hook := range hooks {
+		result = append(result, webhookToInfo(hook))
+	}
+	return result, nil
+}
+
+// UpdateWebhook changes a webhook's URL, type, secret or events
+func (s *WebhookService) UpdateWebhook(ctx context.Context, projectID, webhookID, userID string, input WebhookInput) (*WebhookInfo, error) {
+	hook, err := s.ownedWebhook(ctx, projectID, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := store.UpdateWebhookParams{ID: hook.ID}
+	if input.URL != "" {
+		if err := s.validateWebhookURL(ctx, input.URL); err != nil {
+			return nil, err
+		}
+		params.Url = pgtype.Text{String: input.URL, Valid: true}
+	}
+	if input.Type != "" {
+		if err := validateWebhookType(input.Type); err != nil {
+			return nil, err
+		}
+		params.Type = pgtype.Text{String: input.Type, Valid: true}
+	}
+	if input.Secret != "" {
+		params.Secret = pgtype.Text{String: input.Secret, Valid: true}
+	}
+	if input.Events != nil {
+		if params.Events, err = webhookEvents(input.Events); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.queries.UpdateWebhook(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	info := webhookToInfo(updated)
+	return &info, nil
+}
+
+// DeleteWebhook removes a webhook; it receives no further events
+func (s *WebhookService) DeleteWebhook(ctx context.Context, projectID, webhookID, userID string) error {
+	hook, err := s.ownedWebhook(ctx, projectID, webhookID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.queries.DeleteWebhook(ctx, hook.ID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// Dispatch sends event to the project's webhooks that subscribe to it, with
+// data as the payload's data. Deliveries run in the background and are a
+// side effect: failed ones are retried, then logged. A nil *WebhookService
+// sends nothing.
+func (s *WebhookService) Dispatch(ctx context.Context, projectID pgtype.UUID, event, actorID string, data any) {
+	if s == nil {
+		return
+	}
+
+	hooks, err := s.queries.GetProjectWebhooks(ctx, projectID)
+	if err != nil {
+		log.Printf("Failed to get webhooks for %s event: %v", event, err)
+		return
+	}
+	hooks = slices.DeleteFunc(hooks, func(hook store.Webhook) bool {
+		return !slices.Contains(hook.Events, event)
+	})
+	if len(hooks) == 0 {
+		return
+	}
+
+	deliveryID, err := randomHex(16)
+	if err != nil {
+		log.Printf("Failed to send %s event: %v", event, err)
+		return
+	}
+	payload := WebhookPayload{
+		ID:         deliveryID,
+		Event:      event,
+		ProjectID:  projectID.String(),
+		ActorID:    actorID,
+		OccurredAt: time.Now().UTC().Format(time.RFC3339),
+		Data:       data,
+	}
+
+	// The event happened even if the request that caused it is cancelled
+	ctx = context.WithoutCancel(ctx)
+	bodies := make(map[string][]byte) // Encoded once per webhook type
+	for _, hook := range hooks {
+		body, ok := bodies[hook.Type]
+		if !ok {
+			if body, err = formatWebhook(hook.Type, payload); err != nil {
+				log.Printf("Failed to encode %s event for webhook %s: %v", event, hook.ID.String(), err)
+				continue
+			}
+			bodies[hook.Type] = body
+		}
+		s.spawn(func() {
+			if attempts, err := s.deliver(ctx, hook, event, deliveryID, body); err != nil {
+				log.Printf("Giving up on %s delivery to webhook %s after %d attempts: %v", event, hook.ID.String(), attempts, err)
+			}
+		})
+	}
+}
+
+// deliver POSTs a signed payload to a webhook, retrying with exponential
+// backoff while the receiver can't be reached or answers with a server
+// error. It returns the number of attempts made.
+func (s *WebhookService) deliver(ctx context.Context, hook store.Webhook, event, deliveryID string, body []byte) (int, error) {
+	delay := s.retryDelay
+	var err error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = s.post(ctx, hook, event, deliveryID, body); err == nil {
+			return attempt, nil
+		}
+		if !retry {
+			return attempt, err
+		}
+		if attempt < s.maxAttempts {
+			log.Printf("Webhook %s delivery of %s failed (attempt %d/%d), retrying in %s: %v",
+				hook.ID.String(), event, attempt, s.maxAttempts, delay, err)
+			s.sleep(delay)
+			delay *= 2
+		}
+	}
+	return s.maxAttempts, err
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying. Client errors other than timeouts and rate limiting mean the
+// receiver won't accept the event however often it is sent.
+func (s *WebhookService) post(ctx context.Context, hook store.Webhook, event, deliveryID string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tickit-Webhooks")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(hook.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errBlockedAddress), err
+	}
+	defer resp.Body.Close()
+	// Drain some of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("receiver responded %s", resp.Status)
+}
+
+// ownedWebhook loads a webhook on a project the user owns
+func (s *WebhookService) ownedWebhook(ctx context.Context, projectID, webhookID, userID string) (*store.Webhook, error) {
+	var webhookUUID pgtype.UUID
+	if err := webhookUUID.Scan(webhookID); err != nil {
+		return nil, invalidID("webhook ID", err)
+	}
+
+	project, err := s.projectService.loadOwnedProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := s.queries.GetWebhookByID(ctx, webhookUUID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && hook.ProjectID != project.ID) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &hook, nil
+}
+
+// validateWebhookURL checks that deliveries can be POSTed to rawURL, and
+// that its host is public rather than, say, the metadata service or a
+// database next to us
+func (s *WebhookService) validateWebhookURL(ctx context.Context, rawURL string) error {
+	if !validator.IsValidURL(rawURL) {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidWebhook)
+	}
+	addrs, err := s.lookupIP(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: url host %q can't be resolved", ErrInvalidWebhook, u.Hostname())
+	}
+	for _, addr := range addrs {
+		if blockedAddr(addr) {
+			return fmt.Errorf("%w: url must point to a public address", ErrInvalidWebhook)
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns a client that only connects to public addresses.
+// The check is made on the address being dialed, after DNS resolution, so a
+// host that passed validateWebhookURL can't later resolve somewhere private.
+// Redirects are dialed the same way.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if blockedAddr(addr.Addr()) {
+				return fmt.Errorf("%w: %s", errBlockedAddress, addr.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: webhookTimeout},
+	}
+}
+
+// resolveHost looks up the addresses of host, which may be an IP address
+func resolveHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// blockedAddr reports whether deliveries to addr are refused: loopback,
+// link-local (including cloud metadata services), private and unspecified
+// addresses
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsPrivate() || addr.IsUnspecified()
+}
+
+// validateWebhookType checks that deliveries can be formatted for a type
+func validateWebhookType(webhookType string) error {
+	if _, ok := webhookFormatters[webhookType]; !ok {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidWebhook, webhookType)
+	}
+	return nil
+}
+
+// formatWebhook encodes a payload in the format of a webhook's type, falling
+// back to the generic format
+func formatWebhook(webhookType string, payload WebhookPayload) ([]byte, error) {
+	format, ok := webhookFormatters[webhookType]
+	if !ok {
+		format = webhookFormatters[WebhookTypeGeneric]
+	}
+	return format(payload)
+}
+
+// webhookEvents checks that events is a non-empty list of known events,
+// dropping repeats
+func webhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: subscribe to at least one event", ErrInvalidWebhook)
+	}
+
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		if !slices.Contains(result, event) {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func webhookToInfo(hook store.Webhook) WebhookInfo {
+	info := WebhookInfo{
+		ID:        hook.ID.String(),
+		ProjectID: hook.ProjectID.String(),
+		URL:       hook.Url,
+		Type:      hook.Type,
+		Events:    hook.Events,
+		CreatedAt: FormatTimestamp(hook.CreatedAt),
+		UpdatedAt: FormatTimestamp(hook.UpdatedAt),
+	}
+	if hook.CreatedBy.Valid {
+		info.CreatedBy = hook.CreatedBy.String()
+	}
+	return info
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/cache"
+	"github.com/Bethel-nz/tickit/internal/database/store"
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// receiver is a webhook endpoint that records what it is sent, answering
+// with each of statuses in turn and then 200
+type receiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []delivery
+}
+
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.deliveries = append(r.deliveries, delivery{req.Header.Clone(), body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]delivery(nil), r.deliveries...)
+}
+
+// hosts stands in for DNS in webhook tests
+var hosts = map[string]string{
+	"ci.example.com":       "203.0.113.10",
+	"internal.example.com": "10.0.0.5",
+}
+
+// newWebhookService delivers synchronously and records the delays it would
+// sleep for between attempts. Its client can reach the receivers, which
+// listen on loopback.
+func newWebhookService(db *storetest.DB) (*WebhookService, *[]time.Duration) {
+	queries := store.New(db)
+	svc := NewWebhookService(queries, NewProjectService(queries, cache.NewMemory(), nil, CacheTTLs{})).
+		WithRetry(3, 100*time.Millisecond)
+	svc.client = &http.Client{}
+	svc.lookupIP = func(_ context.Context, host string) ([]netip.Addr, error) {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			return []netip.Addr{addr}, nil
+		}
+		addr, ok := hosts[host]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []netip.Addr{netip.MustParseAddr(addr)}, nil
+	}
+
+	var delays []time.Duration
+	svc.sleep = func(d time.Duration) { delays = append(delays, d) }
+	svc.spawn = func(deliver func()) { deliver() }
+	return svc, &delays
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	got := SignWebhookPayload("It's a Secret to Everybody", []byte("Hello, World!"))
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got != want {
+		t.Errorf("SignWebhookPayload = %s, want %s", got, want)
+	}
+	if SignWebhookPayload("another secret", []byte("Hello, World!")) == want {
+		t.Error("Expected the signature to depend on the secret")
+	}
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	issues := newReceiver(t)
+	comments := newReceiver(t)
+	hook := func(id string, r *receiver, events ...string) []any {
+		return []any{storetest.MustUUID(t, id), storetest.MustUUID(t, project), r.URL, "s3cret-" + id[:1], events}
+	}
+	db := &storetest.DB{Lists: map[string][][]any{
+		"GetProjectWebhooks": {
+			hook("77777777-7777-7777-7777-777777777777", issues, EventIssueCreated, EventIssueClosed),
+			hook("88888888-8888-8888-8888-888888888888", comments, EventCommentCreated),
+		},
+	}}
+	svc, _ := newWebhookService(db)
+
+	issue := IssueInfo{ID: "66666666-6666-6666-6666-666666666666", Number: 42, Title: "Crash on login"}
+	svc.Dispatch(context.Background(), storetest.MustUUID(t, project), EventIssueCreated, owner, issue)
+
+	if got := comments.received(); len(got) != 0 {
+		t.Errorf("Expected the comment webhook to be skipped, got %d deliveries", len(got))
+	}
+	got := issues.received()
+	if len(got) != 1 {
+		t.Fatalf("Expected one delivery to the issue webhook, got %d", len(got))
+	}
+
+	d := got[0]
+	if sig := d.header.Get(WebhookSignatureHeader); sig != SignWebhookPayload("s3cret-7", d.body) {
+		t.Errorf("Signature %q doesn't match the body", sig)
+	}
+	if event := d.header.Get(WebhookEventHeader); event != EventIssueCreated {
+		t.Errorf("Event header = %q, want %q", event, EventIssueCreated)
+	}
+	if ct := d.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var payload struct {
+		WebhookPayload
+		Data IssueInfo `json:"data"`
+	}
+	if err := json.Unmarshal(d.body, &payload); err != nil {
+		t.Fatalf("Invalid payload %s: %v", d.body, err)
+	}
+	if payload.Event != EventIssueCreated || payload.ProjectID != project || payload.ActorID != owner {
+		t.Errorf("Payload = %+v, want %s in %s by %s", payload.WebhookPayload, EventIssueCreated, project, owner)
+	}
+	if payload.ID == "" || payload.ID != d.header.Get(WebhookDeliveryHeader) {
+		t.Errorf("Payload ID %q should match the delivery header %q", payload.ID, d.header.Get(WebhookDeliveryHeader))
+	}
+	if payload.Data.ID != issue.ID || payload.Data.Number != 42 {
+		t.Errorf("Data = %+v, want the issue", payload.Data)
+	}
+
+	t.Run("Events nobody subscribes to aren't sent", func(t *testing.T) {
+		svc.Dispatch(context.Background(), storetest.MustUUID(t, project), EventIssueUpdated, owner, issue)
+		if n := len(issues.received()) + len(comments.received()); n != 1 {
+			t.Errorf("Expected no new deliveries, got %d in total", n)
+		}
+	})
+}
+
+func TestWebhookRetries(t *testing.T) {
+	deliver := func(r *receiver) (int, []time.Duration, error) {
+		svc, delays := newWebhookService(&storetest.DB{})
+		hook := store.Webhook{ID: storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), Url: r.URL, Secret: "s3cret"}
+		attempts, err := svc.deliver(context.Background(), hook, EventIssueCreated, "d1", []byte(`{}`))
+		return attempts, *delays, err
+	}
+
+	t.Run("Server errors are retried with backoff", func(t *testing.T) {
+		r := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+		attempts, delays, err := deliver(r)
+		if err != nil {
+			t.Fatalf("deliver failed: %v", err)
+		}
+		if attempts != 3 || len(r.received()) != 3 {
+			t.Errorf("Made %d attempts and the receiver saw %d, want 3", attempts, len(r.received()))
+		}
+		want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+		if len(delays) != len(want) || delays[0] != want[0] || delays[1] != want[1] {
+			t.Errorf("Backoff delays = %v, want %v", delays, want)
+		}
+		for _, d := range r.received() {
+			if d.header.Get(WebhookDeliveryHeader) != "d1" {
+				t.Errorf("Retries should keep the delivery ID, got %q", d.header.Get(WebhookDeliveryHeader))
+			}
+		}
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		r := newReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		attempts, _, err := deliver(r)
+		if err == nil {
+			t.Fatal("Expected delivery to fail")
+		}
+		if attempts != 3 || len(r.received()) != 3 {
+			t.Errorf("Made %d attempts and the receiver saw %d, want 3", attempts, len(r.received()))
+		}
+	})
+
+	t.Run("Client errors aren't retried", func(t *testing.T) {
+		r := newReceiver(t, http.StatusGone)
+		attempts, delays, err := deliver(r)
+		if err == nil {
+			t.Fatal("Expected delivery to fail")
+		}
+		if attempts != 1 || len(delays) != 0 {
+			t.Errorf("Made %d attempts after %v, want a single attempt", attempts, delays)
+		}
+	})
+
+	t.Run("Rate limiting is retried", func(t *testing.T) {
+		r := newReceiver(t, http.StatusTooManyRequests)
+		if attempts, _, err := deliver(r); err != nil || attempts != 2 {
+			t.Errorf("deliver = %d attempts, %v; want 2 attempts and success", attempts, err)
+		}
+	})
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	// A host can resolve to a public address when the webhook is saved and a
+	// private one by the time it is delivered to, so the address is checked
+	// again when dialing. The receiver listens on 127.0.0.1.
+	r := newReceiver(t)
+	svc, _ := newWebhookService(&storetest.DB{})
+	svc.client = newWebhookClient()
+	hook := store.Webhook{ID: storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), Url: r.URL, Secret: "s3cret"}
+
+	attempts, err := svc.deliver(context.Background(), hook, EventIssueCreated, "d1", []byte(`{}`))
+	if !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("deliver = %v, want errBlockedAddress", err)
+	}
+	if attempts != 1 || len(r.received()) != 0 {
+		t.Errorf("Made %d attempts and the receiver saw %d, want a single refused attempt", attempts, len(r.received()))
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		member  = "22222222-2222-2222-2222-222222222222"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	setup := func() (*storetest.DB, *WebhookService) {
+		db := &storetest.DB{Rows: map[string][]any{
+			"GetProjectByID": {storetest.MustUUID(t, project), "Launch", pgtype.Text{}, storetest.MustUUID(t, owner)},
+			"CreateWebhook":  {storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), storetest.MustUUID(t, project)},
+		}}
+		svc, _ := newWebhookService(db)
+		return db, svc
+	}
+	ctx := context.Background()
+	input := WebhookInput{URL: "https://ci.example.com/hooks/tickit", Events: []string{EventIssueCreated, EventIssueCreated}}
+
+	t.Run("A secret is generated when none is given", func(t *testing.T) {
+		db, svc := setup()
+		if _, err := svc.CreateWebhook(ctx, project, owner, input); err != nil {
+			t.Fatalf("CreateWebhook failed: %v", err)
+		}
+		args := db.Args("CreateWebhook")
+		if len(args) != 1 {
+			t.Fatalf("Expected one webhook to be created, got %d", len(args))
+		}
+		if secret := args[0][2].(string); len(secret) != 64 {
+			t.Errorf("Secret = %q, want 32 random bytes in hex", secret)
+		}
+		if events := args[0][3].([]string); len(events) != 1 || events[0] != EventIssueCreated {
+			t.Errorf("Events = %v, want repeats dropped", events)
+		}
+		if webhookType := args[0][5]; webhookType != WebhookTypeGeneric {
+			t.Errorf("Type = %v, want %s by default", webhookType, WebhookTypeGeneric)
+		}
+	})
+
+	invalid := []struct {
+		name  string
+		input WebhookInput
+	}{
+		{"No events", WebhookInput{URL: input.URL}},
+		{"Unknown event", WebhookInput{URL: input.URL, Events: []string{"issue.deleted"}}},
+		{"Not an http URL", WebhookInput{URL: "ftp://ci.example.com", Events: input.Events}},
+		{"Unknown type", WebhookInput{URL: input.URL, Type: "discord", Events: input.Events}},
+		{"Loopback address", WebhookInput{URL: "http://127.0.0.1:8080/hooks", Events: input.Events}},
+		{"Metadata service", WebhookInput{URL: "http://169.254.169.254/latest/meta-data", Events: input.Events}},
+		{"Unspecified address", WebhookInput{URL: "http://[::]/hooks", Events: input.Events}},
+		{"Host resolving to a private address", WebhookInput{URL: "https://internal.example.com/hooks", Events: input.Events}},
+		{"Host that doesn't resolve", WebhookInput{URL: "https://nowhere.example.com/hooks", Events: input.Events}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			db, svc := setup()
+			if _, err := svc.CreateWebhook(ctx, project, owner, tt.input); !errors.Is(err, ErrInvalidWebhook) {
+				t.Errorf("CreateWebhook = %v, want ErrInvalidWebhook", err)
+			}
+			if db.Count("CreateWebhook") != 0 {
+				t.Error("Expected no webhook to be created")
+			}
+		})
+	}
+
+	t.Run("Only the owner manages webhooks", func(t *testing.T) {
+		db, svc := setup()
+		if _, err := svc.CreateWebhook(ctx, project, member, input); !errors.Is(err, ErrNotProjectOwner) {
+			t.Errorf("CreateWebhook = %v, want ErrNotProjectOwner", err)
+		}
+		if db.Count("CreateWebhook") != 0 {
+			t.Error("Expected no webhook to be created")
+		}
+	})
+}
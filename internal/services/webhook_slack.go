@@ -0,0 +1,118 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// slackExcerptLength bounds how much of a description or comment a Slack
+// message quotes
+const slackExcerptLength = 300
+
+// SlackMessage is the body of a message posted to a Slack incoming webhook.
+// Text is shown in notifications and by clients that can't render blocks.
+// Both are mrkdwn, so user content in them is escaped.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a Block Kit layout block: a section has text and fields, a
+// context block has elements
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a Block Kit text object
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackEscaper escapes the characters Slack's mrkdwn gives a meaning to
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// formatSlack encodes a payload as a Slack message
+func formatSlack(payload WebhookPayload) ([]byte, error) {
+	return json.Marshal(SlackMessageFor(payload))
+}
+
+// SlackMessageFor describes an event as a Slack message. Issue and comment
+// events get a summary of the issue or comment; any other event is named.
+func SlackMessageFor(payload WebhookPayload) SlackMessage {
+	switch data := payload.Data.(type) {
+	case IssueInfo:
+		return slackIssueMessage(payload.Event, data)
+	case CommentInfo:
+		return slackCommentMessage(payload.Event, data)
+	default:
+		return SlackMessage{Text: fmt.Sprintf("Tickit event %s", payload.Event)}
+	}
+}
+
+func slackIssueMessage(event string, issue IssueInfo) SlackMessage {
+	action := "updated"
+	switch event {
+	case EventIssueCreated:
+		action = "opened"
+	case EventIssueClosed:
+		action = "closed"
+	}
+	heading := fmt.Sprintf("Issue #%d %s", issue.Number, action)
+	title := slackEscaper.Replace(issue.Title)
+
+	msg := SlackMessage{
+		Text:   fmt.Sprintf("%s: %s", heading, title),
+		Blocks: []SlackBlock{slackSection(fmt.Sprintf("*%s:* %s", heading, title))},
+	}
+	if issue.Description != "" {
+		msg.Blocks = append(msg.Blocks, slackSection(slackEscaper.Replace(excerpt(issue.Description, slackExcerptLength))))
+	}
+
+	fields := []SlackText{slackField("Status", issue.Status)}
+	if issue.Priority != "" {
+		fields = append(fields, slackField("Priority", issue.Priority))
+	}
+	if issue.DueDate != nil {
+		fields = append(fields, slackField("Due", issue.DueDate.Format("2006-01-02")))
+	}
+	msg.Blocks = append(msg.Blocks, SlackBlock{Type: "section", Fields: fields}, slackContext(event))
+	return msg
+}
+
+func slackCommentMessage(event string, comment CommentInfo) SlackMessage {
+	on := slackEscaper.Replace(comment.ParentTitle)
+	if on == "" {
+		on = "a ticket"
+	}
+
+	// Quote every line of the comment. The quote markers are markup, so they
+	// are added after escaping.
+	quoted := slackEscaper.Replace(excerpt(comment.Content, slackExcerptLength))
+	quoted = "> " + strings.ReplaceAll(quoted, "\n", "\n> ")
+
+	return SlackMessage{
+		Text: fmt.Sprintf("New comment on %s", on),
+		Blocks: []SlackBlock{
+			slackSection(fmt.Sprintf("*New comment on* %s", on)),
+			slackSection(quoted),
+			slackContext(event),
+		},
+	}
+}
+
+func slackSection(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}}
+}
+
+func slackField(name, value string) SlackText {
+	return SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", name, slackEscaper.Replace(value))}
+}
+
+func slackContext(event string) SlackBlock {
+	return SlackBlock{Type: "context", Elements: []SlackText{{Type: "mrkdwn", Text: "Tickit · " + event}}}
+}
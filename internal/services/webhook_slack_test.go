@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bethel-nz/tickit/internal/database/store/storetest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// decodeJSON decodes a JSON document into plain maps and slices for
+// comparing structure
+func decodeJSON(t *testing.T, data []byte) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	return v
+}
+
+func TestSlackMessageFor(t *testing.T) {
+	due := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	issue := IssueInfo{
+		ID:          "66666666-6666-6666-6666-666666666666",
+		Number:      42,
+		Title:       "Crash on <login>",
+		Description: "Tapping sign in & waiting crashes the app",
+		Status:      "open",
+		Priority:    "high",
+		DueDate:     &due,
+	}
+
+	t.Run("Issue created", func(t *testing.T) {
+		body, err := formatWebhook(WebhookTypeSlack, WebhookPayload{Event: EventIssueCreated, Data: issue})
+		if err != nil {
+			t.Fatalf("formatWebhook failed: %v", err)
+		}
+		want := `{
+			"text": "Issue #42 opened: Crash on &lt;login&gt;",
+			"blocks": [
+				{"type": "section", "text": {"type": "mrkdwn", "text": "*Issue #42 opened:* Crash on &lt;login&gt;"}},
+				{"type": "section", "text": {"type": "mrkdwn", "text": "Tapping sign in &amp; waiting crashes the app"}},
+				{"type": "section", "fields": [
+					{"type": "mrkdwn", "text": "*Status*\nopen"},
+					{"type": "mrkdwn", "text": "*Priority*\nhigh"},
+					{"type": "mrkdwn", "text": "*Due*\n2024-05-31"}
+				]},
+				{"type": "context", "elements": [{"type": "mrkdwn", "text": "Tickit · issue.created"}]}
+			]
+		}`
+		if got := decodeJSON(t, body); !reflect.DeepEqual(got, decodeJSON(t, []byte(want))) {
+			t.Errorf("Slack message = %s, want %s", body, want)
+		}
+	})
+
+	t.Run("Issue updated and closed", func(t *testing.T) {
+		bare := IssueInfo{Number: 7, Title: "Typo", Status: "closed"}
+		for event, text := range map[string]string{
+			EventIssueUpdated: "Issue #7 updated: Typo",
+			EventIssueClosed:  "Issue #7 closed: Typo",
+		} {
+			msg := SlackMessageFor(WebhookPayload{Event: event, Data: bare})
+			if msg.Text != text {
+				t.Errorf("%s text = %q, want %q", event, msg.Text, text)
+			}
+			// Heading, fields and context, with no description or empty fields
+			if len(msg.Blocks) != 3 || len(msg.Blocks[1].Fields) != 1 {
+				t.Errorf("%s blocks = %+v, want a heading, the status and context", event, msg.Blocks)
+			}
+		}
+	})
+
+	t.Run("Comment created", func(t *testing.T) {
+		comment := CommentInfo{Content: "Reproduced <here>\nOn iOS 17", ParentTitle: "Crash on login"}
+		msg := SlackMessageFor(WebhookPayload{Event: EventCommentCreated, Data: comment})
+		if msg.Text != "New comment on Crash on login" {
+			t.Errorf("Text = %q", msg.Text)
+		}
+		if len(msg.Blocks) != 3 || msg.Blocks[1].Text == nil || msg.Blocks[1].Text.Text != "> Reproduced &lt;here&gt;\n> On iOS 17" {
+			t.Errorf("Blocks = %+v, want the comment quoted line by line", msg.Blocks)
+		}
+	})
+}
+
+func TestWebhookDispatchFormats(t *testing.T) {
+	const (
+		owner   = "11111111-1111-1111-1111-111111111111"
+		project = "55555555-5555-5555-5555-555555555555"
+	)
+
+	generic := newReceiver(t)
+	slack := newReceiver(t)
+	db := &storetest.DB{Lists: map[string][][]any{
+		"GetProjectWebhooks": {
+			{storetest.MustUUID(t, "77777777-7777-7777-7777-777777777777"), storetest.MustUUID(t, project), generic.URL, "s3cret", []string{EventIssueCreated},
+				storetest.MustUUID(t, owner), pgtype.Timestamp{}, pgtype.Timestamp{}, WebhookTypeGeneric},
+			{storetest.MustUUID(t, "88888888-8888-8888-8888-888888888888"), storetest.MustUUID(t, project), slack.URL, "s3cret", []string{EventIssueCreated},
+				storetest.MustUUID(t, owner), pgtype.Timestamp{}, pgtype.Timestamp{}, WebhookTypeSlack},
+		},
+	}}
+	svc, _ := newWebhookService(db)
+
+	issue := IssueInfo{Number: 42, Title: "Crash on login", Status: "open"}
+	svc.Dispatch(context.Background(), storetest.MustUUID(t, project), EventIssueCreated, owner, issue)
+
+	got := generic.received()
+	if len(got) != 1 {
+		t.Fatalf("Expected one generic delivery, got %d", len(got))
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(got[0].body, &payload); err != nil || payload.Event != EventIssueCreated {
+		t.Errorf("Generic webhook got %s, want the JSON payload", got[0].body)
+	}
+
+	got = slack.received()
+	if len(got) != 1 {
+		t.Fatalf("Expected one Slack delivery, got %d", len(got))
+	}
+	var msg SlackMessage
+	if err := json.Unmarshal(got[0].body, &msg); err != nil || msg.Text != "Issue #42 opened: Crash on login" || len(msg.Blocks) == 0 {
+		t.Errorf("Slack webhook got %s, want a Slack message", got[0].body)
+	}
+	if sig := got[0].header.Get(WebhookSignatureHeader); sig != SignWebhookPayload("s3cret", got[0].body) {
+		t.Errorf("Signature %q doesn't match the Slack body", sig)
+	}
+}
@@ -0,0 +1,102 @@
+// Package slug turns project and team names into URL-friendly identifiers,
+// e.g. "Café Déjà Vu!" becomes "cafe-deja-vu".
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make returns, leaving room for the suffix
+// Unique may add within the storedLength characters the database allows
+const (
+	MaxLength    = 60
+	storedLength = 80
+)
+
+// folded spells out letters that don't decompose into an ASCII letter and an
+// accent
+var folded = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th",
+}
+
+// Make returns the slug for name: lowercase ASCII letters and digits, with
+// every run of anything else turned into a single hyphen. Accents are
+// dropped, so "é" becomes "e", and apostrophes are removed, so "Ada's" becomes
+// "adas". When nothing is left, e.g. for a name in another script, fallback
+// is returned instead.
+func Make(name, fallback string) string {
+	var b strings.Builder
+	hyphen := false
+	write := func(s string) {
+		if hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		hyphen = false
+		b.WriteString(s)
+	}
+
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if b.Len() >= MaxLength {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			write(string(r))
+		case folded[r] != "":
+			write(folded[r])
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			// Accents split off by decomposing, and apostrophes
+		default:
+			hyphen = true
+		}
+	}
+
+	s := b.String()
+	if len(s) > MaxLength {
+		s = s[:MaxLength]
+	}
+	s = strings.TrimRight(s, "-")
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// Valid reports whether s could be a slug returned by Make or Unique:
+// lowercase letters and digits in hyphen-separated groups
+func Valid(s string) bool {
+	if s == "" || len(s) > storedLength || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && s[i-1] != '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Unique returns base if it isn't taken, and otherwise base with the lowest
+// free numeric suffix, starting from 2: my-project, my-project-2, and so on.
+func Unique(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+	if !used[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		if s := base + "-" + strconv.Itoa(n); !used[s] {
+			return s
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"Simple", "My Project", "my-project"},
+		{"Punctuation", "  Q3 -- Launch (v2.0)!  ", "q3-launch-v2-0"},
+		{"Accents", "Café Déjà Vu", "cafe-deja-vu"},
+		{"Folded letters", "Straße Ærø", "strasse-aero"},
+		{"Apostrophe", "Ada's Tasks", "adas-tasks"},
+		{"Emoji", "Rocket 🚀 Team", "rocket-team"},
+		{"Other script", "東京", "project"},
+		{"Only punctuation", "!!!", "project"},
+		{"Already a slug", "my-project-2", "my-project-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.in, "project"); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Long names are cut", func(t *testing.T) {
+		got := Make(strings.Repeat("word ", 30), "project")
+		if len(got) > MaxLength || strings.HasSuffix(got, "-") {
+			t.Errorf("Make() = %q (%d characters), want at most %d without a trailing hyphen", got, len(got), MaxLength)
+		}
+	})
+}
+
+func TestValid(t *testing.T) {
+	for _, s := range []string{"my-project", "q3-launch-v2-0", "project", "my-project-12"} {
+		if !Valid(s) {
+			t.Errorf("Valid(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "My-Project", "my--project", "-project", "project-", "my_project", "café", strings.Repeat("a", 100)} {
+		if Valid(s) {
+			t.Errorf("Valid(%q) = true, want false", s)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"Free", nil, "my-project"},
+		{"Taken", []string{"my-project"}, "my-project-2"},
+		{"Next free suffix", []string{"my-project", "my-project-2", "my-project-3"}, "my-project-4"},
+		{"Gap is reused", []string{"my-project", "my-project-3"}, "my-project-2"},
+		{"Only suffixed ones taken", []string{"my-project-2"}, "my-project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unique("my-project", tt.taken); got != tt.want {
+				t.Errorf("Unique(%v) = %q, want %q", tt.taken, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,14 +4,57 @@ import "time"
 
 // AppConfig holds application configuration values.
 type AppConfig struct {
-	DatabaseURL        string        // PostgreSQL connection string
-	AppPort            int           // Port to listen on
-	DebugMode          bool          // Enable debug mode
-	RequestTimeout     time.Duration // Timeout for requests
-	Threshold          float64       // Threshold value
-	RedisURL           string        // Redis connection URL
-	MaxOpenConns       int           // Maximum open database connections
-	MaxIdleTime        time.Duration // Maximum idle time for database connections
-	ServerReadTimeout  time.Duration // Server Read Timeout
-	ServerWriteTimeout time.Duration // Server Write Timeout
+	DatabaseURL          string        // PostgreSQL connection string
+	DatabaseReplicaURL   string        // Optional read replica for read-only queries
+	DBRetryAttempts      int           // Attempts for queries failing with transient errors (1 disables retries)
+	DBRetryDelay         time.Duration // Delay before the first query retry, doubled for each one after
+	AppPort              int           // Port to listen on
+	DebugMode            bool          // Enable debug mode
+	RequestTimeout       time.Duration // Timeout for requests
+	Threshold            float64       // Threshold value
+	RedisURL             string        // Redis connection URL
+	MaxOpenConns         int           // Maximum open database connections
+	MaxIdleTime          time.Duration // Maximum idle time for database connections
+	ServerReadTimeout    time.Duration // Server Read Timeout
+	ServerWriteTimeout   time.Duration // Server Write Timeout
+	TLSDomains           []string      // Domains to get Let's Encrypt certificates for (empty = plain HTTP)
+	TLSCacheDir          string        // Directory Let's Encrypt certificates are kept in
+	HTTPRedirectPort     int           // Plain HTTP port redirecting to HTTPS when TLS is on (0 = off)
+	InternalAddr         string        // Address the internal endpoints (metrics, health) listen on, apart from APP_PORT
+	CORSAllowedOrigins   []string      // Allowed origins, or "*" for any
+	CORSAllowCredentials bool          // Allow cookies and auth headers on cross-origin requests
+	CORSMaxAge           time.Duration // How long browsers may cache preflight responses
+	MaxBodyBytes         int           // Largest request body accepted outside the upload routes
+	SlugsFollowNames     bool          // Renaming a project or team changes its slug (default keeps it)
+	CreationRateLimit    int           // Max projects/issues/comments a user may create per hour (0 = unlimited)
+	UploadDir            string        // Directory uploaded files are stored in
+	UploadBaseURL        string        // Public URL prefix uploaded files are served under
+	AvatarMaxBytes       int           // Maximum avatar upload size in bytes
+	AttachmentDir        string        // Directory ticket attachments are stored in; not served publicly
+	AttachmentMaxBytes   int           // Maximum attachment upload size in bytes
+	EmailFrom            string        // Sender address for outgoing email
+	EmailFromName        string        // Sender display name for outgoing email
+	SMTPHost             string        // SMTP server host; emails are only logged when empty
+	SMTPPort             int           // SMTP server port
+	SMTPUsername         string        // SMTP username (empty = no authentication)
+	SMTPPassword         string        // SMTP password
+	SMTPUseTLS           bool          // Use implicit TLS instead of STARTTLS
+	EmailMaxAttempts     int           // Delivery attempts before an email is dead-lettered
+	EmailRetryDelay      time.Duration // Delay before the first email retry, doubled for each one after
+	JWTKey               string        // Secret used to sign access tokens
+	JWTPreviousKeys      []string      // Retired keys still accepted for verification
+	JWTExpiry            time.Duration // Access token lifetime
+	JWTIssuer            string        // Issuer written to and required of access tokens
+	TrustedProxies       []string      // CIDRs of proxies whose forwarding headers are trusted
+	CacheTTLUser         time.Duration // How long users and profiles are cached
+	CacheTTLTeam         time.Duration // How long team details are cached
+	CacheTTLTeamMembers  time.Duration // How long team member lists are cached
+	CacheTTLTeamList     time.Duration // How long a user's team list is cached
+	CacheTTLProject      time.Duration // How long project details are cached
+	CacheTTLProjectList  time.Duration // How long project lists are cached
+	CacheTTLProjectStats time.Duration // How long project issue counts are cached
+	CacheTTLComments     time.Duration // How long comment lists are cached
+	CacheTTLAccount      time.Duration // How long an account's disabled flag is cached
+	ResetTokenTTL        time.Duration // How long a password reset link stays valid
+	EmailChangeTTL       time.Duration // How long an email change confirmation link stays valid
 }
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConfigError lists every problem found in an AppConfig
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks the config before anything is started with it, so that a
+// bad value is reported up front instead of failing later on first use. All
+// problems are reported together as a *ConfigError, named by environment
+// variable.
+func (c *AppConfig) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	positive := func(name string, d time.Duration) {
+		check(d > 0, "%s must be a positive duration, got %s", name, d)
+	}
+
+	if _, err := pgxpool.ParseConfig(c.DatabaseURL); err != nil {
+		problems = append(problems, fmt.Sprintf("DATABASE_URL is not a valid connection string: %v", err))
+	}
+	if c.DatabaseReplicaURL != "" {
+		if _, err := pgxpool.ParseConfig(c.DatabaseReplicaURL); err != nil {
+			problems = append(problems, fmt.Sprintf("DATABASE_REPLICA_URL is not a valid connection string: %v", err))
+		}
+	}
+	check(c.MaxOpenConns > 0 && c.MaxOpenConns <= math.MaxInt32, "MAX_OPEN_CONNS must be between 1 and %d, got %d", math.MaxInt32, c.MaxOpenConns)
+	positive("MAX_IDLE_TIME", c.MaxIdleTime)
+	check(c.DBRetryAttempts > 0, "DB_RETRY_ATTEMPTS must be at least 1, got %d", c.DBRetryAttempts)
+	check(c.DBRetryDelay >= 0, "DB_RETRY_DELAY must not be negative, got %s", c.DBRetryDelay)
+	check(strings.TrimSpace(c.RedisURL) != "", "REDIS_URL must be set")
+
+	check(c.AppPort > 0 && c.AppPort <= 65535, "APP_PORT must be between 1 and 65535, got %d", c.AppPort)
+	if c.HTTPRedirectPort != 0 {
+		check(c.HTTPRedirectPort > 0 && c.HTTPRedirectPort <= 65535, "HTTP_REDIRECT_PORT must be between 1 and 65535, got %d", c.HTTPRedirectPort)
+		check(c.HTTPRedirectPort != c.AppPort, "HTTP_REDIRECT_PORT must differ from APP_PORT, both are %d", c.AppPort)
+	}
+	if _, port, err := net.SplitHostPort(c.InternalAddr); err != nil {
+		problems = append(problems, fmt.Sprintf("INTERNAL_ADDR must be a host:port address, got %q", c.InternalAddr))
+	} else {
+		check(port != strconv.Itoa(c.AppPort), "INTERNAL_ADDR must use a different port from APP_PORT, both are %d", c.AppPort)
+	}
+	positive("REQUEST_TIMEOUT", c.RequestTimeout)
+	positive("SERVER_READ_TIMEOUT", c.ServerReadTimeout)
+	positive("SERVER_WRITE_TIMEOUT", c.ServerWriteTimeout)
+	check(c.CORSMaxAge >= 0, "CORS_MAX_AGE must not be negative, got %s", c.CORSMaxAge)
+	check(c.MaxBodyBytes > 0, "MAX_BODY_BYTES must be at least 1, got %d", c.MaxBodyBytes)
+	check(c.CreationRateLimit >= 0, "CREATION_RATE_LIMIT must not be negative, got %d", c.CreationRateLimit)
+	check(c.AvatarMaxBytes > 0, "AVATAR_MAX_BYTES must be at least 1, got %d", c.AvatarMaxBytes)
+	check(c.AttachmentMaxBytes > 0, "ATTACHMENT_MAX_BYTES must be at least 1, got %d", c.AttachmentMaxBytes)
+
+	if c.SMTPHost != "" {
+		check(c.SMTPPort > 0 && c.SMTPPort <= 65535, "SMTP_PORT must be between 1 and 65535, got %d", c.SMTPPort)
+	}
+	check(c.EmailMaxAttempts > 0, "EMAIL_MAX_ATTEMPTS must be at least 1, got %d", c.EmailMaxAttempts)
+	check(c.EmailRetryDelay >= 0, "EMAIL_RETRY_DELAY must not be negative, got %s", c.EmailRetryDelay)
+
+	check(c.JWTKey != "", "TICKIT_JWT_KEY must be set")
+	positive("JWT_EXPIRY", c.JWTExpiry)
+
+	for _, ttl := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"CACHE_TTL_USER", c.CacheTTLUser},
+		{"CACHE_TTL_TEAM", c.CacheTTLTeam},
+		{"CACHE_TTL_TEAM_MEMBERS", c.CacheTTLTeamMembers},
+		{"CACHE_TTL_TEAM_LIST", c.CacheTTLTeamList},
+		{"CACHE_TTL_PROJECT", c.CacheTTLProject},
+		{"CACHE_TTL_PROJECT_LIST", c.CacheTTLProjectList},
+		{"CACHE_TTL_PROJECT_STATS", c.CacheTTLProjectStats},
+		{"CACHE_TTL_COMMENTS", c.CacheTTLComments},
+		{"CACHE_TTL_ACCOUNT", c.CacheTTLAccount},
+		{"RESET_TOKEN_TTL", c.ResetTokenTTL},
+		{"EMAIL_CHANGE_TTL", c.EmailChangeTTL},
+	} {
+		positive(ttl.name, ttl.d)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validConfig() *AppConfig {
+	return &AppConfig{
+		DatabaseURL:          "postgres://admin:secret@db:5432/tickit?sslmode=disable",
+		DBRetryAttempts:      3,
+		DBRetryDelay:         50 * time.Millisecond,
+		AppPort:              5479,
+		InternalAddr:         "localhost:9090",
+		RequestTimeout:       5 * time.Second,
+		RedisURL:             "localhost:6379",
+		MaxOpenConns:         25,
+		MaxIdleTime:          5 * time.Minute,
+		ServerReadTimeout:    10 * time.Second,
+		ServerWriteTimeout:   30 * time.Second,
+		CORSMaxAge:           10 * time.Minute,
+		MaxBodyBytes:         1 << 20,
+		AvatarMaxBytes:       2 << 20,
+		AttachmentMaxBytes:   10 << 20,
+		SMTPPort:             587,
+		EmailMaxAttempts:     3,
+		EmailRetryDelay:      time.Second,
+		JWTKey:               "in-test-secret",
+		JWTExpiry:            24 * time.Hour,
+		CacheTTLUser:         time.Hour,
+		CacheTTLTeam:         time.Hour,
+		CacheTTLTeamMembers:  5 * time.Minute,
+		CacheTTLTeamList:     10 * time.Minute,
+		CacheTTLProject:      time.Hour,
+		CacheTTLProjectList:  10 * time.Minute,
+		CacheTTLProjectStats: 5 * time.Minute,
+		CacheTTLComments:     10 * time.Minute,
+		CacheTTLAccount:      30 * time.Second,
+		ResetTokenTTL:        24 * time.Hour,
+		EmailChangeTTL:       24 * time.Hour,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Valid config was rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *AppConfig)
+		want   []string
+	}{
+		{
+			name:   "Unparseable DSN",
+			modify: func(c *AppConfig) { c.DatabaseURL = "postgres://admin@db:port/tickit" },
+			want:   []string{"DATABASE_URL is not a valid connection string"},
+		},
+		{
+			name:   "Bad replica DSN",
+			modify: func(c *AppConfig) { c.DatabaseReplicaURL = "postgres://db:5432/tickit?connect_timeout=soon" },
+			want:   []string{"DATABASE_REPLICA_URL is not a valid connection string"},
+		},
+		{
+			name:   "Port out of range",
+			modify: func(c *AppConfig) { c.AppPort = 70000 },
+			want:   []string{"APP_PORT must be between 1 and 65535, got 70000"},
+		},
+		{
+			name:   "Redirect port clashes with the app",
+			modify: func(c *AppConfig) { c.HTTPRedirectPort = 5479 },
+			want:   []string{"HTTP_REDIRECT_PORT must differ from APP_PORT, both are 5479"},
+		},
+		{
+			name:   "Internal address without a port",
+			modify: func(c *AppConfig) { c.InternalAddr = "localhost" },
+			want:   []string{`INTERNAL_ADDR must be a host:port address, got "localhost"`},
+		},
+		{
+			name:   "Internal address clashes with the app",
+			modify: func(c *AppConfig) { c.InternalAddr = "127.0.0.1:5479" },
+			want:   []string{"INTERNAL_ADDR must use a different port from APP_PORT, both are 5479"},
+		},
+		{
+			name:   "SMTP port only matters with a host",
+			modify: func(c *AppConfig) { c.SMTPPort = 0 },
+		},
+		{
+			name: "Every problem is reported",
+			modify: func(c *AppConfig) {
+				c.AppPort = 0
+				c.MaxOpenConns = 0
+				c.RequestTimeout = 0
+				c.CacheTTLComments = -time.Minute
+				c.SMTPHost = "smtp.example.com"
+				c.SMTPPort = 0
+				c.JWTKey = ""
+			},
+			want: []string{
+				"MAX_OPEN_CONNS must be between 1 and 2147483647, got 0",
+				"APP_PORT must be between 1 and 65535, got 0",
+				"REQUEST_TIMEOUT must be a positive duration, got 0s",
+				"SMTP_PORT must be between 1 and 65535, got 0",
+				"TICKIT_JWT_KEY must be set",
+				"CACHE_TTL_COMMENTS must be a positive duration, got -1m0s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Validate() = %v, want a *ConfigError", err)
+			}
+			if len(configErr.Problems) != len(tt.want) {
+				t.Fatalf("Problems = %q, want %q", configErr.Problems, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(configErr.Problems[i], want) {
+					t.Errorf("Problem %d = %q, want %q", i, configErr.Problems[i], want)
+				}
+			}
+		})
+	}
+
+	t.Run("The error lists each problem on its own line", func(t *testing.T) {
+		c := validConfig()
+		c.AppPort = 0
+		c.JWTKey = ""
+		err := c.Validate()
+		want := &ConfigError{Problems: []string{"APP_PORT must be between 1 and 65535, got 0", "TICKIT_JWT_KEY must be set"}}
+		if !reflect.DeepEqual(err, want) {
+			t.Fatalf("Validate() = %#v, want %#v", err, want)
+		}
+		if got := err.Error(); got != "invalid configuration:\n  APP_PORT must be between 1 and 65535, got 0\n  TICKIT_JWT_KEY must be set" {
+			t.Errorf("Error() = %q", got)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Kinds of resources whose creation is tracked
+const (
+	KindProject = "project"
+	KindIssue   = "issue"
+	KindComment = "comment"
+)
+
+// bucketTTL keeps hourly buckets long enough to answer "last 24 hours" queries.
+const bucketTTL = 48 * time.Hour
+
+// MaxWindowHours is the widest window TopCreators can report on.
+const MaxWindowHours = 24
+
+// CreatorStat is a user's creation count over a reporting window
+type CreatorStat struct {
+	UserID string `json:"user_id"`
+	Count  int64  `json:"count"`
+}
+
+// Tracker counts resource creations per user in hourly Redis buckets.
+// Each bucket is a sorted set keyed by kind and hour, scored by count.
+type Tracker struct {
+	cache *redis.Client
+	now   func() time.Time
+}
+
+// NewTracker creates a creation-rate tracker backed by Redis
+func NewTracker(cache *redis.Client) *Tracker {
+	return &Tracker{cache: cache, now: time.Now}
+}
+
+func bucketKey(kind string, hour time.Time) string {
+	return fmt.Sprintf("usage:creations:%s:%s", kind, hour.UTC().Format("2006010215"))
+}
+
+// Record counts one creation of kind by userID in the current hour
+func (t *Tracker) Record(ctx context.Context, kind, userID string) error {
+	key := bucketKey(kind, t.now())
+	pipe := t.cache.TxPipeline()
+	pipe.ZIncrBy(ctx, key, 1, userID)
+	pipe.Expire(ctx, key, bucketTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// CurrentHourCount returns how many resources of kind userID created this hour
+func (t *Tracker) CurrentHourCount(ctx context.Context, kind, userID string) (int64, error) {
+	score, err := t.cache.ZScore(ctx, bucketKey(kind, t.now()), userID).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return int64(score), err
+}
+
+// TopCreators returns the users who created the most resources of kind over
+// the last hours hours (including the current one), highest first.
+func (t *Tracker) TopCreators(ctx context.Context, kind string, hours, limit int) ([]CreatorStat, error) {
+	if hours < 1 || hours > MaxWindowHours {
+		hours = MaxWindowHours
+	}
+
+	totals := make(map[string]int64)
+	now := t.now()
+	for i := 0; i < hours; i++ {
+		entries, err := t.cache.ZRangeWithScores(ctx, bucketKey(kind, now.Add(-time.Duration(i)*time.Hour)), 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read creation counts: %w", err)
+		}
+		for _, e := range entries {
+			totals[e.Member.(string)] += int64(e.Score)
+		}
+	}
+
+	stats := make([]CreatorStat, 0, len(totals))
+	for userID, count := range totals {
+		stats = append(stats, CreatorStat{UserID: userID, Count: count})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].UserID < stats[j].UserID
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
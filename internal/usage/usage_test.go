@@ -0,0 +1,85 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestTracker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer cache.Close()
+
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	tracker := NewTracker(cache)
+	tracker.now = func() time.Time { return now }
+
+	record := func(kind, userID string, n int) {
+		for i := 0; i < n; i++ {
+			if err := tracker.Record(ctx, kind, userID); err != nil {
+				t.Fatalf("Record failed: %v", err)
+			}
+		}
+	}
+
+	// Two hours ago, then the current hour
+	now = now.Add(-2 * time.Hour)
+	record(KindIssue, "alice", 4)
+	now = now.Add(2 * time.Hour)
+	record(KindIssue, "bob", 3)
+	record(KindIssue, "carol", 1)
+	record(KindIssue, "alice", 1)
+	record(KindComment, "carol", 10)
+
+	t.Run("Current hour count", func(t *testing.T) {
+		count, err := tracker.CurrentHourCount(ctx, KindIssue, "alice")
+		if err != nil {
+			t.Fatalf("CurrentHourCount failed: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1, got %d", count)
+		}
+
+		count, err = tracker.CurrentHourCount(ctx, KindIssue, "nobody")
+		if err != nil || count != 0 {
+			t.Errorf("Expected 0 for unknown user, got %d (%v)", count, err)
+		}
+	})
+
+	t.Run("Top creators over window", func(t *testing.T) {
+		stats, err := tracker.TopCreators(ctx, KindIssue, 3, 2)
+		if err != nil {
+			t.Fatalf("TopCreators failed: %v", err)
+		}
+		want := []CreatorStat{{"alice", 5}, {"bob", 3}}
+		if len(stats) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, stats)
+		}
+		for i := range want {
+			if stats[i] != want[i] {
+				t.Errorf("Expected %v, got %v", want, stats)
+			}
+		}
+	})
+
+	t.Run("Window excludes older buckets", func(t *testing.T) {
+		stats, err := tracker.TopCreators(ctx, KindIssue, 1, 0)
+		if err != nil {
+			t.Fatalf("TopCreators failed: %v", err)
+		}
+		if len(stats) != 3 || stats[0] != (CreatorStat{"bob", 3}) {
+			t.Errorf("Expected bob first with 3, got %v", stats)
+		}
+	})
+
+	t.Run("Buckets expire", func(t *testing.T) {
+		if ttl := mr.TTL(bucketKey(KindComment, now)); ttl != bucketTTL {
+			t.Errorf("Expected TTL %v, got %v", bucketTTL, ttl)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CheckStruct checks the fields of the struct s (or the struct it points to)
+// against their validate tags, recording errors under each field's JSON name:
+//
+//	Email string `json:"email" validate:"required,email,max=100"`
+//
+// A field's rules are checked in order and only its first failure is kept.
+// The rules are:
+//
+//	required    not blank (strings), not empty (slices, maps) or not zero
+//	omitempty   skip the remaining rules when the field is empty
+//	email       a valid email address
+//	url         an absolute http or https URL
+//	uuid        a UUID
+//	alphanum    only letters and digits
+//	min=N       at least N characters (strings), items (slices, maps) or N
+//	max=N       at most N characters, items or N
+//	oneof=a b   one of the space-separated values
+//
+// Unknown rules and rules that don't fit the field's type panic, as they are
+// mistakes in the request type rather than in the request.
+func (v *Validator) CheckStruct(s any) {
+	rv := reflect.Indirect(reflect.ValueOf(s))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: CheckStruct needs a struct, got %T", s))
+	}
+	v.checkFields(rv)
+}
+
+// checkFields checks each tagged field of rv, including those of embedded
+// structs
+func (v *Validator) checkFields(rv reflect.Value) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			v.checkFields(rv.Field(i))
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		v.checkRules(name, rv.Field(i), strings.Split(tag, ","))
+	}
+}
+
+func (v *Validator) checkRules(name string, value reflect.Value, rules []string) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			v.CheckField(!slices.Contains(rules, "required"), name, name+" is required")
+			return
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range rules {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch rule {
+		case "omitempty":
+			if isEmpty(value) {
+				return
+			}
+		case "required":
+			v.CheckField(!isEmpty(value), name, name+" is required")
+		case "email":
+			v.CheckField(Matches(stringOf(value, name, rule), EmailRX), name, "must be a valid email address")
+		case "url":
+			v.CheckField(IsValidURL(stringOf(value, name, rule)), name, "must be a valid URL")
+		case "uuid":
+			v.CheckField(IsUUID(stringOf(value, name, rule)), name, "must be a valid UUID")
+		case "alphanum":
+			v.CheckField(IsAlphanumeric(stringOf(value, name, rule)), name, "may only contain letters and digits")
+		case "min", "max":
+			v.checkBound(name, value, rule, param)
+		case "oneof":
+			options := strings.Fields(param)
+			v.CheckField(IsOneOf(stringOf(value, name, rule), options...), name, "must be one of "+strings.Join(options, ", "))
+		default:
+			panic(fmt.Sprintf("validator: unknown rule %q on %s", rule, name))
+		}
+	}
+}
+
+// checkBound checks a min or max rule against a string's length in
+// characters, a collection's length, or a number's value
+func (v *Validator) checkBound(name string, value reflect.Value, rule, param string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s on %s needs a number, got %q", rule, name, param))
+	}
+
+	var size float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = value.Float()
+	default:
+		panic(fmt.Sprintf("validator: %s doesn't apply to %s, a %s", rule, name, value.Type()))
+	}
+
+	if rule == "min" {
+		v.CheckField(size >= limit, name, "must be at least "+param+unit)
+	} else {
+		v.CheckField(size <= limit, name, "cannot exceed "+param+unit)
+	}
+}
+
+// isEmpty reports whether a field holds nothing: a blank string, an empty
+// collection or a zero value
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return !NotBlank(value.String())
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func stringOf(value reflect.Value, name, rule string) string {
+	if value.Kind() != reflect.String {
+		panic(fmt.Sprintf("validator: %s doesn't apply to %s, a %s", rule, name, value.Type()))
+	}
+	return value.String()
+}
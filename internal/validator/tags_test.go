@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type signup struct {
+	Email    string   `json:"email" validate:"required,email,max=30"`
+	Name     string   `json:"name,omitempty" validate:"max=5"`
+	Username string   `json:"username" validate:"omitempty,alphanum"`
+	Role     string   `json:"role" validate:"oneof=member admin"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Age      int      `validate:"min=13"`
+	Homepage *string  `json:"homepage" validate:"url"`
+	ignored  string   `validate:"required"`
+}
+
+func TestCheckStruct(t *testing.T) {
+	valid := func() signup {
+		return signup{Email: "ada@example.com", Role: "member", Age: 36}
+	}
+	homepage := "not a url"
+
+	tests := []struct {
+		name   string
+		modify func(s *signup)
+		want   map[string]string
+	}{
+		{"Valid", func(s *signup) {}, nil},
+		{"Required", func(s *signup) { s.Email = "  " }, map[string]string{"email": "email is required"}},
+		{"Email", func(s *signup) { s.Email = "ada" }, map[string]string{"email": "must be a valid email address"}},
+		{"Max characters", func(s *signup) { s.Email = strings.Repeat("a", 20) + "@example.com" }, map[string]string{"email": "cannot exceed 30 characters"}},
+		{"Max counts characters, not bytes", func(s *signup) { s.Name = "Zoë Ü" }, nil},
+		{"Max items", func(s *signup) { s.Tags = []string{"a", "b", "c"} }, map[string]string{"tags": "cannot exceed 2 items"}},
+		{"Min number", func(s *signup) { s.Age = 12 }, map[string]string{"Age": "must be at least 13"}},
+		{"Omitempty skips empty fields", func(s *signup) { s.Username = "" }, nil},
+		{"Omitempty checks the rest", func(s *signup) { s.Username = "ada lovelace" }, map[string]string{"username": "may only contain letters and digits"}},
+		{"Oneof", func(s *signup) { s.Role = "owner" }, map[string]string{"role": "must be one of member, admin"}},
+		{"Pointer", func(s *signup) { s.Homepage = &homepage }, map[string]string{"homepage": "must be a valid URL"}},
+		{
+			"Every field is reported",
+			func(s *signup) { *s = signup{Name: "Ada Lovelace", Role: "admin", Age: 13} },
+			map[string]string{"email": "email is required", "name": "cannot exceed 5 characters"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			var v Validator
+			v.CheckStruct(&s)
+			if !reflect.DeepEqual(v.FieldErrors, tt.want) {
+				t.Errorf("FieldErrors = %v, want %v", v.FieldErrors, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStructPanicsOnBadTags(t *testing.T) {
+	for name, s := range map[string]any{
+		"Unknown rule": &struct {
+			A string `validate:"shiny"`
+		}{},
+		"Wrong type": &struct {
+			A int `validate:"email"`
+		}{},
+		"Bad bound": &struct {
+			A string `validate:"max=ten"`
+		}{},
+		"Not a struct": new(string),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			var v Validator
+			v.CheckStruct(s)
+		})
+	}
+}
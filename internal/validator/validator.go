@@ -124,13 +124,18 @@ func HasSuffix(value, suffix string) bool {
 	return strings.HasSuffix(value, suffix)
 }
 
-// IsValidURL returns true if a string is a valid URL.
+// IsValidURL returns true if a string is a valid absolute http or https URL.
+// Other schemes such as javascript: or data: are rejected so stored URLs are
+// safe to render as links.
 func IsValidURL(value string) bool {
 	if !URLRx.MatchString(value) {
 		return false
 	}
-	_, err := url.ParseRequestURI(value)
-	return err == nil
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 // IsValidDate returns true if a string is a valid date in the specified layout.
@@ -0,0 +1,28 @@
+package validator
+
+import "testing"
+
+func TestIsValidURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/avatar.png", true},
+		{"http://cdn.example.com:8080/a/b.jpg", true},
+		{"javascript:alert(1)", false},
+		{"JavaScript://example.com/%0Aalert(1)", false},
+		{"data:image/png;base64,AAAA", false},
+		{"ftp://example.com/avatar.png", false},
+		{"//example.com/avatar.png", false},
+		{"not a url", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsValidURL(tt.url); got != tt.want {
+				t.Errorf("IsValidURL(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
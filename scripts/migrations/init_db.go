@@ -14,7 +14,10 @@ import (
 func main() {
 
 	flag.Parse()
-	var dbURL = env.String("DATABASE_URL", "", env.Require).Get()
+	dbURL, err := env.String("DATABASE_URL", "", env.Require).Lookup()
+	if err != nil {
+		log.Fatal(err)
+	}
 	var migrationsPath = env.String("MIGRATIONS_PATH", "internal/database/migrations", env.Optional).Get()
 
 	m, err := migrate.New(